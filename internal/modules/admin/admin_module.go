@@ -42,6 +42,7 @@ type AdminModule struct {
 	skillCategoryHandler        *handler.SkillCategoryHandler
 	actionCategoryHandler       *handler.ActionCategoryHandler
 	damageTypeHandler           *handler.DamageTypeHandler
+	currencyHandler             *handler.CurrencyHandler
 	heroAttributeTypeHandler    *handler.HeroAttributeTypeHandler
 	tagHandler                  *handler.TagHandler
 	tagRelationHandler          *handler.TagRelationHandler
@@ -247,6 +248,7 @@ func (m *AdminModule) initHandlers() {
 	m.skillCategoryHandler = handler.NewSkillCategoryHandler(m.db, m.respWriter)
 	m.actionCategoryHandler = handler.NewActionCategoryHandler(m.db, m.respWriter)
 	m.damageTypeHandler = handler.NewDamageTypeHandler(m.db, m.respWriter)
+	m.currencyHandler = handler.NewCurrencyHandler(m.db, m.respWriter)
 	m.heroAttributeTypeHandler = handler.NewHeroAttributeTypeHandler(m.db, m.respWriter)
 	m.tagHandler = handler.NewTagHandler(m.db, m.respWriter)
 	m.tagRelationHandler = handler.NewTagRelationHandler(m.db, m.respWriter)
@@ -429,6 +431,15 @@ func (m *AdminModule) setupRoutes() {
 		adminProtected.PUT("/damage-types/:id", m.damageTypeHandler.UpdateDamageType, systemConfig)
 		adminProtected.DELETE("/damage-types/:id", m.damageTypeHandler.DeleteDamageType, systemConfig)
 
+		// 货币管理
+		adminProtected.GET("/currencies", m.currencyHandler.GetCurrencies, systemConfig)
+		adminProtected.POST("/currencies", m.currencyHandler.CreateCurrency, systemConfig)
+		adminProtected.PUT("/currencies/:code", m.currencyHandler.UpdateCurrency, systemConfig)
+		adminProtected.DELETE("/currencies/:code", m.currencyHandler.DeleteCurrency, systemConfig)
+		adminProtected.GET("/currency-rewards/:source_type/:source_id", m.currencyHandler.GetCurrencyRewards, systemConfig)
+		adminProtected.PUT("/currency-rewards/:source_type/:source_id", m.currencyHandler.SetCurrencyRewards, systemConfig)
+		adminProtected.POST("/heroes/:hero_id/currencies/grant", m.currencyHandler.GrantHeroCurrency, userUpdate)
+
 		// 属性类型管理
 		adminProtected.GET("/hero-attribute-types", m.heroAttributeTypeHandler.GetHeroAttributeTypes, systemConfig)
 		adminProtected.POST("/hero-attribute-types", m.heroAttributeTypeHandler.CreateHeroAttributeType, systemConfig)
@@ -522,6 +533,7 @@ func (m *AdminModule) setupRoutes() {
 			metadata.GET("/formula-variables", m.formulaVariableHandler.GetFormulaVariables)
 			metadata.GET("/formula-variables/all", m.formulaVariableHandler.GetAllFormulaVariables)
 			metadata.GET("/formula-variables/:id", m.formulaVariableHandler.GetFormulaVariable)
+			metadata.POST("/formulas/preview", m.formulaVariableHandler.PreviewFormula)
 
 			// 范围配置规则
 			metadata.GET("/range-config-rules", m.rangeConfigRuleHandler.GetRangeConfigRules)
@@ -623,6 +635,9 @@ func (m *AdminModule) setupRoutes() {
 		adminProtected.GET("/dungeons/:id", m.dungeonHandler.GetDungeon, systemConfig)
 		adminProtected.PUT("/dungeons/:id", m.dungeonHandler.UpdateDungeon, systemConfig)
 		adminProtected.DELETE("/dungeons/:id", m.dungeonHandler.DeleteDungeon, systemConfig)
+		adminProtected.GET("/dungeons/:id/schedule", m.dungeonHandler.GetDungeonSchedule, systemConfig)
+		adminProtected.PUT("/dungeons/:id/schedule", m.dungeonHandler.SetDungeonSchedule, systemConfig)
+		adminProtected.DELETE("/dungeons/:id/schedule", m.dungeonHandler.DeleteDungeonSchedule, systemConfig)
 
 		// 地城房间管理
 		adminProtected.GET("/dungeon-rooms", m.dungeonRoomHandler.GetRooms, systemConfig)
@@ -213,3 +213,43 @@ type EventResponse struct {
 	CreatedAt        string            `json:"created_at"`
 	UpdatedAt        string            `json:"updated_at"`
 }
+
+// DungeonScheduleRule 地城开放规则
+type DungeonScheduleRule struct {
+	Type            string `json:"type" example:"weekly"`                  // 规则类型: weekly/monthly/cron
+	Weekdays        []int  `json:"weekdays,omitempty" example:"2,4"`       // 星期几(0=周日...6=周六)，weekly 必填；monthly 配合 weeks 使用
+	Days            []int  `json:"days,omitempty" example:"1,15"`          // 月内日期(1-31，-1=月末)，monthly 使用
+	Weeks           []int  `json:"weeks,omitempty" example:"1"`            // 月内第几个星期几(1-5，-1=最后一个)，monthly 使用
+	StartTime       string `json:"start_time,omitempty" example:"20:00"`   // 开始时间 HH:MM(weekly/monthly)
+	EndTime         string `json:"end_time,omitempty" example:"22:00"`     // 结束时间 HH:MM，不晚于开始时间表示跨天
+	Cron            string `json:"cron,omitempty" example:"0 20 * * 2,4"`  // cron 表达式(分 时 日 月 周)
+	DurationMinutes int    `json:"duration_minutes,omitempty" example:"0"` // cron 每次开放分钟数(1-1440)
+	ValidFrom       string `json:"valid_from,omitempty" example:""`        // 规则生效日期 YYYY-MM-DD
+	ValidUntil      string `json:"valid_until,omitempty" example:""`       // 规则失效日期 YYYY-MM-DD
+}
+
+// SetDungeonScheduleRequest 设置地城开放时间表请求
+type SetDungeonScheduleRequest struct {
+	Timezone      string                `json:"timezone" example:"Asia/Shanghai"`                          // IANA 时区，默认 UTC
+	Rules         []DungeonScheduleRule `json:"rules"`                                                     // 开放规则，至少 1 条
+	BlackoutDates []string              `json:"blackout_dates" example:"2026-02-17,2026-10-01~2026-10-07"` // 停开日期(YYYY-MM-DD 或 YYYY-MM-DD~YYYY-MM-DD)
+	IsActive      *bool                 `json:"is_active" example:"true"`                                  // 是否启用，默认 true
+}
+
+// DungeonScheduleWindow 开放时段
+type DungeonScheduleWindow struct {
+	Start string `json:"start"` // 开始时间(RFC3339，时间表时区)
+	End   string `json:"end"`   // 结束时间(RFC3339)
+}
+
+// DungeonScheduleResponse 地城开放时间表响应
+type DungeonScheduleResponse struct {
+	DungeonID     string                  `json:"dungeon_id"`
+	Timezone      string                  `json:"timezone"`
+	Rules         []DungeonScheduleRule   `json:"rules"`
+	BlackoutDates []string                `json:"blackout_dates"`
+	IsActive      bool                    `json:"is_active"`
+	IsOpenNow     bool                    `json:"is_open_now"` // 当前是否在开放时段
+	Upcoming      []DungeonScheduleWindow `json:"upcoming"`    // 接下来的开放时段(含正在进行的)
+	UpdatedAt     string                  `json:"updated_at"`
+}
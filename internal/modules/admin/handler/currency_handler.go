@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+
+	"tsu-self/internal/modules/admin/service"
+	"tsu-self/internal/pkg/response"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// CurrencyHandler 货币管理 HTTP 处理器
+type CurrencyHandler struct {
+	service    *service.CurrencyService
+	respWriter response.Writer
+}
+
+// NewCurrencyHandler 创建货币管理处理器
+func NewCurrencyHandler(db *sql.DB, respWriter response.Writer) *CurrencyHandler {
+	return &CurrencyHandler{
+		service:    service.NewCurrencyService(db),
+		respWriter: respWriter,
+	}
+}
+
+// ==================== HTTP Models ====================
+
+// CreateCurrencyRequest 创建货币请求
+type CreateCurrencyRequest struct {
+	Code        string  `json:"currency_code" validate:"required,max=32" example:"arena_token"` // 货币代码(必需)
+	Name        string  `json:"currency_name" validate:"required,max=64" example:"竞技场代币"`       // 货币名称(必需)
+	Description *string `json:"description" example:"竞技场兑换用代币"`                                 // 描述(可选)
+	IsActive    *bool   `json:"is_active" example:"true"`                                       // 是否启用(可选，默认启用)
+}
+
+// UpdateCurrencyRequest 更新货币请求
+type UpdateCurrencyRequest struct {
+	Name        *string `json:"currency_name" validate:"omitempty,max=64"`
+	Description *string `json:"description"`
+	IsActive    *bool   `json:"is_active"`
+}
+
+// CurrencyInfo 货币信息响应
+type CurrencyInfo struct {
+	Code        string `json:"currency_code" example:"honor"` // 货币代码
+	Name        string `json:"currency_name" example:"荣誉"`    // 货币名称
+	Description string `json:"description" example:"职业进阶消耗"`  // 描述
+	IsSystem    bool   `json:"is_system" example:"true"`      // 是否系统货币
+	IsActive    bool   `json:"is_active" example:"true"`      // 是否启用
+	CreatedAt   int64  `json:"created_at" example:"1633024800"`
+	UpdatedAt   int64  `json:"updated_at" example:"1633024800"`
+}
+
+// CurrencyRewardItem 货币奖励配置项
+type CurrencyRewardItem struct {
+	CurrencyCode string `json:"currency_code" validate:"required" example:"honor"` // 货币代码
+	Amount       int64  `json:"amount" validate:"required,gt=0" example:"10"`      // 每名成员所得数量
+}
+
+// SetCurrencyRewardsRequest 覆盖货币奖励配置请求
+type SetCurrencyRewardsRequest struct {
+	Rewards []CurrencyRewardItem `json:"rewards" validate:"dive"`
+}
+
+// GrantHeroCurrencyRequest 发放英雄货币请求
+type GrantHeroCurrencyRequest struct {
+	CurrencyCode string `json:"currency_code" validate:"required" example:"honor"` // 货币代码
+	Amount       int64  `json:"amount" validate:"required" example:"100"`          // 正数发放，负数扣除
+	Note         string `json:"note" validate:"max=255" example:"活动补偿"`            // 备注
+}
+
+// ==================== HTTP Handlers ====================
+
+// GetCurrencies 获取货币列表
+// @Summary 获取货币列表
+// @Description 获取全部货币定义（含系统货币与已停用货币）
+// @Tags 货币
+// @Produce json
+// @Success 200 {object} response.Response{data=[]CurrencyInfo} "成功返回货币列表"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /admin/currencies [get]
+func (h *CurrencyHandler) GetCurrencies(c echo.Context) error {
+	list, err := h.service.ListCurrencies(c.Request().Context())
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	result := make([]CurrencyInfo, len(list))
+	for i, currency := range list {
+		result[i] = convertToCurrencyInfo(currency)
+	}
+	return response.EchoOK(c, h.respWriter, result)
+}
+
+// CreateCurrency 创建货币
+// @Summary 创建货币
+// @Description 创建自定义货币，货币代码必须唯一
+// @Tags 货币
+// @Accept json
+// @Produce json
+// @Param request body CreateCurrencyRequest true "创建货币请求"
+// @Success 200 {object} response.Response{data=CurrencyInfo} "成功返回创建的货币"
+// @Failure 400 {object} response.Response "请求参数错误或货币代码已存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /admin/currencies [post]
+func (h *CurrencyHandler) CreateCurrency(c echo.Context) error {
+	var req CreateCurrencyRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	currency := &interfaces.Currency{
+		Code:     req.Code,
+		Name:     req.Name,
+		IsActive: true,
+	}
+	if req.Description != nil && *req.Description != "" {
+		currency.Description = req.Description
+	}
+	if req.IsActive != nil {
+		currency.IsActive = *req.IsActive
+	}
+
+	if err := h.service.CreateCurrency(c.Request().Context(), currency); err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, convertToCurrencyInfo(currency))
+}
+
+// UpdateCurrency 更新货币
+// @Summary 更新货币
+// @Description 更新货币名称、描述与启用状态，系统货币不可停用
+// @Tags 货币
+// @Accept json
+// @Produce json
+// @Param code path string true "货币代码"
+// @Param request body UpdateCurrencyRequest true "更新货币请求"
+// @Success 200 {object} response.Response{data=CurrencyInfo} "成功返回更新后的货币"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 404 {object} response.Response "货币不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /admin/currencies/{code} [put]
+func (h *CurrencyHandler) UpdateCurrency(c echo.Context) error {
+	var req UpdateCurrencyRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	currency, err := h.service.UpdateCurrency(c.Request().Context(), c.Param("code"), req.Name, req.Description, req.IsActive)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, convertToCurrencyInfo(currency))
+}
+
+// DeleteCurrency 删除货币
+// @Summary 删除货币
+// @Description 删除自定义货币，系统货币不可删除
+// @Tags 货币
+// @Produce json
+// @Param code path string true "货币代码"
+// @Success 200 {object} response.Response "删除成功"
+// @Failure 404 {object} response.Response "货币不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /admin/currencies/{code} [delete]
+func (h *CurrencyHandler) DeleteCurrency(c echo.Context) error {
+	if err := h.service.DeleteCurrency(c.Request().Context(), c.Param("code")); err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, map[string]interface{}{
+		"message": "删除成功",
+	})
+}
+
+// GetCurrencyRewards 获取货币奖励配置
+// @Summary 获取货币奖励配置
+// @Description 获取地下城通关或事件的货币奖励（每名成员所得）
+// @Tags 货币
+// @Produce json
+// @Param source_type path string true "来源类型(dungeon/dungeon_event)"
+// @Param source_id path string true "来源ID"
+// @Success 200 {object} response.Response{data=[]CurrencyRewardItem} "成功返回奖励配置"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /admin/currency-rewards/{source_type}/{source_id} [get]
+func (h *CurrencyHandler) GetCurrencyRewards(c echo.Context) error {
+	rewards, err := h.service.GetRewards(c.Request().Context(), c.Param("source_type"), c.Param("source_id"))
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	result := make([]CurrencyRewardItem, len(rewards))
+	for i, reward := range rewards {
+		result[i] = CurrencyRewardItem{CurrencyCode: reward.CurrencyCode, Amount: reward.Amount}
+	}
+	return response.EchoOK(c, h.respWriter, result)
+}
+
+// SetCurrencyRewards 覆盖货币奖励配置
+// @Summary 覆盖货币奖励配置
+// @Description 整体替换地下城通关或事件的货币奖励，传入空列表表示清空
+// @Tags 货币
+// @Accept json
+// @Produce json
+// @Param source_type path string true "来源类型(dungeon/dungeon_event)"
+// @Param source_id path string true "来源ID"
+// @Param request body SetCurrencyRewardsRequest true "奖励配置"
+// @Success 200 {object} response.Response "保存成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /admin/currency-rewards/{source_type}/{source_id} [put]
+func (h *CurrencyHandler) SetCurrencyRewards(c echo.Context) error {
+	var req SetCurrencyRewardsRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	rewards := make([]*interfaces.CurrencyReward, len(req.Rewards))
+	for i, item := range req.Rewards {
+		rewards[i] = &interfaces.CurrencyReward{CurrencyCode: item.CurrencyCode, Amount: item.Amount}
+	}
+
+	if err := h.service.SetRewards(c.Request().Context(), c.Param("source_type"), c.Param("source_id"), rewards); err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, map[string]interface{}{
+		"message": "保存成功",
+	})
+}
+
+// GrantHeroCurrency 发放英雄货币
+// @Summary 发放/扣除英雄货币
+// @Description 管理员为英雄发放（正数）或扣除（负数）货币，写入货币流水，余额不足时扣除失败
+// @Tags 货币
+// @Accept json
+// @Produce json
+// @Param hero_id path string true "英雄ID"
+// @Param request body GrantHeroCurrencyRequest true "发放请求"
+// @Success 200 {object} response.Response{data=object{balance_after=int}} "成功返回变动后余额"
+// @Failure 400 {object} response.Response "请求参数错误或余额不足"
+// @Failure 404 {object} response.Response "货币不存在"
+// @Router /admin/heroes/{hero_id}/currencies/grant [post]
+func (h *CurrencyHandler) GrantHeroCurrency(c echo.Context) error {
+	var req GrantHeroCurrencyRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	operatorID, _ := c.Get("user_id").(string)
+	txn, err := h.service.GrantToHero(c.Request().Context(), c.Param("hero_id"), req.CurrencyCode, req.Amount, req.Note, operatorID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, map[string]interface{}{
+		"transaction_id": txn.ID,
+		"balance_after":  txn.BalanceAfter,
+	})
+}
+
+func convertToCurrencyInfo(currency *interfaces.Currency) CurrencyInfo {
+	info := CurrencyInfo{
+		Code:      currency.Code,
+		Name:      currency.Name,
+		IsSystem:  currency.IsSystem,
+		IsActive:  currency.IsActive,
+		CreatedAt: currency.CreatedAt.Unix(),
+		UpdatedAt: currency.UpdatedAt.Unix(),
+	}
+	if currency.Description != nil {
+		info.Description = *currency.Description
+	}
+	return info
+}
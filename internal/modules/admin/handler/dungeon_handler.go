@@ -293,6 +293,84 @@ func (h *DungeonHandler) DeleteDungeon(c echo.Context) error {
 	return response.EchoOK(c, h.respWriter, map[string]string{"message": "删除成功"})
 }
 
+// GetDungeonSchedule 获取地城开放时间表
+// @Summary 获取地城开放时间表
+// @Description 获取地城的周期性开放时间表,并返回当前是否开放以及接下来的 5 个开放时段。
+// @Tags 地城管理
+// @Accept json
+// @Produce json
+// @Param id path string true "地城ID"
+// @Success 200 {object} response.Response{data=dto.DungeonScheduleResponse} "查询成功"
+// @Failure 404 {object} response.Response "地城不存在或未配置时间表"
+// @Failure 500 {object} response.Response "服务器错误"
+// @Router /admin/dungeons/{id}/schedule [get]
+func (h *DungeonHandler) GetDungeonSchedule(c echo.Context) error {
+	resp, err := h.service.GetSchedule(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, resp)
+}
+
+// SetDungeonSchedule 设置地城开放时间表
+// @Summary 设置地城开放时间表
+// @Description 创建或覆盖地城的周期性开放时间表。与 `is_time_limited` 的绝对时间范围同时生效,两者都满足时地城才开放。
+// @Description
+// @Description **规则类型**:
+// @Description - `weekly`: `weekdays` + `start_time`/`end_time`,例如周二/周四 20:00-22:00
+// @Description - `monthly`: `days`(1-31,-1=月末) 或 `weeks`+`weekdays`(如每月第一个周六) + `start_time`/`end_time`
+// @Description - `cron`: `cron`(分 时 日 月 周) + `duration_minutes`
+// @Description - 任意规则可加 `valid_from`/`valid_until` 限定生效日期; `end_time` 不晚于 `start_time` 表示跨天
+// @Description - `blackout_dates`: 停开日期,支持单日 `2026-02-17` 或区间 `2026-10-01~2026-10-07`
+// @Description
+// @Description **请求示例**:
+// @Description ```json
+// @Description {
+// @Description   "timezone": "Asia/Shanghai",
+// @Description   "rules": [{"type": "weekly", "weekdays": [2, 4], "start_time": "20:00", "end_time": "22:00"}],
+// @Description   "blackout_dates": ["2026-10-01~2026-10-07"]
+// @Description }
+// @Description ```
+// @Tags 地城管理
+// @Accept json
+// @Produce json
+// @Param id path string true "地城ID"
+// @Param request body dto.SetDungeonScheduleRequest true "开放时间表"
+// @Success 200 {object} response.Response{data=dto.DungeonScheduleResponse} "保存成功"
+// @Failure 400 {object} response.Response "时间表无效"
+// @Failure 404 {object} response.Response "地城不存在"
+// @Failure 500 {object} response.Response "服务器错误"
+// @Router /admin/dungeons/{id}/schedule [put]
+func (h *DungeonHandler) SetDungeonSchedule(c echo.Context) error {
+	var req dto.SetDungeonScheduleRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求参数格式错误")
+	}
+
+	resp, err := h.service.SetSchedule(c.Request().Context(), c.Param("id"), &req)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, resp)
+}
+
+// DeleteDungeonSchedule 删除地城开放时间表
+// @Summary 删除地城开放时间表
+// @Description 删除后地城不再受周期性开放限制(仍受 `is_time_limited` 约束)。
+// @Tags 地城管理
+// @Accept json
+// @Produce json
+// @Param id path string true "地城ID"
+// @Success 200 {object} response.Response "删除成功"
+// @Failure 500 {object} response.Response "服务器错误"
+// @Router /admin/dungeons/{id}/schedule [delete]
+func (h *DungeonHandler) DeleteDungeonSchedule(c echo.Context) error {
+	if err := h.service.DeleteSchedule(c.Request().Context(), c.Param("id")); err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, map[string]string{"message": "删除成功"})
+}
+
 // toDungeonResponse 转换为响应格式
 func (h *DungeonHandler) toDungeonResponse(dungeon *game_config.Dungeon) dto.DungeonResponse {
 	resp := dto.DungeonResponse{
@@ -35,7 +35,7 @@ func NewDungeonRoomHandler(db *sql.DB, respWriter response.Writer) *DungeonRoomH
 // @Description **字段说明**:
 // @Description - `room_code/room_name`: 房间唯一标识 + 展示名称
 // @Description - `room_type`: `battle`(战斗) / `event`(剧情或互动) / `treasure`(奖励) / `rest`(休息/补给)
-// @Description - `trigger_id`: 可选,关联策划脚本或触发器
+// @Description - `trigger_id`: 可选,关联策划脚本或触发器;`treasure` 房间必填,指向配置宝箱掉落与奖励的地城事件
 // @Description - `open_conditions`: JSON对象,定义进入条件,例如 `{"type":"require_item","item_code":"key_ancient"}` 或 `{"type":"check_flag","flag":"cleared_room_1"}`
 // @Description - `is_active`: 控制房间是否可被引用
 // @Description
@@ -54,7 +54,7 @@ type UpdateEffectRequest struct {
 	EffectName         string  `json:"effect_name" example:"火焰伤害"`                             // 效果名称
 	EffectType         string  `json:"effect_type" example:"damage"`                           // 效果类型(damage/heal/buff/debuff等)
 	Parameters         string  `json:"parameters" example:"{\"damage_type\":\"fire\"}"`        // 效果参数JSON
-	CalculationFormula string  `json:"calculation_formula" example:"2d6 + @INT"`               // 计算公式
+	CalculationFormula *string `json:"calculation_formula,omitempty" example:"2d6 + @INT"`     // 计算公式（不传则不修改，传空字符串清除）
 	TriggerCondition   string  `json:"trigger_condition" example:"{\"condition\":\"on_hit\"}"` // 触发条件JSON
 	TriggerChance      float64 `json:"trigger_chance" example:"0.85"`                          // 触发概率(0-1)
 	TargetFilter       string  `json:"target_filter" example:"{\"type\":\"enemy\"}"`           // 目标筛选JSON
@@ -310,6 +310,9 @@ func (h *EffectHandler) UpdateEffect(c echo.Context) error {
 	if req.EffectType != "" {
 		updates["effect_type"] = req.EffectType
 	}
+	if req.CalculationFormula != nil {
+		updates["calculation_formula"] = *req.CalculationFormula
+	}
 	updates["description"] = req.Description
 	updates["tooltip_template"] = req.TooltipTemplate
 	updates["is_active"] = req.IsActive
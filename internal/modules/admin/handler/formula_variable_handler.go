@@ -121,6 +121,59 @@ func (h *FormulaVariableHandler) GetAllFormulaVariables(c echo.Context) error {
 	return response.EchoOK(c, h.respWriter, result)
 }
 
+// PreviewFormulaRequest 公式预览请求
+type PreviewFormulaRequest struct {
+	Formula           string             `json:"formula" validate:"required" example:"2d6 + @INT * skill_level"` // 待预览公式
+	HeroID            string             `json:"hero_id" example:"550e8400-e29b-41d4-a716-446655440000"`         // 示例英雄ID（与 monster_code 二选一）
+	MonsterCode       string             `json:"monster_code" example:"GOBLIN"`                                  // 示例怪物代码
+	TargetHeroID      string             `json:"target_hero_id"`                                                 // 目标英雄ID（可选，对应 target.* 变量）
+	TargetMonsterCode string             `json:"target_monster_code" example:"GOBLIN"`                           // 目标怪物代码（可选）
+	SkillLevel        int                `json:"skill_level" example:"1"`                                        // 技能等级，默认 1
+	Variables         map[string]float64 `json:"variables"`                                                      // 覆盖的变量值
+	Seed              int64              `json:"seed" example:"1"`                                               // 掷骰种子
+}
+
+// PreviewFormula 预览公式计算结果
+// @Summary 预览公式计算结果
+// @Description 校验公式语法与变量(公式变量、英雄属性代码、内置变量),并以示例英雄或怪物的属性求值。返回按种子掷骰的结果以及骰子取极值时的范围。
+// @Tags 元数据
+// @Accept json
+// @Produce json
+// @Param request body PreviewFormulaRequest true "公式预览请求"
+// @Success 200 {object} response.Response{data=service.FormulaPreviewResult} "预览结果"
+// @Failure 400 {object} response.Response "公式无效或参数错误"
+// @Failure 404 {object} response.Response "英雄或怪物不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /admin/metadata/formulas/preview [post]
+// @Security BearerAuth
+func (h *FormulaVariableHandler) PreviewFormula(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req PreviewFormulaRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "参数错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	result, err := h.service.PreviewFormula(ctx, &service.FormulaPreviewRequest{
+		Formula:           req.Formula,
+		HeroID:            req.HeroID,
+		MonsterCode:       req.MonsterCode,
+		TargetHeroID:      req.TargetHeroID,
+		TargetMonsterCode: req.TargetMonsterCode,
+		SkillLevel:        req.SkillLevel,
+		Variables:         req.Variables,
+		Seed:              req.Seed,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	return response.EchoOK(c, h.respWriter, result)
+}
+
 func (h *FormulaVariableHandler) convertToInfo(v *game_config.MetadataDictionary) FormulaVariableInfo {
 	info := FormulaVariableInfo{
 		ID:           v.ID,
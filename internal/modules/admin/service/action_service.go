@@ -16,13 +16,15 @@ import (
 
 // ActionService 动作服务
 type ActionService struct {
-	repo interfaces.ActionRepository
+	repo             interfaces.ActionRepository
+	formulaValidator *FormulaVariableService
 }
 
 // NewActionService 创建动作服务
 func NewActionService(db *sql.DB) *ActionService {
 	return &ActionService{
-		repo: impl.NewActionRepository(db),
+		repo:             impl.NewActionRepository(db),
+		formulaValidator: NewFormulaVariableService(db),
 	}
 }
 
@@ -47,6 +49,13 @@ func (s *ActionService) CreateAction(ctx context.Context, action *game_config.Ac
 		return xerrors.New(xerrors.CodeDuplicateResource, fmt.Sprintf("动作代码已存在: %s", action.ActionCode))
 	}
 
+	// 业务验证：法力消耗公式
+	if action.ManaCostFormula.Valid {
+		if err := s.formulaValidator.ValidateFormula(ctx, action.ManaCostFormula.String); err != nil {
+			return err
+		}
+	}
+
 	return s.repo.Create(ctx, action)
 }
 
@@ -112,6 +121,9 @@ func (s *ActionService) UpdateAction(ctx context.Context, actionID string, updat
 
 	if manaCostFormula, ok := updates["mana_cost_formula"].(string); ok {
 		if manaCostFormula != "" {
+			if err := s.formulaValidator.ValidateFormula(ctx, manaCostFormula); err != nil {
+				return err
+			}
 			action.ManaCostFormula.SetValid(manaCostFormula)
 		} else {
 			action.ManaCostFormula.Valid = false
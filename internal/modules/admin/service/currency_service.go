@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/impl"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// currencyAdminGrantSource 管理员发放/扣除的流水来源，与游戏服保持一致
+const currencyAdminGrantSource = "admin_grant"
+
+var currencyCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,31}$`)
+
+// CurrencyService 货币管理服务
+type CurrencyService struct {
+	db               *sql.DB
+	repo             interfaces.CurrencyRepository
+	heroCurrencyRepo interfaces.HeroCurrencyRepository
+}
+
+// NewCurrencyService 创建货币管理服务
+func NewCurrencyService(db *sql.DB) *CurrencyService {
+	return &CurrencyService{
+		db:               db,
+		repo:             impl.NewCurrencyRepository(db),
+		heroCurrencyRepo: impl.NewHeroCurrencyRepository(db),
+	}
+}
+
+// ListCurrencies 获取货币列表（含停用货币）
+func (s *CurrencyService) ListCurrencies(ctx context.Context) ([]*interfaces.Currency, error) {
+	list, err := s.repo.List(ctx, true)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询货币列表失败")
+	}
+	return list, nil
+}
+
+// CreateCurrency 创建自定义货币
+func (s *CurrencyService) CreateCurrency(ctx context.Context, currency *interfaces.Currency) error {
+	if !currencyCodePattern.MatchString(currency.Code) {
+		return xerrors.New(xerrors.CodeInvalidParams, "货币代码只能包含小写字母、数字和下划线，且以字母开头（2-32 字符）")
+	}
+	if currency.Name == "" {
+		return xerrors.New(xerrors.CodeInvalidParams, "货币名称不能为空")
+	}
+
+	existing, err := s.repo.GetByCode(ctx, currency.Code)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "查询货币失败")
+	}
+	if existing != nil {
+		return xerrors.New(xerrors.CodeDuplicateResource, fmt.Sprintf("货币代码已存在: %s", currency.Code))
+	}
+
+	if err := s.repo.Create(ctx, currency); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "创建货币失败")
+	}
+	return nil
+}
+
+// UpdateCurrency 更新货币名称、描述与启用状态，系统货币不可停用
+func (s *CurrencyService) UpdateCurrency(ctx context.Context, code string, name, description *string, isActive *bool) (*interfaces.Currency, error) {
+	currency, err := s.getCurrency(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != nil {
+		if *name == "" {
+			return nil, xerrors.New(xerrors.CodeInvalidParams, "货币名称不能为空")
+		}
+		currency.Name = *name
+	}
+	if description != nil {
+		if *description == "" {
+			currency.Description = nil
+		} else {
+			currency.Description = description
+		}
+	}
+	if isActive != nil {
+		if currency.IsSystem && !*isActive {
+			return nil, xerrors.New(xerrors.CodeOperationNotAllowed, "系统货币不能停用")
+		}
+		currency.IsActive = *isActive
+	}
+
+	if err := s.repo.Update(ctx, currency); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新货币失败")
+	}
+	return currency, nil
+}
+
+// DeleteCurrency 删除自定义货币，系统货币不可删除
+func (s *CurrencyService) DeleteCurrency(ctx context.Context, code string) error {
+	currency, err := s.getCurrency(ctx, code)
+	if err != nil {
+		return err
+	}
+	if currency.IsSystem {
+		return xerrors.New(xerrors.CodeOperationNotAllowed, "系统货币不能删除")
+	}
+	if err := s.repo.Delete(ctx, code); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "删除货币失败（可能已有英雄持有该货币，请改为停用）")
+	}
+	return nil
+}
+
+// GetRewards 获取某来源（地下城/事件）的货币奖励配置
+func (s *CurrencyService) GetRewards(ctx context.Context, sourceType, sourceID string) ([]*interfaces.CurrencyReward, error) {
+	if err := validateCurrencyRewardSource(sourceType); err != nil {
+		return nil, err
+	}
+	rewards, err := s.repo.ListRewards(ctx, sourceType, sourceID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询货币奖励失败")
+	}
+	return rewards, nil
+}
+
+// SetRewards 覆盖某来源的货币奖励配置，传入空列表表示清空
+func (s *CurrencyService) SetRewards(ctx context.Context, sourceType, sourceID string, rewards []*interfaces.CurrencyReward) error {
+	if err := validateCurrencyRewardSource(sourceType); err != nil {
+		return err
+	}
+
+	seen := make(map[string]struct{}, len(rewards))
+	for _, reward := range rewards {
+		if reward.Amount <= 0 {
+			return xerrors.New(xerrors.CodeInvalidParams, "奖励数量必须大于 0")
+		}
+		if _, ok := seen[reward.CurrencyCode]; ok {
+			return xerrors.New(xerrors.CodeInvalidParams, fmt.Sprintf("货币重复配置: %s", reward.CurrencyCode))
+		}
+		seen[reward.CurrencyCode] = struct{}{}
+
+		currency, err := s.repo.GetByCode(ctx, reward.CurrencyCode)
+		if err != nil {
+			return xerrors.Wrap(err, xerrors.CodeInternalError, "查询货币失败")
+		}
+		if currency == nil {
+			return xerrors.New(xerrors.CodeInvalidParams, fmt.Sprintf("货币不存在: %s", reward.CurrencyCode))
+		}
+	}
+
+	if err := s.repo.ReplaceRewards(ctx, sourceType, sourceID, rewards); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "保存货币奖励失败")
+	}
+	return nil
+}
+
+// GrantToHero 管理员为英雄发放（正数）或扣除（负数）货币，写入流水
+func (s *CurrencyService) GrantToHero(ctx context.Context, heroID, code string, amount int64, note, operatorID string) (*interfaces.CurrencyTransaction, error) {
+	if amount == 0 {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "变动数量不能为 0")
+	}
+	currency, err := s.getCurrency(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if !currency.IsActive {
+		return nil, xerrors.New(xerrors.CodeOperationNotAllowed, "货币已停用")
+	}
+
+	txn := &interfaces.CurrencyTransaction{
+		HeroID:       heroID,
+		CurrencyCode: code,
+		Amount:       amount,
+		SourceType:   currencyAdminGrantSource,
+	}
+	if note != "" {
+		txn.Note = &note
+	}
+	if operatorID != "" {
+		txn.SourceID = &operatorID
+		raw, _ := json.Marshal(map[string]string{"operator_id": operatorID})
+		txn.Metadata = raw
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer tx.Rollback()
+
+	if err := s.heroCurrencyRepo.ApplyTx(ctx, tx, txn); err != nil {
+		if errors.Is(err, interfaces.ErrInsufficientCurrency) {
+			return nil, xerrors.New(xerrors.CodeInsufficientResource, fmt.Sprintf("%s余额不足", currency.Name))
+		}
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "发放货币失败")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	return txn, nil
+}
+
+func (s *CurrencyService) getCurrency(ctx context.Context, code string) (*interfaces.Currency, error) {
+	currency, err := s.repo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询货币失败")
+	}
+	if currency == nil {
+		return nil, xerrors.New(xerrors.CodeResourceNotFound, fmt.Sprintf("货币不存在: %s", code))
+	}
+	return currency, nil
+}
+
+func validateCurrencyRewardSource(sourceType string) error {
+	switch sourceType {
+	case interfaces.CurrencyRewardDungeon, interfaces.CurrencyRewardEvent:
+		return nil
+	default:
+		return xerrors.New(xerrors.CodeInvalidParams, fmt.Sprintf("不支持的奖励来源: %s", sourceType))
+	}
+}
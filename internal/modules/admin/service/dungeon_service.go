@@ -12,6 +12,7 @@ import (
 
 	"tsu-self/internal/entity/game_config"
 	"tsu-self/internal/modules/admin/dto"
+	"tsu-self/internal/pkg/schedule"
 	"tsu-self/internal/pkg/xerrors"
 	"tsu-self/internal/repository/impl"
 	"tsu-self/internal/repository/interfaces"
@@ -21,6 +22,7 @@ import (
 type DungeonService struct {
 	dungeonRepo     interfaces.DungeonRepository
 	dungeonRoomRepo interfaces.DungeonRoomRepository
+	scheduleRepo    interfaces.DungeonScheduleRepository
 	db              *sql.DB
 }
 
@@ -29,6 +31,7 @@ type DungeonServiceDeps struct {
 	DB              *sql.DB
 	DungeonRepo     interfaces.DungeonRepository
 	DungeonRoomRepo interfaces.DungeonRoomRepository
+	ScheduleRepo    interfaces.DungeonScheduleRepository
 }
 
 // NewDungeonService 创建地城服务
@@ -43,6 +46,7 @@ func NewDungeonServiceWithDeps(deps DungeonServiceDeps) *DungeonService {
 	svc := &DungeonService{
 		dungeonRepo:     deps.DungeonRepo,
 		dungeonRoomRepo: deps.DungeonRoomRepo,
+		scheduleRepo:    deps.ScheduleRepo,
 		db:              deps.DB,
 	}
 	if svc.dungeonRepo == nil {
@@ -51,6 +55,9 @@ func NewDungeonServiceWithDeps(deps DungeonServiceDeps) *DungeonService {
 	if svc.dungeonRoomRepo == nil {
 		svc.dungeonRoomRepo = impl.NewDungeonRoomRepository(svc.db)
 	}
+	if svc.scheduleRepo == nil {
+		svc.scheduleRepo = impl.NewDungeonScheduleRepository(svc.db)
+	}
 	return svc
 }
 
@@ -263,6 +270,93 @@ func (s *DungeonService) DeleteDungeon(ctx context.Context, dungeonID string) er
 	return s.dungeonRepo.Delete(ctx, dungeonID)
 }
 
+// dungeonScheduleUpcoming 时间表响应中展示的后续开放时段数量
+const dungeonScheduleUpcoming = 5
+
+// GetSchedule 获取地城开放时间表，未配置时返回资源不存在
+func (s *DungeonService) GetSchedule(ctx context.Context, dungeonID string) (*dto.DungeonScheduleResponse, error) {
+	if _, err := s.dungeonRepo.GetByID(ctx, dungeonID); err != nil {
+		return nil, err
+	}
+	record, err := s.scheduleRepo.GetByDungeonID(ctx, dungeonID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询地城开放时间表失败")
+	}
+	if record == nil {
+		return nil, xerrors.New(xerrors.CodeResourceNotFound, "地城未配置开放时间表")
+	}
+	return toDungeonScheduleResponse(record, time.Now())
+}
+
+// SetSchedule 创建或覆盖地城开放时间表
+func (s *DungeonService) SetSchedule(ctx context.Context, dungeonID string, req *dto.SetDungeonScheduleRequest) (*dto.DungeonScheduleResponse, error) {
+	if _, err := s.dungeonRepo.GetByID(ctx, dungeonID); err != nil {
+		return nil, err
+	}
+
+	sched := schedule.Schedule{
+		Timezone:      req.Timezone,
+		BlackoutDates: req.BlackoutDates,
+	}
+	if sched.Timezone == "" {
+		sched.Timezone = "UTC"
+	}
+	for _, rule := range req.Rules {
+		sched.Rules = append(sched.Rules, schedule.Rule(rule))
+	}
+	if err := sched.Validate(); err != nil {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, fmt.Sprintf("开放时间表无效: %v", err))
+	}
+
+	record := &interfaces.DungeonSchedule{
+		DungeonID: dungeonID,
+		Schedule:  sched,
+		IsActive:  req.IsActive == nil || *req.IsActive,
+	}
+	if err := s.scheduleRepo.Upsert(ctx, record); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "保存地城开放时间表失败")
+	}
+	return toDungeonScheduleResponse(record, time.Now())
+}
+
+// DeleteSchedule 删除地城开放时间表（恢复为不受周期限制）
+func (s *DungeonService) DeleteSchedule(ctx context.Context, dungeonID string) error {
+	if err := s.scheduleRepo.Delete(ctx, dungeonID); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "删除地城开放时间表失败")
+	}
+	return nil
+}
+
+func toDungeonScheduleResponse(record *interfaces.DungeonSchedule, now time.Time) (*dto.DungeonScheduleResponse, error) {
+	compiled, err := schedule.Compile(record.Schedule)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, "地城开放时间表配置无效")
+	}
+
+	resp := &dto.DungeonScheduleResponse{
+		DungeonID:     record.DungeonID,
+		Timezone:      record.Schedule.Timezone,
+		Rules:         make([]dto.DungeonScheduleRule, len(record.Schedule.Rules)),
+		BlackoutDates: record.Schedule.BlackoutDates,
+		IsActive:      record.IsActive,
+		IsOpenNow:     compiled.IsOpen(now),
+		UpdatedAt:     record.UpdatedAt.Format(time.RFC3339),
+	}
+	if resp.BlackoutDates == nil {
+		resp.BlackoutDates = []string{}
+	}
+	for i, rule := range record.Schedule.Rules {
+		resp.Rules[i] = dto.DungeonScheduleRule(rule)
+	}
+	for _, w := range compiled.Upcoming(now, dungeonScheduleUpcoming) {
+		resp.Upcoming = append(resp.Upcoming, dto.DungeonScheduleWindow{
+			Start: w.Start.In(compiled.Location()).Format(time.RFC3339),
+			End:   w.End.In(compiled.Location()).Format(time.RFC3339),
+		})
+	}
+	return resp, nil
+}
+
 // validateRoomSequence 验证房间序列
 func (s *DungeonService) validateRoomSequence(ctx context.Context, sequence []dto.RoomSequenceItem) error {
 	if len(sequence) == 0 {
@@ -13,13 +13,15 @@ import (
 
 // EffectService 效果服务
 type EffectService struct {
-	repo interfaces.EffectRepository
+	repo             interfaces.EffectRepository
+	formulaValidator *FormulaVariableService
 }
 
 // NewEffectService 创建效果服务
 func NewEffectService(db *sql.DB) *EffectService {
 	return &EffectService{
-		repo: impl.NewEffectRepository(db),
+		repo:             impl.NewEffectRepository(db),
+		formulaValidator: NewFormulaVariableService(db),
 	}
 }
 
@@ -44,6 +46,13 @@ func (s *EffectService) CreateEffect(ctx context.Context, effect *game_config.Ef
 		return xerrors.New(xerrors.CodeDuplicateResource, fmt.Sprintf("效果代码已存在: %s", effect.EffectCode))
 	}
 
+	// 业务验证：计算公式
+	if effect.CalculationFormula.Valid {
+		if err := s.formulaValidator.ValidateFormula(ctx, effect.CalculationFormula.String); err != nil {
+			return err
+		}
+	}
+
 	return s.repo.Create(ctx, effect)
 }
 
@@ -71,6 +80,17 @@ func (s *EffectService) UpdateEffect(ctx context.Context, effectID string, updat
 		effect.EffectType = effectType
 	}
 
+	if calculationFormula, ok := updates["calculation_formula"].(string); ok {
+		if calculationFormula != "" {
+			if err := s.formulaValidator.ValidateFormula(ctx, calculationFormula); err != nil {
+				return err
+			}
+			effect.CalculationFormula.SetValid(calculationFormula)
+		} else {
+			effect.CalculationFormula.Valid = false
+		}
+	}
+
 	if description, ok := updates["description"].(string); ok {
 		if description != "" {
 			effect.Description.SetValid(description)
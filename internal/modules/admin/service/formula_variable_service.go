@@ -3,20 +3,43 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aarondl/null/v8"
 
 	"tsu-self/internal/entity/game_config"
+	gameservice "tsu-self/internal/modules/game/service"
+	"tsu-self/internal/pkg/formula"
+	"tsu-self/internal/pkg/xerrors"
 	"tsu-self/internal/repository/impl"
 	"tsu-self/internal/repository/interfaces"
 )
 
+// heroAttributeReader 英雄最终属性来源，与游戏服共用同一套属性计算管线
+type heroAttributeReader interface {
+	GetComputedAttributes(ctx context.Context, heroID string) ([]*gameservice.ComputedAttribute, error)
+}
+
 // FormulaVariableService 公式变量服务（现在使用通用字典表）
 type FormulaVariableService struct {
-	dictRepo interfaces.MetadataDictionaryRepository
+	db                *sql.DB
+	dictRepo          interfaces.MetadataDictionaryRepository
+	attributeTypeRepo interfaces.HeroAttributeTypeRepository
+	heroRepo          interfaces.HeroRepository
+	monsterRepo       interfaces.MonsterRepository
+	heroAttributes    heroAttributeReader
 }
 
 func NewFormulaVariableService(db *sql.DB) *FormulaVariableService {
 	return &FormulaVariableService{
-		dictRepo: impl.NewMetadataDictionaryRepository(db),
+		db:                db,
+		dictRepo:          impl.NewMetadataDictionaryRepository(db),
+		attributeTypeRepo: impl.NewHeroAttributeTypeRepository(db),
+		heroRepo:          impl.NewHeroRepository(db),
+		monsterRepo:       impl.NewMonsterRepository(db),
+		heroAttributes:    gameservice.NewHeroAttributeService(db),
 	}
 }
 
@@ -29,3 +52,269 @@ func (s *FormulaVariableService) GetAll(ctx context.Context) ([]*game_config.Met
 func (s *FormulaVariableService) GetByID(ctx context.Context, id string) (*game_config.MetadataDictionary, error) {
 	return s.dictRepo.GetByID(ctx, id)
 }
+
+// Schema 公式可用变量表：内置变量 + 启用的公式变量 + 启用的英雄属性代码
+func (s *FormulaVariableService) Schema(ctx context.Context) (formula.Schema, error) {
+	schema := formula.BuiltinVariables()
+
+	vars, err := s.dictRepo.GetFormulaVariables(ctx)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询公式变量失败")
+	}
+	for _, v := range vars {
+		if v.IsActive.Valid && !v.IsActive.Bool {
+			continue
+		}
+		schema.Add(v.VariableCode, formula.TypeOfDataType(v.DataType))
+	}
+
+	attrTypes, err := s.activeAttributeTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, attr := range attrTypes {
+		schema.Add(attr.AttributeCode, formula.TypeNumber)
+	}
+	return schema, nil
+}
+
+// ValidateFormula 校验公式语法与变量，空公式视为合法
+func (s *FormulaVariableService) ValidateFormula(ctx context.Context, source string) error {
+	if strings.TrimSpace(source) == "" {
+		return nil
+	}
+	schema, err := s.Schema(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := formula.ParseAndCheck(source, schema); err != nil {
+		return formulaError(err)
+	}
+	return nil
+}
+
+// FormulaPreviewRequest 公式预览请求
+type FormulaPreviewRequest struct {
+	Formula           string             // 待预览公式
+	HeroID            string             // 施放者：英雄
+	MonsterCode       string             // 施放者：怪物（与 HeroID 二选一）
+	TargetHeroID      string             // 目标：英雄（可选）
+	TargetMonsterCode string             // 目标：怪物（可选）
+	SkillLevel        int                // 技能等级，默认 1
+	Variables         map[string]float64 // 手动覆盖的变量值
+	Seed              int64              // 掷骰种子
+}
+
+// FormulaPreviewResult 公式预览结果
+type FormulaPreviewResult struct {
+	Formula   string             `json:"formula"`
+	Value     float64            `json:"value"`     // 按种子掷骰的结果
+	MinValue  float64            `json:"min_value"` // 骰子全部取 1
+	MaxValue  float64            `json:"max_value"` // 骰子全部取最大面
+	HasDice   bool               `json:"has_dice"`
+	Variables map[string]float64 `json:"variables"` // 公式引用到的变量及其取值
+}
+
+// PreviewFormula 以示例英雄或怪物为环境计算公式
+func (s *FormulaVariableService) PreviewFormula(ctx context.Context, req *FormulaPreviewRequest) (*FormulaPreviewResult, error) {
+	if strings.TrimSpace(req.Formula) == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "公式不能为空")
+	}
+	if (req.HeroID == "") == (req.MonsterCode == "") {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "hero_id 与 monster_code 必须且只能指定一个")
+	}
+	if req.TargetHeroID != "" && req.TargetMonsterCode != "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "target_hero_id 与 target_monster_code 只能指定一个")
+	}
+
+	// 1. 解析与类型检查
+	schema, err := s.Schema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	expr, err := formula.ParseAndCheck(req.Formula, schema)
+	if err != nil {
+		return nil, formulaError(err)
+	}
+
+	// 2. 加载施放者与目标的属性
+	attrTypes, err := s.activeAttributeTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vars, err := s.subjectVariables(ctx, req.HeroID, req.MonsterCode, attrTypes)
+	if err != nil {
+		return nil, err
+	}
+	if req.TargetHeroID != "" || req.TargetMonsterCode != "" {
+		targetVars, err := s.subjectVariables(ctx, req.TargetHeroID, req.TargetMonsterCode, attrTypes)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range targetVars {
+			vars[formula.TargetPrefix+k] = v
+		}
+	}
+
+	// 3. 技能等级与手动覆盖
+	skillLevel := req.SkillLevel
+	if skillLevel <= 0 {
+		skillLevel = 1
+	}
+	vars["SKILL_LEVEL"] = float64(skillLevel)
+	if _, ok := vars["STACKS"]; !ok {
+		vars["STACKS"] = 1
+	}
+	for k, v := range req.Variables {
+		vars[formula.Normalize(k)] = v
+	}
+
+	// 4. 求值：种子掷骰 + 最小/最大
+	return evaluatePreview(expr, vars, req.Seed)
+}
+
+// evaluatePreview 在给定变量下求值，并给出骰子取极值时的范围
+func evaluatePreview(expr *formula.Expr, vars map[string]float64, seed int64) (*FormulaPreviewResult, error) {
+	env := formula.NewMapEnv(vars, formula.SeededDice(seed))
+
+	used := make(map[string]float64)
+	var missing []string
+	for _, name := range expr.Variables() {
+		v, ok := env.Lookup(name)
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		used[name] = v
+	}
+	if len(missing) > 0 {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, fmt.Sprintf("缺少变量取值: %s", strings.Join(missing, ", ")))
+	}
+
+	value, err := expr.Eval(env)
+	if err != nil {
+		return nil, formulaError(err)
+	}
+	env.Dice = formula.MinDice
+	minValue, err := expr.Eval(env)
+	if err != nil {
+		return nil, formulaError(err)
+	}
+	env.Dice = formula.MaxDice
+	maxValue, err := expr.Eval(env)
+	if err != nil {
+		return nil, formulaError(err)
+	}
+	// 骰子出现在减数或除数中时最小/最大会颠倒
+	if minValue > maxValue {
+		minValue, maxValue = maxValue, minValue
+	}
+
+	return &FormulaPreviewResult{
+		Formula:   expr.String(),
+		Value:     value,
+		MinValue:  minValue,
+		MaxValue:  maxValue,
+		HasDice:   expr.HasDice(),
+		Variables: used,
+	}, nil
+}
+
+// subjectVariables 加载英雄或怪物的属性变量
+func (s *FormulaVariableService) subjectVariables(ctx context.Context, heroID, monsterCode string, attrTypes []*game_config.HeroAttributeType) (map[string]float64, error) {
+	vars := make(map[string]float64)
+
+	if heroID != "" {
+		hero, err := s.heroRepo.GetByID(ctx, heroID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+		}
+		// 与战斗引擎一致：包含装备、套装、强化、宝石与 Buff
+		attrs, err := s.heroAttributes.GetComputedAttributes(ctx, heroID)
+		if err != nil {
+			return nil, err
+		}
+		for _, attr := range attrs {
+			vars[formula.Normalize(attr.AttributeCode)] = float64(attr.FinalValue)
+		}
+		vars["LEVEL"] = float64(hero.CurrentLevel)
+	} else {
+		monster, err := s.monsterRepo.GetByCode(ctx, monsterCode)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "怪物不存在")
+		}
+		for code, v := range map[string]null.Int16{
+			"STR": monster.BaseSTR,
+			"AGI": monster.BaseAgi,
+			"VIT": monster.BaseVit,
+			"WLP": monster.BaseWLP,
+			"INT": monster.BaseInt,
+			"WIS": monster.BaseWis,
+			"CHA": monster.BaseCha,
+		} {
+			if v.Valid {
+				vars[code] = float64(v.Int16)
+			}
+		}
+		vars["LEVEL"] = float64(monster.MonsterLevel)
+		vars["MAX_HP"] = float64(monster.MaxHP)
+		vars["HP"] = float64(monster.MaxHP)
+		if monster.MaxMP.Valid {
+			vars["MAX_MP"] = float64(monster.MaxMP.Int)
+			vars["MP"] = float64(monster.MaxMP.Int)
+		}
+	}
+
+	fillDerivedAttributes(vars, attrTypes)
+	return vars, nil
+}
+
+// fillDerivedAttributes 按属性类型的计算公式补齐缺失的派生属性（如 INITIATIVE = AGI*2+WIS）
+func fillDerivedAttributes(vars map[string]float64, attrTypes []*game_config.HeroAttributeType) {
+	pending := make(map[string]*formula.Expr)
+	for _, attr := range attrTypes {
+		code := formula.Normalize(attr.AttributeCode)
+		if _, ok := vars[code]; ok || !attr.CalculationFormula.Valid {
+			continue
+		}
+		if expr, err := formula.Parse(attr.CalculationFormula.String); err == nil {
+			pending[code] = expr
+		}
+	}
+
+	// 派生属性之间可能互相引用，逐轮求值直到不再有进展
+	for len(pending) > 0 {
+		progressed := false
+		for code, expr := range pending {
+			value, err := expr.Eval(formula.NewMapEnv(vars, formula.MinDice))
+			if err != nil {
+				continue
+			}
+			vars[code] = value
+			delete(pending, code)
+			progressed = true
+		}
+		if !progressed {
+			return
+		}
+	}
+}
+
+// activeAttributeTypes 查询所有启用的英雄属性类型
+func (s *FormulaVariableService) activeAttributeTypes(ctx context.Context) ([]*game_config.HeroAttributeType, error) {
+	isActive := true
+	attrTypes, _, err := s.attributeTypeRepo.List(ctx, interfaces.HeroAttributeTypeQueryParams{IsActive: &isActive})
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询属性类型失败")
+	}
+	return attrTypes, nil
+}
+
+// formulaError 将解析/校验/求值错误转换为参数错误
+func formulaError(err error) error {
+	var checkErr *formula.CheckError
+	if errors.As(err, &checkErr) {
+		return xerrors.New(xerrors.CodeInvalidParams, checkErr.Error())
+	}
+	return xerrors.New(xerrors.CodeInvalidParams, fmt.Sprintf("公式无效: %v", err))
+}
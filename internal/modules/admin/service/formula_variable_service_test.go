@@ -0,0 +1,47 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/aarondl/null/v8"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/pkg/formula"
+)
+
+func TestFillDerivedAttributes(t *testing.T) {
+	vars := map[string]float64{"AGI": 5, "WIS": 3}
+	fillDerivedAttributes(vars, []*game_config.HeroAttributeType{
+		{AttributeCode: "SPEED", CalculationFormula: null.StringFrom("INITIATIVE + 1")},
+		{AttributeCode: "INITIATIVE", CalculationFormula: null.StringFrom("AGI*2+WIS")},
+		{AttributeCode: "BROKEN", CalculationFormula: null.StringFrom("UNKNOWN * 2")},
+		{AttributeCode: "AGI", CalculationFormula: null.StringFrom("100")},
+	})
+
+	require.Equal(t, 13.0, vars["INITIATIVE"])
+	require.Equal(t, 14.0, vars["SPEED"])
+	require.Equal(t, 5.0, vars["AGI"]) // 已有值不被覆盖
+	require.NotContains(t, vars, "BROKEN")
+}
+
+func TestEvaluatePreview(t *testing.T) {
+	expr, err := formula.Parse("2d6 + INT * skill_level - target.VIT")
+	require.NoError(t, err)
+
+	result, err := evaluatePreview(expr, map[string]float64{"INT": 4, "SKILL_LEVEL": 2, "TARGET.VIT": 3}, 7)
+	require.NoError(t, err)
+	require.True(t, result.HasDice)
+	require.Equal(t, 7.0, result.MinValue)
+	require.Equal(t, 17.0, result.MaxValue)
+	require.GreaterOrEqual(t, result.Value, result.MinValue)
+	require.LessOrEqual(t, result.Value, result.MaxValue)
+	require.Equal(t, map[string]float64{"INT": 4, "skill_level": 2, "target.VIT": 3}, result.Variables)
+
+	again, err := evaluatePreview(expr, map[string]float64{"INT": 4, "SKILL_LEVEL": 2, "TARGET.VIT": 3}, 7)
+	require.NoError(t, err)
+	require.Equal(t, result.Value, again.Value)
+
+	_, err = evaluatePreview(expr, map[string]float64{"INT": 4}, 7)
+	require.Error(t, err)
+}
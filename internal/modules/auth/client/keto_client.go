@@ -4,6 +4,7 @@ package client
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	rts "github.com/ory/keto/proto/ory/keto/relation_tuples/v1alpha2"
 	"google.golang.org/grpc"
@@ -556,9 +557,75 @@ func (k *KetoClient) UpdateTeamMemberRole(ctx context.Context, teamID, heroID, o
 // permission: 权限名称 (如: "select_dungeon", "kick_member", "distribute_loot")
 // heroID: 执行操作的英雄ID
 func (k *KetoClient) CheckTeamPermission(ctx context.Context, teamID, permission, heroID string) (bool, error) {
-	// Keto 权限检查: team_permissions:permission#allowed@hero:heroID
-	// 通过 teams:teamID#role@hero:heroID 关系推导
-	return k.CheckPermission(ctx, "team_permissions", permission, "allowed", fmt.Sprintf("hero:%s", heroID))
+	// Keto 权限检查: team_permissions:teamID:permission#allowed@hero:heroID
+	// 关系由 SyncTeamMemberPermissions 按成员角色与职阶写入
+	return k.CheckPermission(ctx, "team_permissions", teamPermissionObject(teamID, permission), "allowed", fmt.Sprintf("hero:%s", heroID))
+}
+
+// SyncTeamMemberPermissions 将成员在团队内的有效权限同步为 Keto 直接关系
+// 只写入差异: 新增缺失的权限, 删除不再拥有的权限; 返回是否发生变更
+func (k *KetoClient) SyncTeamMemberPermissions(ctx context.Context, teamID, heroID string, permissions []string) (bool, error) {
+	subjectID := fmt.Sprintf("hero:%s", heroID)
+	prefix := teamID + ":"
+
+	tuples, err := k.ListRelations(ctx, "team_permissions", "", "allowed", subjectID)
+	if err != nil {
+		return false, fmt.Errorf("failed to list team permissions: %w", err)
+	}
+
+	existing := make(map[string]bool)
+	for _, tuple := range tuples {
+		if strings.HasPrefix(tuple.Object, prefix) {
+			existing[strings.TrimPrefix(tuple.Object, prefix)] = true
+		}
+	}
+
+	wanted := make(map[string]bool, len(permissions))
+	var toCreate []*RelationTuple
+	for _, permission := range permissions {
+		if wanted[permission] {
+			continue
+		}
+		wanted[permission] = true
+		if !existing[permission] {
+			toCreate = append(toCreate, &RelationTuple{
+				Namespace: "team_permissions",
+				Object:    teamPermissionObject(teamID, permission),
+				Relation:  "allowed",
+				SubjectID: subjectID,
+			})
+		}
+	}
+
+	var toDelete []*RelationTuple
+	for permission := range existing {
+		if !wanted[permission] {
+			toDelete = append(toDelete, &RelationTuple{
+				Namespace: "team_permissions",
+				Object:    teamPermissionObject(teamID, permission),
+				Relation:  "allowed",
+				SubjectID: subjectID,
+			})
+		}
+	}
+
+	if len(toCreate) > 0 {
+		if err := k.BatchCreateRelations(ctx, toCreate); err != nil {
+			return false, fmt.Errorf("failed to grant team permissions: %w", err)
+		}
+	}
+	if len(toDelete) > 0 {
+		if err := k.BatchDeleteRelations(ctx, toDelete); err != nil {
+			return len(toCreate) > 0, fmt.Errorf("failed to revoke team permissions: %w", err)
+		}
+	}
+
+	return len(toCreate) > 0 || len(toDelete) > 0, nil
+}
+
+// teamPermissionObject 团队权限对象: "teamID:permission"
+func teamPermissionObject(teamID, permission string) string {
+	return teamID + ":" + permission
 }
 
 // GetTeamMembers 获取团队所有成员 (按角色分组)
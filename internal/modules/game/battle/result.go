@@ -0,0 +1,142 @@
+package battle
+
+import "fmt"
+
+// 事件类型
+const (
+	EventBattleStart  = "battle_start"
+	EventRoundStart   = "round_start"
+	EventAction       = "action"
+	EventMiss         = "miss"
+	EventDamage       = "damage"
+	EventHeal         = "heal"
+	EventManaRestore  = "mana_restore"
+	EventBuffApplied  = "buff_applied"
+	EventBuffRemoved  = "buff_removed"
+	EventDeath        = "death"
+	EventUnsupported  = "unsupported_effect"
+	EventBattleFinish = "battle_end"
+)
+
+// Event 战斗事件
+type Event struct {
+	Seq      int     `json:"seq"`
+	Round    int     `json:"round"`
+	Type     string  `json:"type"`
+	ActorID  string  `json:"actor_id,omitempty"`
+	TargetID string  `json:"target_id,omitempty"`
+	Action   string  `json:"action,omitempty"`
+	Effect   string  `json:"effect,omitempty"`
+	Buff     string  `json:"buff,omitempty"`
+	Value    float64 `json:"value,omitempty"`
+	TargetHP *int    `json:"target_hp,omitempty"`
+	Detail   string  `json:"detail,omitempty"`
+}
+
+// Participant 英雄参战结果，与 battleResultPayload.participants 一致
+type Participant struct {
+	HeroID  string `json:"hero_id"`
+	TeamID  string `json:"team_id"`
+	Role    string `json:"role"`
+	IsAlive *bool  `json:"is_alive,omitempty"`
+}
+
+// CombatantSummary 单位战斗统计
+type CombatantSummary struct {
+	ID          string `json:"id"`
+	Side        Side   `json:"side"`
+	HP          int    `json:"hp"`
+	MaxHP       int    `json:"max_hp"`
+	IsAlive     bool   `json:"is_alive"`
+	DamageDealt int    `json:"damage_dealt"`
+	DamageTaken int    `json:"damage_taken"`
+	HealingDone int    `json:"healing_done"`
+}
+
+// Result 模拟结果
+type Result struct {
+	BattleID     string              `json:"battle_id"`
+	BattleCode   string              `json:"battle_code"`
+	Seed         int64               `json:"seed"`
+	Status       string              `json:"status"`
+	Rounds       int                 `json:"rounds"`
+	Participants []Participant       `json:"participants"`
+	Combatants   []*CombatantSummary `json:"combatants"`
+	Events       []Event             `json:"events"`
+}
+
+// Payload 战斗结果回调结构（loot 由服务端掉落表生成，不在模拟范围内）
+type Payload struct {
+	BattleID     string        `json:"battle_id"`
+	BattleCode   string        `json:"battle_code"`
+	Participants []Participant `json:"participants"`
+	Result       PayloadResult `json:"result"`
+	Events       []Event       `json:"events"`
+}
+
+// PayloadResult 回调中的 result 字段
+type PayloadResult struct {
+	Status      string             `json:"status"`
+	LootContext PayloadLootContext `json:"loot_context"`
+}
+
+// PayloadLootContext 回调中的 loot_context 字段
+type PayloadLootContext struct {
+	Type      string `json:"type"`
+	TeamID    string `json:"team_id"`
+	DungeonID string `json:"dungeon_id"`
+}
+
+// Payload 转换为战斗结果回调结构
+func (r *Result) Payload(teamID, dungeonID string) *Payload {
+	return &Payload{
+		BattleID:     r.BattleID,
+		BattleCode:   r.BattleCode,
+		Participants: r.Participants,
+		Result: PayloadResult{
+			Status: r.Status,
+			LootContext: PayloadLootContext{
+				Type:      "team",
+				TeamID:    teamID,
+				DungeonID: dungeonID,
+			},
+		},
+		Events: r.Events,
+	}
+}
+
+// Diff 与外部上报结果对比，返回不一致项（为空表示一致）
+// 上报中未携带 is_alive 的英雄不比较存活状态
+func (r *Result) Diff(status string, participants []Participant) []string {
+	var diffs []string
+	if status != r.Status {
+		diffs = append(diffs, fmt.Sprintf("status: reported=%s simulated=%s", status, r.Status))
+	}
+
+	simulated := make(map[string]Participant, len(r.Participants))
+	for _, p := range r.Participants {
+		simulated[p.HeroID] = p
+	}
+	for _, p := range participants {
+		sim, ok := simulated[p.HeroID]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("participant %s: not in simulation", p.HeroID))
+			continue
+		}
+		if p.IsAlive != nil && sim.IsAlive != nil && *p.IsAlive != *sim.IsAlive {
+			diffs = append(diffs, fmt.Sprintf("participant %s: reported is_alive=%t simulated=%t", p.HeroID, *p.IsAlive, *sim.IsAlive))
+		}
+	}
+	return diffs
+}
+
+// BalanceReport 多次模拟的统计结果
+type BalanceReport struct {
+	Runs        int     `json:"runs"`
+	Victories   int     `json:"victories"`
+	Defeats     int     `json:"defeats"`
+	Draws       int     `json:"draws"`
+	WinRate     float64 `json:"win_rate"`
+	AvgRounds   float64 `json:"avg_rounds"`
+	AvgHeroDead float64 `json:"avg_hero_dead"`
+}
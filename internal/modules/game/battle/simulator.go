@@ -0,0 +1,920 @@
+package battle
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"tsu-self/internal/pkg/formula"
+)
+
+// 效果类型归一化后的种类
+const (
+	effectKindDamage      = "damage"
+	effectKindHeal        = "heal"
+	effectKindApplyBuff   = "apply_buff"
+	effectKindRemoveBuff  = "remove_buff"
+	effectKindRestoreMana = "restore_mana"
+	effectKindModifier    = "attribute_modifier"
+)
+
+// effectKind 将 effects.effect_type 归一化（大小写及常见别名）
+func effectKind(effectType string) string {
+	switch strings.ToUpper(strings.TrimSpace(effectType)) {
+	case "DAMAGE", "DMG", "DMG_CALCULATION":
+		return effectKindDamage
+	case "HEAL", "HEALING":
+		return effectKindHeal
+	case "APPLY_BUFF", "ADD_BUFF", "BUFF", "DEBUFF":
+		return effectKindApplyBuff
+	case "REMOVE_BUFF", "DISPEL":
+		return effectKindRemoveBuff
+	case "RESTORE_MANA", "MANA", "MP_RESTORE":
+		return effectKindRestoreMana
+	case "ATTRIBUTE_MODIFIER", "MODIFY_ATTRIBUTE", "STAT_MODIFIER":
+		return effectKindModifier
+	}
+	return ""
+}
+
+// activeBuff 单位身上生效中的 Buff
+type activeBuff struct {
+	buff      *Buff
+	stacks    int
+	remaining int // 剩余回合，<=0 表示永久
+	source    *unit
+}
+
+// unit 单位的战斗中状态
+type unit struct {
+	*Combatant
+	hp        int
+	mp        int
+	alive     bool
+	readyAt   map[string]int // 动作代码 -> 冷却结束的回合
+	uses      map[string]int
+	buffs     []*activeBuff
+	summary   *CombatantSummary
+	resolving map[string]bool // 派生属性递归保护
+}
+
+type simulator struct {
+	setup    *Setup
+	rng      *rand.Rand
+	units    []*unit
+	formulas map[string]*formula.Expr
+	events   []Event
+	round    int
+}
+
+// Simulate 按 Setup.Seed 确定性地模拟一场战斗，相同输入必然得到相同结果
+func Simulate(setup *Setup) (*Result, error) {
+	s, err := newSimulator(setup)
+	if err != nil {
+		return nil, err
+	}
+	return s.run()
+}
+
+// RunBalance 以 Seed, Seed+1, ... 连续模拟 runs 次并汇总胜率
+func RunBalance(setup *Setup, runs int) (*BalanceReport, error) {
+	if runs <= 0 {
+		return nil, fmt.Errorf("模拟次数必须大于 0")
+	}
+	report := &BalanceReport{Runs: runs}
+	totalRounds, totalDead := 0, 0
+	for i := 0; i < runs; i++ {
+		run := *setup
+		run.Seed = setup.Seed + int64(i)
+		result, err := Simulate(&run)
+		if err != nil {
+			return nil, err
+		}
+		switch result.Status {
+		case StatusVictory:
+			report.Victories++
+		case StatusDefeat:
+			report.Defeats++
+		default:
+			report.Draws++
+		}
+		totalRounds += result.Rounds
+		for _, p := range result.Participants {
+			if p.IsAlive != nil && !*p.IsAlive {
+				totalDead++
+			}
+		}
+	}
+	report.WinRate = float64(report.Victories) / float64(runs)
+	report.AvgRounds = float64(totalRounds) / float64(runs)
+	report.AvgHeroDead = float64(totalDead) / float64(runs)
+	return report, nil
+}
+
+func newSimulator(setup *Setup) (*simulator, error) {
+	if setup == nil || len(setup.Heroes) == 0 || len(setup.Monsters) == 0 {
+		return nil, fmt.Errorf("战斗双方都至少需要一个单位")
+	}
+	s := &simulator{
+		setup:    setup,
+		rng:      rand.New(rand.NewSource(setup.Seed)),
+		formulas: make(map[string]*formula.Expr),
+	}
+
+	seen := make(map[string]bool)
+	for _, group := range [][]*Combatant{setup.Heroes, setup.Monsters} {
+		for _, c := range group {
+			if c.ID == "" || seen[c.ID] {
+				return nil, fmt.Errorf("单位ID为空或重复: %q", c.ID)
+			}
+			seen[c.ID] = true
+			if c.MaxHP <= 0 {
+				return nil, fmt.Errorf("单位 %s 的最大生命值必须大于 0", c.ID)
+			}
+			s.units = append(s.units, &unit{
+				Combatant: c,
+				hp:        c.MaxHP,
+				mp:        c.MaxMP,
+				alive:     true,
+				readyAt:   make(map[string]int),
+				uses:      make(map[string]int),
+				summary:   &CombatantSummary{ID: c.ID, Side: c.Side, MaxHP: c.MaxHP},
+				resolving: make(map[string]bool),
+			})
+		}
+	}
+
+	if err := s.compileConfig(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// compileConfig 预先解析全部公式并校验 Buff 引用，配置错误在开战前暴露
+func (s *simulator) compileConfig() error {
+	checkEffect := func(effect *Effect) error {
+		if err := s.compile(effect.Formula); err != nil {
+			return err
+		}
+		kind := effectKind(effect.Type)
+		if kind == effectKindApplyBuff || kind == effectKindRemoveBuff {
+			if _, ok := s.setup.Buffs[effect.BuffCode]; !ok {
+				return fmt.Errorf("效果 %s 引用了不存在的 Buff %q", effect.Code, effect.BuffCode)
+			}
+		}
+		return nil
+	}
+
+	for _, u := range s.units {
+		for _, action := range u.Actions {
+			if err := s.compile(action.ManaCostFormula); err != nil {
+				return err
+			}
+			for _, effect := range action.Effects {
+				if err := checkEffect(effect); err != nil {
+					return err
+				}
+			}
+		}
+		for _, code := range u.PassiveBuffs {
+			if _, ok := s.setup.Buffs[code]; !ok {
+				return fmt.Errorf("单位 %s 的被动 Buff %q 不存在", u.ID, code)
+			}
+		}
+	}
+	for _, buff := range s.setup.Buffs {
+		for _, be := range buff.Effects {
+			if err := checkEffect(be.Effect); err != nil {
+				return err
+			}
+		}
+	}
+	for _, gb := range s.setup.GlobalBuffs {
+		if _, ok := s.setup.Buffs[gb.BuffCode]; !ok {
+			return fmt.Errorf("全程 Buff %q 不存在", gb.BuffCode)
+		}
+	}
+	for _, source := range s.setup.DerivedAttributes {
+		if err := s.compile(source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *simulator) compile(source string) error {
+	if strings.TrimSpace(source) == "" {
+		return nil
+	}
+	if _, ok := s.formulas[source]; ok {
+		return nil
+	}
+	expr, err := formula.Parse(source)
+	if err != nil {
+		return err
+	}
+	s.formulas[source] = expr
+	return nil
+}
+
+func (s *simulator) run() (*Result, error) {
+	maxRounds := s.setup.MaxRounds
+	if maxRounds <= 0 {
+		maxRounds = DefaultMaxRounds
+	}
+
+	s.emit(Event{Type: EventBattleStart, Detail: s.setup.BattleCode})
+	if err := s.applyOpeningBuffs(); err != nil {
+		return nil, err
+	}
+
+	status := s.outcome()
+	for status == "" && s.round < maxRounds {
+		s.round++
+		s.emit(Event{Type: EventRoundStart})
+
+		order, err := s.initiativeOrder()
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range order {
+			if !u.alive {
+				continue
+			}
+			if err := s.takeTurn(u); err != nil {
+				return nil, err
+			}
+			if status = s.outcome(); status != "" {
+				break
+			}
+		}
+	}
+	if status == "" {
+		status = StatusDraw
+	}
+	s.emit(Event{Type: EventBattleFinish, Detail: status})
+	return s.buildResult(status), nil
+}
+
+// applyOpeningBuffs 开战时施加怪物被动与战斗全程 Buff
+func (s *simulator) applyOpeningBuffs() error {
+	for _, u := range s.units {
+		for _, code := range u.PassiveBuffs {
+			if err := s.applyBuff(u, u, s.setup.Buffs[code], 0); err != nil {
+				return err
+			}
+		}
+	}
+	for _, gb := range s.setup.GlobalBuffs {
+		for _, u := range s.units {
+			if gb.Target == "all" ||
+				(gb.Target == "allies" && u.Side == SideHero) ||
+				(gb.Target == "enemies" && u.Side == SideMonster) {
+				if err := s.applyBuff(nil, u, s.setup.Buffs[gb.BuffCode], 0); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// initiativeOrder 先攻值 + 1d20 降序，平局时英雄优先、站位靠前优先
+func (s *simulator) initiativeOrder() ([]*unit, error) {
+	type entry struct {
+		u     *unit
+		value float64
+	}
+	entries := make([]entry, 0, len(s.units))
+	for _, u := range s.units {
+		if !u.alive {
+			continue
+		}
+		code := u.InitiativeAttribute
+		if code == "" {
+			code = DefaultInitiativeAttribute
+		}
+		value, err := s.attributeOrZero(u, code)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{u: u, value: value + float64(s.rng.Intn(20)+1)})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.value != b.value {
+			return a.value > b.value
+		}
+		if a.u.Side != b.u.Side {
+			return a.u.Side == SideHero
+		}
+		if a.u.Position != b.u.Position {
+			return a.u.Position < b.u.Position
+		}
+		return a.u.ID < b.u.ID
+	})
+	order := make([]*unit, len(entries))
+	for i, e := range entries {
+		order[i] = e.u
+	}
+	return order, nil
+}
+
+// takeTurn 单位回合：恢复 -> 回合开始 Buff -> 行动 -> 回合结束 Buff 与持续时间结算
+func (s *simulator) takeTurn(u *unit) error {
+	if u.HPRecovery > 0 {
+		u.hp = min(u.MaxHP, u.hp+u.HPRecovery)
+	}
+	if u.MPRecovery > 0 {
+		u.mp = min(u.MaxMP, u.mp+u.MPRecovery)
+	}
+
+	if err := s.fireBuffEffects(u, TimingTurnStart); err != nil {
+		return err
+	}
+	if !u.alive {
+		return nil
+	}
+
+	ap := u.ActionPoints
+	if ap <= 0 {
+		ap = DefaultActionPoints
+	}
+	used := make(map[string]bool)
+	for s.outcome() == "" {
+		action, targets, manaCost, err := s.chooseAction(u, ap, used)
+		if err != nil {
+			return err
+		}
+		if action == nil {
+			break
+		}
+		used[action.Code] = true
+		ap -= max(action.APCost, 0)
+		u.mp -= manaCost
+		u.uses[action.Code]++
+		u.readyAt[action.Code] = s.round + action.Cooldown + 1
+		if err := s.executeAction(u, action, targets); err != nil {
+			return err
+		}
+	}
+
+	if !u.alive {
+		return nil
+	}
+	if err := s.fireBuffEffects(u, TimingTurnEnd); err != nil {
+		return err
+	}
+	return s.tickBuffs(u)
+}
+
+// chooseAction 按配置顺序选取第一个可用的动作（行动点、法力、冷却、次数、目标均满足）
+func (s *simulator) chooseAction(u *unit, ap int, used map[string]bool) (*Action, []*unit, int, error) {
+	for _, action := range u.Actions {
+		if used[action.Code] || max(action.APCost, 0) > ap {
+			continue
+		}
+		if s.round < u.readyAt[action.Code] {
+			continue
+		}
+		if action.UsesPerBattle > 0 && u.uses[action.Code] >= action.UsesPerBattle {
+			continue
+		}
+		manaCost, err := s.manaCost(u, action)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if manaCost > u.mp {
+			continue
+		}
+		targets := s.selectTargets(u, action)
+		if len(targets) == 0 {
+			continue
+		}
+		return action, targets, manaCost, nil
+	}
+	return nil, nil, 0, nil
+}
+
+func (s *simulator) manaCost(u *unit, action *Action) (int, error) {
+	if action.ManaCostFormula == "" {
+		return max(action.ManaCost, 0), nil
+	}
+	v, err := s.formulas[action.ManaCostFormula].Eval(&evalEnv{sim: s, actor: u, action: action, stacks: 1})
+	if err != nil {
+		return 0, err
+	}
+	return max(int(math.Round(v)), 0), nil
+}
+
+// selectTargets 目标选择：敌方单体取站位最靠前者，友方单体取生命比例最低者；纯治疗动作只选受伤单位
+func (s *simulator) selectTargets(u *unit, action *Action) []*unit {
+	if action.Target.Type == "self" {
+		if isHealOnly(action) && u.hp >= u.MaxHP {
+			return nil
+		}
+		return []*unit{u}
+	}
+
+	side := opposite(u.Side)
+	if action.Target.Ally {
+		side = u.Side
+	}
+	var candidates []*unit
+	for _, c := range s.units {
+		if !c.alive || c.Side != side {
+			continue
+		}
+		if isHealOnly(action) && c.hp >= c.MaxHP {
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if action.Target.Ally {
+			ra, rb := float64(a.hp)/float64(a.MaxHP), float64(b.hp)/float64(b.MaxHP)
+			if ra != rb {
+				return ra < rb
+			}
+		}
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		return a.ID < b.ID
+	})
+
+	switch action.Target.Type {
+	case "all", "area", "multiple":
+		return candidates
+	case "random":
+		return []*unit{candidates[s.rng.Intn(len(candidates))]}
+	}
+	return candidates[:1]
+}
+
+func (s *simulator) executeAction(u *unit, action *Action, targets []*unit) error {
+	s.emit(Event{Type: EventAction, ActorID: u.ID, Action: action.Code, Value: float64(len(targets))})
+
+	for _, target := range targets {
+		if !target.alive {
+			continue
+		}
+		hit, err := s.rollHit(u, target, action)
+		if err != nil {
+			return err
+		}
+		if !hit {
+			s.emit(Event{Type: EventMiss, ActorID: u.ID, TargetID: target.ID, Action: action.Code})
+		}
+		for _, effect := range action.Effects {
+			if !target.alive {
+				break
+			}
+			if !s.shouldTrigger(effect, hit) {
+				continue
+			}
+			if effectKind(effect.Type) == effectKindModifier {
+				// 属性修正只在 Buff 中持续生效
+				s.emit(Event{Type: EventUnsupported, ActorID: u.ID, TargetID: target.ID, Action: action.Code, Effect: effect.Code})
+				continue
+			}
+			if err := s.applyEffect(u, target, effect, action, 1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rollHit 对友方必中；对敌方按 hit_rate_config 计算命中率（百分比）后掷骰
+func (s *simulator) rollHit(u, target *unit, action *Action) (bool, error) {
+	cfg := action.HitRate
+	if cfg == nil || target.Side == u.Side {
+		return true, nil
+	}
+
+	rate := cfg.BaseHitRate
+	if cfg.UseAttackerAccuracy {
+		code := cfg.AccuracyAttribute
+		if code == "" {
+			code = "ACCURACY"
+		}
+		accuracy, err := s.attributeOrZero(u, code)
+		if err != nil {
+			return false, err
+		}
+		multiplier := cfg.AccuracyMultiplier
+		if multiplier == 0 {
+			multiplier = 1
+		}
+		rate += accuracy * multiplier
+	}
+	if cfg.UseTargetDefense {
+		code := cfg.DefenseAttribute
+		if code == "" {
+			code = "DODGE"
+		}
+		dodge, err := s.attributeOrZero(target, code)
+		if err != nil {
+			return false, err
+		}
+		rate -= dodge
+	}
+	if cfg.MaxHitRate > 0 && rate > cfg.MaxHitRate {
+		rate = cfg.MaxHitRate
+	}
+	if rate < cfg.MinHitRate {
+		rate = cfg.MinHitRate
+	}
+	return s.rng.Float64()*100 < rate, nil
+}
+
+func (s *simulator) shouldTrigger(effect *Effect, hit bool) bool {
+	switch effect.Trigger {
+	case TriggerAlways:
+	case TriggerOnMiss:
+		if hit {
+			return false
+		}
+	default:
+		if !hit {
+			return false
+		}
+	}
+	if effect.TriggerChance > 0 && effect.TriggerChance < 100 {
+		return s.rng.Float64()*100 < effect.TriggerChance
+	}
+	return true
+}
+
+// applyEffect 结算单个效果，actor 可能为空（全程 Buff）
+func (s *simulator) applyEffect(actor, target *unit, effect *Effect, action *Action, stacks int) error {
+	if actor == nil {
+		actor = target
+	}
+	actionCode := ""
+	if action != nil {
+		actionCode = action.Code
+	}
+
+	switch effectKind(effect.Type) {
+	case effectKindDamage:
+		value, err := s.effectValue(actor, target, effect, action, stacks)
+		if err != nil {
+			return err
+		}
+		damage := s.mitigate(target, effect.DamageType, value)
+		target.hp -= damage
+		if target.hp < 0 {
+			target.hp = 0
+		}
+		actor.summary.DamageDealt += damage
+		target.summary.DamageTaken += damage
+		hp := target.hp
+		s.emit(Event{Type: EventDamage, ActorID: actor.ID, TargetID: target.ID, Action: actionCode, Effect: effect.Code, Value: float64(damage), TargetHP: &hp, Detail: effect.DamageType})
+		if target.hp == 0 {
+			target.alive = false
+			s.emit(Event{Type: EventDeath, ActorID: actor.ID, TargetID: target.ID})
+		}
+	case effectKindHeal:
+		value, err := s.effectValue(actor, target, effect, action, stacks)
+		if err != nil {
+			return err
+		}
+		heal := min(max(int(math.Round(value)), 0), target.MaxHP-target.hp)
+		target.hp += heal
+		actor.summary.HealingDone += heal
+		hp := target.hp
+		s.emit(Event{Type: EventHeal, ActorID: actor.ID, TargetID: target.ID, Action: actionCode, Effect: effect.Code, Value: float64(heal), TargetHP: &hp})
+	case effectKindRestoreMana:
+		value, err := s.effectValue(actor, target, effect, action, stacks)
+		if err != nil {
+			return err
+		}
+		restored := min(max(int(math.Round(value)), 0), target.MaxMP-target.mp)
+		target.mp += restored
+		s.emit(Event{Type: EventManaRestore, ActorID: actor.ID, TargetID: target.ID, Action: actionCode, Effect: effect.Code, Value: float64(restored)})
+	case effectKindApplyBuff:
+		return s.applyBuff(actor, target, s.setup.Buffs[effect.BuffCode], effect.BuffDuration)
+	case effectKindRemoveBuff:
+		return s.removeBuff(target, effect.BuffCode)
+	default:
+		s.emit(Event{Type: EventUnsupported, ActorID: actor.ID, TargetID: target.ID, Action: actionCode, Effect: effect.Code, Detail: effect.Type})
+	}
+	return nil
+}
+
+// effectValue 有公式按公式计算，否则取 parameters.value
+func (s *simulator) effectValue(actor, target *unit, effect *Effect, action *Action, stacks int) (float64, error) {
+	if effect.Formula == "" {
+		return effect.Params["value"] * float64(max(stacks, 1)), nil
+	}
+	return s.formulas[effect.Formula].Eval(&evalEnv{sim: s, actor: actor, target: target, action: action, stacks: stacks})
+}
+
+// mitigate 按目标抗性减伤：<TYPE>_RESIST 为比例减伤，<TYPE>_DR 为固定减伤
+func (s *simulator) mitigate(target *unit, damageType string, value float64) int {
+	if damageType != "" && target.DamageResistances != nil {
+		code := strings.ToUpper(damageType)
+		if resist, ok := target.DamageResistances[code+"_RESIST"]; ok {
+			value *= 1 - math.Max(0, math.Min(resist, 1))
+		}
+		value -= target.DamageResistances[code+"_DR"]
+	}
+	return max(int(math.Round(value)), 0)
+}
+
+// applyBuff 按 stack_rule 叠加，duration 为 0 时使用 Buff 默认持续回合
+func (s *simulator) applyBuff(source, target *unit, buff *Buff, duration int) error {
+	if !target.alive {
+		return nil
+	}
+	if duration == 0 {
+		duration = buff.Duration
+	}
+	sourceID := ""
+	if source != nil {
+		sourceID = source.ID
+	}
+
+	if existing := findBuff(target, buff.Code); existing != nil {
+		switch buff.StackRule {
+		case StackRuleStackable:
+			if buff.MaxStacks <= 0 || existing.stacks < buff.MaxStacks {
+				existing.stacks++
+			}
+			existing.remaining = duration
+			s.emit(Event{Type: EventBuffApplied, ActorID: sourceID, TargetID: target.ID, Buff: buff.Code, Value: float64(existing.stacks)})
+			return nil
+		case StackRuleRefresh:
+			existing.remaining = duration
+			s.emit(Event{Type: EventBuffApplied, ActorID: sourceID, TargetID: target.ID, Buff: buff.Code, Value: float64(existing.stacks)})
+			return nil
+		case StackRuleReplace:
+			if err := s.removeBuff(target, buff.Code); err != nil {
+				return err
+			}
+		default:
+			// no_stack：已存在时不生效
+			return nil
+		}
+	}
+
+	active := &activeBuff{buff: buff, stacks: 1, remaining: duration, source: source}
+	target.buffs = append(target.buffs, active)
+	s.emit(Event{Type: EventBuffApplied, ActorID: sourceID, TargetID: target.ID, Buff: buff.Code, Value: 1})
+	return s.fireEffects(active, target, TimingOnApply)
+}
+
+func (s *simulator) removeBuff(target *unit, code string) error {
+	for i, active := range target.buffs {
+		if active.buff.Code != code {
+			continue
+		}
+		target.buffs = append(target.buffs[:i:i], target.buffs[i+1:]...)
+		s.emit(Event{Type: EventBuffRemoved, TargetID: target.ID, Buff: code})
+		return s.fireEffects(active, target, TimingOnRemove)
+	}
+	return nil
+}
+
+// fireBuffEffects 触发单位身上所有 Buff 在指定时机的效果
+func (s *simulator) fireBuffEffects(u *unit, timing string) error {
+	snapshot := append([]*activeBuff(nil), u.buffs...)
+	for _, active := range snapshot {
+		if !u.alive {
+			return nil
+		}
+		if err := s.fireEffects(active, u, timing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *simulator) fireEffects(active *activeBuff, bearer *unit, timing string) error {
+	for _, be := range active.buff.Effects {
+		if be.Timing != timing || effectKind(be.Effect.Type) == effectKindModifier {
+			continue
+		}
+		if !bearer.alive {
+			return nil
+		}
+		if !s.shouldTrigger(be.Effect, true) {
+			continue
+		}
+		if err := s.applyEffect(active.source, bearer, be.Effect, nil, active.stacks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tickBuffs 回合结束时有限时 Buff 持续回合减一，到期移除
+func (s *simulator) tickBuffs(u *unit) error {
+	var expired []string
+	for _, active := range u.buffs {
+		if active.remaining <= 0 {
+			continue
+		}
+		active.remaining--
+		if active.remaining == 0 {
+			expired = append(expired, active.buff.Code)
+		}
+	}
+	for _, code := range expired {
+		if err := s.removeBuff(u, code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attribute 属性值 = 基础值（缺失时按派生公式计算）+ Buff 属性修正
+func (s *simulator) attribute(u *unit, code string) (float64, bool, error) {
+	if u.resolving[code] {
+		return 0, false, fmt.Errorf("属性 %s 存在循环引用", code)
+	}
+	u.resolving[code] = true
+	defer delete(u.resolving, code)
+
+	value, found := u.Attributes[code]
+	if !found {
+		if source, ok := s.setup.DerivedAttributes[code]; ok && source != "" {
+			v, err := s.formulas[source].Eval(&evalEnv{sim: s, actor: u, stacks: 1})
+			if err != nil {
+				return 0, false, err
+			}
+			value, found = v, true
+		}
+	}
+
+	for _, active := range u.buffs {
+		for _, be := range active.buff.Effects {
+			if effectKind(be.Effect.Type) != effectKindModifier || be.Effect.AttributeCode != code {
+				continue
+			}
+			v, err := s.effectValue(u, u, be.Effect, nil, active.stacks)
+			if err != nil {
+				return 0, false, err
+			}
+			value += v
+			found = true
+		}
+	}
+	return value, found, nil
+}
+
+func (s *simulator) attributeOrZero(u *unit, code string) (float64, error) {
+	v, _, err := s.attribute(u, code)
+	return v, err
+}
+
+// outcome 怪物全灭为胜利，英雄全灭为失败，否则返回空
+func (s *simulator) outcome() string {
+	heroesAlive, monstersAlive := false, false
+	for _, u := range s.units {
+		if !u.alive {
+			continue
+		}
+		if u.Side == SideHero {
+			heroesAlive = true
+		} else {
+			monstersAlive = true
+		}
+	}
+	switch {
+	case !heroesAlive:
+		return StatusDefeat
+	case !monstersAlive:
+		return StatusVictory
+	}
+	return ""
+}
+
+func (s *simulator) emit(e Event) {
+	e.Seq = len(s.events) + 1
+	e.Round = s.round
+	s.events = append(s.events, e)
+}
+
+func (s *simulator) buildResult(status string) *Result {
+	result := &Result{
+		BattleID:   s.setup.BattleID,
+		BattleCode: s.setup.BattleCode,
+		Seed:       s.setup.Seed,
+		Status:     status,
+		Rounds:     s.round,
+		Events:     s.events,
+	}
+	for _, u := range s.units {
+		u.summary.HP = u.hp
+		u.summary.IsAlive = u.alive
+		result.Combatants = append(result.Combatants, u.summary)
+		if u.Side != SideHero {
+			continue
+		}
+		alive := u.alive
+		result.Participants = append(result.Participants, Participant{
+			HeroID:  u.ID,
+			TeamID:  u.TeamID,
+			Role:    u.Role,
+			IsAlive: &alive,
+		})
+	}
+	return result
+}
+
+// evalEnv 公式变量：施放者属性、level、skill_level、stacks、HP/MP，以及 target.<属性>
+type evalEnv struct {
+	sim    *simulator
+	actor  *unit
+	target *unit
+	action *Action
+	stacks int
+}
+
+func (e *evalEnv) Lookup(name string) (float64, bool) {
+	if rest, ok := cutPrefixFold(name, "target."); ok {
+		if e.target == nil {
+			return 0, false
+		}
+		return (&evalEnv{sim: e.sim, actor: e.target, action: e.action, stacks: e.stacks}).Lookup(rest)
+	}
+
+	switch strings.ToUpper(name) {
+	case "LEVEL":
+		return float64(e.actor.Level), true
+	case "SKILL_LEVEL":
+		if e.action == nil || e.action.SkillLevel <= 0 {
+			return 1, true
+		}
+		return float64(e.action.SkillLevel), true
+	case "STACKS":
+		return float64(max(e.stacks, 1)), true
+	case "HP":
+		return float64(e.actor.hp), true
+	case "MAX_HP":
+		return float64(e.actor.MaxHP), true
+	case "MP":
+		return float64(e.actor.mp), true
+	case "MAX_MP":
+		return float64(e.actor.MaxMP), true
+	}
+
+	v, found, err := e.sim.attribute(e.actor, name)
+	if err != nil {
+		return 0, false
+	}
+	return v, found
+}
+
+func (e *evalEnv) RollDice(count, sides int) int {
+	total := 0
+	for i := 0; i < count; i++ {
+		total += e.sim.rng.Intn(sides) + 1
+	}
+	return total
+}
+
+func findBuff(u *unit, code string) *activeBuff {
+	for _, active := range u.buffs {
+		if active.buff.Code == code {
+			return active
+		}
+	}
+	return nil
+}
+
+func isHealOnly(action *Action) bool {
+	if len(action.Effects) == 0 {
+		return false
+	}
+	for _, effect := range action.Effects {
+		if effectKind(effect.Type) != effectKindHeal {
+			return false
+		}
+	}
+	return true
+}
+
+func opposite(side Side) Side {
+	if side == SideHero {
+		return SideMonster
+	}
+	return SideHero
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):], true
+	}
+	return s, false
+}
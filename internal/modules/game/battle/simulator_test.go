@@ -0,0 +1,238 @@
+package battle
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testSetup(seed int64) *Setup {
+	slash := &Action{
+		Code:    "SLASH",
+		APCost:  1,
+		HitRate: &HitRateConfig{BaseHitRate: 75, UseAttackerAccuracy: true, AccuracyAttribute: "AGI", MinHitRate: 5, MaxHitRate: 95},
+		Target:  TargetConfig{Type: "single"},
+		Effects: []*Effect{{Code: "SLASH_DMG", Type: "damage", Formula: "1d8 + STR", DamageType: "physical"}},
+	}
+	fireball := &Action{
+		Code:            "FIREBALL",
+		APCost:          1,
+		ManaCostFormula: "5 + 2*skill_level",
+		Cooldown:        2,
+		SkillLevel:      2,
+		Target:          TargetConfig{Type: "all"},
+		Effects: []*Effect{
+			{Code: "FIRE_DMG", Type: "DMG_CALCULATION", Formula: "2d6 + @INT", DamageType: "fire"},
+			{Code: "BURN", Type: "APPLY_BUFF", BuffCode: "BURNING", TriggerChance: 50},
+		},
+	}
+	bite := &Action{
+		Code:    "BITE",
+		APCost:  1,
+		HitRate: &HitRateConfig{BaseHitRate: 70, UseTargetDefense: true, DefenseAttribute: "DODGE", MinHitRate: 5, MaxHitRate: 95},
+		Target:  TargetConfig{Type: "single"},
+		Effects: []*Effect{{Code: "BITE_DMG", Type: "damage", Formula: "1d6 + STR"}},
+	}
+
+	return &Setup{
+		BattleID:   "battle-1",
+		BattleCode: "GOBLIN_CAMP",
+		Seed:       seed,
+		Heroes: []*Combatant{
+			{ID: "hero-a", Side: SideHero, TeamID: "team-1", Role: "leader", Position: 1, Level: 5, MaxHP: 60, MaxMP: 30,
+				Attributes: map[string]float64{"STR": 6, "AGI": 8, "WIS": 2, "INT": 2, "DODGE": 5}, Actions: []*Action{slash}},
+			{ID: "hero-b", Side: SideHero, TeamID: "team-1", Role: "member", Position: 2, Level: 5, MaxHP: 40, MaxMP: 40,
+				Attributes: map[string]float64{"STR": 2, "AGI": 4, "WIS": 6, "INT": 9, "DODGE": 2}, Actions: []*Action{fireball, slash}},
+		},
+		Monsters: []*Combatant{
+			{ID: "GOBLIN#1", Side: SideMonster, Position: 1, Level: 3, MaxHP: 30,
+				Attributes: map[string]float64{"STR": 3, "AGI": 5, "WIS": 1}, Actions: []*Action{bite},
+				DamageResistances: map[string]float64{"FIRE_RESIST": 0.5}},
+			{ID: "GOBLIN#2", Side: SideMonster, Position: 2, Level: 3, MaxHP: 30,
+				Attributes: map[string]float64{"STR": 3, "AGI": 5, "WIS": 1}, Actions: []*Action{bite}},
+		},
+		Buffs: map[string]*Buff{
+			"BURNING": {Code: "BURNING", StackRule: StackRuleStackable, MaxStacks: 3, Duration: 2, Effects: []*BuffEffect{
+				{Timing: TimingTurnStart, Effect: &Effect{Code: "BURN_TICK", Type: "damage", Params: map[string]float64{"value": 2}}},
+			}},
+		},
+		DerivedAttributes: map[string]string{"INITIATIVE": "AGI*2+WIS"},
+	}
+}
+
+func TestSimulateDeterministic(t *testing.T) {
+	first, err := Simulate(testSetup(42))
+	require.NoError(t, err)
+	second, err := Simulate(testSetup(42))
+	require.NoError(t, err)
+
+	a, err := json.Marshal(first)
+	require.NoError(t, err)
+	b, err := json.Marshal(second)
+	require.NoError(t, err)
+	require.JSONEq(t, string(a), string(b))
+
+	require.Contains(t, []string{StatusVictory, StatusDefeat, StatusDraw}, first.Status)
+	require.Len(t, first.Participants, 2)
+	require.Equal(t, EventBattleStart, first.Events[0].Type)
+	require.Equal(t, EventBattleFinish, first.Events[len(first.Events)-1].Type)
+	for i, e := range first.Events {
+		require.Equal(t, i+1, e.Seq)
+	}
+}
+
+func TestSimulateOutcome(t *testing.T) {
+	setup := testSetup(7)
+	setup.Monsters = setup.Monsters[:1]
+	setup.Monsters[0].MaxHP = 5
+	result, err := Simulate(setup)
+	require.NoError(t, err)
+	require.Equal(t, StatusVictory, result.Status)
+	for _, p := range result.Participants {
+		require.True(t, *p.IsAlive)
+	}
+
+	setup = testSetup(7)
+	for _, m := range setup.Monsters {
+		m.MaxHP = 10000
+		m.Attributes["STR"] = 100
+	}
+	result, err = Simulate(setup)
+	require.NoError(t, err)
+	require.Equal(t, StatusDefeat, result.Status)
+	for _, p := range result.Participants {
+		require.False(t, *p.IsAlive)
+	}
+
+	setup = testSetup(7)
+	setup.MaxRounds = 1
+	for _, m := range setup.Monsters {
+		m.MaxHP = 10000
+	}
+	result, err = Simulate(setup)
+	require.NoError(t, err)
+	require.Equal(t, StatusDraw, result.Status)
+	require.Equal(t, 1, result.Rounds)
+}
+
+func TestSimulateManaAndCooldown(t *testing.T) {
+	setup := testSetup(3)
+	setup.MaxRounds = 4
+	for _, m := range setup.Monsters {
+		m.MaxHP = 10000
+		m.Actions = nil
+	}
+	result, err := Simulate(setup)
+	require.NoError(t, err)
+
+	var casts []int
+	for _, e := range result.Events {
+		if e.Type == EventAction && e.Action == "FIREBALL" {
+			casts = append(casts, e.Round)
+		}
+	}
+	// 冷却 2 回合：第 1、4 回合施放；法力 40，每次消耗 9
+	require.Equal(t, []int{1, 4}, casts)
+}
+
+func TestBuffStacking(t *testing.T) {
+	s, err := newSimulator(testSetup(1))
+	require.NoError(t, err)
+	target := s.units[2]
+	buff := s.setup.Buffs["BURNING"]
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s.applyBuff(s.units[0], target, buff, 0))
+	}
+	require.Len(t, target.buffs, 1)
+	require.Equal(t, 3, target.buffs[0].stacks)
+
+	// 回合开始按层数结算持续伤害
+	require.NoError(t, s.fireBuffEffects(target, TimingTurnStart))
+	require.Equal(t, 24, target.hp)
+
+	// 持续 2 回合后移除
+	require.NoError(t, s.tickBuffs(target))
+	require.Len(t, target.buffs, 1)
+	require.NoError(t, s.tickBuffs(target))
+	require.Empty(t, target.buffs)
+
+	buff.StackRule = StackRuleNoStack
+	require.NoError(t, s.applyBuff(nil, target, buff, 5))
+	require.NoError(t, s.applyBuff(nil, target, buff, 1))
+	require.Equal(t, 5, target.buffs[0].remaining)
+}
+
+func TestBuffAttributeModifier(t *testing.T) {
+	setup := testSetup(1)
+	setup.Buffs["HASTE"] = &Buff{Code: "HASTE", StackRule: StackRuleStackable, Effects: []*BuffEffect{
+		{Timing: TimingOnApply, Effect: &Effect{Code: "HASTE_AGI", Type: "attribute_modifier", AttributeCode: "AGI", Params: map[string]float64{"value": 3}}},
+	}}
+	setup.GlobalBuffs = []GlobalBuff{{BuffCode: "HASTE", Target: "allies"}}
+	s, err := newSimulator(setup)
+	require.NoError(t, err)
+	require.NoError(t, s.applyOpeningBuffs())
+
+	hero := s.units[0]
+	agi, err := s.attributeOrZero(hero, "AGI")
+	require.NoError(t, err)
+	require.Equal(t, 11.0, agi)
+	initiative, err := s.attributeOrZero(hero, "INITIATIVE")
+	require.NoError(t, err)
+	require.Equal(t, 24.0, initiative)
+
+	monster := s.units[2]
+	agi, err = s.attributeOrZero(monster, "AGI")
+	require.NoError(t, err)
+	require.Equal(t, 5.0, agi)
+}
+
+func TestSimulateInvalidConfig(t *testing.T) {
+	setup := testSetup(1)
+	setup.Monsters[0].PassiveBuffs = []string{"MISSING"}
+	_, err := Simulate(setup)
+	require.Error(t, err)
+
+	setup = testSetup(1)
+	setup.Heroes[0].Actions[0].Effects[0].Formula = "1d8 +"
+	_, err = Simulate(setup)
+	require.Error(t, err)
+
+	setup = testSetup(1)
+	setup.Monsters = nil
+	_, err = Simulate(setup)
+	require.Error(t, err)
+}
+
+func TestResultPayloadAndDiff(t *testing.T) {
+	result, err := Simulate(testSetup(42))
+	require.NoError(t, err)
+
+	raw, err := json.Marshal(result.Payload("team-1", "dungeon-1"))
+	require.NoError(t, err)
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &payload))
+	require.Equal(t, "battle-1", payload["battle_id"])
+	participants := payload["participants"].([]interface{})
+	require.Contains(t, participants[0].(map[string]interface{}), "hero_id")
+	require.Contains(t, participants[0].(map[string]interface{}), "is_alive")
+	require.Equal(t, result.Status, payload["result"].(map[string]interface{})["status"])
+
+	require.Empty(t, result.Diff(result.Status, result.Participants))
+	flipped := !*result.Participants[0].IsAlive
+	diffs := result.Diff("draw-not-real", []Participant{{HeroID: "hero-a", IsAlive: &flipped}, {HeroID: "ghost"}})
+	require.Len(t, diffs, 3)
+}
+
+func TestRunBalance(t *testing.T) {
+	report, err := RunBalance(testSetup(100), 20)
+	require.NoError(t, err)
+	require.Equal(t, 20, report.Runs)
+	require.Equal(t, 20, report.Victories+report.Defeats+report.Draws)
+	require.Greater(t, report.AvgRounds, 0.0)
+
+	again, err := RunBalance(testSetup(100), 20)
+	require.NoError(t, err)
+	require.Equal(t, report, again)
+}
@@ -0,0 +1,154 @@
+// Package battle 服务端战斗模拟器
+//
+// 基于 game_config 中 actions / effects / buffs 的配置，按固定种子确定性地推演一场战斗，
+// 输出与战斗结果回调（battleResultPayload）一致的 participants / events 结构，
+// 用于校验外部引擎上报的结果和离线数值平衡模拟。包内不访问数据库，配置由调用方装配为 Setup。
+package battle
+
+// Side 阵营
+type Side string
+
+const (
+	SideHero    Side = "hero"
+	SideMonster Side = "monster"
+)
+
+// 战斗结果状态，与 battle_result_service 保持一致
+const (
+	StatusVictory = "victory"
+	StatusDefeat  = "defeat"
+	StatusDraw    = "draw"
+)
+
+// Buff 叠加规则（buffs.stack_rule）
+const (
+	StackRuleNoStack   = "no_stack"
+	StackRuleStackable = "stackable"
+	StackRuleReplace   = "replace"
+	StackRuleRefresh   = "refresh"
+)
+
+// Buff 效果触发时机（buff_effects.trigger_timing）
+const (
+	TimingOnApply   = "on_apply"
+	TimingTurnStart = "turn_start"
+	TimingTurnEnd   = "turn_end"
+	TimingOnRemove  = "on_remove"
+)
+
+// 效果触发条件（effects.trigger_condition.condition）
+const (
+	TriggerOnHit  = "on_hit"
+	TriggerOnMiss = "on_miss"
+	TriggerAlways = "always"
+)
+
+const (
+	// DefaultMaxRounds 默认最大回合数，超出判定为平局
+	DefaultMaxRounds = 30
+	// DefaultActionPoints 每回合默认行动点
+	DefaultActionPoints = 1
+	// DefaultInitiativeAttribute 默认先攻属性
+	DefaultInitiativeAttribute = "INITIATIVE"
+)
+
+// HitRateConfig 命中率配置（actions.hit_rate_config）
+type HitRateConfig struct {
+	BaseHitRate         float64 `json:"base_hit_rate"`
+	UseAttackerAccuracy bool    `json:"use_attacker_accuracy"`
+	AccuracyAttribute   string  `json:"accuracy_attribute"`
+	AccuracyMultiplier  float64 `json:"accuracy_multiplier"`
+	UseTargetDefense    bool    `json:"use_target_defense"`
+	DefenseAttribute    string  `json:"defense_attribute"`
+	MinHitRate          float64 `json:"min_hit_rate"`
+	MaxHitRate          float64 `json:"max_hit_rate"`
+}
+
+// TargetConfig 目标配置（actions.target_config）
+type TargetConfig struct {
+	Type string `json:"type"` // single / all / self
+	Ally bool   `json:"ally"` // true 表示作用于友方
+}
+
+// Effect 效果配置（effects，已合并 parameter_overrides）
+type Effect struct {
+	Code          string
+	Type          string             // damage / heal / apply_buff / remove_buff / restore_mana / attribute_modifier
+	Formula       string             // calculation_formula，为空时读取 Params["value"]
+	Trigger       string             // on_hit（默认）/ on_miss / always
+	TriggerChance float64            // 触发概率（百分比 0-100），0 视为 100
+	DamageType    string             // 伤害类型，用于匹配目标抗性
+	BuffCode      string             // apply_buff / remove_buff 使用的 Buff
+	BuffDuration  int                // 覆盖 Buff 默认持续回合，0 表示使用 Buff 默认值
+	AttributeCode string             // attribute_modifier 修改的属性
+	Params        map[string]float64 // 其余数值参数
+}
+
+// Action 动作配置（actions + action_effects）
+type Action struct {
+	Code            string
+	APCost          int
+	ManaCost        int
+	ManaCostFormula string // 优先于 ManaCost
+	Cooldown        int
+	UsesPerBattle   int // 0 表示不限
+	SkillLevel      int
+	HitRate         *HitRateConfig // 为空时必中
+	Target          TargetConfig
+	Effects         []*Effect // 按 execution_order 排序
+}
+
+// BuffEffect Buff 关联效果（buff_effects）
+type BuffEffect struct {
+	Timing string
+	Effect *Effect
+}
+
+// Buff Buff 配置（buffs + buff_effects）
+type Buff struct {
+	Code      string
+	StackRule string
+	MaxStacks int
+	Duration  int // 持续回合，<=0 表示永久
+	Effects   []*BuffEffect
+}
+
+// GlobalBuff 战斗全程 Buff（dungeon_battles.global_buffs）
+type GlobalBuff struct {
+	BuffCode string `json:"buff_code"`
+	Target   string `json:"target"` // allies / enemies / all（相对英雄方）
+}
+
+// Combatant 参战单位
+type Combatant struct {
+	ID                  string // 英雄ID，怪物为 monster_code#position
+	Name                string
+	Side                Side
+	TeamID              string
+	Role                string
+	Position            int
+	Level               int
+	MaxHP               int
+	MaxMP               int
+	HPRecovery          int // 每回合开始恢复
+	MPRecovery          int
+	ActionPoints        int // 每回合行动点，0 使用 DefaultActionPoints
+	InitiativeAttribute string
+	Attributes          map[string]float64
+	DamageResistances   map[string]float64 // <TYPE>_RESIST 为减伤比例，<TYPE>_DR 为固定减伤
+	Actions             []*Action          // 顺序即优先级
+	PassiveBuffs        []string
+}
+
+// Setup 一场模拟战斗的完整输入
+type Setup struct {
+	BattleID          string
+	BattleCode        string
+	Seed              int64
+	MaxRounds         int
+	Heroes            []*Combatant
+	Monsters          []*Combatant
+	Buffs             map[string]*Buff
+	GlobalBuffs       []GlobalBuff
+	DerivedAttributes map[string]string // 派生属性公式（hero_attribute_type.calculation_formula），单位未提供时按公式计算
+}
@@ -53,11 +53,19 @@ type GameModule struct {
 	upgradeCostHandler            *handler.UpgradeCostHandler
 	equipmentHandler              *handler.EquipmentHandler
 	equipmentSetHandler           *handler.EquipmentSetHandler
+	gemSocketHandler              *handler.GemSocketHandler
+	itemUseHandler                *handler.ItemUseHandler
+	heroRespecHandler             *handler.HeroRespecHandler
 	inventoryHandler              *handler.InventoryHandler
 	teamHandler                   *handler.TeamHandler
 	teamMemberHandler             *handler.TeamMemberHandler
 	teamWarehouseHandler          *handler.TeamWarehouseHandler
+	teamContributionHandler       *handler.TeamContributionHandler
+	teamRankHandler               *handler.TeamRankHandler
+	teamActivityHandler           *handler.TeamActivityHandler
 	teamDungeonHandler            *handler.TeamDungeonHandler
+	dungeonHandler                *handler.DungeonHandler
+	currencyHandler               *handler.CurrencyHandler
 	teamRPCHandler                *handler.TeamRPCHandler
 	battleResultHandler           *handler.BattleResultHandler
 	teamPermissionMW              *custommiddleware.TeamPermissionMiddleware
@@ -65,6 +73,10 @@ type GameModule struct {
 	teamLeaderTransferTask        *tasks.TeamLeaderTransferTask
 	teamInvitationExpireTask      *tasks.TeamInvitationExpireTask
 	teamPermissionConsistencyTask *tasks.TeamPermissionConsistencyTask
+	periodResetTask               *tasks.PeriodResetTask
+	teamLootRollExpireTask        *tasks.TeamLootRollExpireTask
+	teamContributionAuctionTask   *tasks.TeamContributionAuctionTask
+	teamActivityCleanupTask       *tasks.TeamActivityCleanupTask
 	respWriter                    response.Writer
 }
 
@@ -375,13 +387,21 @@ func (m *GameModule) initServicesAndHandlers() {
 		m.serviceContainer.GetAttributeUpgradeCostRepo(),
 		m.respWriter,
 	)
-	m.equipmentHandler = handler.NewEquipmentHandler(m.db, m.respWriter)
+	m.equipmentHandler = handler.NewEquipmentHandler(m.serviceContainer, m.respWriter)
 	m.equipmentSetHandler = handler.NewEquipmentSetHandler(m.serviceContainer.GetEquipmentSetService(), m.respWriter)
+	m.gemSocketHandler = handler.NewGemSocketHandler(m.serviceContainer, m.respWriter)
+	m.itemUseHandler = handler.NewItemUseHandler(m.serviceContainer, m.respWriter)
+	m.heroRespecHandler = handler.NewHeroRespecHandler(m.serviceContainer, m.respWriter)
 	m.inventoryHandler = handler.NewInventoryHandler(m.db, m.respWriter)
 	m.teamHandler = handler.NewTeamHandler(m.serviceContainer, m.respWriter)
 	m.teamMemberHandler = handler.NewTeamMemberHandler(m.serviceContainer, m.respWriter)
 	m.teamWarehouseHandler = handler.NewTeamWarehouseHandler(m.serviceContainer, m.respWriter)
+	m.teamContributionHandler = handler.NewTeamContributionHandler(m.serviceContainer, m.respWriter)
+	m.teamRankHandler = handler.NewTeamRankHandler(m.serviceContainer, m.respWriter)
+	m.teamActivityHandler = handler.NewTeamActivityHandler(m.serviceContainer, m.respWriter)
 	m.teamDungeonHandler = handler.NewTeamDungeonHandler(m.serviceContainer, m.respWriter)
+	m.dungeonHandler = handler.NewDungeonHandler(m.serviceContainer, m.respWriter)
+	m.currencyHandler = handler.NewCurrencyHandler(m.serviceContainer, m.respWriter)
 	m.teamRPCHandler = handler.NewTeamRPCHandler(m.serviceContainer, m.db)
 	m.battleResultHandler = handler.NewBattleResultHandler(m.serviceContainer, m.respWriter)
 
@@ -415,6 +435,22 @@ func (m *GameModule) startCronTasks() {
 	m.teamInvitationExpireTask = tasks.NewTeamInvitationExpireTask(m.db, logger)
 	m.teamInvitationExpireTask.Start()
 
+	// 战利品投骰超时结算任务
+	m.teamLootRollExpireTask = tasks.NewTeamLootRollExpireTask(m.db, m.serviceContainer.GetTeamWarehouseService(), logger)
+	m.teamLootRollExpireTask.Start()
+
+	// 贡献点竞拍结算任务
+	m.teamContributionAuctionTask = tasks.NewTeamContributionAuctionTask(m.db, m.serviceContainer.GetContributionService(), logger)
+	m.teamContributionAuctionTask.Start()
+
+	// 团队动态清理任务
+	activityRetention, err := tasks.LoadActivityRetention()
+	if err != nil {
+		logger.Warn("【团队定时任务】团队动态保留时长配置非法，使用默认值", "error", err.Error())
+	}
+	m.teamActivityCleanupTask = tasks.NewTeamActivityCleanupTask(m.serviceContainer.GetTeamActivityService(), activityRetention, logger)
+	m.teamActivityCleanupTask.Start()
+
 	// 权限一致性检查任务（仅在 Keto 可用时启动）
 	if m.serviceContainer.GetTeamPermissionService() != nil {
 		m.teamPermissionConsistencyTask = tasks.NewTeamPermissionConsistencyTask(
@@ -428,10 +464,22 @@ func (m *GameModule) startCronTasks() {
 		fmt.Println("[Game Module] Team permission consistency task skipped (Keto not available)")
 	}
 
+	// 3. 日/周周期重置任务（地城挑战次数、世界掉落窗口）
+	resetSchedule, err := tasks.LoadResetSchedule()
+	if err != nil {
+		logger.Warn("【定时任务】周期重置配置非法，使用默认值", "error", err.Error())
+	}
+	m.periodResetTask = tasks.NewPeriodResetTask(m.db, resetSchedule, logger)
+	m.periodResetTask.Start()
+
 	fmt.Println("[Game Module] Cron tasks started successfully:")
 	fmt.Println("  ✓ Cleanup Task (每天凌晨2点)")
 	fmt.Println("  ✓ Team Leader Transfer Task (每小时)")
 	fmt.Println("  ✓ Team Invitation Expire Task (每小时)")
+	fmt.Println("  ✓ Team Loot Roll Expire Task (每分钟)")
+	fmt.Println("  ✓ Team Contribution Auction Task (每分钟)")
+	fmt.Printf("  ✓ Team Activity Cleanup Task (每天凌晨3点30分，保留 %d 天)\n", int(activityRetention.Hours()/24))
+	fmt.Printf("  ✓ Period Reset Task (每天 %02d:00 %s)\n", resetSchedule.Hour, resetSchedule.Location)
 }
 
 // setupRoutes sets up HTTP routes
@@ -463,14 +511,19 @@ func (m *GameModule) setupRoutes() {
 		heroes.Use(custommiddleware.AuthMiddleware(m.respWriter, logger, m.db))
 		{
 			// 英雄管理
-			heroes.POST("", m.heroHandler.CreateHero)                                 // 创建英雄
-			heroes.GET("", m.heroHandler.GetUserHeroes)                               // 获取用户英雄列表
-			heroes.GET("/:hero_id", m.heroHandler.GetHero)                            // 获取英雄详情
-			heroes.GET("/:hero_id/full", m.heroHandler.GetHeroFull)                   // 获取英雄完整信息（含职业、属性、技能）
-			heroes.GET("/:hero_id/advancement-check", m.heroHandler.CheckAdvancement) // 检查职业进阶条件
-			heroes.POST("/:hero_id/experience", m.heroHandler.AddExperience)          // 增加经验（测试用）
-			heroes.POST("/:hero_id/advance", m.heroHandler.AdvanceClass)              // 职业进阶
-			heroes.POST("/:hero_id/transfer", m.heroHandler.TransferClass)            // 职业转职
+			heroes.POST("", m.heroHandler.CreateHero)                                     // 创建英雄
+			heroes.GET("", m.heroHandler.GetUserHeroes)                                   // 获取用户英雄列表
+			heroes.GET("/:hero_id", m.heroHandler.GetHero)                                // 获取英雄详情
+			heroes.GET("/:hero_id/full", m.heroHandler.GetHeroFull)                       // 获取英雄完整信息（含职业、属性、技能）
+			heroes.GET("/:hero_id/advancement-check", m.heroHandler.CheckAdvancement)     // 检查职业进阶条件
+			heroes.POST("/:hero_id/experience", m.heroHandler.AddExperience)              // 增加经验（已弃用，测试用，需 ENABLE_TEST_EXPERIENCE_API=true）
+			heroes.GET("/:hero_id/experience/awards", m.heroHandler.ListExperienceAwards) // 经验获取记录
+			heroes.POST("/:hero_id/advance", m.heroHandler.AdvanceClass)                  // 职业进阶
+			heroes.POST("/:hero_id/transfer", m.heroHandler.TransferClass)                // 职业转职
+
+			// 货币
+			heroes.GET("/:hero_id/currencies", m.currencyHandler.GetHeroCurrencies)                         // 获取货币余额
+			heroes.GET("/:hero_id/currencies/transactions", m.currencyHandler.ListHeroCurrencyTransactions) // 获取货币流水
 
 			// 属性管理
 			heroes.GET("/:hero_id/attributes", m.heroAttributeHandler.GetComputedAttributes)       // 获取属性
@@ -484,6 +537,11 @@ func (m *GameModule) setupRoutes() {
 			heroes.POST("/:hero_id/skills/:skill_id/upgrade", m.heroSkillHandler.UpgradeSkill)   // 升级技能
 			heroes.POST("/:hero_id/skills/:skill_id/rollback", m.heroSkillHandler.RollbackSkill) // 回退技能
 
+			// 洗点
+			heroes.GET("/:hero_id/respec", m.heroRespecHandler.GetRespecQuote)            // 查询洗点消耗
+			heroes.POST("/:hero_id/respec", m.heroRespecHandler.Respec)                   // 洗点（重置属性与技能）
+			heroes.GET("/:hero_id/respec/history", m.heroRespecHandler.ListRespecHistory) // 洗点记录
+
 			// 英雄激活管理
 			heroes.PATCH("/:hero_id/activate", m.heroActivationHandler.ActivateHero)     // 激活英雄
 			heroes.PATCH("/:hero_id/deactivate", m.heroActivationHandler.DeactivateHero) // 停用英雄
@@ -511,6 +569,14 @@ func (m *GameModule) setupRoutes() {
 			skills.GET("/:skill_id/full", m.skillDetailHandler.GetSkillFull)         // 获取技能完整信息（深度关联）
 		}
 
+		// Dungeon catalog routes (需要认证)
+		dungeons := game.Group("/dungeons")
+		dungeons.Use(custommiddleware.AuthMiddleware(m.respWriter, logger, m.db))
+		{
+			dungeons.GET("", m.dungeonHandler.ListDungeons)           // 获取地城列表（含开放状态与下次开放时间）
+			dungeons.GET("/:dungeon_id", m.dungeonHandler.GetDungeon) // 获取地城详情（含房间概览与团队资格）
+		}
+
 		// Upgrade cost routes (公开访问 - 配置数据)
 		costs := game.Group("")
 		{
@@ -527,21 +593,30 @@ func (m *GameModule) setupRoutes() {
 			costs.GET("/attribute-upgrade-costs/:point_number", m.upgradeCostHandler.GetAttributeUpgradeCost) // 获取指定点数升级消耗
 		}
 
-		// // Equipment routes (需要认证)
-		// equipment := game.Group("/equipment")
-		// equipment.Use(custommiddleware.AuthMiddleware(m.respWriter, logger))
-		// {
-		// 	equipment.POST("/equip", m.equipmentHandler.EquipItem)                      // 穿戴装备
-		// 	equipment.POST("/unequip", m.equipmentHandler.UnequipItem)                  // 卸下装备
-		// 	equipment.GET("/equipped/:hero_id", m.equipmentHandler.GetEquippedItems)    // 查询已装备物品
-		// 	equipment.GET("/slots/:hero_id", m.equipmentHandler.GetEquipmentSlots)      // 查询装备槽位
-		// 	equipment.GET("/bonus/:hero_id", m.equipmentHandler.GetEquipmentBonus)      // 查询装备属性加成
-
-		// 	// Equipment Set routes (套装相关)
-		// 	equipment.GET("/sets", m.equipmentSetHandler.ListSets)                      // 查询可用套装列表
-		// 	equipment.GET("/sets/:set_id", m.equipmentSetHandler.GetSetInfo)            // 查询套装详细信息
-		// 	equipment.GET("/sets/active/:hero_id", m.equipmentSetHandler.GetActiveSets) // 查询英雄激活的套装
-		// }
+		// Equipment routes (需要认证 + 英雄上下文，只能操作当前英雄)
+		equipment := game.Group("/equipment")
+		equipment.Use(custommiddleware.AuthMiddleware(m.respWriter, logger, m.db))
+		equipment.Use(custommiddleware.HeroMiddleware(m.db, m.respWriter, logger))
+		{
+			equipment.POST("/equip", m.equipmentHandler.EquipItem)                   // 穿戴装备
+			equipment.POST("/unequip", m.equipmentHandler.UnequipItem)               // 卸下装备
+			equipment.POST("/enhance", m.equipmentHandler.EnhanceItem)               // 强化装备
+			equipment.POST("/repair", m.equipmentHandler.RepairItem)                 // 修理装备
+			equipment.GET("/equipped/:hero_id", m.equipmentHandler.GetEquippedItems) // 查询已装备物品
+			equipment.GET("/slots/:hero_id", m.equipmentHandler.GetEquipmentSlots)   // 查询装备槽位
+			equipment.GET("/bonus/:hero_id", m.equipmentHandler.GetEquipmentBonus)   // 查询装备属性加成
+
+			// Equipment Set routes (套装相关)
+			equipment.GET("/sets", m.equipmentSetHandler.ListSets)                      // 查询可用套装列表
+			equipment.GET("/sets/active/:hero_id", m.equipmentSetHandler.GetActiveSets) // 查询英雄激活的套装
+			equipment.GET("/sets/:set_id", m.equipmentSetHandler.GetSetInfo)            // 查询套装详细信息
+
+			// Gem socket routes (宝石镶嵌)
+			equipment.POST("/gems/socket", m.gemSocketHandler.SocketGem)            // 镶嵌宝石
+			equipment.POST("/gems/replace", m.gemSocketHandler.ReplaceGem)          // 替换宝石
+			equipment.POST("/gems/unsocket", m.gemSocketHandler.UnsocketGem)        // 拆卸宝石
+			equipment.GET("/gems/:item_instance_id", m.gemSocketHandler.GetSockets) // 查询装备孔位
+		}
 
 		// Inventory routes (需要认证)
 		inventory := game.Group("/inventory")
@@ -551,6 +626,9 @@ func (m *GameModule) setupRoutes() {
 			inventory.POST("/move", m.inventoryHandler.MoveItem)       // 移动物品
 			inventory.POST("/discard", m.inventoryHandler.DiscardItem) // 丢弃物品
 			inventory.POST("/sort", m.inventoryHandler.SortInventory)  // 整理背包
+
+			// 使用物品需要英雄上下文
+			inventory.POST("/use", m.itemUseHandler.UseItem, custommiddleware.HeroMiddleware(m.db, m.respWriter, logger)) // 使用物品
 		}
 
 		//Team routes (需要认证 + 英雄上下文)
@@ -568,16 +646,16 @@ func (m *GameModule) setupRoutes() {
 				teams.GET("/:team_id", m.teamHandler.GetTeam)
 			}
 
-			// 更新团队信息（只有队长可以）
+			// 更新团队信息（update_team_info）
 			if m.teamPermissionMW != nil {
-				teams.PUT("/:team_id", m.teamHandler.UpdateTeamInfo, m.teamPermissionMW.RequireTeamLeader)
+				teams.PUT("/:team_id", m.teamHandler.UpdateTeamInfo, m.teamPermissionMW.RequireTeamPermission(service.TeamPermUpdateTeamInfo))
 			} else {
 				teams.PUT("/:team_id", m.teamHandler.UpdateTeamInfo)
 			}
 
-			// 解散团队（只有队长可以）
+			// 解散团队（disband_team，仅队长）
 			if m.teamPermissionMW != nil {
-				teams.POST("/:team_id/disband", m.teamHandler.DisbandTeam, m.teamPermissionMW.RequireTeamLeader)
+				teams.POST("/:team_id/disband", m.teamHandler.DisbandTeam, m.teamPermissionMW.RequireTeamPermission(service.TeamPermDisbandTeam))
 			} else {
 				teams.POST("/:team_id/disband", m.teamHandler.DisbandTeam)
 			}
@@ -589,26 +667,50 @@ func (m *GameModule) setupRoutes() {
 				teams.POST("/:team_id/leave", m.teamHandler.LeaveTeam)
 			}
 
+			// 职阶与权限（查看需要是团队成员，管理需 manage_ranks）
+			if m.teamPermissionMW != nil {
+				teams.GET("/:team_id/ranks", m.teamRankHandler.ListRanks, m.teamPermissionMW.RequireTeamMember)
+				teams.POST("/:team_id/ranks", m.teamRankHandler.CreateRank, m.teamPermissionMW.RequireTeamPermission(service.TeamPermManageRanks))
+				teams.POST("/:team_id/ranks/assign", m.teamRankHandler.AssignRank, m.teamPermissionMW.RequireTeamPermission(service.TeamPermManageRanks))
+				teams.PUT("/:team_id/ranks/:rank_id", m.teamRankHandler.UpdateRank, m.teamPermissionMW.RequireTeamPermission(service.TeamPermManageRanks))
+				teams.DELETE("/:team_id/ranks/:rank_id", m.teamRankHandler.DeleteRank, m.teamPermissionMW.RequireTeamPermission(service.TeamPermManageRanks))
+				teams.GET("/:team_id/permissions", m.teamRankHandler.GetMyPermissions, m.teamPermissionMW.RequireTeamMember)
+			} else {
+				teams.GET("/:team_id/ranks", m.teamRankHandler.ListRanks)
+				teams.POST("/:team_id/ranks", m.teamRankHandler.CreateRank)
+				teams.POST("/:team_id/ranks/assign", m.teamRankHandler.AssignRank)
+				teams.PUT("/:team_id/ranks/:rank_id", m.teamRankHandler.UpdateRank)
+				teams.DELETE("/:team_id/ranks/:rank_id", m.teamRankHandler.DeleteRank)
+				teams.GET("/:team_id/permissions", m.teamRankHandler.GetMyPermissions)
+			}
+
+			// 团队动态（需要 view_team_info）
+			if m.teamPermissionMW != nil {
+				teams.GET("/:team_id/activity", m.teamActivityHandler.ListActivity, m.teamPermissionMW.RequireTeamPermission(service.TeamPermViewTeamInfo))
+			} else {
+				teams.GET("/:team_id/activity", m.teamActivityHandler.ListActivity)
+			}
+
 			// 成员管理
 			teams.POST("/join/apply", m.teamMemberHandler.ApplyToJoin) // 申请加入团队（任何认证用户都可以）
 
-			// 审批加入申请（管理员或队长）
+			// 审批加入申请（approve_join_request）
 			if m.teamPermissionMW != nil {
-				teams.POST("/join/approve", m.teamMemberHandler.ApproveJoinRequest, m.teamPermissionMW.RequireTeamAdmin)
+				teams.POST("/join/approve", m.teamMemberHandler.ApproveJoinRequest, m.teamPermissionMW.RequireTeamPermission(service.TeamPermApproveJoinRequest))
 			} else {
 				teams.POST("/join/approve", m.teamMemberHandler.ApproveJoinRequest)
 			}
 
-			// 邀请成员（任何团队成员都可以）
+			// 邀请成员（invite_member，普通成员默认拥有）
 			if m.teamPermissionMW != nil {
-				teams.POST("/invite", m.teamMemberHandler.InviteMember, m.teamPermissionMW.RequireTeamMember)
+				teams.POST("/invite", m.teamMemberHandler.InviteMember, m.teamPermissionMW.RequireTeamPermission(service.TeamPermInviteMember))
 			} else {
 				teams.POST("/invite", m.teamMemberHandler.InviteMember)
 			}
 
-			// 审批邀请（管理员或队长）
+			// 审批邀请（approve_invitation）
 			if m.teamPermissionMW != nil {
-				teams.POST("/invite/approve", m.teamMemberHandler.ApproveInvitation, m.teamPermissionMW.RequireTeamAdmin)
+				teams.POST("/invite/approve", m.teamMemberHandler.ApproveInvitation, m.teamPermissionMW.RequireTeamPermission(service.TeamPermApproveInvitation))
 			} else {
 				teams.POST("/invite/approve", m.teamMemberHandler.ApproveInvitation)
 			}
@@ -616,23 +718,23 @@ func (m *GameModule) setupRoutes() {
 			teams.POST("/invite/accept", m.teamMemberHandler.AcceptInvitation) // 接受邀请（被邀请人）
 			teams.POST("/invite/reject", m.teamMemberHandler.RejectInvitation) // 拒绝邀请（被邀请人）
 
-			// 踢出成员（管理员或队长）
+			// 踢出成员（kick_member，只能踢出等级更低的成员）
 			if m.teamPermissionMW != nil {
-				teams.POST("/members/kick", m.teamMemberHandler.KickMember, m.teamPermissionMW.RequireTeamAdmin)
+				teams.POST("/members/kick", m.teamMemberHandler.KickMember, m.teamPermissionMW.RequireTeamPermission(service.TeamPermKickMember))
 			} else {
 				teams.POST("/members/kick", m.teamMemberHandler.KickMember)
 			}
 
-			// 任命管理员（只有队长可以）
+			// 任命管理员（appoint_admin）
 			if m.teamPermissionMW != nil {
-				teams.POST("/members/promote", m.teamMemberHandler.PromoteToAdmin, m.teamPermissionMW.RequireTeamLeader)
+				teams.POST("/members/promote", m.teamMemberHandler.PromoteToAdmin, m.teamPermissionMW.RequireTeamPermission(service.TeamPermAppointAdmin))
 			} else {
 				teams.POST("/members/promote", m.teamMemberHandler.PromoteToAdmin)
 			}
 
-			// 撤销管理员（只有队长可以）
+			// 撤销管理员（demote_admin）
 			if m.teamPermissionMW != nil {
-				teams.POST("/members/demote", m.teamMemberHandler.DemoteAdmin, m.teamPermissionMW.RequireTeamLeader)
+				teams.POST("/members/demote", m.teamMemberHandler.DemoteAdmin, m.teamPermissionMW.RequireTeamPermission(service.TeamPermDemoteAdmin))
 			} else {
 				teams.POST("/members/demote", m.teamMemberHandler.DemoteAdmin)
 			}
@@ -646,10 +748,10 @@ func (m *GameModule) setupRoutes() {
 				teams.GET("/:team_id/warehouse", m.teamWarehouseHandler.GetWarehouse)
 			}
 
-			// 分配金币（管理员或队长）
+			// 分配金币与物品（distribute_loot）
 			if m.teamPermissionMW != nil {
-				teams.POST("/:team_id/warehouse/distribute-gold", m.teamWarehouseHandler.DistributeGold, m.teamPermissionMW.RequireTeamAdmin)
-				teams.POST("/:team_id/warehouse/distribute-items", m.teamWarehouseHandler.DistributeItems, m.teamPermissionMW.RequireTeamAdmin)
+				teams.POST("/:team_id/warehouse/distribute-gold", m.teamWarehouseHandler.DistributeGold, m.teamPermissionMW.RequireTeamPermission(service.TeamPermDistributeLoot))
+				teams.POST("/:team_id/warehouse/distribute-items", m.teamWarehouseHandler.DistributeItems, m.teamPermissionMW.RequireTeamPermission(service.TeamPermDistributeLoot))
 			} else {
 				teams.POST("/:team_id/warehouse/distribute-gold", m.teamWarehouseHandler.DistributeGold)
 				teams.POST("/:team_id/warehouse/distribute-items", m.teamWarehouseHandler.DistributeItems)
@@ -658,22 +760,78 @@ func (m *GameModule) setupRoutes() {
 			// 查看仓库物品（需要是团队成员）
 			if m.teamPermissionMW != nil {
 				teams.GET("/:team_id/warehouse/items", m.teamWarehouseHandler.GetWarehouseItems, m.teamPermissionMW.RequireTeamMember)
-				teams.GET("/:team_id/warehouse/distributions", m.teamWarehouseHandler.GetDistributionHistory, m.teamPermissionMW.RequireTeamAdmin)
+				teams.GET("/:team_id/warehouse/distributions", m.teamWarehouseHandler.GetDistributionHistory, m.teamPermissionMW.RequireTeamPermission(service.TeamPermViewWarehouse))
 			} else {
 				teams.GET("/:team_id/warehouse/items", m.teamWarehouseHandler.GetWarehouseItems)
 				teams.GET("/:team_id/warehouse/distributions", m.teamWarehouseHandler.GetDistributionHistory)
 			}
 
+			// 自动分配策略与投骰（查看、投骰需要是团队成员，修改策略需 distribute_loot）
+			if m.teamPermissionMW != nil {
+				teams.GET("/:team_id/warehouse/loot-policy", m.teamWarehouseHandler.GetLootPolicy, m.teamPermissionMW.RequireTeamMember)
+				teams.PUT("/:team_id/warehouse/loot-policy", m.teamWarehouseHandler.UpdateLootPolicy, m.teamPermissionMW.RequireTeamPermission(service.TeamPermDistributeLoot))
+				teams.GET("/:team_id/warehouse/rolls", m.teamWarehouseHandler.ListLootRolls, m.teamPermissionMW.RequireTeamMember)
+				teams.POST("/:team_id/warehouse/rolls/:roll_id", m.teamWarehouseHandler.VoteLootRoll, m.teamPermissionMW.RequireTeamMember)
+			} else {
+				teams.GET("/:team_id/warehouse/loot-policy", m.teamWarehouseHandler.GetLootPolicy)
+				teams.PUT("/:team_id/warehouse/loot-policy", m.teamWarehouseHandler.UpdateLootPolicy)
+				teams.GET("/:team_id/warehouse/rolls", m.teamWarehouseHandler.ListLootRolls)
+				teams.POST("/:team_id/warehouse/rolls/:roll_id", m.teamWarehouseHandler.VoteLootRoll)
+			}
+
+			// 成员捐献与取用申请（捐献、申请、撤回需要是团队成员，审批需 distribute_loot）
+			if m.teamPermissionMW != nil {
+				teams.POST("/:team_id/warehouse/deposits/gold", m.teamWarehouseHandler.DepositGold, m.teamPermissionMW.RequireTeamMember)
+				teams.POST("/:team_id/warehouse/deposits/items", m.teamWarehouseHandler.DepositItems, m.teamPermissionMW.RequireTeamMember)
+				teams.GET("/:team_id/warehouse/withdrawals", m.teamWarehouseHandler.ListWithdrawals, m.teamPermissionMW.RequireTeamMember)
+				teams.POST("/:team_id/warehouse/withdrawals", m.teamWarehouseHandler.CreateWithdrawal, m.teamPermissionMW.RequireTeamMember)
+				teams.POST("/:team_id/warehouse/withdrawals/:request_id/review", m.teamWarehouseHandler.ReviewWithdrawal, m.teamPermissionMW.RequireTeamPermission(service.TeamPermDistributeLoot))
+				teams.POST("/:team_id/warehouse/withdrawals/:request_id/cancel", m.teamWarehouseHandler.CancelWithdrawal, m.teamPermissionMW.RequireTeamMember)
+			} else {
+				teams.POST("/:team_id/warehouse/deposits/gold", m.teamWarehouseHandler.DepositGold)
+				teams.POST("/:team_id/warehouse/deposits/items", m.teamWarehouseHandler.DepositItems)
+				teams.GET("/:team_id/warehouse/withdrawals", m.teamWarehouseHandler.ListWithdrawals)
+				teams.POST("/:team_id/warehouse/withdrawals", m.teamWarehouseHandler.CreateWithdrawal)
+				teams.POST("/:team_id/warehouse/withdrawals/:request_id/review", m.teamWarehouseHandler.ReviewWithdrawal)
+				teams.POST("/:team_id/warehouse/withdrawals/:request_id/cancel", m.teamWarehouseHandler.CancelWithdrawal)
+			}
+
+			// 贡献点（查看、出价需要是团队成员，发放与配置需 manage_contribution，竞拍管理需 distribute_loot）
+			if m.teamPermissionMW != nil {
+				teams.GET("/:team_id/contribution", m.teamContributionHandler.GetStandings, m.teamPermissionMW.RequireTeamMember)
+				teams.GET("/:team_id/contribution/ledger", m.teamContributionHandler.GetLedger, m.teamPermissionMW.RequireTeamMember)
+				teams.POST("/:team_id/contribution/grants", m.teamContributionHandler.GrantPoints, m.teamPermissionMW.RequireTeamPermission(service.TeamPermManageContribution))
+				teams.GET("/:team_id/contribution/settings", m.teamContributionHandler.GetSettings, m.teamPermissionMW.RequireTeamMember)
+				teams.PUT("/:team_id/contribution/settings", m.teamContributionHandler.UpdateSettings, m.teamPermissionMW.RequireTeamPermission(service.TeamPermManageContribution))
+				teams.GET("/:team_id/contribution/auctions", m.teamContributionHandler.ListAuctions, m.teamPermissionMW.RequireTeamMember)
+				teams.POST("/:team_id/contribution/auctions", m.teamContributionHandler.CreateAuction, m.teamPermissionMW.RequireTeamPermission(service.TeamPermDistributeLoot))
+				teams.POST("/:team_id/contribution/auctions/:auction_id/bids", m.teamContributionHandler.PlaceBid, m.teamPermissionMW.RequireTeamMember)
+				teams.POST("/:team_id/contribution/auctions/:auction_id/cancel", m.teamContributionHandler.CancelAuction, m.teamPermissionMW.RequireTeamPermission(service.TeamPermDistributeLoot))
+			} else {
+				teams.GET("/:team_id/contribution", m.teamContributionHandler.GetStandings)
+				teams.GET("/:team_id/contribution/ledger", m.teamContributionHandler.GetLedger)
+				teams.POST("/:team_id/contribution/grants", m.teamContributionHandler.GrantPoints)
+				teams.GET("/:team_id/contribution/settings", m.teamContributionHandler.GetSettings)
+				teams.PUT("/:team_id/contribution/settings", m.teamContributionHandler.UpdateSettings)
+				teams.GET("/:team_id/contribution/auctions", m.teamContributionHandler.ListAuctions)
+				teams.POST("/:team_id/contribution/auctions", m.teamContributionHandler.CreateAuction)
+				teams.POST("/:team_id/contribution/auctions/:auction_id/bids", m.teamContributionHandler.PlaceBid)
+				teams.POST("/:team_id/contribution/auctions/:auction_id/cancel", m.teamContributionHandler.CancelAuction)
+			}
+
 			// 地城路由
 			if m.teamDungeonHandler != nil {
 				if m.teamPermissionMW != nil {
-					teams.POST("/:team_id/dungeons/select", m.teamDungeonHandler.SelectDungeon, m.teamPermissionMW.RequireTeamAdmin)
-					teams.POST("/:team_id/dungeons/enter", m.teamDungeonHandler.EnterDungeon, m.teamPermissionMW.RequireTeamAdmin)
+					teams.POST("/:team_id/dungeons/select", m.teamDungeonHandler.SelectDungeon, m.teamPermissionMW.RequireTeamPermission(service.TeamPermSelectDungeon))
+					teams.POST("/:team_id/dungeons/enter", m.teamDungeonHandler.EnterDungeon, m.teamPermissionMW.RequireTeamPermission(service.TeamPermEnterDungeon))
 					teams.GET("/:team_id/dungeons/progress", m.teamDungeonHandler.GetDungeonProgress, m.teamPermissionMW.RequireTeamMember)
 					teams.POST("/:team_id/dungeons/complete", m.teamDungeonHandler.CompleteDungeon, m.teamPermissionMW.RequireTeamAdmin)
-					teams.POST("/:team_id/dungeons/fail", m.teamDungeonHandler.FailDungeon, m.teamPermissionMW.RequireTeamAdmin)
-					teams.POST("/:team_id/dungeons/abandon", m.teamDungeonHandler.AbandonDungeon, m.teamPermissionMW.RequireTeamAdmin)
+					teams.POST("/:team_id/dungeons/fail", m.teamDungeonHandler.FailDungeon, m.teamPermissionMW.RequireTeamPermission(service.TeamPermEnterDungeon))
+					teams.POST("/:team_id/dungeons/abandon", m.teamDungeonHandler.AbandonDungeon, m.teamPermissionMW.RequireTeamPermission(service.TeamPermAbandonDungeon))
 					teams.GET("/:team_id/dungeons/history", m.teamDungeonHandler.GetDungeonHistory, m.teamPermissionMW.RequireTeamMember)
+					teams.GET("/:team_id/dungeons/rooms/current", m.teamDungeonHandler.GetCurrentRoom, m.teamPermissionMW.RequireTeamMember)
+					teams.POST("/:team_id/dungeons/rooms/advance", m.teamDungeonHandler.AdvanceRoom, m.teamPermissionMW.RequireTeamPermission(service.TeamPermEnterDungeon))
+					teams.POST("/:team_id/dungeons/rooms/resolve", m.teamDungeonHandler.ResolveRoom, m.teamPermissionMW.RequireTeamPermission(service.TeamPermEnterDungeon))
 				} else {
 					teams.POST("/:team_id/dungeons/select", m.teamDungeonHandler.SelectDungeon)
 					teams.POST("/:team_id/dungeons/enter", m.teamDungeonHandler.EnterDungeon)
@@ -682,6 +840,9 @@ func (m *GameModule) setupRoutes() {
 					teams.POST("/:team_id/dungeons/fail", m.teamDungeonHandler.FailDungeon)
 					teams.POST("/:team_id/dungeons/abandon", m.teamDungeonHandler.AbandonDungeon)
 					teams.GET("/:team_id/dungeons/history", m.teamDungeonHandler.GetDungeonHistory)
+					teams.GET("/:team_id/dungeons/rooms/current", m.teamDungeonHandler.GetCurrentRoom)
+					teams.POST("/:team_id/dungeons/rooms/advance", m.teamDungeonHandler.AdvanceRoom)
+					teams.POST("/:team_id/dungeons/rooms/resolve", m.teamDungeonHandler.ResolveRoom)
 				}
 			}
 		}
@@ -692,6 +853,7 @@ func (m *GameModule) setupRoutes() {
 	{
 		battles := internalGroup.Group("/battles")
 		battles.POST("/result", m.battleResultHandler.ReportResult)
+		battles.GET("/heroes/:hero_id/attributes", m.battleResultHandler.GetHeroAttributes)
 	}
 
 	// Swagger UI
@@ -748,6 +910,18 @@ func (m *GameModule) Run(closeSig chan bool) {
 // OnDestroy module destroy
 func (m *GameModule) OnDestroy() {
 	// Stop cron tasks
+	if m.periodResetTask != nil {
+		m.periodResetTask.Stop()
+	}
+	if m.teamLootRollExpireTask != nil {
+		m.teamLootRollExpireTask.Stop()
+	}
+	if m.teamContributionAuctionTask != nil {
+		m.teamContributionAuctionTask.Stop()
+	}
+	if m.teamActivityCleanupTask != nil {
+		m.teamActivityCleanupTask.Stop()
+	}
 	if m.cleanupTask != nil {
 		m.cleanupTask.Stop()
 		fmt.Println("[Game Module] Cron tasks stopped")
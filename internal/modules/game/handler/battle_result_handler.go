@@ -1,33 +1,62 @@
 package handler
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
 	"tsu-self/internal/modules/game/service"
 	"tsu-self/internal/pkg/response"
+	"tsu-self/internal/pkg/security"
 )
 
 // BattleResultHandler 接收战斗引擎的回调。
+//
+// 配置 BATTLE_RESULT_HMAC_KEYS（"key_id:secret,..."，可同时配置多把用于轮换）后，
+// 请求必须携带 HMAC 签名、时间戳与 nonce；未配置时回退到 BATTLE_RESULT_TOKEN 静态令牌。
+// 两者都未配置时拒绝所有回调。
 type BattleResultHandler struct {
 	battleService *service.BattleResultService
+	attrService   *service.HeroAttributeService
 	respWriter    response.Writer
 	token         string
+	verifier      *security.HMACVerifier
+	verifierErr   error // 密钥配置错误时拒绝所有请求
 }
 
 // NewBattleResultHandler 构造函数。
 func NewBattleResultHandler(sc *service.ServiceContainer, respWriter response.Writer) *BattleResultHandler {
-	var token string
-	if t := os.Getenv("BATTLE_RESULT_TOKEN"); t != "" {
-		token = t
-	}
-	return &BattleResultHandler{
+	h := &BattleResultHandler{
 		battleService: sc.GetBattleResultService(),
+		attrService:   sc.GetHeroAttributeService(),
 		respWriter:    respWriter,
-		token:         token,
+		token:         os.Getenv("BATTLE_RESULT_TOKEN"),
+	}
+
+	if spec := os.Getenv("BATTLE_RESULT_HMAC_KEYS"); spec != "" {
+		keys, err := security.ParseHMACKeys(spec)
+		if err != nil {
+			fmt.Printf("[Game Module] Invalid BATTLE_RESULT_HMAC_KEYS, battle callbacks will be rejected: %v\n", err)
+			h.verifierErr = err
+			return h
+		}
+		maxSkew := security.DefaultSignatureMaxSkew
+		if v := os.Getenv("BATTLE_RESULT_SIGNATURE_MAX_SKEW"); v != "" {
+			if d, err := time.ParseDuration(v); err == nil && d > 0 {
+				maxSkew = d
+			}
+		}
+		h.verifier = security.NewHMACVerifier(keys, maxSkew, sc.GetBattleCallbackNonceRepo())
 	}
+	if !h.verifier.Enabled() && h.token == "" {
+		fmt.Printf("[Game Module] Neither BATTLE_RESULT_HMAC_KEYS nor BATTLE_RESULT_TOKEN is set, battle callbacks will be rejected\n")
+	}
+	return h
 }
 
 type battleResultPayload struct {
@@ -40,9 +69,10 @@ type battleResultPayload struct {
 }
 
 type battleParticipant struct {
-	HeroID string `json:"hero_id"`
-	TeamID string `json:"team_id"`
-	Role   string `json:"role"`
+	HeroID  string `json:"hero_id"`
+	TeamID  string `json:"team_id"`
+	Role    string `json:"role"`
+	IsAlive *bool  `json:"is_alive,omitempty"`
 }
 
 type battleResultInfo struct {
@@ -51,9 +81,11 @@ type battleResultInfo struct {
 }
 
 type lootContext struct {
-	Type      string `json:"type"`
-	TeamID    string `json:"team_id"`
-	DungeonID string `json:"dungeon_id"`
+	Type       string `json:"type"`
+	TeamID     string `json:"team_id"`
+	DungeonID  string `json:"dungeon_id"`
+	ProgressID string `json:"progress_id"` // 房间战斗所属的地城进度
+	RoomID     string `json:"room_id"`     // 房间战斗所属的房间
 }
 
 type battleLootPayload struct {
@@ -72,8 +104,8 @@ func (h *BattleResultHandler) ReportResult(c echo.Context) error {
 	if h.battleService == nil {
 		return response.EchoError(c, h.respWriter, echo.NewHTTPError(http.StatusInternalServerError, "battle result service unavailable"))
 	}
-	if h.token != "" && c.Request().Header.Get("X-Battle-Token") != h.token {
-		return response.EchoUnauthorized(c, h.respWriter, "battle token invalid")
+	if err := h.authorize(c); err != nil {
+		return response.EchoUnauthorized(c, h.respWriter, err.Error())
 	}
 
 	var payload battleResultPayload
@@ -95,6 +127,58 @@ func (h *BattleResultHandler) ReportResult(c echo.Context) error {
 	return response.EchoOK(c, h.respWriter, toDungeonProgressResponse(progress))
 }
 
+// GetHeroAttributes 战斗引擎查询英雄最终属性（与英雄面板同源）。
+func (h *BattleResultHandler) GetHeroAttributes(c echo.Context) error {
+	if h.attrService == nil {
+		return response.EchoError(c, h.respWriter, echo.NewHTTPError(http.StatusInternalServerError, "hero attribute service unavailable"))
+	}
+	if err := h.authorize(c); err != nil {
+		return response.EchoUnauthorized(c, h.respWriter, err.Error())
+	}
+
+	heroID := c.Param("hero_id")
+	if heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "hero_id is required")
+	}
+
+	attrs, err := h.attrService.GetComputedAttributes(c.Request().Context(), heroID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, toComputedAttributeResponses(attrs))
+}
+
+// authorize 校验回调来源：配置了签名密钥时校验 HMAC 签名，否则校验静态令牌
+func (h *BattleResultHandler) authorize(c echo.Context) error {
+	if h.verifierErr != nil {
+		return fmt.Errorf("battle signature misconfigured")
+	}
+	if !h.verifier.Enabled() {
+		if h.token == "" {
+			return fmt.Errorf("battle callback authentication not configured")
+		}
+		if c.Request().Header.Get("X-Battle-Token") == h.token {
+			return nil
+		}
+		return fmt.Errorf("battle token invalid")
+	}
+
+	// 读取请求体用于验签，再放回供后续 Bind 使用
+	req := c.Request()
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return fmt.Errorf("read battle payload failed")
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if err := h.verifier.Verify(req.Context(), req, body); err != nil {
+		return fmt.Errorf("battle signature invalid: %w", err)
+	}
+	return nil
+}
+
 func (p *battleResultPayload) firstHeroID() string {
 	if p == nil {
 		return ""
@@ -131,11 +215,42 @@ func (p *battleResultPayload) toServiceInput() *service.BattleResultInput {
 		BattleCode:   p.BattleCode,
 		TeamID:       p.Result.LootContext.TeamID,
 		DungeonID:    p.Result.LootContext.DungeonID,
+		ProgressID:   p.Result.LootContext.ProgressID,
+		RoomID:       p.Result.LootContext.RoomID,
 		HeroID:       p.firstHeroID(),
 		ResultStatus: p.Result.Status,
 		Participants: p.Participants,
 		Events:       p.Events,
 		RawPayload:   p,
 		Loot:         p.toLootData(),
+
+		WearParticipants: p.wearParticipants(),
+		HeroIDs:          p.heroIDs(),
+	}
+}
+
+// heroIDs 提取参战英雄ID，用于服务端经验发放
+func (p *battleResultPayload) heroIDs() []string {
+	ids := make([]string, 0, len(p.Participants))
+	for _, participant := range p.Participants {
+		if participant.HeroID != "" {
+			ids = append(ids, participant.HeroID)
+		}
+	}
+	return ids
+}
+
+// wearParticipants 提取参与耐久磨损的英雄，is_alive=false 视为阵亡
+func (p *battleResultPayload) wearParticipants() []service.BattleWearParticipant {
+	participants := make([]service.BattleWearParticipant, 0, len(p.Participants))
+	for _, participant := range p.Participants {
+		if participant.HeroID == "" {
+			continue
+		}
+		participants = append(participants, service.BattleWearParticipant{
+			HeroID: participant.HeroID,
+			Died:   participant.IsAlive != nil && !*participant.IsAlive,
+		})
 	}
+	return participants
 }
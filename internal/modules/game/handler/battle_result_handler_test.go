@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/modules/game/service"
+	"tsu-self/internal/pkg/response"
+	"tsu-self/internal/pkg/security"
+	"tsu-self/internal/repository/interfaces"
+)
+
+type memoryBattleReportRepo struct {
+	reports  int
+	outcomes map[string]*interfaces.BattleOutcome
+}
+
+func (r *memoryBattleReportRepo) Create(ctx context.Context, report *interfaces.BattleReport) error {
+	r.reports++
+	return nil
+}
+
+func (r *memoryBattleReportRepo) GetSettledReport(ctx context.Context, battleID string) (*interfaces.BattleReport, error) {
+	return nil, nil
+}
+
+func (r *memoryBattleReportRepo) ClaimOutcome(ctx context.Context, battleID string, staleAfter time.Duration) (string, *interfaces.BattleOutcome, error) {
+	if existing, ok := r.outcomes[battleID]; ok && existing.Status != interfaces.BattleOutcomeFailed {
+		return "", existing, nil
+	}
+	r.outcomes[battleID] = &interfaces.BattleOutcome{BattleID: battleID, Status: interfaces.BattleOutcomeProcessing}
+	return "claim-" + battleID, nil, nil
+}
+
+func (r *memoryBattleReportRepo) CompleteOutcome(ctx context.Context, battleID, claimToken string, outcome json.RawMessage) error {
+	r.outcomes[battleID].Status = interfaces.BattleOutcomeCompleted
+	r.outcomes[battleID].Outcome = outcome
+	return nil
+}
+
+func (r *memoryBattleReportRepo) FailOutcome(ctx context.Context, battleID, claimToken, reason string) error {
+	r.outcomes[battleID].Status = interfaces.BattleOutcomeFailed
+	return nil
+}
+
+type countingDungeonCompleter struct {
+	calls int
+}
+
+func (d *countingDungeonCompleter) CompleteDungeon(ctx context.Context, req *service.CompleteDungeonRequest) (*game_runtime.TeamDungeonProgress, error) {
+	d.calls++
+	return &game_runtime.TeamDungeonProgress{ID: "progress-1", TeamID: req.TeamID, DungeonID: req.DungeonID}, nil
+}
+
+func newSignedBattleRequest(keyID, secret, nonce string, body []byte) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/internal/battles/result", bytes.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := security.SignRequest([]byte(secret), req.Method, req.URL.RequestURI(), timestamp, nonce, body)
+	req.Header.Set(security.HeaderSignatureKeyID, keyID)
+	req.Header.Set(security.HeaderSignatureTimestamp, timestamp)
+	req.Header.Set(security.HeaderSignatureNonce, nonce)
+	req.Header.Set(security.HeaderSignature, hex.EncodeToString(signature))
+	return req
+}
+
+func TestBattleResultHandler_SignedCallbacks(t *testing.T) {
+	repo := &memoryBattleReportRepo{outcomes: make(map[string]*interfaces.BattleOutcome)}
+	dungeon := &countingDungeonCompleter{}
+	h := &BattleResultHandler{
+		battleService: service.NewBattleResultService(repo, dungeon),
+		respWriter:    response.DefaultResponseHandler(),
+		verifier:      security.NewHMACVerifier(map[string]string{"k1": "old-secret", "k2": "new-secret"}, time.Minute, nil),
+	}
+	e := echo.New()
+	body := []byte(`{"battle_id":"battle-h1","result":{"status":"victory","loot_context":{"team_id":"team-1","dungeon_id":"dungeon-1"}}}`)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		require.NoError(t, h.ReportResult(e.NewContext(req, rec)))
+		return rec
+	}
+
+	// 接受：有效签名
+	rec := serve(newSignedBattleRequest("k1", "old-secret", "nonce-1", body))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, 1, dungeon.calls)
+
+	// 拒绝：重放同一 nonce
+	rec = serve(newSignedBattleRequest("k1", "old-secret", "nonce-1", body))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// 拒绝：签名错误、缺少签名、仅携带旧静态令牌
+	rec = serve(newSignedBattleRequest("k2", "old-secret", "nonce-2", body))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	unsigned := httptest.NewRequest(http.MethodPost, "/api/v1/internal/battles/result", bytes.NewReader(body))
+	unsigned.Header.Set("X-Battle-Token", "anything")
+	rec = serve(unsigned)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Equal(t, 1, dungeon.calls)
+
+	// 接受：新密钥 + 新 nonce 重复上报同一 battle_id，返回首次结果且不重复结算
+	rec = serve(newSignedBattleRequest("k2", "new-secret", "nonce-3", body))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), "progress-1")
+	require.Equal(t, 1, dungeon.calls)
+	require.Equal(t, 1, repo.reports)
+}
+
+func TestBattleResultHandler_StaticToken(t *testing.T) {
+	h := &BattleResultHandler{respWriter: response.DefaultResponseHandler(), token: "static"}
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/internal/battles/heroes/h1/attributes", nil)
+	require.Error(t, h.authorize(e.NewContext(req, httptest.NewRecorder())))
+
+	req.Header.Set("X-Battle-Token", "static")
+	require.NoError(t, h.authorize(e.NewContext(req, httptest.NewRecorder())))
+}
+
+func TestBattleResultHandler_RejectsWhenUnconfigured(t *testing.T) {
+	h := &BattleResultHandler{respWriter: response.DefaultResponseHandler()}
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/internal/battles/heroes/h1/attributes", nil)
+	require.Error(t, h.authorize(e.NewContext(req, httptest.NewRecorder())))
+
+	req.Header.Set("X-Battle-Token", "")
+	require.Error(t, h.authorize(e.NewContext(req, httptest.NewRecorder())))
+}
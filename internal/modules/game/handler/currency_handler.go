@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tsu-self/internal/modules/game/service"
+	"tsu-self/internal/pkg/response"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// CurrencyHandler 英雄货币 HTTP Handler
+type CurrencyHandler struct {
+	currencyService *service.CurrencyService
+	respWriter      response.Writer
+}
+
+// NewCurrencyHandler 创建货币 Handler
+func NewCurrencyHandler(serviceContainer *service.ServiceContainer, respWriter response.Writer) *CurrencyHandler {
+	return &CurrencyHandler{
+		currencyService: serviceContainer.GetCurrencyService(),
+		respWriter:      respWriter,
+	}
+}
+
+// ==================== 请求/响应模型 ====================
+
+type heroCurrencyBalanceResponse struct {
+	Code    string `json:"currency_code"`
+	Name    string `json:"currency_name"`
+	Balance int64  `json:"balance"`
+}
+
+type currencyTransactionResponse struct {
+	ID           string          `json:"id"`
+	CurrencyCode string          `json:"currency_code"`
+	Amount       int64           `json:"amount"`
+	BalanceAfter int64           `json:"balance_after"`
+	SourceType   string          `json:"source_type"`
+	SourceID     *string         `json:"source_id,omitempty"`
+	Note         *string         `json:"note,omitempty"`
+	Metadata     json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt    string          `json:"created_at"`
+}
+
+// ==================== Handlers ====================
+
+// GetHeroCurrencies 英雄货币余额
+// @Summary 获取英雄货币余额
+// @Description 返回所有已启用货币的余额（金币、荣誉及自定义货币），未持有的货币余额为 0
+// @Tags 英雄
+// @Produce json
+// @Param hero_id path string true "英雄ID"
+// @Success 200 {object} response.Response{data=[]heroCurrencyBalanceResponse}
+// @Router /game/heroes/{hero_id}/currencies [get]
+func (h *CurrencyHandler) GetHeroCurrencies(c echo.Context) error {
+	heroID := c.Param("hero_id")
+	if heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "英雄ID不能为空")
+	}
+
+	balances, err := h.currencyService.GetWallet(c.Request().Context(), heroID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	resp := make([]heroCurrencyBalanceResponse, len(balances))
+	for i, balance := range balances {
+		resp[i] = heroCurrencyBalanceResponse{Code: balance.Code, Name: balance.Name, Balance: balance.Balance}
+	}
+	return response.EchoOK(c, h.respWriter, resp)
+}
+
+// ListHeroCurrencyTransactions 英雄货币流水
+// @Summary 获取英雄货币流水
+// @Description 按时间倒序分页返回货币流水，可按货币与来源过滤
+// @Tags 英雄
+// @Produce json
+// @Param hero_id path string true "英雄ID"
+// @Param currency query string false "货币代码"
+// @Param source_type query string false "来源类型"
+// @Param limit query int false "每页数量" default(20)
+// @Param offset query int false "偏移量" default(0)
+// @Success 200 {object} response.Response{data=map[string]interface{}}
+// @Router /game/heroes/{hero_id}/currencies/transactions [get]
+func (h *CurrencyHandler) ListHeroCurrencyTransactions(c echo.Context) error {
+	heroID := c.Param("hero_id")
+	if heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "英雄ID不能为空")
+	}
+
+	limit, offset := parsePagination(c, 20)
+	list, total, err := h.currencyService.ListTransactions(c.Request().Context(), interfaces.CurrencyTransactionQuery{
+		HeroID:       heroID,
+		CurrencyCode: c.QueryParam("currency"),
+		SourceType:   c.QueryParam("source_type"),
+		Limit:        limit,
+		Offset:       offset,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	items := make([]currencyTransactionResponse, len(list))
+	for i, txn := range list {
+		items[i] = currencyTransactionResponse{
+			ID:           txn.ID,
+			CurrencyCode: txn.CurrencyCode,
+			Amount:       txn.Amount,
+			BalanceAfter: txn.BalanceAfter,
+			SourceType:   txn.SourceType,
+			SourceID:     txn.SourceID,
+			Note:         txn.Note,
+			Metadata:     txn.Metadata,
+			CreatedAt:    txn.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	return response.EchoOK(c, h.respWriter, map[string]interface{}{
+		"list":   items,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tsu-self/internal/modules/game/service"
+	"tsu-self/internal/pkg/response"
+	"tsu-self/internal/pkg/schedule"
+)
+
+// DungeonHandler 地城目录 HTTP Handler
+type DungeonHandler struct {
+	catalogService *service.DungeonCatalogService
+	respWriter     response.Writer
+}
+
+// NewDungeonHandler 创建地城目录 Handler
+func NewDungeonHandler(serviceContainer *service.ServiceContainer, respWriter response.Writer) *DungeonHandler {
+	return &DungeonHandler{
+		catalogService: serviceContainer.GetDungeonCatalogService(),
+		respWriter:     respWriter,
+	}
+}
+
+// ==================== 请求/响应模型 ====================
+
+type dungeonWindowResponse struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type dungeonListItem struct {
+	ID            string                 `json:"id"`
+	DungeonCode   string                 `json:"dungeon_code"`
+	DungeonName   string                 `json:"dungeon_name"`
+	MinLevel      int16                  `json:"min_level"`
+	MaxLevel      int16                  `json:"max_level"`
+	Description   *string                `json:"description,omitempty"`
+	IsOpen        bool                   `json:"is_open"`
+	ClosedReason  string                 `json:"closed_reason,omitempty"`
+	CurrentWindow *dungeonWindowResponse `json:"current_window,omitempty"`
+	NextOpening   *dungeonWindowResponse `json:"next_opening,omitempty"`
+	MaxAttempts   *int16                 `json:"max_attempts_per_day,omitempty"`
+	RoomCount     int                    `json:"room_count"`
+	Rooms         []dungeonRoomOutline   `json:"rooms"`
+	Eligibility   *dungeonEligibility    `json:"eligibility,omitempty"`
+}
+
+type dungeonRoomOutline struct {
+	Sort     int    `json:"sort"`
+	RoomID   string `json:"room_id,omitempty"`
+	RoomCode string `json:"room_code,omitempty"`
+	RoomName string `json:"room_name,omitempty"`
+	RoomType string `json:"room_type,omitempty"`
+}
+
+type dungeonMemberEligibility struct {
+	HeroID   string `json:"hero_id"`
+	HeroName string `json:"hero_name"`
+	Level    int    `json:"level"`
+	Eligible bool   `json:"eligible"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type dungeonEligibility struct {
+	TeamID            string                     `json:"team_id"`
+	Eligible          bool                       `json:"eligible"`
+	Reasons           []string                   `json:"reasons"`
+	MemberCount       int                        `json:"member_count"`
+	MinMembers        int                        `json:"min_members"`
+	Members           []dungeonMemberEligibility `json:"members"`
+	AttemptsUsed      int                        `json:"attempts_used"`
+	MaxAttempts       *int                       `json:"max_attempts,omitempty"`
+	RemainingAttempts *int                       `json:"remaining_attempts,omitempty"`
+}
+
+// ==================== Handlers ====================
+
+// ListDungeons 地城列表
+// @Summary 地城列表
+// @Description 列出已上线地城的等级区间、房间概览、开放状态与下次开放时间；指定 team_id 与 hero_id 时附带团队资格报告
+// @Tags 地城
+// @Produce json
+// @Param team_id query string false "团队ID"
+// @Param hero_id query string false "英雄ID（需为团队成员，指定 team_id 时必填）"
+// @Param limit query int false "每页数量" default(20)
+// @Param offset query int false "偏移量" default(0)
+// @Success 200 {object} response.Response{data=map[string]interface{}}
+// @Router /game/dungeons [get]
+func (h *DungeonHandler) ListDungeons(c echo.Context) error {
+	teamID := c.QueryParam("team_id")
+	heroID := c.QueryParam("hero_id")
+	if teamID != "" && heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "hero_id 不能为空")
+	}
+
+	limit, offset := parsePagination(c, 20)
+	list, total, err := h.catalogService.ListDungeons(c.Request().Context(), service.DungeonCatalogQuery{
+		TeamID: teamID,
+		HeroID: heroID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	items := make([]dungeonListItem, len(list))
+	for i, entry := range list {
+		items[i] = toDungeonListItem(entry)
+	}
+
+	return response.EchoOK(c, h.respWriter, map[string]interface{}{
+		"list":   items,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetDungeon 地城详情
+// @Summary 地城详情
+// @Description 获取地城的等级区间、房间概览与开放状态；指定 team_id 与 hero_id 时附带团队资格报告
+// @Tags 地城
+// @Produce json
+// @Param dungeon_id path string true "地城ID"
+// @Param team_id query string false "团队ID"
+// @Param hero_id query string false "英雄ID（需为团队成员，指定 team_id 时必填）"
+// @Success 200 {object} response.Response{data=dungeonListItem}
+// @Router /game/dungeons/{dungeon_id} [get]
+func (h *DungeonHandler) GetDungeon(c echo.Context) error {
+	teamID := c.QueryParam("team_id")
+	heroID := c.QueryParam("hero_id")
+	if teamID != "" && heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "hero_id 不能为空")
+	}
+
+	item, err := h.catalogService.GetDungeon(c.Request().Context(), c.Param("dungeon_id"), teamID, heroID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, toDungeonListItem(item))
+}
+
+func toDungeonListItem(entry *service.DungeonCatalogItem) dungeonListItem {
+	dungeon := entry.Dungeon
+	item := dungeonListItem{
+		ID:            dungeon.ID,
+		DungeonCode:   dungeon.DungeonCode,
+		DungeonName:   dungeon.DungeonName,
+		MinLevel:      dungeon.MinLevel,
+		MaxLevel:      dungeon.MaxLevel,
+		IsOpen:        entry.Opening.IsOpen,
+		ClosedReason:  entry.Opening.Reason,
+		CurrentWindow: toDungeonWindowResponse(entry.Opening.CurrentWindow, entry.Opening.Location),
+		NextOpening:   toDungeonWindowResponse(entry.Opening.NextOpening, entry.Opening.Location),
+	}
+	if dungeon.Description.Valid {
+		value := dungeon.Description.String
+		item.Description = &value
+	}
+	if dungeon.MaxAttemptsPerDay.Valid {
+		value := dungeon.MaxAttemptsPerDay.Int16
+		item.MaxAttempts = &value
+	}
+
+	item.RoomCount = len(entry.Rooms)
+	item.Rooms = make([]dungeonRoomOutline, len(entry.Rooms))
+	for i, room := range entry.Rooms {
+		item.Rooms[i] = dungeonRoomOutline{
+			Sort:     room.Sort,
+			RoomID:   room.RoomID,
+			RoomCode: room.RoomCode,
+			RoomName: room.RoomName,
+			RoomType: room.RoomType,
+		}
+	}
+
+	if report := entry.Eligibility; report != nil {
+		eligibility := &dungeonEligibility{
+			TeamID:            report.TeamID,
+			Eligible:          report.Eligible,
+			Reasons:           report.Reasons,
+			MemberCount:       report.MemberCount,
+			MinMembers:        report.MinMembers,
+			Members:           make([]dungeonMemberEligibility, len(report.Members)),
+			AttemptsUsed:      report.AttemptsUsed,
+			MaxAttempts:       report.MaxAttempts,
+			RemainingAttempts: report.RemainingAttempts,
+		}
+		if eligibility.Reasons == nil {
+			eligibility.Reasons = []string{}
+		}
+		for i, member := range report.Members {
+			eligibility.Members[i] = dungeonMemberEligibility{
+				HeroID:   member.HeroID,
+				HeroName: member.HeroName,
+				Level:    member.Level,
+				Eligible: member.Eligible,
+				Reason:   member.Reason,
+			}
+		}
+		item.Eligibility = eligibility
+	}
+	return item
+}
+
+func toDungeonWindowResponse(w *schedule.Window, loc *time.Location) *dungeonWindowResponse {
+	if w == nil {
+		return nil
+	}
+	start, end := w.Start, w.End
+	if loc != nil {
+		start, end = start.In(loc), end.In(loc)
+	}
+	return &dungeonWindowResponse{
+		Start: start.Format(time.RFC3339),
+		End:   end.Format(time.RFC3339),
+	}
+}
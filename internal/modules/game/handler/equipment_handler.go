@@ -1,10 +1,9 @@
 package handler
 
 import (
-	"database/sql"
-
 	"github.com/labstack/echo/v4"
 
+	custommiddleware "tsu-self/internal/middleware"
 	"tsu-self/internal/modules/game/service"
 	"tsu-self/internal/pkg/response"
 	"tsu-self/internal/pkg/xerrors"
@@ -12,27 +11,43 @@ import (
 
 // EquipmentHandler 装备穿戴处理器
 type EquipmentHandler struct {
-	db              *sql.DB
-	equipmentSvc    *service.EquipmentService
-	respWriter      response.Writer
+	equipmentSvc   *service.EquipmentService
+	enhancementSvc *service.EquipmentEnhancementService
+	durabilitySvc  *service.EquipmentDurabilityService
+	respWriter     response.Writer
 }
 
 // NewEquipmentHandler 创建装备穿戴处理器
-func NewEquipmentHandler(db *sql.DB, respWriter response.Writer) *EquipmentHandler {
+func NewEquipmentHandler(serviceContainer *service.ServiceContainer, respWriter response.Writer) *EquipmentHandler {
 	return &EquipmentHandler{
-		db:           db,
-		equipmentSvc: service.NewEquipmentService(db),
-		respWriter:   respWriter,
+		equipmentSvc:   serviceContainer.GetEquipmentService(),
+		enhancementSvc: serviceContainer.GetEnhancementService(),
+		durabilitySvc:  serviceContainer.GetDurabilityService(),
+		respWriter:     respWriter,
+	}
+}
+
+// resolveHeroID 校验英雄归属：只能操作当前激活的英雄（由 HeroMiddleware 设置）
+// requested 为空时直接使用当前英雄
+func (h *EquipmentHandler) resolveHeroID(c echo.Context, requested string) (string, error) {
+	heroID, err := custommiddleware.GetCurrentHeroID(c)
+	if err != nil {
+		return "", err
+	}
+	if requested != "" && requested != heroID {
+		return "", xerrors.New(xerrors.CodePermissionDenied, "只能操作当前英雄的装备")
 	}
+	return heroID, nil
 }
 
 // ==================== HTTP Request/Response Models ====================
 
 // EquipItemRequest 穿戴装备请求
 type EquipItemRequest struct {
-	HeroID         string  `json:"hero_id" validate:"required" example:"550e8400-e29b-41d4-a716-446655440000"`         // 英雄ID（必填）
+	HeroID         string  `json:"hero_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`                   // 英雄ID（可选，必须为当前英雄）
 	ItemInstanceID string  `json:"item_instance_id" validate:"required" example:"660e8400-e29b-41d4-a716-446655440001"` // 物品实例ID（必填）
 	SlotType       *string `json:"slot_type,omitempty" example:"weapon"`                                               // 槽位类型（可选，不指定则自动选择）
+	SlotID         *string `json:"slot_id,omitempty" example:"770e8400-e29b-41d4-a716-446655440002"`                   // 目标槽位ID（可选，已有装备时原子替换）
 }
 
 // EquipItemResponse 穿戴装备响应
@@ -45,7 +60,7 @@ type EquipItemResponse struct {
 
 // UnequipItemRequest 卸下装备请求
 type UnequipItemRequest struct {
-	HeroID string `json:"hero_id" validate:"required" example:"550e8400-e29b-41d4-a716-446655440000"` // 英雄ID（必填）
+	HeroID string `json:"hero_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`          // 英雄ID（可选，必须为当前英雄）
 	SlotID string `json:"slot_id" validate:"required" example:"770e8400-e29b-41d4-a716-446655440002"` // 槽位ID（必填）
 }
 
@@ -56,6 +71,43 @@ type UnequipItemResponse struct {
 	UnequippedItem *PlayerItemInfo `json:"unequipped_item,omitempty"`  // 卸下的装备信息
 }
 
+// EnhanceItemRequest 强化装备请求
+type EnhanceItemRequest struct {
+	HeroID         string `json:"hero_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`                   // 英雄ID（可选，必须为当前英雄）
+	ItemInstanceID string `json:"item_instance_id" validate:"required" example:"660e8400-e29b-41d4-a716-446655440001"` // 物品实例ID（必填）
+}
+
+// EnhanceItemResponse 强化装备响应
+type EnhanceItemResponse struct {
+	ItemInstanceID string  `json:"item_instance_id" example:"660e8400-e29b-41d4-a716-446655440001"` // 物品实例ID
+	Result         string  `json:"result" example:"success"`                                        // 强化结果 success/failed/downgraded
+	LevelBefore    int     `json:"level_before" example:"5"`                                        // 强化前等级
+	LevelAfter     int     `json:"level_after" example:"6"`                                         // 强化后等级
+	SuccessRate    float64 `json:"success_rate" example:"0.8"`                                      // 本次成功率
+	GoldCost       int64   `json:"gold_cost" example:"200"`                                         // 消耗金币
+	MaterialID     string  `json:"material_id,omitempty"`                                           // 消耗材料ID
+	MaterialCost   int     `json:"material_cost" example:"2"`                                       // 消耗材料数量
+}
+
+// RepairItemRequest 修理装备请求
+type RepairItemRequest struct {
+	HeroID         string `json:"hero_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`                   // 英雄ID（可选，必须为当前英雄）
+	ItemInstanceID string `json:"item_instance_id" validate:"required" example:"660e8400-e29b-41d4-a716-446655440001"` // 装备实例ID（必填）
+	Method         string `json:"method" validate:"required,oneof=kit gold" example:"gold"`                            // 修理方式 kit/gold
+	KitInstanceID  string `json:"kit_instance_id,omitempty" example:"880e8400-e29b-41d4-a716-446655440003"`            // 修理材料实例ID（method=kit 时必填）
+}
+
+// RepairItemResponse 修理装备响应
+type RepairItemResponse struct {
+	ItemInstanceID   string `json:"item_instance_id" example:"660e8400-e29b-41d4-a716-446655440001"` // 装备实例ID
+	Method           string `json:"method" example:"gold"`                                           // 修理方式
+	DurabilityBefore int    `json:"durability_before" example:"12"`                                  // 修理前耐久
+	DurabilityAfter  int    `json:"durability_after" example:"100"`                                  // 修理后耐久
+	MaxDurability    int    `json:"max_durability" example:"100"`                                    // 最大耐久
+	GoldCost         int64  `json:"gold_cost" example:"264"`                                         // 消耗金币
+	KitInstanceID    string `json:"kit_instance_id,omitempty"`                                       // 消耗的修理材料实例ID
+}
+
 // EquipmentSlotInfo 装备槽位信息
 type EquipmentSlotInfo struct {
 	ID              string  `json:"id" example:"770e8400-e29b-41d4-a716-446655440002"`         // 槽位ID
@@ -131,14 +183,22 @@ func (h *EquipmentHandler) EquipItem(c echo.Context) error {
 		return response.EchoValidationError(c, h.respWriter, err)
 	}
 
+	heroID, err := h.resolveHeroID(c, req.HeroID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
 	// 3. 调用服务
 	svcReq := &service.EquipItemRequest{
-		HeroID:         req.HeroID,
+		HeroID:         heroID,
 		ItemInstanceID: req.ItemInstanceID,
 	}
 	if req.SlotType != nil {
 		svcReq.SlotType = *req.SlotType
 	}
+	if req.SlotID != nil {
+		svcReq.SlotID = *req.SlotID
+	}
 
 	svcResp, err := h.equipmentSvc.EquipItem(c.Request().Context(), svcReq)
 	if err != nil {
@@ -186,9 +246,14 @@ func (h *EquipmentHandler) UnequipItem(c echo.Context) error {
 		return response.EchoError(c, h.respWriter, xerrors.New(xerrors.CodeInvalidParams, "请求参数验证失败"))
 	}
 
+	heroID, err := h.resolveHeroID(c, req.HeroID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
 	// 3. 调用服务
 	svcReq := &service.UnequipItemRequest{
-		HeroID: req.HeroID,
+		HeroID: heroID,
 		SlotID: req.SlotID,
 	}
 
@@ -210,6 +275,109 @@ func (h *EquipmentHandler) UnequipItem(c echo.Context) error {
 	return response.EchoOK(c, h.respWriter, resp)
 }
 
+// EnhanceItem 强化装备
+// @Summary 强化装备
+// @Description 消耗强化材料与金币强化装备，按等级配置可能成功、失败或降级
+// @Tags Equipment
+// @Accept json
+// @Produce json
+// @Param request body EnhanceItemRequest true "强化装备请求"
+// @Success 200 {object} response.Response{data=EnhanceItemResponse} "成功"
+// @Failure 400 {object} response.Response "参数错误"
+// @Failure 404 {object} response.Response "资源不存在"
+// @Failure 500 {object} response.Response "服务器错误"
+// @Router /game/equipment/enhance [post]
+func (h *EquipmentHandler) EnhanceItem(c echo.Context) error {
+	// 1. 解析请求
+	var req EnhanceItemRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求参数格式错误")
+	}
+
+	// 2. 验证请求
+	if err := c.Validate(&req); err != nil {
+		return response.EchoValidationError(c, h.respWriter, err)
+	}
+
+	heroID, err := h.resolveHeroID(c, req.HeroID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	// 3. 调用服务
+	result, err := h.enhancementSvc.EnhanceItem(c.Request().Context(), &service.EnhanceItemRequest{
+		HeroID:         heroID,
+		ItemInstanceID: req.ItemInstanceID,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	// 4. 构建响应
+	return response.EchoOK(c, h.respWriter, &EnhanceItemResponse{
+		ItemInstanceID: result.ItemInstanceID,
+		Result:         result.Result,
+		LevelBefore:    result.LevelBefore,
+		LevelAfter:     result.LevelAfter,
+		SuccessRate:    result.SuccessRate,
+		GoldCost:       result.GoldCost,
+		MaterialID:     result.MaterialID,
+		MaterialCost:   result.MaterialCost,
+	})
+}
+
+// RepairItem 修理装备
+// @Summary 修理装备
+// @Description 使用背包中的修理材料恢复耐久，或消耗金币（按品质单价）直接修满
+// @Tags Equipment
+// @Accept json
+// @Produce json
+// @Param request body RepairItemRequest true "修理装备请求"
+// @Success 200 {object} response.Response{data=RepairItemResponse} "成功"
+// @Failure 400 {object} response.Response "参数错误"
+// @Failure 404 {object} response.Response "资源不存在"
+// @Failure 500 {object} response.Response "服务器错误"
+// @Router /game/equipment/repair [post]
+func (h *EquipmentHandler) RepairItem(c echo.Context) error {
+	// 1. 解析请求
+	var req RepairItemRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求参数格式错误")
+	}
+
+	// 2. 验证请求
+	if err := c.Validate(&req); err != nil {
+		return response.EchoValidationError(c, h.respWriter, err)
+	}
+
+	heroID, err := h.resolveHeroID(c, req.HeroID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	// 3. 调用服务
+	result, err := h.durabilitySvc.RepairItem(c.Request().Context(), &service.RepairItemRequest{
+		HeroID:         heroID,
+		ItemInstanceID: req.ItemInstanceID,
+		Method:         req.Method,
+		KitInstanceID:  req.KitInstanceID,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	// 4. 构建响应
+	return response.EchoOK(c, h.respWriter, &RepairItemResponse{
+		ItemInstanceID:   result.ItemInstanceID,
+		Method:           result.Method,
+		DurabilityBefore: result.DurabilityBefore,
+		DurabilityAfter:  result.DurabilityAfter,
+		MaxDurability:    result.MaxDurability,
+		GoldCost:         result.GoldCost,
+		KitInstanceID:    result.KitInstanceID,
+	})
+}
+
 // GetEquippedItems 查询已装备物品
 // @Summary 查询已装备物品
 // @Description 查询英雄当前已装备的所有物品
@@ -224,9 +392,9 @@ func (h *EquipmentHandler) UnequipItem(c echo.Context) error {
 // @Router /game/equipment/equipped/{hero_id} [get]
 func (h *EquipmentHandler) GetEquippedItems(c echo.Context) error {
 	// 1. 获取参数
-	heroID := c.Param("hero_id")
-	if heroID == "" {
-		return response.EchoError(c, h.respWriter, xerrors.New(xerrors.CodeInvalidParams, "英雄ID不能为空"))
+	heroID, err := h.resolveHeroID(c, c.Param("hero_id"))
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
 	}
 
 	// 2. 调用服务
@@ -261,9 +429,9 @@ func (h *EquipmentHandler) GetEquippedItems(c echo.Context) error {
 // @Router /game/equipment/slots/{hero_id} [get]
 func (h *EquipmentHandler) GetEquipmentSlots(c echo.Context) error {
 	// 1. 获取参数
-	heroID := c.Param("hero_id")
-	if heroID == "" {
-		return response.EchoError(c, h.respWriter, xerrors.New(xerrors.CodeInvalidParams, "英雄ID不能为空"))
+	heroID, err := h.resolveHeroID(c, c.Param("hero_id"))
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
 	}
 
 	// 2. 调用服务
@@ -298,9 +466,9 @@ func (h *EquipmentHandler) GetEquipmentSlots(c echo.Context) error {
 // @Router /game/equipment/bonus/{hero_id} [get]
 func (h *EquipmentHandler) GetEquipmentBonus(c echo.Context) error {
 	// 1. 获取参数
-	heroID := c.Param("hero_id")
-	if heroID == "" {
-		return response.EchoError(c, h.respWriter, xerrors.New(xerrors.CodeInvalidParams, "英雄ID不能为空"))
+	heroID, err := h.resolveHeroID(c, c.Param("hero_id"))
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
 	}
 
 	// 2. 调用服务
@@ -1,8 +1,10 @@
 package handler
 
 import (
+	custommiddleware "tsu-self/internal/middleware"
 	"tsu-self/internal/modules/game/service"
 	"tsu-self/internal/pkg/response"
+	"tsu-self/internal/pkg/xerrors"
 
 	"github.com/labstack/echo/v4"
 )
@@ -136,6 +138,11 @@ func (h *EquipmentSetHandler) GetActiveSets(c echo.Context) error {
 		return response.EchoBadRequest(c, h.respWriter, "英雄ID不能为空")
 	}
 
+	// 只能查询当前英雄（由 HeroMiddleware 设置）
+	if currentHeroID, err := custommiddleware.GetCurrentHeroID(c); err == nil && currentHeroID != heroID {
+		return response.EchoError(c, h.respWriter, xerrors.New(xerrors.CodePermissionDenied, "只能查询当前英雄的套装"))
+	}
+
 	// 调用服务层
 	activeSets, err := h.equipmentSetSvc.GetActiveSets(c.Request().Context(), heroID)
 	if err != nil {
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"github.com/labstack/echo/v4"
+
+	custommiddleware "tsu-self/internal/middleware"
+	"tsu-self/internal/modules/game/service"
+	"tsu-self/internal/pkg/response"
+)
+
+// GemSocketHandler 宝石镶嵌处理器
+type GemSocketHandler struct {
+	gemSocketSvc *service.GemSocketService
+	respWriter   response.Writer
+}
+
+// NewGemSocketHandler 创建宝石镶嵌处理器
+func NewGemSocketHandler(serviceContainer *service.ServiceContainer, respWriter response.Writer) *GemSocketHandler {
+	return &GemSocketHandler{
+		gemSocketSvc: serviceContainer.GetGemSocketService(),
+		respWriter:   respWriter,
+	}
+}
+
+// ==================== HTTP Request/Response Models ====================
+
+// SocketGemRequest 镶嵌/替换宝石请求
+type SocketGemRequest struct {
+	ItemInstanceID string `json:"item_instance_id" validate:"required" example:"660e8400-e29b-41d4-a716-446655440001"` // 装备实例ID
+	GemInstanceID  string `json:"gem_instance_id" validate:"required" example:"880e8400-e29b-41d4-a716-446655440003"`  // 背包中的宝石实例ID
+	SocketIndex    int    `json:"socket_index" validate:"min=0" example:"0"`                                           // 孔位索引
+}
+
+// UnsocketGemRequest 拆卸宝石请求
+type UnsocketGemRequest struct {
+	ItemInstanceID string `json:"item_instance_id" validate:"required" example:"660e8400-e29b-41d4-a716-446655440001"` // 装备实例ID
+	SocketIndex    int    `json:"socket_index" validate:"min=0" example:"0"`                                           // 孔位索引
+}
+
+// SocketSlotInfo 孔位信息
+type SocketSlotInfo struct {
+	SocketIndex   int      `json:"socket_index" example:"0"`       // 孔位索引
+	Size          string   `json:"size,omitempty" example:"small"` // 孔位大小（空表示不限）
+	Colors        []string `json:"colors,omitempty"`               // 允许的宝石颜色（空表示不限）
+	GemInstanceID *string  `json:"gem_instance_id,omitempty"`      // 已镶嵌的宝石实例ID
+	GemItemID     *string  `json:"gem_item_id,omitempty"`          // 已镶嵌的宝石配置ID
+}
+
+// SocketGemResponse 镶嵌操作响应
+type SocketGemResponse struct {
+	ItemInstanceID string            `json:"item_instance_id"`         // 装备实例ID
+	Sockets        []*SocketSlotInfo `json:"sockets"`                  // 孔位列表
+	RemovedGemID   *string           `json:"removed_gem_id,omitempty"` // 回到背包的宝石实例ID
+}
+
+// ==================== HTTP Handlers ====================
+
+// SocketGem 镶嵌宝石
+// @Summary 镶嵌宝石
+// @Description 将背包中的宝石镶嵌到装备的空孔位，宝石颜色与大小需符合孔位布局
+// @Tags Equipment
+// @Accept json
+// @Produce json
+// @Param request body SocketGemRequest true "镶嵌宝石请求"
+// @Success 200 {object} response.Response{data=SocketGemResponse} "成功"
+// @Failure 400 {object} response.Response "参数错误"
+// @Failure 404 {object} response.Response "资源不存在"
+// @Failure 500 {object} response.Response "服务器错误"
+// @Router /game/equipment/gems/socket [post]
+func (h *GemSocketHandler) SocketGem(c echo.Context) error {
+	return h.socket(c, false)
+}
+
+// ReplaceGem 替换宝石
+// @Summary 替换宝石
+// @Description 用背包中的宝石替换孔位中已镶嵌的宝石，原宝石回到背包
+// @Tags Equipment
+// @Accept json
+// @Produce json
+// @Param request body SocketGemRequest true "替换宝石请求"
+// @Success 200 {object} response.Response{data=SocketGemResponse} "成功"
+// @Failure 400 {object} response.Response "参数错误"
+// @Failure 404 {object} response.Response "资源不存在"
+// @Failure 500 {object} response.Response "服务器错误"
+// @Router /game/equipment/gems/replace [post]
+func (h *GemSocketHandler) ReplaceGem(c echo.Context) error {
+	return h.socket(c, true)
+}
+
+func (h *GemSocketHandler) socket(c echo.Context, replace bool) error {
+	var req SocketGemRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求参数格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoValidationError(c, h.respWriter, err)
+	}
+
+	heroID, err := custommiddleware.GetCurrentHeroID(c)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	svcReq := &service.SocketGemRequest{
+		HeroID:         heroID,
+		ItemInstanceID: req.ItemInstanceID,
+		GemInstanceID:  req.GemInstanceID,
+		SocketIndex:    req.SocketIndex,
+	}
+	var result *service.SocketGemResult
+	if replace {
+		result, err = h.gemSocketSvc.ReplaceGem(c.Request().Context(), svcReq)
+	} else {
+		result, err = h.gemSocketSvc.SocketGem(c.Request().Context(), svcReq)
+	}
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, toSocketGemResponse(result))
+}
+
+// UnsocketGem 拆卸宝石
+// @Summary 拆卸宝石
+// @Description 将孔位中的宝石拆下放回背包
+// @Tags Equipment
+// @Accept json
+// @Produce json
+// @Param request body UnsocketGemRequest true "拆卸宝石请求"
+// @Success 200 {object} response.Response{data=SocketGemResponse} "成功"
+// @Failure 400 {object} response.Response "参数错误"
+// @Failure 404 {object} response.Response "资源不存在"
+// @Failure 500 {object} response.Response "服务器错误"
+// @Router /game/equipment/gems/unsocket [post]
+func (h *GemSocketHandler) UnsocketGem(c echo.Context) error {
+	var req UnsocketGemRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求参数格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoValidationError(c, h.respWriter, err)
+	}
+
+	heroID, err := custommiddleware.GetCurrentHeroID(c)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	result, err := h.gemSocketSvc.UnsocketGem(c.Request().Context(), &service.UnsocketGemRequest{
+		HeroID:         heroID,
+		ItemInstanceID: req.ItemInstanceID,
+		SocketIndex:    req.SocketIndex,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, toSocketGemResponse(result))
+}
+
+// GetSockets 查询装备孔位
+// @Summary 查询装备孔位
+// @Description 查询装备的孔位布局与已镶嵌宝石
+// @Tags Equipment
+// @Produce json
+// @Param item_instance_id path string true "装备实例ID"
+// @Success 200 {object} response.Response{data=SocketGemResponse} "成功"
+// @Failure 400 {object} response.Response "参数错误"
+// @Failure 404 {object} response.Response "资源不存在"
+// @Failure 500 {object} response.Response "服务器错误"
+// @Router /game/equipment/gems/{item_instance_id} [get]
+func (h *GemSocketHandler) GetSockets(c echo.Context) error {
+	itemInstanceID := c.Param("item_instance_id")
+	if itemInstanceID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "装备实例ID不能为空")
+	}
+
+	heroID, err := custommiddleware.GetCurrentHeroID(c)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	sockets, err := h.gemSocketSvc.GetSockets(c.Request().Context(), heroID, itemInstanceID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, toSocketGemResponse(&service.SocketGemResult{
+		ItemInstanceID: itemInstanceID,
+		Sockets:        sockets,
+	}))
+}
+
+func toSocketGemResponse(result *service.SocketGemResult) *SocketGemResponse {
+	resp := &SocketGemResponse{
+		ItemInstanceID: result.ItemInstanceID,
+		Sockets:        make([]*SocketSlotInfo, 0, len(result.Sockets)),
+		RemovedGemID:   result.RemovedGemID,
+	}
+	for _, slot := range result.Sockets {
+		resp.Sockets = append(resp.Sockets, &SocketSlotInfo{
+			SocketIndex:   slot.SocketIndex,
+			Size:          slot.Size,
+			Colors:        slot.Colors,
+			GemInstanceID: slot.GemInstanceID,
+			GemItemID:     slot.GemItemID,
+		})
+	}
+	return resp
+}
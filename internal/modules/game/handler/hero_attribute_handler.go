@@ -34,13 +34,26 @@ type RollbackAttributeRequest struct {
 	AttributeCode string `json:"attribute_code" validate:"required" example:"STR" enums:"STR,DEX,CON,INT,WIS,CHA"` // 属性代码（必填）：要回退的属性
 }
 
+// AttributeSourceBonusResponse HTTP attribute source bonus
+type AttributeSourceBonusResponse struct {
+	Flat    float64 `json:"flat" example:"5"`      // 固定加值
+	Percent float64 `json:"percent" example:"0.1"` // 百分比加成（小数，0.1 = +10%）
+}
+
 // ComputedAttributeResponse HTTP computed attribute response
 type ComputedAttributeResponse struct {
-	AttributeCode string `json:"attribute_code" example:"STR"` // 属性代码
-	AttributeName string `json:"attribute_name" example:"力量"`  // 属性名称
-	BaseValue     int    `json:"base_value" example:"15"`      // 基础值（职业初始+玩家加点）
-	ClassBonus    int    `json:"class_bonus" example:"2"`      // 职业加成（来自职业配置）
-	FinalValue    int    `json:"final_value" example:"17"`     // 最终值（base + class_bonus）
+	AttributeCode  string                       `json:"attribute_code" example:"STR"` // 属性代码
+	AttributeName  string                       `json:"attribute_name" example:"力量"`  // 属性名称
+	BaseValue      int                          `json:"base_value" example:"15"`      // 基础值（初始值+玩家加点）
+	InitialValue   int                          `json:"initial_value" example:"1"`    // 初始值
+	AllocatedValue int                          `json:"allocated_value" example:"14"` // 玩家加点
+	ClassBonus     int                          `json:"class_bonus" example:"2"`      // 职业加成（来自职业配置）
+	Equipment      AttributeSourceBonusResponse `json:"equipment"`                    // 装备加成（未强化部分）
+	Set            AttributeSourceBonusResponse `json:"set"`                          // 套装加成
+	Enhancement    AttributeSourceBonusResponse `json:"enhancement"`                  // 强化增量
+	Gem            AttributeSourceBonusResponse `json:"gem"`                          // 镶嵌宝石加成
+	Buff           AttributeSourceBonusResponse `json:"buff"`                         // 生效中的 Buff
+	FinalValue     int                          `json:"final_value" example:"17"`     // 最终值（固定值之和 × (1 + 百分比之和)）
 }
 
 // ==================== HTTP Handlers ====================
@@ -150,7 +163,7 @@ func (h *HeroAttributeHandler) RollbackAttribute(c echo.Context) error {
 
 // GetComputedAttributes handles getting computed attributes
 // @Summary 获取英雄计算属性
-// @Description 获取英雄的计算后属性值列表。包含：基础值（加点值）、职业加成、最终计算值。数据来自计算视图（hero_computed_attributes）
+// @Description 获取英雄的计算后属性值列表。包含：初始值、玩家加点、职业加成、装备/套装/强化/Buff 加成明细及最终值。战斗引擎使用同一数据源
 // @Tags 英雄属性
 // @Accept json
 // @Produce json
@@ -171,32 +184,27 @@ func (h *HeroAttributeHandler) GetComputedAttributes(c echo.Context) error {
 		return response.EchoError(c, h.respWriter, err)
 	}
 
-	// 转换为 HTTP 响应
+	return response.EchoOK(c, h.respWriter, toComputedAttributeResponses(attrs))
+}
+
+// toComputedAttributeResponses 转换为 HTTP 响应
+func toComputedAttributeResponses(attrs []*service.ComputedAttribute) []*ComputedAttributeResponse {
 	respList := make([]*ComputedAttributeResponse, len(attrs))
 	for i, attr := range attrs {
-		baseValue := 0
-		if !attr.BaseValue.IsZero() {
-			baseValue = int(attr.BaseValue.Int)
-		}
-
-		classBonus := 0
-		if !attr.ClassBonus.IsZero() {
-			classBonus = attr.ClassBonus.Int
-		}
-
-		finalValue := 0
-		if !attr.FinalValue.IsZero() {
-			finalValue = attr.FinalValue.Int
-		}
-
 		respList[i] = &ComputedAttributeResponse{
-			AttributeCode: attr.AttributeCode.String,
-			AttributeName: attr.AttributeName.String,
-			BaseValue:     baseValue,
-			ClassBonus:    classBonus,
-			FinalValue:    finalValue,
+			AttributeCode:  attr.AttributeCode,
+			AttributeName:  attr.AttributeName,
+			BaseValue:      attr.BaseValue + attr.AllocatedValue,
+			InitialValue:   attr.BaseValue,
+			AllocatedValue: attr.AllocatedValue,
+			ClassBonus:     attr.ClassBonus,
+			Equipment:      AttributeSourceBonusResponse(attr.Equipment),
+			Set:            AttributeSourceBonusResponse(attr.Set),
+			Enhancement:    AttributeSourceBonusResponse(attr.Enhancement),
+			Gem:            AttributeSourceBonusResponse(attr.Gem),
+			Buff:           AttributeSourceBonusResponse(attr.Buff),
+			FinalValue:     attr.FinalValue,
 		}
 	}
-
-	return response.EchoOK(c, h.respWriter, respList)
+	return respList
 }
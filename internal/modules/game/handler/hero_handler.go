@@ -1,23 +1,32 @@
 package handler
 
 import (
+	"os"
+	"time"
+
 	"github.com/labstack/echo/v4"
 
 	"tsu-self/internal/modules/game/service"
 	"tsu-self/internal/pkg/response"
+	"tsu-self/internal/pkg/xerrors"
 )
 
 // HeroHandler handles hero HTTP requests
 type HeroHandler struct {
-	heroService *service.HeroService
-	respWriter  response.Writer
+	heroService           *service.HeroService
+	experienceService     *service.ExperienceAwardService
+	respWriter            response.Writer
+	testExperienceEnabled bool // 测试加经验接口开关
 }
 
 // NewHeroHandler creates a new hero handler
 func NewHeroHandler(serviceContainer *service.ServiceContainer, respWriter response.Writer) *HeroHandler {
 	return &HeroHandler{
-		heroService: serviceContainer.GetHeroService(),
-		respWriter:  respWriter,
+		heroService:       serviceContainer.GetHeroService(),
+		experienceService: serviceContainer.GetExperienceService(),
+		respWriter:        respWriter,
+		// 默认关闭，只有显式设置 ENABLE_TEST_EXPERIENCE_API=true 时开放（正式经验来源为战斗与事件结算）
+		testExperienceEnabled: os.Getenv("ENABLE_TEST_EXPERIENCE_API") == "true",
 	}
 }
 
@@ -96,6 +105,8 @@ type AdvancementCheckResponse struct {
 	CurrentLevel        int      `json:"current_level" example:"5"`                       // 当前等级
 	RequiredHonor       int      `json:"required_honor" example:"100"`                    // 要求荣誉值
 	CurrentHonor        int      `json:"current_honor" example:"0"`                       // 当前荣誉值
+	// 逐项检查结果（等级、荣誉、属性、技能、物品），物品未满足时 shortfall 为缺口数量
+	Requirements []service.AdvancementRequirementStatus `json:"requirements"`
 }
 
 // HeroResponse HTTP hero response (基础信息，不含详细属性和技能)
@@ -363,8 +374,8 @@ func (h *HeroHandler) TransferClass(c echo.Context) error {
 }
 
 // AddExperience handles adding experience to a hero
-// @Summary 增加英雄经验（已弃用）
-// @Description 已弃用，请改用 Admin 工具接口 `/api/v1/admin/tools/grant-experience`
+// @Summary 增加英雄经验（已弃用，测试用）
+// @Description 已弃用，请改用 Admin 工具接口 `/api/v1/admin/tools/grant-experience`。仅在设置 ENABLE_TEST_EXPERIENCE_API=true 时可用，且只能给自己的英雄加经验；正式经验来源为战斗与事件房间结算
 // @Tags 英雄
 // @Deprecated true
 // @Accept json
@@ -373,11 +384,14 @@ func (h *HeroHandler) TransferClass(c echo.Context) error {
 // @Param request body AddExperienceRequest true "增加经验请求"
 // @Success 200 {object} response.Response{data=object{message=string,hero=HeroResponse}} "增加成功"
 // @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 403 {object} response.Response "测试接口未开启或英雄不属于当前用户"
 // @Failure 404 {object} response.Response "英雄不存在"
 // @Failure 500 {object} response.Response "服务器内部错误"
 // @Router /game/heroes/{hero_id}/experience [post]
 func (h *HeroHandler) AddExperience(c echo.Context) error {
-	return response.EchoBadRequest(c, h.respWriter, "接口已废弃，请在 Admin /api/v1/admin/tools/grant-experience 使用加经验工具")
+	if !h.testExperienceEnabled {
+		return response.EchoForbidden(c, h.respWriter, "hero_experience", "add")
+	}
 
 	heroID := c.Param("hero_id")
 	if heroID == "" {
@@ -393,6 +407,19 @@ func (h *HeroHandler) AddExperience(c echo.Context) error {
 		return response.EchoBadRequest(c, h.respWriter, err.Error())
 	}
 
+	// 只能给自己的英雄加经验
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return response.EchoUnauthorized(c, h.respWriter, "未登录")
+	}
+	owner, err := h.heroService.GetHeroByID(c.Request().Context(), heroID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	if owner.UserID != userID {
+		return response.EchoError(c, h.respWriter, xerrors.New(xerrors.CodePermissionDenied, "该英雄不属于您"))
+	}
+
 	// 调用 Service
 	hero, err := h.heroService.AddExperience(c.Request().Context(), heroID, req.Amount)
 	if err != nil {
@@ -425,6 +452,67 @@ func (h *HeroHandler) AddExperience(c echo.Context) error {
 	})
 }
 
+// ExperienceAwardResponse 经验发放记录
+type ExperienceAwardResponse struct {
+	ID          string  `json:"id"`
+	SourceType  string  `json:"source_type" enums:"battle,dungeon_event"` // 经验来源
+	SourceID    string  `json:"source_id"`                                // 来源ID（战斗ID或事件日志ID）
+	HeroLevel   int     `json:"hero_level"`                               // 发放时英雄等级
+	BaseXP      int64   `json:"base_xp"`                                  // 分配并按等级差衰减后的经验
+	PenaltyRate float64 `json:"penalty_rate"`                             // 等级差衰减比例（1 表示不衰减）
+	RestedBonus int64   `json:"rested_bonus"`                             // 休息经验加成
+	AwardedXP   int64   `json:"awarded_xp"`                               // 实际获得经验
+	CreatedAt   string  `json:"created_at"`
+}
+
+// ListExperienceAwards 经验发放记录
+// @Summary 获取经验获取记录
+// @Description 按时间倒序分页返回英雄从战斗与地城事件获得的经验明细
+// @Tags 英雄
+// @Produce json
+// @Param hero_id path string true "英雄ID"
+// @Param limit query int false "每页数量" default(20)
+// @Param offset query int false "偏移量" default(0)
+// @Success 200 {object} response.Response{data=map[string]interface{}}
+// @Failure 403 {object} response.Response "英雄不属于当前用户"
+// @Failure 404 {object} response.Response "英雄不存在"
+// @Router /game/heroes/{hero_id}/experience/awards [get]
+func (h *HeroHandler) ListExperienceAwards(c echo.Context) error {
+	heroID := c.Param("hero_id")
+	if heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "英雄ID不能为空")
+	}
+	userID, _ := c.Get("user_id").(string)
+
+	limit, offset := parsePagination(c, 20)
+	list, total, err := h.experienceService.ListAwards(c.Request().Context(), heroID, userID, limit, offset)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	items := make([]ExperienceAwardResponse, len(list))
+	for i, award := range list {
+		items[i] = ExperienceAwardResponse{
+			ID:          award.ID,
+			SourceType:  award.SourceType,
+			SourceID:    award.SourceID,
+			HeroLevel:   award.HeroLevel,
+			BaseXP:      award.BaseXP,
+			PenaltyRate: award.PenaltyRate,
+			RestedBonus: award.RestedBonus,
+			AwardedXP:   award.AwardedXP,
+			CreatedAt:   award.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	return response.EchoOK(c, h.respWriter, map[string]interface{}{
+		"list":   items,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
 // GetHeroFull handles getting hero full information
 // @Summary 获取英雄完整信息
 // @Description 获取英雄的完整信息，包括基本信息、职业详情、属性列表、技能列表
@@ -506,7 +594,7 @@ func (h *HeroHandler) GetHeroFull(c echo.Context) error {
 
 // CheckAdvancement handles checking advancement requirements
 // @Summary 检查职业进阶条件
-// @Description 检查英雄是否满足指定职业的进阶条件，返回详细的检查结果（等级、荣誉值、缺失的条件等）
+// @Description 检查英雄是否满足指定职业的进阶条件，返回详细的检查结果（等级、荣誉值、属性、技能与物品逐项是否满足及缺口）
 // @Tags 英雄
 // @Accept json
 // @Produce json
@@ -542,6 +630,7 @@ func (h *HeroHandler) CheckAdvancement(c echo.Context) error {
 		CurrentLevel:        serviceResult.CurrentLevel,
 		RequiredHonor:       serviceResult.RequiredHonor,
 		CurrentHonor:        serviceResult.CurrentHonor,
+		Requirements:        serviceResult.Requirements,
 	}
 
 	return response.EchoOK(c, h.respWriter, resp)
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tsu-self/internal/modules/game/service"
+	"tsu-self/internal/pkg/response"
+)
+
+// HeroRespecHandler 英雄洗点 HTTP Handler
+type HeroRespecHandler struct {
+	respecService *service.HeroRespecService
+	respWriter    response.Writer
+}
+
+// NewHeroRespecHandler 创建英雄洗点 Handler
+func NewHeroRespecHandler(serviceContainer *service.ServiceContainer, respWriter response.Writer) *HeroRespecHandler {
+	return &HeroRespecHandler{
+		respecService: serviceContainer.GetRespecService(),
+		respWriter:    respWriter,
+	}
+}
+
+// ==================== 请求/响应模型 ====================
+
+// RespecRequest 洗点请求
+type RespecRequest struct {
+	PaymentMethod string `json:"payment_method" validate:"required,oneof=gold item" example:"gold"` // 支付方式：gold=金币，item=洗点道具
+}
+
+// RespecQuoteResponse 洗点报价响应
+type RespecQuoteResponse struct {
+	HeroLevel   int    `json:"hero_level" example:"12"`          // 英雄等级
+	CostGold    int64  `json:"cost_gold" example:"1000"`         // 金币消耗
+	GoldBalance int64  `json:"gold_balance" example:"2500"`      // 当前金币
+	CanPayGold  bool   `json:"can_pay_gold" example:"true"`      // 金币是否足够
+	ItemID      string `json:"item_id,omitempty"`                // 洗点道具ID（为空表示不支持道具支付）
+	ItemCode    string `json:"item_code,omitempty"`              // 洗点道具代码
+	ItemName    string `json:"item_name,omitempty"`              // 洗点道具名称
+	ItemCount   int    `json:"item_count,omitempty" example:"1"` // 需要道具数量
+	ItemOwned   int    `json:"item_owned" example:"0"`           // 背包持有数量
+	CanPayItem  bool   `json:"can_pay_item" example:"false"`     // 道具是否足够
+}
+
+// RespecResponse 洗点结果响应
+type RespecResponse struct {
+	OperationID     string `json:"operation_id"`                  // 洗点记录ID
+	PaymentMethod   string `json:"payment_method" example:"gold"` // 支付方式
+	GoldSpent       int64  `json:"gold_spent" example:"1000"`     // 消耗金币
+	ItemID          string `json:"item_id,omitempty"`             // 消耗道具ID
+	ItemsSpent      int    `json:"items_spent,omitempty"`         // 消耗道具数量
+	RefundedXP      int64  `json:"refunded_xp" example:"3200"`    // 返还经验
+	AttributesReset int    `json:"attributes_reset" example:"3"`  // 重置的属性数
+	SkillsRemoved   int    `json:"skills_removed" example:"2"`    // 遗忘的技能数
+	SkillsReset     int    `json:"skills_reset" example:"1"`      // 降回 1 级的初始技能数
+}
+
+type respecOperationResponse struct {
+	ID              string          `json:"id"`
+	PaymentMethod   string          `json:"payment_method"`
+	GoldSpent       int64           `json:"gold_spent"`
+	ItemID          *string         `json:"item_id,omitempty"`
+	ItemsSpent      json.RawMessage `json:"items_spent,omitempty"`
+	RefundedXP      int64           `json:"refunded_xp"`
+	AttributesReset int             `json:"attributes_reset"`
+	SkillsRemoved   int             `json:"skills_removed"`
+	SkillsReset     int             `json:"skills_reset"`
+	Snapshot        json.RawMessage `json:"snapshot,omitempty"`
+	CreatedAt       string          `json:"created_at"`
+}
+
+// ==================== Handlers ====================
+
+// GetRespecQuote 查询洗点消耗
+// @Summary 查询洗点消耗
+// @Description 返回英雄当前等级档位的洗点消耗（金币或洗点道具）及是否负担得起
+// @Tags 英雄
+// @Produce json
+// @Param hero_id path string true "英雄ID"
+// @Success 200 {object} response.Response{data=RespecQuoteResponse}
+// @Failure 403 {object} response.Response "英雄不属于当前用户"
+// @Failure 404 {object} response.Response "英雄不存在"
+// @Router /game/heroes/{hero_id}/respec [get]
+func (h *HeroRespecHandler) GetRespecQuote(c echo.Context) error {
+	heroID := c.Param("hero_id")
+	if heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "英雄ID不能为空")
+	}
+	userID, _ := c.Get("user_id").(string)
+
+	quote, err := h.respecService.GetRespecQuote(c.Request().Context(), heroID, userID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, &RespecQuoteResponse{
+		HeroLevel:   quote.HeroLevel,
+		CostGold:    quote.CostGold,
+		GoldBalance: quote.GoldBalance,
+		CanPayGold:  quote.CanPayGold,
+		ItemID:      quote.ItemID,
+		ItemCode:    quote.ItemCode,
+		ItemName:    quote.ItemName,
+		ItemCount:   quote.ItemCount,
+		ItemOwned:   quote.ItemOwned,
+		CanPayItem:  quote.CanPayItem,
+	})
+}
+
+// Respec 洗点
+// @Summary 洗点
+// @Description 支付金币或洗点道具，重置全部属性加点与主动学习的技能（初始技能降回 1 级）并返还经验。洗点后原有加点/技能操作不可再回退
+// @Tags 英雄
+// @Accept json
+// @Produce json
+// @Param hero_id path string true "英雄ID"
+// @Param request body RespecRequest true "洗点请求"
+// @Success 200 {object} response.Response{data=RespecResponse}
+// @Failure 400 {object} response.Response "请求参数错误或资源不足"
+// @Failure 403 {object} response.Response "英雄不属于当前用户"
+// @Failure 404 {object} response.Response "英雄不存在"
+// @Router /game/heroes/{hero_id}/respec [post]
+func (h *HeroRespecHandler) Respec(c echo.Context) error {
+	heroID := c.Param("hero_id")
+	if heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "英雄ID不能为空")
+	}
+
+	var req RespecRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+	userID, _ := c.Get("user_id").(string)
+
+	result, err := h.respecService.Respec(c.Request().Context(), &service.RespecRequest{
+		HeroID:        heroID,
+		UserID:        userID,
+		PaymentMethod: req.PaymentMethod,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, &RespecResponse{
+		OperationID:     result.OperationID,
+		PaymentMethod:   result.PaymentMethod,
+		GoldSpent:       result.GoldSpent,
+		ItemID:          result.ItemID,
+		ItemsSpent:      result.ItemsSpent,
+		RefundedXP:      result.RefundedXP,
+		AttributesReset: result.AttributesReset,
+		SkillsRemoved:   result.SkillsRemoved,
+		SkillsReset:     result.SkillsReset,
+	})
+}
+
+// ListRespecHistory 洗点记录
+// @Summary 获取洗点记录
+// @Description 按时间倒序分页返回英雄的洗点记录（含洗点前的属性与技能快照）
+// @Tags 英雄
+// @Produce json
+// @Param hero_id path string true "英雄ID"
+// @Param limit query int false "每页数量" default(20)
+// @Param offset query int false "偏移量" default(0)
+// @Success 200 {object} response.Response{data=map[string]interface{}}
+// @Router /game/heroes/{hero_id}/respec/history [get]
+func (h *HeroRespecHandler) ListRespecHistory(c echo.Context) error {
+	heroID := c.Param("hero_id")
+	if heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "英雄ID不能为空")
+	}
+	userID, _ := c.Get("user_id").(string)
+
+	limit, offset := parsePagination(c, 20)
+	list, total, err := h.respecService.ListRespecOperations(c.Request().Context(), heroID, userID, limit, offset)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	items := make([]respecOperationResponse, len(list))
+	for i, op := range list {
+		items[i] = respecOperationResponse{
+			ID:              op.ID,
+			PaymentMethod:   op.PaymentMethod,
+			GoldSpent:       op.GoldSpent,
+			ItemID:          op.ItemID,
+			ItemsSpent:      op.ItemsSpent,
+			RefundedXP:      op.RefundedXP,
+			AttributesReset: op.AttributesReset,
+			SkillsRemoved:   op.SkillsRemoved,
+			SkillsReset:     op.SkillsReset,
+			Snapshot:        op.Snapshot,
+			CreatedAt:       op.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	return response.EchoOK(c, h.respWriter, map[string]interface{}{
+		"list":   items,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
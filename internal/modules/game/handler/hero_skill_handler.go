@@ -35,27 +35,48 @@ type UpgradeSkillRequest struct {
 
 // AvailableSkillResponse HTTP available skill response
 type AvailableSkillResponse struct {
-	SkillID           string `json:"skill_id" example:"skill-001"`                // 技能ID
-	SkillName         string `json:"skill_name" example:"烈焰斩"`                    // 技能名称
-	SkillCode         string `json:"skill_code" example:"FLAME_SLASH"`            // 技能代码
-	MaxLevel          int    `json:"max_level" example:"10"`                      // 技能最大等级
-	MaxLearnableLevel int    `json:"max_learnable_level" example:"5"`             // 当前可学习的最大等级（受英雄等级限制）
-	CanLearn          bool   `json:"can_learn" example:"true"`                    // 是否可以学习（满足所有条件）
-	Requirements      string `json:"requirements,omitempty" example:"需要等级5、力量15"` // 学习要求描述
+	SkillID           string             `json:"skill_id" example:"skill-001"`                // 技能ID
+	SkillName         string             `json:"skill_name" example:"烈焰斩"`                    // 技能名称
+	SkillCode         string             `json:"skill_code" example:"FLAME_SLASH"`            // 技能代码
+	MaxLevel          int                `json:"max_level" example:"10"`                      // 技能最大等级
+	MaxLearnableLevel int                `json:"max_learnable_level" example:"5"`             // 当前可学习的最大等级（受英雄等级限制）
+	CanLearn          bool               `json:"can_learn" example:"true"`                    // 是否可以学习（满足所有条件）
+	Requirements      string             `json:"requirements,omitempty" example:"需要等级5、力量15"` // 学习要求描述
+	LearnCost         *SkillCostResponse `json:"learn_cost,omitempty"`                        // 学习消耗及当前是否负担得起（未配置时省略）
+}
+
+// SkillCostResponse HTTP skill cost response
+type SkillCostResponse struct {
+	Level     int                          `json:"level" example:"2"`                              // 学习/升级到的等级
+	CostXP    int                          `json:"cost_xp" example:"200"`                          // 消耗经验
+	CostGold  int                          `json:"cost_gold" example:"100"`                        // 消耗金币
+	Materials []*SkillMaterialCostResponse `json:"materials"`                                      // 消耗材料
+	CanAfford bool                         `json:"can_afford" example:"false"`                     // 当前是否负担得起
+	Missing   []string                     `json:"missing,omitempty" example:"金币不足: 需要 100，当前 50"` // 不足的资源说明
+}
+
+// SkillMaterialCostResponse HTTP skill material cost response
+type SkillMaterialCostResponse struct {
+	ItemID   string `json:"item_id" example:"item-001"`     // 物品ID
+	ItemCode string `json:"item_code" example:"skill_book"` // 物品代码
+	ItemName string `json:"item_name" example:"技能书"`        // 物品名称
+	Count    int    `json:"count" example:"2"`              // 需要数量
+	Owned    int    `json:"owned" example:"1"`              // 背包持有数量
 }
 
 // LearnedSkillResponse HTTP learned skill response
 type LearnedSkillResponse struct {
-	HeroSkillID    string `json:"hero_skill_id" example:"hero-skill-001"`                       // 英雄技能实例ID
-	SkillID        string `json:"skill_id" example:"skill-001"`                                 // 技能配置ID
-	SkillName      string `json:"skill_name" example:"烈焰斩"`                                     // 技能名称
-	SkillCode      string `json:"skill_code" example:"FLAME_SLASH"`                             // 技能代码
-	SkillLevel     int    `json:"skill_level" example:"3"`                                      // 当前等级
-	MaxLevel       int    `json:"max_level" example:"10"`                                       // 最大等级
-	LearnedMethod  string `json:"learned_method" example:"manual" enums:"initial,manual,quest"` // 学习方式：initial=初始技能，manual=手动学习，quest=任务奖励
-	FirstLearnedAt string `json:"first_learned_at" example:"2025-10-17 10:30:00"`               // 首次学习时间
-	CanUpgrade     bool   `json:"can_upgrade" example:"true"`                                   // 是否可以升级
-	CanRollback    bool   `json:"can_rollback" example:"true"`                                  // 是否可以回退（1小时内）
+	HeroSkillID    string             `json:"hero_skill_id" example:"hero-skill-001"`                       // 英雄技能实例ID
+	SkillID        string             `json:"skill_id" example:"skill-001"`                                 // 技能配置ID
+	SkillName      string             `json:"skill_name" example:"烈焰斩"`                                     // 技能名称
+	SkillCode      string             `json:"skill_code" example:"FLAME_SLASH"`                             // 技能代码
+	SkillLevel     int                `json:"skill_level" example:"3"`                                      // 当前等级
+	MaxLevel       int                `json:"max_level" example:"10"`                                       // 最大等级
+	LearnedMethod  string             `json:"learned_method" example:"manual" enums:"initial,manual,quest"` // 学习方式：initial=初始技能，manual=手动学习，quest=任务奖励
+	FirstLearnedAt string             `json:"first_learned_at" example:"2025-10-17 10:30:00"`               // 首次学习时间
+	CanUpgrade     bool               `json:"can_upgrade" example:"true"`                                   // 是否可以升级
+	CanRollback    bool               `json:"can_rollback" example:"true"`                                  // 是否可以回退（1小时内）
+	UpgradeCost    *SkillCostResponse `json:"upgrade_cost,omitempty"`                                       // 升到下一级的消耗及当前是否负担得起（不可升级或未配置时省略）
 }
 
 // ==================== HTTP Handlers ====================
@@ -94,6 +115,7 @@ func (h *HeroSkillHandler) GetAvailableSkills(c echo.Context) error {
 			MaxLearnableLevel: skill.MaxLearnableLevel,
 			CanLearn:          skill.CanLearn,
 			Requirements:      skill.Requirements,
+			LearnCost:         convertSkillCost(skill.LearnCost),
 		}
 	}
 
@@ -146,7 +168,7 @@ func (h *HeroSkillHandler) LearnSkill(c echo.Context) error {
 
 // UpgradeSkill handles upgrading a skill
 // @Summary 升级技能
-// @Description 升级已学习的技能。升级消耗经验，支持1小时内的回退
+// @Description 升级已学习的技能。升级消耗经验、金币与材料，支持1小时内的回退（回退时全额返还）
 // @Tags 英雄技能
 // @Accept json
 // @Produce json
@@ -156,7 +178,7 @@ func (h *HeroSkillHandler) LearnSkill(c echo.Context) error {
 // @Success 200 {object} response.Response{data=object{message=string}} "升级成功，返回 {\"message\": \"技能升级成功\"}"
 // @Failure 400 {object} response.Response "请求参数错误"
 // @Failure 404 {object} response.Response "英雄或技能未学习"
-// @Failure 409 {object} response.Response "经验不足或达到最大等级"
+// @Failure 409 {object} response.Response "经验、金币或材料不足，或达到最大等级"
 // @Failure 500 {object} response.Response "服务器内部错误"
 // @Router /game/heroes/{hero_id}/skills/{skill_id}/upgrade [post]
 func (h *HeroSkillHandler) UpgradeSkill(c echo.Context) error {
@@ -261,8 +283,33 @@ func (h *HeroSkillHandler) GetLearnedSkills(c echo.Context) error {
 			FirstLearnedAt: skill.FirstLearnedAt,
 			CanUpgrade:     skill.CanUpgrade,
 			CanRollback:    skill.CanRollback,
+			UpgradeCost:    convertSkillCost(skill.UpgradeCost),
 		}
 	}
 
 	return response.EchoOK(c, h.respWriter, respList)
 }
+
+func convertSkillCost(info *service.SkillCostInfo) *SkillCostResponse {
+	if info == nil {
+		return nil
+	}
+	resp := &SkillCostResponse{
+		Level:     info.Level,
+		CostXP:    info.CostXP,
+		CostGold:  info.CostGold,
+		Materials: make([]*SkillMaterialCostResponse, len(info.Materials)),
+		CanAfford: info.CanAfford,
+		Missing:   info.Missing,
+	}
+	for i, material := range info.Materials {
+		resp.Materials[i] = &SkillMaterialCostResponse{
+			ItemID:   material.ItemID,
+			ItemCode: material.ItemCode,
+			ItemName: material.ItemName,
+			Count:    material.Count,
+			Owned:    material.Owned,
+		}
+	}
+	return resp
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	custommiddleware "tsu-self/internal/middleware"
+	"tsu-self/internal/modules/game/service"
+	"tsu-self/internal/pkg/response"
+)
+
+// ItemUseHandler 消耗品使用处理器
+type ItemUseHandler struct {
+	itemUseSvc *service.ItemUseService
+	respWriter response.Writer
+}
+
+// NewItemUseHandler 创建消耗品使用处理器
+func NewItemUseHandler(serviceContainer *service.ServiceContainer, respWriter response.Writer) *ItemUseHandler {
+	return &ItemUseHandler{
+		itemUseSvc: serviceContainer.GetItemUseService(),
+		respWriter: respWriter,
+	}
+}
+
+// ==================== HTTP Request/Response Models ====================
+
+// UseItemRequest 使用物品请求
+type UseItemRequest struct {
+	ItemInstanceID string `json:"item_instance_id" validate:"required" example:"660e8400-e29b-41d4-a716-446655440001"` // 背包中的物品实例ID
+}
+
+// UseItemEffectInfo 使用效果结果
+type UseItemEffectInfo struct {
+	Effect           string     `json:"effect" example:"ADD_EXPERIENCE"`           // 效果类型
+	Applied          bool       `json:"applied" example:"true"`                    // 是否已生效
+	Skipped          string     `json:"skipped,omitempty"`                         // 跳过原因
+	ExperienceGained int64      `json:"experience_gained,omitempty" example:"500"` // 获得经验
+	GoldGained       int64      `json:"gold_gained,omitempty" example:"200"`       // 获得金币
+	RefundedXP       int64      `json:"refunded_xp,omitempty" example:"1200"`      // 重置返还的经验
+	BuffCode         string     `json:"buff_code,omitempty" example:"STR_ELIXIR"`  // 施加的 Buff
+	BuffExpiresAt    *time.Time `json:"buff_expires_at,omitempty"`                 // Buff 过期时间
+}
+
+// UseItemResponse 使用物品响应
+type UseItemResponse struct {
+	ItemInstanceID string               `json:"item_instance_id"`            // 物品实例ID
+	ItemID         string               `json:"item_id"`                     // 物品配置ID
+	RemainingCount int                  `json:"remaining_count" example:"4"` // 剩余堆叠数量（0 表示已用完）
+	Effects        []*UseItemEffectInfo `json:"effects"`                     // 效果执行结果
+}
+
+// ==================== HTTP Handlers ====================
+
+// UseItem 使用物品
+// @Summary 使用物品
+// @Description 使用当前英雄背包中的消耗品，按物品的 use_effects 执行经验、金币、重置卷轴、限时 Buff 等效果并扣减一个堆叠
+// @Tags Inventory
+// @Accept json
+// @Produce json
+// @Param request body UseItemRequest true "使用物品请求"
+// @Success 200 {object} response.Response{data=UseItemResponse} "成功"
+// @Failure 400 {object} response.Response "参数错误"
+// @Failure 403 {object} response.Response "只能使用自己的物品"
+// @Failure 404 {object} response.Response "资源不存在"
+// @Failure 500 {object} response.Response "服务器错误"
+// @Router /game/inventory/use [post]
+func (h *ItemUseHandler) UseItem(c echo.Context) error {
+	var req UseItemRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求参数格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoValidationError(c, h.respWriter, err)
+	}
+
+	heroID, err := custommiddleware.GetCurrentHeroID(c)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	result, err := h.itemUseSvc.UseItem(c.Request().Context(), &service.UseItemRequest{
+		HeroID:         heroID,
+		ItemInstanceID: req.ItemInstanceID,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	resp := &UseItemResponse{
+		ItemInstanceID: result.ItemInstanceID,
+		ItemID:         result.ItemID,
+		RemainingCount: result.RemainingCount,
+		Effects:        make([]*UseItemEffectInfo, 0, len(result.Effects)),
+	}
+	for _, effect := range result.Effects {
+		resp.Effects = append(resp.Effects, &UseItemEffectInfo{
+			Effect:           effect.Effect,
+			Applied:          effect.Applied,
+			Skipped:          effect.Skipped,
+			ExperienceGained: effect.ExperienceGained,
+			GoldGained:       effect.GoldGained,
+			RefundedXP:       effect.RefundedXP,
+			BuffCode:         effect.BuffCode,
+			BuffExpiresAt:    effect.BuffExpiresAt,
+		})
+	}
+	return response.EchoOK(c, h.respWriter, resp)
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tsu-self/internal/modules/game/service"
+	"tsu-self/internal/pkg/response"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// TeamActivityHandler 团队动态 Handler
+type TeamActivityHandler struct {
+	activityService *service.TeamActivityService
+	respWriter      response.Writer
+}
+
+// NewTeamActivityHandler 创建团队动态 Handler
+func NewTeamActivityHandler(serviceContainer *service.ServiceContainer, respWriter response.Writer) *TeamActivityHandler {
+	return &TeamActivityHandler{
+		activityService: serviceContainer.GetTeamActivityService(),
+		respWriter:      respWriter,
+	}
+}
+
+// ==================== HTTP Request/Response Models ====================
+
+// TeamActivityResponse 团队动态
+type TeamActivityResponse struct {
+	ID           string          `json:"id" example:"activity-uuid-001"`
+	Category     string          `json:"category" example:"warehouse"`          // team/member/rank/warehouse/contribution/dungeon
+	EventType    string          `json:"event_type" example:"gold_distributed"` // 事件类型
+	ActorHeroID  *string         `json:"actor_hero_id,omitempty"`               // 操作者，系统触发时为空
+	TargetHeroID *string         `json:"target_hero_id,omitempty"`              // 受影响的成员
+	SourceID     *string         `json:"source_id,omitempty"`                   // 关联的业务记录ID
+	Details      json.RawMessage `json:"details,omitempty"`                     // 事件详情
+	CreatedAt    string          `json:"created_at" example:"2025-01-01T12:00:00Z"`
+}
+
+// TeamActivityListResponse 团队动态列表
+type TeamActivityListResponse struct {
+	Activities []*TeamActivityResponse `json:"activities"`
+	Total      int64                   `json:"total"`
+	Limit      int                     `json:"limit"`
+	Offset     int                     `json:"offset"`
+}
+
+// ==================== HTTP Handlers ====================
+
+// ListActivity 查看团队动态
+// @Summary 查看团队动态
+// @Description 按时间倒序查看团队时间线（成员变动、职阶、仓库、贡献点、地城），需要查看团队信息权限
+// @Tags 团队动态
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param hero_id query string true "操作者英雄ID"
+// @Param category query string false "动态分类（team/member/rank/warehouse/contribution/dungeon）"
+// @Param event_type query string false "事件类型"
+// @Param filter_hero_id query string false "只看与该英雄相关的动态"
+// @Param since query string false "起始时间（RFC3339，含）"
+// @Param until query string false "截止时间（RFC3339，不含）"
+// @Param limit query int false "每页数量（最大 100）" default(20)
+// @Param offset query int false "偏移量" default(0)
+// @Success 200 {object} response.Response{data=TeamActivityListResponse} "获取成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 403 {object} response.Response "没有查看团队动态的权限"
+// @Failure 404 {object} response.Response "您不是该团队成员"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/activity [get]
+func (h *TeamActivityHandler) ListActivity(c echo.Context) error {
+	teamID := c.Param("team_id")
+	heroID := c.QueryParam("hero_id")
+	if teamID == "" || heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "参数不能为空")
+	}
+	since, err := parseOptionalTime(c.QueryParam("since"))
+	if err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "since 时间格式错误，应为 RFC3339")
+	}
+	until, err := parseOptionalTime(c.QueryParam("until"))
+	if err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "until 时间格式错误，应为 RFC3339")
+	}
+	limit, offset := parsePagination(c, 20)
+
+	activities, total, err := h.activityService.ListActivity(c.Request().Context(), &service.ListTeamActivityRequest{
+		TeamID:       teamID,
+		HeroID:       heroID,
+		Category:     c.QueryParam("category"),
+		EventType:    c.QueryParam("event_type"),
+		FilterHeroID: c.QueryParam("filter_hero_id"),
+		Since:        since,
+		Until:        until,
+		Limit:        limit,
+		Offset:       offset,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	resp := &TeamActivityListResponse{
+		Activities: make([]*TeamActivityResponse, 0, len(activities)),
+		Total:      total,
+		Limit:      limit,
+		Offset:     offset,
+	}
+	for _, activity := range activities {
+		resp.Activities = append(resp.Activities, toTeamActivityResponse(activity))
+	}
+	return response.EchoOK(c, h.respWriter, resp)
+}
+
+// ==================== Helper Functions ====================
+
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+func toTeamActivityResponse(activity *interfaces.TeamActivity) *TeamActivityResponse {
+	return &TeamActivityResponse{
+		ID:           activity.ID,
+		Category:     activity.Category,
+		EventType:    activity.EventType,
+		ActorHeroID:  activity.ActorHeroID,
+		TargetHeroID: activity.TargetHeroID,
+		SourceID:     activity.SourceID,
+		Details:      activity.Details,
+		CreatedAt:    activity.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,502 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tsu-self/internal/modules/game/service"
+	"tsu-self/internal/pkg/response"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// TeamContributionHandler 团队贡献点（DKP）Handler
+type TeamContributionHandler struct {
+	contributionService *service.TeamContributionService
+	respWriter          response.Writer
+}
+
+// NewTeamContributionHandler 创建团队贡献点 Handler
+func NewTeamContributionHandler(serviceContainer *service.ServiceContainer, respWriter response.Writer) *TeamContributionHandler {
+	return &TeamContributionHandler{
+		contributionService: serviceContainer.GetContributionService(),
+		respWriter:          respWriter,
+	}
+}
+
+// ==================== HTTP Request/Response Models ====================
+
+// ContributionStandingResponse 成员贡献点
+type ContributionStandingResponse struct {
+	HeroID         string `json:"hero_id" example:"hero-uuid-001"`
+	Balance        int64  `json:"balance" example:"120"`         // 可用贡献点（出价冻结的点数已扣除）
+	LifetimeEarned int64  `json:"lifetime_earned" example:"300"` // 累计获得
+}
+
+// ContributionEntryResponse 贡献点流水
+type ContributionEntryResponse struct {
+	ID             string  `json:"id" example:"entry-uuid-001"`
+	HeroID         string  `json:"hero_id" example:"hero-uuid-001"`
+	Amount         int64   `json:"amount" example:"10"` // 正数为获得，负数为消耗
+	BalanceAfter   int64   `json:"balance_after" example:"130"`
+	SourceType     string  `json:"source_type" example:"dungeon_completion"` // dungeon_completion/manual_grant/auction_bid/auction_refund
+	SourceID       *string `json:"source_id,omitempty"`
+	OperatorHeroID *string `json:"operator_hero_id,omitempty"`
+	Note           *string `json:"note,omitempty"`
+	CreatedAt      string  `json:"created_at" example:"2025-01-01T12:00:00Z"`
+}
+
+// ContributionLedgerResponse 贡献点流水列表
+type ContributionLedgerResponse struct {
+	Entries []*ContributionEntryResponse `json:"entries"`
+	Total   int64                        `json:"total"`
+	Limit   int                          `json:"limit"`
+	Offset  int                          `json:"offset"`
+}
+
+// GrantContributionRequest HTTP 手动发放贡献点请求
+type GrantContributionRequest struct {
+	HeroID  string   `json:"hero_id" validate:"required" example:"hero-uuid-001"` // 操作者英雄ID
+	HeroIDs []string `json:"hero_ids" validate:"required,min=1"`                  // 接收成员
+	Amount  int64    `json:"amount" validate:"required" example:"20"`             // 正数为发放，负数为扣除
+	Note    string   `json:"note" example:"周末出勤"`
+}
+
+// ContributionSettingsResponse 贡献点配置
+type ContributionSettingsResponse struct {
+	DungeonCompletionPoints int `json:"dungeon_completion_points" example:"10"`  // 通关每人获得
+	AuctionDurationSeconds  int `json:"auction_duration_seconds" example:"3600"` // 竞拍默认时长
+}
+
+// UpdateContributionSettingsRequest HTTP 更新贡献点配置请求
+type UpdateContributionSettingsRequest struct {
+	HeroID                  string `json:"hero_id" validate:"required" example:"hero-uuid-001"`
+	DungeonCompletionPoints *int   `json:"dungeon_completion_points,omitempty" example:"10"`
+	AuctionDurationSeconds  *int   `json:"auction_duration_seconds,omitempty" example:"3600"`
+}
+
+// CreateContributionAuctionRequest HTTP 创建竞拍请求
+type CreateContributionAuctionRequest struct {
+	HeroID          string `json:"hero_id" validate:"required" example:"hero-uuid-001"`
+	ItemID          string `json:"item_id" validate:"required" example:"item-uuid-001"`
+	Quantity        int    `json:"quantity" validate:"required,min=1" example:"1"`
+	MinBid          int64  `json:"min_bid,omitempty" example:"10"`            // 起拍价，默认 1
+	DurationSeconds int    `json:"duration_seconds,omitempty" example:"3600"` // 竞拍时长，默认使用团队配置
+}
+
+// PlaceContributionBidRequest HTTP 出价请求
+type PlaceContributionBidRequest struct {
+	HeroID string `json:"hero_id" validate:"required" example:"hero-uuid-001"`
+	Amount int64  `json:"amount" validate:"required,min=1" example:"25"`
+}
+
+// CancelContributionAuctionRequest HTTP 取消竞拍请求
+type CancelContributionAuctionRequest struct {
+	HeroID string `json:"hero_id" validate:"required" example:"hero-uuid-001"`
+}
+
+// ContributionAuctionResponse 竞拍
+type ContributionAuctionResponse struct {
+	ID                  string  `json:"id" example:"auction-uuid-001"`
+	ItemID              string  `json:"item_id" example:"item-uuid-001"`
+	Quantity            int     `json:"quantity" example:"1"`
+	MinBid              int64   `json:"min_bid" example:"10"`
+	Status              string  `json:"status" example:"open"` // open/settling/awarded/unsold/failed/cancelled
+	CreatedBy           string  `json:"created_by" example:"hero-uuid-001"`
+	EndsAt              string  `json:"ends_at" example:"2025-01-01T13:00:00Z"`
+	HighestBid          int64   `json:"highest_bid" example:"25"`
+	HighestBidderHeroID *string `json:"highest_bidder_hero_id,omitempty"`
+	ResultNote          *string `json:"result_note,omitempty"`
+	CreatedAt           string  `json:"created_at" example:"2025-01-01T12:00:00Z"`
+	SettledAt           *string `json:"settled_at,omitempty"`
+}
+
+// ContributionAuctionsResponse 竞拍列表
+type ContributionAuctionsResponse struct {
+	Auctions []*ContributionAuctionResponse `json:"auctions"`
+	Total    int64                          `json:"total"`
+	Limit    int                            `json:"limit"`
+	Offset   int                            `json:"offset"`
+}
+
+// ==================== HTTP Handlers ====================
+
+// GetStandings 查看贡献点排行
+// @Summary 查看贡献点排行
+// @Description 查看团队成员的贡献点余额与累计获得（任何团队成员均可查看）
+// @Tags 团队贡献点
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param hero_id query string true "操作者英雄ID"
+// @Success 200 {object} response.Response{data=[]ContributionStandingResponse} "获取成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 404 {object} response.Response "您不是该团队成员"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/contribution [get]
+func (h *TeamContributionHandler) GetStandings(c echo.Context) error {
+	teamID := c.Param("team_id")
+	heroID := c.QueryParam("hero_id")
+	if teamID == "" || heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "参数不能为空")
+	}
+
+	standings, err := h.contributionService.GetStandings(c.Request().Context(), teamID, heroID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	resp := make([]*ContributionStandingResponse, 0, len(standings))
+	for _, standing := range standings {
+		resp = append(resp, &ContributionStandingResponse{
+			HeroID:         standing.HeroID,
+			Balance:        standing.Balance,
+			LifetimeEarned: standing.LifetimeEarned,
+		})
+	}
+	return response.EchoOK(c, h.respWriter, resp)
+}
+
+// GetLedger 查看贡献点流水
+// @Summary 查看贡献点流水
+// @Tags 团队贡献点
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param hero_id query string true "操作者英雄ID"
+// @Param member_hero_id query string false "只看某名成员"
+// @Param limit query int false "每页数量" default(20)
+// @Param offset query int false "偏移量" default(0)
+// @Success 200 {object} response.Response{data=ContributionLedgerResponse} "获取成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 404 {object} response.Response "您不是该团队成员"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/contribution/ledger [get]
+func (h *TeamContributionHandler) GetLedger(c echo.Context) error {
+	teamID := c.Param("team_id")
+	heroID := c.QueryParam("hero_id")
+	if teamID == "" || heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "参数不能为空")
+	}
+	limit, offset := parsePagination(c, 20)
+
+	entries, total, err := h.contributionService.ListLedger(c.Request().Context(), teamID, heroID, c.QueryParam("member_hero_id"), limit, offset)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	resp := &ContributionLedgerResponse{
+		Entries: make([]*ContributionEntryResponse, 0, len(entries)),
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+	}
+	for _, entry := range entries {
+		resp.Entries = append(resp.Entries, toContributionEntryResponse(entry))
+	}
+	return response.EchoOK(c, h.respWriter, resp)
+}
+
+// GrantPoints 手动发放贡献点
+// @Summary 手动发放贡献点
+// @Description 向一名或多名成员发放（正数）或扣除（负数）贡献点，余额不能为负（队长和管理员）
+// @Tags 团队贡献点
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param request body GrantContributionRequest true "发放请求"
+// @Success 200 {object} response.Response{data=[]ContributionEntryResponse} "发放成功"
+// @Failure 400 {object} response.Response "请求参数错误或贡献点不足"
+// @Failure 403 {object} response.Response "需要管理员或队长权限"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/contribution/grants [post]
+func (h *TeamContributionHandler) GrantPoints(c echo.Context) error {
+	teamID := c.Param("team_id")
+	if teamID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "团队ID不能为空")
+	}
+
+	var req GrantContributionRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	entries, err := h.contributionService.GrantPoints(c.Request().Context(), &service.GrantContributionRequest{
+		TeamID:     teamID,
+		OperatorID: req.HeroID,
+		HeroIDs:    req.HeroIDs,
+		Amount:     req.Amount,
+		Note:       req.Note,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	resp := make([]*ContributionEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		resp = append(resp, toContributionEntryResponse(entry))
+	}
+	return response.EchoOK(c, h.respWriter, resp)
+}
+
+// GetSettings 查看贡献点配置
+// @Summary 查看贡献点配置
+// @Tags 团队贡献点
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param hero_id query string true "操作者英雄ID"
+// @Success 200 {object} response.Response{data=ContributionSettingsResponse} "获取成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 404 {object} response.Response "您不是该团队成员"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/contribution/settings [get]
+func (h *TeamContributionHandler) GetSettings(c echo.Context) error {
+	teamID := c.Param("team_id")
+	heroID := c.QueryParam("hero_id")
+	if teamID == "" || heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "参数不能为空")
+	}
+
+	settings, err := h.contributionService.GetSettings(c.Request().Context(), teamID, heroID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, &ContributionSettingsResponse{
+		DungeonCompletionPoints: settings.DungeonCompletionPoints,
+		AuctionDurationSeconds:  settings.AuctionDurationSeconds,
+	})
+}
+
+// UpdateSettings 更新贡献点配置
+// @Summary 更新贡献点配置
+// @Tags 团队贡献点
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param request body UpdateContributionSettingsRequest true "配置"
+// @Success 200 {object} response.Response{data=ContributionSettingsResponse} "更新成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 403 {object} response.Response "需要管理员或队长权限"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/contribution/settings [put]
+func (h *TeamContributionHandler) UpdateSettings(c echo.Context) error {
+	teamID := c.Param("team_id")
+	if teamID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "团队ID不能为空")
+	}
+
+	var req UpdateContributionSettingsRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	settings, err := h.contributionService.UpdateSettings(c.Request().Context(), &service.UpdateContributionSettingsRequest{
+		TeamID:                  teamID,
+		HeroID:                  req.HeroID,
+		DungeonCompletionPoints: req.DungeonCompletionPoints,
+		AuctionDurationSeconds:  req.AuctionDurationSeconds,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, &ContributionSettingsResponse{
+		DungeonCompletionPoints: settings.DungeonCompletionPoints,
+		AuctionDurationSeconds:  settings.AuctionDurationSeconds,
+	})
+}
+
+// ListAuctions 获取竞拍列表
+// @Summary 获取竞拍列表
+// @Tags 团队贡献点
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param hero_id query string true "操作者英雄ID"
+// @Param status query string false "状态过滤：open/settling/awarded/unsold/failed/cancelled"
+// @Param limit query int false "每页数量" default(20)
+// @Param offset query int false "偏移量" default(0)
+// @Success 200 {object} response.Response{data=ContributionAuctionsResponse} "获取成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 404 {object} response.Response "您不是该团队成员"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/contribution/auctions [get]
+func (h *TeamContributionHandler) ListAuctions(c echo.Context) error {
+	teamID := c.Param("team_id")
+	heroID := c.QueryParam("hero_id")
+	if teamID == "" || heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "参数不能为空")
+	}
+	limit, offset := parsePagination(c, 20)
+
+	auctions, total, err := h.contributionService.ListAuctions(c.Request().Context(), teamID, heroID, c.QueryParam("status"), limit, offset)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	resp := &ContributionAuctionsResponse{
+		Auctions: make([]*ContributionAuctionResponse, 0, len(auctions)),
+		Total:    total,
+		Limit:    limit,
+		Offset:   offset,
+	}
+	for _, auction := range auctions {
+		resp.Auctions = append(resp.Auctions, toContributionAuctionResponse(auction))
+	}
+	return response.EchoOK(c, h.respWriter, resp)
+}
+
+// CreateAuction 上架竞拍
+// @Summary 上架竞拍
+// @Description 将仓库物品上架贡献点竞拍，到期后最高出价者获得物品（队长和管理员）
+// @Tags 团队贡献点
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param request body CreateContributionAuctionRequest true "竞拍"
+// @Success 200 {object} response.Response{data=ContributionAuctionResponse} "创建成功"
+// @Failure 400 {object} response.Response "请求参数错误或库存不足"
+// @Failure 403 {object} response.Response "需要管理员或队长权限"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/contribution/auctions [post]
+func (h *TeamContributionHandler) CreateAuction(c echo.Context) error {
+	teamID := c.Param("team_id")
+	if teamID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "团队ID不能为空")
+	}
+
+	var req CreateContributionAuctionRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	auction, err := h.contributionService.CreateAuction(c.Request().Context(), &service.CreateContributionAuctionRequest{
+		TeamID:          teamID,
+		HeroID:          req.HeroID,
+		ItemID:          req.ItemID,
+		Quantity:        req.Quantity,
+		MinBid:          req.MinBid,
+		DurationSeconds: req.DurationSeconds,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, toContributionAuctionResponse(auction))
+}
+
+// PlaceBid 竞拍出价
+// @Summary 竞拍出价
+// @Description 出价时冻结相应贡献点，被超价时退还；当前最高出价者加价只冻结差额
+// @Tags 团队贡献点
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param auction_id path string true "竞拍ID"
+// @Param request body PlaceContributionBidRequest true "出价"
+// @Success 200 {object} response.Response{data=ContributionAuctionResponse} "出价成功"
+// @Failure 400 {object} response.Response "出价过低、贡献点不足或竞拍已结束"
+// @Failure 404 {object} response.Response "竞拍不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/contribution/auctions/{auction_id}/bids [post]
+func (h *TeamContributionHandler) PlaceBid(c echo.Context) error {
+	teamID := c.Param("team_id")
+	auctionID := c.Param("auction_id")
+	if teamID == "" || auctionID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "参数不能为空")
+	}
+
+	var req PlaceContributionBidRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	auction, err := h.contributionService.PlaceBid(c.Request().Context(), &service.PlaceContributionBidRequest{
+		TeamID:    teamID,
+		AuctionID: auctionID,
+		HeroID:    req.HeroID,
+		Amount:    req.Amount,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, toContributionAuctionResponse(auction))
+}
+
+// CancelAuction 取消竞拍
+// @Summary 取消竞拍
+// @Description 取消进行中的竞拍并退还最高出价（队长和管理员）
+// @Tags 团队贡献点
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param auction_id path string true "竞拍ID"
+// @Param request body CancelContributionAuctionRequest true "操作者"
+// @Success 200 {object} response.Response{data=ContributionAuctionResponse} "取消成功"
+// @Failure 400 {object} response.Response "竞拍已结束"
+// @Failure 403 {object} response.Response "需要管理员或队长权限"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/contribution/auctions/{auction_id}/cancel [post]
+func (h *TeamContributionHandler) CancelAuction(c echo.Context) error {
+	teamID := c.Param("team_id")
+	auctionID := c.Param("auction_id")
+	if teamID == "" || auctionID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "参数不能为空")
+	}
+
+	var req CancelContributionAuctionRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	auction, err := h.contributionService.CancelAuction(c.Request().Context(), teamID, auctionID, req.HeroID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, toContributionAuctionResponse(auction))
+}
+
+func toContributionEntryResponse(entry *interfaces.TeamContributionEntry) *ContributionEntryResponse {
+	return &ContributionEntryResponse{
+		ID:             entry.ID,
+		HeroID:         entry.HeroID,
+		Amount:         entry.Amount,
+		BalanceAfter:   entry.BalanceAfter,
+		SourceType:     entry.SourceType,
+		SourceID:       entry.SourceID,
+		OperatorHeroID: entry.OperatorHeroID,
+		Note:           entry.Note,
+		CreatedAt:      entry.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func toContributionAuctionResponse(auction *interfaces.TeamContributionAuction) *ContributionAuctionResponse {
+	resp := &ContributionAuctionResponse{
+		ID:                  auction.ID,
+		ItemID:              auction.ItemID,
+		Quantity:            auction.Quantity,
+		MinBid:              auction.MinBid,
+		Status:              auction.Status,
+		CreatedBy:           auction.CreatedBy,
+		EndsAt:              auction.EndsAt.Format(time.RFC3339),
+		HighestBid:          auction.HighestBid,
+		HighestBidderHeroID: auction.HighestBidderHeroID,
+		ResultNote:          auction.ResultNote,
+		CreatedAt:           auction.CreatedAt.Format(time.RFC3339),
+	}
+	if auction.SettledAt != nil {
+		settledAt := auction.SettledAt.Format(time.RFC3339)
+		resp.SettledAt = &settledAt
+	}
+	return resp
+}
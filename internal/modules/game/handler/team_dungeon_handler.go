@@ -40,9 +40,8 @@ type enterDungeonRequest struct {
 }
 
 type completeDungeonRequest struct {
-	HeroID    string      `json:"hero_id" validate:"required"`
-	DungeonID string      `json:"dungeon_id" validate:"required"`
-	Loot      lootPayload `json:"loot"`
+	HeroID    string `json:"hero_id" validate:"required"`
+	DungeonID string `json:"dungeon_id" validate:"required"`
 }
 
 type failDungeonRequest struct {
@@ -57,17 +56,6 @@ type abandonDungeonRequest struct {
 	Reason    string `json:"reason,omitempty"`
 }
 
-type lootPayload struct {
-	Gold  int64             `json:"gold"`
-	Items []lootItemPayload `json:"items"`
-}
-
-type lootItemPayload struct {
-	ItemID   string `json:"item_id" validate:"required"`
-	ItemType string `json:"item_type,omitempty"`
-	Quantity int    `json:"quantity" validate:"gte=1"`
-}
-
 type dungeonProgressResponse struct {
 	ID              string   `json:"id"`
 	TeamID          string   `json:"team_id"`
@@ -80,6 +68,33 @@ type dungeonProgressResponse struct {
 	LastUpdatedTime string   `json:"updated_at"`
 }
 
+type advanceRoomRequest struct {
+	HeroID string `json:"hero_id" validate:"required"`
+}
+
+type resolveRoomRequest struct {
+	HeroID   string `json:"hero_id" validate:"required"`
+	RoomID   string `json:"room_id,omitempty"`
+	BattleID string `json:"battle_id,omitempty"`
+}
+
+type dungeonRoomResponse struct {
+	RoomID    string  `json:"room_id"`
+	RoomCode  string  `json:"room_code"`
+	RoomName  *string `json:"room_name,omitempty"`
+	RoomType  string  `json:"room_type"`
+	TriggerID *string `json:"trigger_id,omitempty"`
+}
+
+type roomTraversalResponse struct {
+	Progress       *dungeonProgressResponse `json:"progress"`
+	Room           *dungeonRoomResponse     `json:"room,omitempty"`
+	Resolution     *service.RoomResolution  `json:"resolution,omitempty"`
+	SkippedRooms   []string                 `json:"skipped_rooms,omitempty"`
+	ReturnedTo     string                   `json:"returned_to,omitempty"`
+	DungeonCleared bool                     `json:"dungeon_cleared"`
+}
+
 type dungeonHistoryItem struct {
 	DungeonID     string `json:"dungeon_id"`
 	AttemptsCount int    `json:"attempts_count"`
@@ -175,6 +190,7 @@ func (h *TeamDungeonHandler) GetDungeonProgress(c echo.Context) error {
 
 // CompleteDungeon 完成地城
 // @Summary 完成地城
+// @Description 仅限队长/管理员手动完成；战利品只由战斗结算在服务端计算，不接受客户端上报
 // @Tags 地城
 // @Accept json
 // @Produce json
@@ -196,10 +212,6 @@ func (h *TeamDungeonHandler) CompleteDungeon(c echo.Context) error {
 		TeamID:    teamID,
 		HeroID:    req.HeroID,
 		DungeonID: req.DungeonID,
-		Loot: service.LootData{
-			Gold:  req.Loot.Gold,
-			Items: convertLootItems(req.Loot.Items),
-		},
 	})
 	if err != nil {
 		return response.EchoError(c, h.respWriter, err)
@@ -315,6 +327,90 @@ func (h *TeamDungeonHandler) GetDungeonHistory(c echo.Context) error {
 	})
 }
 
+// GetCurrentRoom 查看当前房间
+// @Summary 查看团队当前所在房间
+// @Tags 地城
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param hero_id query string true "英雄ID"
+// @Success 200 {object} response.Response{data=roomTraversalResponse}
+// @Router /game/teams/{team_id}/dungeons/rooms/current [get]
+func (h *TeamDungeonHandler) GetCurrentRoom(c echo.Context) error {
+	teamID := c.Param("team_id")
+	heroID := c.QueryParam("hero_id")
+	if heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "hero_id 不能为空")
+	}
+
+	result, err := h.dungeonService.GetCurrentRoom(c.Request().Context(), teamID, heroID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, toRoomTraversalResponse(result))
+}
+
+// AdvanceRoom 前进到下一房间
+// @Summary 前进到下一房间
+// @Description 队长/管理员在当前房间结算后推进到下一个房间，按房间序列的条件跳过规则与房间开启条件判定
+// @Tags 地城
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param request body advanceRoomRequest true "前进请求"
+// @Success 200 {object} response.Response{data=roomTraversalResponse}
+// @Router /game/teams/{team_id}/dungeons/rooms/advance [post]
+func (h *TeamDungeonHandler) AdvanceRoom(c echo.Context) error {
+	teamID := c.Param("team_id")
+	var req advanceRoomRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	result, err := h.dungeonService.AdvanceRoom(c.Request().Context(), &service.AdvanceRoomRequest{
+		TeamID: teamID,
+		HeroID: req.HeroID,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, toRoomTraversalResponse(result))
+}
+
+// ResolveRoom 结算当前房间
+// @Summary 结算当前房间
+// @Description 队长/管理员结算当前房间，战斗房间需提供已由战斗引擎回调结算的 battle_id，胜负以战报为准，失败时按回退规则返回或判定挑战失败；最后一个房间成功时自动通关
+// @Tags 地城
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param request body resolveRoomRequest true "结算请求"
+// @Success 200 {object} response.Response{data=roomTraversalResponse}
+// @Router /game/teams/{team_id}/dungeons/rooms/resolve [post]
+func (h *TeamDungeonHandler) ResolveRoom(c echo.Context) error {
+	teamID := c.Param("team_id")
+	var req resolveRoomRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	result, err := h.dungeonService.ResolveRoom(c.Request().Context(), &service.ResolveRoomRequest{
+		TeamID:   teamID,
+		HeroID:   req.HeroID,
+		RoomID:   req.RoomID,
+		BattleID: req.BattleID,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, toRoomTraversalResponse(result))
+}
+
 // ==================== 辅助函数 ====================
 
 func toDungeonProgressResponse(progress *game_runtime.TeamDungeonProgress) *dungeonProgressResponse {
@@ -346,6 +442,35 @@ func toDungeonProgressResponse(progress *game_runtime.TeamDungeonProgress) *dung
 	}
 }
 
+func toRoomTraversalResponse(result *service.RoomTraversalResult) *roomTraversalResponse {
+	if result == nil {
+		return nil
+	}
+	resp := &roomTraversalResponse{
+		Progress:       toDungeonProgressResponse(result.Progress),
+		Resolution:     result.Resolution,
+		SkippedRooms:   result.SkippedRooms,
+		ReturnedTo:     result.ReturnedTo,
+		DungeonCleared: result.DungeonCleared,
+	}
+	if room := result.Room; room != nil {
+		resp.Room = &dungeonRoomResponse{
+			RoomID:   room.ID,
+			RoomCode: room.RoomCode,
+			RoomType: room.RoomType,
+		}
+		if room.RoomName.Valid {
+			value := room.RoomName.String
+			resp.Room.RoomName = &value
+		}
+		if room.TriggerID.Valid {
+			value := room.TriggerID.String
+			resp.Room.TriggerID = &value
+		}
+	}
+	return resp
+}
+
 func decodeCompletedRooms(data types.JSON) []string {
 	if len(data) == 0 {
 		return nil
@@ -357,24 +482,6 @@ func decodeCompletedRooms(data types.JSON) []string {
 	return rooms
 }
 
-func convertLootItems(items []lootItemPayload) []service.LootItem {
-	if len(items) == 0 {
-		return nil
-	}
-	result := make([]service.LootItem, 0, len(items))
-	for _, item := range items {
-		if item.ItemID == "" || item.Quantity <= 0 {
-			continue
-		}
-		result = append(result, service.LootItem{
-			ItemID:   item.ItemID,
-			ItemType: item.ItemType,
-			Quantity: item.Quantity,
-		})
-	}
-	return result
-}
-
 // helper: parse limit/offset with defaults
 func parsePagination(c echo.Context, defaultLimit int) (int, int) {
 	limit := defaultLimit
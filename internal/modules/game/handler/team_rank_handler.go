@@ -0,0 +1,298 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tsu-self/internal/modules/game/service"
+	"tsu-self/internal/pkg/response"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// TeamRankHandler 团队职阶 Handler
+type TeamRankHandler struct {
+	rankService *service.TeamRankService
+	respWriter  response.Writer
+}
+
+// NewTeamRankHandler 创建团队职阶 Handler
+func NewTeamRankHandler(serviceContainer *service.ServiceContainer, respWriter response.Writer) *TeamRankHandler {
+	return &TeamRankHandler{
+		rankService: serviceContainer.GetTeamRankService(),
+		respWriter:  respWriter,
+	}
+}
+
+// ==================== HTTP Request/Response Models ====================
+
+// SaveTeamRankRequest HTTP 创建/更新职阶请求
+type SaveTeamRankRequest struct {
+	HeroID      string   `json:"hero_id" validate:"required" example:"hero-uuid-001"`  // 操作者英雄ID
+	Name        string   `json:"name" validate:"required,max=32" example:"军需官"`        // 职阶名称
+	Level       int      `json:"level" validate:"required,min=1,max=99" example:"40"`  // 职阶等级，需低于操作者等级
+	Permissions []string `json:"permissions" example:"distribute_loot,view_warehouse"` // 授予的权限
+}
+
+// AssignTeamRankRequest HTTP 分配职阶请求
+type AssignTeamRankRequest struct {
+	HeroID       string `json:"hero_id" validate:"required" example:"hero-uuid-001"`        // 操作者英雄ID
+	TargetHeroID string `json:"target_hero_id" validate:"required" example:"hero-uuid-002"` // 目标成员英雄ID
+	RankID       string `json:"rank_id" example:"rank-uuid-001"`                            // 职阶ID，为空表示取消职阶
+}
+
+// TeamRankResponse 团队职阶
+type TeamRankResponse struct {
+	ID          string   `json:"id" example:"rank-uuid-001"`
+	Name        string   `json:"name" example:"军需官"`
+	Level       int      `json:"level" example:"40"`
+	Permissions []string `json:"permissions"`
+	CreatedAt   string   `json:"created_at" example:"2025-01-01T12:00:00Z"`
+	UpdatedAt   string   `json:"updated_at" example:"2025-01-01T12:00:00Z"`
+}
+
+// TeamMemberPermissionsResponse 成员有效权限
+type TeamMemberPermissionsResponse struct {
+	Role        string            `json:"role" example:"member"` // leader/admin/member
+	Rank        *TeamRankResponse `json:"rank,omitempty"`        // 已分配的职阶
+	Level       int               `json:"level" example:"40"`    // 权限等级，队长为 100
+	Permissions []string          `json:"permissions"`
+}
+
+// ==================== HTTP Handlers ====================
+
+// ListRanks 查看团队职阶
+// @Summary 查看团队职阶
+// @Description 查看团队自定义职阶及其权限（任何团队成员均可查看）
+// @Tags 团队职阶
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param hero_id query string true "操作者英雄ID"
+// @Success 200 {object} response.Response{data=[]TeamRankResponse} "获取成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 404 {object} response.Response "您不是该团队成员"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/ranks [get]
+func (h *TeamRankHandler) ListRanks(c echo.Context) error {
+	teamID := c.Param("team_id")
+	heroID := c.QueryParam("hero_id")
+	if teamID == "" || heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "参数不能为空")
+	}
+
+	ranks, err := h.rankService.ListRanks(c.Request().Context(), teamID, heroID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	resp := make([]*TeamRankResponse, 0, len(ranks))
+	for _, rank := range ranks {
+		resp = append(resp, toTeamRankResponse(rank))
+	}
+	return response.EchoOK(c, h.respWriter, resp)
+}
+
+// CreateRank 创建职阶
+// @Summary 创建职阶
+// @Description 创建自定义职阶，等级需低于操作者，且只能授予操作者自身拥有的权限（需要 manage_ranks 权限）
+// @Tags 团队职阶
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param request body SaveTeamRankRequest true "职阶定义"
+// @Success 200 {object} response.Response{data=TeamRankResponse} "创建成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 403 {object} response.Response "缺少 manage_ranks 权限"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/ranks [post]
+func (h *TeamRankHandler) CreateRank(c echo.Context) error {
+	teamID := c.Param("team_id")
+	if teamID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "团队ID不能为空")
+	}
+
+	var req SaveTeamRankRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	rank, err := h.rankService.CreateRank(c.Request().Context(), &service.SaveTeamRankRequest{
+		TeamID:      teamID,
+		HeroID:      req.HeroID,
+		Name:        req.Name,
+		Level:       req.Level,
+		Permissions: req.Permissions,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, toTeamRankResponse(rank))
+}
+
+// UpdateRank 更新职阶
+// @Summary 更新职阶
+// @Description 修改职阶名称、等级与权限，持有该职阶的成员权限随之同步（需要 manage_ranks 权限）
+// @Tags 团队职阶
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param rank_id path string true "职阶ID"
+// @Param request body SaveTeamRankRequest true "职阶定义"
+// @Success 200 {object} response.Response{data=TeamRankResponse} "更新成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 403 {object} response.Response "缺少 manage_ranks 权限"
+// @Failure 404 {object} response.Response "职阶不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/ranks/{rank_id} [put]
+func (h *TeamRankHandler) UpdateRank(c echo.Context) error {
+	teamID := c.Param("team_id")
+	rankID := c.Param("rank_id")
+	if teamID == "" || rankID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "参数不能为空")
+	}
+
+	var req SaveTeamRankRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	rank, err := h.rankService.UpdateRank(c.Request().Context(), &service.SaveTeamRankRequest{
+		TeamID:      teamID,
+		HeroID:      req.HeroID,
+		RankID:      rankID,
+		Name:        req.Name,
+		Level:       req.Level,
+		Permissions: req.Permissions,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, toTeamRankResponse(rank))
+}
+
+// DeleteRank 删除职阶
+// @Summary 删除职阶
+// @Description 删除职阶，原持有成员恢复角色默认权限（需要 manage_ranks 权限）
+// @Tags 团队职阶
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param rank_id path string true "职阶ID"
+// @Param hero_id query string true "操作者英雄ID"
+// @Success 200 {object} response.Response "删除成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 403 {object} response.Response "缺少 manage_ranks 权限"
+// @Failure 404 {object} response.Response "职阶不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/ranks/{rank_id} [delete]
+func (h *TeamRankHandler) DeleteRank(c echo.Context) error {
+	teamID := c.Param("team_id")
+	rankID := c.Param("rank_id")
+	heroID := c.QueryParam("hero_id")
+	if teamID == "" || rankID == "" || heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "参数不能为空")
+	}
+
+	if err := h.rankService.DeleteRank(c.Request().Context(), teamID, heroID, rankID); err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, map[string]interface{}{
+		"message": "职阶已删除",
+	})
+}
+
+// AssignRank 分配职阶
+// @Summary 分配职阶
+// @Description 为等级低于自己的成员分配职阶，rank_id 为空时取消职阶（需要 manage_ranks 权限）
+// @Tags 团队职阶
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param request body AssignTeamRankRequest true "分配请求"
+// @Success 200 {object} response.Response "分配成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 403 {object} response.Response "缺少 manage_ranks 权限或目标等级不低于自己"
+// @Failure 404 {object} response.Response "目标成员或职阶不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/ranks/assign [post]
+func (h *TeamRankHandler) AssignRank(c echo.Context) error {
+	teamID := c.Param("team_id")
+	if teamID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "团队ID不能为空")
+	}
+
+	var req AssignTeamRankRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	if err := h.rankService.AssignRank(c.Request().Context(), &service.AssignTeamRankRequest{
+		TeamID:       teamID,
+		HeroID:       req.HeroID,
+		TargetHeroID: req.TargetHeroID,
+		RankID:       req.RankID,
+	}); err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+	return response.EchoOK(c, h.respWriter, map[string]interface{}{
+		"message": "职阶已更新",
+	})
+}
+
+// GetMyPermissions 查看自己的团队权限
+// @Summary 查看自己的团队权限
+// @Description 返回当前英雄在团队中的角色、职阶与有效权限
+// @Tags 团队职阶
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param hero_id query string true "操作者英雄ID"
+// @Success 200 {object} response.Response{data=TeamMemberPermissionsResponse} "获取成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 404 {object} response.Response "您不是该团队成员"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/permissions [get]
+func (h *TeamRankHandler) GetMyPermissions(c echo.Context) error {
+	teamID := c.Param("team_id")
+	heroID := c.QueryParam("hero_id")
+	if teamID == "" || heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "参数不能为空")
+	}
+
+	perms, err := h.rankService.GetMemberPermissions(c.Request().Context(), teamID, heroID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	resp := &TeamMemberPermissionsResponse{
+		Role:        perms.Role,
+		Level:       perms.Level,
+		Permissions: perms.Permissions,
+	}
+	if perms.Rank != nil {
+		resp.Rank = toTeamRankResponse(perms.Rank)
+	}
+	return response.EchoOK(c, h.respWriter, resp)
+}
+
+func toTeamRankResponse(rank *interfaces.TeamRank) *TeamRankResponse {
+	permissions := rank.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return &TeamRankResponse{
+		ID:          rank.ID,
+		Name:        rank.Name,
+		Level:       rank.Level,
+		Permissions: permissions,
+		CreatedAt:   rank.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   rank.UpdatedAt.Format(time.RFC3339),
+	}
+}
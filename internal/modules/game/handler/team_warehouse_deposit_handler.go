@@ -0,0 +1,329 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"tsu-self/internal/modules/game/service"
+	"tsu-self/internal/pkg/response"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// ==================== HTTP Request/Response Models ====================
+
+// DepositGoldRequest HTTP 捐献金币请求
+type DepositGoldRequest struct {
+	HeroID string `json:"hero_id" validate:"required" example:"hero-uuid-001"` // 捐献者英雄ID
+	Amount int64  `json:"amount" validate:"required,gt=0" example:"500"`       // 捐献金币数量
+}
+
+// DepositItemsRequest HTTP 捐献物品请求
+type DepositItemsRequest struct {
+	HeroID string         `json:"hero_id" validate:"required" example:"hero-uuid-001"` // 捐献者英雄ID
+	Items  map[string]int `json:"items" validate:"required"`                           // 物品ID -> 数量
+}
+
+// CreateWithdrawalRequest HTTP 取用申请请求
+type CreateWithdrawalRequest struct {
+	HeroID   string `json:"hero_id" validate:"required" example:"hero-uuid-001"` // 申请人英雄ID
+	ItemID   string `json:"item_id" validate:"required" example:"item-uuid-001"` // 申请的物品ID
+	Quantity int    `json:"quantity" validate:"required,gt=0" example:"1"`       // 申请数量
+	Note     string `json:"note" validate:"max=200" example:"打团需要药水"`            // 申请说明（可选）
+}
+
+// ReviewWithdrawalRequest HTTP 审批取用申请请求
+type ReviewWithdrawalRequest struct {
+	HeroID  string `json:"hero_id" validate:"required" example:"hero-uuid-001"` // 审批人英雄ID
+	Approve bool   `json:"approve" example:"true"`                              // true 批准，false 拒绝
+	Note    string `json:"note" validate:"max=200" example:"已发放"`               // 审批说明（可选）
+}
+
+// CancelWithdrawalRequest HTTP 撤回取用申请请求
+type CancelWithdrawalRequest struct {
+	HeroID string `json:"hero_id" validate:"required" example:"hero-uuid-001"` // 申请人英雄ID
+}
+
+// WithdrawalResponse 取用申请
+type WithdrawalResponse struct {
+	ID             string  `json:"id" example:"request-uuid-001"`
+	HeroID         string  `json:"hero_id" example:"hero-uuid-001"`
+	ItemID         string  `json:"item_id" example:"item-uuid-001"`
+	Quantity       int     `json:"quantity" example:"1"`
+	Note           *string `json:"note,omitempty"`
+	Status         string  `json:"status" example:"pending"` // pending/approved/denied/cancelled
+	ReviewerHeroID *string `json:"reviewer_hero_id,omitempty"`
+	ReviewNote     *string `json:"review_note,omitempty"`
+	CreatedAt      string  `json:"created_at" example:"2025-01-01T12:00:00Z"`
+	ReviewedAt     *string `json:"reviewed_at,omitempty"`
+}
+
+// WithdrawalsResponse 取用申请列表
+type WithdrawalsResponse struct {
+	Requests []*WithdrawalResponse `json:"requests"`
+	Total    int64                 `json:"total"`
+	Limit    int                   `json:"limit"`
+	Offset   int                   `json:"offset"`
+}
+
+// ==================== HTTP Handlers ====================
+
+// DepositGold 捐献金币
+// @Summary 捐献金币到团队仓库
+// @Description 团队成员从自己的钱包捐献金币到团队仓库，扣款与入库在同一事务内完成
+// @Tags 团队仓库
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param request body DepositGoldRequest true "捐献金币请求"
+// @Success 200 {object} response.Response "捐献成功"
+// @Failure 400 {object} response.Response "请求参数错误或金币不足"
+// @Failure 404 {object} response.Response "您不是该团队成员"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/warehouse/deposits/gold [post]
+func (h *TeamWarehouseHandler) DepositGold(c echo.Context) error {
+	teamID := c.Param("team_id")
+	if teamID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "团队ID不能为空")
+	}
+
+	var req DepositGoldRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	if err := h.warehouseService.DepositGold(c.Request().Context(), &service.DepositGoldRequest{
+		TeamID: teamID,
+		HeroID: req.HeroID,
+		Amount: req.Amount,
+	}); err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	return response.EchoOK(c, h.respWriter, map[string]interface{}{})
+}
+
+// DepositItems 捐献物品
+// @Summary 捐献背包物品到团队仓库
+// @Description 团队成员从背包捐献物品到团队仓库，受仓库种类与堆叠上限限制；已绑定、强化、镶嵌或耐久受损的物品不可捐献
+// @Tags 团队仓库
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param request body DepositItemsRequest true "捐献物品请求"
+// @Success 200 {object} response.Response "捐献成功"
+// @Failure 400 {object} response.Response "请求参数错误、仓库已满或背包物品不足"
+// @Failure 404 {object} response.Response "您不是该团队成员"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/warehouse/deposits/items [post]
+func (h *TeamWarehouseHandler) DepositItems(c echo.Context) error {
+	teamID := c.Param("team_id")
+	if teamID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "团队ID不能为空")
+	}
+
+	var req DepositItemsRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	if err := h.warehouseService.DepositItems(c.Request().Context(), &service.DepositItemsRequest{
+		TeamID: teamID,
+		HeroID: req.HeroID,
+		Items:  req.Items,
+	}); err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	return response.EchoOK(c, h.respWriter, map[string]interface{}{})
+}
+
+// CreateWithdrawal 申请取用仓库物品
+// @Summary 申请取用仓库物品
+// @Description 团队成员申请从仓库取用物品，由队长或管理员审批；每人同时最多 5 个待审批申请
+// @Tags 团队仓库
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param request body CreateWithdrawalRequest true "取用申请"
+// @Success 200 {object} response.Response{data=WithdrawalResponse} "申请成功"
+// @Failure 400 {object} response.Response "请求参数错误或库存不足"
+// @Failure 404 {object} response.Response "您不是该团队成员"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/warehouse/withdrawals [post]
+func (h *TeamWarehouseHandler) CreateWithdrawal(c echo.Context) error {
+	teamID := c.Param("team_id")
+	if teamID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "团队ID不能为空")
+	}
+
+	var req CreateWithdrawalRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	withdrawal, err := h.warehouseService.CreateWithdrawalRequest(c.Request().Context(), &service.CreateWithdrawalRequest{
+		TeamID:   teamID,
+		HeroID:   req.HeroID,
+		ItemID:   req.ItemID,
+		Quantity: req.Quantity,
+		Note:     req.Note,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	return response.EchoOK(c, h.respWriter, toWithdrawalResponse(withdrawal))
+}
+
+// ListWithdrawals 查询取用申请
+// @Summary 查询取用申请
+// @Description 队长和管理员可查看全部申请，普通成员只能查看自己的申请
+// @Tags 团队仓库
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param hero_id query string true "操作者英雄ID"
+// @Param status query string false "状态过滤：pending/approved/denied/cancelled"
+// @Param limit query int false "每页数量" default(20)
+// @Param offset query int false "偏移量" default(0)
+// @Success 200 {object} response.Response{data=WithdrawalsResponse} "获取成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 404 {object} response.Response "您不是该团队成员"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/warehouse/withdrawals [get]
+func (h *TeamWarehouseHandler) ListWithdrawals(c echo.Context) error {
+	teamID := c.Param("team_id")
+	heroID := c.QueryParam("hero_id")
+	if teamID == "" || heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "参数不能为空")
+	}
+	limit, offset := parsePagination(c, 20)
+
+	list, total, err := h.warehouseService.ListWithdrawalRequests(c.Request().Context(), teamID, heroID, c.QueryParam("status"), limit, offset)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	resp := &WithdrawalsResponse{
+		Requests: make([]*WithdrawalResponse, 0, len(list)),
+		Total:    total,
+		Limit:    limit,
+		Offset:   offset,
+	}
+	for _, withdrawal := range list {
+		resp.Requests = append(resp.Requests, toWithdrawalResponse(withdrawal))
+	}
+
+	return response.EchoOK(c, h.respWriter, resp)
+}
+
+// ReviewWithdrawal 审批取用申请
+// @Summary 审批取用申请
+// @Description 队长或管理员批准或拒绝取用申请，批准后物品直接发放到申请人背包；不能审批自己的申请
+// @Tags 团队仓库
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param request_id path string true "申请ID"
+// @Param request body ReviewWithdrawalRequest true "审批结果"
+// @Success 200 {object} response.Response{data=WithdrawalResponse} "审批成功"
+// @Failure 400 {object} response.Response "请求参数错误、申请已处理或库存/背包不足"
+// @Failure 403 {object} response.Response "需要管理员或队长权限"
+// @Failure 404 {object} response.Response "申请不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/warehouse/withdrawals/{request_id}/review [post]
+func (h *TeamWarehouseHandler) ReviewWithdrawal(c echo.Context) error {
+	teamID := c.Param("team_id")
+	requestID := c.Param("request_id")
+	if teamID == "" || requestID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "参数不能为空")
+	}
+
+	var req ReviewWithdrawalRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	withdrawal, err := h.warehouseService.ReviewWithdrawalRequest(c.Request().Context(), &service.ReviewWithdrawalRequest{
+		TeamID:     teamID,
+		RequestID:  requestID,
+		ReviewerID: req.HeroID,
+		Approve:    req.Approve,
+		Note:       req.Note,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	return response.EchoOK(c, h.respWriter, toWithdrawalResponse(withdrawal))
+}
+
+// CancelWithdrawal 撤回取用申请
+// @Summary 撤回取用申请
+// @Description 申请人撤回自己待审批的取用申请
+// @Tags 团队仓库
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param request_id path string true "申请ID"
+// @Param request body CancelWithdrawalRequest true "撤回请求"
+// @Success 200 {object} response.Response{data=WithdrawalResponse} "撤回成功"
+// @Failure 400 {object} response.Response "请求参数错误或申请已处理"
+// @Failure 403 {object} response.Response "只能撤回自己的申请"
+// @Failure 404 {object} response.Response "申请不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/warehouse/withdrawals/{request_id}/cancel [post]
+func (h *TeamWarehouseHandler) CancelWithdrawal(c echo.Context) error {
+	teamID := c.Param("team_id")
+	requestID := c.Param("request_id")
+	if teamID == "" || requestID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "参数不能为空")
+	}
+
+	var req CancelWithdrawalRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	withdrawal, err := h.warehouseService.CancelWithdrawalRequest(c.Request().Context(), teamID, requestID, req.HeroID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	return response.EchoOK(c, h.respWriter, toWithdrawalResponse(withdrawal))
+}
+
+func toWithdrawalResponse(withdrawal *interfaces.TeamWarehouseWithdrawal) *WithdrawalResponse {
+	resp := &WithdrawalResponse{
+		ID:             withdrawal.ID,
+		HeroID:         withdrawal.HeroID,
+		ItemID:         withdrawal.ItemID,
+		Quantity:       withdrawal.Quantity,
+		Note:           withdrawal.Note,
+		Status:         withdrawal.Status,
+		ReviewerHeroID: withdrawal.ReviewerHeroID,
+		ReviewNote:     withdrawal.ReviewNote,
+		CreatedAt:      withdrawal.CreatedAt.Format(time.RFC3339),
+	}
+	if withdrawal.ReviewedAt != nil {
+		reviewedAt := withdrawal.ReviewedAt.Format(time.RFC3339)
+		resp.ReviewedAt = &reviewedAt
+	}
+	return resp
+}
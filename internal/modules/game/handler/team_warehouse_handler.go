@@ -2,12 +2,14 @@ package handler
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
 	"tsu-self/internal/entity/game_runtime"
 	"tsu-self/internal/modules/game/service"
 	"tsu-self/internal/pkg/response"
+	"tsu-self/internal/repository/interfaces"
 )
 
 // TeamWarehouseHandler 团队仓库管理 Handler
@@ -65,6 +67,8 @@ type DistributionHistoryRecord struct {
 	ItemType          string  `json:"item_type" example:"item|gold"`
 	ItemID            *string `json:"item_id,omitempty" example:"item-uuid"`
 	Quantity          int64   `json:"quantity" example:"100"`
+	Policy            string  `json:"policy" example:"manual"`                       // 分配策略：manual/equal_split/round_robin/need_greed/class_need
+	DecisionNote      *string `json:"decision_note,omitempty" example:"need 87 点胜出"` // 自动分配的决策说明
 	DistributedAt     string  `json:"distributed_at" example:"2025-12-03T08:45:21Z"`
 }
 
@@ -76,6 +80,61 @@ type DistributionHistoryResponse struct {
 	Offset  int                          `json:"offset"`  // 偏移量
 }
 
+// LootPolicyResponse 仓库分配策略响应
+type LootPolicyResponse struct {
+	TeamID             string `json:"team_id" example:"team-uuid-001"`
+	GoldPolicy         string `json:"gold_policy" example:"equal_split"`  // 金币策略：manual/equal_split
+	ItemPolicy         string `json:"item_policy" example:"need_greed"`   // 物品策略：manual/round_robin/need_greed/class_need
+	RollTimeoutSeconds int    `json:"roll_timeout_seconds" example:"120"` // 投骰超时秒数
+}
+
+// UpdateLootPolicyRequest HTTP 更新分配策略请求
+type UpdateLootPolicyRequest struct {
+	HeroID             string `json:"hero_id" validate:"required" example:"hero-uuid-001"`   // 操作者英雄ID
+	GoldPolicy         string `json:"gold_policy" validate:"required" example:"equal_split"` // 金币策略
+	ItemPolicy         string `json:"item_policy" validate:"required" example:"need_greed"`  // 物品策略
+	RollTimeoutSeconds int    `json:"roll_timeout_seconds,omitempty" example:"120"`          // 投骰超时秒数（10-86400，不填保持不变）
+}
+
+// LootRollResponse 投骰响应
+type LootRollResponse struct {
+	ID            string  `json:"id" example:"roll-uuid-001"`
+	ItemID        string  `json:"item_id" example:"item-uuid"`
+	ItemType      string  `json:"item_type" example:"equipment"`
+	Quantity      int     `json:"quantity" example:"1"`
+	Policy        string  `json:"policy" example:"need_greed"`
+	Status        string  `json:"status" example:"open"` // open/resolving/awarded/passed/failed
+	ExpiresAt     string  `json:"expires_at" example:"2025-01-01T12:02:00Z"`
+	WinnerHeroID  *string `json:"winner_hero_id,omitempty" example:"hero-uuid-001"`
+	WinningChoice *string `json:"winning_choice,omitempty" example:"need"`
+	WinningRoll   *int    `json:"winning_roll,omitempty" example:"87"`
+	ResultNote    *string `json:"result_note,omitempty"`
+	CreatedAt     string  `json:"created_at" example:"2025-01-01T12:00:00Z"`
+	ResolvedAt    *string `json:"resolved_at,omitempty" example:"2025-01-01T12:01:00Z"`
+}
+
+// LootRollsResponse 投骰列表响应
+type LootRollsResponse struct {
+	Rolls  []*LootRollResponse `json:"rolls"`
+	Total  int64               `json:"total"`
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
+}
+
+// VoteLootRollRequest HTTP 投骰请求
+type VoteLootRollRequest struct {
+	HeroID string `json:"hero_id" validate:"required" example:"hero-uuid-001"` // 投骰英雄ID
+	Choice string `json:"choice" validate:"required" example:"need"`           // need/greed/pass
+}
+
+// LootRollVoteResponse 投骰结果响应
+type LootRollVoteResponse struct {
+	RollID    string `json:"roll_id" example:"roll-uuid-001"`
+	HeroID    string `json:"hero_id" example:"hero-uuid-001"`
+	Choice    string `json:"choice" example:"need"`
+	RollValue int    `json:"roll_value" example:"87"` // 放弃时为 0
+}
+
 // ==================== HTTP Handlers ====================
 
 // GetWarehouse 查看团队仓库
@@ -321,6 +380,8 @@ func (h *TeamWarehouseHandler) GetDistributionHistory(c echo.Context) error {
 			ItemType:          r.ItemType,
 			ItemID:            r.ItemID,
 			Quantity:          r.Quantity,
+			Policy:            r.Policy,
+			DecisionNote:      r.DecisionNote,
 			DistributedAt:     r.DistributedAt,
 		})
 	}
@@ -334,3 +395,198 @@ func (h *TeamWarehouseHandler) GetDistributionHistory(c echo.Context) error {
 
 	return response.EchoOK(c, h.respWriter, resp)
 }
+
+// GetLootPolicy 查看仓库分配策略
+// @Summary 查看仓库分配策略
+// @Description 查看团队仓库的自动分配策略（任何团队成员均可查看）
+// @Tags 团队仓库
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param hero_id query string true "操作者英雄ID"
+// @Success 200 {object} response.Response{data=LootPolicyResponse} "获取成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 404 {object} response.Response "您不是该团队成员"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/warehouse/loot-policy [get]
+func (h *TeamWarehouseHandler) GetLootPolicy(c echo.Context) error {
+	teamID := c.Param("team_id")
+	heroID := c.QueryParam("hero_id")
+	if teamID == "" || heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "参数不能为空")
+	}
+
+	policy, err := h.warehouseService.GetLootPolicy(c.Request().Context(), teamID, heroID)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	return response.EchoOK(c, h.respWriter, toLootPolicyResponse(policy))
+}
+
+// UpdateLootPolicy 更新仓库分配策略
+// @Summary 更新仓库分配策略
+// @Description 设置入库后的自动分配方式（队长和管理员）：金币 manual/equal_split，物品 manual/round_robin/need_greed/class_need
+// @Tags 团队仓库
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param request body UpdateLootPolicyRequest true "分配策略"
+// @Success 200 {object} response.Response{data=LootPolicyResponse} "更新成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 403 {object} response.Response "需要管理员或队长权限"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/warehouse/loot-policy [put]
+func (h *TeamWarehouseHandler) UpdateLootPolicy(c echo.Context) error {
+	teamID := c.Param("team_id")
+	if teamID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "团队ID不能为空")
+	}
+
+	var req UpdateLootPolicyRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	policy, err := h.warehouseService.UpdateLootPolicy(c.Request().Context(), &service.UpdateLootPolicyRequest{
+		TeamID:             teamID,
+		HeroID:             req.HeroID,
+		GoldPolicy:         req.GoldPolicy,
+		ItemPolicy:         req.ItemPolicy,
+		RollTimeoutSeconds: req.RollTimeoutSeconds,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	return response.EchoOK(c, h.respWriter, toLootPolicyResponse(policy))
+}
+
+// ListLootRolls 获取战利品投骰列表
+// @Summary 获取战利品投骰列表
+// @Tags 团队仓库
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param hero_id query string true "操作者英雄ID"
+// @Param status query string false "状态过滤：open/resolving/awarded/passed/failed"
+// @Param limit query int false "每页数量" default(20)
+// @Param offset query int false "偏移量" default(0)
+// @Success 200 {object} response.Response{data=LootRollsResponse} "获取成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Failure 404 {object} response.Response "您不是该团队成员"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/warehouse/rolls [get]
+func (h *TeamWarehouseHandler) ListLootRolls(c echo.Context) error {
+	teamID := c.Param("team_id")
+	heroID := c.QueryParam("hero_id")
+	if teamID == "" || heroID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "参数不能为空")
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rolls, total, err := h.warehouseService.ListLootRolls(c.Request().Context(), teamID, heroID, c.QueryParam("status"), limit, offset)
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	resp := &LootRollsResponse{
+		Rolls:  make([]*LootRollResponse, 0, len(rolls)),
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}
+	for _, roll := range rolls {
+		resp.Rolls = append(resp.Rolls, toLootRollResponse(roll))
+	}
+
+	return response.EchoOK(c, h.respWriter, resp)
+}
+
+// VoteLootRoll 战利品投骰
+// @Summary 战利品投骰
+// @Description 对进行中的投骰选择需求/贪婪/放弃，点数由服务端生成；全员投完或超时后自动结算
+// @Tags 团队仓库
+// @Accept json
+// @Produce json
+// @Param team_id path string true "团队ID"
+// @Param roll_id path string true "投骰ID"
+// @Param request body VoteLootRollRequest true "投骰选择"
+// @Success 200 {object} response.Response{data=LootRollVoteResponse} "投骰成功"
+// @Failure 400 {object} response.Response "请求参数错误或投骰已结束"
+// @Failure 404 {object} response.Response "投骰不存在"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /game/teams/{team_id}/warehouse/rolls/{roll_id} [post]
+func (h *TeamWarehouseHandler) VoteLootRoll(c echo.Context) error {
+	teamID := c.Param("team_id")
+	rollID := c.Param("roll_id")
+	if teamID == "" || rollID == "" {
+		return response.EchoBadRequest(c, h.respWriter, "参数不能为空")
+	}
+
+	var req VoteLootRollRequest
+	if err := c.Bind(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, "请求格式错误")
+	}
+	if err := c.Validate(&req); err != nil {
+		return response.EchoBadRequest(c, h.respWriter, err.Error())
+	}
+
+	vote, err := h.warehouseService.VoteLootRoll(c.Request().Context(), &service.VoteLootRollRequest{
+		TeamID: teamID,
+		RollID: rollID,
+		HeroID: req.HeroID,
+		Choice: req.Choice,
+	})
+	if err != nil {
+		return response.EchoError(c, h.respWriter, err)
+	}
+
+	return response.EchoOK(c, h.respWriter, &LootRollVoteResponse{
+		RollID:    vote.RollID,
+		HeroID:    vote.HeroID,
+		Choice:    vote.Choice,
+		RollValue: vote.RollValue,
+	})
+}
+
+func toLootPolicyResponse(policy *interfaces.TeamLootPolicy) *LootPolicyResponse {
+	return &LootPolicyResponse{
+		TeamID:             policy.TeamID,
+		GoldPolicy:         policy.GoldPolicy,
+		ItemPolicy:         policy.ItemPolicy,
+		RollTimeoutSeconds: policy.RollTimeoutSeconds,
+	}
+}
+
+func toLootRollResponse(roll *interfaces.TeamLootRoll) *LootRollResponse {
+	resp := &LootRollResponse{
+		ID:            roll.ID,
+		ItemID:        roll.ItemID,
+		ItemType:      roll.ItemType,
+		Quantity:      roll.Quantity,
+		Policy:        roll.Policy,
+		Status:        roll.Status,
+		ExpiresAt:     roll.ExpiresAt.Format(time.RFC3339),
+		WinnerHeroID:  roll.WinnerHeroID,
+		WinningChoice: roll.WinningChoice,
+		WinningRoll:   roll.WinningRoll,
+		ResultNote:    roll.ResultNote,
+		CreatedAt:     roll.CreatedAt.Format(time.RFC3339),
+	}
+	if roll.ResolvedAt != nil {
+		resolvedAt := roll.ResolvedAt.Format(time.RFC3339)
+		resp.ResolvedAt = &resolvedAt
+	}
+	return resp
+}
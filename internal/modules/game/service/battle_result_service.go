@@ -4,6 +4,9 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 
 	"tsu-self/internal/entity/game_runtime"
 	"tsu-self/internal/pkg/xerrors"
@@ -16,22 +19,48 @@ type BattleResultInput struct {
 	BattleCode   string
 	TeamID       string
 	DungeonID    string
+	ProgressID   string // 房间战斗所属的地城进度
+	RoomID       string // 房间战斗所属的房间，由 ResolveRoom 按战报结算
 	HeroID       string
 	ResultStatus string
 	Participants interface{}
 	Events       interface{}
 	RawPayload   interface{}
 	Loot         LootData
+	// WearParticipants 参与耐久磨损结算的英雄（含阵亡标记）
+	WearParticipants []BattleWearParticipant
+	// HeroIDs 参战英雄，胜利时按怪物经验分配
+	HeroIDs []string
 }
 
 type dungeonCompleter interface {
 	CompleteDungeon(ctx context.Context, req *CompleteDungeonRequest) (*game_runtime.TeamDungeonProgress, error)
 }
 
+type battleWearApplier interface {
+	ApplyBattleWear(ctx context.Context, req *ApplyBattleWearRequest) error
+}
+
+type battleExperienceAwarder interface {
+	AwardBattleExperience(ctx context.Context, battleID, battleCode string, heroIDs []string) ([]*HeroExperienceAward, error)
+}
+
+type battleLootRoller interface {
+	RollBattleLoot(ctx context.Context, req *RollBattleLootRequest) (*LootData, error)
+}
+
+// battleOutcomeStaleAfter 处理中状态超过该时长视为中断，允许重新结算
+const battleOutcomeStaleAfter = 5 * time.Minute
+
 // BattleResultService 负责记录战斗结果并驱动后续掉落逻辑。
 type BattleResultService struct {
 	battleReportRepo interfaces.BattleReportRepository
 	dungeonService   dungeonCompleter
+	lootRoller       battleLootRoller
+	trustEngineLoot  bool
+	wearApplier      battleWearApplier
+	expAwarder       battleExperienceAwarder
+	stepRepo         interfaces.BattleResultStepRepository
 }
 
 // NewBattleResultService 构造函数。
@@ -42,25 +71,118 @@ func NewBattleResultService(repo interfaces.BattleReportRepository, dungeonServi
 	}
 }
 
+// ConfigureLoot 设置服务端掉落计算；trustEngineLoot 为 true 时额外接受战斗引擎上报的战利品。
+func (s *BattleResultService) ConfigureLoot(roller battleLootRoller, trustEngineLoot bool) {
+	s.lootRoller = roller
+	s.trustEngineLoot = trustEngineLoot
+}
+
+// ConfigureDurability 设置战斗后的装备耐久磨损结算。
+func (s *BattleResultService) ConfigureDurability(applier battleWearApplier) {
+	s.wearApplier = applier
+}
+
+// ConfigureExperience 设置战斗胜利后的服务端经验发放。
+func (s *BattleResultService) ConfigureExperience(awarder battleExperienceAwarder) {
+	s.expAwarder = awarder
+}
+
+// ConfigureSteps 设置战斗结算步骤记录，中断后重新结算时跳过已生效的步骤。
+func (s *BattleResultService) ConfigureSteps(repo interfaces.BattleResultStepRepository) {
+	s.stepRepo = repo
+}
+
 // RecordAndComplete 记录战斗结果并在满足条件时完成地城。
+// 同一 battle_id 只结算一次，重复回调直接返回首次的结算结果；
+// 处理中断被重新认领时，磨损、世界掉落与地城通关按结算步骤记录跳过已生效的部分。
 func (s *BattleResultService) RecordAndComplete(ctx context.Context, input *BattleResultInput) (*game_runtime.TeamDungeonProgress, error) {
 	if input == nil || input.BattleID == "" {
 		return nil, xerrors.New(xerrors.CodeInvalidParams, "battle_id 不能为空")
 	}
+
+	claimToken, existing, err := s.battleReportRepo.ClaimOutcome(ctx, input.BattleID, battleOutcomeStaleAfter)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询战斗结算记录失败")
+	}
+	if claimToken == "" {
+		return replayedOutcome(existing)
+	}
+
+	// 处理超时被重新认领后，本次的完成/失败不会覆盖新认领者的状态
+	progress, err := s.recordAndComplete(ctx, input)
+	if err != nil {
+		if failErr := s.battleReportRepo.FailOutcome(ctx, input.BattleID, claimToken, err.Error()); failErr != nil {
+			fmt.Printf("Warning: Failed to mark battle outcome failed (battle=%s): %v\n", input.BattleID, failErr)
+		}
+		return nil, err
+	}
+
+	var outcome json.RawMessage
+	if progress != nil {
+		if outcome, err = json.Marshal(progress); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "序列化结算结果失败")
+		}
+	}
+	if err := s.battleReportRepo.CompleteOutcome(ctx, input.BattleID, claimToken, outcome); err != nil {
+		// 结算已生效，不能向引擎报错触发重试；认领被接管时由新认领者写回结果
+		if errors.Is(err, interfaces.ErrBattleOutcomeClaimLost) {
+			fmt.Printf("Warning: Battle outcome was reclaimed before saving (battle=%s)\n", input.BattleID)
+		} else {
+			fmt.Printf("Warning: Failed to save battle outcome (battle=%s): %v\n", input.BattleID, err)
+		}
+	}
+	return progress, nil
+}
+
+// replayedOutcome 返回已结算 battle_id 的首次结果
+func replayedOutcome(existing *interfaces.BattleOutcome) (*game_runtime.TeamDungeonProgress, error) {
+	if existing == nil || existing.Status != interfaces.BattleOutcomeCompleted {
+		return nil, xerrors.New(xerrors.CodeResourceLocked, "战斗结果正在处理中")
+	}
+	if len(existing.Outcome) == 0 || string(existing.Outcome) == "null" {
+		return nil, nil
+	}
+	var progress game_runtime.TeamDungeonProgress
+	if err := json.Unmarshal(existing.Outcome, &progress); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "解析战斗结算结果失败")
+	}
+	return &progress, nil
+}
+
+func (s *BattleResultService) recordAndComplete(ctx context.Context, input *BattleResultInput) (*game_runtime.TeamDungeonProgress, error) {
 	if err := s.persistBattleReport(ctx, input); err != nil {
 		return nil, err
 	}
+	s.applyWear(ctx, input)
 
-	// 非地城胜利场景仅记录战报。
-	if input.ResultStatus != "victory" || input.TeamID == "" || input.DungeonID == "" {
+	// 胜利后发放战斗经验（非地城战斗同样发放）
+	if input.ResultStatus == "victory" && s.expAwarder != nil && input.BattleCode != "" && len(input.HeroIDs) > 0 {
+		if _, err := s.expAwarder.AwardBattleExperience(ctx, input.BattleID, input.BattleCode, input.HeroIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	// 非地城胜利场景仅记录战报；房间战斗同样只记录，由 ResolveRoom 按战报结算房间。
+	if input.ResultStatus != "victory" || input.TeamID == "" || input.DungeonID == "" || input.RoomID != "" {
 		return nil, nil
 	}
 
+	// 上次结算已完成地城（仅结果未落库）时直接复用，避免重复发放战利品与通关奖励
+	if completed, err := s.completedDungeonStep(ctx, input.BattleID); err != nil || completed != nil {
+		return completed, err
+	}
+
+	loot, err := s.resolveLoot(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
 	progress, err := s.dungeonService.CompleteDungeon(ctx, &CompleteDungeonRequest{
 		TeamID:    input.TeamID,
 		HeroID:    input.HeroID,
 		DungeonID: input.DungeonID,
-		Loot:      input.Loot,
+		BattleID:  input.BattleID,
+		Loot:      loot,
 	})
 	if err != nil {
 		return nil, err
@@ -68,6 +190,68 @@ func (s *BattleResultService) RecordAndComplete(ctx context.Context, input *Batt
 	return progress, nil
 }
 
+// applyWear 按战斗结果扣减参战英雄装备耐久；磨损失败不影响结算。
+func (s *BattleResultService) applyWear(ctx context.Context, input *BattleResultInput) {
+	if s.wearApplier == nil || len(input.WearParticipants) == 0 {
+		return
+	}
+	err := s.wearApplier.ApplyBattleWear(ctx, &ApplyBattleWearRequest{
+		BattleID:     input.BattleID,
+		DungeonID:    input.DungeonID,
+		ResultStatus: input.ResultStatus,
+		Participants: input.WearParticipants,
+	})
+	if err != nil {
+		fmt.Printf("Warning: Failed to apply durability wear (battle=%s): %v\n", input.BattleID, err)
+	}
+}
+
+// completedDungeonStep 返回该战斗已提交的地城通关结果，未通关时返回 nil
+func (s *BattleResultService) completedDungeonStep(ctx context.Context, battleID string) (*game_runtime.TeamDungeonProgress, error) {
+	if s.stepRepo == nil {
+		return nil, nil
+	}
+	raw, found, err := s.stepRepo.GetStep(ctx, battleID, interfaces.BattleStepDungeonCompleted)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询战斗结算步骤失败")
+	}
+	if !found {
+		return nil, nil
+	}
+	var progress game_runtime.TeamDungeonProgress
+	if err := json.Unmarshal(raw, &progress); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "解析地城通关结果失败")
+	}
+	return &progress, nil
+}
+
+// resolveLoot 以服务端掉落为准；仅在可信模式下合并引擎上报的战利品。
+func (s *BattleResultService) resolveLoot(ctx context.Context, input *BattleResultInput) (LootData, error) {
+	loot := LootData{}
+	if s.trustEngineLoot {
+		loot.Gold = input.Loot.Gold
+		loot.Items = mergeLootItems(nil, input.Loot.Items...)
+	}
+	if s.lootRoller == nil || input.BattleCode == "" {
+		return loot, nil
+	}
+
+	rolled, err := s.lootRoller.RollBattleLoot(ctx, &RollBattleLootRequest{
+		BattleID:   input.BattleID,
+		BattleCode: input.BattleCode,
+		TeamID:     input.TeamID,
+		DungeonID:  input.DungeonID,
+	})
+	if err != nil {
+		return loot, err
+	}
+	if rolled != nil {
+		loot.Gold += rolled.Gold
+		loot.Items = mergeLootItems(loot.Items, rolled.Items...)
+	}
+	return loot, nil
+}
+
 func (s *BattleResultService) persistBattleReport(ctx context.Context, input *BattleResultInput) error {
 	participantsJSON, err := json.Marshal(input.Participants)
 	if err != nil {
@@ -91,6 +275,8 @@ func (s *BattleResultService) persistBattleReport(ctx context.Context, input *Ba
 		BattleCode:   input.BattleCode,
 		TeamID:       input.TeamID,
 		DungeonID:    input.DungeonID,
+		ProgressID:   input.ProgressID,
+		RoomID:       input.RoomID,
 		ResultStatus: input.ResultStatus,
 		LootGold:     input.Loot.Gold,
 		LootItems:    lootJSON,
@@ -2,8 +2,14 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/stretchr/testify/require"
 
 	"tsu-self/internal/entity/game_runtime"
@@ -11,8 +17,11 @@ import (
 )
 
 type fakeBattleReportRepo struct {
-	reports []*interfaces.BattleReport
-	err     error
+	reports  []*interfaces.BattleReport
+	outcomes map[string]*interfaces.BattleOutcome
+	tokens   map[string]string
+	claims   int
+	err      error
 }
 
 func (f *fakeBattleReportRepo) Create(ctx context.Context, report *interfaces.BattleReport) error {
@@ -23,16 +32,70 @@ func (f *fakeBattleReportRepo) Create(ctx context.Context, report *interfaces.Ba
 	return nil
 }
 
+func (f *fakeBattleReportRepo) GetSettledReport(ctx context.Context, battleID string) (*interfaces.BattleReport, error) {
+	if outcome, ok := f.outcomes[battleID]; !ok || outcome.Status != interfaces.BattleOutcomeCompleted {
+		return nil, nil
+	}
+	for _, report := range f.reports {
+		if report.BattleID == battleID {
+			return report, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeBattleReportRepo) ClaimOutcome(ctx context.Context, battleID string, staleAfter time.Duration) (string, *interfaces.BattleOutcome, error) {
+	if f.outcomes == nil {
+		f.outcomes = make(map[string]*interfaces.BattleOutcome)
+		f.tokens = make(map[string]string)
+	}
+	existing, ok := f.outcomes[battleID]
+	if ok && existing.Status != interfaces.BattleOutcomeFailed {
+		return "", existing, nil
+	}
+	f.claims++
+	f.outcomes[battleID] = &interfaces.BattleOutcome{BattleID: battleID, Status: interfaces.BattleOutcomeProcessing}
+	f.tokens[battleID] = fmt.Sprintf("claim-%d", f.claims)
+	return f.tokens[battleID], nil, nil
+}
+
+// reclaim 模拟处理超时后被其他请求重新认领
+func (f *fakeBattleReportRepo) reclaim(battleID string) {
+	f.claims++
+	f.tokens[battleID] = fmt.Sprintf("claim-%d", f.claims)
+}
+
+func (f *fakeBattleReportRepo) CompleteOutcome(ctx context.Context, battleID, claimToken string, outcome json.RawMessage) error {
+	if f.tokens[battleID] != claimToken {
+		return interfaces.ErrBattleOutcomeClaimLost
+	}
+	f.outcomes[battleID].Status = interfaces.BattleOutcomeCompleted
+	f.outcomes[battleID].Outcome = outcome
+	return nil
+}
+
+func (f *fakeBattleReportRepo) FailOutcome(ctx context.Context, battleID, claimToken, reason string) error {
+	if f.tokens[battleID] != claimToken {
+		return interfaces.ErrBattleOutcomeClaimLost
+	}
+	f.outcomes[battleID].Status = interfaces.BattleOutcomeFailed
+	return nil
+}
+
 type fakeDungeonCompleter struct {
 	called  bool
 	lastReq *CompleteDungeonRequest
 	result  *game_runtime.TeamDungeonProgress
 	err     error
+	during  func() // 结算过程中执行，用于模拟并发
 }
 
 func (f *fakeDungeonCompleter) CompleteDungeon(ctx context.Context, req *CompleteDungeonRequest) (*game_runtime.TeamDungeonProgress, error) {
 	f.called = true
 	f.lastReq = req
+	if f.during != nil {
+		f.during()
+	}
 	if f.err != nil {
 		return nil, f.err
 	}
@@ -98,6 +161,27 @@ func TestBattleResultServiceCompletesDungeonOnVictory(t *testing.T) {
 	require.Contains(t, string(repo.reports[0].LootItems), "item-1")
 }
 
+func TestBattleResultServiceLeavesRoomBattlesToRoomResolution(t *testing.T) {
+	repo := &fakeBattleReportRepo{}
+	dungeon := &fakeDungeonCompleter{}
+	svc := NewBattleResultService(repo, dungeon)
+
+	progress, err := svc.RecordAndComplete(context.Background(), &BattleResultInput{
+		BattleID:     "battle-room",
+		ResultStatus: "victory",
+		TeamID:       "team-1",
+		DungeonID:    "dungeon-1",
+		ProgressID:   "progress-1",
+		RoomID:       "room-1",
+	})
+	require.NoError(t, err)
+	require.Nil(t, progress)
+	require.False(t, dungeon.called, "房间战斗不直接完成地城")
+	require.Len(t, repo.reports, 1)
+	require.Equal(t, "progress-1", repo.reports[0].ProgressID)
+	require.Equal(t, "room-1", repo.reports[0].RoomID)
+}
+
 func TestBattleResultServiceRequiresBattleID(t *testing.T) {
 	repo := &fakeBattleReportRepo{}
 	svc := NewBattleResultService(repo, &fakeDungeonCompleter{})
@@ -110,6 +194,138 @@ func TestBattleResultServiceRequiresBattleID(t *testing.T) {
 	require.Empty(t, repo.reports)
 }
 
+func TestBattleResultServiceReplayReturnsOriginalOutcome(t *testing.T) {
+	repo := &fakeBattleReportRepo{}
+	dungeon := &fakeDungeonCompleter{}
+	svc := NewBattleResultService(repo, dungeon)
+
+	input := &BattleResultInput{BattleID: "battle-replay", ResultStatus: "victory", TeamID: "team-1", DungeonID: "dungeon-1"}
+	first, err := svc.RecordAndComplete(context.Background(), input)
+	require.NoError(t, err)
+	require.NotNil(t, first)
+
+	dungeon.called = false
+	again, err := svc.RecordAndComplete(context.Background(), input)
+	require.NoError(t, err)
+	require.False(t, dungeon.called, "重复 battle_id 不应再次完成地城")
+	require.Len(t, repo.reports, 1)
+	require.Equal(t, first.ID, again.ID)
+	require.Equal(t, first.DungeonID, again.DungeonID)
+
+	// 非地城胜利的重放同样不重复落库
+	defeat := &BattleResultInput{BattleID: "battle-replay-defeat", ResultStatus: "defeat"}
+	_, err = svc.RecordAndComplete(context.Background(), defeat)
+	require.NoError(t, err)
+	progress, err := svc.RecordAndComplete(context.Background(), defeat)
+	require.NoError(t, err)
+	require.Nil(t, progress)
+	require.Len(t, repo.reports, 2)
+}
+
+func TestBattleResultServiceReplayWhileProcessing(t *testing.T) {
+	repo := &fakeBattleReportRepo{outcomes: map[string]*interfaces.BattleOutcome{
+		"battle-busy": {BattleID: "battle-busy", Status: interfaces.BattleOutcomeProcessing},
+	}}
+	dungeon := &fakeDungeonCompleter{}
+	svc := NewBattleResultService(repo, dungeon)
+
+	_, err := svc.RecordAndComplete(context.Background(), &BattleResultInput{BattleID: "battle-busy", ResultStatus: "victory", TeamID: "t", DungeonID: "d"})
+	require.Error(t, err)
+	require.False(t, dungeon.called)
+	require.Empty(t, repo.reports)
+}
+
+func TestBattleResultServiceRetriesFailedOutcome(t *testing.T) {
+	repo := &fakeBattleReportRepo{}
+	dungeon := &fakeDungeonCompleter{err: errors.New("boom")}
+	svc := NewBattleResultService(repo, dungeon)
+
+	input := &BattleResultInput{BattleID: "battle-retry", ResultStatus: "victory", TeamID: "team-1", DungeonID: "dungeon-1"}
+	_, err := svc.RecordAndComplete(context.Background(), input)
+	require.Error(t, err)
+	require.Equal(t, interfaces.BattleOutcomeFailed, repo.outcomes["battle-retry"].Status)
+
+	dungeon.err = nil
+	progress, err := svc.RecordAndComplete(context.Background(), input)
+	require.NoError(t, err)
+	require.NotNil(t, progress)
+	require.Equal(t, interfaces.BattleOutcomeCompleted, repo.outcomes["battle-retry"].Status)
+}
+
+func TestBattleResultServiceDoesNotOverwriteReclaimedOutcome(t *testing.T) {
+	repo := &fakeBattleReportRepo{}
+	dungeon := &fakeDungeonCompleter{err: errors.New("boom")}
+	dungeon.during = func() { repo.reclaim("battle-slow") }
+	svc := NewBattleResultService(repo, dungeon)
+
+	// 处理超时后被其他请求重新认领，原处理者的失败不能覆盖新认领者的处理中状态
+	input := &BattleResultInput{BattleID: "battle-slow", ResultStatus: "victory", TeamID: "team-1", DungeonID: "dungeon-1"}
+	_, err := svc.RecordAndComplete(context.Background(), input)
+	require.Error(t, err)
+	require.Equal(t, interfaces.BattleOutcomeProcessing, repo.outcomes["battle-slow"].Status)
+
+	// 完成同理
+	dungeon.err = nil
+	repo.outcomes["battle-slow"].Status = interfaces.BattleOutcomeFailed
+	progress, err := svc.RecordAndComplete(context.Background(), input)
+	require.NoError(t, err)
+	require.NotNil(t, progress)
+	require.Equal(t, interfaces.BattleOutcomeProcessing, repo.outcomes["battle-slow"].Status)
+}
+
+type fakeBattleStepRepo struct {
+	steps map[string]json.RawMessage
+}
+
+func (f *fakeBattleStepRepo) MarkStep(ctx context.Context, execer boil.ContextExecutor, battleID, step string, result json.RawMessage) (bool, error) {
+	if f.steps == nil {
+		f.steps = make(map[string]json.RawMessage)
+	}
+	key := battleID + ":" + step
+	if _, ok := f.steps[key]; ok {
+		return false, nil
+	}
+	f.steps[key] = result
+	return true, nil
+}
+
+func (f *fakeBattleStepRepo) GetStep(ctx context.Context, battleID, step string) (json.RawMessage, bool, error) {
+	result, ok := f.steps[battleID+":"+step]
+	return result, ok, nil
+}
+
+func TestBattleResultServiceSkipsSettledDungeonOnReclaim(t *testing.T) {
+	repo := &fakeBattleReportRepo{}
+	dungeon := &fakeDungeonCompleter{}
+	roller := &fakeLootRoller{loot: &LootData{Gold: 30}}
+	steps := &fakeBattleStepRepo{}
+	svc := NewBattleResultService(repo, dungeon)
+	svc.ConfigureLoot(roller, false)
+	svc.ConfigureSteps(steps)
+
+	// 上次结算已提交地城通关，但结果未能写回（处理中超时后被重新认领）
+	settled, err := json.Marshal(&game_runtime.TeamDungeonProgress{ID: "progress-1", TeamID: "team-1", DungeonID: "dungeon-1", Status: "completed"})
+	require.NoError(t, err)
+	_, err = steps.MarkStep(context.Background(), nil, "battle-reclaim", interfaces.BattleStepDungeonCompleted, settled)
+	require.NoError(t, err)
+
+	input := &BattleResultInput{BattleID: "battle-reclaim", BattleCode: "battle_forest_boss", ResultStatus: "victory", TeamID: "team-1", DungeonID: "dungeon-1"}
+	progress, err := svc.RecordAndComplete(context.Background(), input)
+	require.NoError(t, err)
+	require.Equal(t, "progress-1", progress.ID)
+	require.False(t, dungeon.called, "已通关的战斗不再重复发放通关奖励")
+	require.Nil(t, roller.lastReq, "已通关的战斗不再重复掉落")
+	require.Equal(t, interfaces.BattleOutcomeCompleted, repo.outcomes["battle-reclaim"].Status)
+
+	// 未结算的战斗照常通关，并把 battle_id 传给各结算步骤
+	input.BattleID = "battle-fresh"
+	_, err = svc.RecordAndComplete(context.Background(), input)
+	require.NoError(t, err)
+	require.True(t, dungeon.called)
+	require.Equal(t, "battle-fresh", dungeon.lastReq.BattleID)
+	require.Equal(t, "battle-fresh", roller.lastReq.BattleID)
+}
+
 func TestBattleResultServiceHandlesMarshalError(t *testing.T) {
 	repo := &fakeBattleReportRepo{}
 	svc := NewBattleResultService(repo, &fakeDungeonCompleter{})
@@ -160,3 +376,133 @@ func TestBattleResultServiceHandlesRawPayloadMarshalError(t *testing.T) {
 	require.Error(t, err)
 	require.Empty(t, repo.reports)
 }
+
+type fakeLootRoller struct {
+	lastReq *RollBattleLootRequest
+	loot    *LootData
+}
+
+func (f *fakeLootRoller) RollBattleLoot(ctx context.Context, req *RollBattleLootRequest) (*LootData, error) {
+	f.lastReq = req
+	return f.loot, nil
+}
+
+func TestBattleResultServiceUsesServerLoot(t *testing.T) {
+	repo := &fakeBattleReportRepo{}
+	dungeon := &fakeDungeonCompleter{}
+	roller := &fakeLootRoller{loot: &LootData{Gold: 30, Items: []LootItem{{ItemID: "server-item", Quantity: 2}}}}
+	svc := NewBattleResultService(repo, dungeon)
+	svc.ConfigureLoot(roller, false)
+
+	input := &BattleResultInput{
+		BattleID:     "battle-6",
+		BattleCode:   "battle_forest_boss",
+		ResultStatus: "victory",
+		TeamID:       "team-1",
+		DungeonID:    "dungeon-1",
+		Participants: []map[string]string{{"hero_id": "hero-1"}},
+		Loot:         LootData{Gold: 9999, Items: []LootItem{{ItemID: "engine-item", Quantity: 1}}},
+	}
+	_, err := svc.RecordAndComplete(context.Background(), input)
+	require.NoError(t, err)
+	require.Equal(t, "battle_forest_boss", roller.lastReq.BattleCode)
+	require.EqualValues(t, 30, dungeon.lastReq.Loot.Gold)
+	require.Equal(t, []LootItem{{ItemID: "server-item", Quantity: 2}}, dungeon.lastReq.Loot.Items)
+}
+
+func TestBattleResultServiceMergesTrustedEngineLoot(t *testing.T) {
+	dungeon := &fakeDungeonCompleter{}
+	roller := &fakeLootRoller{loot: &LootData{Gold: 30, Items: []LootItem{{ItemID: "shared", Quantity: 2}}}}
+	svc := NewBattleResultService(&fakeBattleReportRepo{}, dungeon)
+	svc.ConfigureLoot(roller, true)
+
+	input := &BattleResultInput{
+		BattleID:     "battle-7",
+		BattleCode:   "battle_forest_boss",
+		ResultStatus: "victory",
+		TeamID:       "team-1",
+		DungeonID:    "dungeon-1",
+		Loot:         LootData{Gold: 10, Items: []LootItem{{ItemID: "shared", Quantity: 1}, {ItemID: "engine-item", Quantity: 1}}},
+	}
+	_, err := svc.RecordAndComplete(context.Background(), input)
+	require.NoError(t, err)
+	require.EqualValues(t, 40, dungeon.lastReq.Loot.Gold)
+	require.Equal(t, []LootItem{{ItemID: "shared", Quantity: 3}, {ItemID: "engine-item", Quantity: 1}}, dungeon.lastReq.Loot.Items)
+}
+
+type fakeWearApplier struct {
+	lastReq *ApplyBattleWearRequest
+	err     error
+}
+
+func (f *fakeWearApplier) ApplyBattleWear(ctx context.Context, req *ApplyBattleWearRequest) error {
+	f.lastReq = req
+	return f.err
+}
+
+func TestBattleResultServiceAppliesWear(t *testing.T) {
+	repo := &fakeBattleReportRepo{}
+	dungeon := &fakeDungeonCompleter{}
+	wear := &fakeWearApplier{err: errors.New("db down")}
+	svc := NewBattleResultService(repo, dungeon)
+	svc.ConfigureDurability(wear)
+
+	input := &BattleResultInput{
+		BattleID:         "battle-8",
+		ResultStatus:     "defeat",
+		TeamID:           "team-1",
+		DungeonID:        "dungeon-1",
+		WearParticipants: []BattleWearParticipant{{HeroID: "hero-1"}, {HeroID: "hero-2", Died: true}},
+	}
+	// 磨损失败不影响战报记录
+	_, err := svc.RecordAndComplete(context.Background(), input)
+	require.NoError(t, err)
+	require.Len(t, repo.reports, 1)
+	require.NotNil(t, wear.lastReq)
+	require.Equal(t, "battle-8", wear.lastReq.BattleID)
+	require.Equal(t, "dungeon-1", wear.lastReq.DungeonID)
+	require.Equal(t, "defeat", wear.lastReq.ResultStatus)
+	require.Equal(t, input.WearParticipants, wear.lastReq.Participants)
+}
+
+func TestRollGold(t *testing.T) {
+	require.EqualValues(t, 0, rollGold(null.Int{}, null.Int{}))
+	require.EqualValues(t, 5, rollGold(null.IntFrom(5), null.Int{}))
+	for i := 0; i < 20; i++ {
+		gold := rollGold(null.IntFrom(3), null.IntFrom(6))
+		require.GreaterOrEqual(t, gold, int64(3))
+		require.LessOrEqual(t, gold, int64(6))
+	}
+}
+
+type fakeExperienceAwarder struct {
+	battleIDs []string
+	heroIDs   []string
+}
+
+func (f *fakeExperienceAwarder) AwardBattleExperience(ctx context.Context, battleID, battleCode string, heroIDs []string) ([]*HeroExperienceAward, error) {
+	f.battleIDs = append(f.battleIDs, battleID)
+	f.heroIDs = heroIDs
+	return nil, nil
+}
+
+func TestBattleResultServiceAwardsExperienceOnVictory(t *testing.T) {
+	repo := &fakeBattleReportRepo{}
+	awarder := &fakeExperienceAwarder{}
+	svc := NewBattleResultService(repo, &fakeDungeonCompleter{})
+	svc.ConfigureExperience(awarder)
+
+	// 野外战斗（无地城）同样发放经验
+	_, err := svc.RecordAndComplete(context.Background(), &BattleResultInput{
+		BattleID: "battle-xp", BattleCode: "field-1", ResultStatus: "victory", HeroIDs: []string{"h1", "h2"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"battle-xp"}, awarder.battleIDs)
+	require.Equal(t, []string{"h1", "h2"}, awarder.heroIDs)
+
+	_, err = svc.RecordAndComplete(context.Background(), &BattleResultInput{
+		BattleID: "battle-lost", BattleCode: "field-1", ResultStatus: "defeat", HeroIDs: []string{"h1"},
+	})
+	require.NoError(t, err)
+	require.Len(t, awarder.battleIDs, 1, "失败不发放经验")
+}
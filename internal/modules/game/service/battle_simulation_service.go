@@ -0,0 +1,594 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aarondl/null/v8"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/modules/game/battle"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/impl"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// 英雄未配置 MAX_HP / MAX_MP 属性时的兜底公式
+const (
+	heroBaseHP               = 20
+	heroHPPerVIT             = 10
+	heroHPPerLevel           = 5
+	heroBaseMP               = 10
+	heroMPPerINT             = 5
+	startingAttackActionFlag = "STARTING_ATTACK_ACTION"
+)
+
+// BattleSimulationService 从配置表装配战斗并调用 battle 包进行确定性模拟
+type BattleSimulationService struct {
+	heroRepo              interfaces.HeroRepository
+	heroSkillRepo         interfaces.HeroSkillRepository
+	teamMemberRepo        interfaces.TeamMemberRepository
+	attributeTypeRepo     interfaces.HeroAttributeTypeRepository
+	skillUnlockActionRepo interfaces.SkillUnlockActionRepository
+	actionRepo            interfaces.ActionRepository
+	actionEffectRepo      interfaces.ActionEffectRepository
+	effectRepo            interfaces.EffectRepository
+	buffRepo              interfaces.BuffRepository
+	buffEffectRepo        interfaces.BuffEffectRepository
+	monsterRepo           interfaces.MonsterRepository
+	monsterSkillRepo      interfaces.MonsterSkillRepository
+	battleRepo            interfaces.DungeonBattleRepository
+	attributeService      *HeroAttributeService
+}
+
+// NewBattleSimulationService 创建战斗模拟服务
+func NewBattleSimulationService(db *sql.DB) *BattleSimulationService {
+	return &BattleSimulationService{
+		heroRepo:              impl.NewHeroRepository(db),
+		heroSkillRepo:         impl.NewHeroSkillRepository(db),
+		teamMemberRepo:        impl.NewTeamMemberRepository(db),
+		attributeTypeRepo:     impl.NewHeroAttributeTypeRepository(db),
+		skillUnlockActionRepo: impl.NewSkillUnlockActionRepository(db),
+		actionRepo:            impl.NewActionRepository(db),
+		actionEffectRepo:      impl.NewActionEffectRepository(db),
+		effectRepo:            impl.NewEffectRepository(db),
+		buffRepo:              impl.NewBuffRepository(db),
+		buffEffectRepo:        impl.NewBuffEffectRepository(db),
+		monsterRepo:           impl.NewMonsterRepository(db),
+		monsterSkillRepo:      impl.NewMonsterSkillRepository(db),
+		battleRepo:            impl.NewDungeonBattleRepository(db),
+		attributeService:      NewHeroAttributeService(db),
+	}
+}
+
+// SimulateBattleRequest 模拟请求
+type SimulateBattleRequest struct {
+	BattleCode string   // dungeon_battles.battle_code
+	BattleID   string   // 可选，写入结果的 battle_id
+	TeamID     string   // 指定时英雄角色取队伍成员角色；HeroIDs 为空时使用全部成员
+	HeroIDs    []string // 参战英雄
+	Seed       int64
+	MaxRounds  int
+}
+
+// monsterSetupEntry dungeon_battles.monster_setup 条目
+type monsterSetupEntry struct {
+	MonsterCode   string `json:"monster_code"`
+	Position      int    `json:"position"`
+	LevelOverride *int16 `json:"level_override"`
+}
+
+// Simulate 模拟一场战斗
+func (s *BattleSimulationService) Simulate(ctx context.Context, req *SimulateBattleRequest) (*battle.Result, error) {
+	setup, err := s.BuildSetup(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	result, err := battle.Simulate(setup)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInvalidParams, "战斗配置无效")
+	}
+	return result, nil
+}
+
+// Balance 以连续种子多次模拟，输出胜率等统计
+func (s *BattleSimulationService) Balance(ctx context.Context, req *SimulateBattleRequest, runs int) (*battle.BalanceReport, error) {
+	setup, err := s.BuildSetup(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	report, err := battle.RunBalance(setup, runs)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInvalidParams, "战斗配置无效")
+	}
+	return report, nil
+}
+
+// BuildSetup 读取战斗、怪物、英雄及动作/效果/Buff 配置，装配为模拟输入
+func (s *BattleSimulationService) BuildSetup(ctx context.Context, req *SimulateBattleRequest) (*battle.Setup, error) {
+	if req.BattleCode == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "战斗代码不能为空")
+	}
+
+	// 1. 战斗配置
+	battleConfig, err := s.battleRepo.GetByCode(ctx, req.BattleCode)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "战斗配置不存在")
+	}
+
+	loader := &battleConfigLoader{svc: s, actions: make(map[string]*battle.Action), buffs: make(map[string]*battle.Buff)}
+	setup := &battle.Setup{
+		BattleID:   req.BattleID,
+		BattleCode: battleConfig.BattleCode,
+		Seed:       req.Seed,
+		MaxRounds:  req.MaxRounds,
+		Buffs:      loader.buffs,
+	}
+
+	// 2. 派生属性公式
+	setup.DerivedAttributes, err = s.derivedAttributeFormulas(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 怪物阵容
+	setup.Monsters, err = loader.loadMonsters(ctx, battleConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. 英雄
+	setup.Heroes, err = loader.loadHeroes(ctx, req, setup.DerivedAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. 全程 Buff
+	if len(battleConfig.GlobalBuffs) > 0 {
+		if err := json.Unmarshal(battleConfig.GlobalBuffs, &setup.GlobalBuffs); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInvalidParams, "全程Buff配置格式错误")
+		}
+		for _, gb := range setup.GlobalBuffs {
+			if err := loader.loadBuff(ctx, gb.BuffCode); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return setup, nil
+}
+
+func (s *BattleSimulationService) derivedAttributeFormulas(ctx context.Context) (map[string]string, error) {
+	active := true
+	attrTypes, _, err := s.attributeTypeRepo.List(ctx, interfaces.HeroAttributeTypeQueryParams{IsActive: &active})
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询属性类型失败")
+	}
+	formulas := make(map[string]string)
+	for _, t := range attrTypes {
+		if t.CalculationFormula.Valid && strings.TrimSpace(t.CalculationFormula.String) != "" {
+			formulas[t.AttributeCode] = t.CalculationFormula.String
+		}
+	}
+	return formulas, nil
+}
+
+// battleConfigLoader 单次装配内缓存动作与 Buff，避免重复查询
+type battleConfigLoader struct {
+	svc     *BattleSimulationService
+	actions map[string]*battle.Action // action_id -> 动作
+	buffs   map[string]*battle.Buff   // buff_code -> Buff
+}
+
+func (l *battleConfigLoader) loadMonsters(ctx context.Context, battleConfig *game_config.DungeonBattle) ([]*battle.Combatant, error) {
+	var entries []monsterSetupEntry
+	if err := json.Unmarshal(battleConfig.MonsterSetup, &entries); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInvalidParams, "怪物配置格式错误")
+	}
+	if len(entries) == 0 {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "战斗未配置怪物")
+	}
+
+	monsters := make([]*battle.Combatant, 0, len(entries))
+	for _, entry := range entries {
+		monster, err := l.svc.monsterRepo.GetByCode(ctx, entry.MonsterCode)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, fmt.Sprintf("怪物不存在: %s", entry.MonsterCode))
+		}
+
+		level := int(monster.MonsterLevel)
+		if entry.LevelOverride != nil {
+			level = int(*entry.LevelOverride)
+		}
+		c := &battle.Combatant{
+			ID:                  fmt.Sprintf("%s#%d", monster.MonsterCode, entry.Position),
+			Name:                monster.MonsterName,
+			Side:                battle.SideMonster,
+			Position:            entry.Position,
+			Level:               level,
+			MaxHP:               monster.MaxHP,
+			MaxMP:               monster.MaxMP.Int,
+			HPRecovery:          monster.HPRecovery.Int,
+			MPRecovery:          monster.MPRecovery.Int,
+			InitiativeAttribute: monster.InitiativeAttributeCode.String,
+			Attributes:          monsterAttributes(monster),
+		}
+		if monster.DamageResistances.Valid {
+			if err := json.Unmarshal(monster.DamageResistances.JSON, &c.DamageResistances); err != nil {
+				return nil, xerrors.Wrap(err, xerrors.CodeInvalidParams, fmt.Sprintf("怪物 %s 抗性配置格式错误", monster.MonsterCode))
+			}
+		}
+		if c.PassiveBuffs, err = parsePassiveBuffCodes(monster.PassiveBuffs); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInvalidParams, fmt.Sprintf("怪物 %s 被动配置格式错误", monster.MonsterCode))
+		}
+		for _, code := range c.PassiveBuffs {
+			if err := l.loadBuff(ctx, code); err != nil {
+				return nil, err
+			}
+		}
+
+		skills, err := l.svc.monsterSkillRepo.GetByMonsterID(ctx, monster.ID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询怪物技能失败")
+		}
+		for _, skill := range skills {
+			if skill.IsActive.Valid && !skill.IsActive.Bool {
+				continue
+			}
+			for _, code := range skill.GainActions {
+				action, err := l.svc.actionRepo.GetByCode(ctx, code)
+				if err != nil {
+					return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, fmt.Sprintf("动作不存在: %s", code))
+				}
+				converted, err := l.loadAction(ctx, action, int(skill.SkillLevel))
+				if err != nil {
+					return nil, err
+				}
+				c.Actions = append(c.Actions, converted)
+			}
+		}
+		monsters = append(monsters, c)
+	}
+	return monsters, nil
+}
+
+func (l *battleConfigLoader) loadHeroes(ctx context.Context, req *SimulateBattleRequest, derived map[string]string) ([]*battle.Combatant, error) {
+	heroIDs := req.HeroIDs
+	roles := make(map[string]string)
+	if req.TeamID != "" {
+		members, err := l.svc.teamMemberRepo.ListByTeam(ctx, req.TeamID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询队伍成员失败")
+		}
+		for _, m := range members {
+			roles[m.HeroID] = m.Role
+			if len(req.HeroIDs) == 0 {
+				heroIDs = append(heroIDs, m.HeroID)
+			}
+		}
+	}
+	if len(heroIDs) == 0 {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "参战英雄不能为空")
+	}
+
+	startingAttacks, err := l.startingAttackActions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	heroes := make([]*battle.Combatant, 0, len(heroIDs))
+	for i, heroID := range heroIDs {
+		hero, err := l.svc.heroRepo.GetByID(ctx, heroID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+		}
+		computed, err := l.svc.attributeService.GetComputedAttributes(ctx, heroID)
+		if err != nil {
+			return nil, err
+		}
+
+		// 有计算公式的派生属性交给模拟器按公式计算
+		attrs := make(map[string]float64, len(computed))
+		for _, attr := range computed {
+			if _, ok := derived[attr.AttributeCode]; ok {
+				continue
+			}
+			attrs[attr.AttributeCode] = float64(attr.FinalValue)
+		}
+
+		role := roles[heroID]
+		if role == "" {
+			role = "member"
+		}
+		c := &battle.Combatant{
+			ID:         heroID,
+			Name:       hero.HeroName,
+			Side:       battle.SideHero,
+			TeamID:     req.TeamID,
+			Role:       role,
+			Position:   i + 1,
+			Level:      int(hero.CurrentLevel),
+			Attributes: attrs,
+		}
+		c.MaxHP, c.MaxMP = heroResourcePools(attrs, c.Level)
+
+		skillActions, err := l.heroSkillActions(ctx, heroID)
+		if err != nil {
+			return nil, err
+		}
+		c.Actions = append(skillActions, startingAttacks...)
+		heroes = append(heroes, c)
+	}
+	return heroes, nil
+}
+
+// heroSkillActions 已学技能在当前等级解锁的动作，法力消耗高的优先
+func (l *battleConfigLoader) heroSkillActions(ctx context.Context, heroID string) ([]*battle.Action, error) {
+	skills, err := l.svc.heroSkillRepo.GetByHeroID(ctx, heroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询英雄技能失败")
+	}
+
+	var actions []*battle.Action
+	for _, skill := range skills {
+		unlocks, err := l.svc.skillUnlockActionRepo.GetBySkillID(ctx, skill.SkillID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询技能解锁动作失败")
+		}
+		for _, unlock := range unlocks {
+			if unlock.UnlockLevel > skill.SkillLevel {
+				continue
+			}
+			action, err := l.svc.actionRepo.GetByID(ctx, unlock.ActionID)
+			if err != nil {
+				return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "动作不存在")
+			}
+			converted, err := l.loadAction(ctx, action, skill.SkillLevel)
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, converted)
+		}
+	}
+	sort.SliceStable(actions, func(i, j int) bool {
+		if actions[i].ManaCost != actions[j].ManaCost {
+			return actions[i].ManaCost > actions[j].ManaCost
+		}
+		return actions[i].Code < actions[j].Code
+	})
+	return actions, nil
+}
+
+// startingAttackActions 带 STARTING_ATTACK_ACTION 标记的基础攻击，所有英雄兜底可用
+func (l *battleConfigLoader) startingAttackActions(ctx context.Context) ([]*battle.Action, error) {
+	active := true
+	actions, _, err := l.svc.actionRepo.List(ctx, interfaces.ActionQueryParams{IsActive: &active})
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询动作失败")
+	}
+	var result []*battle.Action
+	for _, action := range actions {
+		if !containsString(action.StartFlags, startingAttackActionFlag) {
+			continue
+		}
+		converted, err := l.loadAction(ctx, action, 1)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, converted)
+	}
+	return result, nil
+}
+
+// loadAction 转换动作及其效果（按 execution_order 排序，合并 parameter_overrides）
+func (l *battleConfigLoader) loadAction(ctx context.Context, action *game_config.Action, skillLevel int) (*battle.Action, error) {
+	cacheKey := fmt.Sprintf("%s@%d", action.ID, skillLevel)
+	if cached, ok := l.actions[cacheKey]; ok {
+		return cached, nil
+	}
+
+	converted := &battle.Action{
+		Code:            action.ActionCode,
+		APCost:          1,
+		ManaCost:        action.ManaCost.Int,
+		ManaCostFormula: action.ManaCostFormula.String,
+		Cooldown:        action.CooldownTurns.Int,
+		UsesPerBattle:   action.UsesPerBattle.Int,
+		SkillLevel:      skillLevel,
+		Target:          battle.TargetConfig{Type: "single"},
+	}
+	if action.ActionPointCost.Valid {
+		converted.APCost = action.ActionPointCost.Int
+	}
+	if action.TargetConfig.Valid {
+		if err := json.Unmarshal(action.TargetConfig.JSON, &converted.Target); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInvalidParams, fmt.Sprintf("动作 %s 目标配置格式错误", action.ActionCode))
+		}
+	}
+	if action.HitRateConfig.Valid {
+		// 未配置的字段沿用建表默认值
+		hitRate := &battle.HitRateConfig{BaseHitRate: 75, AccuracyMultiplier: 1, MinHitRate: 5, MaxHitRate: 95}
+		if err := json.Unmarshal(action.HitRateConfig.JSON, hitRate); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInvalidParams, fmt.Sprintf("动作 %s 命中配置格式错误", action.ActionCode))
+		}
+		converted.HitRate = hitRate
+	}
+
+	links, err := l.svc.actionEffectRepo.GetByActionID(ctx, action.ID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询动作效果失败")
+	}
+	sort.SliceStable(links, func(i, j int) bool { return links[i].ExecutionOrder.Int < links[j].ExecutionOrder.Int })
+	for _, link := range links {
+		effect, err := l.loadEffect(ctx, link.EffectID, link.ParameterOverrides)
+		if err != nil {
+			return nil, err
+		}
+		converted.Effects = append(converted.Effects, effect)
+	}
+
+	l.actions[cacheKey] = converted
+	return converted, nil
+}
+
+// loadEffect 转换效果，Buff 类效果同时装载引用的 Buff
+func (l *battleConfigLoader) loadEffect(ctx context.Context, effectID string, overrides null.JSON) (*battle.Effect, error) {
+	effect, err := l.svc.effectRepo.GetByID(ctx, effectID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "效果不存在")
+	}
+
+	params := make(map[string]interface{})
+	if len(effect.Parameters) > 0 {
+		if err := json.Unmarshal(effect.Parameters, &params); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInvalidParams, fmt.Sprintf("效果 %s 参数格式错误", effect.EffectCode))
+		}
+	}
+	if overrides.Valid {
+		var extra map[string]interface{}
+		if err := json.Unmarshal(overrides.JSON, &extra); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInvalidParams, fmt.Sprintf("效果 %s 参数覆盖格式错误", effect.EffectCode))
+		}
+		for k, v := range extra {
+			params[k] = v
+		}
+	}
+
+	converted := &battle.Effect{
+		Code:    effect.EffectCode,
+		Type:    effect.EffectType,
+		Formula: effect.CalculationFormula.String,
+		Params:  make(map[string]float64),
+	}
+	for k, v := range params {
+		switch val := v.(type) {
+		case float64:
+			converted.Params[k] = val
+		case string:
+			switch k {
+			case "damage_type":
+				converted.DamageType = val
+			case "buff_code":
+				converted.BuffCode = val
+			case "attribute_code":
+				converted.AttributeCode = val
+			}
+		}
+	}
+	converted.BuffDuration = int(converted.Params["duration"])
+	if !effect.TriggerChance.IsZero() {
+		converted.TriggerChance, _ = effect.TriggerChance.Float64()
+	}
+	if effect.TriggerCondition.Valid {
+		var cond struct {
+			Condition string `json:"condition"`
+		}
+		if err := json.Unmarshal(effect.TriggerCondition.JSON, &cond); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInvalidParams, fmt.Sprintf("效果 %s 触发条件格式错误", effect.EffectCode))
+		}
+		converted.Trigger = cond.Condition
+	}
+
+	if converted.BuffCode != "" {
+		if err := l.loadBuff(ctx, converted.BuffCode); err != nil {
+			return nil, err
+		}
+	}
+	return converted, nil
+}
+
+// loadBuff 装载 Buff 及其各触发时机的效果
+func (l *battleConfigLoader) loadBuff(ctx context.Context, code string) error {
+	if _, ok := l.buffs[code]; ok {
+		return nil
+	}
+	buff, err := l.svc.buffRepo.GetByCode(ctx, code)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeResourceNotFound, fmt.Sprintf("Buff不存在: %s", code))
+	}
+
+	converted := &battle.Buff{
+		Code:      buff.BuffCode,
+		StackRule: buff.StackRule.String,
+		MaxStacks: buff.MaxStacks.Int,
+		Duration:  buff.DefaultDuration.Int,
+	}
+	// 先登记再装载效果，防止 Buff 效果互相引用时无限递归
+	l.buffs[code] = converted
+
+	links, err := l.svc.buffEffectRepo.GetByBuffID(ctx, buff.ID)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "查询Buff效果失败")
+	}
+	sort.SliceStable(links, func(i, j int) bool { return links[i].ExecutionOrder.Int < links[j].ExecutionOrder.Int })
+	for _, link := range links {
+		effect, err := l.loadEffect(ctx, link.EffectID, link.ParameterOverrides)
+		if err != nil {
+			return err
+		}
+		converted.Effects = append(converted.Effects, &battle.BuffEffect{Timing: link.TriggerTiming, Effect: effect})
+	}
+	return nil
+}
+
+// monsterAttributes 怪物基础属性映射为属性代码
+func monsterAttributes(monster *game_config.Monster) map[string]float64 {
+	attrs := make(map[string]float64)
+	for code, value := range map[string]null.Int16{
+		"STR": monster.BaseSTR,
+		"AGI": monster.BaseAgi,
+		"VIT": monster.BaseVit,
+		"WLP": monster.BaseWLP,
+		"INT": monster.BaseInt,
+		"WIS": monster.BaseWis,
+		"CHA": monster.BaseCha,
+	} {
+		if value.Valid {
+			attrs[code] = float64(value.Int16)
+		}
+	}
+	return attrs
+}
+
+// parsePassiveBuffCodes 兼容 ["CODE"] 与 [{"buff_code":"CODE"}] 两种格式
+func parsePassiveBuffCodes(raw null.JSON) ([]string, error) {
+	if !raw.Valid || len(raw.JSON) == 0 {
+		return nil, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw.JSON, &items); err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, len(items))
+	for _, item := range items {
+		var code string
+		if err := json.Unmarshal(item, &code); err == nil {
+			codes = append(codes, code)
+			continue
+		}
+		var obj struct {
+			BuffCode string `json:"buff_code"`
+		}
+		if err := json.Unmarshal(item, &obj); err != nil || obj.BuffCode == "" {
+			return nil, fmt.Errorf("无法识别的被动配置: %s", string(item))
+		}
+		codes = append(codes, obj.BuffCode)
+	}
+	return codes, nil
+}
+
+// heroResourcePools 优先使用 MAX_HP / MAX_MP 属性，缺失时按体质、智力与等级估算
+func heroResourcePools(attrs map[string]float64, level int) (int, int) {
+	maxHP := int(attrs["MAX_HP"])
+	if maxHP <= 0 {
+		maxHP = heroBaseHP + heroHPPerVIT*int(attrs["VIT"]) + heroHPPerLevel*level
+	}
+	maxMP := int(attrs["MAX_MP"])
+	if maxMP <= 0 {
+		maxMP = heroBaseMP + heroMPPerINT*int(attrs["INT"])
+	}
+	return maxHP, maxMP
+}
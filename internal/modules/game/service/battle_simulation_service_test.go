@@ -0,0 +1,36 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/aarondl/null/v8"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_config"
+)
+
+func TestParsePassiveBuffCodes(t *testing.T) {
+	codes, err := parsePassiveBuffCodes(null.JSONFrom([]byte(`["THICK_HIDE",{"buff_code":"REGEN"}]`)))
+	require.NoError(t, err)
+	require.Equal(t, []string{"THICK_HIDE", "REGEN"}, codes)
+
+	codes, err = parsePassiveBuffCodes(null.JSON{})
+	require.NoError(t, err)
+	require.Empty(t, codes)
+
+	_, err = parsePassiveBuffCodes(null.JSONFrom([]byte(`[{"value":1}]`)))
+	require.Error(t, err)
+}
+
+func TestMonsterAttributesAndHeroPools(t *testing.T) {
+	attrs := monsterAttributes(&game_config.Monster{BaseSTR: null.Int16From(8), BaseAgi: null.Int16From(4)})
+	require.Equal(t, map[string]float64{"STR": 8, "AGI": 4}, attrs)
+
+	hp, mp := heroResourcePools(map[string]float64{"VIT": 3, "INT": 2}, 4)
+	require.Equal(t, heroBaseHP+3*heroHPPerVIT+4*heroHPPerLevel, hp)
+	require.Equal(t, heroBaseMP+2*heroMPPerINT, mp)
+
+	hp, mp = heroResourcePools(map[string]float64{"MAX_HP": 150, "MAX_MP": 40, "VIT": 3}, 4)
+	require.Equal(t, 150, hp)
+	require.Equal(t, 40, mp)
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"database/sql"
+	"os"
 
 	"tsu-self/internal/modules/auth/client"
 	"tsu-self/internal/repository/impl"
@@ -41,28 +42,54 @@ type ServiceContainer struct {
 	teamKickedRecordRepo       interfaces.TeamKickedRecordRepository
 	teamWarehouseRepo          interfaces.TeamWarehouseRepository
 	teamWarehouseItemRepo      interfaces.TeamWarehouseItemRepository
-	heroWalletRepo             interfaces.HeroWalletRepository
+	heroCurrencyRepo           interfaces.HeroCurrencyRepository
+	currencyRepo               interfaces.CurrencyRepository
 	teamLootHistoryRepo        interfaces.TeamLootHistoryRepository
 	teamWarehouseLootLogRepo   interfaces.TeamWarehouseLootLogRepository
+	teamLootPolicyRepo         interfaces.TeamLootPolicyRepository
+	teamWithdrawalRepo         interfaces.TeamWarehouseWithdrawalRepository
+	teamContributionRepo       interfaces.TeamContributionRepository
+	teamRankRepo               interfaces.TeamRankRepository
+	teamActivityRepo           interfaces.TeamActivityRepository
 	playerItemRepo             interfaces.PlayerItemRepository
 	dungeonRepo                interfaces.DungeonRepository
+	dungeonScheduleRepo        interfaces.DungeonScheduleRepository
+	dungeonRoomRepo            interfaces.DungeonRoomRepository
+	dungeonEventRepo           interfaces.DungeonEventRepository
+	dungeonEventLogRepo        interfaces.TeamDungeonEventLogRepository
+	heroActiveBuffRepo         interfaces.HeroActiveBuffRepository
 	teamDungeonProgressRepo    interfaces.TeamDungeonProgressRepository
 	teamDungeonRecordRepo      interfaces.TeamDungeonRecordRepository
 	battleReportRepo           interfaces.BattleReportRepository
+	battleCallbackNonceRepo    interfaces.BattleCallbackNonceRepository
+	battleStepRepo             interfaces.BattleResultStepRepository
 
 	// 所有 Service（共享实例）
+	CurrencyService       *CurrencyService
 	HeroService           *HeroService
 	HeroAttributeService  *HeroAttributeService
 	HeroSkillService      *HeroSkillService
 	ClassService          *ClassService
 	SkillDetailService    *SkillDetailService
 	EquipmentSetService   *EquipmentSetService
+	EquipmentService      *EquipmentService
+	EnhancementService    *EquipmentEnhancementService
+	GemSocketService      *GemSocketService
+	DurabilityService     *EquipmentDurabilityService
+	ItemUseService        *ItemUseService
+	RespecService         *HeroRespecService
+	ExperienceService     *ExperienceAwardService
 	TeamService           *TeamService
 	TeamMemberService     *TeamMemberService
 	TeamWarehouseService  *TeamWarehouseService
+	ContributionService   *TeamContributionService
 	TeamDungeonService    *TeamDungeonService
+	DungeonCatalogService *DungeonCatalogService
 	TeamPermissionService *TeamPermissionService
+	TeamRankService       *TeamRankService
+	TeamActivityService   *TeamActivityService
 	BattleResultService   *BattleResultService
+	ItemDropService       *ItemDropService
 }
 
 // NewServiceContainer 创建服务容器
@@ -100,16 +127,36 @@ func NewServiceContainer(db *sql.DB, ketoClient *client.KetoClient, permissionCa
 	c.teamKickedRecordRepo = impl.NewTeamKickedRecordRepository(db)
 	c.teamWarehouseRepo = impl.NewTeamWarehouseRepository(db)
 	c.teamWarehouseItemRepo = impl.NewTeamWarehouseItemRepository(db)
-	c.heroWalletRepo = impl.NewHeroWalletRepository(db)
+	c.heroCurrencyRepo = impl.NewHeroCurrencyRepository(db)
+	c.currencyRepo = impl.NewCurrencyRepository(db)
 	c.teamLootHistoryRepo = impl.NewTeamLootHistoryRepository(db)
 	c.teamWarehouseLootLogRepo = impl.NewTeamWarehouseLootLogRepository(db)
+	c.teamLootPolicyRepo = impl.NewTeamLootPolicyRepository(db)
+	c.teamWithdrawalRepo = impl.NewTeamWarehouseWithdrawalRepository(db)
+	c.teamContributionRepo = impl.NewTeamContributionRepository(db)
+	c.teamRankRepo = impl.NewTeamRankRepository(db)
+	c.teamActivityRepo = impl.NewTeamActivityRepository(db)
 	c.playerItemRepo = impl.NewPlayerItemRepository(db)
 	c.dungeonRepo = impl.NewDungeonRepository(db)
+	c.dungeonScheduleRepo = impl.NewDungeonScheduleRepository(db)
+	c.dungeonRoomRepo = impl.NewDungeonRoomRepository(db)
+	c.dungeonEventRepo = impl.NewDungeonEventRepository(db)
+	c.dungeonEventLogRepo = impl.NewTeamDungeonEventLogRepository(db)
+	c.heroActiveBuffRepo = impl.NewHeroActiveBuffRepository(db)
 	c.teamDungeonProgressRepo = impl.NewTeamDungeonProgressRepository(db)
 	c.teamDungeonRecordRepo = impl.NewTeamDungeonRecordRepository(db)
 	c.battleReportRepo = impl.NewBattleReportRepository(db)
+	c.battleCallbackNonceRepo = impl.NewBattleCallbackNonceRepository(db)
+	c.battleStepRepo = impl.NewBattleResultStepRepository(db)
+
+	// 初始化 CurrencyService（依赖 repository）
+	c.CurrencyService = &CurrencyService{
+		db:               db,
+		currencyRepo:     c.currencyRepo,
+		heroCurrencyRepo: c.heroCurrencyRepo,
+	}
 
-	// 初始化 HeroService（依赖 repository）
+	// 初始化 HeroService（依赖 repository 和 CurrencyService）
 	c.HeroService = &HeroService{
 		db:                         db,
 		heroRepo:                   c.heroRepo,
@@ -121,6 +168,14 @@ func NewServiceContainer(db *sql.DB, ketoClient *client.KetoClient, permissionCa
 		heroLevelRequirementRepo:   c.heroLevelRequirementRepo,
 		heroAllocatedAttributeRepo: c.heroAllocatedAttributeRepo,
 		classAdvancedReqRepo:       c.classAdvancedReqRepo,
+		attributeOpRepo:            c.attributeOpRepo,
+		skillOpRepo:                c.skillOpRepo,
+		attributeUpgradeCostRepo:   c.attributeUpgradeCostRepo,
+		itemRepo:                   c.itemRepo,
+		playerItemRepo:             c.playerItemRepo,
+		equipmentSlotRepo:          impl.NewEquipmentSlotRepository(db),
+		itemOpLogRepo:              impl.NewItemOperationLogRepository(db),
+		currencyService:            c.CurrencyService,
 	}
 
 	// 初始化 HeroAttributeService（依赖 repository 和 HeroService）
@@ -132,7 +187,13 @@ func NewServiceContainer(db *sql.DB, ketoClient *client.KetoClient, permissionCa
 		heroAttributeTypeRepo:      c.heroAttributeTypeRepo,
 		heroAllocatedAttributeRepo: c.heroAllocatedAttributeRepo,
 		heroService:                c.HeroService,
+		equipmentRepo:              c.equipmentRepo,
+		itemRepo:                   c.itemRepo,
+		activeBuffRepo:             c.heroActiveBuffRepo,
+		gemSocketConfigRepo:        impl.NewGemSocketConfigRepository(db),
+		attributeCache:             permissionCache,
 	}
+	c.HeroService.attributeInvalidator = c.HeroAttributeService
 
 	// 初始化 HeroSkillService（依赖 repository 和 HeroService）
 	c.HeroSkillService = &HeroSkillService{
@@ -144,6 +205,9 @@ func NewServiceContainer(db *sql.DB, ketoClient *client.KetoClient, permissionCa
 		skillRepo:            c.skillRepo,
 		skillUpgradeCostRepo: c.skillUpgradeCostRepo,
 		skillOpRepo:          c.skillOpRepo,
+		itemRepo:             c.itemRepo,
+		playerItemRepo:       c.playerItemRepo,
+		heroCurrencyRepo:     c.heroCurrencyRepo,
 		heroService:          c.HeroService,
 	}
 
@@ -166,15 +230,60 @@ func NewServiceContainer(db *sql.DB, ketoClient *client.KetoClient, permissionCa
 		c.equipmentRepo,
 		c.itemRepo,
 	)
+	c.HeroAttributeService.equipmentSetSvc = c.EquipmentSetService
+
+	// 初始化 EquipmentService（穿脱装备后失效英雄属性缓存）
+	c.EquipmentService = NewEquipmentService(db)
+	c.EquipmentService.equipmentSetSvc = c.EquipmentSetService
+	c.EquipmentService.attributeInvalidator = c.HeroAttributeService
+
+	// 初始化 EquipmentEnhancementService（强化已穿戴装备后失效英雄属性缓存）
+	c.EnhancementService = NewEquipmentEnhancementService(db)
+	c.EnhancementService.attributeInvalidator = c.HeroAttributeService
+
+	// 初始化 GemSocketService（已穿戴装备镶嵌变化后失效英雄属性缓存）
+	c.GemSocketService = NewGemSocketService(db)
+	c.GemSocketService.attributeInvalidator = c.HeroAttributeService
+
+	// 初始化 EquipmentDurabilityService（耐久变化后失效英雄属性缓存）
+	c.DurabilityService = NewEquipmentDurabilityService(db)
+	c.DurabilityService.attributeInvalidator = c.HeroAttributeService
+	c.DurabilityService.battleStepRepo = c.battleStepRepo
+
+	// 初始化 ItemUseService（经验走 HeroService，使用后失效英雄属性缓存）
+	c.ItemUseService = NewItemUseService(db)
+	c.ItemUseService.heroService = c.HeroService
+	c.ItemUseService.attributeInvalidator = c.HeroAttributeService
+
+	// 初始化 HeroRespecService（重置复用 HeroService，洗点后失效英雄属性缓存）
+	c.RespecService = NewHeroRespecService(db)
+	c.RespecService.heroService = c.HeroService
+	c.RespecService.attributeInvalidator = c.HeroAttributeService
 
 	// 初始化 TeamPermissionService（依赖 repository、ketoClient、permissionCache）
 	c.TeamPermissionService = NewTeamPermissionService(db, ketoClient, permissionCache)
+	c.TeamPermissionService.teamMemberRepo = c.teamMemberRepo
+	c.TeamPermissionService.teamRankRepo = c.teamRankRepo
+
+	// 初始化 TeamRankService（职阶变更后通过 TeamPermissionService 同步 Keto）
+	c.TeamRankService = NewTeamRankService(db, c.TeamPermissionService)
+	c.TeamRankService.teamMemberRepo = c.teamMemberRepo
+	c.TeamRankService.teamRankRepo = c.teamRankRepo
+	c.TeamRankService.teamActivityRepo = c.teamActivityRepo
+
+	// 初始化 TeamActivityService（各团队服务共用同一个动态仓储写入时间线）
+	c.TeamActivityService = NewTeamActivityService(db)
+	c.TeamActivityService.teamMemberRepo = c.teamMemberRepo
+	c.TeamActivityService.teamRankRepo = c.teamRankRepo
+	c.TeamActivityService.teamActivityRepo = c.teamActivityRepo
 
 	// 初始化 TeamService（依赖 repository 和 TeamPermissionService）
 	c.TeamService = NewTeamService(db, c.TeamPermissionService)
+	c.TeamService.teamActivityRepo = c.teamActivityRepo
 
 	// 初始化 TeamMemberService（依赖 repository 和 TeamPermissionService）
 	c.TeamMemberService = NewTeamMemberService(db, c.TeamPermissionService)
+	c.TeamMemberService.teamActivityRepo = c.teamActivityRepo
 
 	// 初始化 TeamWarehouseService（依赖 repository）
 	c.TeamWarehouseService = &TeamWarehouseService{
@@ -182,29 +291,84 @@ func NewServiceContainer(db *sql.DB, ketoClient *client.KetoClient, permissionCa
 		teamMemberRepo:        c.teamMemberRepo,
 		teamWarehouseRepo:     c.teamWarehouseRepo,
 		teamWarehouseItemRepo: c.teamWarehouseItemRepo,
-		heroWalletRepo:        c.heroWalletRepo,
+		heroCurrencyRepo:      c.heroCurrencyRepo,
 		lootHistoryRepo:       c.teamLootHistoryRepo,
 		lootLogRepo:           c.teamWarehouseLootLogRepo,
+		lootPolicyRepo:        c.teamLootPolicyRepo,
+		withdrawalRepo:        c.teamWithdrawalRepo,
+		teamRankRepo:          c.teamRankRepo,
+		teamActivityRepo:      c.teamActivityRepo,
 		itemRepo:              c.itemRepo,
 		heroRepo:              c.heroRepo,
 		playerItemRepo:        c.playerItemRepo,
 	}
 
+	// 初始化 ItemDropService（依赖 repository）
+	c.ItemDropService = NewItemDropService(db)
+	c.ItemDropService.battleStepRepo = c.battleStepRepo
+
+	// 初始化 ExperienceAwardService（升级复用 HeroService，战斗经验取自怪物配置）
+	c.ExperienceService = NewExperienceAwardService(db)
+	c.ExperienceService.heroRepo = c.heroRepo
+	c.ExperienceService.heroService = c.HeroService
+	c.ExperienceService.battleSource = c.ItemDropService
+
+	// 初始化 TeamContributionService（竞拍成交复用仓库分配）
+	c.ContributionService = NewTeamContributionService(db, c.TeamWarehouseService)
+	c.ContributionService.teamMemberRepo = c.teamMemberRepo
+	c.ContributionService.teamWarehouseRepo = c.teamWarehouseRepo
+	c.ContributionService.teamWarehouseItemRepo = c.teamWarehouseItemRepo
+	c.ContributionService.contributionRepo = c.teamContributionRepo
+	c.ContributionService.teamRankRepo = c.teamRankRepo
+	c.ContributionService.teamActivityRepo = c.teamActivityRepo
+
 	// 初始化 TeamDungeonService（依赖 repository）
 	c.TeamDungeonService = NewTeamDungeonService(db, &TeamDungeonDependencies{
 		WarehouseService: c.TeamWarehouseService,
 		TeamMemberRepo:   c.teamMemberRepo,
+		TeamRankRepo:     c.teamRankRepo,
 		DungeonRepo:      c.dungeonRepo,
+		ScheduleRepo:     c.dungeonScheduleRepo,
 		ProgressRepo:     c.teamDungeonProgressRepo,
 		RecordRepo:       c.teamDungeonRecordRepo,
 		HeroRepo:         c.heroRepo,
+		RoomRepo:         c.dungeonRoomRepo,
+		EventRepo:        c.dungeonEventRepo,
+		ItemRepo:         c.itemRepo,
+		ActiveBuffRepo:   c.heroActiveBuffRepo,
+		EventLogRepo:     c.dungeonEventLogRepo,
+		ActivityRepo:     c.teamActivityRepo,
+		BattleStepRepo:   c.battleStepRepo,
+		BattleReportRepo: c.battleReportRepo,
+		HeroService:      c.HeroService,
+		DropService:      c.ItemDropService,
+		CurrencyService:  c.CurrencyService,
+
+		ExperienceService:   c.ExperienceService,
+		ContributionService: c.ContributionService,
 	})
 
+	c.DungeonCatalogService = &DungeonCatalogService{
+		dungeonRepo:    c.dungeonRepo,
+		scheduleRepo:   c.dungeonScheduleRepo,
+		roomRepo:       c.dungeonRoomRepo,
+		dungeonService: c.TeamDungeonService,
+	}
+
 	c.BattleResultService = NewBattleResultService(c.battleReportRepo, c.TeamDungeonService)
+	c.BattleResultService.ConfigureLoot(c.ItemDropService, os.Getenv("BATTLE_TRUSTED_LOOT") == "true")
+	c.BattleResultService.ConfigureDurability(c.DurabilityService)
+	c.BattleResultService.ConfigureExperience(c.ExperienceService)
+	c.BattleResultService.ConfigureSteps(c.battleStepRepo)
 
 	return c
 }
 
+// GetCurrencyService 获取货币服务
+func (c *ServiceContainer) GetCurrencyService() *CurrencyService {
+	return c.CurrencyService
+}
+
 // GetHeroService 获取英雄服务
 func (c *ServiceContainer) GetHeroService() *HeroService {
 	return c.HeroService
@@ -235,6 +399,11 @@ func (c *ServiceContainer) GetHeroLevelRequirementRepo() interfaces.HeroLevelReq
 	return c.heroLevelRequirementRepo
 }
 
+// GetBattleCallbackNonceRepo 获取战斗回调 nonce 仓储
+func (c *ServiceContainer) GetBattleCallbackNonceRepo() interfaces.BattleCallbackNonceRepository {
+	return c.battleCallbackNonceRepo
+}
+
 // GetSkillUpgradeCostRepo 获取技能升级消耗仓储
 func (c *ServiceContainer) GetSkillUpgradeCostRepo() interfaces.SkillUpgradeCostRepository {
 	return c.skillUpgradeCostRepo
@@ -250,11 +419,56 @@ func (c *ServiceContainer) GetEquipmentSetService() *EquipmentSetService {
 	return c.EquipmentSetService
 }
 
+// GetEquipmentService 获取装备穿戴服务
+func (c *ServiceContainer) GetEquipmentService() *EquipmentService {
+	return c.EquipmentService
+}
+
+// GetEnhancementService 获取装备强化服务
+func (c *ServiceContainer) GetEnhancementService() *EquipmentEnhancementService {
+	return c.EnhancementService
+}
+
+// GetGemSocketService 获取宝石镶嵌服务
+func (c *ServiceContainer) GetGemSocketService() *GemSocketService {
+	return c.GemSocketService
+}
+
+// GetDurabilityService 获取装备耐久服务
+func (c *ServiceContainer) GetDurabilityService() *EquipmentDurabilityService {
+	return c.DurabilityService
+}
+
+// GetItemUseService 获取消耗品使用服务
+func (c *ServiceContainer) GetItemUseService() *ItemUseService {
+	return c.ItemUseService
+}
+
+// GetRespecService 获取英雄洗点服务
+func (c *ServiceContainer) GetRespecService() *HeroRespecService {
+	return c.RespecService
+}
+
+// GetExperienceService 获取经验发放服务
+func (c *ServiceContainer) GetExperienceService() *ExperienceAwardService {
+	return c.ExperienceService
+}
+
+// GetContributionService 获取团队贡献点服务
+func (c *ServiceContainer) GetContributionService() *TeamContributionService {
+	return c.ContributionService
+}
+
 // GetTeamDungeonService 获取团队地城服务
 func (c *ServiceContainer) GetTeamDungeonService() *TeamDungeonService {
 	return c.TeamDungeonService
 }
 
+// GetDungeonCatalogService 获取地城目录服务
+func (c *ServiceContainer) GetDungeonCatalogService() *DungeonCatalogService {
+	return c.DungeonCatalogService
+}
+
 // GetTeamService 获取团队服务
 func (c *ServiceContainer) GetTeamService() *TeamService {
 	return c.TeamService
@@ -275,7 +489,22 @@ func (c *ServiceContainer) GetTeamPermissionService() *TeamPermissionService {
 	return c.TeamPermissionService
 }
 
+// GetTeamRankService 获取团队职阶服务
+func (c *ServiceContainer) GetTeamRankService() *TeamRankService {
+	return c.TeamRankService
+}
+
+// GetTeamActivityService 获取团队动态服务
+func (c *ServiceContainer) GetTeamActivityService() *TeamActivityService {
+	return c.TeamActivityService
+}
+
 // GetBattleResultService 获取战斗结果服务
 func (c *ServiceContainer) GetBattleResultService() *BattleResultService {
 	return c.BattleResultService
 }
+
+// GetItemDropService 获取物品掉落服务
+func (c *ServiceContainer) GetItemDropService() *ItemDropService {
+	return c.ItemDropService
+}
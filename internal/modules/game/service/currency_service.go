@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/impl"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// 货币流水来源
+const (
+	CurrencySourceDungeon       = "dungeon_completion"
+	CurrencySourceEvent         = "dungeon_event"
+	CurrencySourceAdvancement   = "class_advancement"
+	CurrencySourceAdmin         = "admin_grant"
+	CurrencySourceEnhancement   = "enhancement"
+	CurrencySourceRepair        = "repair"
+	CurrencySourceItemUse       = "item_use"
+	CurrencySourceWarehouse     = "warehouse"
+	CurrencySourceSkillUpgrade  = "skill_upgrade"
+	CurrencySourceSkillRollback = "skill_rollback"
+	CurrencySourceRespec        = "respec"
+)
+
+// CurrencyChange 一笔货币变动，Amount 为正表示获得，为负表示消耗
+type CurrencyChange struct {
+	HeroID     string
+	Currency   string
+	Amount     int64
+	SourceType string
+	SourceID   string
+	Note       string
+	Metadata   map[string]interface{}
+}
+
+// applyCurrencyTx 在事务内记账（调整余额 + 追加流水），余额不足时返回 CodeInsufficientResource
+func applyCurrencyTx(ctx context.Context, repo interfaces.HeroCurrencyRepository, tx boil.ContextExecutor, change CurrencyChange) (*interfaces.CurrencyTransaction, error) {
+	txn := &interfaces.CurrencyTransaction{
+		HeroID:       change.HeroID,
+		CurrencyCode: change.Currency,
+		Amount:       change.Amount,
+		SourceType:   change.SourceType,
+	}
+	if change.SourceID != "" {
+		txn.SourceID = &change.SourceID
+	}
+	if change.Note != "" {
+		txn.Note = &change.Note
+	}
+	if len(change.Metadata) > 0 {
+		raw, err := json.Marshal(change.Metadata)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "序列化流水信息失败")
+		}
+		txn.Metadata = raw
+	}
+
+	if err := repo.ApplyTx(ctx, tx, txn); err != nil {
+		if errors.Is(err, interfaces.ErrInsufficientCurrency) {
+			return nil, xerrors.New(xerrors.CodeInsufficientResource, fmt.Sprintf("%s不足", currencyDisplayName(change.Currency)))
+		}
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新货币余额失败")
+	}
+	return txn, nil
+}
+
+func currencyDisplayName(code string) string {
+	switch code {
+	case interfaces.CurrencyGold:
+		return "金币"
+	case interfaces.CurrencyHonor:
+		return "荣誉"
+	default:
+		return "货币 " + code
+	}
+}
+
+// CurrencyService 英雄多货币账本服务
+type CurrencyService struct {
+	db               *sql.DB
+	currencyRepo     interfaces.CurrencyRepository
+	heroCurrencyRepo interfaces.HeroCurrencyRepository
+}
+
+// NewCurrencyService 创建货币服务
+func NewCurrencyService(db *sql.DB) *CurrencyService {
+	return &CurrencyService{
+		db:               db,
+		currencyRepo:     impl.NewCurrencyRepository(db),
+		heroCurrencyRepo: impl.NewHeroCurrencyRepository(db),
+	}
+}
+
+// HeroCurrencyBalance 英雄货币余额
+type HeroCurrencyBalance struct {
+	Code    string
+	Name    string
+	Balance int64
+}
+
+// ApplyTx 在调用方事务内记账，校验货币已启用
+func (s *CurrencyService) ApplyTx(ctx context.Context, tx boil.ContextExecutor, change CurrencyChange) (*interfaces.CurrencyTransaction, error) {
+	if change.HeroID == "" || change.Currency == "" || change.SourceType == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+	if change.Amount == 0 {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "变动数量不能为 0")
+	}
+	currency, err := s.currencyRepo.GetByCode(ctx, change.Currency)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询货币失败")
+	}
+	if currency == nil || !currency.IsActive {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, fmt.Sprintf("货币 %s 不存在或已停用", change.Currency))
+	}
+	return applyCurrencyTx(ctx, s.heroCurrencyRepo, tx, change)
+}
+
+// Apply 在单个事务内原子执行多笔变动
+func (s *CurrencyService) Apply(ctx context.Context, changes ...CurrencyChange) ([]*interfaces.CurrencyTransaction, error) {
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer tx.Rollback()
+
+	result := make([]*interfaces.CurrencyTransaction, 0, len(changes))
+	for _, change := range changes {
+		txn, err := s.ApplyTx(ctx, tx, change)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, txn)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	return result, nil
+}
+
+// GetBalance 获取英雄单个货币余额
+func (s *CurrencyService) GetBalance(ctx context.Context, heroID, currency string) (int64, error) {
+	balance, err := s.heroCurrencyRepo.GetBalance(ctx, heroID, currency)
+	if err != nil {
+		return 0, xerrors.Wrap(err, xerrors.CodeInternalError, "查询货币余额失败")
+	}
+	return balance, nil
+}
+
+// GetWallet 获取英雄全部已启用货币的余额，未持有的货币余额为 0
+func (s *CurrencyService) GetWallet(ctx context.Context, heroID string) ([]HeroCurrencyBalance, error) {
+	if heroID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "英雄ID不能为空")
+	}
+	currencies, err := s.currencyRepo.List(ctx, false)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询货币列表失败")
+	}
+	balances, err := s.heroCurrencyRepo.GetBalances(ctx, heroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询货币余额失败")
+	}
+
+	result := make([]HeroCurrencyBalance, 0, len(currencies))
+	for _, currency := range currencies {
+		result = append(result, HeroCurrencyBalance{
+			Code:    currency.Code,
+			Name:    currency.Name,
+			Balance: balances[currency.Code],
+		})
+	}
+	return result, nil
+}
+
+// ListTransactions 分页查询英雄货币流水
+func (s *CurrencyService) ListTransactions(ctx context.Context, query interfaces.CurrencyTransactionQuery) ([]*interfaces.CurrencyTransaction, int64, error) {
+	if query.HeroID == "" {
+		return nil, 0, xerrors.New(xerrors.CodeInvalidParams, "英雄ID不能为空")
+	}
+	list, total, err := s.heroCurrencyRepo.ListTransactions(ctx, query)
+	if err != nil {
+		return nil, 0, xerrors.Wrap(err, xerrors.CodeInternalError, "查询货币流水失败")
+	}
+	return list, total, nil
+}
+
+// rewardChanges 按奖励配置与额外奖励为每名成员生成变动
+func (s *CurrencyService) rewardChanges(ctx context.Context, rewardType, rewardSourceID string, heroIDs []string, extra map[string]int64, source CurrencyChange) ([]CurrencyChange, error) {
+	amounts := make(map[string]int64)
+	rewards, err := s.currencyRepo.ListRewards(ctx, rewardType, rewardSourceID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询货币奖励失败")
+	}
+	for _, reward := range rewards {
+		amounts[reward.CurrencyCode] += reward.Amount
+	}
+	for code, amount := range extra {
+		if amount > 0 {
+			amounts[code] += amount
+		}
+	}
+
+	codes := make([]string, 0, len(amounts))
+	for code := range amounts {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	var changes []CurrencyChange
+	for _, heroID := range heroIDs {
+		for _, code := range codes {
+			change := source
+			change.HeroID = heroID
+			change.Currency = code
+			change.Amount = amounts[code]
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// GrantRewards 为成员发放来源对应的货币奖励（每人一份），所有变动在同一事务内完成
+func (s *CurrencyService) GrantRewards(ctx context.Context, rewardType, rewardSourceID string, heroIDs []string, extra map[string]int64, source CurrencyChange) error {
+	changes, err := s.rewardChanges(ctx, rewardType, rewardSourceID, heroIDs, extra, source)
+	if err != nil {
+		return err
+	}
+	_, err = s.Apply(ctx, changes...)
+	return err
+}
+
+// currencyRewardSummary 每名成员所得货币，键为货币代码
+func currencyRewardSummary(changes []CurrencyChange) map[string]int64 {
+	summary := make(map[string]int64)
+	for _, change := range changes {
+		if change.HeroID == changes[0].HeroID {
+			summary[change.Currency] += change.Amount
+		}
+	}
+	return summary
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/repository/interfaces"
+)
+
+type stubCurrencyRepo struct {
+	interfaces.CurrencyRepository
+	rewards []*interfaces.CurrencyReward
+}
+
+func (r *stubCurrencyRepo) ListRewards(ctx context.Context, sourceType, sourceID string) ([]*interfaces.CurrencyReward, error) {
+	return r.rewards, nil
+}
+
+func TestRewardChanges(t *testing.T) {
+	svc := &CurrencyService{currencyRepo: &stubCurrencyRepo{rewards: []*interfaces.CurrencyReward{
+		{CurrencyCode: interfaces.CurrencyHonor, Amount: 10},
+		{CurrencyCode: interfaces.CurrencyGold, Amount: 50},
+	}}}
+
+	source := CurrencyChange{SourceType: CurrencySourceDungeon, SourceID: "dungeon-1"}
+	changes, err := svc.rewardChanges(context.Background(), interfaces.CurrencyRewardDungeon, "dungeon-1",
+		[]string{"h1", "h2"}, map[string]int64{interfaces.CurrencyHonor: 5, "token": 0}, source)
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+
+	// 货币按代码排序，每名成员一份
+	require.Equal(t, "h1", changes[0].HeroID)
+	require.Equal(t, interfaces.CurrencyGold, changes[0].Currency)
+	require.Equal(t, int64(50), changes[0].Amount)
+	require.Equal(t, interfaces.CurrencyHonor, changes[1].Currency)
+	require.Equal(t, int64(15), changes[1].Amount)
+	require.Equal(t, "h2", changes[2].HeroID)
+	require.Equal(t, CurrencySourceDungeon, changes[3].SourceType)
+	require.Equal(t, "dungeon-1", changes[3].SourceID)
+
+	require.Equal(t, map[string]int64{interfaces.CurrencyGold: 50, interfaces.CurrencyHonor: 15}, currencyRewardSummary(changes))
+}
+
+func TestRewardChangesEmpty(t *testing.T) {
+	svc := &CurrencyService{currencyRepo: &stubCurrencyRepo{}}
+
+	changes, err := svc.rewardChanges(context.Background(), interfaces.CurrencyRewardEvent, "event-1", []string{"h1"}, nil, CurrencyChange{})
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
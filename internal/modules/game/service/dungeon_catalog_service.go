@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/pkg/schedule"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/impl"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// DungeonOpening 地城在某一时刻的开放状态
+type DungeonOpening struct {
+	IsOpen        bool             // 当前是否开放
+	Reason        string           // 未开放原因
+	CurrentWindow *schedule.Window // 当前开放时段（周期时间表或限时范围）
+	NextOpening   *schedule.Window // 下次开放时段（当前未开放时）
+	Location      *time.Location   // 时间表时区，未配置时为 nil
+}
+
+// evaluateDungeonOpening 综合 is_time_limited 绝对时间范围与周期时间表计算开放状态，两者都满足才开放
+func evaluateDungeonOpening(dungeon *game_config.Dungeon, sched *interfaces.DungeonSchedule, now time.Time) DungeonOpening {
+	if !dungeon.IsActive {
+		return DungeonOpening{Reason: "地城暂未开放"}
+	}
+
+	// 1. 绝对时间范围
+	var rangeStart, rangeEnd time.Time
+	if dungeon.IsTimeLimited {
+		if !dungeon.TimeLimitStart.Valid || !dungeon.TimeLimitEnd.Valid {
+			return DungeonOpening{Reason: "地城时间配置不正确"}
+		}
+		rangeStart, rangeEnd = dungeon.TimeLimitStart.Time, dungeon.TimeLimitEnd.Time
+		if now.After(rangeEnd) {
+			return DungeonOpening{Reason: "地城开放时间已结束"}
+		}
+	}
+	inRange := func(t time.Time) bool {
+		return rangeStart.IsZero() || (!t.Before(rangeStart) && !t.After(rangeEnd))
+	}
+
+	// 2. 无周期时间表：仅受绝对时间范围约束
+	if sched == nil || !sched.IsActive {
+		if !inRange(now) {
+			return DungeonOpening{
+				Reason:      "当前不在地城开放时间",
+				NextOpening: &schedule.Window{Start: rangeStart, End: rangeEnd},
+			}
+		}
+		opening := DungeonOpening{IsOpen: true}
+		if dungeon.IsTimeLimited {
+			opening.CurrentWindow = &schedule.Window{Start: rangeStart, End: rangeEnd}
+		}
+		return opening
+	}
+
+	compiled, err := schedule.Compile(sched.Schedule)
+	if err != nil {
+		return DungeonOpening{Reason: "地城时间配置不正确"}
+	}
+	opening := DungeonOpening{Location: compiled.Location()}
+
+	// 3. 周期时间表与绝对范围取交集
+	clip := func(w schedule.Window) schedule.Window {
+		if !rangeStart.IsZero() {
+			if w.Start.Before(rangeStart) {
+				w.Start = rangeStart
+			}
+			if w.End.After(rangeEnd) {
+				w.End = rangeEnd
+			}
+		}
+		return w
+	}
+	if current, ok := compiled.Current(now); ok && inRange(now) {
+		w := clip(current)
+		opening.IsOpen = true
+		opening.CurrentWindow = &w
+		return opening
+	}
+
+	from := now
+	if rangeStart.After(from) {
+		from = rangeStart
+	}
+	for next, ok := compiled.Next(from); ok; next, ok = compiled.Next(next.End) {
+		w := clip(next)
+		if !rangeEnd.IsZero() && !w.Start.Before(rangeEnd) {
+			break
+		}
+		if w.Start.After(now) && w.End.After(w.Start) {
+			opening.NextOpening = &w
+			break
+		}
+	}
+	opening.Reason = "当前不在地城开放时间"
+	return opening
+}
+
+// openingError 未开放时的错误，附带下次开放时间
+func (o DungeonOpening) openingError() error {
+	if o.IsOpen {
+		return nil
+	}
+	if o.NextOpening != nil {
+		start := o.NextOpening.Start
+		if o.Location != nil {
+			start = start.In(o.Location)
+		}
+		return xerrors.New(xerrors.CodeInvalidParams, fmt.Sprintf("%s，下次开放: %s", o.Reason, start.Format(time.RFC3339)))
+	}
+	return xerrors.New(xerrors.CodeInvalidParams, o.Reason)
+}
+
+// DungeonCatalogService 玩家侧地城目录
+type DungeonCatalogService struct {
+	dungeonRepo    interfaces.DungeonRepository
+	scheduleRepo   interfaces.DungeonScheduleRepository
+	roomRepo       interfaces.DungeonRoomRepository
+	dungeonService *TeamDungeonService
+}
+
+// NewDungeonCatalogService 创建地城目录服务
+func NewDungeonCatalogService(db *sql.DB) *DungeonCatalogService {
+	return &DungeonCatalogService{
+		dungeonRepo:    impl.NewDungeonRepository(db),
+		scheduleRepo:   impl.NewDungeonScheduleRepository(db),
+		roomRepo:       impl.NewDungeonRoomRepository(db),
+		dungeonService: NewTeamDungeonService(db, nil),
+	}
+}
+
+// DungeonCatalogQuery 地城目录查询参数，TeamID 与 HeroID 同时提供时计算团队资格
+type DungeonCatalogQuery struct {
+	TeamID string
+	HeroID string
+	Limit  int
+	Offset int
+}
+
+// DungeonRoomOutline 房间概览（不暴露条件跳转等细节）
+type DungeonRoomOutline struct {
+	Sort     int
+	RoomID   string
+	RoomCode string
+	RoomName string
+	RoomType string
+}
+
+// DungeonCatalogItem 地城目录项
+type DungeonCatalogItem struct {
+	Dungeon     *game_config.Dungeon
+	Opening     DungeonOpening
+	Rooms       []DungeonRoomOutline
+	Eligibility *DungeonEligibility // 未指定团队时为 nil
+}
+
+// ListDungeons 列出已上线地城及其开放状态
+func (s *DungeonCatalogService) ListDungeons(ctx context.Context, query DungeonCatalogQuery) ([]*DungeonCatalogItem, int64, error) {
+	isActive := true
+	dungeons, total, err := s.dungeonRepo.List(ctx, interfaces.DungeonQueryParams{
+		IsActive: &isActive,
+		Limit:    query.Limit,
+		Offset:   query.Offset,
+		OrderBy:  "min_level",
+	})
+	if err != nil {
+		return nil, 0, xerrors.Wrap(err, xerrors.CodeInternalError, "查询地城列表失败")
+	}
+
+	items, err := s.buildItems(ctx, dungeons, query.TeamID, query.HeroID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// GetDungeon 获取地城详情
+func (s *DungeonCatalogService) GetDungeon(ctx context.Context, dungeonID, teamID, heroID string) (*DungeonCatalogItem, error) {
+	if dungeonID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "地城ID不能为空")
+	}
+	dungeon, err := s.dungeonRepo.GetByID(ctx, dungeonID)
+	if err != nil || !dungeon.IsActive {
+		return nil, xerrors.New(xerrors.CodeResourceNotFound, "地城不存在")
+	}
+
+	items, err := s.buildItems(ctx, []*game_config.Dungeon{dungeon}, teamID, heroID)
+	if err != nil {
+		return nil, err
+	}
+	return items[0], nil
+}
+
+// buildItems 组装开放状态、房间概览与团队资格
+func (s *DungeonCatalogService) buildItems(ctx context.Context, dungeons []*game_config.Dungeon, teamID, heroID string) ([]*DungeonCatalogItem, error) {
+	// 1. 开放时间表
+	ids := make([]string, len(dungeons))
+	for i, dungeon := range dungeons {
+		ids[i] = dungeon.ID
+	}
+	schedules, err := s.scheduleRepo.ListByDungeonIDs(ctx, ids)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询地城开放时间表失败")
+	}
+
+	// 2. 房间概览
+	sequences := make([]roomSequence, len(dungeons))
+	var roomIDs, roomCodes []string
+	for i, dungeon := range dungeons {
+		seq, err := parseRoomSequence(dungeon.RoomSequence)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, "地城房间配置不正确")
+		}
+		sequences[i] = seq
+		for _, step := range seq {
+			if step.RoomID != "" {
+				roomIDs = append(roomIDs, step.RoomID)
+			} else {
+				roomCodes = append(roomCodes, step.RoomCode)
+			}
+		}
+	}
+	rooms, err := s.loadRooms(ctx, roomIDs, roomCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 团队资格
+	var eligibility map[string]*DungeonEligibility
+	if teamID != "" {
+		eligibility, err = s.dungeonService.EvaluateEligibility(ctx, teamID, heroID, dungeons)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	items := make([]*DungeonCatalogItem, len(dungeons))
+	for i, dungeon := range dungeons {
+		outline := make([]DungeonRoomOutline, len(sequences[i]))
+		for j, step := range sequences[i] {
+			outline[j] = DungeonRoomOutline{Sort: step.Sort, RoomID: step.RoomID, RoomCode: step.RoomCode}
+			if room := rooms[step.key()]; room != nil {
+				outline[j].RoomID = room.ID
+				outline[j].RoomCode = room.RoomCode
+				outline[j].RoomName = room.RoomName.String
+				outline[j].RoomType = room.RoomType
+			}
+		}
+		items[i] = &DungeonCatalogItem{
+			Dungeon:     dungeon,
+			Opening:     evaluateDungeonOpening(dungeon, schedules[dungeon.ID], now),
+			Rooms:       outline,
+			Eligibility: eligibility[dungeon.ID],
+		}
+	}
+	return items, nil
+}
+
+// loadRooms 批量加载房间，按 ID 与代码建立索引
+func (s *DungeonCatalogService) loadRooms(ctx context.Context, ids, codes []string) (map[string]*game_config.DungeonRoom, error) {
+	result := make(map[string]*game_config.DungeonRoom)
+	byID, err := s.roomRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询房间失败")
+	}
+	byCode, err := s.roomRepo.GetByCodes(ctx, codes)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询房间失败")
+	}
+	for _, room := range byID {
+		result[room.ID] = room
+	}
+	for _, room := range byCode {
+		result[room.RoomCode] = room
+	}
+	return result, nil
+}
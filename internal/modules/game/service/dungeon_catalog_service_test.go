@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aarondl/null/v8"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/pkg/schedule"
+	"tsu-self/internal/repository/interfaces"
+)
+
+func TestEvaluateDungeonOpening(t *testing.T) {
+	dungeon := &game_config.Dungeon{ID: "d1", IsActive: true}
+	weekly := &interfaces.DungeonSchedule{
+		DungeonID: "d1",
+		IsActive:  true,
+		Schedule: schedule.Schedule{
+			Timezone: "UTC",
+			Rules:    []schedule.Rule{{Type: schedule.RuleWeekly, Weekdays: []int{2}, StartTime: "20:00", EndTime: "22:00"}},
+		},
+	}
+
+	// 无时间表：始终开放
+	require.True(t, evaluateDungeonOpening(dungeon, nil, time.Now()).IsOpen)
+
+	// 2026-09-15 周二
+	opening := evaluateDungeonOpening(dungeon, weekly, time.Date(2026, 9, 15, 21, 0, 0, 0, time.UTC))
+	require.True(t, opening.IsOpen)
+	require.NoError(t, opening.openingError())
+
+	opening = evaluateDungeonOpening(dungeon, weekly, time.Date(2026, 9, 16, 10, 0, 0, 0, time.UTC))
+	require.False(t, opening.IsOpen)
+	require.NotNil(t, opening.NextOpening)
+	require.Equal(t, time.Date(2026, 9, 22, 20, 0, 0, 0, time.UTC), opening.NextOpening.Start)
+	require.ErrorContains(t, opening.openingError(), "2026-09-22T20:00:00Z")
+
+	// 停用的时间表不生效
+	inactive := *weekly
+	inactive.IsActive = false
+	require.True(t, evaluateDungeonOpening(dungeon, &inactive, time.Date(2026, 9, 16, 10, 0, 0, 0, time.UTC)).IsOpen)
+
+	// 与限时范围取交集：范围从 09-20 开始，下次开放顺延到 09-22
+	limited := *dungeon
+	limited.IsTimeLimited = true
+	limited.TimeLimitStart = null.TimeFrom(time.Date(2026, 9, 20, 0, 0, 0, 0, time.UTC))
+	limited.TimeLimitEnd = null.TimeFrom(time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC))
+	opening = evaluateDungeonOpening(&limited, weekly, time.Date(2026, 9, 15, 21, 0, 0, 0, time.UTC))
+	require.False(t, opening.IsOpen)
+	require.Equal(t, time.Date(2026, 9, 22, 20, 0, 0, 0, time.UTC), opening.NextOpening.Start)
+
+	// 范围内已无开放时段
+	opening = evaluateDungeonOpening(&limited, weekly, time.Date(2026, 9, 29, 23, 0, 0, 0, time.UTC))
+	require.False(t, opening.IsOpen)
+	require.Nil(t, opening.NextOpening)
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/aarondl/null/v8"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/impl"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// 修理方式
+const (
+	RepairMethodKit  = "kit"  // 消耗修理材料
+	RepairMethodGold = "gold" // 消耗金币，修满
+)
+
+// EquipmentDurabilityService 装备耐久服务：战斗磨损与修理
+type EquipmentDurabilityService struct {
+	db                   *sql.DB
+	itemRepo             interfaces.ItemRepository
+	playerItemRepo       interfaces.PlayerItemRepository
+	heroRepo             interfaces.HeroRepository
+	heroCurrencyRepo     interfaces.HeroCurrencyRepository
+	durabilityConfigRepo interfaces.EquipmentDurabilityConfigRepository
+	itemOpLogRepo        interfaces.ItemOperationLogRepository
+	battleStepRepo       interfaces.BattleResultStepRepository
+	attributeInvalidator heroAttributeInvalidator // 可选，耐久变化后失效英雄属性缓存
+}
+
+// NewEquipmentDurabilityService 创建装备耐久服务
+func NewEquipmentDurabilityService(db *sql.DB) *EquipmentDurabilityService {
+	return &EquipmentDurabilityService{
+		db:                   db,
+		itemRepo:             impl.NewItemRepository(db),
+		playerItemRepo:       impl.NewPlayerItemRepository(db),
+		heroRepo:             impl.NewHeroRepository(db),
+		heroCurrencyRepo:     impl.NewHeroCurrencyRepository(db),
+		durabilityConfigRepo: impl.NewEquipmentDurabilityConfigRepository(db),
+		itemOpLogRepo:        impl.NewItemOperationLogRepository(db),
+		battleStepRepo:       impl.NewBattleResultStepRepository(db),
+	}
+}
+
+// BattleWearParticipant 参与磨损结算的英雄
+type BattleWearParticipant struct {
+	HeroID string
+	Died   bool
+}
+
+// ApplyBattleWearRequest 战斗磨损请求
+type ApplyBattleWearRequest struct {
+	BattleID     string // 非空时同一场战斗只磨损一次
+	DungeonID    string
+	ResultStatus string // victory/defeat/...
+	Participants []BattleWearParticipant
+}
+
+// RepairItemRequest 修理装备请求
+type RepairItemRequest struct {
+	HeroID         string
+	ItemInstanceID string
+	Method         string // kit/gold
+	KitInstanceID  string // Method 为 kit 时必填
+}
+
+// RepairItemResult 修理结果
+type RepairItemResult struct {
+	ItemInstanceID   string
+	Method           string
+	DurabilityBefore int
+	DurabilityAfter  int
+	MaxDurability    int
+	GoldCost         int64
+	KitInstanceID    string
+}
+
+// durabilityState 修理日志中的装备状态
+type durabilityState struct {
+	CurrentDurability int    `json:"current_durability"`
+	MaxDurability     int    `json:"max_durability"`
+	Method            string `json:"method,omitempty"`
+	GoldCost          int64  `json:"gold_cost,omitempty"`
+	KitInstanceID     string `json:"kit_instance_id,omitempty"`
+}
+
+// ApplyBattleWear 按地城磨损配置扣减参战英雄已穿戴装备的耐久
+func (s *EquipmentDurabilityService) ApplyBattleWear(ctx context.Context, req *ApplyBattleWearRequest) error {
+	if req == nil || len(req.Participants) == 0 {
+		return nil
+	}
+	config, err := s.durabilityConfigRepo.GetWearConfig(ctx, req.DungeonID)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "查询耐久磨损配置失败")
+	}
+	if config == nil {
+		return nil
+	}
+
+	// 磨损与战斗步骤标记在同一事务内写入，重试结算时不会重复扣减
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer tx.Rollback()
+
+	if req.BattleID != "" && s.battleStepRepo != nil {
+		marked, err := s.battleStepRepo.MarkStep(ctx, tx, req.BattleID, interfaces.BattleStepDurabilityWear, nil)
+		if err != nil {
+			return xerrors.Wrap(err, xerrors.CodeInternalError, "登记战斗结算步骤失败")
+		}
+		if !marked {
+			return nil
+		}
+	}
+
+	var wornHeroIDs []string
+	for _, participant := range req.Participants {
+		amount := battleWearAmount(config, req.ResultStatus, participant.Died)
+		if participant.HeroID == "" || amount <= 0 {
+			continue
+		}
+		updates, err := s.playerItemRepo.WearEquippedDurability(ctx, tx, participant.HeroID, amount)
+		if err != nil {
+			return xerrors.Wrap(err, xerrors.CodeInternalError, "扣减装备耐久失败")
+		}
+		if len(updates) > 0 {
+			wornHeroIDs = append(wornHeroIDs, participant.HeroID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	if s.attributeInvalidator != nil {
+		for _, heroID := range wornHeroIDs {
+			s.attributeInvalidator.InvalidateComputedAttributes(ctx, heroID)
+		}
+	}
+	return nil
+}
+
+// RepairItem 修理装备：修理材料按材料配置恢复耐久，金币修理直接修满
+func (s *EquipmentDurabilityService) RepairItem(ctx context.Context, req *RepairItemRequest) (*RepairItemResult, error) {
+	// 1. 验证参数
+	if req.HeroID == "" || req.ItemInstanceID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "英雄ID和装备实例ID不能为空")
+	}
+	if req.Method != RepairMethodKit && req.Method != RepairMethodGold {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "修理方式只能为 kit 或 gold")
+	}
+	if req.Method == RepairMethodKit && req.KitInstanceID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "使用修理材料时必须指定材料实例ID")
+	}
+	hero, err := s.heroRepo.GetByID(ctx, req.HeroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+	}
+
+	// 2. 开启事务
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			// 仅当 Rollback 失败且不是已提交的事务时，才表示有问题
+		}
+	}()
+
+	// 3. 锁定装备并校验归属
+	item, err := s.playerItemRepo.GetByIDForUpdate(ctx, tx, req.ItemInstanceID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "装备实例不存在")
+	}
+	if item.OwnerID != hero.UserID || (item.HeroID.Valid && item.HeroID.String != hero.ID) {
+		return nil, xerrors.New(xerrors.CodePermissionDenied, "该装备不属于您")
+	}
+	if item.ItemLocation != "backpack" && item.ItemLocation != "equipped" {
+		return nil, xerrors.New(xerrors.CodeOperationNotAllowed, "只能修理背包中或已穿戴的装备")
+	}
+	itemConfig, err := s.itemRepo.GetByID(ctx, item.ItemID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "装备配置不存在")
+	}
+
+	maxDurability := itemMaxDurability(item, itemConfig)
+	current := maxDurability
+	if item.CurrentDurability.Valid {
+		current = item.CurrentDurability.Int
+	}
+	if maxDurability <= 0 || current >= maxDurability {
+		return nil, xerrors.New(xerrors.CodeOperationNotAllowed, "装备耐久已满,无需修理")
+	}
+
+	result := &RepairItemResult{
+		ItemInstanceID:   item.ID,
+		Method:           req.Method,
+		DurabilityBefore: current,
+		MaxDurability:    maxDurability,
+	}
+
+	// 4. 扣除修理消耗
+	switch req.Method {
+	case RepairMethodKit:
+		restored, err := s.consumeRepairKit(ctx, tx, hero.ID, req.KitInstanceID, itemConfig)
+		if err != nil {
+			return nil, err
+		}
+		result.KitInstanceID = req.KitInstanceID
+		result.DurabilityAfter = current + restored
+		if restored <= 0 || result.DurabilityAfter > maxDurability {
+			result.DurabilityAfter = maxDurability
+		}
+	case RepairMethodGold:
+		goldPerPoint, found, err := s.durabilityConfigRepo.GetRepairGoldPerPoint(ctx, itemConfig.ItemQuality)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询修理价格失败")
+		}
+		if !found {
+			return nil, xerrors.New(xerrors.CodeOperationNotAllowed, "该品质装备不支持金币修理")
+		}
+		result.GoldCost = int64(maxDurability-current) * int64(goldPerPoint)
+		if result.GoldCost > 0 {
+			if _, err := applyCurrencyTx(ctx, s.heroCurrencyRepo, tx, CurrencyChange{
+				HeroID:     hero.ID,
+				Currency:   interfaces.CurrencyGold,
+				Amount:     -result.GoldCost,
+				SourceType: CurrencySourceRepair,
+				SourceID:   req.ItemInstanceID,
+			}); err != nil {
+				return nil, err
+			}
+		}
+		result.DurabilityAfter = maxDurability
+	}
+
+	// 5. 更新耐久并记录日志
+	item.CurrentDurability = null.IntFrom(result.DurabilityAfter)
+	if err := s.playerItemRepo.Update(ctx, tx, item); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新装备耐久失败")
+	}
+	before := durabilityState{CurrentDurability: current, MaxDurability: maxDurability}
+	after := durabilityState{
+		CurrentDurability: result.DurabilityAfter,
+		MaxDurability:     maxDurability,
+		Method:            result.Method,
+		GoldCost:          result.GoldCost,
+		KitInstanceID:     result.KitInstanceID,
+	}
+	if err := writeItemOperationLog(ctx, s.itemOpLogRepo, tx, item.ID, "repair", hero.UserID, before, after); err != nil {
+		return nil, err
+	}
+
+	// 6. 提交事务
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	if item.ItemLocation == "equipped" && s.attributeInvalidator != nil {
+		s.attributeInvalidator.InvalidateComputedAttributes(ctx, hero.ID)
+	}
+	return result, nil
+}
+
+// consumeRepairKit 校验并消耗一个修理材料，返回可恢复的耐久（0 表示修满）
+func (s *EquipmentDurabilityService) consumeRepairKit(ctx context.Context, tx *sql.Tx, heroID, kitInstanceID string, equipConfig *game_config.Item) (int, error) {
+	kit, err := s.playerItemRepo.GetByIDForUpdate(ctx, tx, kitInstanceID)
+	if err != nil {
+		return 0, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "修理材料不存在")
+	}
+	if kit.ItemLocation != "backpack" || !kit.HeroID.Valid || kit.HeroID.String != heroID {
+		return 0, xerrors.New(xerrors.CodePermissionDenied, "只能使用当前英雄背包中的修理材料")
+	}
+	kitConfig, err := s.itemRepo.GetByID(ctx, kit.ItemID)
+	if err != nil {
+		return 0, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "修理材料配置不存在")
+	}
+	if err := validateRepairKit(kitConfig, equipConfig); err != nil {
+		return 0, err
+	}
+
+	plan, err := planStackConsumption([]*game_runtime.PlayerItem{kit}, 1)
+	if err != nil {
+		return 0, err
+	}
+	if err := applyStackConsumption(ctx, s.playerItemRepo, tx, plan); err != nil {
+		return 0, err
+	}
+	if kitConfig.RepairDurabilityAmount.Valid {
+		return kitConfig.RepairDurabilityAmount.Int, nil
+	}
+	return 0, nil
+}
+
+// validateRepairKit 校验修理材料是否适用于该装备（品质、材料类型）
+func validateRepairKit(kitConfig, equipConfig *game_config.Item) error {
+	if kitConfig.ItemType != "repair_material" {
+		return xerrors.New(xerrors.CodeInvalidParams, "该物品不是修理材料")
+	}
+	if len(kitConfig.RepairApplicableQuality) > 0 {
+		applicable := false
+		for _, quality := range kitConfig.RepairApplicableQuality {
+			if quality == equipConfig.ItemQuality {
+				applicable = true
+				break
+			}
+		}
+		if !applicable {
+			return xerrors.New(xerrors.CodeOperationNotAllowed, fmt.Sprintf("该修理材料不适用于%s品质装备", equipConfig.ItemQuality))
+		}
+	}
+	if kitConfig.RepairMaterialType.Valid &&
+		(!equipConfig.MaterialType.Valid || equipConfig.MaterialType.String != kitConfig.RepairMaterialType.String) {
+		return xerrors.New(xerrors.CodeOperationNotAllowed, "修理材料与装备材质不匹配")
+	}
+	return nil
+}
+
+// battleWearAmount 计算单个参战英雄每件装备的磨损值
+func battleWearAmount(config *interfaces.DurabilityWearConfig, resultStatus string, died bool) int {
+	amount := config.DefeatLoss
+	if resultStatus == "victory" {
+		amount = config.VictoryLoss
+	}
+	if died {
+		amount += config.DeathExtraLoss
+	}
+	return amount
+}
+
+// itemMaxDurability 装备最大耐久（实例覆盖优先），0 表示不计耐久
+func itemMaxDurability(item *game_runtime.PlayerItem, config *game_config.Item) int {
+	if item.MaxDurabilityOverride.Valid {
+		return item.MaxDurabilityOverride.Int
+	}
+	if config.MaxDurability.Valid {
+		return config.MaxDurability.Int
+	}
+	return 0
+}
+
+// isItemBroken 耐久为 0 的装备视为损坏，不提供任何加成
+func isItemBroken(item *game_runtime.PlayerItem) bool {
+	return item.CurrentDurability.Valid && item.CurrentDurability.Int <= 0
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/aarondl/null/v8"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/repository/interfaces"
+)
+
+func TestBattleWearAmount(t *testing.T) {
+	config := &interfaces.DurabilityWearConfig{VictoryLoss: 1, DefeatLoss: 3, DeathExtraLoss: 2}
+
+	require.Equal(t, 1, battleWearAmount(config, "victory", false))
+	require.Equal(t, 3, battleWearAmount(config, "victory", true))
+	require.Equal(t, 3, battleWearAmount(config, "defeat", false))
+	require.Equal(t, 5, battleWearAmount(config, "defeat", true))
+	// 其它结果（如撤退）按失败处理
+	require.Equal(t, 3, battleWearAmount(config, "retreat", false))
+}
+
+func TestItemMaxDurability(t *testing.T) {
+	config := &game_config.Item{MaxDurability: null.IntFrom(100)}
+
+	require.Equal(t, 100, itemMaxDurability(&game_runtime.PlayerItem{}, config))
+	require.Equal(t, 120, itemMaxDurability(&game_runtime.PlayerItem{MaxDurabilityOverride: null.IntFrom(120)}, config))
+	require.Equal(t, 0, itemMaxDurability(&game_runtime.PlayerItem{}, &game_config.Item{}))
+}
+
+func TestIsItemBroken(t *testing.T) {
+	require.False(t, isItemBroken(&game_runtime.PlayerItem{}))
+	require.False(t, isItemBroken(&game_runtime.PlayerItem{CurrentDurability: null.IntFrom(1)}))
+	require.True(t, isItemBroken(&game_runtime.PlayerItem{CurrentDurability: null.IntFrom(0)}))
+}
+
+func TestValidateRepairKit(t *testing.T) {
+	equip := &game_config.Item{ItemType: "equipment", ItemQuality: "rare", MaterialType: null.StringFrom("metal")}
+
+	require.NoError(t, validateRepairKit(&game_config.Item{ItemType: "repair_material"}, equip))
+	require.NoError(t, validateRepairKit(&game_config.Item{
+		ItemType:                "repair_material",
+		RepairApplicableQuality: []string{"uncommon", "rare"},
+		RepairMaterialType:      null.StringFrom("metal"),
+	}, equip))
+
+	// 非修理材料
+	require.Error(t, validateRepairKit(&game_config.Item{ItemType: "consumable"}, equip))
+	// 品质不适用
+	require.Error(t, validateRepairKit(&game_config.Item{
+		ItemType:                "repair_material",
+		RepairApplicableQuality: []string{"common"},
+	}, equip))
+	// 材质不匹配
+	require.Error(t, validateRepairKit(&game_config.Item{
+		ItemType:           "repair_material",
+		RepairMaterialType: null.StringFrom("leather"),
+	}, equip))
+}
@@ -137,6 +137,11 @@ func (s *EquipmentEffectService) CalculateAttributeBonuses(
 		return nil, err
 	}
 
+	// 耐久耗尽的装备不提供任何加成
+	if maxDurability > 0 && currentDurability <= 0 {
+		return make(map[string]*AttributeBonus), nil
+	}
+
 	// 计算实际强化等级(考虑耐久度)
 	effectiveEnhancementLevel := enhancementLevel
 	if maxDurability > 0 && currentDurability >= 0 {
@@ -278,6 +278,21 @@ func TestEquipmentEffectService_CalculateAttributeBonuses(t *testing.T) {
 	}
 }
 
+func TestEquipmentEffectService_CalculateAttributeBonuses_Broken(t *testing.T) {
+	svc := NewEquipmentEffectService()
+	effectsJSON := null.JSONFrom([]byte(`[{"Data_type": "Status", "Data_ID": "STR", "Bouns_type": "bonus", "Bouns_Number": "10"}]`))
+
+	// 耐久耗尽的装备不提供任何加成
+	bonuses, err := svc.CalculateAttributeBonuses(effectsJSON, 5, 0, 100)
+	assert.NoError(t, err)
+	assert.Empty(t, bonuses)
+
+	// 不计耐久的装备始终生效
+	bonuses, err = svc.CalculateAttributeBonuses(effectsJSON, 0, 0, 0)
+	assert.NoError(t, err)
+	assert.InDelta(t, 10.0, bonuses["STR"].FlatBonus, 0.001)
+}
+
 func TestEquipmentEffectService_MergeAttributeBonuses(t *testing.T) {
 	svc := NewEquipmentEffectService()
 
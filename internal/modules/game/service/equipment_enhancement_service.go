@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/aarondl/null/v8"
+
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/pkg/notify"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/impl"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// 强化结果
+const (
+	EnhanceResultSuccess    = "success"    // 成功，等级 +1
+	EnhanceResultFailed     = "failed"     // 失败，等级不变
+	EnhanceResultDowngraded = "downgraded" // 失败并降级
+)
+
+// EquipmentEnhancementService 装备强化服务
+type EquipmentEnhancementService struct {
+	db                   *sql.DB
+	itemRepo             interfaces.ItemRepository
+	playerItemRepo       interfaces.PlayerItemRepository
+	heroRepo             interfaces.HeroRepository
+	heroCurrencyRepo     interfaces.HeroCurrencyRepository
+	enhancementLevelRepo interfaces.EquipmentEnhancementLevelRepository
+	itemOpLogRepo        interfaces.ItemOperationLogRepository
+	attributeInvalidator heroAttributeInvalidator // 可选，强化已穿戴装备后失效英雄属性缓存
+	rng                  func() float64
+}
+
+// NewEquipmentEnhancementService 创建装备强化服务
+func NewEquipmentEnhancementService(db *sql.DB) *EquipmentEnhancementService {
+	return &EquipmentEnhancementService{
+		db:                   db,
+		itemRepo:             impl.NewItemRepository(db),
+		playerItemRepo:       impl.NewPlayerItemRepository(db),
+		heroRepo:             impl.NewHeroRepository(db),
+		heroCurrencyRepo:     impl.NewHeroCurrencyRepository(db),
+		enhancementLevelRepo: impl.NewEquipmentEnhancementLevelRepository(db),
+		itemOpLogRepo:        impl.NewItemOperationLogRepository(db),
+		rng:                  rand.Float64,
+	}
+}
+
+// EnhanceItemRequest 强化装备请求
+type EnhanceItemRequest struct {
+	HeroID         string
+	ItemInstanceID string
+}
+
+// EnhanceItemResult 强化装备结果
+type EnhanceItemResult struct {
+	ItemInstanceID string
+	Result         string // success|failed|downgraded
+	LevelBefore    int
+	LevelAfter     int
+	SuccessRate    float64
+	GoldCost       int64
+	MaterialID     string
+	MaterialCost   int
+}
+
+// enhancementState 强化操作日志中的装备状态
+type enhancementState struct {
+	EnhancementLevel int    `json:"enhancement_level"`
+	Result           string `json:"result,omitempty"`
+	GoldCost         int64  `json:"gold_cost,omitempty"`
+	MaterialID       string `json:"material_id,omitempty"`
+	MaterialCost     int    `json:"material_cost,omitempty"`
+}
+
+// EnhanceItem 强化装备
+// 在同一事务内扣除背包强化材料与英雄金币，按目标等级配置掷骰决定成功/失败/降级
+func (s *EquipmentEnhancementService) EnhanceItem(ctx context.Context, req *EnhanceItemRequest) (*EnhanceItemResult, error) {
+	// 1. 验证参数
+	if req.HeroID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "英雄ID不能为空")
+	}
+	if req.ItemInstanceID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "物品实例ID不能为空")
+	}
+
+	hero, err := s.heroRepo.GetByID(ctx, req.HeroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+	}
+
+	// 2. 开启事务
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			// 仅当 Rollback 失败且不是已提交的事务时，才表示有问题
+		}
+	}()
+
+	// 3. 锁定装备实例并校验归属
+	item, err := s.playerItemRepo.GetByIDForUpdate(ctx, tx, req.ItemInstanceID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "装备实例不存在")
+	}
+	if item.OwnerID != hero.UserID || (item.HeroID.Valid && item.HeroID.String != hero.ID) {
+		return nil, xerrors.New(xerrors.CodePermissionDenied, "该装备不属于您")
+	}
+	if item.ItemLocation != "backpack" && item.ItemLocation != "equipped" {
+		return nil, xerrors.New(xerrors.CodeOperationNotAllowed, "只能强化背包中或已穿戴的装备")
+	}
+
+	// 4. 校验装备配置
+	itemConfig, err := s.itemRepo.GetByID(ctx, item.ItemID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "装备配置不存在")
+	}
+	if itemConfig.ItemType != "equipment" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "该物品不是装备,无法强化")
+	}
+	if !itemConfig.EnhancementMaterialID.Valid && !itemConfig.EnhancementCostGold.Valid {
+		return nil, xerrors.New(xerrors.CodeOperationNotAllowed, "该装备不可强化")
+	}
+
+	// 5. 查询目标等级配置
+	currentLevel := 0
+	if item.EnhancementLevel.Valid {
+		currentLevel = int(item.EnhancementLevel.Int16)
+	}
+	levelConfig, err := s.enhancementLevelRepo.GetByTargetLevel(ctx, currentLevel+1)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询强化等级配置失败")
+	}
+	if levelConfig == nil {
+		return nil, xerrors.New(xerrors.CodeOperationNotAllowed, "装备已达到最大强化等级")
+	}
+
+	// 6. 扣除强化材料
+	result := &EnhanceItemResult{
+		ItemInstanceID: item.ID,
+		LevelBefore:    currentLevel,
+		SuccessRate:    levelConfig.SuccessRate,
+		GoldCost:       enhancementGoldCost(itemConfig.EnhancementCostGold, levelConfig.GoldMultiplier),
+	}
+	if itemConfig.EnhancementMaterialID.Valid && levelConfig.MaterialQuantity > 0 {
+		result.MaterialID = itemConfig.EnhancementMaterialID.String
+		result.MaterialCost = levelConfig.MaterialQuantity
+		if err := consumeBackpackItem(ctx, s.playerItemRepo, tx, hero.ID, result.MaterialID, result.MaterialCost); err != nil {
+			return nil, err
+		}
+	}
+
+	// 7. 扣除金币
+	if result.GoldCost > 0 {
+		if _, err := applyCurrencyTx(ctx, s.heroCurrencyRepo, tx, CurrencyChange{
+			HeroID:     hero.ID,
+			Currency:   interfaces.CurrencyGold,
+			Amount:     -result.GoldCost,
+			SourceType: CurrencySourceEnhancement,
+			SourceID:   req.ItemInstanceID,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// 8. 掷骰并更新强化等级
+	result.Result, result.LevelAfter = rollEnhancement(currentLevel, levelConfig, s.rng)
+	if result.LevelAfter != currentLevel {
+		item.EnhancementLevel = null.Int16From(int16(result.LevelAfter))
+		if err := s.playerItemRepo.Update(ctx, tx, item); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新强化等级失败")
+		}
+	}
+
+	// 9. 记录强化日志
+	before := enhancementState{EnhancementLevel: currentLevel}
+	after := enhancementState{
+		EnhancementLevel: result.LevelAfter,
+		Result:           result.Result,
+		GoldCost:         result.GoldCost,
+		MaterialID:       result.MaterialID,
+		MaterialCost:     result.MaterialCost,
+	}
+	if err := writeItemOperationLog(ctx, s.itemOpLogRepo, tx, item.ID, "enhance", hero.UserID, before, after); err != nil {
+		return nil, err
+	}
+
+	// 10. 提交事务
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+
+	// 11. 已穿戴装备等级变化时失效属性缓存
+	if item.ItemLocation == "equipped" && result.LevelAfter != currentLevel && s.attributeInvalidator != nil {
+		s.attributeInvalidator.InvalidateComputedAttributes(ctx, hero.ID)
+	}
+
+	_ = notify.Publish(ctx, notify.SubjectItemEnhanced, EnhancementEvent{
+		HeroID:         hero.ID,
+		ItemInstanceID: item.ID,
+		ItemID:         item.ItemID,
+		Result:         result.Result,
+		LevelBefore:    result.LevelBefore,
+		LevelAfter:     result.LevelAfter,
+		GoldCost:       result.GoldCost,
+		MaterialID:     result.MaterialID,
+		MaterialCost:   result.MaterialCost,
+	})
+
+	return result, nil
+}
+
+// rollEnhancement 按等级配置决定强化结果，返回结果与强化后等级
+// 先判定成功；失败后再判定是否降级，降级不低于 0
+func rollEnhancement(currentLevel int, config *interfaces.EquipmentEnhancementLevel, rng func() float64) (string, int) {
+	if rng() < config.SuccessRate {
+		return EnhanceResultSuccess, currentLevel + 1
+	}
+	if config.DowngradeLevels > 0 && rng() < config.DowngradeRate {
+		level := currentLevel - config.DowngradeLevels
+		if level < 0 {
+			level = 0
+		}
+		if level < currentLevel {
+			return EnhanceResultDowngraded, level
+		}
+	}
+	return EnhanceResultFailed, currentLevel
+}
+
+// enhancementGoldCost 计算强化金币消耗（基础消耗 × 等级倍率，向上取整）
+func enhancementGoldCost(baseCost null.Int, multiplier float64) int64 {
+	if !baseCost.Valid || baseCost.Int <= 0 || multiplier <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(float64(baseCost.Int) * multiplier))
+}
+
+// stackConsumption 单个背包堆叠的扣除结果
+type stackConsumption struct {
+	item      *game_runtime.PlayerItem
+	remaining int
+}
+
+// planStackConsumption 计算从背包堆叠中扣除指定数量后的剩余数量，数量不足时返回错误
+func planStackConsumption(stacks []*game_runtime.PlayerItem, quantity int) ([]stackConsumption, error) {
+	var plan []stackConsumption
+	need := quantity
+	for _, stack := range stacks {
+		if need <= 0 {
+			break
+		}
+		count := 1
+		if stack.StackCount.Valid {
+			count = stack.StackCount.Int
+		}
+		if count <= 0 {
+			continue
+		}
+		take := count
+		if take > need {
+			take = need
+		}
+		plan = append(plan, stackConsumption{item: stack, remaining: count - take})
+		need -= take
+	}
+	if need > 0 {
+		return nil, xerrors.New(xerrors.CodeInsufficientResource, "背包材料不足")
+	}
+	return plan, nil
+}
+
+// consumeBackpackItem 在事务内从英雄背包扣除指定物品，堆叠耗尽时软删除
+func consumeBackpackItem(ctx context.Context, repo interfaces.PlayerItemRepository, tx *sql.Tx, heroID, itemID string, quantity int) error {
+	if quantity <= 0 {
+		return nil
+	}
+	stacks, err := repo.ListBackpackStacksForUpdate(ctx, tx, heroID, itemID)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "查询背包物品失败")
+	}
+	plan, err := planStackConsumption(stacks, quantity)
+	if err != nil {
+		return err
+	}
+	return applyStackConsumption(ctx, repo, tx, plan)
+}
+
+// applyStackConsumption 写回堆叠扣除结果，堆叠耗尽时软删除
+func applyStackConsumption(ctx context.Context, repo interfaces.PlayerItemRepository, tx *sql.Tx, plan []stackConsumption) error {
+	for _, step := range plan {
+		if step.remaining > 0 {
+			step.item.StackCount = null.IntFrom(step.remaining)
+		} else {
+			step.item.DeletedAt = null.TimeFrom(time.Now())
+		}
+		if err := repo.Update(ctx, tx, step.item); err != nil {
+			return xerrors.Wrap(err, xerrors.CodeInternalError, "扣除背包物品失败")
+		}
+	}
+	return nil
+}
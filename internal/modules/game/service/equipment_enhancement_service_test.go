@@ -0,0 +1,65 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/aarondl/null/v8"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/repository/interfaces"
+)
+
+func sequenceRNG(values ...float64) func() float64 {
+	i := 0
+	return func() float64 {
+		v := values[i]
+		i++
+		return v
+	}
+}
+
+func TestRollEnhancement(t *testing.T) {
+	cfg := &interfaces.EquipmentEnhancementLevel{TargetLevel: 9, SuccessRate: 0.5, DowngradeRate: 0.3, DowngradeLevels: 2}
+
+	result, level := rollEnhancement(8, cfg, sequenceRNG(0.1))
+	require.Equal(t, EnhanceResultSuccess, result)
+	require.Equal(t, 9, level)
+
+	result, level = rollEnhancement(8, cfg, sequenceRNG(0.9, 0.5))
+	require.Equal(t, EnhanceResultFailed, result)
+	require.Equal(t, 8, level)
+
+	result, level = rollEnhancement(8, cfg, sequenceRNG(0.9, 0.1))
+	require.Equal(t, EnhanceResultDowngraded, result)
+	require.Equal(t, 6, level)
+
+	// 降级不低于 0，等级为 0 时视为普通失败
+	result, level = rollEnhancement(0, cfg, sequenceRNG(0.9, 0.1))
+	require.Equal(t, EnhanceResultFailed, result)
+	require.Equal(t, 0, level)
+}
+
+func TestEnhancementGoldCost(t *testing.T) {
+	require.Equal(t, int64(0), enhancementGoldCost(null.Int{}, 2))
+	require.Equal(t, int64(100), enhancementGoldCost(null.IntFrom(100), 1))
+	require.Equal(t, int64(141), enhancementGoldCost(null.IntFrom(100), 1.405))
+}
+
+func TestPlanStackConsumption(t *testing.T) {
+	stacks := []*game_runtime.PlayerItem{
+		{ID: "a", StackCount: null.IntFrom(1)},
+		{ID: "b"},
+		{ID: "c", StackCount: null.IntFrom(5)},
+	}
+
+	plan, err := planStackConsumption(stacks, 4)
+	require.NoError(t, err)
+	require.Len(t, plan, 3)
+	require.Equal(t, 0, plan[0].remaining)
+	require.Equal(t, 0, plan[1].remaining)
+	require.Equal(t, 3, plan[2].remaining)
+
+	_, err = planStackConsumption(stacks, 8)
+	require.Error(t, err)
+}
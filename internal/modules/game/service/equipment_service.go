@@ -3,7 +3,9 @@ package service
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/aarondl/null/v8"
 
@@ -24,6 +26,14 @@ type EquipmentService struct {
 	equipmentRepo        interfaces.EquipmentRepository
 	equipmentEffectSvc   *EquipmentEffectService
 	equipmentSetSvc      *EquipmentSetService
+	teamMemberRepo       interfaces.TeamMemberRepository
+	itemOpLogRepo        interfaces.ItemOperationLogRepository
+	attributeInvalidator heroAttributeInvalidator // 可选，穿脱装备后失效英雄属性缓存
+}
+
+// heroAttributeInvalidator 英雄属性缓存失效接口（由 HeroAttributeService 实现）
+type heroAttributeInvalidator interface {
+	InvalidateComputedAttributes(ctx context.Context, heroID string)
 }
 
 // NewEquipmentService 创建装备穿戴服务
@@ -41,6 +51,8 @@ func NewEquipmentService(db *sql.DB) *EquipmentService {
 		equipmentRepo:      equipmentRepo,
 		equipmentEffectSvc: NewEquipmentEffectService(),
 		equipmentSetSvc:    NewEquipmentSetService(equipmentSetRepo, equipmentRepo, itemRepo),
+		teamMemberRepo:     impl.NewTeamMemberRepository(db),
+		itemOpLogRepo:      impl.NewItemOperationLogRepository(db),
 	}
 }
 
@@ -49,6 +61,7 @@ type EquipItemRequest struct {
 	HeroID         string `json:"hero_id"`
 	ItemInstanceID string `json:"item_instance_id"`
 	SlotType       string `json:"slot_type,omitempty"` // 可选,如果不指定则自动选择
+	SlotID         string `json:"slot_id,omitempty"`   // 可选,指定目标槽位(已有装备时替换)
 }
 
 // EquipmentSlotDTO 装备槽位DTO
@@ -84,6 +97,7 @@ type EquipItemResponse struct {
 }
 
 // EquipItem 穿戴装备
+// 目标槽位已有装备时在同一事务内完成替换（旧装备回到背包）
 func (s *EquipmentService) EquipItem(ctx context.Context, req *EquipItemRequest) (*EquipItemResponse, error) {
 	// 1. 验证参数
 	if req.HeroID == "" {
@@ -131,6 +145,9 @@ func (s *EquipmentService) EquipItem(ctx context.Context, req *EquipItemRequest)
 	if itemInstance.OwnerID != hero.UserID {
 		return nil, xerrors.New(xerrors.CodePermissionDenied, "该装备不属于您")
 	}
+	if itemInstance.ItemLocation != "backpack" && itemInstance.ItemLocation != "equipped" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "只能穿戴背包中的装备")
+	}
 
 	// 8. 验证装备耐久度 > 0
 	if itemInstance.CurrentDurability.Valid && itemInstance.CurrentDurability.Int <= 0 {
@@ -147,12 +164,7 @@ func (s *EquipmentService) EquipItem(ctx context.Context, req *EquipItemRequest)
 		return nil, err
 	}
 
-	// 11. 验证装备唯一性
-	if err := s.validateUniqueness(ctx, itemConfig, hero); err != nil {
-		return nil, err
-	}
-
-	// 12. 确定槽位类型
+	// 11. 确定槽位类型
 	slotType := req.SlotType
 	if slotType == "" {
 		if !itemConfig.EquipSlot.Valid {
@@ -160,41 +172,84 @@ func (s *EquipmentService) EquipItem(ctx context.Context, req *EquipItemRequest)
 		}
 		slotType = itemConfig.EquipSlot.String
 	}
+	if itemConfig.EquipSlot.Valid && itemConfig.EquipSlot.String != slotType {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "装备不能穿戴到该类型槽位")
+	}
+
+	// 12. 锁定目标槽位(校验解锁等级)
+	slot, err := s.selectTargetSlot(ctx, tx, hero, slotType, req.SlotID)
+	if err != nil {
+		return nil, err
+	}
 
-	// 13. 查找可用槽位
-	slot, err := s.equipmentSlotRepo.FindAvailableSlot(ctx, req.HeroID, slotType)
+	// 13. 装备已穿戴时从原槽位移出
+	sourceSlot, err := s.equipmentSlotRepo.GetSlotByEquippedItem(ctx, tx, req.ItemInstanceID)
 	if err != nil {
-		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "没有可用的装备槽位")
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询装备所在槽位失败")
+	}
+	if sourceSlot != nil && sourceSlot.ID == slot.ID {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "该装备已穿戴在此槽位")
+	}
+
+	// 14. 验证装备唯一性(排除目标槽位和原槽位)
+	excludeSlotIDs := []string{slot.ID}
+	if sourceSlot != nil {
+		excludeSlotIDs = append(excludeSlotIDs, sourceSlot.ID)
+	}
+	if err := s.validateUniqueness(ctx, tx, itemConfig, hero, excludeSlotIDs); err != nil {
+		return nil, err
 	}
 
-	// 14. 如果槽位已有装备,先卸下
+	itemBefore := newItemSlotState(itemInstance.ItemLocation, sourceSlot)
+	if sourceSlot != nil {
+		sourceSlot.EquippedItemID = null.String{}
+		if err := s.equipmentSlotRepo.UpdateSlot(ctx, tx, sourceSlot); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新原槽位失败")
+		}
+	}
+
+	// 15. 如果槽位已有装备,先卸下(同一事务内替换)
 	var unequippedItem *game_runtime.PlayerItem
 	if slot.EquippedItemID.Valid {
+		replacedBefore := newItemSlotState("equipped", slot)
 		unequippedItem, err = s.unequipItemInternal(ctx, tx, slot.EquippedItemID.String)
 		if err != nil {
 			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "卸下原装备失败")
 		}
+		unequippedItem.ItemLocation = "backpack"
+		if err := s.recordItemOperation(ctx, tx, unequippedItem.ID, "unequip", hero.UserID, replacedBefore, newItemSlotState("backpack", nil)); err != nil {
+			return nil, err
+		}
 	}
 
-	// 15. 更新槽位装备ID
+	// 16. 更新槽位装备ID
 	slot.EquippedItemID.SetValid(req.ItemInstanceID)
 	if err := s.equipmentSlotRepo.UpdateSlot(ctx, tx, slot); err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新槽位失败")
 	}
 
-	// 16. 更新装备位置为"equipped"
+	// 17. 更新装备位置为"equipped"
 	if err := s.playerItemRepo.UpdateLocation(ctx, tx, req.ItemInstanceID, "equipped"); err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新装备位置失败")
 	}
 
-	// 17. 提交事务
+	// 18. 记录操作日志
+	if err := s.recordItemOperation(ctx, tx, req.ItemInstanceID, "equip", hero.UserID, itemBefore, newItemSlotState("equipped", slot)); err != nil {
+		return nil, err
+	}
+
+	// 19. 提交事务
 	if err := tx.Commit(); err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
 	}
 
-	// 18. TODO: 失效英雄属性缓存
+	// 20. 失效英雄属性缓存
+	s.invalidateHeroAttributes(ctx, req.HeroID)
+	if sourceSlot != nil && sourceSlot.HeroID != req.HeroID {
+		s.invalidateHeroAttributes(ctx, sourceSlot.HeroID)
+	}
 
-	// 18. 转换为DTO
+	// 21. 转换为DTO
 	resp := &EquipItemResponse{
 		Success:      true,
 		Message:      "装备穿戴成功",
@@ -208,6 +263,95 @@ func (s *EquipmentService) EquipItem(ctx context.Context, req *EquipItemRequest)
 	return resp, nil
 }
 
+// selectTargetSlot 锁定目标槽位
+// 指定 slotID 时使用该槽位；否则优先选择空槽位，全部占用时选择第一个已解锁槽位进行替换
+func (s *EquipmentService) selectTargetSlot(ctx context.Context, tx *sql.Tx, hero *game_runtime.Hero, slotType, slotID string) (*game_runtime.HeroEquipmentSlot, error) {
+	slots, err := s.equipmentSlotRepo.ListSlotsByTypeForUpdate(ctx, tx, hero.ID, slotType)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询装备槽位失败")
+	}
+
+	unlockLevels, err := s.slotUnlockLevels(ctx, hero.ClassID)
+	if err != nil {
+		return nil, err
+	}
+
+	var firstUnlocked *game_runtime.HeroEquipmentSlot
+	var lockedLevel int16
+	for _, slot := range slots {
+		unlocked, level := isSlotUnlocked(slot, hero.CurrentLevel, unlockLevels)
+		if slotID != "" {
+			if slot.ID != slotID {
+				continue
+			}
+			if !unlocked {
+				return nil, slotLockedError(slot, level)
+			}
+			return slot, nil
+		}
+		if !unlocked {
+			if slot.IsUnlocked && (lockedLevel == 0 || level < lockedLevel) {
+				lockedLevel = level
+			}
+			continue
+		}
+		if !slot.EquippedItemID.Valid {
+			return slot, nil
+		}
+		if firstUnlocked == nil {
+			firstUnlocked = slot
+		}
+	}
+
+	if slotID != "" {
+		return nil, xerrors.New(xerrors.CodeResourceNotFound, "槽位不存在或类型不匹配")
+	}
+	if firstUnlocked != nil {
+		return firstUnlocked, nil
+	}
+	if lockedLevel > 0 {
+		return nil, xerrors.New(xerrors.CodeInsufficientLevel, fmt.Sprintf("槽位未解锁(需要等级%d)", lockedLevel))
+	}
+	return nil, xerrors.New(xerrors.CodeResourceNotFound, fmt.Sprintf("没有可用的%s槽位", slotType))
+}
+
+func slotLockedError(slot *game_runtime.HeroEquipmentSlot, level int16) error {
+	if !slot.IsUnlocked {
+		return xerrors.New(xerrors.CodeOperationNotAllowed, "槽位未解锁")
+	}
+	return xerrors.New(xerrors.CodeInsufficientLevel, fmt.Sprintf("槽位未解锁(需要等级%d)", level))
+}
+
+// slotUnlockLevels 查询职业槽位配置的解锁等级
+func (s *EquipmentService) slotUnlockLevels(ctx context.Context, classID string) (map[string]int16, error) {
+	levels := make(map[string]int16)
+	if classID == "" {
+		return levels, nil
+	}
+
+	configs, err := s.equipmentSlotRepo.GetSlotConfigs(ctx, classID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询槽位配置失败")
+	}
+	for _, config := range configs {
+		if config.UnlockLevel.Valid {
+			levels[config.SlotType] = config.UnlockLevel.Int16
+		}
+	}
+	return levels, nil
+}
+
+// isSlotUnlocked 判断槽位是否已解锁，职业槽位配置优先于槽位自身记录的解锁等级
+func isSlotUnlocked(slot *game_runtime.HeroEquipmentSlot, heroLevel int16, unlockLevels map[string]int16) (bool, int16) {
+	level := int16(0)
+	if configLevel, ok := unlockLevels[slot.SlotType]; ok {
+		level = configLevel
+	} else if slot.UnlockLevel.Valid {
+		level = slot.UnlockLevel.Int16
+	}
+	return slot.IsUnlocked && heroLevel >= level, level
+}
+
 // validateClassRequirement 验证职业要求
 func (s *EquipmentService) validateClassRequirement(itemConfig *game_config.Item, hero *game_runtime.Hero) error {
 	// TODO: 职业要求通过 ItemClassRelations 关联表实现，需要查询关联表
@@ -241,20 +385,87 @@ func (s *EquipmentService) validateLevelRequirement(itemConfig *game_config.Item
 }
 
 // validateUniqueness 验证装备唯一性
-func (s *EquipmentService) validateUniqueness(ctx context.Context, itemConfig *game_config.Item, hero *game_runtime.Hero) error {
+// - character: 该英雄所有槽位内只能穿戴一件
+// - account: 该账户下所有英雄只能穿戴一件
+// - team: 所在队伍内所有成员只能穿戴一件
+// - guild: 公会系统尚未上线，按账户唯一处理
+func (s *EquipmentService) validateUniqueness(ctx context.Context, tx *sql.Tx, itemConfig *game_config.Item, hero *game_runtime.Hero, excludeSlotIDs []string) error {
 	if !itemConfig.UniquenessType.Valid || itemConfig.UniquenessType.String == "none" {
 		return nil // 没有唯一性限制
 	}
 
-	// TODO: 实现唯一性验证
-	// - account: 检查该账户下所有角色是否已装备
-	// - character: 检查该角色是否已装备
-	// - team: 检查该队伍内其他成员是否已装备
-	// - guild: 检查该公会内其他成员是否已装备
+	heroIDs, err := s.uniquenessScope(ctx, itemConfig.UniquenessType.String, hero)
+	if err != nil {
+		return err
+	}
 
+	count, err := s.equipmentSlotRepo.CountEquippedByItemConfig(ctx, tx, itemConfig.ID, heroIDs, excludeSlotIDs)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "校验装备唯一性失败")
+	}
+	if count > 0 {
+		return xerrors.New(xerrors.CodeOperationNotAllowed, uniquenessMessage(itemConfig.UniquenessType.String))
+	}
 	return nil
 }
 
+// uniquenessScope 返回唯一性校验涉及的英雄范围
+func (s *EquipmentService) uniquenessScope(ctx context.Context, uniquenessType string, hero *game_runtime.Hero) ([]string, error) {
+	switch uniquenessType {
+	case "character":
+		return []string{hero.ID}, nil
+	case "account", "guild":
+		heroes, err := s.heroRepo.GetByUserID(ctx, hero.UserID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询账户英雄失败")
+		}
+		heroIDs := make([]string, 0, len(heroes)+1)
+		heroIDs = append(heroIDs, hero.ID)
+		for _, h := range heroes {
+			if h.ID != hero.ID {
+				heroIDs = append(heroIDs, h.ID)
+			}
+		}
+		return heroIDs, nil
+	case "team":
+		heroIDs := []string{hero.ID}
+		if s.teamMemberRepo == nil {
+			return heroIDs, nil
+		}
+		memberships, err := s.teamMemberRepo.ListByHero(ctx, hero.ID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询英雄所在队伍失败")
+		}
+		seen := map[string]bool{hero.ID: true}
+		for _, membership := range memberships {
+			members, err := s.teamMemberRepo.ListByTeam(ctx, membership.TeamID)
+			if err != nil {
+				return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询队伍成员失败")
+			}
+			for _, member := range members {
+				if !seen[member.HeroID] {
+					seen[member.HeroID] = true
+					heroIDs = append(heroIDs, member.HeroID)
+				}
+			}
+		}
+		return heroIDs, nil
+	default:
+		return nil, xerrors.New(xerrors.CodeInvalidParams, fmt.Sprintf("未知的唯一性类型: %s", uniquenessType))
+	}
+}
+
+func uniquenessMessage(uniquenessType string) string {
+	switch uniquenessType {
+	case "character":
+		return "该装备为角色唯一,不能同时穿戴多件"
+	case "team":
+		return "该装备为队伍唯一,队伍中已有成员穿戴"
+	default:
+		return "该装备为账户唯一,账户下已有英雄穿戴"
+	}
+}
+
 // unequipItemInternal 卸下装备(内部方法,在事务中调用)
 func (s *EquipmentService) unequipItemInternal(ctx context.Context, tx *sql.Tx, itemInstanceID string) (*game_runtime.PlayerItem, error) {
 	// 获取装备实例
@@ -294,6 +505,11 @@ func (s *EquipmentService) UnequipItem(ctx context.Context, req *UnequipItemRequ
 		return nil, xerrors.New(xerrors.CodeInvalidParams, "槽位ID不能为空")
 	}
 
+	hero, err := s.heroRepo.GetByID(ctx, req.HeroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "英雄不存在")
+	}
+
 	// 2. 开启事务
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -305,8 +521,8 @@ func (s *EquipmentService) UnequipItem(ctx context.Context, req *UnequipItemRequ
 		}
 	}()
 
-	// 3. 获取槽位
-	slot, err := s.equipmentSlotRepo.GetSlotByID(ctx, req.SlotID)
+	// 3. 获取槽位(带锁)
+	slot, err := s.equipmentSlotRepo.GetSlotByIDForUpdate(ctx, tx, req.SlotID)
 	if err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "槽位不存在")
 	}
@@ -320,12 +536,14 @@ func (s *EquipmentService) UnequipItem(ctx context.Context, req *UnequipItemRequ
 	if !slot.EquippedItemID.Valid {
 		return nil, xerrors.New(xerrors.CodeInvalidParams, "该槽位没有装备")
 	}
+	itemBefore := newItemSlotState("equipped", slot)
 
 	// 6. 卸下装备
 	unequippedItem, err := s.unequipItemInternal(ctx, tx, slot.EquippedItemID.String)
 	if err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "卸下装备失败")
 	}
+	unequippedItem.ItemLocation = "backpack"
 
 	// 7. 更新槽位装备ID为NULL
 	slot.EquippedItemID = null.String{}
@@ -333,14 +551,20 @@ func (s *EquipmentService) UnequipItem(ctx context.Context, req *UnequipItemRequ
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新槽位失败")
 	}
 
-	// 8. 提交事务
+	// 8. 记录操作日志
+	if err := s.recordItemOperation(ctx, tx, unequippedItem.ID, "unequip", hero.UserID, itemBefore, newItemSlotState("backpack", nil)); err != nil {
+		return nil, err
+	}
+
+	// 9. 提交事务
 	if err := tx.Commit(); err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
 	}
 
-	// 9. TODO: 失效英雄属性缓存
+	// 10. 失效英雄属性缓存
+	s.invalidateHeroAttributes(ctx, req.HeroID)
 
-	// 9. 转换为DTO
+	// 11. 转换为DTO
 	return &UnequipItemResponse{
 		Success:        true,
 		Message:        "装备卸下成功",
@@ -356,13 +580,12 @@ func (s *EquipmentService) GetEquippedItems(ctx context.Context, heroID string)
 	}
 
 	// 2. 获取英雄信息
-	hero, err := s.heroRepo.GetByID(ctx, heroID)
-	if err != nil {
+	if _, err := s.heroRepo.GetByID(ctx, heroID); err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "英雄不存在")
 	}
 
-	// 3. 查询已装备物品
-	items, err := s.playerItemRepo.GetEquippedItems(ctx, hero.UserID)
+	// 3. 查询该英雄槽位中的装备(同账户其他英雄的装备不计入)
+	items, err := s.equipmentRepo.GetEquippedItems(ctx, heroID)
 	if err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询已装备物品失败")
 	}
@@ -383,16 +606,33 @@ func (s *EquipmentService) GetEquipmentSlots(ctx context.Context, heroID string)
 		return nil, xerrors.New(xerrors.CodeInvalidParams, "英雄ID不能为空")
 	}
 
+	hero, err := s.heroRepo.GetByID(ctx, heroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "英雄不存在")
+	}
+
 	// 2. 查询槽位
 	slots, err := s.equipmentSlotRepo.GetSlots(ctx, heroID)
 	if err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询装备槽位失败")
 	}
 
-	// 3. 转换为DTO
+	unlockLevels, err := s.slotUnlockLevels(ctx, hero.ClassID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 转换为DTO(按当前等级计算实际解锁状态)
 	dtos := make([]*EquipmentSlotDTO, 0, len(slots))
 	for _, slot := range slots {
-		dtos = append(dtos, convertSlotToDTO(slot))
+		dto := convertSlotToDTO(slot)
+		unlocked, level := isSlotUnlocked(slot, hero.CurrentLevel, unlockLevels)
+		dto.IsUnlocked = unlocked
+		if level > 0 {
+			unlockLevel := int(level)
+			dto.UnlockLevel = &unlockLevel
+		}
+		dtos = append(dtos, dto)
 	}
 
 	return dtos, nil
@@ -496,6 +736,67 @@ func (s *EquipmentService) GetEquipmentAttributeBonus(ctx context.Context, heroI
 
 // ==================== Helper Functions ====================
 
+// itemSlotState 装备操作前后状态(写入 item_operation_logs)
+type itemSlotState struct {
+	ItemLocation string  `json:"item_location"`
+	HeroID       *string `json:"hero_id,omitempty"`
+	SlotID       *string `json:"slot_id,omitempty"`
+	SlotType     *string `json:"slot_type,omitempty"`
+	SlotIndex    *int    `json:"slot_index,omitempty"`
+}
+
+func newItemSlotState(location string, slot *game_runtime.HeroEquipmentSlot) itemSlotState {
+	state := itemSlotState{ItemLocation: location}
+	if slot != nil {
+		index := int(slot.SlotIndex)
+		state.HeroID = &slot.HeroID
+		state.SlotID = &slot.ID
+		state.SlotType = &slot.SlotType
+		state.SlotIndex = &index
+	}
+	return state
+}
+
+// recordItemOperation 在事务内写入物品操作日志
+func (s *EquipmentService) recordItemOperation(ctx context.Context, tx *sql.Tx, itemInstanceID, operationType, operatorID string, before, after interface{}) error {
+	if s.itemOpLogRepo == nil {
+		return nil
+	}
+	return writeItemOperationLog(ctx, s.itemOpLogRepo, tx, itemInstanceID, operationType, operatorID, before, after)
+}
+
+// writeItemOperationLog 写入物品操作日志(穿戴、强化、修理等共用)
+func writeItemOperationLog(ctx context.Context, repo interfaces.ItemOperationLogRepository, tx *sql.Tx, itemInstanceID, operationType, operatorID string, before, after interface{}) error {
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "序列化操作前状态失败")
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "序列化操作后状态失败")
+	}
+
+	log := &game_runtime.ItemOperationLog{
+		ItemInstanceID: itemInstanceID,
+		OperationType:  operationType,
+		OperatorID:     operatorID,
+		StateBefore:    null.JSONFrom(beforeJSON),
+		StateAfter:     null.JSONFrom(afterJSON),
+		IsSuccess:      true,
+		OperatedAt:     time.Now(),
+	}
+	if err := repo.Create(ctx, tx, log); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "写入物品操作日志失败")
+	}
+	return nil
+}
+
+func (s *EquipmentService) invalidateHeroAttributes(ctx context.Context, heroID string) {
+	if s.attributeInvalidator != nil {
+		s.attributeInvalidator.InvalidateComputedAttributes(ctx, heroID)
+	}
+}
+
 // convertSlotToDTO 转换槽位Entity为DTO
 func convertSlotToDTO(slot *game_runtime.HeroEquipmentSlot) *EquipmentSlotDTO {
 	if slot == nil {
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/aarondl/null/v8"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/interfaces"
+)
+
+type fakeSlotRepo struct {
+	interfaces.EquipmentSlotRepository
+	slots   []*game_runtime.HeroEquipmentSlot
+	configs []*game_config.EquipmentSlotConfig
+}
+
+func (f *fakeSlotRepo) ListSlotsByTypeForUpdate(ctx context.Context, tx *sql.Tx, heroID, slotType string) ([]*game_runtime.HeroEquipmentSlot, error) {
+	var out []*game_runtime.HeroEquipmentSlot
+	for _, slot := range f.slots {
+		if slot.HeroID == heroID && slot.SlotType == slotType {
+			out = append(out, slot)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeSlotRepo) GetSlotConfigs(ctx context.Context, classID string) ([]*game_config.EquipmentSlotConfig, error) {
+	return f.configs, nil
+}
+
+func TestIsSlotUnlocked(t *testing.T) {
+	slot := &game_runtime.HeroEquipmentSlot{SlotType: "ring", IsUnlocked: true, UnlockLevel: null.Int16From(5)}
+
+	ok, level := isSlotUnlocked(slot, 4, nil)
+	require.False(t, ok)
+	require.Equal(t, int16(5), level)
+
+	// 职业配置优先
+	ok, level = isSlotUnlocked(slot, 4, map[string]int16{"ring": 3})
+	require.True(t, ok)
+	require.Equal(t, int16(3), level)
+
+	slot.IsUnlocked = false
+	ok, _ = isSlotUnlocked(slot, 10, nil)
+	require.False(t, ok)
+}
+
+func TestSelectTargetSlot(t *testing.T) {
+	hero := &game_runtime.Hero{ID: "h1", ClassID: "c1", CurrentLevel: 10}
+	repo := &fakeSlotRepo{
+		slots: []*game_runtime.HeroEquipmentSlot{
+			{ID: "s0", HeroID: "h1", SlotType: "ring", SlotIndex: 0, IsUnlocked: true, EquippedItemID: null.StringFrom("i0")},
+			{ID: "s1", HeroID: "h1", SlotType: "ring", SlotIndex: 1, IsUnlocked: true},
+			{ID: "s2", HeroID: "h1", SlotType: "ring", SlotIndex: 2, IsUnlocked: true, UnlockLevel: null.Int16From(20)},
+		},
+	}
+	svc := &EquipmentService{equipmentSlotRepo: repo}
+	ctx := context.Background()
+
+	// 优先空槽位
+	slot, err := svc.selectTargetSlot(ctx, nil, hero, "ring", "")
+	require.NoError(t, err)
+	require.Equal(t, "s1", slot.ID)
+
+	// 全部占用时替换第一个已解锁槽位
+	repo.slots[1].EquippedItemID = null.StringFrom("i1")
+	slot, err = svc.selectTargetSlot(ctx, nil, hero, "ring", "")
+	require.NoError(t, err)
+	require.Equal(t, "s0", slot.ID)
+
+	// 指定未解锁槽位
+	_, err = svc.selectTargetSlot(ctx, nil, hero, "ring", "s2")
+	require.Error(t, err)
+	var appErr *xerrors.AppError
+	require.ErrorAs(t, err, &appErr)
+	require.Equal(t, xerrors.CodeInsufficientLevel, appErr.Code)
+
+	// 指定槽位不存在
+	_, err = svc.selectTargetSlot(ctx, nil, hero, "ring", "missing")
+	require.Error(t, err)
+
+	// 没有已解锁槽位
+	_, err = svc.selectTargetSlot(ctx, nil, &game_runtime.Hero{ID: "h1", CurrentLevel: 1}, "weapon", "")
+	require.Error(t, err)
+}
+
+func TestNewItemSlotState(t *testing.T) {
+	slot := &game_runtime.HeroEquipmentSlot{ID: "s1", HeroID: "h1", SlotType: "weapon", SlotIndex: 1}
+	data, err := json.Marshal(newItemSlotState("equipped", slot))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"item_location":"equipped","hero_id":"h1","slot_id":"s1","slot_type":"weapon","slot_index":1}`, string(data))
+
+	data, err = json.Marshal(newItemSlotState("backpack", nil))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"item_location":"backpack"}`, string(data))
+}
@@ -118,10 +118,10 @@ func (s *EquipmentSetService) GetActiveSets(ctx context.Context, heroID string)
 	setCountMap := make(map[string]int)
 	for _, item := range equippedItems {
 		config, ok := itemConfigMap[item.ItemID]
-		if !ok {
+		if !ok || isItemBroken(item) {
 			continue
 		}
-		// 检查装备是否属于某个套装
+		// 检查装备是否属于某个套装（损坏装备不计入件数）
 		if config.SetID.Valid {
 			setID := config.SetID.String
 			setCountMap[setID]++
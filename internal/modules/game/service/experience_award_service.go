@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"sort"
+	"time"
+
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/impl"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// defaultExperienceAwardRule 未配置任何规则时的兜底：平分、不衰减、无休息经验
+var defaultExperienceAwardRule = interfaces.ExperienceAwardRule{
+	SourceType:        interfaces.ExperienceSourceDefault,
+	SplitMode:         interfaces.ExperienceSplitEqual,
+	LevelPenaltyFloor: 1,
+}
+
+type battleExperienceSource interface {
+	GetBattleExperience(ctx context.Context, battleCode string) (*BattleExperience, error)
+}
+
+// ExperienceAwardService 服务端经验发放：按来源规则分配战斗/事件经验，处理等级差衰减与休息经验，升级走 HeroService.AutoLevelUp
+type ExperienceAwardService struct {
+	db           *sql.DB
+	heroRepo     interfaces.HeroRepository
+	awardRepo    interfaces.ExperienceAwardRepository
+	heroService  *HeroService
+	battleSource battleExperienceSource // 可选，战斗经验来源（怪物 drop_exp）
+	now          func() time.Time
+}
+
+// NewExperienceAwardService 创建经验发放服务
+func NewExperienceAwardService(db *sql.DB) *ExperienceAwardService {
+	return &ExperienceAwardService{
+		db:          db,
+		heroRepo:    impl.NewHeroRepository(db),
+		awardRepo:   impl.NewExperienceAwardRepository(db),
+		heroService: NewHeroService(db),
+		now:         time.Now,
+	}
+}
+
+// ExperienceAwardRequest 经验发放请求
+type ExperienceAwardRequest struct {
+	SourceType string   // battle/dungeon_event
+	SourceID   string   // 幂等键：battle_id 或事件日志ID
+	TotalXP    int64    // 基础经验（equal 模式为总额，full 模式为每人所得）
+	MinLevel   int      // 内容等级区间，<=0 表示不做等级差衰减
+	MaxLevel   int      //
+	HeroIDs    []string // 参与英雄
+}
+
+// HeroExperienceAward 单个英雄的发放结果
+type HeroExperienceAward struct {
+	HeroID      string  `json:"hero_id"`
+	BaseXP      int64   `json:"base_xp"`      // 分配并衰减后的经验
+	PenaltyRate float64 `json:"penalty_rate"` // 等级差衰减比例（1 表示不衰减）
+	RestedBonus int64   `json:"rested_bonus"` // 休息经验加成
+	AwardedXP   int64   `json:"awarded_xp"`
+	LevelBefore int     `json:"level_before"`
+	LevelAfter  int     `json:"level_after"`
+	Duplicate   bool    `json:"duplicate,omitempty"` // 该来源已发放过，本次跳过
+}
+
+// Award 在同一事务内按规则向参与英雄发放经验；同一来源对同一英雄只发放一次
+func (s *ExperienceAwardService) Award(ctx context.Context, req *ExperienceAwardRequest) ([]*HeroExperienceAward, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			// 仅当 Rollback 失败且不是已提交的事务时，才表示有问题
+		}
+	}()
+
+	awards, err := s.AwardTx(ctx, tx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	return awards, nil
+}
+
+// AwardTx 在调用方事务内发放经验，供需要与其他结算原子提交的场景使用
+func (s *ExperienceAwardService) AwardTx(ctx context.Context, tx *sql.Tx, req *ExperienceAwardRequest) ([]*HeroExperienceAward, error) {
+	// 1. 验证参数
+	if req.SourceType == "" || req.SourceID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "经验来源不能为空")
+	}
+	heroIDs := uniqueSortedIDs(req.HeroIDs)
+	if req.TotalXP <= 0 || len(heroIDs) == 0 {
+		return nil, nil
+	}
+
+	// 2. 加载规则（来源规则缺失时回退到 default 行）
+	rule, err := s.loadRule(ctx, req.SourceType)
+	if err != nil {
+		return nil, err
+	}
+	restedRule, err := s.loadRule(ctx, interfaces.ExperienceSourceDefault)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 逐个英雄计算并发放（按ID排序加锁，避免并发结算死锁）
+	now := s.now()
+	shares := splitExperience(rule.SplitMode, req.TotalXP, len(heroIDs))
+	awards := make([]*HeroExperienceAward, 0, len(heroIDs))
+	for i, heroID := range heroIDs {
+		hero, err := s.heroRepo.GetByIDForUpdate(ctx, tx, heroID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+		}
+		award := &HeroExperienceAward{
+			HeroID:      heroID,
+			PenaltyRate: levelPenaltyRate(rule, int(hero.CurrentLevel), req.MinLevel, req.MaxLevel),
+			LevelBefore: int(hero.CurrentLevel),
+			LevelAfter:  int(hero.CurrentLevel),
+		}
+		award.BaseXP = int64(math.Floor(float64(shares[i]) * award.PenaltyRate))
+
+		rested, err := s.awardRepo.GetRestedForUpdate(ctx, tx, heroID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询休息经验失败")
+		}
+		if rested == nil {
+			rested = &interfaces.HeroRestedExperience{HeroID: heroID, AccruedAt: now}
+		}
+		pool := accrueRestedXP(restedRule, rested.RestedXP, rested.AccruedAt, now)
+		award.RestedBonus = restedBonus(rule.RestedBonusRate, pool, award.BaseXP)
+		award.AwardedXP = award.BaseXP + award.RestedBonus
+
+		claimed, err := s.awardRepo.ClaimAward(ctx, tx, &interfaces.ExperienceAwardRecord{
+			HeroID:      heroID,
+			SourceType:  req.SourceType,
+			SourceID:    req.SourceID,
+			HeroLevel:   award.LevelBefore,
+			BaseXP:      award.BaseXP,
+			PenaltyRate: award.PenaltyRate,
+			RestedBonus: award.RestedBonus,
+			AwardedXP:   award.AwardedXP,
+		})
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "写入经验发放记录失败")
+		}
+		if !claimed {
+			awards = append(awards, &HeroExperienceAward{HeroID: heroID, Duplicate: true})
+			continue
+		}
+
+		rested.RestedXP = pool - award.RestedBonus
+		rested.AccruedAt = now
+		if err := s.awardRepo.SaveRested(ctx, tx, rested); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新休息经验失败")
+		}
+		if award.AwardedXP > 0 {
+			updated, err := s.heroService.AddExperienceTx(ctx, tx, heroID, award.AwardedXP)
+			if err != nil {
+				return nil, err
+			}
+			award.LevelAfter = int(updated.CurrentLevel)
+		}
+		awards = append(awards, award)
+	}
+	return awards, nil
+}
+
+// AwardBattleExperience 战斗胜利后按怪物 drop_exp 向参战英雄发放经验，battle_id 作为幂等键
+func (s *ExperienceAwardService) AwardBattleExperience(ctx context.Context, battleID, battleCode string, heroIDs []string) ([]*HeroExperienceAward, error) {
+	if s.battleSource == nil || battleCode == "" || len(heroIDs) == 0 {
+		return nil, nil
+	}
+	exp, err := s.battleSource.GetBattleExperience(ctx, battleCode)
+	if err != nil {
+		return nil, err
+	}
+	return s.Award(ctx, &ExperienceAwardRequest{
+		SourceType: interfaces.ExperienceSourceBattle,
+		SourceID:   battleID,
+		TotalXP:    exp.TotalXP,
+		MinLevel:   exp.MinLevel,
+		MaxLevel:   exp.MaxLevel,
+		HeroIDs:    heroIDs,
+	})
+}
+
+// ListAwards 分页查询英雄的经验发放记录
+func (s *ExperienceAwardService) ListAwards(ctx context.Context, heroID, userID string, limit, offset int) ([]*interfaces.ExperienceAwardRecord, int64, error) {
+	hero, err := s.heroRepo.GetByID(ctx, heroID)
+	if err != nil {
+		return nil, 0, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+	}
+	if userID != "" && hero.UserID != userID {
+		return nil, 0, xerrors.New(xerrors.CodePermissionDenied, "该英雄不属于您")
+	}
+	list, total, err := s.awardRepo.ListAwards(ctx, heroID, limit, offset)
+	if err != nil {
+		return nil, 0, xerrors.Wrap(err, xerrors.CodeInternalError, "查询经验发放记录失败")
+	}
+	return list, total, nil
+}
+
+// loadRule 获取来源规则，未配置时依次回退到 default 行与内置兜底
+func (s *ExperienceAwardService) loadRule(ctx context.Context, sourceType string) (*interfaces.ExperienceAwardRule, error) {
+	for _, key := range []string{sourceType, interfaces.ExperienceSourceDefault} {
+		rule, err := s.awardRepo.GetRule(ctx, key)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询经验分配规则失败")
+		}
+		if rule != nil {
+			return rule, nil
+		}
+	}
+	rule := defaultExperienceAwardRule
+	return &rule, nil
+}
+
+// splitExperience 按分配方式计算每名英雄的基础经验；平分时余数依次分给前几名
+func splitExperience(mode string, total int64, n int) []int64 {
+	shares := make([]int64, n)
+	if n == 0 {
+		return shares
+	}
+	if mode == interfaces.ExperienceSplitFull {
+		for i := range shares {
+			shares[i] = total
+		}
+		return shares
+	}
+	each, remainder := total/int64(n), total%int64(n)
+	for i := range shares {
+		shares[i] = each
+		if int64(i) < remainder {
+			shares[i]++
+		}
+	}
+	return shares
+}
+
+// levelPenaltyRate 等级差衰减：英雄等级偏离内容等级区间超过宽限后每级按 step 衰减，不低于 floor
+func levelPenaltyRate(rule *interfaces.ExperienceAwardRule, heroLevel, minLevel, maxLevel int) float64 {
+	if minLevel <= 0 {
+		return 1
+	}
+	if maxLevel < minLevel {
+		maxLevel = minLevel
+	}
+	diff := 0
+	switch {
+	case heroLevel > maxLevel:
+		diff = heroLevel - maxLevel
+	case heroLevel < minLevel:
+		diff = minLevel - heroLevel
+	}
+	diff -= rule.LevelPenaltyGrace
+	if diff <= 0 {
+		return 1
+	}
+	return math.Max(rule.LevelPenaltyFloor, 1-float64(diff)*rule.LevelPenaltyStep)
+}
+
+// accrueRestedXP 计算截至 now 的休息经验池（按整小时累积，不超过上限）
+func accrueRestedXP(rule *interfaces.ExperienceAwardRule, pool int64, accruedAt, now time.Time) int64 {
+	if rule.RestedXPPerHour <= 0 || rule.RestedXPCap <= 0 || !now.After(accruedAt) {
+		return pool
+	}
+	hours := int64(now.Sub(accruedAt) / time.Hour)
+	return min(pool+hours*rule.RestedXPPerHour, max(rule.RestedXPCap, pool))
+}
+
+// restedBonus 本次可消耗的休息经验：不超过基础经验 * rate，也不超过池中余量
+func restedBonus(rate float64, pool, baseXP int64) int64 {
+	if rate <= 0 || pool <= 0 || baseXP <= 0 {
+		return 0
+	}
+	return min(pool, int64(math.Floor(float64(baseXP)*rate)))
+}
+
+// uniqueSortedIDs 去重并排序（空字符串忽略）
+func uniqueSortedIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/repository/interfaces"
+)
+
+func TestSplitExperience(t *testing.T) {
+	require.Equal(t, []int64{34, 33, 33}, splitExperience(interfaces.ExperienceSplitEqual, 100, 3))
+	require.Equal(t, []int64{50, 50}, splitExperience(interfaces.ExperienceSplitFull, 50, 2))
+	require.Empty(t, splitExperience(interfaces.ExperienceSplitEqual, 100, 0))
+}
+
+func TestLevelPenaltyRate(t *testing.T) {
+	rule := &interfaces.ExperienceAwardRule{LevelPenaltyGrace: 3, LevelPenaltyStep: 0.1, LevelPenaltyFloor: 0.2}
+
+	require.Equal(t, 1.0, levelPenaltyRate(rule, 15, 10, 20), "区间内不衰减")
+	require.Equal(t, 1.0, levelPenaltyRate(rule, 23, 10, 20), "宽限内不衰减")
+	require.InDelta(t, 0.8, levelPenaltyRate(rule, 25, 10, 20), 1e-9)
+	require.InDelta(t, 0.9, levelPenaltyRate(rule, 6, 10, 20), 1e-9)
+	require.Equal(t, 0.2, levelPenaltyRate(rule, 40, 10, 20), "不低于下限")
+	require.Equal(t, 1.0, levelPenaltyRate(rule, 40, 0, 0), "未配置等级区间")
+}
+
+func TestRestedExperience(t *testing.T) {
+	rule := &interfaces.ExperienceAwardRule{RestedXPPerHour: 20, RestedXPCap: 100}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	require.Equal(t, int64(50), accrueRestedXP(rule, 10, start, start.Add(2*time.Hour+30*time.Minute)))
+	require.Equal(t, int64(100), accrueRestedXP(rule, 10, start, start.Add(24*time.Hour)))
+	require.Equal(t, int64(10), accrueRestedXP(rule, 10, start, start))
+	require.Equal(t, int64(10), accrueRestedXP(&interfaces.ExperienceAwardRule{}, 10, start, start.Add(time.Hour)))
+
+	require.Equal(t, int64(30), restedBonus(1, 30, 50), "不超过池中余量")
+	require.Equal(t, int64(25), restedBonus(0.5, 100, 50))
+	require.Equal(t, int64(0), restedBonus(0, 100, 50))
+}
+
+func TestUniqueSortedIDs(t *testing.T) {
+	require.Equal(t, []string{"a", "b"}, uniqueSortedIDs([]string{"b", "", "a", "b"}))
+}
@@ -0,0 +1,609 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/aarondl/null/v8"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/impl"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// 镶嵌中的宝石实例所在位置
+const itemLocationSocketed = "socketed"
+
+// gemSizes 孔位布局按此顺序编号（先小孔后大孔）
+var gemSizes = []string{"small", "large"}
+
+// GemSocketService 宝石镶嵌服务
+type GemSocketService struct {
+	db                   *sql.DB
+	itemRepo             interfaces.ItemRepository
+	playerItemRepo       interfaces.PlayerItemRepository
+	heroRepo             interfaces.HeroRepository
+	socketConfigRepo     interfaces.GemSocketConfigRepository
+	itemOpLogRepo        interfaces.ItemOperationLogRepository
+	attributeInvalidator heroAttributeInvalidator // 可选，已穿戴装备镶嵌变化后失效英雄属性缓存
+}
+
+// NewGemSocketService 创建宝石镶嵌服务
+func NewGemSocketService(db *sql.DB) *GemSocketService {
+	return &GemSocketService{
+		db:               db,
+		itemRepo:         impl.NewItemRepository(db),
+		playerItemRepo:   impl.NewPlayerItemRepository(db),
+		heroRepo:         impl.NewHeroRepository(db),
+		socketConfigRepo: impl.NewGemSocketConfigRepository(db),
+		itemOpLogRepo:    impl.NewItemOperationLogRepository(db),
+	}
+}
+
+// SocketGemRequest 镶嵌/替换宝石请求
+type SocketGemRequest struct {
+	HeroID         string
+	ItemInstanceID string // 装备实例ID
+	GemInstanceID  string // 背包中的宝石实例ID
+	SocketIndex    int
+}
+
+// UnsocketGemRequest 拆卸宝石请求
+type UnsocketGemRequest struct {
+	HeroID         string
+	ItemInstanceID string
+	SocketIndex    int
+}
+
+// SocketSlotDTO 装备孔位状态
+type SocketSlotDTO struct {
+	SocketIndex   int
+	Size          string   // small/large，空表示不限
+	Colors        []string // 允许的颜色，空表示不限
+	GemInstanceID *string
+	GemItemID     *string
+}
+
+// SocketGemResult 镶嵌操作结果
+type SocketGemResult struct {
+	ItemInstanceID string
+	Sockets        []*SocketSlotDTO
+	RemovedGemID   *string // 被替换/拆下的宝石实例ID（已回到背包）
+}
+
+// socketedGem player_items.socketed_gems 中的一项
+type socketedGem struct {
+	SocketIndex int    `json:"socket_index"`
+	GemItemID   string `json:"gem_item_id"`       // 宝石实例ID
+	ItemID      string `json:"item_id,omitempty"` // 宝石配置ID
+}
+
+// socketSpec 单个孔位的限制
+type socketSpec struct {
+	Size   string   `json:"size"`
+	Colors []string `json:"colors,omitempty"`
+}
+
+// gemRequirement gem_effect_configs.gem_combination 中的一项
+type gemRequirement struct {
+	GemID string `json:"gem_id"`
+	Color string `json:"color"`
+	Size  string `json:"size"`
+	Count int    `json:"count"`
+}
+
+// SocketGem 镶嵌宝石到空孔位
+func (s *GemSocketService) SocketGem(ctx context.Context, req *SocketGemRequest) (*SocketGemResult, error) {
+	return s.socketGem(ctx, req, false)
+}
+
+// ReplaceGem 替换已镶嵌的宝石，原宝石回到背包
+func (s *GemSocketService) ReplaceGem(ctx context.Context, req *SocketGemRequest) (*SocketGemResult, error) {
+	return s.socketGem(ctx, req, true)
+}
+
+func (s *GemSocketService) socketGem(ctx context.Context, req *SocketGemRequest, replace bool) (*SocketGemResult, error) {
+	// 1. 验证参数
+	if req.HeroID == "" || req.ItemInstanceID == "" || req.GemInstanceID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "英雄ID、装备实例ID和宝石实例ID不能为空")
+	}
+	hero, err := s.heroRepo.GetByID(ctx, req.HeroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+	}
+
+	// 2. 开启事务
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			// 仅当 Rollback 失败且不是已提交的事务时，才表示有问题
+		}
+	}()
+
+	// 3. 锁定装备并解析孔位
+	item, specs, gems, err := s.lockSocketedItem(ctx, tx, hero, req.ItemInstanceID)
+	if err != nil {
+		return nil, err
+	}
+	if req.SocketIndex < 0 || req.SocketIndex >= len(specs) {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "孔位不存在")
+	}
+	current := findSocketedGem(gems, req.SocketIndex)
+	if replace && current == nil {
+		return nil, xerrors.New(xerrors.CodeOperationNotAllowed, "该孔位没有宝石,无法替换")
+	}
+	if !replace && current != nil {
+		return nil, xerrors.New(xerrors.CodeOperationNotAllowed, "该孔位已镶嵌宝石")
+	}
+
+	// 4. 锁定背包中的宝石并校验颜色/大小
+	gem, err := s.playerItemRepo.GetByIDForUpdate(ctx, tx, req.GemInstanceID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "宝石实例不存在")
+	}
+	if gem.ItemLocation != "backpack" || !gem.HeroID.Valid || gem.HeroID.String != hero.ID {
+		return nil, xerrors.New(xerrors.CodePermissionDenied, "只能镶嵌当前英雄背包中的宝石")
+	}
+	gemConfig, err := s.itemRepo.GetByID(ctx, gem.ItemID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "宝石配置不存在")
+	}
+	if err := validateGemForSocket(specs[req.SocketIndex], gemConfig); err != nil {
+		return nil, err
+	}
+
+	before := gems
+	result := &SocketGemResult{ItemInstanceID: item.ID}
+
+	// 5. 原宝石回到背包
+	if current != nil {
+		if err := s.returnGemToBackpack(ctx, tx, current.GemItemID, hero.ID); err != nil {
+			return nil, err
+		}
+		removed := current.GemItemID
+		result.RemovedGemID = &removed
+	}
+
+	// 6. 宝石移入孔位（堆叠时拆出一个）
+	socketedID, err := s.takeGemFromBackpack(ctx, tx, gem)
+	if err != nil {
+		return nil, err
+	}
+	after := setSocketedGem(gems, socketedGem{SocketIndex: req.SocketIndex, GemItemID: socketedID, ItemID: gem.ItemID})
+
+	// 7. 保存镶嵌信息并记录日志
+	if err := s.saveSocketedGems(ctx, tx, item, after); err != nil {
+		return nil, err
+	}
+	if err := writeItemOperationLog(ctx, s.itemOpLogRepo, tx, item.ID, "socket", hero.UserID, before, after); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	s.invalidateIfEquipped(ctx, item, hero.ID)
+
+	result.Sockets = buildSocketSlots(specs, after)
+	return result, nil
+}
+
+// UnsocketGem 拆卸宝石，宝石回到背包
+func (s *GemSocketService) UnsocketGem(ctx context.Context, req *UnsocketGemRequest) (*SocketGemResult, error) {
+	// 1. 验证参数
+	if req.HeroID == "" || req.ItemInstanceID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "英雄ID和装备实例ID不能为空")
+	}
+	hero, err := s.heroRepo.GetByID(ctx, req.HeroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+	}
+
+	// 2. 开启事务
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			// 仅当 Rollback 失败且不是已提交的事务时，才表示有问题
+		}
+	}()
+
+	// 3. 锁定装备并定位宝石
+	item, specs, gems, err := s.lockSocketedItem(ctx, tx, hero, req.ItemInstanceID)
+	if err != nil {
+		return nil, err
+	}
+	current := findSocketedGem(gems, req.SocketIndex)
+	if current == nil {
+		return nil, xerrors.New(xerrors.CodeOperationNotAllowed, "该孔位没有宝石")
+	}
+
+	// 4. 宝石回到背包
+	if err := s.returnGemToBackpack(ctx, tx, current.GemItemID, hero.ID); err != nil {
+		return nil, err
+	}
+	removed := current.GemItemID
+	after := removeSocketedGem(gems, req.SocketIndex)
+
+	// 5. 保存镶嵌信息并记录日志
+	if err := s.saveSocketedGems(ctx, tx, item, after); err != nil {
+		return nil, err
+	}
+	if err := writeItemOperationLog(ctx, s.itemOpLogRepo, tx, item.ID, "unsocket", hero.UserID, gems, after); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	s.invalidateIfEquipped(ctx, item, hero.ID)
+
+	return &SocketGemResult{
+		ItemInstanceID: item.ID,
+		Sockets:        buildSocketSlots(specs, after),
+		RemovedGemID:   &removed,
+	}, nil
+}
+
+// GetSockets 查询装备孔位与已镶嵌宝石
+func (s *GemSocketService) GetSockets(ctx context.Context, heroID, itemInstanceID string) ([]*SocketSlotDTO, error) {
+	hero, err := s.heroRepo.GetByID(ctx, heroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+	}
+	item, err := s.playerItemRepo.GetByID(ctx, itemInstanceID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "装备实例不存在")
+	}
+	if item.OwnerID != hero.UserID {
+		return nil, xerrors.New(xerrors.CodePermissionDenied, "该装备不属于您")
+	}
+	specs, err := s.itemSockets(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	gems, err := parseSocketedGems(item.SocketedGems)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "解析镶嵌信息失败")
+	}
+	return buildSocketSlots(specs, gems), nil
+}
+
+// lockSocketedItem 锁定装备实例，校验归属并返回孔位与当前镶嵌
+func (s *GemSocketService) lockSocketedItem(ctx context.Context, tx *sql.Tx, hero *game_runtime.Hero, itemInstanceID string) (*game_runtime.PlayerItem, []socketSpec, []socketedGem, error) {
+	item, err := s.playerItemRepo.GetByIDForUpdate(ctx, tx, itemInstanceID)
+	if err != nil {
+		return nil, nil, nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "装备实例不存在")
+	}
+	if item.OwnerID != hero.UserID || (item.HeroID.Valid && item.HeroID.String != hero.ID) {
+		return nil, nil, nil, xerrors.New(xerrors.CodePermissionDenied, "该装备不属于您")
+	}
+	if item.ItemLocation != "backpack" && item.ItemLocation != "equipped" {
+		return nil, nil, nil, xerrors.New(xerrors.CodeOperationNotAllowed, "只能操作背包中或已穿戴的装备")
+	}
+	specs, err := s.itemSockets(ctx, item)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	gems, err := parseSocketedGems(item.SocketedGems)
+	if err != nil {
+		return nil, nil, nil, xerrors.Wrap(err, xerrors.CodeInternalError, "解析镶嵌信息失败")
+	}
+	return item, specs, gems, nil
+}
+
+// itemSockets 根据装备配置和孔位类型布局计算孔位列表
+func (s *GemSocketService) itemSockets(ctx context.Context, item *game_runtime.PlayerItem) ([]socketSpec, error) {
+	config, err := s.itemRepo.GetByID(ctx, item.ItemID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "装备配置不存在")
+	}
+	if config.ItemType != "equipment" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "该物品不是装备,无法镶嵌")
+	}
+
+	var layout []socketSpec
+	if config.SocketType.Valid && config.SocketType.String != "" {
+		socketType, err := s.socketConfigRepo.GetSocketTypeByCode(ctx, config.SocketType.String)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询孔位类型失败")
+		}
+		if socketType == nil {
+			return nil, xerrors.New(xerrors.CodeResourceNotFound, "孔位类型配置不存在")
+		}
+		if layout, err = parseSocketLayout(socketType.SocketLayout); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "解析孔位布局失败")
+		}
+	}
+
+	specs := buildItemSockets(layout, config.SocketCount)
+	if len(specs) == 0 {
+		return nil, xerrors.New(xerrors.CodeOperationNotAllowed, "该装备没有宝石孔位")
+	}
+	return specs, nil
+}
+
+// takeGemFromBackpack 将背包宝石移入孔位，堆叠数量大于1时拆分出一个实例，返回孔位中的宝石实例ID
+func (s *GemSocketService) takeGemFromBackpack(ctx context.Context, tx *sql.Tx, gem *game_runtime.PlayerItem) (string, error) {
+	count := 1
+	if gem.StackCount.Valid {
+		count = gem.StackCount.Int
+	}
+	if count <= 1 {
+		gem.ItemLocation = itemLocationSocketed
+		gem.LocationIndex = null.Int{}
+		if err := s.playerItemRepo.Update(ctx, tx, gem); err != nil {
+			return "", xerrors.Wrap(err, xerrors.CodeInternalError, "移动宝石失败")
+		}
+		return gem.ID, nil
+	}
+
+	gem.StackCount = null.IntFrom(count - 1)
+	if err := s.playerItemRepo.Update(ctx, tx, gem); err != nil {
+		return "", xerrors.Wrap(err, xerrors.CodeInternalError, "扣减宝石堆叠失败")
+	}
+	socketed := &game_runtime.PlayerItem{
+		ItemID:         gem.ItemID,
+		OwnerID:        gem.OwnerID,
+		HeroID:         gem.HeroID,
+		SourceType:     gem.SourceType,
+		SourceID:       gem.SourceID,
+		ItemLocation:   itemLocationSocketed,
+		IsBound:        gem.IsBound,
+		BoundAccountID: gem.BoundAccountID,
+		StackCount:     null.IntFrom(1),
+	}
+	if err := s.playerItemRepo.Create(ctx, tx, socketed); err != nil {
+		return "", xerrors.Wrap(err, xerrors.CodeInternalError, "拆分宝石失败")
+	}
+	return socketed.ID, nil
+}
+
+// returnGemToBackpack 将孔位中的宝石实例放回英雄背包
+func (s *GemSocketService) returnGemToBackpack(ctx context.Context, tx *sql.Tx, gemInstanceID, heroID string) error {
+	gem, err := s.playerItemRepo.GetByIDForUpdate(ctx, tx, gemInstanceID)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeResourceNotFound, "已镶嵌的宝石实例不存在")
+	}
+	gem.ItemLocation = "backpack"
+	gem.HeroID = null.StringFrom(heroID)
+	gem.LocationIndex = null.Int{}
+	if err := s.playerItemRepo.Update(ctx, tx, gem); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "宝石放回背包失败")
+	}
+	return nil
+}
+
+func (s *GemSocketService) saveSocketedGems(ctx context.Context, tx *sql.Tx, item *game_runtime.PlayerItem, gems []socketedGem) error {
+	if len(gems) == 0 {
+		item.SocketedGems = null.JSON{}
+	} else {
+		data, err := json.Marshal(gems)
+		if err != nil {
+			return xerrors.Wrap(err, xerrors.CodeInternalError, "序列化镶嵌信息失败")
+		}
+		item.SocketedGems = null.JSONFrom(data)
+	}
+	if err := s.playerItemRepo.Update(ctx, tx, item); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "保存镶嵌信息失败")
+	}
+	return nil
+}
+
+func (s *GemSocketService) invalidateIfEquipped(ctx context.Context, item *game_runtime.PlayerItem, heroID string) {
+	if item.ItemLocation == "equipped" && s.attributeInvalidator != nil {
+		s.attributeInvalidator.InvalidateComputedAttributes(ctx, heroID)
+	}
+}
+
+// parseSocketLayout 解析孔位布局
+// 支持 {"small":2,"large":1}（先小孔后大孔编号）或 [{"size":"small","colors":["red"]}]
+func parseSocketLayout(raw []byte) ([]socketSpec, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var specs []socketSpec
+	if err := json.Unmarshal(raw, &specs); err == nil {
+		for _, spec := range specs {
+			if spec.Size != "" && !isGemSize(spec.Size) {
+				return nil, fmt.Errorf("未知的孔位大小: %s", spec.Size)
+			}
+		}
+		return specs, nil
+	}
+
+	var counts map[string]int
+	if err := json.Unmarshal(raw, &counts); err != nil {
+		return nil, fmt.Errorf("孔位布局格式错误: %w", err)
+	}
+	for size := range counts {
+		if !isGemSize(size) {
+			return nil, fmt.Errorf("未知的孔位大小: %s", size)
+		}
+	}
+	for _, size := range gemSizes {
+		for i := 0; i < counts[size]; i++ {
+			specs = append(specs, socketSpec{Size: size})
+		}
+	}
+	return specs, nil
+}
+
+// buildItemSockets 按装备孔位数量截取/补齐布局；无布局时孔位不限颜色和大小
+func buildItemSockets(layout []socketSpec, socketCount null.Int16) []socketSpec {
+	count := len(layout)
+	if socketCount.Valid {
+		count = int(socketCount.Int16)
+	}
+	specs := make([]socketSpec, 0, count)
+	for i := 0; i < count; i++ {
+		if i < len(layout) {
+			specs = append(specs, layout[i])
+		} else if len(layout) == 0 {
+			specs = append(specs, socketSpec{})
+		}
+	}
+	return specs
+}
+
+// validateGemForSocket 校验宝石颜色与大小是否符合孔位要求
+func validateGemForSocket(spec socketSpec, gem *game_config.Item) error {
+	if gem.ItemType != "gem" {
+		return xerrors.New(xerrors.CodeInvalidParams, "该物品不是宝石")
+	}
+	if spec.Size != "" && (!gem.GemSize.Valid || gem.GemSize.String != spec.Size) {
+		return xerrors.New(xerrors.CodeInvalidParams, fmt.Sprintf("该孔位只能镶嵌%s宝石", spec.Size))
+	}
+	if len(spec.Colors) > 0 {
+		if !gem.GemColor.Valid {
+			return xerrors.New(xerrors.CodeInvalidParams, "宝石颜色不符合孔位要求")
+		}
+		for _, color := range spec.Colors {
+			if color == gem.GemColor.String {
+				return nil
+			}
+		}
+		return xerrors.New(xerrors.CodeInvalidParams, "宝石颜色不符合孔位要求")
+	}
+	return nil
+}
+
+func isGemSize(size string) bool {
+	for _, s := range gemSizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+func parseSocketedGems(raw null.JSON) ([]socketedGem, error) {
+	if !raw.Valid || len(raw.JSON) == 0 {
+		return nil, nil
+	}
+	var gems []socketedGem
+	if err := json.Unmarshal(raw.JSON, &gems); err != nil {
+		return nil, err
+	}
+	return gems, nil
+}
+
+func findSocketedGem(gems []socketedGem, index int) *socketedGem {
+	for i := range gems {
+		if gems[i].SocketIndex == index {
+			return &gems[i]
+		}
+	}
+	return nil
+}
+
+// setSocketedGem 返回放入/替换指定孔位后的新列表（按孔位排序）
+func setSocketedGem(gems []socketedGem, gem socketedGem) []socketedGem {
+	out := append(removeSocketedGem(gems, gem.SocketIndex), gem)
+	sort.Slice(out, func(i, j int) bool { return out[i].SocketIndex < out[j].SocketIndex })
+	return out
+}
+
+// removeSocketedGem 返回移除指定孔位后的新列表
+func removeSocketedGem(gems []socketedGem, index int) []socketedGem {
+	out := make([]socketedGem, 0, len(gems))
+	for _, g := range gems {
+		if g.SocketIndex != index {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+func buildSocketSlots(specs []socketSpec, gems []socketedGem) []*SocketSlotDTO {
+	slots := make([]*SocketSlotDTO, 0, len(specs))
+	for i, spec := range specs {
+		slot := &SocketSlotDTO{SocketIndex: i, Size: spec.Size, Colors: spec.Colors}
+		if gem := findSocketedGem(gems, i); gem != nil {
+			gemInstanceID, gemItemID := gem.GemItemID, gem.ItemID
+			slot.GemInstanceID = &gemInstanceID
+			slot.GemItemID = &gemItemID
+		}
+		slots = append(slots, slot)
+	}
+	return slots
+}
+
+// matches 判断宝石是否满足组合条件（gem_id 或 颜色/大小）
+func (r gemRequirement) matches(gem *game_config.Item) bool {
+	if r.GemID != "" {
+		return gem.ID == r.GemID
+	}
+	if r.Color != "" && (!gem.GemColor.Valid || gem.GemColor.String != r.Color) {
+		return false
+	}
+	if r.Size != "" && (!gem.GemSize.Valid || gem.GemSize.String != r.Size) {
+		return false
+	}
+	return r.Color != "" || r.Size != ""
+}
+
+// gemCombinationTimes 计算单件装备上的宝石满足组合的次数
+// 每个条件独立计数，同一宝石可同时满足多个条件
+func gemCombinationTimes(requirements []gemRequirement, gems []*game_config.Item) int {
+	if len(requirements) == 0 {
+		return 0
+	}
+	times := -1
+	for _, req := range requirements {
+		count := req.Count
+		if count <= 0 {
+			count = 1
+		}
+		matched := 0
+		for _, gem := range gems {
+			if req.matches(gem) {
+				matched++
+			}
+		}
+		if t := matched / count; times < 0 || t < times {
+			times = t
+		}
+	}
+	return times
+}
+
+// accumulateGemBonuses 按已启用的宝石效果配置累加单件装备的宝石加成
+func accumulateGemBonuses(effectSvc *EquipmentEffectService, gems []*game_config.Item, effectConfigs []*game_config.GemEffectConfig, bonuses map[string]*AttributeBonus) error {
+	if len(gems) == 0 {
+		return nil
+	}
+	for _, config := range effectConfigs {
+		var requirements []gemRequirement
+		if err := json.Unmarshal(config.GemCombination, &requirements); err != nil {
+			return fmt.Errorf("解析宝石组合失败(%s): %w", config.ID, err)
+		}
+		times := gemCombinationTimes(requirements, gems)
+		if times == 0 {
+			continue
+		}
+		effects, err := effectSvc.ParseOutOfCombatEffects(null.JSONFrom(config.Effects))
+		if err != nil {
+			return fmt.Errorf("解析宝石效果失败(%s): %w", config.ID, err)
+		}
+		for _, effect := range effects {
+			value := effect.BonusValue * float64(times)
+			if effect.BonusType == "percent" {
+				addAttributeBonus(bonuses, effect.TargetAttribute, 0, value)
+			} else {
+				addAttributeBonus(bonuses, effect.TargetAttribute, value, 0)
+			}
+		}
+	}
+	return nil
+}
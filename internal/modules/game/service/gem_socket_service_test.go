@@ -0,0 +1,70 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/types"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_config"
+)
+
+func TestParseSocketLayout(t *testing.T) {
+	specs, err := parseSocketLayout([]byte(`{"large":1,"small":2}`))
+	require.NoError(t, err)
+	require.Equal(t, []socketSpec{{Size: "small"}, {Size: "small"}, {Size: "large"}}, specs)
+
+	specs, err = parseSocketLayout([]byte(`[{"size":"large","colors":["red"]},{"size":""}]`))
+	require.NoError(t, err)
+	require.Len(t, specs, 2)
+	require.Equal(t, []string{"red"}, specs[0].Colors)
+
+	_, err = parseSocketLayout([]byte(`{"huge":1}`))
+	require.Error(t, err)
+
+	// 孔位数量截取布局；无布局时孔位不限
+	require.Len(t, buildItemSockets(specs, null.Int16From(1)), 1)
+	require.Equal(t, []socketSpec{{}, {}}, buildItemSockets(nil, null.Int16From(2)))
+}
+
+func TestValidateGemForSocket(t *testing.T) {
+	redSmall := &game_config.Item{ItemType: "gem", GemColor: null.StringFrom("red"), GemSize: null.StringFrom("small")}
+
+	require.NoError(t, validateGemForSocket(socketSpec{}, redSmall))
+	require.NoError(t, validateGemForSocket(socketSpec{Size: "small", Colors: []string{"blue", "red"}}, redSmall))
+	require.Error(t, validateGemForSocket(socketSpec{Size: "large"}, redSmall))
+	require.Error(t, validateGemForSocket(socketSpec{Colors: []string{"blue"}}, redSmall))
+	require.Error(t, validateGemForSocket(socketSpec{}, &game_config.Item{ItemType: "equipment"}))
+}
+
+func TestSocketedGemList(t *testing.T) {
+	gems := setSocketedGem(nil, socketedGem{SocketIndex: 2, GemItemID: "g2"})
+	gems = setSocketedGem(gems, socketedGem{SocketIndex: 0, GemItemID: "g0"})
+	gems = setSocketedGem(gems, socketedGem{SocketIndex: 2, GemItemID: "g3"})
+	require.Equal(t, []socketedGem{{SocketIndex: 0, GemItemID: "g0"}, {SocketIndex: 2, GemItemID: "g3"}}, gems)
+
+	gems = removeSocketedGem(gems, 0)
+	require.Nil(t, findSocketedGem(gems, 0))
+	require.Equal(t, "g3", findSocketedGem(gems, 2).GemItemID)
+}
+
+func TestAccumulateGemBonuses(t *testing.T) {
+	ruby := &game_config.Item{ID: "ruby", ItemType: "gem", GemColor: null.StringFrom("red"), GemSize: null.StringFrom("small")}
+	topaz := &game_config.Item{ID: "topaz", ItemType: "gem", GemColor: null.StringFrom("yellow"), GemSize: null.StringFrom("large")}
+
+	configs := []*game_config.GemEffectConfig{
+		{ID: "single", GemCombination: types.JSON(`[{"gem_id":"ruby","count":1}]`),
+			Effects: types.JSON(`[{"Data_type":"Status","Data_ID":"STR","Bouns_type":"bonus","Bouns_Number":"3"}]`)},
+		{ID: "combo", GemCombination: types.JSON(`[{"color":"red","count":1},{"color":"yellow","size":"large","count":1}]`),
+			Effects: types.JSON(`[{"Data_type":"Status","Data_ID":"ATK","Bouns_type":"percent","Bouns_Number":"10"}]`)},
+		{ID: "pair", GemCombination: types.JSON(`[{"size":"large","count":2}]`),
+			Effects: types.JSON(`[{"Data_type":"Status","Data_ID":"DEX","Bouns_type":"bonus","Bouns_Number":"5"}]`)},
+	}
+
+	bonuses := map[string]*AttributeBonus{}
+	require.NoError(t, accumulateGemBonuses(NewEquipmentEffectService(), []*game_config.Item{ruby, ruby, topaz}, configs, bonuses))
+	require.InDelta(t, 6, bonuses["STR"].FlatBonus, 1e-9)
+	require.InDelta(t, 0.1, bonuses["ATK"].PercentBonus, 1e-9)
+	require.NotContains(t, bonuses, "DEX")
+}
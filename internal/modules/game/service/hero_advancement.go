@@ -0,0 +1,398 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aarondl/null/v8"
+	"github.com/google/uuid"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// 进阶条件类型
+const (
+	AdvancementReqLevel     = "level"
+	AdvancementReqHonor     = "honor"
+	AdvancementReqAttribute = "attribute"
+	AdvancementReqSkill     = "skill"
+	AdvancementReqItem      = "item"
+)
+
+// AdvancementRequirementStatus 单项进阶条件的检查结果
+type AdvancementRequirementStatus struct {
+	Type      string `json:"type"`                // level/honor/attribute/skill/item
+	Key       string `json:"key,omitempty"`       // 属性代码、技能ID/代码或物品ID/代码
+	Name      string `json:"name,omitempty"`      // 技能或物品名称
+	Required  int    `json:"required"`            // 要求数值（技能为等级，物品为数量）
+	Current   int    `json:"current"`             // 当前数值
+	Shortfall int    `json:"shortfall,omitempty"` // 差额
+	Passed    bool   `json:"passed"`
+	Message   string `json:"message,omitempty"` // 未满足时的说明
+}
+
+// error 将未满足的条件转换为对应错误码
+func (r AdvancementRequirementStatus) error() error {
+	code := xerrors.CodeInsufficientResource
+	switch r.Type {
+	case AdvancementReqLevel:
+		code = xerrors.CodeInsufficientLevel
+	case AdvancementReqAttribute:
+		code = xerrors.CodeInsufficientAttributes
+	case AdvancementReqSkill:
+		code = xerrors.CodeInsufficientSkills
+	}
+	return xerrors.New(code, r.Message)
+}
+
+// advancementSnapshot 英雄当前状态（用于与进阶要求比对）
+type advancementSnapshot struct {
+	level      int
+	honor      int64
+	attributes map[string]int
+	skills     map[string]int    // 已学技能等级，skill_id 与 skill_code 均可索引
+	skillNames map[string]string // 技能名称，键同 skills
+	items      map[string]int    // 要求中的物品键 -> 持有数量（背包+已穿戴）
+	itemNames  map[string]string // 要求中的物品键 -> 物品名称
+}
+
+// parseRequiredSkills 解析技能要求，兼容 {"skill": level} 与 ["skill"]（等级视为 1）两种格式
+func parseRequiredSkills(raw null.JSON) (map[string]int, error) {
+	return parseRequirementCounts(raw)
+}
+
+// parseRequiredItems 解析物品要求，兼容 {"item": quantity} 与 ["item"]（数量视为 1）两种格式
+func parseRequiredItems(raw null.JSON) (map[string]int, error) {
+	return parseRequirementCounts(raw)
+}
+
+func parseRequirementCounts(raw null.JSON) (map[string]int, error) {
+	if raw.IsZero() || len(raw.JSON) == 0 || string(raw.JSON) == "null" {
+		return nil, nil
+	}
+
+	var counts map[string]int
+	if err := json.Unmarshal(raw.JSON, &counts); err == nil {
+		for key, count := range counts {
+			if count <= 0 {
+				counts[key] = 1
+			}
+		}
+		return counts, nil
+	}
+
+	var keys []string
+	if err := json.Unmarshal(raw.JSON, &keys); err != nil {
+		return nil, err
+	}
+	counts = make(map[string]int, len(keys))
+	for _, key := range keys {
+		counts[key]++
+	}
+	return counts, nil
+}
+
+func sortedRequirementKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// evaluateAdvancement 比对进阶要求与英雄状态，返回逐项检查结果
+func evaluateAdvancement(req *game_config.ClassAdvancedRequirement, snap *advancementSnapshot) (*AdvancementCheckResult, error) {
+	result := &AdvancementCheckResult{
+		CanAdvance:    true,
+		RequiredLevel: req.RequiredLevel,
+		CurrentLevel:  snap.level,
+		RequiredHonor: req.RequiredHonor,
+		CurrentHonor:  int(snap.honor),
+	}
+	add := func(status AdvancementRequirementStatus) {
+		status.Passed = status.Current >= status.Required
+		if !status.Passed {
+			status.Shortfall = status.Required - status.Current
+			result.CanAdvance = false
+			result.MissingRequirements = append(result.MissingRequirements, status.Message)
+		} else {
+			status.Message = ""
+		}
+		result.Requirements = append(result.Requirements, status)
+	}
+
+	// 1. 等级
+	add(AdvancementRequirementStatus{
+		Type:     AdvancementReqLevel,
+		Required: req.RequiredLevel,
+		Current:  snap.level,
+		Message:  fmt.Sprintf("等级不足（需要%d级，当前%d级）", req.RequiredLevel, snap.level),
+	})
+
+	// 2. 荣誉
+	if req.RequiredHonor > 0 {
+		add(AdvancementRequirementStatus{
+			Type:     AdvancementReqHonor,
+			Key:      interfaces.CurrencyHonor,
+			Required: req.RequiredHonor,
+			Current:  int(snap.honor),
+			Message:  fmt.Sprintf("荣誉不足（需要%d，当前%d）", req.RequiredHonor, snap.honor),
+		})
+	}
+
+	// 3. 属性
+	if !req.RequiredAttributes.IsZero() {
+		var requiredAttrs map[string]int
+		if err := json.Unmarshal(req.RequiredAttributes.JSON, &requiredAttrs); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "解析属性要求失败")
+		}
+		for _, code := range sortedRequirementKeys(requiredAttrs) {
+			current := snap.attributes[code]
+			add(AdvancementRequirementStatus{
+				Type:     AdvancementReqAttribute,
+				Key:      code,
+				Required: requiredAttrs[code],
+				Current:  current,
+				Message:  fmt.Sprintf("属性%s不足（需要%d，当前%d）", code, requiredAttrs[code], current),
+			})
+		}
+	}
+
+	// 4. 技能（需已学习且达到要求等级）
+	requiredSkills, err := parseRequiredSkills(req.RequiredSkills)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "解析技能要求失败")
+	}
+	for _, key := range sortedRequirementKeys(requiredSkills) {
+		required := requiredSkills[key]
+		current, learned := snap.skills[key]
+		message := fmt.Sprintf("技能%s等级不足（需要%d级，当前%d级）", key, required, current)
+		if !learned {
+			message = fmt.Sprintf("未学习技能：%s", key)
+		}
+		add(AdvancementRequirementStatus{
+			Type:     AdvancementReqSkill,
+			Key:      key,
+			Name:     snap.skillNames[key],
+			Required: required,
+			Current:  current,
+			Message:  message,
+		})
+	}
+
+	// 5. 物品（背包与已穿戴合计）
+	requiredItems, err := parseRequiredItems(req.RequiredItems)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "解析物品要求失败")
+	}
+	for _, key := range sortedRequirementKeys(requiredItems) {
+		required := requiredItems[key]
+		current := snap.items[key]
+		name := snap.itemNames[key]
+		label := name
+		if label == "" {
+			label = key
+		}
+		add(AdvancementRequirementStatus{
+			Type:     AdvancementReqItem,
+			Key:      key,
+			Name:     name,
+			Required: required,
+			Current:  current,
+			Message:  fmt.Sprintf("缺少物品：%s x%d（持有%d）", label, required-current, current),
+		})
+	}
+
+	return result, nil
+}
+
+// firstAdvancementError 返回第一项未满足条件对应的错误
+func firstAdvancementError(result *AdvancementCheckResult) error {
+	for _, status := range result.Requirements {
+		if !status.Passed {
+			return status.error()
+		}
+	}
+	return nil
+}
+
+// loadAdvancementSnapshot 读取英雄等级、荣誉、属性、技能与进阶所需物品的持有量
+// 返回的 itemIDs 为物品要求键到物品配置ID的映射（无法解析的键不包含在内）
+func (s *HeroService) loadAdvancementSnapshot(ctx context.Context, hero *game_runtime.Hero, req *game_config.ClassAdvancedRequirement) (*advancementSnapshot, map[string]string, error) {
+	snap := &advancementSnapshot{
+		level:      int(hero.CurrentLevel),
+		attributes: make(map[string]int),
+		skills:     make(map[string]int),
+		skillNames: make(map[string]string),
+		items:      make(map[string]int),
+		itemNames:  make(map[string]string),
+	}
+
+	// 1. 荣誉
+	honor, err := s.currencyService.GetBalance(ctx, hero.ID, interfaces.CurrencyHonor)
+	if err != nil {
+		return nil, nil, err
+	}
+	snap.honor = honor
+
+	// 2. 属性
+	if !req.RequiredAttributes.IsZero() {
+		attrs, err := s.heroAllocatedAttributeRepo.GetByHeroID(ctx, hero.ID)
+		if err != nil {
+			return nil, nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询属性失败")
+		}
+		for _, attr := range attrs {
+			snap.attributes[attr.AttributeCode] = attr.Value
+		}
+	}
+
+	// 3. 技能
+	requiredSkills, err := parseRequiredSkills(req.RequiredSkills)
+	if err != nil {
+		return nil, nil, xerrors.Wrap(err, xerrors.CodeInternalError, "解析技能要求失败")
+	}
+	if len(requiredSkills) > 0 {
+		learned, err := s.heroSkillRepo.GetByHeroID(ctx, hero.ID)
+		if err != nil {
+			return nil, nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询技能失败")
+		}
+		for _, heroSkill := range learned {
+			snap.skills[heroSkill.SkillID] = heroSkill.SkillLevel
+			skill, err := s.skillRepo.GetByID(ctx, heroSkill.SkillID)
+			if err == nil && skill != nil {
+				snap.skills[skill.SkillCode] = heroSkill.SkillLevel
+				snap.skillNames[heroSkill.SkillID] = skill.SkillName
+				snap.skillNames[skill.SkillCode] = skill.SkillName
+			}
+		}
+	}
+
+	// 4. 物品：要求键可以是物品配置ID或物品代码
+	requiredItems, err := parseRequiredItems(req.RequiredItems)
+	if err != nil {
+		return nil, nil, xerrors.Wrap(err, xerrors.CodeInternalError, "解析物品要求失败")
+	}
+	itemIDs := make(map[string]string, len(requiredItems))
+	if len(requiredItems) > 0 {
+		ids := make([]string, 0, len(requiredItems))
+		for key := range requiredItems {
+			item, err := resolveItemConfig(ctx, s.itemRepo, key)
+			if err != nil {
+				// 配置引用了不存在的物品时视为未持有
+				continue
+			}
+			itemIDs[key] = item.ID
+			snap.itemNames[key] = item.ItemName
+			ids = append(ids, item.ID)
+		}
+
+		counts, err := s.playerItemRepo.CountHeroItems(ctx, hero.ID, ids, []string{"backpack", "equipped"})
+		if err != nil {
+			return nil, nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询英雄物品失败")
+		}
+		for key, itemID := range itemIDs {
+			snap.items[key] = counts[itemID]
+		}
+	}
+
+	return snap, itemIDs, nil
+}
+
+// resolveItemConfig 按物品配置ID或物品代码查找物品配置
+func resolveItemConfig(ctx context.Context, repo interfaces.ItemRepository, key string) (*game_config.Item, error) {
+	if _, err := uuid.Parse(key); err == nil {
+		return repo.GetByID(ctx, key)
+	}
+	return repo.GetByCode(ctx, key)
+}
+
+// consumeAdvancementItems 在事务内扣除进阶所需物品：优先扣背包堆叠，不足部分扣已穿戴装备（同时清空槽位）
+// 返回是否扣除了已穿戴装备
+func (s *HeroService) consumeAdvancementItems(ctx context.Context, tx *sql.Tx, hero *game_runtime.Hero, requiredItems map[string]int, itemIDs map[string]string) (bool, error) {
+	consumedEquipped := false
+	for _, key := range sortedRequirementKeys(requiredItems) {
+		itemID, ok := itemIDs[key]
+		if !ok {
+			return false, xerrors.New(xerrors.CodeInsufficientResource, fmt.Sprintf("缺少物品：%s", key))
+		}
+		need := requiredItems[key]
+
+		// 1. 背包堆叠
+		stacks, err := s.playerItemRepo.ListBackpackStacksForUpdate(ctx, tx, hero.ID, itemID)
+		if err != nil {
+			return false, xerrors.Wrap(err, xerrors.CodeInternalError, "查询背包物品失败")
+		}
+		fromBackpack := min(stackTotal(stacks), need)
+		plan, err := planStackConsumption(stacks, fromBackpack)
+		if err != nil {
+			return false, err
+		}
+		if err := applyStackConsumption(ctx, s.playerItemRepo, tx, plan); err != nil {
+			return false, err
+		}
+		for _, step := range plan {
+			if err := s.recordAdvancementConsume(ctx, tx, hero, step.item, "backpack", nil); err != nil {
+				return false, err
+			}
+		}
+		need -= fromBackpack
+		if need == 0 {
+			continue
+		}
+
+		// 2. 已穿戴装备
+		equipped, err := s.playerItemRepo.ListEquippedByItemForUpdate(ctx, tx, hero.ID, itemID)
+		if err != nil {
+			return false, xerrors.Wrap(err, xerrors.CodeInternalError, "查询已穿戴物品失败")
+		}
+		if len(equipped) < need {
+			return false, xerrors.New(xerrors.CodeInsufficientResource, fmt.Sprintf("缺少物品：%s x%d", key, need-len(equipped)))
+		}
+		for _, item := range equipped[:need] {
+			slot, err := s.equipmentSlotRepo.GetSlotByEquippedItem(ctx, tx, item.ID)
+			if err != nil {
+				return false, xerrors.Wrap(err, xerrors.CodeInternalError, "查询装备槽位失败")
+			}
+			if slot != nil {
+				slot.EquippedItemID = null.String{}
+				if err := s.equipmentSlotRepo.UpdateSlot(ctx, tx, slot); err != nil {
+					return false, xerrors.Wrap(err, xerrors.CodeInternalError, "更新槽位失败")
+				}
+			}
+			item.DeletedAt = null.TimeFrom(time.Now())
+			if err := s.playerItemRepo.Update(ctx, tx, item); err != nil {
+				return false, xerrors.Wrap(err, xerrors.CodeInternalError, "扣除已穿戴物品失败")
+			}
+			if err := s.recordAdvancementConsume(ctx, tx, hero, item, "equipped", slot); err != nil {
+				return false, err
+			}
+			consumedEquipped = true
+		}
+	}
+	return consumedEquipped, nil
+}
+
+// recordAdvancementConsume 记录进阶消耗物品的操作日志
+func (s *HeroService) recordAdvancementConsume(ctx context.Context, tx *sql.Tx, hero *game_runtime.Hero, item *game_runtime.PlayerItem, location string, slot *game_runtime.HeroEquipmentSlot) error {
+	if s.itemOpLogRepo == nil {
+		return nil
+	}
+	before := newItemSlotState(location, slot)
+	after := map[string]interface{}{
+		"item_location": location,
+		"reason":        "class_advancement",
+		"deleted":       item.DeletedAt.Valid,
+	}
+	if item.StackCount.Valid {
+		after["stack_count"] = item.StackCount.Int
+	}
+	return writeItemOperationLog(ctx, s.itemOpLogRepo, tx, item.ID, "consume", hero.UserID, before, after)
+}
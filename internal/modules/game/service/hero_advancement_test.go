@@ -0,0 +1,79 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/aarondl/null/v8"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/pkg/xerrors"
+)
+
+func TestParseRequirementCounts(t *testing.T) {
+	counts, err := parseRequiredSkills(null.JSONFrom([]byte(`{"fireball": 3, "heal": 0}`)))
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"fireball": 3, "heal": 1}, counts)
+
+	counts, err = parseRequiredSkills(null.JSONFrom([]byte(`["fireball", "heal"]`)))
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"fireball": 1, "heal": 1}, counts)
+
+	counts, err = parseRequiredItems(null.JSON{})
+	require.NoError(t, err)
+	require.Nil(t, counts)
+
+	_, err = parseRequiredItems(null.JSONFrom([]byte(`"bad"`)))
+	require.Error(t, err)
+}
+
+func TestEvaluateAdvancement(t *testing.T) {
+	req := &game_config.ClassAdvancedRequirement{
+		RequiredLevel:      20,
+		RequiredHonor:      100,
+		RequiredAttributes: null.JSONFrom([]byte(`{"STR": 15}`)),
+		RequiredSkills:     null.JSONFrom([]byte(`{"slash": 3, "whirlwind": 1}`)),
+		RequiredItems:      null.JSONFrom([]byte(`{"proof_of_valor": 2}`)),
+	}
+	snap := &advancementSnapshot{
+		level:      20,
+		honor:      120,
+		attributes: map[string]int{"STR": 15},
+		skills:     map[string]int{"slash": 3, "whirlwind": 1},
+		items:      map[string]int{"proof_of_valor": 2},
+		itemNames:  map[string]string{"proof_of_valor": "勇气证明"},
+	}
+
+	result, err := evaluateAdvancement(req, snap)
+	require.NoError(t, err)
+	require.True(t, result.CanAdvance)
+	require.Empty(t, result.MissingRequirements)
+	require.Len(t, result.Requirements, 6)
+	require.NoError(t, firstAdvancementError(result))
+
+	// 技能等级不足、未学技能、物品缺口
+	snap.skills = map[string]int{"slash": 2}
+	snap.items = map[string]int{"proof_of_valor": 1}
+	result, err = evaluateAdvancement(req, snap)
+	require.NoError(t, err)
+	require.False(t, result.CanAdvance)
+	require.Len(t, result.MissingRequirements, 3)
+
+	byKey := make(map[string]AdvancementRequirementStatus)
+	for _, status := range result.Requirements {
+		byKey[status.Type+":"+status.Key] = status
+	}
+	require.Equal(t, 1, byKey["skill:slash"].Shortfall)
+	require.Equal(t, "未学习技能：whirlwind", byKey["skill:whirlwind"].Message)
+	item := byKey["item:proof_of_valor"]
+	require.False(t, item.Passed)
+	require.Equal(t, 1, item.Shortfall)
+	require.Equal(t, "勇气证明", item.Name)
+	require.True(t, byKey["level:"].Passed)
+
+	err = firstAdvancementError(result)
+	require.Error(t, err)
+	var appErr *xerrors.AppError
+	require.ErrorAs(t, err, &appErr)
+	require.Equal(t, xerrors.CodeInsufficientSkills, appErr.Code)
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/aarondl/null/v8"
 	"github.com/google/uuid"
 
 	"tsu-self/internal/entity/game_runtime"
@@ -17,24 +16,39 @@ import (
 
 // HeroAttributeService 英雄属性服务
 type HeroAttributeService struct {
-	db                           *sql.DB
-	heroRepo                     interfaces.HeroRepository
-	attributeUpgradeCostRepo     interfaces.AttributeUpgradeCostRepository
-	attributeOpRepo              interfaces.HeroAttributeOperationRepository
-	heroAttributeTypeRepo        interfaces.HeroAttributeTypeRepository
-	heroAllocatedAttributeRepo   interfaces.HeroAllocatedAttributeRepository
-	heroService                  *HeroService
+	db                         *sql.DB
+	heroRepo                   interfaces.HeroRepository
+	attributeUpgradeCostRepo   interfaces.AttributeUpgradeCostRepository
+	attributeOpRepo            interfaces.HeroAttributeOperationRepository
+	heroAttributeTypeRepo      interfaces.HeroAttributeTypeRepository
+	heroAllocatedAttributeRepo interfaces.HeroAllocatedAttributeRepository
+	heroService                *HeroService
+	equipmentRepo              interfaces.EquipmentRepository
+	itemRepo                   interfaces.ItemRepository
+	activeBuffRepo             interfaces.HeroActiveBuffRepository
+	gemSocketConfigRepo        interfaces.GemSocketConfigRepository
+	equipmentSetSvc            *EquipmentSetService
+	attributeCache             permissionCacheClient // 可选，为空时不缓存
 }
 
 // NewHeroAttributeService 创建英雄属性服务
 func NewHeroAttributeService(db *sql.DB) *HeroAttributeService {
+	equipmentRepo := impl.NewEquipmentRepository(db)
+	itemRepo := impl.NewItemRepository(db)
+
 	return &HeroAttributeService{
-		db:                       db,
-		heroRepo:                 impl.NewHeroRepository(db),
-		attributeUpgradeCostRepo: impl.NewAttributeUpgradeCostRepository(db),
-		attributeOpRepo:          impl.NewHeroAttributeOperationRepository(db),
-		heroAttributeTypeRepo:    impl.NewHeroAttributeTypeRepository(db),
-		heroService:              NewHeroService(db),
+		db:                         db,
+		heroRepo:                   impl.NewHeroRepository(db),
+		attributeUpgradeCostRepo:   impl.NewAttributeUpgradeCostRepository(db),
+		attributeOpRepo:            impl.NewHeroAttributeOperationRepository(db),
+		heroAttributeTypeRepo:      impl.NewHeroAttributeTypeRepository(db),
+		heroAllocatedAttributeRepo: impl.NewHeroAllocatedAttributeRepository(db),
+		heroService:                NewHeroService(db),
+		equipmentRepo:              equipmentRepo,
+		itemRepo:                   itemRepo,
+		activeBuffRepo:             impl.NewHeroActiveBuffRepository(db),
+		gemSocketConfigRepo:        impl.NewGemSocketConfigRepository(db),
+		equipmentSetSvc:            NewEquipmentSetService(impl.NewEquipmentSetRepository(db), equipmentRepo, itemRepo),
 	}
 }
 
@@ -146,6 +160,9 @@ func (s *HeroAttributeService) AllocateAttribute(ctx context.Context, req *Alloc
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
 	}
 
+	// 12. 失效英雄属性缓存
+	s.InvalidateComputedAttributes(ctx, req.HeroID)
+
 	return nil
 }
 
@@ -219,19 +236,8 @@ func (s *HeroAttributeService) RollbackAttributeAllocation(ctx context.Context,
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
 	}
 
-	return nil
-}
-
-// GetComputedAttributes 获取英雄的计算属性（通过视图）
-func (s *HeroAttributeService) GetComputedAttributes(ctx context.Context, heroID string) ([]*game_runtime.HeroComputedAttribute, error) {
-	// 查询视图
-	attributes, err := game_runtime.HeroComputedAttributes(
-		game_runtime.HeroComputedAttributeWhere.HeroID.EQ(null.StringFrom(heroID)),
-	).All(ctx, s.db)
+	// 10. 失效英雄属性缓存
+	s.InvalidateComputedAttributes(ctx, heroID)
 
-	if err != nil {
-		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询计算属性失败")
-	}
-
-	return attributes, nil
+	return nil
 }
@@ -0,0 +1,447 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/aarondl/null/v8"
+	"github.com/redis/go-redis/v9"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// 属性计算管线：初始值 + 加点 + 职业 + 装备 + 套装 + 强化 + 宝石 + Buff
+// 静态部分（不含 Buff）按英雄缓存，穿脱装备、加点、回退时失效；Buff 有时效，每次实时叠加。
+
+const (
+	computedAttributeCacheTTL = 10 * time.Minute
+	initialAttributeValue     = 1 // 与创建英雄时 hero_allocated_attributes 的初始值一致
+)
+
+// AttributeSourceBonus 单一来源的属性加成（Percent 为小数，0.1 表示 +10%）
+type AttributeSourceBonus struct {
+	Flat    float64 `json:"flat"`
+	Percent float64 `json:"percent"`
+}
+
+func (b *AttributeSourceBonus) add(flat, percent float64) {
+	b.Flat += flat
+	b.Percent += percent
+}
+
+// ComputedAttribute 英雄最终属性及来源明细
+type ComputedAttribute struct {
+	AttributeCode  string               `json:"attribute_code"`
+	AttributeName  string               `json:"attribute_name"`
+	BaseValue      int                  `json:"base_value"`      // 初始值
+	AllocatedValue int                  `json:"allocated_value"` // 玩家加点
+	ClassBonus     int                  `json:"class_bonus"`     // 当前职业加成
+	Equipment      AttributeSourceBonus `json:"equipment"`       // 装备局外效果（未强化部分）
+	Set            AttributeSourceBonus `json:"set"`             // 套装加成
+	Enhancement    AttributeSourceBonus `json:"enhancement"`     // 强化带来的增量
+	Gem            AttributeSourceBonus `json:"gem"`             // 镶嵌宝石及宝石组合
+	Buff           AttributeSourceBonus `json:"buff"`            // 生效中的 Buff
+	FinalValue     int                  `json:"final_value"`
+}
+
+// computeFinal 最终值 = (固定值之和) * (1 + 百分比之和)，四舍五入
+func (a *ComputedAttribute) computeFinal() {
+	flat := float64(a.BaseValue+a.AllocatedValue+a.ClassBonus) +
+		a.Equipment.Flat + a.Set.Flat + a.Enhancement.Flat + a.Gem.Flat + a.Buff.Flat
+	percent := a.Equipment.Percent + a.Set.Percent + a.Enhancement.Percent + a.Gem.Percent + a.Buff.Percent
+	a.FinalValue = int(math.Round(flat * (1 + percent)))
+}
+
+// computedAttributeSnapshot 缓存的静态属性（不含 Buff）
+type computedAttributeSnapshot struct {
+	HeroVersion int64                `json:"hero_version"` // heroes.updated_at，升级/转职等变更后自动失效
+	Attributes  []*ComputedAttribute `json:"attributes"`
+}
+
+// buffModifier buff_params 中的属性修正
+// 支持 {"modifiers":[{...}]} 或单条 {"attribute_code":"STR","bonus_type":"flat","value":5}
+type buffModifier struct {
+	AttributeCode string  `json:"attribute_code"`
+	BonusType     string  `json:"bonus_type"` // flat / percent（percent 按百分数配置，10 表示 +10%）
+	Value         float64 `json:"value"`
+	PerLevel      float64 `json:"per_level"` // 按施法者等级额外加成
+}
+
+type buffParams struct {
+	buffModifier
+	Modifiers []buffModifier `json:"modifiers"`
+}
+
+// GetComputedAttributes 获取英雄最终属性（含各来源明细），战斗引擎与英雄面板统一使用
+func (s *HeroAttributeService) GetComputedAttributes(ctx context.Context, heroID string) ([]*ComputedAttribute, error) {
+	hero, err := s.heroRepo.GetByID(ctx, heroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+	}
+
+	snapshot, err := s.loadAttributeSnapshot(ctx, hero)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := snapshot.Attributes
+	if s.activeBuffRepo != nil {
+		buffs, err := s.activeBuffRepo.ListActiveByHero(ctx, heroID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询英雄Buff失败")
+		}
+		bonuses, err := collectBuffBonuses(buffs)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "解析Buff参数失败")
+		}
+		attrs, err = s.mergeSourceBonuses(ctx, attrs, bonuses, func(a *ComputedAttribute) *AttributeSourceBonus { return &a.Buff })
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, attr := range attrs {
+		attr.computeFinal()
+	}
+	return attrs, nil
+}
+
+// InvalidateComputedAttributes 失效英雄属性缓存
+func (s *HeroAttributeService) InvalidateComputedAttributes(ctx context.Context, heroID string) {
+	if s.attributeCache == nil || heroID == "" {
+		return
+	}
+	if err := s.attributeCache.DeleteKey(ctx, buildComputedAttributeCacheKey(heroID)); err != nil && !errors.Is(err, redis.Nil) {
+		fmt.Printf("Warning: Failed to clear hero attribute cache (hero=%s): %v\n", heroID, err)
+	}
+}
+
+// loadAttributeSnapshot 优先读取缓存，未命中则重新计算并回写
+func (s *HeroAttributeService) loadAttributeSnapshot(ctx context.Context, hero *game_runtime.Hero) (*computedAttributeSnapshot, error) {
+	version := hero.UpdatedAt.UnixNano()
+	cacheKey := buildComputedAttributeCacheKey(hero.ID)
+
+	if s.attributeCache != nil {
+		raw, err := s.attributeCache.GetString(ctx, cacheKey)
+		if err == nil && raw != "" {
+			var cached computedAttributeSnapshot
+			if jsonErr := json.Unmarshal([]byte(raw), &cached); jsonErr == nil && cached.HeroVersion == version {
+				return &cached, nil
+			}
+		} else if err != nil && !errors.Is(err, redis.Nil) {
+			fmt.Printf("Warning: Failed to read hero attribute cache (hero=%s): %v\n", hero.ID, err)
+		}
+	}
+
+	attrs, err := s.computeStaticAttributes(ctx, hero.ID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &computedAttributeSnapshot{HeroVersion: version, Attributes: attrs}
+
+	if s.attributeCache != nil {
+		if data, err := json.Marshal(snapshot); err == nil {
+			if err := s.attributeCache.SetWithTTL(ctx, cacheKey, data, computedAttributeCacheTTL); err != nil {
+				fmt.Printf("Warning: Failed to write hero attribute cache (hero=%s): %v\n", hero.ID, err)
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+// computeStaticAttributes 计算不含 Buff 的属性
+func (s *HeroAttributeService) computeStaticAttributes(ctx context.Context, heroID string) ([]*ComputedAttribute, error) {
+	// 1. 基础值与职业加成（视图）
+	rows, err := game_runtime.HeroComputedAttributes(
+		game_runtime.HeroComputedAttributeWhere.HeroID.EQ(null.StringFrom(heroID)),
+	).All(ctx, s.db)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询计算属性失败")
+	}
+	attrs := buildBaseAttributes(rows)
+
+	if s.equipmentRepo == nil || s.itemRepo == nil {
+		return attrs, nil
+	}
+
+	// 2. 装备、强化与宝石
+	equipBonuses, enhanceBonuses, gemBonuses, err := s.collectEquipmentBonuses(ctx, heroID)
+	if err != nil {
+		return nil, err
+	}
+	if attrs, err = s.mergeSourceBonuses(ctx, attrs, equipBonuses, func(a *ComputedAttribute) *AttributeSourceBonus { return &a.Equipment }); err != nil {
+		return nil, err
+	}
+	if attrs, err = s.mergeSourceBonuses(ctx, attrs, enhanceBonuses, func(a *ComputedAttribute) *AttributeSourceBonus { return &a.Enhancement }); err != nil {
+		return nil, err
+	}
+	if attrs, err = s.mergeSourceBonuses(ctx, attrs, gemBonuses, func(a *ComputedAttribute) *AttributeSourceBonus { return &a.Gem }); err != nil {
+		return nil, err
+	}
+
+	// 3. 套装
+	if s.equipmentSetSvc != nil {
+		setBonuses, err := s.equipmentSetSvc.CalculateSetBonuses(ctx, heroID)
+		if err != nil {
+			return nil, err
+		}
+		if attrs, err = s.mergeSourceBonuses(ctx, attrs, normalizeSetBonuses(setBonuses), func(a *ComputedAttribute) *AttributeSourceBonus { return &a.Set }); err != nil {
+			return nil, err
+		}
+	}
+
+	return attrs, nil
+}
+
+// collectEquipmentBonuses 计算已穿戴装备的加成，拆分为未强化部分、强化增量和宝石加成
+func (s *HeroAttributeService) collectEquipmentBonuses(ctx context.Context, heroID string) (map[string]*AttributeBonus, map[string]*AttributeBonus, map[string]*AttributeBonus, error) {
+	equipBonuses := make(map[string]*AttributeBonus)
+	enhanceBonuses := make(map[string]*AttributeBonus)
+	gemBonuses := make(map[string]*AttributeBonus)
+
+	items, err := s.equipmentRepo.GetEquippedItems(ctx, heroID)
+	if err != nil {
+		return nil, nil, nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询已装备物品失败")
+	}
+	if len(items) == 0 {
+		return equipBonuses, enhanceBonuses, gemBonuses, nil
+	}
+
+	// 装备配置与镶嵌宝石配置一并查询
+	socketed := make(map[string][]socketedGem, len(items))
+	itemIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		itemIDs = append(itemIDs, item.ItemID)
+		gems, err := parseSocketedGems(item.SocketedGems)
+		if err != nil {
+			return nil, nil, nil, xerrors.Wrap(err, xerrors.CodeInternalError, "解析镶嵌信息失败")
+		}
+		for _, gem := range gems {
+			if gem.ItemID != "" {
+				itemIDs = append(itemIDs, gem.ItemID)
+			}
+		}
+		socketed[item.ID] = gems
+	}
+	configs, err := s.itemRepo.GetByIDs(ctx, itemIDs)
+	if err != nil {
+		return nil, nil, nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询装备配置失败")
+	}
+	configMap := make(map[string]*game_config.Item, len(configs))
+	for _, config := range configs {
+		configMap[config.ID] = config
+	}
+
+	var gemEffects []*game_config.GemEffectConfig
+	if s.gemSocketConfigRepo != nil {
+		if gemEffects, err = s.gemSocketConfigRepo.ListActiveGemEffects(ctx); err != nil {
+			return nil, nil, nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询宝石效果配置失败")
+		}
+	}
+
+	effectSvc := NewEquipmentEffectService()
+	for _, item := range items {
+		config, ok := configMap[item.ItemID]
+		if !ok {
+			continue
+		}
+		if err := accumulateItemBonuses(effectSvc, item, config, equipBonuses, enhanceBonuses); err != nil {
+			return nil, nil, nil, xerrors.Wrap(err, xerrors.CodeInternalError, "计算装备属性加成失败")
+		}
+
+		// 损坏装备上的宝石同样不生效
+		if isItemBroken(item) {
+			continue
+		}
+		gems := make([]*game_config.Item, 0, len(socketed[item.ID]))
+		for _, gem := range socketed[item.ID] {
+			if gemConfig, ok := configMap[gem.ItemID]; ok {
+				gems = append(gems, gemConfig)
+			}
+		}
+		if err := accumulateGemBonuses(effectSvc, gems, gemEffects, gemBonuses); err != nil {
+			return nil, nil, nil, xerrors.Wrap(err, xerrors.CodeInternalError, "计算宝石属性加成失败")
+		}
+	}
+	return equipBonuses, enhanceBonuses, gemBonuses, nil
+}
+
+// accumulateItemBonuses 累加单件装备的加成；强化增量 = 强化后 - 未强化
+func accumulateItemBonuses(
+	effectSvc *EquipmentEffectService,
+	item *game_runtime.PlayerItem,
+	config *game_config.Item,
+	equipBonuses, enhanceBonuses map[string]*AttributeBonus,
+) error {
+	maxDurability := itemMaxDurability(item, config)
+	currentDurability := maxDurability
+	if item.CurrentDurability.Valid {
+		currentDurability = item.CurrentDurability.Int
+	}
+	enhancementLevel := 0
+	if item.EnhancementLevel.Valid {
+		enhancementLevel = int(item.EnhancementLevel.Int16)
+	}
+
+	base, err := effectSvc.CalculateAttributeBonuses(config.OutOfCombatEffects, 0, currentDurability, maxDurability)
+	if err != nil {
+		return err
+	}
+	enhanced, err := effectSvc.CalculateAttributeBonuses(config.OutOfCombatEffects, enhancementLevel, currentDurability, maxDurability)
+	if err != nil {
+		return err
+	}
+
+	for code, bonus := range base {
+		addAttributeBonus(equipBonuses, code, bonus.FlatBonus, bonus.PercentBonus)
+	}
+	for code, bonus := range enhanced {
+		flat, percent := bonus.FlatBonus, bonus.PercentBonus
+		if b, ok := base[code]; ok {
+			flat -= b.FlatBonus
+			percent -= b.PercentBonus
+		}
+		if flat != 0 || percent != 0 {
+			addAttributeBonus(enhanceBonuses, code, flat, percent)
+		}
+	}
+	return nil
+}
+
+// normalizeSetBonuses 套装百分比按百分数配置，转换为小数以与装备效果保持一致
+func normalizeSetBonuses(bonuses map[string]*AttributeBonus) map[string]*AttributeBonus {
+	normalized := make(map[string]*AttributeBonus, len(bonuses))
+	for code, bonus := range bonuses {
+		addAttributeBonus(normalized, code, bonus.FlatBonus, bonus.PercentBonus/100.0)
+	}
+	return normalized
+}
+
+// collectBuffBonuses 汇总 Buff 的属性修正
+func collectBuffBonuses(buffs []*interfaces.HeroActiveBuff) (map[string]*AttributeBonus, error) {
+	bonuses := make(map[string]*AttributeBonus)
+	for _, buff := range buffs {
+		modifiers, err := parseBuffModifiers(buff.BuffParams)
+		if err != nil {
+			return nil, fmt.Errorf("buff %s: %w", buff.BuffCode, err)
+		}
+		for _, m := range modifiers {
+			value := m.Value + m.PerLevel*float64(buff.CasterLevel)
+			switch m.BonusType {
+			case "", "flat", "bonus":
+				addAttributeBonus(bonuses, m.AttributeCode, value, 0)
+			case "percent":
+				addAttributeBonus(bonuses, m.AttributeCode, 0, value/100.0)
+			default:
+				return nil, fmt.Errorf("buff %s: 未知加成类型 %s", buff.BuffCode, m.BonusType)
+			}
+		}
+	}
+	return bonuses, nil
+}
+
+// parseBuffModifiers 解析 buff_params，无属性修正的 Buff 返回空
+func parseBuffModifiers(raw json.RawMessage) ([]buffModifier, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var params buffParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, err
+	}
+	modifiers := make([]buffModifier, 0, len(params.Modifiers)+1)
+	if params.AttributeCode != "" {
+		modifiers = append(modifiers, params.buffModifier)
+	}
+	for _, m := range params.Modifiers {
+		if m.AttributeCode != "" {
+			modifiers = append(modifiers, m)
+		}
+	}
+	return modifiers, nil
+}
+
+// buildBaseAttributes 将视图行拆分为初始值与加点值
+func buildBaseAttributes(rows []*game_runtime.HeroComputedAttribute) []*ComputedAttribute {
+	attrs := make([]*ComputedAttribute, 0, len(rows))
+	for _, row := range rows {
+		allocated := 0
+		if row.BaseValue.Valid {
+			allocated = row.BaseValue.Int - initialAttributeValue
+		}
+		if allocated < 0 {
+			allocated = 0
+		}
+		attrs = append(attrs, &ComputedAttribute{
+			AttributeCode:  row.AttributeCode.String,
+			AttributeName:  row.AttributeName.String,
+			BaseValue:      initialAttributeValue,
+			AllocatedValue: allocated,
+			ClassBonus:     row.ClassBonus.Int,
+		})
+	}
+	return attrs
+}
+
+// mergeSourceBonuses 将加成并入对应来源；视图外的属性（如 MAX_HP）按需补充
+func (s *HeroAttributeService) mergeSourceBonuses(
+	ctx context.Context,
+	attrs []*ComputedAttribute,
+	bonuses map[string]*AttributeBonus,
+	source func(*ComputedAttribute) *AttributeSourceBonus,
+) ([]*ComputedAttribute, error) {
+	if len(bonuses) == 0 {
+		return attrs, nil
+	}
+
+	index := make(map[string]*ComputedAttribute, len(attrs))
+	for _, attr := range attrs {
+		index[attr.AttributeCode] = attr
+	}
+
+	codes := make([]string, 0, len(bonuses))
+	for code := range bonuses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		attr, ok := index[code]
+		if !ok {
+			name := code
+			if s.heroAttributeTypeRepo != nil {
+				// 未配置的属性代码仍计入加成，名称回退为代码
+				if attrType, err := s.heroAttributeTypeRepo.GetByCode(ctx, code); err == nil && attrType != nil {
+					name = attrType.AttributeName
+				}
+			}
+			attr = &ComputedAttribute{AttributeCode: code, AttributeName: name}
+			attrs = append(attrs, attr)
+			index[code] = attr
+		}
+		source(attr).add(bonuses[code].FlatBonus, bonuses[code].PercentBonus)
+	}
+	return attrs, nil
+}
+
+func addAttributeBonus(bonuses map[string]*AttributeBonus, code string, flat, percent float64) {
+	bonus, ok := bonuses[code]
+	if !ok {
+		bonus = &AttributeBonus{AttributeCode: code}
+		bonuses[code] = bonus
+	}
+	bonus.FlatBonus += flat
+	bonus.PercentBonus += percent
+}
+
+func buildComputedAttributeCacheKey(heroID string) string {
+	return fmt.Sprintf("hero:attrs:%s", heroID)
+}
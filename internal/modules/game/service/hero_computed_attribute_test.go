@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aarondl/null/v8"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/repository/interfaces"
+)
+
+func TestBuildBaseAttributes(t *testing.T) {
+	attrs := buildBaseAttributes([]*game_runtime.HeroComputedAttribute{
+		{AttributeCode: null.StringFrom("STR"), AttributeName: null.StringFrom("力量"), BaseValue: null.IntFrom(6), ClassBonus: null.IntFrom(2)},
+		{AttributeCode: null.StringFrom("DEX"), AttributeName: null.StringFrom("敏捷")},
+	})
+	require.Len(t, attrs, 2)
+	require.Equal(t, 1, attrs[0].BaseValue)
+	require.Equal(t, 5, attrs[0].AllocatedValue)
+	require.Equal(t, 2, attrs[0].ClassBonus)
+	require.Equal(t, 0, attrs[1].AllocatedValue)
+}
+
+func TestAccumulateItemBonuses_SplitsEnhancement(t *testing.T) {
+	config := &game_config.Item{
+		ID:            "sword",
+		MaxDurability: null.IntFrom(100),
+		OutOfCombatEffects: null.JSONFrom([]byte(`[
+			{"Data_type":"Status","Data_ID":"STR","Bouns_type":"bonus","Bouns_Number":"10"},
+			{"Data_type":"Status","Data_ID":"ATK","Bouns_type":"percent","Bouns_Number":"10"}
+		]`)),
+	}
+	item := &game_runtime.PlayerItem{
+		ItemID:            "sword",
+		EnhancementLevel:  null.Int16From(4),
+		CurrentDurability: null.IntFrom(100),
+	}
+
+	equip := map[string]*AttributeBonus{}
+	enhance := map[string]*AttributeBonus{}
+	require.NoError(t, accumulateItemBonuses(NewEquipmentEffectService(), item, config, equip, enhance))
+
+	require.InDelta(t, 10, equip["STR"].FlatBonus, 1e-9)
+	require.InDelta(t, 0.1, equip["ATK"].PercentBonus, 1e-9)
+	// +4 强化 = +20%
+	require.InDelta(t, 2, enhance["STR"].FlatBonus, 1e-9)
+	require.InDelta(t, 0.02, enhance["ATK"].PercentBonus, 1e-9)
+}
+
+func TestCollectBuffBonuses(t *testing.T) {
+	buffs := []*interfaces.HeroActiveBuff{
+		{BuffCode: "BLESS", CasterLevel: 5, BuffParams: json.RawMessage(`{"attribute_code":"STR","value":2,"per_level":1}`)},
+		{BuffCode: "HASTE", BuffParams: json.RawMessage(`{"modifiers":[{"attribute_code":"DEX","bonus_type":"percent","value":20}]}`)},
+		{BuffCode: "MARK", BuffParams: json.RawMessage(`{"value":5}`)},
+	}
+	bonuses, err := collectBuffBonuses(buffs)
+	require.NoError(t, err)
+	require.Len(t, bonuses, 2)
+	require.InDelta(t, 7, bonuses["STR"].FlatBonus, 1e-9)
+	require.InDelta(t, 0.2, bonuses["DEX"].PercentBonus, 1e-9)
+
+	_, err = collectBuffBonuses([]*interfaces.HeroActiveBuff{
+		{BuffCode: "BAD", BuffParams: json.RawMessage(`{"attribute_code":"STR","bonus_type":"double","value":1}`)},
+	})
+	require.Error(t, err)
+}
+
+func TestMergeSourceBonusesAndFinalValue(t *testing.T) {
+	s := &HeroAttributeService{}
+	attrs := []*ComputedAttribute{{AttributeCode: "STR", BaseValue: 1, AllocatedValue: 9, ClassBonus: 2}}
+
+	attrs, err := s.mergeSourceBonuses(context.Background(), attrs, map[string]*AttributeBonus{
+		"STR":    {FlatBonus: 8},
+		"MAX_HP": {FlatBonus: 50},
+	}, func(a *ComputedAttribute) *AttributeSourceBonus { return &a.Equipment })
+	require.NoError(t, err)
+	attrs, err = s.mergeSourceBonuses(context.Background(), attrs,
+		normalizeSetBonuses(map[string]*AttributeBonus{"STR": {PercentBonus: 10}}),
+		func(a *ComputedAttribute) *AttributeSourceBonus { return &a.Set })
+	require.NoError(t, err)
+
+	require.Len(t, attrs, 2)
+	require.Equal(t, "MAX_HP", attrs[1].AttributeCode)
+	require.Equal(t, "MAX_HP", attrs[1].AttributeName)
+
+	for _, attr := range attrs {
+		attr.computeFinal()
+	}
+	// (1 + 9 + 2 + 8) * 1.1 = 22
+	require.Equal(t, 22, attrs[0].FinalValue)
+	require.Equal(t, 50, attrs[1].FinalValue)
+}
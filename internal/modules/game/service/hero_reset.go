@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/pkg/xerrors"
+)
+
+// 初始属性值，与 initializeAllocatedAttributesInTable 保持一致
+const initialAllocatedAttributeValue = 1
+
+// heroResetResult 属性/技能重置结果
+type heroResetResult struct {
+	RefundedXP      int64          // 返还的经验
+	AttributesReset int            // 重置的属性数
+	SkillsRemoved   int            // 遗忘的主动学习技能数
+	SkillsReset     int            // 降回 1 级的初始技能数
+	AttributeValues map[string]int // 重置前的属性值（属性代码 -> 值），仅含被重置的属性
+	SkillLevels     map[string]int // 重置前的技能等级（技能ID -> 等级），仅含被遗忘或降级的技能
+}
+
+// resetAllocatedAttributes 在事务内将所有已分配属性恢复为初始值并返还经验
+// 重置后原有加点操作不可再回退，避免重复返还
+func (s *HeroService) resetAllocatedAttributes(ctx context.Context, tx *sql.Tx, hero *game_runtime.Hero) (*heroResetResult, error) {
+	attrs, err := s.heroAllocatedAttributeRepo.GetByHeroIDForUpdate(ctx, tx, hero.ID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询属性分配失败")
+	}
+
+	result := &heroResetResult{AttributeValues: make(map[string]int)}
+	now := time.Now()
+	for _, attr := range attrs {
+		if attr.Value == initialAllocatedAttributeValue && attr.SpentXP == 0 {
+			continue
+		}
+		spentXP := attr.SpentXP
+		if spentXP == 0 && attr.Value > initialAllocatedAttributeValue && s.attributeUpgradeCostRepo != nil {
+			// 未记录花费的加点（如后台直接调整）按当前配置折算
+			if spentXP, err = s.attributeUpgradeCostRepo.CalculateCost(ctx, initialAllocatedAttributeValue, attr.Value); err != nil {
+				return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "计算属性返还经验失败")
+			}
+		}
+		result.RefundedXP += int64(spentXP)
+		result.AttributesReset++
+		result.AttributeValues[attr.AttributeCode] = attr.Value
+
+		attr.Value = initialAllocatedAttributeValue
+		attr.SpentXP = 0
+		attr.UpdatedAt = now
+		if err := s.heroAllocatedAttributeRepo.Update(ctx, tx, attr); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "重置属性分配失败")
+		}
+	}
+	if err := s.attributeOpRepo.ExpireRollbackable(ctx, tx, hero.ID); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "清理属性操作历史失败")
+	}
+
+	if err := s.refundExperience(ctx, tx, hero, result.RefundedXP); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// resetLearnedSkills 在事务内遗忘主动学习的技能、初始技能降回 1 级，按技能操作历史返还经验
+// 装备、任务等其他来源的技能不是用经验换来的，保持不变
+func (s *HeroService) resetLearnedSkills(ctx context.Context, tx *sql.Tx, hero *game_runtime.Hero) (*heroResetResult, error) {
+	skills, err := s.heroSkillRepo.GetByHeroID(ctx, hero.ID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询英雄技能失败")
+	}
+
+	result := &heroResetResult{SkillLevels: make(map[string]int)}
+	for _, skill := range skills {
+		initial := isInitialHeroSkill(skill)
+		if !initial && !isManualHeroSkill(skill) {
+			continue
+		}
+		if initial && skill.SkillLevel <= 1 {
+			continue
+		}
+
+		operations, err := s.skillOpRepo.GetByHeroSkillID(ctx, skill.ID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询技能操作历史失败")
+		}
+		result.RefundedXP += skillSpentXP(operations)
+		result.SkillLevels[skill.SkillID] = int(skill.SkillLevel)
+
+		if !initial {
+			// 操作历史随技能记录级联删除
+			if err := s.heroSkillRepo.Delete(ctx, tx, skill.ID); err != nil {
+				return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "删除技能失败")
+			}
+			result.SkillsRemoved++
+			continue
+		}
+
+		skill.SkillLevel = 1
+		skill.UpdatedAt = time.Now()
+		if err := s.heroSkillRepo.Update(ctx, tx, skill); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "重置技能等级失败")
+		}
+		if err := s.skillOpRepo.ExpireRollbackable(ctx, tx, skill.ID); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "清理技能操作历史失败")
+		}
+		result.SkillsReset++
+	}
+
+	if err := s.refundExperience(ctx, tx, hero, result.RefundedXP); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// refundExperience 返还已花费经验（experience_total 不变）
+func (s *HeroService) refundExperience(ctx context.Context, tx *sql.Tx, hero *game_runtime.Hero, amount int64) error {
+	if amount <= 0 {
+		return nil
+	}
+	hero.ExperienceAvailable += amount
+	hero.ExperienceSpent -= amount
+	if hero.ExperienceSpent < 0 {
+		hero.ExperienceSpent = 0
+	}
+	hero.UpdatedAt = time.Now()
+	if err := s.heroRepo.Update(ctx, tx, hero); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "更新英雄失败")
+	}
+	return nil
+}
+
+// isInitialHeroSkill 职业解锁的初始技能在重置时保留
+func isInitialHeroSkill(skill *game_runtime.HeroSkill) bool {
+	return skill.LearnedMethod.Valid && skill.LearnedMethod.String == "class_unlock"
+}
+
+// isManualHeroSkill 主动学习（花费经验）的技能在重置时遗忘
+func isManualHeroSkill(skill *game_runtime.HeroSkill) bool {
+	return skill.LearnedMethod.Valid && skill.LearnedMethod.String == "manual"
+}
+
+// skillSpentXP 汇总技能未回退操作花费的经验
+func skillSpentXP(operations []*game_runtime.HeroSkillOperation) int64 {
+	var total int64
+	for _, op := range operations {
+		if op.RolledBackAt.Valid {
+			continue
+		}
+		total += int64(op.XPSpent)
+	}
+	return total
+}
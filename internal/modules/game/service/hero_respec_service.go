@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/impl"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// 洗点支付方式
+const (
+	RespecPaymentGold = "gold"
+	RespecPaymentItem = "item"
+)
+
+// HeroRespecService 英雄洗点服务：一次性重置全部属性加点与主动学习的技能，返还经验
+type HeroRespecService struct {
+	db                   *sql.DB
+	heroRepo             interfaces.HeroRepository
+	itemRepo             interfaces.ItemRepository
+	playerItemRepo       interfaces.PlayerItemRepository
+	heroCurrencyRepo     interfaces.HeroCurrencyRepository
+	respecRepo           interfaces.HeroRespecRepository
+	itemOpLogRepo        interfaces.ItemOperationLogRepository
+	heroService          *HeroService
+	attributeInvalidator heroAttributeInvalidator // 可选，洗点后失效英雄属性缓存
+}
+
+// NewHeroRespecService 创建英雄洗点服务
+func NewHeroRespecService(db *sql.DB) *HeroRespecService {
+	return &HeroRespecService{
+		db:               db,
+		heroRepo:         impl.NewHeroRepository(db),
+		itemRepo:         impl.NewItemRepository(db),
+		playerItemRepo:   impl.NewPlayerItemRepository(db),
+		heroCurrencyRepo: impl.NewHeroCurrencyRepository(db),
+		respecRepo:       impl.NewHeroRespecRepository(db),
+		itemOpLogRepo:    impl.NewItemOperationLogRepository(db),
+		heroService:      NewHeroService(db),
+	}
+}
+
+// RespecQuote 洗点报价：当前等级档位的消耗及英雄是否负担得起
+type RespecQuote struct {
+	HeroLevel   int
+	CostGold    int64
+	GoldBalance int64
+	CanPayGold  bool
+	ItemID      string // 为空表示不支持道具支付
+	ItemCode    string
+	ItemName    string
+	ItemCount   int
+	ItemOwned   int
+	CanPayItem  bool
+}
+
+// RespecRequest 洗点请求
+type RespecRequest struct {
+	HeroID        string
+	UserID        string // 非空时校验英雄归属
+	PaymentMethod string // gold/item
+}
+
+// RespecResult 洗点结果
+type RespecResult struct {
+	OperationID     string
+	PaymentMethod   string
+	GoldSpent       int64
+	ItemID          string
+	ItemsSpent      int
+	RefundedXP      int64
+	AttributesReset int
+	SkillsRemoved   int
+	SkillsReset     int
+}
+
+// respecSnapshot 洗点前状态，写入审计记录
+type respecSnapshot struct {
+	Attributes map[string]int `json:"attributes"`
+	Skills     map[string]int `json:"skills"`
+}
+
+// GetRespecQuote 查询洗点消耗
+func (s *HeroRespecService) GetRespecQuote(ctx context.Context, heroID, userID string) (*RespecQuote, error) {
+	hero, err := s.heroRepo.GetByID(ctx, heroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+	}
+	if userID != "" && hero.UserID != userID {
+		return nil, xerrors.New(xerrors.CodePermissionDenied, "该英雄不属于您")
+	}
+	cost, err := s.loadRespecCost(ctx, hero)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &RespecQuote{HeroLevel: int(hero.CurrentLevel), CostGold: cost.CostGold}
+	quote.GoldBalance, err = s.heroCurrencyRepo.GetBalance(ctx, hero.ID, interfaces.CurrencyGold)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询金币余额失败")
+	}
+	quote.CanPayGold = quote.GoldBalance >= cost.CostGold
+
+	if cost.ItemID != nil {
+		item, err := s.itemRepo.GetByID(ctx, *cost.ItemID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "洗点道具配置不存在")
+		}
+		owned, err := s.playerItemRepo.CountHeroItems(ctx, hero.ID, []string{item.ID}, []string{"backpack"})
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询背包道具失败")
+		}
+		quote.ItemID = item.ID
+		quote.ItemCode = item.ItemCode
+		quote.ItemName = item.ItemName
+		quote.ItemCount = cost.ItemCount
+		quote.ItemOwned = owned[item.ID]
+		quote.CanPayItem = quote.ItemOwned >= cost.ItemCount
+	}
+	return quote, nil
+}
+
+// Respec 洗点：在同一事务内支付消耗、重置全部属性加点与主动学习的技能、返还经验并写入一条审计记录
+// 重置后原有加点/技能操作不可再回退
+func (s *HeroRespecService) Respec(ctx context.Context, req *RespecRequest) (*RespecResult, error) {
+	// 1. 验证参数
+	if req.HeroID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "英雄ID不能为空")
+	}
+	if req.PaymentMethod != RespecPaymentGold && req.PaymentMethod != RespecPaymentItem {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "支付方式只能为 gold 或 item")
+	}
+
+	// 2. 开启事务
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			// 仅当 Rollback 失败且不是已提交的事务时，才表示有问题
+		}
+	}()
+
+	// 3. 锁定英雄并校验归属
+	hero, err := s.heroRepo.GetByIDForUpdate(ctx, tx, req.HeroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+	}
+	if req.UserID != "" && hero.UserID != req.UserID {
+		return nil, xerrors.New(xerrors.CodePermissionDenied, "该英雄不属于您")
+	}
+	cost, err := s.loadRespecCost(ctx, hero)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. 支付消耗
+	result := &RespecResult{OperationID: uuid.New().String(), PaymentMethod: req.PaymentMethod}
+	operation := &interfaces.HeroRespecOperation{
+		ID:            result.OperationID,
+		HeroID:        hero.ID,
+		PaymentMethod: req.PaymentMethod,
+	}
+	switch req.PaymentMethod {
+	case RespecPaymentGold:
+		if cost.CostGold > 0 {
+			if _, err := applyCurrencyTx(ctx, s.heroCurrencyRepo, tx, CurrencyChange{
+				HeroID:     hero.ID,
+				Currency:   interfaces.CurrencyGold,
+				Amount:     -cost.CostGold,
+				SourceType: CurrencySourceRespec,
+				SourceID:   result.OperationID,
+			}); err != nil {
+				return nil, err
+			}
+		}
+		result.GoldSpent = cost.CostGold
+		operation.GoldSpent = cost.CostGold
+	case RespecPaymentItem:
+		if cost.ItemID == nil {
+			return nil, xerrors.New(xerrors.CodeOperationNotAllowed, "当前等级不支持使用道具洗点")
+		}
+		stacks, err := s.consumeRespecItem(ctx, tx, hero, *cost.ItemID, cost.ItemCount)
+		if err != nil {
+			return nil, err
+		}
+		if operation.ItemsSpent, err = json.Marshal(stacks); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "序列化道具消耗失败")
+		}
+		result.ItemID = *cost.ItemID
+		result.ItemsSpent = cost.ItemCount
+		operation.ItemID = cost.ItemID
+	}
+
+	// 5. 重置属性与技能并返还经验
+	attrReset, err := s.heroService.resetAllocatedAttributes(ctx, tx, hero)
+	if err != nil {
+		return nil, err
+	}
+	skillReset, err := s.heroService.resetLearnedSkills(ctx, tx, hero)
+	if err != nil {
+		return nil, err
+	}
+	result.RefundedXP = attrReset.RefundedXP + skillReset.RefundedXP
+	result.AttributesReset = attrReset.AttributesReset
+	result.SkillsRemoved = skillReset.SkillsRemoved
+	result.SkillsReset = skillReset.SkillsReset
+	if result.AttributesReset+result.SkillsRemoved+result.SkillsReset == 0 {
+		return nil, xerrors.New(xerrors.CodeOperationNotAllowed, "没有可重置的属性或技能")
+	}
+
+	// 6. 写入审计记录
+	snapshot, err := json.Marshal(respecSnapshot{Attributes: attrReset.AttributeValues, Skills: skillReset.SkillLevels})
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "序列化洗点快照失败")
+	}
+	operation.Snapshot = snapshot
+	operation.RefundedXP = result.RefundedXP
+	operation.AttributesReset = result.AttributesReset
+	operation.SkillsRemoved = result.SkillsRemoved
+	operation.SkillsReset = result.SkillsReset
+	if err := s.respecRepo.CreateOperation(ctx, tx, operation); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "写入洗点记录失败")
+	}
+
+	// 7. 提交事务
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	if s.attributeInvalidator != nil {
+		s.attributeInvalidator.InvalidateComputedAttributes(ctx, hero.ID)
+	}
+	return result, nil
+}
+
+// ListRespecOperations 分页查询英雄的洗点记录
+func (s *HeroRespecService) ListRespecOperations(ctx context.Context, heroID, userID string, limit, offset int) ([]*interfaces.HeroRespecOperation, int64, error) {
+	hero, err := s.heroRepo.GetByID(ctx, heroID)
+	if err != nil {
+		return nil, 0, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+	}
+	if userID != "" && hero.UserID != userID {
+		return nil, 0, xerrors.New(xerrors.CodePermissionDenied, "该英雄不属于您")
+	}
+	list, total, err := s.respecRepo.ListOperations(ctx, heroID, limit, offset)
+	if err != nil {
+		return nil, 0, xerrors.Wrap(err, xerrors.CodeInternalError, "查询洗点记录失败")
+	}
+	return list, total, nil
+}
+
+// loadRespecCost 获取英雄等级适用的洗点消耗，未配置时不允许洗点
+func (s *HeroRespecService) loadRespecCost(ctx context.Context, hero *game_runtime.Hero) (*interfaces.HeroRespecCost, error) {
+	cost, err := s.respecRepo.GetCostForLevel(ctx, int(hero.CurrentLevel))
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询洗点消耗配置失败")
+	}
+	if cost == nil {
+		return nil, xerrors.New(xerrors.CodeOperationNotAllowed, "未配置洗点消耗，暂不支持洗点")
+	}
+	return cost, nil
+}
+
+// consumeRespecItem 从背包扣除洗点道具（数量少的堆叠优先），返回实际扣除的堆叠
+func (s *HeroRespecService) consumeRespecItem(ctx context.Context, tx *sql.Tx, hero *game_runtime.Hero, itemID string, count int) ([]skillMaterialStack, error) {
+	stacks, err := s.playerItemRepo.ListBackpackStacksForUpdate(ctx, tx, hero.ID, itemID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询背包道具失败")
+	}
+	if owned := stackTotal(stacks); owned < count {
+		return nil, xerrors.New(xerrors.CodeInsufficientResource,
+			fmt.Sprintf("洗点道具不足: 需要 %d，当前 %d", count, owned))
+	}
+	plan, err := planStackConsumption(stacks, count)
+	if err != nil {
+		return nil, err
+	}
+
+	spent := make([]skillMaterialStack, 0, len(plan))
+	for _, step := range plan {
+		before := stackTotal([]*game_runtime.PlayerItem{step.item})
+		spent = append(spent, skillMaterialStack{InstanceID: step.item.ID, Taken: before - step.remaining})
+	}
+	if err := applyStackConsumption(ctx, s.playerItemRepo, tx, plan); err != nil {
+		return nil, err
+	}
+	for i, step := range plan {
+		before := itemUseState{StackCount: spent[i].Taken + step.remaining}
+		after := map[string]interface{}{"stack_count": step.remaining, "reason": "respec"}
+		if err := writeItemOperationLog(ctx, s.itemOpLogRepo, tx, step.item.ID, "consume", hero.UserID, before, after); err != nil {
+			return nil, err
+		}
+	}
+	return spent, nil
+}
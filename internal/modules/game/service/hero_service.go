@@ -3,8 +3,6 @@ package service
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
-	"fmt"
 	"time"
 
 	"github.com/aarondl/null/v8"
@@ -30,6 +28,15 @@ type HeroService struct {
 	heroLevelRequirementRepo   interfaces.HeroLevelRequirementRepository
 	heroAllocatedAttributeRepo interfaces.HeroAllocatedAttributeRepository
 	classAdvancedReqRepo       interfaces.ClassAdvancedRequirementRepository
+	attributeOpRepo            interfaces.HeroAttributeOperationRepository
+	skillOpRepo                interfaces.HeroSkillOperationRepository
+	attributeUpgradeCostRepo   interfaces.AttributeUpgradeCostRepository
+	itemRepo                   interfaces.ItemRepository
+	playerItemRepo             interfaces.PlayerItemRepository
+	equipmentSlotRepo          interfaces.EquipmentSlotRepository
+	itemOpLogRepo              interfaces.ItemOperationLogRepository
+	currencyService            *CurrencyService
+	attributeInvalidator       heroAttributeInvalidator // 可选，进阶消耗已穿戴装备后失效英雄属性缓存
 }
 
 // NewHeroService 创建英雄服务
@@ -46,6 +53,14 @@ func NewHeroService(db *sql.DB) *HeroService {
 		heroLevelRequirementRepo:   impl.NewHeroLevelRequirementRepository(db),
 		heroAllocatedAttributeRepo: impl.NewHeroAllocatedAttributeRepository(db),
 		classAdvancedReqRepo:       impl.NewClassAdvancedRequirementRepository(db),
+		attributeOpRepo:            impl.NewHeroAttributeOperationRepository(db),
+		skillOpRepo:                impl.NewHeroSkillOperationRepository(db),
+		attributeUpgradeCostRepo:   impl.NewAttributeUpgradeCostRepository(db),
+		itemRepo:                   impl.NewItemRepository(db),
+		playerItemRepo:             impl.NewPlayerItemRepository(db),
+		equipmentSlotRepo:          impl.NewEquipmentSlotRepository(db),
+		itemOpLogRepo:              impl.NewItemOperationLogRepository(db),
+		currencyService:            NewCurrencyService(db),
 	}
 }
 
@@ -308,68 +323,41 @@ func (s *HeroService) AdvanceClass(ctx context.Context, heroID, targetClassID st
 		return xerrors.New(xerrors.CodeOperationNotAllowed, "不存在该进阶路径")
 	}
 
-	// 5. 检查等级要求
-	if hero.CurrentLevel < int16(advReq.RequiredLevel) {
-		return xerrors.New(xerrors.CodeInsufficientLevel, fmt.Sprintf("需要等级 %d", advReq.RequiredLevel))
+	// 5. 检查等级、荣誉、属性、技能与物品要求
+	snapshot, itemIDs, err := s.loadAdvancementSnapshot(ctx, hero, advReq)
+	if err != nil {
+		return err
 	}
-
-	// 6. 检查属性要求
-	if !advReq.RequiredAttributes.IsZero() {
-		var reqAttrs map[string]int
-		if err := json.Unmarshal(advReq.RequiredAttributes.JSON, &reqAttrs); err != nil {
-			return xerrors.Wrap(err, xerrors.CodeInternalError, "解析属性要求失败")
-		}
-
-		// 获取英雄当前属性
-		heroAttrs, err := s.heroAllocatedAttributeRepo.GetByHeroID(ctx, heroID)
-		if err != nil {
-			return xerrors.Wrap(err, xerrors.CodeInternalError, "获取英雄属性失败")
-		}
-
-		// 构建属性值映射
-		attrMap := make(map[string]int)
-		for _, attr := range heroAttrs {
-			attrMap[attr.AttributeCode] = int(attr.Value)
-		}
-
-		// 验证每个属性要求
-		for attrCode, requiredValue := range reqAttrs {
-			currentValue, exists := attrMap[attrCode]
-			if !exists || currentValue < requiredValue {
-				return xerrors.New(xerrors.CodeInsufficientAttributes, fmt.Sprintf("属性 %s 不足，需要 %d", attrCode, requiredValue))
-			}
-		}
+	check, err := evaluateAdvancement(advReq, snapshot)
+	if err != nil {
+		return err
+	}
+	if !check.CanAdvance {
+		return firstAdvancementError(check)
 	}
 
-	// 7. 检查技能要求
-	if !advReq.RequiredSkills.IsZero() {
-		var reqSkills []string
-		if err := json.Unmarshal(advReq.RequiredSkills.JSON, &reqSkills); err != nil {
-			return xerrors.Wrap(err, xerrors.CodeInternalError, "解析技能要求失败")
-		}
-
-		// 获取英雄已学习的技能
-		learnedSkills, err := s.heroSkillRepo.GetByHeroID(ctx, heroID)
-		if err != nil {
-			return xerrors.Wrap(err, xerrors.CodeInternalError, "获取已学习技能失败")
-		}
-
-		// 构建已学习技能映射
-		learnedSkillMap := make(map[string]bool)
-		for _, heroSkill := range learnedSkills {
-			// 通过 skill_id 查询 game_config.skills 获取 skill_code
-			skill, err := s.skillRepo.GetByID(ctx, heroSkill.SkillID)
-			if err == nil && skill != nil {
-				learnedSkillMap[skill.SkillCode] = true
-			}
+	// 6. 扣除荣誉（与职业变更在同一事务内，余额不足时整体回滚）
+	if advReq.RequiredHonor > 0 {
+		if _, err := s.currencyService.ApplyTx(ctx, tx, CurrencyChange{
+			HeroID:     heroID,
+			Currency:   interfaces.CurrencyHonor,
+			Amount:     -int64(advReq.RequiredHonor),
+			SourceType: CurrencySourceAdvancement,
+			SourceID:   targetClassID,
+			Metadata:   map[string]interface{}{"from_class_id": currentClassHistory.ClassID},
+		}); err != nil {
+			return err
 		}
+	}
 
-		// 验证每个技能要求
-		for _, requiredSkillID := range reqSkills {
-			if !learnedSkillMap[requiredSkillID] {
-				return xerrors.New(xerrors.CodeInsufficientSkills, fmt.Sprintf("缺少必需技能: %s", requiredSkillID))
-			}
-		}
+	// 7. 扣除所需物品（背包优先，其次已穿戴）
+	requiredItems, err := parseRequiredItems(advReq.RequiredItems)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "解析物品要求失败")
+	}
+	consumedEquipped, err := s.consumeAdvancementItems(ctx, tx, hero, requiredItems, itemIDs)
+	if err != nil {
+		return err
 	}
 
 	// 8. 更新职业历史（旧职业 is_current=false，新职业 acquisition_type='advancement'）
@@ -400,6 +388,11 @@ func (s *HeroService) AdvanceClass(ctx context.Context, heroID, targetClassID st
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
 	}
 
+	// 12. 消耗了已穿戴装备时失效英雄属性缓存
+	if consumedEquipped && s.attributeInvalidator != nil {
+		s.attributeInvalidator.InvalidateComputedAttributes(ctx, heroID)
+	}
+
 	return nil
 }
 
@@ -487,13 +480,33 @@ func (s *HeroService) AddExperience(ctx context.Context, heroID string, amount i
 		}
 	}()
 
-	// 2. 获取英雄信息（加锁）
+	// 2. 增加经验并检查升级
+	if _, err := s.AddExperienceTx(ctx, tx, heroID, amount); err != nil {
+		return nil, err
+	}
+
+	// 3. 提交事务
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+
+	// 4. 返回更新后的英雄信息
+	return s.heroRepo.GetByID(ctx, heroID)
+}
+
+// AddExperienceTx 在调用方事务内增加经验并检查是否可以升级
+func (s *HeroService) AddExperienceTx(ctx context.Context, tx *sql.Tx, heroID string, amount int64) (*game_runtime.Hero, error) {
+	if amount <= 0 {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "经验值必须大于0")
+	}
+
+	// 1. 获取英雄信息（加锁）
 	hero, err := s.heroRepo.GetByIDForUpdate(ctx, tx, heroID)
 	if err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
 	}
 
-	// 3. 增加经验
+	// 2. 增加经验
 	hero.ExperienceTotal += amount
 	hero.ExperienceAvailable += amount
 	hero.UpdatedAt = time.Now()
@@ -502,19 +515,15 @@ func (s *HeroService) AddExperience(ctx context.Context, heroID string, amount i
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新英雄失败")
 	}
 
-	// 4. 检查是否可以升级
-	_, _, err = s.AutoLevelUp(ctx, tx, heroID)
+	// 3. 检查是否可以升级
+	leveledUp, newLevel, err := s.AutoLevelUp(ctx, tx, heroID)
 	if err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "自动升级检查失败")
 	}
-
-	// 5. 提交事务
-	if err := tx.Commit(); err != nil {
-		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	if leveledUp {
+		hero.CurrentLevel = int16(newLevel)
 	}
-
-	// 6. 返回更新后的英雄信息
-	return s.heroRepo.GetByID(ctx, heroID)
+	return hero, nil
 }
 
 // HeroFullInfo 英雄完整信息（聚合）
@@ -658,15 +667,16 @@ func (s *HeroService) GetHeroFullInfo(ctx context.Context, heroID string) (*Hero
 
 // AdvancementCheckResult 进阶条件检查结果
 type AdvancementCheckResult struct {
-	CanAdvance          bool     `json:"can_advance"`
-	MissingRequirements []string `json:"missing_requirements,omitempty"`
-	RequiredLevel       int      `json:"required_level"`
-	CurrentLevel        int      `json:"current_level"`
-	RequiredHonor       int      `json:"required_honor"`
-	CurrentHonor        int      `json:"current_honor"`
+	CanAdvance          bool                           `json:"can_advance"`
+	MissingRequirements []string                       `json:"missing_requirements,omitempty"`
+	RequiredLevel       int                            `json:"required_level"`
+	CurrentLevel        int                            `json:"current_level"`
+	RequiredHonor       int                            `json:"required_honor"`
+	CurrentHonor        int                            `json:"current_honor"`
+	Requirements        []AdvancementRequirementStatus `json:"requirements"`
 }
 
-// CheckAdvancementRequirements 检查是否满足职业进阶条件
+// CheckAdvancementRequirements 检查是否满足职业进阶条件，逐项返回检查结果（含物品缺口）
 func (s *HeroService) CheckAdvancementRequirements(ctx context.Context, heroID, targetClassID string) (*AdvancementCheckResult, error) {
 	// 1. 获取英雄信息
 	hero, err := s.heroRepo.GetByID(ctx, heroID)
@@ -683,97 +693,10 @@ func (s *HeroService) CheckAdvancementRequirements(ctx context.Context, heroID,
 		return nil, xerrors.New(xerrors.CodeResourceNotFound, "不存在该进阶路径")
 	}
 
-	result := &AdvancementCheckResult{
-		CanAdvance:    true,
-		RequiredLevel: requirement.RequiredLevel,
-		CurrentLevel:  int(hero.CurrentLevel),
-		RequiredHonor: requirement.RequiredHonor,
-		CurrentHonor:  0, // TODO: 从英雄表或其他表获取荣誉值
-	}
-
-	var missingReqs []string
-
-	// 3. 检查等级要求
-	if int(hero.CurrentLevel) < requirement.RequiredLevel {
-		result.CanAdvance = false
-		missingReqs = append(missingReqs, fmt.Sprintf("等级不足（需要%d级，当前%d级）", requirement.RequiredLevel, hero.CurrentLevel))
-	}
-
-	// 4. 检查荣誉要求
-	// TODO: 实现荣誉系统后添加检查
-	// if currentHonor < requirement.RequiredHonor {
-	//     result.CanAdvance = false
-	//     missingReqs = append(missingReqs, fmt.Sprintf("荣誉不足（需要%d，当前%d）", requirement.RequiredHonor, currentHonor))
-	// }
-
-	// 5. 检查属性要求
-	if !requirement.RequiredAttributes.IsZero() {
-		var requiredAttrs map[string]int
-		if err := requirement.RequiredAttributes.Unmarshal(&requiredAttrs); err == nil && len(requiredAttrs) > 0 {
-			// 获取当前属性
-			allocatedAttrs, err := s.heroAllocatedAttributeRepo.GetByHeroID(ctx, heroID)
-			if err != nil {
-				return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询属性失败")
-			}
-
-			currentAttrs := make(map[string]int)
-			for _, attr := range allocatedAttrs {
-				currentAttrs[attr.AttributeCode] = attr.Value
-			}
-
-			// 检查每个属性要求
-			for attrCode, requiredValue := range requiredAttrs {
-				currentValue := currentAttrs[attrCode]
-				if currentValue < requiredValue {
-					result.CanAdvance = false
-					missingReqs = append(missingReqs, fmt.Sprintf("属性%s不足（需要%d，当前%d）", attrCode, requiredValue, currentValue))
-				}
-			}
-		}
-	}
-
-	// 6. 检查技能要求
-	if !requirement.RequiredSkills.IsZero() {
-		var requiredSkills []string
-		if err := requirement.RequiredSkills.Unmarshal(&requiredSkills); err == nil && len(requiredSkills) > 0 {
-			// 获取英雄已学习的技能
-			learnedSkills, err := s.heroSkillRepo.GetByHeroID(ctx, heroID)
-			if err != nil {
-				return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询技能失败")
-			}
-
-			// 构建已学习技能的 skill_code map（需要查询 game_config.skills 表）
-			learnedSkillCodes := make(map[string]bool)
-			for _, heroSkill := range learnedSkills {
-				// 通过 skill_id 查询 game_config.skills 获取 skill_code
-				skill, err := s.skillRepo.GetByID(ctx, heroSkill.SkillID)
-				if err == nil && skill != nil {
-					learnedSkillCodes[skill.SkillCode] = true
-				}
-			}
-
-			// 检查每个技能要求
-			for _, skillCode := range requiredSkills {
-				if !learnedSkillCodes[skillCode] {
-					result.CanAdvance = false
-					missingReqs = append(missingReqs, fmt.Sprintf("未学习技能：%s", skillCode))
-				}
-			}
-		}
-	}
-
-	// 7. 检查物品要求
-	if !requirement.RequiredItems.IsZero() {
-		var requiredItems map[string]int
-		if err := requirement.RequiredItems.Unmarshal(&requiredItems); err == nil && len(requiredItems) > 0 {
-			// TODO: 实现物品系统后添加检查
-			for itemCode, count := range requiredItems {
-				result.CanAdvance = false
-				missingReqs = append(missingReqs, fmt.Sprintf("缺少物品：%s x%d", itemCode, count))
-			}
-		}
+	// 3. 读取英雄状态并逐项比对
+	snapshot, _, err := s.loadAdvancementSnapshot(ctx, hero, requirement)
+	if err != nil {
+		return nil, err
 	}
-
-	result.MissingRequirements = missingReqs
-	return result, nil
+	return evaluateAdvancement(requirement, snapshot)
 }
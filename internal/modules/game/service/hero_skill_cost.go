@@ -0,0 +1,354 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aarondl/null/v8"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// skillMaterialCost 技能消耗配置中的材料项，格式: [{"item_code": "xxx", "count": 5}]（也可使用 item_id）
+type skillMaterialCost struct {
+	ItemID   string `json:"item_id,omitempty"`
+	ItemCode string `json:"item_code,omitempty"`
+	Count    int    `json:"count"`
+}
+
+// SkillMaterialRequirement 技能消耗的材料及英雄背包持有量
+type SkillMaterialRequirement struct {
+	ItemID   string
+	ItemCode string
+	ItemName string
+	Count    int
+	Owned    int
+}
+
+// SkillCostInfo 技能学习/升级到 Level 级的消耗及英雄当前是否负担得起
+type SkillCostInfo struct {
+	Level     int
+	CostXP    int
+	CostGold  int
+	Materials []*SkillMaterialRequirement
+	CanAfford bool
+	Missing   []string // 不足的资源说明
+}
+
+// skillMaterialSpent 实际扣除的材料，写入 hero_skill_operations.materials_spent，回退时按堆叠精确返还
+type skillMaterialSpent struct {
+	ItemID   string               `json:"item_id"`
+	ItemCode string               `json:"item_code,omitempty"`
+	Count    int                  `json:"count"`
+	Stacks   []skillMaterialStack `json:"stacks"`
+}
+
+type skillMaterialStack struct {
+	InstanceID string `json:"instance_id"`
+	Taken      int    `json:"taken"`
+}
+
+// parseSkillMaterialCosts 解析 cost_materials 配置
+func parseSkillMaterialCosts(raw null.JSON) ([]skillMaterialCost, error) {
+	if raw.IsZero() || len(raw.JSON) == 0 || string(raw.JSON) == "null" {
+		return nil, nil
+	}
+	var costs []skillMaterialCost
+	if err := json.Unmarshal(raw.JSON, &costs); err != nil {
+		return nil, err
+	}
+	result := costs[:0]
+	for _, cost := range costs {
+		if cost.Count <= 0 {
+			continue
+		}
+		if cost.ItemID == "" && cost.ItemCode == "" {
+			return nil, fmt.Errorf("材料项缺少 item_code 或 item_id")
+		}
+		result = append(result, cost)
+	}
+	return result, nil
+}
+
+// stackTotal 计算堆叠列表的物品总数（无堆叠数的实例按 1 计）
+func stackTotal(stacks []*game_runtime.PlayerItem) int {
+	total := 0
+	for _, stack := range stacks {
+		if stack.StackCount.Valid {
+			total += stack.StackCount.Int
+		} else {
+			total++
+		}
+	}
+	return total
+}
+
+// resolveSkillCost 解析某一等级的 XP、金币与材料消耗
+func (s *HeroSkillService) resolveSkillCost(ctx context.Context, cost *game_config.SkillUpgradeCost) (*SkillCostInfo, error) {
+	info := &SkillCostInfo{Level: cost.LevelNumber}
+	if cost.CostXP.Valid {
+		info.CostXP = cost.CostXP.Int
+	}
+	if cost.CostGold.Valid {
+		info.CostGold = cost.CostGold.Int
+	}
+
+	materials, err := parseSkillMaterialCosts(cost.CostMaterials)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "解析材料消耗配置失败")
+	}
+	byItemID := make(map[string]*SkillMaterialRequirement, len(materials))
+	for _, material := range materials {
+		key := material.ItemID
+		if key == "" {
+			key = material.ItemCode
+		}
+		item, err := resolveItemConfig(ctx, s.itemRepo, key)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, fmt.Sprintf("材料配置不存在: %s", key))
+		}
+		if existing, ok := byItemID[item.ID]; ok {
+			existing.Count += material.Count
+			continue
+		}
+		requirement := &SkillMaterialRequirement{
+			ItemID:   item.ID,
+			ItemCode: item.ItemCode,
+			ItemName: item.ItemName,
+			Count:    material.Count,
+		}
+		byItemID[item.ID] = requirement
+		info.Materials = append(info.Materials, requirement)
+	}
+	return info, nil
+}
+
+// fillSkillAffordability 填充材料持有量并判断英雄当前是否负担得起
+func (s *HeroSkillService) fillSkillAffordability(ctx context.Context, hero *game_runtime.Hero, info *SkillCostInfo) error {
+	info.Missing = nil
+	if hero.ExperienceAvailable < int64(info.CostXP) {
+		info.Missing = append(info.Missing, fmt.Sprintf("经验不足: 需要 %d，当前 %d", info.CostXP, hero.ExperienceAvailable))
+	}
+
+	if info.CostGold > 0 {
+		gold, err := s.heroCurrencyRepo.GetBalance(ctx, hero.ID, interfaces.CurrencyGold)
+		if err != nil {
+			return xerrors.Wrap(err, xerrors.CodeInternalError, "查询金币余额失败")
+		}
+		if gold < int64(info.CostGold) {
+			info.Missing = append(info.Missing, fmt.Sprintf("金币不足: 需要 %d，当前 %d", info.CostGold, gold))
+		}
+	}
+
+	if len(info.Materials) > 0 {
+		itemIDs := make([]string, len(info.Materials))
+		for i, material := range info.Materials {
+			itemIDs[i] = material.ItemID
+		}
+		owned, err := s.playerItemRepo.CountHeroItems(ctx, hero.ID, itemIDs, []string{"backpack"})
+		if err != nil {
+			return xerrors.Wrap(err, xerrors.CodeInternalError, "查询背包材料失败")
+		}
+		for _, material := range info.Materials {
+			material.Owned = owned[material.ItemID]
+			if material.Owned < material.Count {
+				info.Missing = append(info.Missing, fmt.Sprintf("材料不足: %s 需要 %d，当前 %d", material.ItemName, material.Count, material.Owned))
+			}
+		}
+	}
+
+	info.CanAfford = len(info.Missing) == 0
+	return nil
+}
+
+// chargeSkillCost 在事务内扣除金币与背包材料，返回实际扣除的材料明细（写入 materials_spent）
+func (s *HeroSkillService) chargeSkillCost(ctx context.Context, tx *sql.Tx, hero *game_runtime.Hero, info *SkillCostInfo, operationID, skillID string) (null.JSON, error) {
+	// 1. 金币（记入货币流水）
+	if info.CostGold > 0 {
+		if _, err := applyCurrencyTx(ctx, s.heroCurrencyRepo, tx, CurrencyChange{
+			HeroID:     hero.ID,
+			Currency:   interfaces.CurrencyGold,
+			Amount:     -int64(info.CostGold),
+			SourceType: CurrencySourceSkillUpgrade,
+			SourceID:   operationID,
+			Metadata:   map[string]interface{}{"skill_id": skillID, "level": info.Level},
+		}); err != nil {
+			return null.JSON{}, err
+		}
+	}
+
+	// 2. 背包材料（数量少的堆叠优先）
+	spent := make([]skillMaterialSpent, 0, len(info.Materials))
+	for _, material := range info.Materials {
+		stacks, err := s.playerItemRepo.ListBackpackStacksForUpdate(ctx, tx, hero.ID, material.ItemID)
+		if err != nil {
+			return null.JSON{}, xerrors.Wrap(err, xerrors.CodeInternalError, "查询背包材料失败")
+		}
+		if owned := stackTotal(stacks); owned < material.Count {
+			return null.JSON{}, xerrors.New(xerrors.CodeInsufficientResource,
+				fmt.Sprintf("材料不足: %s 需要 %d，当前 %d", material.ItemName, material.Count, owned))
+		}
+		plan, err := planStackConsumption(stacks, material.Count)
+		if err != nil {
+			return null.JSON{}, err
+		}
+
+		record := skillMaterialSpent{ItemID: material.ItemID, ItemCode: material.ItemCode, Count: material.Count}
+		for _, step := range plan {
+			before := stackTotal([]*game_runtime.PlayerItem{step.item})
+			record.Stacks = append(record.Stacks, skillMaterialStack{InstanceID: step.item.ID, Taken: before - step.remaining})
+		}
+		if err := applyStackConsumption(ctx, s.playerItemRepo, tx, plan); err != nil {
+			return null.JSON{}, err
+		}
+		spent = append(spent, record)
+	}
+
+	raw, err := json.Marshal(spent)
+	if err != nil {
+		return null.JSON{}, xerrors.Wrap(err, xerrors.CodeInternalError, "序列化材料消耗失败")
+	}
+	return null.JSONFrom(raw), nil
+}
+
+// refundSkillCost 在事务内按操作记录精确返还金币与材料
+// 材料优先回填到原堆叠（已耗尽的堆叠恢复），原堆叠已离开英雄背包时在背包新建堆叠
+func (s *HeroSkillService) refundSkillCost(ctx context.Context, tx *sql.Tx, hero *game_runtime.Hero, operation *game_runtime.HeroSkillOperation) error {
+	// 1. 金币（旧记录的 cost_gold 只是配置快照，没有扣款流水，说明当时未实际扣除，无需返还）
+	charged, err := s.skillGoldCharged(ctx, hero.ID, operation)
+	if err != nil {
+		return err
+	}
+	if charged {
+		if _, err := applyCurrencyTx(ctx, s.heroCurrencyRepo, tx, CurrencyChange{
+			HeroID:     hero.ID,
+			Currency:   interfaces.CurrencyGold,
+			Amount:     int64(operation.GoldSpent),
+			SourceType: CurrencySourceSkillRollback,
+			SourceID:   operation.ID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	// 2. 材料（旧记录只有配置快照、没有堆叠明细，说明当时未实际扣除，无需返还）
+	if operation.MaterialsSpent.IsZero() {
+		return nil
+	}
+	var spent []skillMaterialSpent
+	if err := json.Unmarshal(operation.MaterialsSpent.JSON, &spent); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "解析材料消耗记录失败")
+	}
+	for _, record := range spent {
+		for _, stack := range record.Stacks {
+			if err := s.restoreMaterialStack(ctx, tx, hero, record.ItemID, stack); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// skillGoldCharged 判断操作记录的金币是否实际扣除（存在对应的技能升级扣款流水）
+func (s *HeroSkillService) skillGoldCharged(ctx context.Context, heroID string, operation *game_runtime.HeroSkillOperation) (bool, error) {
+	if operation.GoldSpent <= 0 {
+		return false, nil
+	}
+	_, total, err := s.heroCurrencyRepo.ListTransactions(ctx, interfaces.CurrencyTransactionQuery{
+		HeroID:       heroID,
+		CurrencyCode: interfaces.CurrencyGold,
+		SourceType:   CurrencySourceSkillUpgrade,
+		SourceID:     operation.ID,
+		Limit:        1,
+	})
+	if err != nil {
+		return false, xerrors.Wrap(err, xerrors.CodeInternalError, "查询技能扣款流水失败")
+	}
+	return total > 0, nil
+}
+
+func (s *HeroSkillService) restoreMaterialStack(ctx context.Context, tx *sql.Tx, hero *game_runtime.Hero, itemID string, stack skillMaterialStack) error {
+	if stack.Taken <= 0 {
+		return nil
+	}
+	item, err := s.playerItemRepo.GetByIDIncludingDeletedForUpdate(ctx, tx, stack.InstanceID)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "查询材料堆叠失败")
+	}
+
+	// 原堆叠仍属于该英雄：已耗尽的恢复为返还数量，未耗尽的累加
+	if item != nil && item.HeroID.Valid && item.HeroID.String == hero.ID &&
+		(item.DeletedAt.Valid || item.ItemLocation == "backpack") {
+		if item.DeletedAt.Valid {
+			item.DeletedAt = null.Time{}
+			item.ItemLocation = "backpack"
+			item.StackCount = null.IntFrom(stack.Taken)
+		} else {
+			item.StackCount = null.IntFrom(stackTotal([]*game_runtime.PlayerItem{item}) + stack.Taken)
+		}
+		if err := s.playerItemRepo.Update(ctx, tx, item); err != nil {
+			return xerrors.Wrap(err, xerrors.CodeInternalError, "返还材料失败")
+		}
+		return nil
+	}
+
+	sourceType := "other"
+	if item != nil {
+		sourceType = item.SourceType
+	}
+	restored := &game_runtime.PlayerItem{
+		ItemID:       itemID,
+		OwnerID:      hero.UserID,
+		HeroID:       null.StringFrom(hero.ID),
+		SourceType:   sourceType,
+		ItemLocation: "backpack",
+		StackCount:   null.IntFrom(stack.Taken),
+	}
+	if err := s.playerItemRepo.Create(ctx, tx, restored); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "返还材料失败")
+	}
+	return nil
+}
+
+// skillCostLookup 按等级缓存同一英雄的技能消耗与负担能力（列表接口中多个技能共享同一等级配置）
+type skillCostLookup struct {
+	service *HeroSkillService
+	hero    *game_runtime.Hero
+	byLevel map[int]*SkillCostInfo
+}
+
+func newSkillCostLookup(service *HeroSkillService, hero *game_runtime.Hero) *skillCostLookup {
+	return &skillCostLookup{service: service, hero: hero, byLevel: make(map[int]*SkillCostInfo)}
+}
+
+// get 返回升到 level 级的消耗，未配置该等级时返回 nil
+func (l *skillCostLookup) get(ctx context.Context, level int) (*SkillCostInfo, error) {
+	if info, ok := l.byLevel[level]; ok {
+		return info, nil
+	}
+	cost, err := l.service.skillUpgradeCostRepo.GetByLevel(ctx, level)
+	if err != nil {
+		var appErr *xerrors.AppError
+		if errors.As(err, &appErr) && appErr.Code == xerrors.CodeResourceNotFound {
+			// 未配置该等级消耗
+			l.byLevel[level] = nil
+			return nil, nil
+		}
+		return nil, err
+	}
+	info, err := l.service.resolveSkillCost(ctx, cost)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.service.fillSkillAffordability(ctx, l.hero, info); err != nil {
+		return nil, err
+	}
+	l.byLevel[level] = info
+	return info, nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aarondl/null/v8"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/repository/interfaces"
+)
+
+func TestParseSkillMaterialCosts(t *testing.T) {
+	costs, err := parseSkillMaterialCosts(null.JSONFrom([]byte(`[{"item_code": "skill_book", "count": 2}, {"item_id": "item-1", "count": 0}]`)))
+	require.NoError(t, err)
+	require.Equal(t, []skillMaterialCost{{ItemCode: "skill_book", Count: 2}}, costs)
+
+	costs, err = parseSkillMaterialCosts(null.JSON{})
+	require.NoError(t, err)
+	require.Nil(t, costs)
+
+	costs, err = parseSkillMaterialCosts(null.JSONFrom([]byte(`null`)))
+	require.NoError(t, err)
+	require.Nil(t, costs)
+
+	_, err = parseSkillMaterialCosts(null.JSONFrom([]byte(`[{"count": 1}]`)))
+	require.Error(t, err)
+
+	_, err = parseSkillMaterialCosts(null.JSONFrom([]byte(`{"skill_book": 1}`)))
+	require.Error(t, err)
+}
+
+func TestStackTotal(t *testing.T) {
+	stacks := []*game_runtime.PlayerItem{
+		{ID: "a", StackCount: null.IntFrom(5)},
+		{ID: "b", StackCount: null.IntFrom(3)},
+		{ID: "c"},
+	}
+	require.Equal(t, 9, stackTotal(stacks))
+	require.Equal(t, 0, stackTotal(nil))
+}
+
+type fakeSkillLedgerRepo struct {
+	interfaces.HeroCurrencyRepository
+	charges   map[string]bool // 技能升级扣款流水，键为 source_id
+	lastQuery interfaces.CurrencyTransactionQuery
+}
+
+func (f *fakeSkillLedgerRepo) ListTransactions(_ context.Context, query interfaces.CurrencyTransactionQuery) ([]*interfaces.CurrencyTransaction, int64, error) {
+	f.lastQuery = query
+	if f.charges[query.SourceID] {
+		return []*interfaces.CurrencyTransaction{{SourceType: query.SourceType}}, 1, nil
+	}
+	return nil, 0, nil
+}
+
+func TestSkillGoldCharged(t *testing.T) {
+	ctx := context.Background()
+	ledger := &fakeSkillLedgerRepo{charges: map[string]bool{"op-new": true}}
+	svc := &HeroSkillService{heroCurrencyRepo: ledger}
+
+	charged, err := svc.skillGoldCharged(ctx, "hero-1", &game_runtime.HeroSkillOperation{ID: "op-new", GoldSpent: 50})
+	require.NoError(t, err)
+	require.True(t, charged)
+	require.Equal(t, CurrencySourceSkillUpgrade, ledger.lastQuery.SourceType)
+	require.Equal(t, interfaces.CurrencyGold, ledger.lastQuery.CurrencyCode)
+
+	// 旧记录只有 cost_gold 快照，没有扣款流水
+	charged, err = svc.skillGoldCharged(ctx, "hero-1", &game_runtime.HeroSkillOperation{ID: "op-legacy", GoldSpent: 50})
+	require.NoError(t, err)
+	require.False(t, charged)
+
+	ledger.lastQuery = interfaces.CurrencyTransactionQuery{}
+	charged, err = svc.skillGoldCharged(ctx, "hero-1", &game_runtime.HeroSkillOperation{ID: "op-new"})
+	require.NoError(t, err)
+	require.False(t, charged)
+	require.Empty(t, ledger.lastQuery.HeroID, "未消耗金币时不查询流水")
+}
@@ -16,8 +16,6 @@ import (
 	"tsu-self/internal/repository/interfaces"
 )
 
-// TODO:当前的技能的学习的升级只考虑了经验，没有考虑金币和材料
-
 // HeroSkillService 英雄技能服务
 type HeroSkillService struct {
 	db                   *sql.DB
@@ -28,6 +26,9 @@ type HeroSkillService struct {
 	skillRepo            interfaces.SkillRepository
 	skillUpgradeCostRepo interfaces.SkillUpgradeCostRepository
 	skillOpRepo          interfaces.HeroSkillOperationRepository
+	itemRepo             interfaces.ItemRepository
+	playerItemRepo       interfaces.PlayerItemRepository
+	heroCurrencyRepo     interfaces.HeroCurrencyRepository
 	heroService          *HeroService
 }
 
@@ -39,8 +40,12 @@ func NewHeroSkillService(db *sql.DB) *HeroSkillService {
 		heroSkillRepo:        impl.NewHeroSkillRepository(db),
 		heroClassHistoryRepo: impl.NewHeroClassHistoryRepository(db),
 		classSkillPoolRepo:   impl.NewClassSkillPoolRepository(db),
+		skillRepo:            impl.NewSkillRepository(db),
 		skillUpgradeCostRepo: impl.NewSkillUpgradeCostRepository(db),
 		skillOpRepo:          impl.NewHeroSkillOperationRepository(db),
+		itemRepo:             impl.NewItemRepository(db),
+		playerItemRepo:       impl.NewPlayerItemRepository(db),
+		heroCurrencyRepo:     impl.NewHeroCurrencyRepository(db),
 		heroService:          NewHeroService(db),
 	}
 }
@@ -132,11 +137,12 @@ func (s *HeroSkillService) LearnSkill(ctx context.Context, req *LearnSkillReques
 		return xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
 	}
 
-	// 7. 验证资源是否足够
-	costXP := 0
-	if !cost.CostXP.IsZero() {
-		costXP = int(cost.CostXP.Int)
+	// 7. 解析消耗并验证经验是否足够
+	costInfo, err := s.resolveSkillCost(ctx, cost)
+	if err != nil {
+		return err
 	}
+	costXP := costInfo.CostXP
 
 	if hero.ExperienceAvailable < int64(costXP) {
 		return xerrors.New(xerrors.CodeInsufficientExperience,
@@ -152,6 +158,12 @@ func (s *HeroSkillService) LearnSkill(ctx context.Context, req *LearnSkillReques
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "更新英雄失败")
 	}
 
+	operationID := uuid.New().String()
+	materialsSpent, err := s.chargeSkillCost(ctx, tx, hero, costInfo, operationID, req.SkillID)
+	if err != nil {
+		return err
+	}
+
 	// 9. 插入 hero_skills
 	now := time.Now()
 	heroSkill := &game_runtime.HeroSkill{
@@ -169,19 +181,14 @@ func (s *HeroSkillService) LearnSkill(ctx context.Context, req *LearnSkillReques
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "创建技能记录失败")
 	}
 
-	// 10. 创建操作历史
-	costGold := 0
-	if !cost.CostGold.IsZero() {
-		costGold = int(cost.CostGold.Int)
-	}
-
+	// 10. 创建操作历史（记录实际扣除的金币与材料，供回退精确返还）
 	operation := &game_runtime.HeroSkillOperation{
-		ID:               uuid.New().String(),
+		ID:               operationID,
 		HeroSkillID:      heroSkill.ID,
 		LevelsAdded:      1,
 		XPSpent:          costXP,
-		GoldSpent:        costGold,
-		MaterialsSpent:   cost.CostMaterials,
+		GoldSpent:        costInfo.CostGold,
+		MaterialsSpent:   materialsSpent,
 		LevelBefore:      0,
 		LevelAfter:       1,
 		CreatedAt:        now,
@@ -270,18 +277,19 @@ func (s *HeroSkillService) UpgradeSkill(ctx context.Context, req *UpgradeSkillRe
 		return xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
 	}
 
-	// 7. 验证资源
-	costXP := 0
-	if !cost.CostXP.IsZero() {
-		costXP = int(cost.CostXP.Int)
+	// 7. 解析消耗并验证经验是否足够
+	costInfo, err := s.resolveSkillCost(ctx, cost)
+	if err != nil {
+		return err
 	}
+	costXP := costInfo.CostXP
 
 	if hero.ExperienceAvailable < int64(costXP) {
 		return xerrors.New(xerrors.CodeInsufficientExperience,
 			fmt.Sprintf("经验不足: 需要 %d，当前 %d", costXP, hero.ExperienceAvailable))
 	}
 
-	// 8. 扣除资源（注：experience_total 不应在此增加）
+	// 8. 扣除经验、金币与背包材料（注：experience_total 不应在此增加）
 	hero.ExperienceAvailable -= int64(costXP)
 	hero.ExperienceSpent += int64(costXP)
 	hero.UpdatedAt = time.Now()
@@ -290,6 +298,12 @@ func (s *HeroSkillService) UpgradeSkill(ctx context.Context, req *UpgradeSkillRe
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "更新英雄失败")
 	}
 
+	operationID := uuid.New().String()
+	materialsSpent, err := s.chargeSkillCost(ctx, tx, hero, costInfo, operationID, heroSkill.SkillID)
+	if err != nil {
+		return err
+	}
+
 	// 9. 更新 hero_skills
 	oldLevel := heroSkill.SkillLevel
 	heroSkill.SkillLevel = nextLevel
@@ -299,20 +313,15 @@ func (s *HeroSkillService) UpgradeSkill(ctx context.Context, req *UpgradeSkillRe
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "更新技能失败")
 	}
 
-	// 10. 创建操作历史
+	// 10. 创建操作历史（记录实际扣除的金币与材料，供回退精确返还）
 	now := time.Now()
-	costGold := 0
-	if !cost.CostGold.IsZero() {
-		costGold = int(cost.CostGold.Int)
-	}
-
 	operation := &game_runtime.HeroSkillOperation{
-		ID:               uuid.New().String(),
+		ID:               operationID,
 		HeroSkillID:      heroSkill.ID,
 		LevelsAdded:      1, // 当前实现只支持升级1级（req.Levels 被忽略）
 		XPSpent:          costXP,
-		GoldSpent:        costGold,
-		MaterialsSpent:   cost.CostMaterials,
+		GoldSpent:        costInfo.CostGold,
+		MaterialsSpent:   materialsSpent,
 		LevelBefore:      int(oldLevel),
 		LevelAfter:       nextLevel,
 		CreatedAt:        now,
@@ -389,6 +398,11 @@ func (s *HeroSkillService) RollbackSkillOperation(ctx context.Context, heroID, s
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "更新英雄失败")
 	}
 
+	// 6.5. 返还金币与材料
+	if err := s.refundSkillCost(ctx, tx, hero, operation); err != nil {
+		return err
+	}
+
 	// 7. 回退技能等级
 	if operation.LevelBefore == 0 {
 		// 如果回退到level=0，删除记录
@@ -418,15 +432,16 @@ func (s *HeroSkillService) RollbackSkillOperation(ctx context.Context, heroID, s
 
 // AvailableSkillInfo 可学习技能信息
 type AvailableSkillInfo struct {
-	SkillID              string   // 技能ID
-	SkillName            string   // 技能名称
-	SkillCode            string   // 技能代码
-	MaxLevel             int      // 技能最大等级
-	MaxLearnableLevel    int      // 职业可学习的最大等级
-	CanLearn             bool     // 是否可以学习（考虑前置技能）
-	PrerequisiteSkillIds []string // 前置技能ID列表
-	MissingSkillIds      []string // 未满足的前置技能ID列表
-	Requirements         string   // 其他要求描述
+	SkillID              string         // 技能ID
+	SkillName            string         // 技能名称
+	SkillCode            string         // 技能代码
+	MaxLevel             int            // 技能最大等级
+	MaxLearnableLevel    int            // 职业可学习的最大等级
+	CanLearn             bool           // 是否可以学习（考虑前置技能）
+	PrerequisiteSkillIds []string       // 前置技能ID列表
+	MissingSkillIds      []string       // 未满足的前置技能ID列表
+	Requirements         string         // 其他要求描述
+	LearnCost            *SkillCostInfo // 学习消耗（1级）及当前是否负担得起，未配置时为 nil
 }
 
 // GetAvailableSkills 获取可学习技能
@@ -455,6 +470,17 @@ func (s *HeroSkillService) GetAvailableSkills(ctx context.Context, heroID string
 		learnedSkillIDs[skill.SkillID] = true
 	}
 
+	// 学习消耗（1级）对所有技能相同，只计算一次
+	hero, err := s.heroRepo.GetByID(ctx, heroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+	}
+	costs := newSkillCostLookup(s, hero)
+	learnCost, err := costs.get(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+
 	// 4. 转换为可学习技能信息（排除已学习的技能）
 	availableSkills := make([]*AvailableSkillInfo, 0)
 	for _, pool := range skillPools {
@@ -502,6 +528,7 @@ func (s *HeroSkillService) GetAvailableSkills(ctx context.Context, heroID string
 			CanLearn:             canLearn,
 			PrerequisiteSkillIds: pool.PrerequisiteSkillIds,
 			MissingSkillIds:      missingSkillIDs,
+			LearnCost:            learnCost,
 		})
 	}
 
@@ -520,6 +547,7 @@ type LearnedSkillInfo struct {
 	FirstLearnedAt string
 	CanUpgrade     bool
 	CanRollback    bool
+	UpgradeCost    *SkillCostInfo // 升到下一级的消耗及当前是否负担得起，不可升级或未配置时为 nil
 }
 
 // GetLearnedSkills 获取已学习技能列表
@@ -536,6 +564,12 @@ func (s *HeroSkillService) GetLearnedSkills(ctx context.Context, heroID string)
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "获取当前职业失败")
 	}
 
+	hero, err := s.heroRepo.GetByID(ctx, heroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+	}
+	costs := newSkillCostLookup(s, hero)
+
 	// 3. 转换为已学习技能信息（联接 Skills 表）
 	learnedSkills := make([]*LearnedSkillInfo, 0, len(heroSkills))
 	for _, heroSkill := range heroSkills {
@@ -566,6 +600,15 @@ func (s *HeroSkillService) GetLearnedSkills(ctx context.Context, heroID string)
 			}
 		}
 
+		// 下一级消耗及是否负担得起
+		var upgradeCost *SkillCostInfo
+		if canUpgrade {
+			upgradeCost, err = costs.get(ctx, int(heroSkill.SkillLevel)+1)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		// 判断是否可以回退（查询最近一次未回退且未过期的操作）
 		canRollback := false
 		operation, err := s.skillOpRepo.GetLatestRollbackable(ctx, heroSkill.ID)
@@ -594,6 +637,7 @@ func (s *HeroSkillService) GetLearnedSkills(ctx context.Context, heroID string)
 			FirstLearnedAt: firstLearnedAt,
 			CanUpgrade:     canUpgrade,
 			CanRollback:    canRollback,
+			UpgradeCost:    upgradeCost,
 		})
 	}
 
@@ -27,6 +27,14 @@ type ItemDropService struct {
 	itemRepo               interfaces.ItemRepository
 	playerItemRepo         interfaces.PlayerItemRepository
 	itemDropRecordRepo     interfaces.ItemDropRecordRepository
+	monsterRepo            interfaces.MonsterRepository
+	monsterDropRepo        interfaces.MonsterDropRepository
+	dungeonBattleRepo      interfaces.DungeonBattleRepository
+	dungeonRoomRepo        interfaces.DungeonRoomRepository
+	dungeonRepo            interfaces.DungeonRepository
+	teamMemberRepo         interfaces.TeamMemberRepository
+	heroRepo               interfaces.HeroRepository
+	battleStepRepo         interfaces.BattleResultStepRepository
 }
 
 // NewItemDropService 创建物品掉落服务
@@ -39,6 +47,14 @@ func NewItemDropService(db *sql.DB) *ItemDropService {
 		itemRepo:            impl.NewItemRepository(db),
 		playerItemRepo:      impl.NewPlayerItemRepository(db),
 		itemDropRecordRepo:  impl.NewItemDropRecordRepository(db),
+		monsterRepo:         impl.NewMonsterRepository(db),
+		monsterDropRepo:     impl.NewMonsterDropRepository(db),
+		dungeonBattleRepo:   impl.NewDungeonBattleRepository(db),
+		dungeonRoomRepo:     impl.NewDungeonRoomRepository(db),
+		dungeonRepo:         impl.NewDungeonRepository(db),
+		teamMemberRepo:      impl.NewTeamMemberRepository(db),
+		heroRepo:            impl.NewHeroRepository(db),
+		battleStepRepo:      impl.NewBattleResultStepRepository(db),
 	}
 }
 
@@ -182,6 +198,50 @@ func (s *ItemDropService) DropFromMonster(ctx context.Context, req *DropFromMons
 	}, nil
 }
 
+// RollDropPool 按掉落池配置掷骰，返回战利品列表（不落库，由调用方决定入库位置）
+func (s *ItemDropService) RollDropPool(ctx context.Context, poolID string, level int) ([]LootItem, error) {
+	pool, err := s.dropPoolRepo.GetByID(ctx, poolID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "掉落池不存在")
+	}
+	if !pool.IsActive {
+		return nil, nil
+	}
+
+	poolItems, err := s.dropPoolRepo.GetPoolItemsByLevel(ctx, pool.ID, level)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询掉落池物品失败")
+	}
+	if len(poolItems) == 0 {
+		return nil, nil
+	}
+
+	selected := s.selectItemsFromPool(poolItems, s.determineDropCount(pool))
+	loot := make([]LootItem, 0, len(selected))
+	index := make(map[string]int, len(selected))
+	for _, poolItem := range selected {
+		quantity := s.randomQuantity(poolItem.MinQuantity, poolItem.MaxQuantity)
+		if quantity <= 0 {
+			continue
+		}
+		if i, ok := index[poolItem.ItemID]; ok {
+			loot[i].Quantity += quantity
+			continue
+		}
+		itemConfig, err := s.itemRepo.GetByID(ctx, poolItem.ItemID)
+		if err != nil {
+			continue
+		}
+		index[poolItem.ItemID] = len(loot)
+		loot = append(loot, LootItem{
+			ItemID:   poolItem.ItemID,
+			ItemType: itemConfig.ItemType,
+			Quantity: quantity,
+		})
+	}
+	return loot, nil
+}
+
 // determineDropCount 确定掉落数量
 func (s *ItemDropService) determineDropCount(pool *game_config.DropPool) int {
 	// 保底掉落
@@ -332,6 +392,7 @@ type CheckWorldDropRequest struct {
 	TeamSize      int    `json:"team_size"`
 	IsFirstKill   bool   `json:"is_first_kill"`
 	TeamID        string `json:"team_id"`
+	BattleID      string `json:"-"` // 战斗结算时非空：同一场战斗只累计一次世界掉落
 }
 
 // CheckWorldDropResponse 检查世界掉落响应
@@ -463,6 +524,89 @@ func (s *ItemDropService) CheckWorldDrop(ctx context.Context, req *CheckWorldDro
 	}, nil
 }
 
+// RollWorldDropLoot 判定世界掉落并返回战利品（不创建物品实例，计入世界掉落统计）
+func (s *ItemDropService) RollWorldDropLoot(ctx context.Context, req *CheckWorldDropRequest) ([]LootItem, error) {
+	configs, err := s.worldDropConfigRepo.GetActiveConfigs(ctx)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询世界掉落配置失败")
+	}
+	eligibleConfigs := s.filterConfigsByTriggerCondition(configs, req)
+	if len(eligibleConfigs) == 0 {
+		return nil, nil
+	}
+
+	// 战斗结算重试时复用首次的世界掉落，不重复累计掉落统计
+	trackBattle := req.BattleID != "" && s.battleStepRepo != nil
+	if trackBattle {
+		if stored, found, err := s.storedWorldDropLoot(ctx, req.BattleID); err != nil || found {
+			return stored, err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer tx.Rollback()
+
+	var loot []LootItem
+	for _, config := range eligibleConfigs {
+		canDrop, err := s.checkWorldDropLimits(ctx, tx, config)
+		if err != nil {
+			return nil, err
+		}
+		if !canDrop || rand.Float64() > s.calculateFinalDropRate(config, req) {
+			continue
+		}
+		itemConfig, err := s.itemRepo.GetByID(ctx, config.ItemID)
+		if err != nil {
+			continue
+		}
+		if err := s.worldDropStatsRepo.IncrementDropCount(ctx, tx, config.ID); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新世界掉落统计失败")
+		}
+		loot = mergeLootItems(loot, LootItem{ItemID: config.ItemID, ItemType: itemConfig.ItemType, Quantity: 1})
+	}
+
+	if trackBattle {
+		lootJSON, err := json.Marshal(loot)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "序列化世界掉落失败")
+		}
+		marked, err := s.battleStepRepo.MarkStep(ctx, tx, req.BattleID, interfaces.BattleStepWorldDrop, lootJSON)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "登记战斗结算步骤失败")
+		}
+		if !marked {
+			// 并发结算已先一步提交，放弃本次统计
+			tx.Rollback()
+			stored, _, err := s.storedWorldDropLoot(ctx, req.BattleID)
+			return stored, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	return loot, nil
+}
+
+// storedWorldDropLoot 读取某场战斗已结算的世界掉落
+func (s *ItemDropService) storedWorldDropLoot(ctx context.Context, battleID string) ([]LootItem, bool, error) {
+	raw, found, err := s.battleStepRepo.GetStep(ctx, battleID, interfaces.BattleStepWorldDrop)
+	if err != nil {
+		return nil, false, xerrors.Wrap(err, xerrors.CodeInternalError, "查询战斗结算步骤失败")
+	}
+	if !found || len(raw) == 0 {
+		return nil, found, nil
+	}
+	var loot []LootItem
+	if err := json.Unmarshal(raw, &loot); err != nil {
+		return nil, true, xerrors.Wrap(err, xerrors.CodeInternalError, "解析世界掉落失败")
+	}
+	return loot, true, nil
+}
+
 // filterConfigsByTriggerCondition 筛选符合触发条件的配置
 func (s *ItemDropService) filterConfigsByTriggerCondition(
 	configs []*game_config.WorldDropConfig,
@@ -609,6 +753,209 @@ func (s *ItemDropService) calculateFinalDropRate(
 	return finalRate
 }
 
+// RollBattleLootRequest 战斗胜利掉落请求
+type RollBattleLootRequest struct {
+	BattleID   string // 战斗结算的唯一ID，用于世界掉落幂等
+	BattleCode string // 战斗配置代码或ID（兼容房间代码）
+	TeamID     string
+	DungeonID  string
+}
+
+// battleMonsterSetup dungeon_battles.monster_setup 单项
+type battleMonsterSetup struct {
+	MonsterCode   string `json:"monster_code"`
+	Position      int    `json:"position"`
+	LevelOverride *int   `json:"level_override"`
+}
+
+// RollBattleLoot 服务端计算战斗胜利掉落：怪物金币、怪物掉落池与世界掉落
+func (s *ItemDropService) RollBattleLoot(ctx context.Context, req *RollBattleLootRequest) (*LootData, error) {
+	if req == nil || req.BattleCode == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "战斗代码不能为空")
+	}
+
+	monsters, err := s.loadBattleMonsters(ctx, req.BattleCode)
+	if err != nil {
+		return nil, err
+	}
+
+	loot := &LootData{}
+	for _, entry := range monsters {
+		monster, level := entry.monster, entry.level
+		loot.Gold += rollGold(monster.DropGoldMin, monster.DropGoldMax)
+
+		drops, err := s.monsterDropRepo.GetByMonsterID(ctx, monster.ID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询怪物掉落配置失败")
+		}
+		for _, drop := range drops {
+			if drop.IsActive.Valid && !drop.IsActive.Bool {
+				continue
+			}
+			if drop.DropChance.Big == nil {
+				continue
+			}
+			chance, _ := drop.DropChance.Float64()
+			if rand.Float64() >= chance {
+				continue
+			}
+			rolls := s.randomQuantity(drop.MinQuantity, drop.MaxQuantity)
+			for i := 0; i < rolls; i++ {
+				items, err := s.RollDropPool(ctx, drop.DropPoolID, level)
+				if err != nil {
+					return nil, err
+				}
+				loot.Items = mergeLootItems(loot.Items, items...)
+			}
+		}
+	}
+
+	partyLevel, teamSize := s.partyLevel(ctx, req.TeamID)
+	worldReq := &CheckWorldDropRequest{
+		PlayerLevel: partyLevel,
+		DungeonID:   req.DungeonID,
+		TeamSize:    teamSize,
+		TeamID:      req.TeamID,
+		BattleID:    req.BattleID,
+	}
+	if req.DungeonID != "" {
+		if dungeon, err := s.dungeonRepo.GetByID(ctx, req.DungeonID); err == nil {
+			worldReq.DungeonLevel = int(dungeon.MinLevel)
+		}
+	}
+	worldLoot, err := s.RollWorldDropLoot(ctx, worldReq)
+	if err != nil {
+		return nil, err
+	}
+	loot.Items = mergeLootItems(loot.Items, worldLoot...)
+
+	return loot, nil
+}
+
+// rollGold 在 [min, max] 范围内随机金币，均未配置时不掉落
+func rollGold(minGold, maxGold null.Int) int64 {
+	if !minGold.Valid && !maxGold.Valid {
+		return 0
+	}
+	low, high := minGold.Int, maxGold.Int
+	if !minGold.Valid {
+		low = 0
+	}
+	if !maxGold.Valid {
+		high = low
+	}
+	if low < 0 {
+		low = 0
+	}
+	if high <= low {
+		return int64(low)
+	}
+	return int64(low + rand.Intn(high-low+1))
+}
+
+// battleMonster 战斗中的怪物及其实际等级
+type battleMonster struct {
+	monster *game_config.Monster
+	level   int
+}
+
+// loadBattleMonsters 解析战斗配置（dungeon_battles.monster_setup）中的怪物，等级以 level_override 为准
+func (s *ItemDropService) loadBattleMonsters(ctx context.Context, battleCode string) ([]battleMonster, error) {
+	battle, err := s.resolveBattle(ctx, battleCode)
+	if err != nil {
+		return nil, err
+	}
+	var setups []battleMonsterSetup
+	if len(battle.MonsterSetup) > 0 {
+		if err := json.Unmarshal(battle.MonsterSetup, &setups); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, "战斗怪物配置错误")
+		}
+	}
+
+	monsters := make([]battleMonster, 0, len(setups))
+	for _, setup := range setups {
+		monster, err := s.monsterRepo.GetByCode(ctx, setup.MonsterCode)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, fmt.Sprintf("怪物 %s 不存在", setup.MonsterCode))
+		}
+		level := int(monster.MonsterLevel)
+		if setup.LevelOverride != nil && *setup.LevelOverride > 0 {
+			level = *setup.LevelOverride
+		}
+		monsters = append(monsters, battleMonster{monster: monster, level: level})
+	}
+	return monsters, nil
+}
+
+// BattleExperience 战斗胜利的经验总额（怪物 drop_exp 之和）及怪物等级区间
+type BattleExperience struct {
+	TotalXP  int64
+	MinLevel int
+	MaxLevel int
+}
+
+// GetBattleExperience 计算战斗配置的经验总额，供经验发放按等级差衰减
+func (s *ItemDropService) GetBattleExperience(ctx context.Context, battleCode string) (*BattleExperience, error) {
+	monsters, err := s.loadBattleMonsters(ctx, battleCode)
+	if err != nil {
+		return nil, err
+	}
+	exp := &BattleExperience{}
+	for i, entry := range monsters {
+		if entry.monster.DropExp.Valid {
+			exp.TotalXP += int64(entry.monster.DropExp.Int)
+		}
+		if i == 0 || entry.level < exp.MinLevel {
+			exp.MinLevel = entry.level
+		}
+		if entry.level > exp.MaxLevel {
+			exp.MaxLevel = entry.level
+		}
+	}
+	return exp, nil
+}
+
+// resolveBattle 按战斗代码/ID 查找战斗配置，找不到时尝试按房间代码解析 trigger_id
+func (s *ItemDropService) resolveBattle(ctx context.Context, ref string) (*game_config.DungeonBattle, error) {
+	if battle, err := s.dungeonBattleRepo.GetByCode(ctx, ref); err == nil {
+		return battle, nil
+	}
+	if battle, err := s.dungeonBattleRepo.GetByID(ctx, ref); err == nil {
+		return battle, nil
+	}
+	if room, err := s.dungeonRoomRepo.GetByCode(ctx, ref); err == nil && room.TriggerID.Valid {
+		if battle, err := s.dungeonBattleRepo.GetByCode(ctx, room.TriggerID.String); err == nil {
+			return battle, nil
+		}
+		if battle, err := s.dungeonBattleRepo.GetByID(ctx, room.TriggerID.String); err == nil {
+			return battle, nil
+		}
+	}
+	return nil, xerrors.New(xerrors.CodeResourceNotFound, fmt.Sprintf("战斗配置 %s 不存在", ref))
+}
+
+// partyLevel 返回团队平均等级与人数
+func (s *ItemDropService) partyLevel(ctx context.Context, teamID string) (int, int) {
+	if teamID == "" {
+		return 1, 1
+	}
+	members, err := s.teamMemberRepo.ListByTeam(ctx, teamID)
+	if err != nil || len(members) == 0 {
+		return 1, 1
+	}
+	total := 0
+	for _, member := range members {
+		if hero, err := s.heroRepo.GetByID(ctx, member.HeroID); err == nil {
+			total += int(hero.CurrentLevel)
+		}
+	}
+	level := total / len(members)
+	if level < 1 {
+		level = 1
+	}
+	return level, len(members)
+}
+
 func init() {
 	// 初始化随机数种子
 	rand.Seed(time.Now().UnixNano())
@@ -0,0 +1,416 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/aarondl/null/v8"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/impl"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// 消耗品使用效果类型（对应 effect_type_definitions.effect_type_code）
+const (
+	UseEffectAddExperience   = "ADD_EXPERIENCE"
+	UseEffectAddGold         = "ADD_GOLD"
+	UseEffectResetAttributes = "RESET_ATTRIBUTES"
+	UseEffectResetSkills     = "RESET_SKILLS"
+	UseEffectApplyBuff       = "APPLY_BUFF"
+)
+
+// ItemUseService 消耗品使用服务，按 items.use_effects 执行效果
+type ItemUseService struct {
+	db                   *sql.DB
+	itemRepo             interfaces.ItemRepository
+	playerItemRepo       interfaces.PlayerItemRepository
+	heroRepo             interfaces.HeroRepository
+	heroCurrencyRepo     interfaces.HeroCurrencyRepository
+	effectTypeRepo       interfaces.EffectTypeDefinitionRepository
+	buffRepo             interfaces.BuffRepository
+	activeBuffRepo       interfaces.HeroActiveBuffRepository
+	itemOpLogRepo        interfaces.ItemOperationLogRepository
+	heroService          *HeroService
+	attributeInvalidator heroAttributeInvalidator // 可选，使用物品后失效英雄属性缓存
+	rng                  func(n int64) int64
+}
+
+// NewItemUseService 创建消耗品使用服务
+func NewItemUseService(db *sql.DB) *ItemUseService {
+	return &ItemUseService{
+		db:               db,
+		itemRepo:         impl.NewItemRepository(db),
+		playerItemRepo:   impl.NewPlayerItemRepository(db),
+		heroRepo:         impl.NewHeroRepository(db),
+		heroCurrencyRepo: impl.NewHeroCurrencyRepository(db),
+		effectTypeRepo:   impl.NewEffectTypeDefinitionRepository(db),
+		buffRepo:         impl.NewBuffRepository(db),
+		activeBuffRepo:   impl.NewHeroActiveBuffRepository(db),
+		itemOpLogRepo:    impl.NewItemOperationLogRepository(db),
+		heroService:      NewHeroService(db),
+		rng:              rand.Int63n,
+	}
+}
+
+// UseItemRequest 使用物品请求
+type UseItemRequest struct {
+	HeroID         string
+	ItemInstanceID string
+}
+
+// UseItemResult 使用物品结果
+type UseItemResult struct {
+	ItemInstanceID string
+	ItemID         string
+	RemainingCount int // 剩余堆叠数量，0 表示已用完
+	Effects        []*AppliedUseEffect
+}
+
+// AppliedUseEffect 单个使用效果的执行结果
+type AppliedUseEffect struct {
+	Effect           string     `json:"effect"`
+	Applied          bool       `json:"applied"`
+	Skipped          string     `json:"skipped,omitempty"` // 跳过原因（failure_handling=continue）
+	ExperienceGained int64      `json:"experience_gained,omitempty"`
+	GoldGained       int64      `json:"gold_gained,omitempty"`
+	RefundedXP       int64      `json:"refunded_xp,omitempty"`
+	BuffCode         string     `json:"buff_code,omitempty"`
+	BuffExpiresAt    *time.Time `json:"buff_expires_at,omitempty"`
+}
+
+// useEffect use_effects 中的单个效果，格式与 in_combat_effects 一致
+type useEffect struct {
+	Effect string                     `json:"Effect"`
+	Params map[string]json.RawMessage `json:"params"`
+}
+
+// useEffectPlan 校验通过（或按 continue 跳过）的效果
+type useEffectPlan struct {
+	effect  useEffect
+	skipped string
+}
+
+// itemUseState 使用日志中的物品状态
+type itemUseState struct {
+	StackCount int                 `json:"stack_count"`
+	UsedCount  int                 `json:"used_count"`
+	Effects    []*AppliedUseEffect `json:"effects,omitempty"`
+}
+
+// UseItem 使用背包中的消耗品
+// 在同一事务内校验并执行全部使用效果、扣减一个堆叠并记录使用日志；任一效果失败时物品不被消耗
+func (s *ItemUseService) UseItem(ctx context.Context, req *UseItemRequest) (*UseItemResult, error) {
+	// 1. 验证参数
+	if req.HeroID == "" || req.ItemInstanceID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "英雄ID和物品实例ID不能为空")
+	}
+	hero, err := s.heroRepo.GetByID(ctx, req.HeroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+	}
+
+	// 2. 开启事务
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			// 仅当 Rollback 失败且不是已提交的事务时，才表示有问题
+		}
+	}()
+
+	// 3. 锁定物品实例并校验归属
+	item, err := s.playerItemRepo.GetByIDForUpdate(ctx, tx, req.ItemInstanceID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "物品实例不存在")
+	}
+	if item.OwnerID != hero.UserID || (item.HeroID.Valid && item.HeroID.String != hero.ID) {
+		return nil, xerrors.New(xerrors.CodePermissionDenied, "该物品不属于您")
+	}
+	if item.ItemLocation != "backpack" {
+		return nil, xerrors.New(xerrors.CodeOperationNotAllowed, "只能使用背包中的物品")
+	}
+
+	// 4. 校验物品配置与使用条件
+	itemConfig, err := s.itemRepo.GetByID(ctx, item.ItemID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "物品配置不存在")
+	}
+	effects, err := parseUseEffects(itemConfig.UseEffects)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, "物品使用效果配置错误")
+	}
+	if len(effects) == 0 {
+		return nil, xerrors.New(xerrors.CodeOperationNotAllowed, "该物品不可使用")
+	}
+	if itemConfig.RequiredLevel.Valid && hero.CurrentLevel < itemConfig.RequiredLevel.Int16 {
+		return nil, xerrors.New(xerrors.CodeInsufficientLevel,
+			fmt.Sprintf("等级不足: 需要 %d 级", itemConfig.RequiredLevel.Int16))
+	}
+
+	// 5. 按元效果类型定义校验效果
+	definitions, err := s.loadEffectDefinitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plans, err := planUseEffects(effects, definitions)
+	if err != nil {
+		return nil, err
+	}
+
+	// 6. 执行效果
+	result := &UseItemResult{ItemInstanceID: item.ID, ItemID: item.ItemID}
+	for _, plan := range plans {
+		if plan.skipped != "" {
+			result.Effects = append(result.Effects, &AppliedUseEffect{Effect: plan.effect.Effect, Skipped: plan.skipped})
+			continue
+		}
+		applied, err := s.applyUseEffect(ctx, tx, hero, item, plan.effect)
+		if err != nil {
+			return nil, err
+		}
+		result.Effects = append(result.Effects, applied)
+	}
+
+	// 7. 扣减堆叠并累加使用次数
+	before := itemUseState{StackCount: itemStackCount(item), UsedCount: int(item.UsedCount.Int)}
+	consumption, err := planStackConsumption([]*game_runtime.PlayerItem{item}, 1)
+	if err != nil {
+		return nil, err
+	}
+	item.UsedCount = null.IntFrom(before.UsedCount + 1)
+	if err := applyStackConsumption(ctx, s.playerItemRepo, tx, consumption); err != nil {
+		return nil, err
+	}
+	result.RemainingCount = consumption[0].remaining
+
+	// 8. 记录使用日志
+	after := itemUseState{StackCount: result.RemainingCount, UsedCount: before.UsedCount + 1, Effects: result.Effects}
+	if err := writeItemOperationLog(ctx, s.itemOpLogRepo, tx, item.ID, "use", hero.UserID, before, after); err != nil {
+		return nil, err
+	}
+
+	// 9. 提交事务
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	if s.attributeInvalidator != nil {
+		s.attributeInvalidator.InvalidateComputedAttributes(ctx, hero.ID)
+	}
+	return result, nil
+}
+
+// loadEffectDefinitions 加载启用的元效果类型定义
+func (s *ItemUseService) loadEffectDefinitions(ctx context.Context) (map[string]*game_config.EffectTypeDefinition, error) {
+	defs, err := s.effectTypeRepo.GetAll(ctx)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询元效果类型定义失败")
+	}
+	definitions := make(map[string]*game_config.EffectTypeDefinition, len(defs))
+	for _, def := range defs {
+		definitions[def.EffectTypeCode] = def
+	}
+	return definitions, nil
+}
+
+// applyUseEffect 执行单个使用效果
+func (s *ItemUseService) applyUseEffect(ctx context.Context, tx *sql.Tx, hero *game_runtime.Hero, item *game_runtime.PlayerItem, effect useEffect) (*AppliedUseEffect, error) {
+	applied := &AppliedUseEffect{Effect: effect.Effect, Applied: true}
+	switch effect.Effect {
+	case UseEffectAddExperience:
+		amount, _, _ := useEffectInt(effect.Params, "amount")
+		if _, err := s.heroService.AddExperienceTx(ctx, tx, hero.ID, amount); err != nil {
+			return nil, err
+		}
+		applied.ExperienceGained = amount
+
+	case UseEffectAddGold:
+		amount, _, _ := useEffectInt(effect.Params, "amount")
+		if maxAmount, ok, _ := useEffectInt(effect.Params, "amount_max"); ok && maxAmount > amount {
+			amount += s.rng(maxAmount - amount + 1)
+		}
+		if amount > 0 {
+			if _, err := applyCurrencyTx(ctx, s.heroCurrencyRepo, tx, CurrencyChange{
+				HeroID:     hero.ID,
+				Currency:   interfaces.CurrencyGold,
+				Amount:     amount,
+				SourceType: CurrencySourceItemUse,
+				SourceID:   item.ID,
+			}); err != nil {
+				return nil, err
+			}
+		}
+		applied.GoldGained = amount
+
+	case UseEffectResetAttributes, UseEffectResetSkills:
+		locked, err := s.heroRepo.GetByIDForUpdate(ctx, tx, hero.ID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+		}
+		var reset *heroResetResult
+		if effect.Effect == UseEffectResetAttributes {
+			reset, err = s.heroService.resetAllocatedAttributes(ctx, tx, locked)
+		} else {
+			reset, err = s.heroService.resetLearnedSkills(ctx, tx, locked)
+		}
+		if err != nil {
+			return nil, err
+		}
+		applied.RefundedXP = reset.RefundedXP
+
+	case UseEffectApplyBuff:
+		buff, err := s.newItemBuff(ctx, hero, item, effect.Params)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.activeBuffRepo.Create(ctx, tx, buff); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "施加Buff失败")
+		}
+		applied.BuffCode = buff.BuffCode
+		applied.BuffExpiresAt = buff.ExpiresAt
+
+	default:
+		return nil, xerrors.New(xerrors.CodeDataIntegrityError, fmt.Sprintf("不支持的使用效果: %s", effect.Effect))
+	}
+	return applied, nil
+}
+
+// newItemBuff 根据 APPLY_BUFF 参数构造限时 Buff，buff_code/duration_seconds 以外的参数作为 buff_params
+func (s *ItemUseService) newItemBuff(ctx context.Context, hero *game_runtime.Hero, item *game_runtime.PlayerItem, params map[string]json.RawMessage) (*interfaces.HeroActiveBuff, error) {
+	var buffCode string
+	if err := json.Unmarshal(params["buff_code"], &buffCode); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, "Buff代码配置错误")
+	}
+	duration, _, _ := useEffectInt(params, "duration_seconds")
+	buffParams, err := itemBuffParams(params)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, "Buff参数配置错误")
+	}
+
+	var buffID *string
+	if buff, err := s.buffRepo.GetByCode(ctx, buffCode); err == nil && buff != nil {
+		buffID = &buff.ID
+	}
+	expiresAt := time.Now().Add(time.Duration(duration) * time.Second)
+	return &interfaces.HeroActiveBuff{
+		HeroID:      hero.ID,
+		BuffID:      buffID,
+		BuffCode:    buffCode,
+		BuffParams:  buffParams,
+		CasterLevel: int(hero.CurrentLevel),
+		SourceType:  "item",
+		SourceID:    &item.ID,
+		ExpiresAt:   &expiresAt,
+	}, nil
+}
+
+// parseUseEffects 解析 items.use_effects
+func parseUseEffects(raw null.JSON) ([]useEffect, error) {
+	if !raw.Valid || len(raw.JSON) == 0 || string(raw.JSON) == "null" {
+		return nil, nil
+	}
+	var effects []useEffect
+	if err := json.Unmarshal(raw.JSON, &effects); err != nil {
+		return nil, err
+	}
+	return effects, nil
+}
+
+// planUseEffects 按元效果类型定义校验效果
+// 未注册/未启用的效果视为配置错误；参数不合法时按 failure_handling 决定跳过（continue）或整次失败
+func planUseEffects(effects []useEffect, definitions map[string]*game_config.EffectTypeDefinition) ([]useEffectPlan, error) {
+	plans := make([]useEffectPlan, 0, len(effects))
+	for _, effect := range effects {
+		def, ok := definitions[effect.Effect]
+		if !ok {
+			return nil, xerrors.New(xerrors.CodeDataIntegrityError, fmt.Sprintf("未定义的使用效果: %s", effect.Effect))
+		}
+		if err := validateUseEffectParams(effect, def); err != nil {
+			if def.FailureHandling.Valid && def.FailureHandling.String == "continue" {
+				plans = append(plans, useEffectPlan{effect: effect, skipped: err.Error()})
+				continue
+			}
+			return nil, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, "物品使用效果配置错误")
+		}
+		plans = append(plans, useEffectPlan{effect: effect})
+	}
+	return plans, nil
+}
+
+// validateUseEffectParams 校验必填参数（parameter_list）及已知效果的参数取值
+func validateUseEffectParams(effect useEffect, def *game_config.EffectTypeDefinition) error {
+	for _, name := range def.ParameterList {
+		if _, ok := effect.Params[name]; !ok {
+			return fmt.Errorf("%s 缺少参数 %s", effect.Effect, name)
+		}
+	}
+
+	switch effect.Effect {
+	case UseEffectAddExperience, UseEffectAddGold:
+		amount, ok, err := useEffectInt(effect.Params, "amount")
+		if err != nil || !ok || amount <= 0 {
+			return fmt.Errorf("%s 参数 amount 必须为正整数", effect.Effect)
+		}
+		if _, _, err := useEffectInt(effect.Params, "amount_max"); err != nil {
+			return fmt.Errorf("%s 参数 amount_max 必须为整数", effect.Effect)
+		}
+	case UseEffectApplyBuff:
+		var buffCode string
+		if err := json.Unmarshal(effect.Params["buff_code"], &buffCode); err != nil || buffCode == "" {
+			return fmt.Errorf("%s 参数 buff_code 不能为空", effect.Effect)
+		}
+		duration, ok, err := useEffectInt(effect.Params, "duration_seconds")
+		if err != nil || !ok || duration <= 0 {
+			return fmt.Errorf("%s 参数 duration_seconds 必须为正整数", effect.Effect)
+		}
+		buffParams, err := itemBuffParams(effect.Params)
+		if err != nil {
+			return fmt.Errorf("%s 参数错误: %v", effect.Effect, err)
+		}
+		if _, err := parseBuffModifiers(buffParams); err != nil {
+			return fmt.Errorf("%s 属性修正参数错误: %v", effect.Effect, err)
+		}
+	}
+	return nil
+}
+
+// useEffectInt 读取整数参数，返回值、是否存在及解析错误
+func useEffectInt(params map[string]json.RawMessage, key string) (int64, bool, error) {
+	raw, ok := params[key]
+	if !ok {
+		return 0, false, nil
+	}
+	var value int64
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return 0, true, err
+	}
+	return value, true, nil
+}
+
+// itemBuffParams 去掉 APPLY_BUFF 的控制参数，剩余部分作为 buff_params
+func itemBuffParams(params map[string]json.RawMessage) (json.RawMessage, error) {
+	rest := make(map[string]json.RawMessage, len(params))
+	for key, value := range params {
+		if key == "buff_code" || key == "duration_seconds" {
+			continue
+		}
+		rest[key] = value
+	}
+	return json.Marshal(rest)
+}
+
+// itemStackCount 物品堆叠数量（未设置视为 1）
+func itemStackCount(item *game_runtime.PlayerItem) int {
+	if item.StackCount.Valid {
+		return item.StackCount.Int
+	}
+	return 1
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aarondl/null/v8"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/entity/game_runtime"
+)
+
+func testEffectDefinitions() map[string]*game_config.EffectTypeDefinition {
+	return map[string]*game_config.EffectTypeDefinition{
+		UseEffectAddExperience:   {EffectTypeCode: UseEffectAddExperience, ParameterList: []string{"amount"}},
+		UseEffectAddGold:         {EffectTypeCode: UseEffectAddGold, ParameterList: []string{"amount"}, FailureHandling: null.StringFrom("continue")},
+		UseEffectResetAttributes: {EffectTypeCode: UseEffectResetAttributes},
+		UseEffectApplyBuff:       {EffectTypeCode: UseEffectApplyBuff, ParameterList: []string{"buff_code", "duration_seconds"}},
+	}
+}
+
+func TestParseUseEffects(t *testing.T) {
+	effects, err := parseUseEffects(null.JSONFrom([]byte(`[{"Effect":"ADD_EXPERIENCE","params":{"amount":500}},{"Effect":"RESET_ATTRIBUTES"}]`)))
+	require.NoError(t, err)
+	require.Len(t, effects, 2)
+	require.Equal(t, UseEffectAddExperience, effects[0].Effect)
+	require.JSONEq(t, `500`, string(effects[0].Params["amount"]))
+
+	effects, err = parseUseEffects(null.JSON{})
+	require.NoError(t, err)
+	require.Empty(t, effects)
+
+	_, err = parseUseEffects(null.JSONFrom([]byte(`{"Effect":"ADD_GOLD"}`)))
+	require.Error(t, err)
+}
+
+func TestPlanUseEffects(t *testing.T) {
+	defs := testEffectDefinitions()
+	parse := func(raw string) []useEffect {
+		effects, err := parseUseEffects(null.JSONFrom([]byte(raw)))
+		require.NoError(t, err)
+		return effects
+	}
+
+	plans, err := planUseEffects(parse(`[
+		{"Effect":"ADD_EXPERIENCE","params":{"amount":500}},
+		{"Effect":"APPLY_BUFF","params":{"buff_code":"STR_ELIXIR","duration_seconds":600,"attribute_code":"STR","value":5}},
+		{"Effect":"RESET_ATTRIBUTES"}
+	]`), defs)
+	require.NoError(t, err)
+	require.Len(t, plans, 3)
+	for _, plan := range plans {
+		require.Empty(t, plan.skipped)
+	}
+
+	// 未注册的效果
+	_, err = planUseEffects(parse(`[{"Effect":"TELEPORT"}]`), defs)
+	require.Error(t, err)
+
+	// 缺少必填参数，默认整次失败
+	_, err = planUseEffects(parse(`[{"Effect":"ADD_EXPERIENCE","params":{}}]`), defs)
+	require.Error(t, err)
+
+	// 参数非法且 failure_handling=continue 时跳过
+	plans, err = planUseEffects(parse(`[{"Effect":"ADD_GOLD","params":{"amount":-1}},{"Effect":"ADD_EXPERIENCE","params":{"amount":10}}]`), defs)
+	require.NoError(t, err)
+	require.Len(t, plans, 2)
+	require.NotEmpty(t, plans[0].skipped)
+	require.Empty(t, plans[1].skipped)
+
+	// Buff 持续时间必须为正
+	_, err = planUseEffects(parse(`[{"Effect":"APPLY_BUFF","params":{"buff_code":"X","duration_seconds":0}}]`), defs)
+	require.Error(t, err)
+}
+
+func TestItemBuffParams(t *testing.T) {
+	params := map[string]json.RawMessage{
+		"buff_code":        json.RawMessage(`"STR_ELIXIR"`),
+		"duration_seconds": json.RawMessage(`600`),
+		"attribute_code":   json.RawMessage(`"STR"`),
+		"value":            json.RawMessage(`5`),
+	}
+	raw, err := itemBuffParams(params)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"attribute_code":"STR","value":5}`, string(raw))
+
+	modifiers, err := parseBuffModifiers(raw)
+	require.NoError(t, err)
+	require.Len(t, modifiers, 1)
+	require.Equal(t, "STR", modifiers[0].AttributeCode)
+}
+
+func TestSkillSpentXP(t *testing.T) {
+	ops := []*game_runtime.HeroSkillOperation{
+		{XPSpent: 100},
+		{XPSpent: 200, RolledBackAt: null.TimeFrom(time.Now())},
+		{XPSpent: 50},
+	}
+	require.EqualValues(t, 150, skillSpentXP(ops))
+	require.True(t, isInitialHeroSkill(&game_runtime.HeroSkill{LearnedMethod: null.StringFrom("class_unlock")}))
+	require.False(t, isInitialHeroSkill(&game_runtime.HeroSkill{LearnedMethod: null.StringFrom("manual")}))
+	require.True(t, isManualHeroSkill(&game_runtime.HeroSkill{LearnedMethod: null.StringFrom("manual")}))
+	require.False(t, isManualHeroSkill(&game_runtime.HeroSkill{LearnedMethod: null.StringFrom("equipment")}))
+	require.False(t, isManualHeroSkill(&game_runtime.HeroSkill{LearnedMethod: null.StringFrom("quest")}))
+}
@@ -21,3 +21,16 @@ type LootEvent struct {
 	Result      string                   `json:"result"` // success|failed
 	Reason      string                   `json:"reason,omitempty"`
 }
+
+// EnhancementEvent 装备强化通知事件
+type EnhancementEvent struct {
+	HeroID         string `json:"hero_id"`
+	ItemInstanceID string `json:"item_instance_id"`
+	ItemID         string `json:"item_id"`
+	Result         string `json:"result"` // success|failed|downgraded
+	LevelBefore    int    `json:"level_before"`
+	LevelAfter     int    `json:"level_after"`
+	GoldCost       int64  `json:"gold_cost"`
+	MaterialID     string `json:"material_id,omitempty"`
+	MaterialCost   int    `json:"material_cost"`
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/impl"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// 团队动态默认配置
+const (
+	maxTeamActivityPageSize = 100
+	teamActivityPurgeBatch  = 1000
+)
+
+// teamActivityCategories 事件类型所属分类
+var teamActivityCategories = map[string]string{
+	interfaces.TeamActivityTeamCreated:       interfaces.TeamActivityCategoryTeam,
+	interfaces.TeamActivityTeamUpdated:       interfaces.TeamActivityCategoryTeam,
+	interfaces.TeamActivityTeamDisbanded:     interfaces.TeamActivityCategoryTeam,
+	interfaces.TeamActivityLeaderTransferred: interfaces.TeamActivityCategoryTeam,
+
+	interfaces.TeamActivityJoinRequested:      interfaces.TeamActivityCategoryMember,
+	interfaces.TeamActivityJoinApproved:       interfaces.TeamActivityCategoryMember,
+	interfaces.TeamActivityJoinRejected:       interfaces.TeamActivityCategoryMember,
+	interfaces.TeamActivityMemberInvited:      interfaces.TeamActivityCategoryMember,
+	interfaces.TeamActivityInvitationApproved: interfaces.TeamActivityCategoryMember,
+	interfaces.TeamActivityInvitationRejected: interfaces.TeamActivityCategoryMember,
+	interfaces.TeamActivityInvitationAccepted: interfaces.TeamActivityCategoryMember,
+	interfaces.TeamActivityInvitationDeclined: interfaces.TeamActivityCategoryMember,
+	interfaces.TeamActivityMemberLeft:         interfaces.TeamActivityCategoryMember,
+	interfaces.TeamActivityMemberKicked:       interfaces.TeamActivityCategoryMember,
+	interfaces.TeamActivityAdminPromoted:      interfaces.TeamActivityCategoryMember,
+	interfaces.TeamActivityAdminDemoted:       interfaces.TeamActivityCategoryMember,
+
+	interfaces.TeamActivityRankCreated:  interfaces.TeamActivityCategoryRank,
+	interfaces.TeamActivityRankUpdated:  interfaces.TeamActivityCategoryRank,
+	interfaces.TeamActivityRankDeleted:  interfaces.TeamActivityCategoryRank,
+	interfaces.TeamActivityRankAssigned: interfaces.TeamActivityCategoryRank,
+
+	interfaces.TeamActivityLootStored:          interfaces.TeamActivityCategoryWarehouse,
+	interfaces.TeamActivityGoldDistributed:     interfaces.TeamActivityCategoryWarehouse,
+	interfaces.TeamActivityItemsDistributed:    interfaces.TeamActivityCategoryWarehouse,
+	interfaces.TeamActivityGoldDeposited:       interfaces.TeamActivityCategoryWarehouse,
+	interfaces.TeamActivityItemsDeposited:      interfaces.TeamActivityCategoryWarehouse,
+	interfaces.TeamActivityWithdrawalRequested: interfaces.TeamActivityCategoryWarehouse,
+	interfaces.TeamActivityWithdrawalApproved:  interfaces.TeamActivityCategoryWarehouse,
+	interfaces.TeamActivityWithdrawalRejected:  interfaces.TeamActivityCategoryWarehouse,
+	interfaces.TeamActivityWithdrawalCancelled: interfaces.TeamActivityCategoryWarehouse,
+	interfaces.TeamActivityLootPolicyUpdated:   interfaces.TeamActivityCategoryWarehouse,
+	interfaces.TeamActivityLootRollResolved:    interfaces.TeamActivityCategoryWarehouse,
+
+	interfaces.TeamActivityContributionSettingsUpdated: interfaces.TeamActivityCategoryContribution,
+	interfaces.TeamActivityContributionGranted:         interfaces.TeamActivityCategoryContribution,
+	interfaces.TeamActivityAuctionCreated:              interfaces.TeamActivityCategoryContribution,
+	interfaces.TeamActivityAuctionCancelled:            interfaces.TeamActivityCategoryContribution,
+	interfaces.TeamActivityAuctionSettled:              interfaces.TeamActivityCategoryContribution,
+
+	interfaces.TeamActivityDungeonSelected:  interfaces.TeamActivityCategoryDungeon,
+	interfaces.TeamActivityDungeonEntered:   interfaces.TeamActivityCategoryDungeon,
+	interfaces.TeamActivityDungeonCompleted: interfaces.TeamActivityCategoryDungeon,
+	interfaces.TeamActivityDungeonFailed:    interfaces.TeamActivityCategoryDungeon,
+	interfaces.TeamActivityDungeonAbandoned: interfaces.TeamActivityCategoryDungeon,
+}
+
+// isTeamActivityCategory 是否为已知的动态分类
+func isTeamActivityCategory(category string) bool {
+	for _, known := range teamActivityCategories {
+		if known == category {
+			return true
+		}
+	}
+	return false
+}
+
+// teamActivityEntry 待写入的团队动态，空字符串字段写入为 NULL
+type teamActivityEntry struct {
+	TeamID       string
+	EventType    string
+	ActorHeroID  string
+	TargetHeroID string
+	SourceID     string
+	Details      map[string]interface{}
+}
+
+// build 转换为仓储记录，分类由事件类型决定
+func (e teamActivityEntry) build() (*interfaces.TeamActivity, error) {
+	category, ok := teamActivityCategories[e.EventType]
+	if !ok {
+		return nil, fmt.Errorf("未知的团队动态类型: %s", e.EventType)
+	}
+	activity := &interfaces.TeamActivity{
+		TeamID:       e.TeamID,
+		Category:     category,
+		EventType:    e.EventType,
+		ActorHeroID:  optionalString(e.ActorHeroID),
+		TargetHeroID: optionalString(e.TargetHeroID),
+		SourceID:     optionalString(e.SourceID),
+	}
+	if len(e.Details) > 0 {
+		details, err := json.Marshal(e.Details)
+		if err != nil {
+			return nil, fmt.Errorf("序列化团队动态详情失败: %w", err)
+		}
+		activity.Details = details
+	}
+	return activity, nil
+}
+
+// recordTeamActivity 在业务事务内写入团队动态，写入失败时由调用方回滚事务；仓储未配置时跳过
+func recordTeamActivity(ctx context.Context, repo interfaces.TeamActivityRepository, execer boil.ContextExecutor, entry teamActivityEntry) error {
+	if repo == nil {
+		return nil
+	}
+	activity, err := entry.build()
+	if err != nil {
+		return err
+	}
+	return repo.Create(ctx, execer, activity)
+}
+
+// logTeamActivity 用于没有事务的单条写入流程，业务已生效后追加动态，失败只记录警告
+func logTeamActivity(ctx context.Context, repo interfaces.TeamActivityRepository, entry teamActivityEntry) {
+	if err := recordTeamActivity(ctx, repo, nil, entry); err != nil {
+		fmt.Printf("Warning: Failed to record team activity %s for team %s: %v\n", entry.EventType, entry.TeamID, err)
+	}
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// TeamActivityService 团队动态服务：成员查看团队时间线，定时任务清理过期动态
+type TeamActivityService struct {
+	teamMemberRepo   interfaces.TeamMemberRepository
+	teamRankRepo     interfaces.TeamRankRepository
+	teamActivityRepo interfaces.TeamActivityRepository
+	now              func() time.Time
+}
+
+// NewTeamActivityService 创建团队动态服务
+func NewTeamActivityService(db *sql.DB) *TeamActivityService {
+	return &TeamActivityService{
+		teamMemberRepo:   impl.NewTeamMemberRepository(db),
+		teamRankRepo:     impl.NewTeamRankRepository(db),
+		teamActivityRepo: impl.NewTeamActivityRepository(db),
+		now:              time.Now,
+	}
+}
+
+// ListTeamActivityRequest 查询团队动态请求
+type ListTeamActivityRequest struct {
+	TeamID       string
+	HeroID       string // 查询者英雄ID
+	Category     string // 为空时不过滤
+	EventType    string // 为空时不过滤
+	FilterHeroID string // 只看与该英雄相关（操作者或受影响者）的动态
+	Since        *time.Time
+	Until        *time.Time
+	Limit        int
+	Offset       int
+}
+
+// ListActivity 分页查询团队动态（需要 view_team_info 权限），按时间倒序
+func (s *TeamActivityService) ListActivity(ctx context.Context, req *ListTeamActivityRequest) ([]*interfaces.TeamActivity, int64, error) {
+	// 1. 验证参数
+	if req.TeamID == "" || req.HeroID == "" {
+		return nil, 0, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+	if req.Category != "" && !isTeamActivityCategory(req.Category) {
+		return nil, 0, xerrors.New(xerrors.CodeInvalidParams, "未知的动态分类")
+	}
+	if req.EventType != "" {
+		if _, ok := teamActivityCategories[req.EventType]; !ok {
+			return nil, 0, xerrors.New(xerrors.CodeInvalidParams, "未知的动态类型")
+		}
+	}
+	if req.Since != nil && req.Until != nil && !req.Since.Before(*req.Until) {
+		return nil, 0, xerrors.New(xerrors.CodeInvalidParams, "开始时间必须早于结束时间")
+	}
+	limit := req.Limit
+	if limit > maxTeamActivityPageSize {
+		limit = maxTeamActivityPageSize
+	}
+
+	// 2. 检查权限
+	member, err := s.teamMemberRepo.GetByTeamAndHero(ctx, req.TeamID, req.HeroID)
+	if err != nil {
+		return nil, 0, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
+	}
+	if _, err := requireTeamPermission(ctx, s.teamRankRepo, member, TeamPermViewTeamInfo, "您没有查看团队动态的权限"); err != nil {
+		return nil, 0, err
+	}
+
+	// 3. 查询
+	list, total, err := s.teamActivityRepo.List(ctx, interfaces.TeamActivityQuery{
+		TeamID:    req.TeamID,
+		Category:  req.Category,
+		EventType: req.EventType,
+		HeroID:    req.FilterHeroID,
+		Since:     req.Since,
+		Until:     req.Until,
+		Limit:     limit,
+		Offset:    req.Offset,
+	})
+	if err != nil {
+		return nil, 0, xerrors.Wrap(err, xerrors.CodeInternalError, "查询团队动态失败")
+	}
+	return list, total, nil
+}
+
+// PurgeExpired 分批删除超过保留时长的团队动态（定时任务调用），返回删除条数
+func (s *TeamActivityService) PurgeExpired(ctx context.Context, retention time.Duration) (int64, error) {
+	if retention <= 0 {
+		return 0, fmt.Errorf("保留时长必须大于 0")
+	}
+	before := s.now().Add(-retention)
+
+	var total int64
+	for {
+		deleted, err := s.teamActivityRepo.DeleteBefore(ctx, before, teamActivityPurgeBatch)
+		if err != nil {
+			return total, err
+		}
+		total += deleted
+		if deleted < teamActivityPurgeBatch {
+			return total, nil
+		}
+	}
+}
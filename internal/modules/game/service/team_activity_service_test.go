@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/repository/interfaces"
+)
+
+type fakeTeamActivityRepo struct {
+	created     []*interfaces.TeamActivity
+	lastQuery   interfaces.TeamActivityQuery
+	expired     int64
+	deleteCalls int
+}
+
+func (f *fakeTeamActivityRepo) Create(_ context.Context, _ boil.ContextExecutor, activity *interfaces.TeamActivity) error {
+	f.created = append(f.created, activity)
+	return nil
+}
+
+func (f *fakeTeamActivityRepo) List(_ context.Context, query interfaces.TeamActivityQuery) ([]*interfaces.TeamActivity, int64, error) {
+	f.lastQuery = query
+	return f.created, int64(len(f.created)), nil
+}
+
+func (f *fakeTeamActivityRepo) DeleteBefore(_ context.Context, _ time.Time, limit int) (int64, error) {
+	f.deleteCalls++
+	deleted := f.expired
+	if deleted > int64(limit) {
+		deleted = int64(limit)
+	}
+	f.expired -= deleted
+	return deleted, nil
+}
+
+func TestTeamActivityEntry_Build(t *testing.T) {
+	activity, err := teamActivityEntry{
+		TeamID:       "team-1",
+		EventType:    interfaces.TeamActivityGoldDistributed,
+		ActorHeroID:  "hero-leader",
+		TargetHeroID: "hero-member",
+		Details:      map[string]interface{}{"amount": 100},
+	}.build()
+	require.NoError(t, err)
+	assert.Equal(t, interfaces.TeamActivityCategoryWarehouse, activity.Category, "分类由事件类型决定")
+	require.NotNil(t, activity.ActorHeroID)
+	assert.Equal(t, "hero-leader", *activity.ActorHeroID)
+	assert.Nil(t, activity.SourceID, "空字符串写入为 NULL")
+	assert.JSONEq(t, `{"amount":100}`, string(activity.Details))
+
+	activity, err = teamActivityEntry{TeamID: "team-1", EventType: interfaces.TeamActivityDungeonFailed}.build()
+	require.NoError(t, err)
+	assert.Equal(t, interfaces.TeamActivityCategoryDungeon, activity.Category)
+	assert.Nil(t, activity.ActorHeroID, "系统触发时操作者为空")
+	assert.Nil(t, activity.Details)
+
+	_, err = teamActivityEntry{TeamID: "team-1", EventType: "unknown"}.build()
+	assert.Error(t, err)
+}
+
+func TestRecordTeamActivity(t *testing.T) {
+	ctx := context.Background()
+	entry := teamActivityEntry{TeamID: "team-1", EventType: interfaces.TeamActivityMemberLeft, ActorHeroID: "hero-1"}
+
+	assert.NoError(t, recordTeamActivity(ctx, nil, nil, entry), "未配置动态仓储时跳过")
+
+	repo := &fakeTeamActivityRepo{}
+	require.NoError(t, recordTeamActivity(ctx, repo, nil, entry))
+	require.Len(t, repo.created, 1)
+	assert.Equal(t, interfaces.TeamActivityCategoryMember, repo.created[0].Category)
+
+	err := recordTeamActivity(ctx, repo, nil, teamActivityEntry{TeamID: "team-1", EventType: "unknown"})
+	assert.Error(t, err, "未知事件类型由调用方回滚事务")
+	assert.Len(t, repo.created, 1)
+}
+
+func TestTeamActivityService_ListActivity(t *testing.T) {
+	ctx := context.Background()
+	members := &fakeTeamMemberRepo{members: map[string]*game_runtime.TeamMember{
+		"team-1:hero-member": {TeamID: "team-1", HeroID: "hero-member", Role: "member"},
+	}}
+	ranks := &fakeTeamRankRepo{memberRanks: map[string]*interfaces.TeamRank{}}
+	activities := &fakeTeamActivityRepo{created: []*interfaces.TeamActivity{
+		{ID: "activity-1", TeamID: "team-1", Category: interfaces.TeamActivityCategoryTeam, Details: json.RawMessage(`{}`)},
+	}}
+	svc := &TeamActivityService{teamMemberRepo: members, teamRankRepo: ranks, teamActivityRepo: activities, now: time.Now}
+
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(24 * time.Hour)
+	list, total, err := svc.ListActivity(ctx, &ListTeamActivityRequest{
+		TeamID:       "team-1",
+		HeroID:       "hero-member",
+		Category:     interfaces.TeamActivityCategoryWarehouse,
+		FilterHeroID: "hero-leader",
+		Since:        &since,
+		Until:        &until,
+		Limit:        500,
+		Offset:       40,
+	})
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, interfaces.TeamActivityQuery{
+		TeamID:   "team-1",
+		Category: interfaces.TeamActivityCategoryWarehouse,
+		HeroID:   "hero-leader",
+		Since:    &since,
+		Until:    &until,
+		Limit:    maxTeamActivityPageSize,
+		Offset:   40,
+	}, activities.lastQuery, "每页数量不超过上限")
+
+	tests := []struct {
+		name string
+		req  *ListTeamActivityRequest
+	}{
+		{"missing hero", &ListTeamActivityRequest{TeamID: "team-1"}},
+		{"unknown category", &ListTeamActivityRequest{TeamID: "team-1", HeroID: "hero-member", Category: "guild"}},
+		{"unknown event type", &ListTeamActivityRequest{TeamID: "team-1", HeroID: "hero-member", EventType: "unknown"}},
+		{"since not before until", &ListTeamActivityRequest{TeamID: "team-1", HeroID: "hero-member", Since: &until, Until: &since}},
+		{"not a member", &ListTeamActivityRequest{TeamID: "team-1", HeroID: "hero-stranger"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := svc.ListActivity(ctx, tt.req)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestTeamActivityService_PurgeExpired(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeTeamActivityRepo{expired: 2*teamActivityPurgeBatch + 5}
+	svc := &TeamActivityService{teamActivityRepo: repo, now: time.Now}
+
+	deleted, err := svc.PurgeExpired(ctx, 90*24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2*teamActivityPurgeBatch+5), deleted)
+	assert.Equal(t, 3, repo.deleteCalls, "删除不足一批时停止")
+
+	_, err = svc.PurgeExpired(ctx, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,795 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/pkg/notify"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/impl"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// 贡献点默认配置
+const (
+	defaultDungeonCompletionPoints = 10
+	defaultAuctionDurationSeconds  = 3600
+	contributionAuctionSettleBatch = 100
+
+	// 停留在结算中超过该时长（自到期起算）视为结算中断，重新结算
+	contributionAuctionSettlingStaleAfter = time.Minute
+)
+
+// TeamContributionService 团队贡献点（DKP）服务：通关与手动发放获得贡献点，出价竞拍仓库物品
+// 出价时冻结（扣除）点数，被超价或流拍时退还；到期后最高出价者通过 DistributeItems 获得物品
+type TeamContributionService struct {
+	db                    *sql.DB
+	teamMemberRepo        interfaces.TeamMemberRepository
+	teamWarehouseRepo     interfaces.TeamWarehouseRepository
+	teamWarehouseItemRepo interfaces.TeamWarehouseItemRepository
+	contributionRepo      interfaces.TeamContributionRepository
+	teamRankRepo          interfaces.TeamRankRepository
+	teamActivityRepo      interfaces.TeamActivityRepository
+	warehouseService      *TeamWarehouseService
+	now                   func() time.Time
+}
+
+// NewTeamContributionService 创建团队贡献点服务
+func NewTeamContributionService(db *sql.DB, warehouseService *TeamWarehouseService) *TeamContributionService {
+	if warehouseService == nil {
+		warehouseService = NewTeamWarehouseService(db)
+	}
+	return &TeamContributionService{
+		db:                    db,
+		teamMemberRepo:        impl.NewTeamMemberRepository(db),
+		teamWarehouseRepo:     impl.NewTeamWarehouseRepository(db),
+		teamWarehouseItemRepo: impl.NewTeamWarehouseItemRepository(db),
+		contributionRepo:      impl.NewTeamContributionRepository(db),
+		teamRankRepo:          impl.NewTeamRankRepository(db),
+		teamActivityRepo:      impl.NewTeamActivityRepository(db),
+		warehouseService:      warehouseService,
+		now:                   time.Now,
+	}
+}
+
+// ==================== 配置 ====================
+
+// GetSettings 查看贡献点配置（团队成员可查看），未配置时返回默认值
+func (s *TeamContributionService) GetSettings(ctx context.Context, teamID, heroID string) (*interfaces.TeamContributionSettings, error) {
+	if _, err := s.ensureMember(ctx, teamID, heroID); err != nil {
+		return nil, err
+	}
+	return s.loadSettings(ctx, teamID)
+}
+
+// UpdateContributionSettingsRequest 更新贡献点配置请求
+type UpdateContributionSettingsRequest struct {
+	TeamID                  string
+	HeroID                  string // 操作者英雄ID
+	DungeonCompletionPoints *int   // 通关每人获得的贡献点，nil 保持不变
+	AuctionDurationSeconds  *int   // 竞拍默认时长，nil 保持不变
+}
+
+// UpdateSettings 更新贡献点配置（需要 manage_contribution 权限）
+func (s *TeamContributionService) UpdateSettings(ctx context.Context, req *UpdateContributionSettingsRequest) (*interfaces.TeamContributionSettings, error) {
+	if _, err := s.ensurePermission(ctx, req.TeamID, req.HeroID, TeamPermManageContribution); err != nil {
+		return nil, err
+	}
+	settings, err := s.loadSettings(ctx, req.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	if req.DungeonCompletionPoints != nil {
+		if *req.DungeonCompletionPoints < 0 {
+			return nil, xerrors.New(xerrors.CodeInvalidParams, "通关贡献点不能为负数")
+		}
+		settings.DungeonCompletionPoints = *req.DungeonCompletionPoints
+	}
+	if req.AuctionDurationSeconds != nil {
+		if err := validateAuctionDuration(*req.AuctionDurationSeconds); err != nil {
+			return nil, err
+		}
+		settings.AuctionDurationSeconds = *req.AuctionDurationSeconds
+	}
+	if err := s.contributionRepo.UpsertSettings(ctx, settings); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "保存贡献点配置失败")
+	}
+	logTeamActivity(ctx, s.teamActivityRepo, teamActivityEntry{
+		TeamID:      req.TeamID,
+		EventType:   interfaces.TeamActivityContributionSettingsUpdated,
+		ActorHeroID: req.HeroID,
+		Details: map[string]interface{}{
+			"dungeon_completion_points": settings.DungeonCompletionPoints,
+			"auction_duration_seconds":  settings.AuctionDurationSeconds,
+		},
+	})
+	return settings, nil
+}
+
+// ==================== 获得与查询 ====================
+
+// GrantDungeonCompletionTx 通关地城时在同一事务内向当前成员发放贡献点
+func (s *TeamContributionService) GrantDungeonCompletionTx(ctx context.Context, tx *sql.Tx, progress *game_runtime.TeamDungeonProgress) error {
+	settings, err := s.loadSettings(ctx, progress.TeamID)
+	if err != nil {
+		return err
+	}
+	if settings.DungeonCompletionPoints <= 0 {
+		return nil
+	}
+	members, err := s.teamMemberRepo.ListByTeam(ctx, progress.TeamID)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "查询成员失败")
+	}
+
+	note := fmt.Sprintf("通关地城 %s", progress.DungeonID)
+	for _, member := range members {
+		entry := &interfaces.TeamContributionEntry{
+			TeamID:     progress.TeamID,
+			HeroID:     member.HeroID,
+			Amount:     int64(settings.DungeonCompletionPoints),
+			SourceType: interfaces.ContributionSourceDungeon,
+			SourceID:   &progress.ID,
+			Note:       &note,
+		}
+		if err := s.contributionRepo.ApplyTx(ctx, tx, entry); err != nil {
+			return xerrors.Wrap(err, xerrors.CodeInternalError, "发放通关贡献点失败")
+		}
+	}
+	return nil
+}
+
+// GrantContributionRequest 手动发放贡献点请求
+type GrantContributionRequest struct {
+	TeamID     string
+	OperatorID string   // 操作者英雄ID（需要 manage_contribution 权限）
+	HeroIDs    []string // 接收成员
+	Amount     int64    // 正数为发放，负数为扣除
+	Note       string
+}
+
+// GrantPoints 手动发放或扣除贡献点（需要 manage_contribution 权限），全部成员在同一事务内生效
+func (s *TeamContributionService) GrantPoints(ctx context.Context, req *GrantContributionRequest) ([]*interfaces.TeamContributionEntry, error) {
+	// 1. 验证参数与权限
+	heroIDs := uniqueSortedIDs(req.HeroIDs)
+	if len(heroIDs) == 0 {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "接收成员不能为空")
+	}
+	if req.Amount == 0 {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "贡献点数量不能为 0")
+	}
+	if _, err := s.ensurePermission(ctx, req.TeamID, req.OperatorID, TeamPermManageContribution); err != nil {
+		return nil, err
+	}
+	for _, heroID := range heroIDs {
+		if _, err := s.teamMemberRepo.GetByTeamAndHero(ctx, req.TeamID, heroID); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInvalidParams, fmt.Sprintf("英雄 %s 不是该团队成员", heroID))
+		}
+	}
+
+	// 2. 开启事务
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			// 仅当 Rollback 失败且不是已提交的事务时，才表示有问题
+		}
+	}()
+
+	// 3. 逐个成员调整余额
+	entries := make([]*interfaces.TeamContributionEntry, 0, len(heroIDs))
+	for _, heroID := range heroIDs {
+		entry := &interfaces.TeamContributionEntry{
+			TeamID:         req.TeamID,
+			HeroID:         heroID,
+			Amount:         req.Amount,
+			SourceType:     interfaces.ContributionSourceManual,
+			OperatorHeroID: &req.OperatorID,
+		}
+		if req.Note != "" {
+			entry.Note = &req.Note
+		}
+		if err := s.applyEntry(ctx, tx, entry); err != nil {
+			return nil, err
+		}
+		details := map[string]interface{}{"amount": req.Amount, "balance_after": entry.BalanceAfter}
+		if req.Note != "" {
+			details["note"] = req.Note
+		}
+		if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, teamActivityEntry{
+			TeamID:       req.TeamID,
+			EventType:    interfaces.TeamActivityContributionGranted,
+			ActorHeroID:  req.OperatorID,
+			TargetHeroID: heroID,
+			SourceID:     entry.ID,
+			Details:      details,
+		}); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+		}
+		entries = append(entries, entry)
+	}
+
+	// 4. 提交事务
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	return entries, nil
+}
+
+// GetStandings 查看贡献点排行（团队成员可查看），当前成员无记录时余额为 0
+func (s *TeamContributionService) GetStandings(ctx context.Context, teamID, heroID string) ([]*interfaces.TeamContributionBalance, error) {
+	if _, err := s.ensureMember(ctx, teamID, heroID); err != nil {
+		return nil, err
+	}
+	members, err := s.teamMemberRepo.ListByTeam(ctx, teamID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询成员失败")
+	}
+	balances, err := s.contributionRepo.ListBalances(ctx, teamID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询贡献点排行失败")
+	}
+	return buildContributionStandings(teamID, members, balances), nil
+}
+
+// ListLedger 分页查询贡献点流水（团队成员可查看），filterHeroID 为空时查询全队
+func (s *TeamContributionService) ListLedger(ctx context.Context, teamID, heroID, filterHeroID string, limit, offset int) ([]*interfaces.TeamContributionEntry, int64, error) {
+	if _, err := s.ensureMember(ctx, teamID, heroID); err != nil {
+		return nil, 0, err
+	}
+	list, total, err := s.contributionRepo.ListEntries(ctx, teamID, filterHeroID, limit, offset)
+	if err != nil {
+		return nil, 0, xerrors.Wrap(err, xerrors.CodeInternalError, "查询贡献点流水失败")
+	}
+	return list, total, nil
+}
+
+// ==================== 竞拍 ====================
+
+// CreateContributionAuctionRequest 创建竞拍请求
+type CreateContributionAuctionRequest struct {
+	TeamID          string
+	HeroID          string // 操作者英雄ID（需要 distribute_loot 权限）
+	ItemID          string
+	Quantity        int
+	MinBid          int64 // 起拍价，<=0 时为 1
+	DurationSeconds int   // 竞拍时长，<=0 时使用团队配置
+}
+
+// CreateAuction 将仓库物品上架竞拍（需要 distribute_loot 权限）
+func (s *TeamContributionService) CreateAuction(ctx context.Context, req *CreateContributionAuctionRequest) (*interfaces.TeamContributionAuction, error) {
+	// 1. 验证参数与权限
+	if req.ItemID == "" || req.Quantity <= 0 {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "物品与数量不能为空")
+	}
+	if _, err := s.ensurePermission(ctx, req.TeamID, req.HeroID, TeamPermDistributeLoot); err != nil {
+		return nil, err
+	}
+	duration := req.DurationSeconds
+	if duration <= 0 {
+		settings, err := s.loadSettings(ctx, req.TeamID)
+		if err != nil {
+			return nil, err
+		}
+		duration = settings.AuctionDurationSeconds
+	}
+	if err := validateAuctionDuration(duration); err != nil {
+		return nil, err
+	}
+	minBid := req.MinBid
+	if minBid <= 0 {
+		minBid = 1
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			// 仅当 Rollback 失败且不是已提交的事务时，才表示有问题
+		}
+	}()
+
+	// 2. 锁定仓库后检查库存（扣除正在竞拍的数量），并发上架同一物品时串行校验
+	warehouse, err := s.teamWarehouseRepo.GetByTeamIDForUpdate(ctx, tx, req.TeamID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "团队仓库不存在")
+	}
+	stock, err := s.teamWarehouseItemRepo.GetItemCount(ctx, warehouse.ID, req.ItemID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询物品库存失败")
+	}
+	reserved, err := s.contributionRepo.SumOpenAuctionQuantity(ctx, tx, warehouse.ID, req.ItemID, "")
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询竞拍中物品失败")
+	}
+	if stock-reserved < req.Quantity {
+		msg := fmt.Sprintf("仓库可竞拍数量不足：库存 %d，竞拍中 %d", stock, reserved)
+		return nil, xerrors.New(xerrors.CodeInvalidParams, msg).WithMetadata("user_message", msg)
+	}
+
+	// 3. 创建竞拍
+	auction := &interfaces.TeamContributionAuction{
+		TeamID:      req.TeamID,
+		WarehouseID: warehouse.ID,
+		ItemID:      req.ItemID,
+		Quantity:    req.Quantity,
+		MinBid:      minBid,
+		Status:      interfaces.ContributionAuctionOpen,
+		CreatedBy:   req.HeroID,
+		EndsAt:      s.now().Add(time.Duration(duration) * time.Second),
+	}
+	if err := s.contributionRepo.CreateAuction(ctx, tx, auction); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "创建竞拍失败")
+	}
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, auctionActivity(interfaces.TeamActivityAuctionCreated, req.HeroID, auction)); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	return auction, nil
+}
+
+// PlaceContributionBidRequest 出价请求
+type PlaceContributionBidRequest struct {
+	TeamID    string
+	AuctionID string
+	HeroID    string
+	Amount    int64
+}
+
+// PlaceBid 出价：冻结出价点数并退还被超过的出价；最高出价者加价时只冻结差额
+func (s *TeamContributionService) PlaceBid(ctx context.Context, req *PlaceContributionBidRequest) (*interfaces.TeamContributionAuction, error) {
+	// 1. 验证参数与成员身份
+	if req.AuctionID == "" || req.Amount <= 0 {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "出价必须大于0")
+	}
+	if _, err := s.ensureMember(ctx, req.TeamID, req.HeroID); err != nil {
+		return nil, err
+	}
+
+	// 2. 开启事务
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			// 仅当 Rollback 失败且不是已提交的事务时，才表示有问题
+		}
+	}()
+
+	// 3. 锁定竞拍并校验
+	auction, err := s.lockAuction(ctx, tx, req.TeamID, req.AuctionID)
+	if err != nil {
+		return nil, err
+	}
+	if auction.Status != interfaces.ContributionAuctionOpen || !s.now().Before(auction.EndsAt) {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "竞拍已结束")
+	}
+	if next := nextMinimumBid(auction); req.Amount < next {
+		msg := fmt.Sprintf("出价至少为 %d", next)
+		return nil, xerrors.New(xerrors.CodeInvalidParams, msg).WithMetadata("user_message", msg)
+	}
+
+	// 4. 冻结点数，退还上一位最高出价者
+	source := &auction.ID
+	hold := req.Amount
+	previousBidder := auction.HighestBidderHeroID
+	if previousBidder != nil && *previousBidder == req.HeroID {
+		hold = req.Amount - auction.HighestBid
+	}
+	if err := s.applyEntry(ctx, tx, &interfaces.TeamContributionEntry{
+		TeamID:     req.TeamID,
+		HeroID:     req.HeroID,
+		Amount:     -hold,
+		SourceType: interfaces.ContributionSourceAuctionBid,
+		SourceID:   source,
+	}); err != nil {
+		return nil, err
+	}
+	if previousBidder != nil && *previousBidder != req.HeroID {
+		if err := s.refundHighestBid(ctx, tx, auction, "被超价退还"); err != nil {
+			return nil, err
+		}
+	}
+
+	// 5. 更新最高出价并记录
+	auction.HighestBid = req.Amount
+	auction.HighestBidderHeroID = &req.HeroID
+	if err := s.contributionRepo.UpdateAuction(ctx, tx, auction); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新竞拍失败")
+	}
+	if err := s.contributionRepo.CreateBid(ctx, tx, &interfaces.TeamContributionBid{
+		AuctionID: auction.ID,
+		HeroID:    req.HeroID,
+		Amount:    req.Amount,
+	}); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "记录出价失败")
+	}
+
+	// 6. 提交事务
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	return auction, nil
+}
+
+// CancelAuction 取消竞拍并退还最高出价（需要 distribute_loot 权限）
+func (s *TeamContributionService) CancelAuction(ctx context.Context, teamID, auctionID, heroID string) (*interfaces.TeamContributionAuction, error) {
+	if _, err := s.ensurePermission(ctx, teamID, heroID, TeamPermDistributeLoot); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			// 仅当 Rollback 失败且不是已提交的事务时，才表示有问题
+		}
+	}()
+
+	auction, err := s.lockAuction(ctx, tx, teamID, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	if auction.Status != interfaces.ContributionAuctionOpen {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "竞拍已结束")
+	}
+	if err := s.refundHighestBid(ctx, tx, auction, "竞拍取消退还"); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	note := "竞拍已取消"
+	auction.Status = interfaces.ContributionAuctionCancelled
+	auction.ResultNote = &note
+	auction.SettledAt = &now
+	if err := s.contributionRepo.UpdateAuction(ctx, tx, auction); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新竞拍失败")
+	}
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, auctionActivity(interfaces.TeamActivityAuctionCancelled, heroID, auction)); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	return auction, nil
+}
+
+// ListAuctions 分页查询团队竞拍（团队成员可查看）
+func (s *TeamContributionService) ListAuctions(ctx context.Context, teamID, heroID, status string, limit, offset int) ([]*interfaces.TeamContributionAuction, int64, error) {
+	if _, err := s.ensureMember(ctx, teamID, heroID); err != nil {
+		return nil, 0, err
+	}
+	list, total, err := s.contributionRepo.ListAuctions(ctx, teamID, status, limit, offset)
+	if err != nil {
+		return nil, 0, xerrors.Wrap(err, xerrors.CodeInternalError, "查询竞拍失败")
+	}
+	return list, total, nil
+}
+
+// SettleEndedAuctions 结算已到期的竞拍，返回处理数量
+func (s *TeamContributionService) SettleEndedAuctions(ctx context.Context) (int, error) {
+	ids, err := s.contributionRepo.ListEndedAuctionIDs(ctx, s.now(), contributionAuctionSettleBatch)
+	if err != nil {
+		return 0, xerrors.Wrap(err, xerrors.CodeInternalError, "查询到期竞拍失败")
+	}
+	settled := 0
+	for _, id := range ids {
+		if err := s.settleAuction(ctx, id); err != nil {
+			fmt.Printf("Warning: Failed to settle contribution auction (auction=%s): %v\n", id, err)
+			continue
+		}
+		settled++
+	}
+	return settled, nil
+}
+
+// RetryStaleAuctions 重新结算中断（停留在结算中）的竞拍，返回处理数量
+func (s *TeamContributionService) RetryStaleAuctions(ctx context.Context) (int, error) {
+	ids, err := s.contributionRepo.ListStaleSettlingAuctionIDs(ctx, s.now().Add(-contributionAuctionSettlingStaleAfter), contributionAuctionSettleBatch)
+	if err != nil {
+		return 0, xerrors.Wrap(err, xerrors.CodeInternalError, "查询中断的竞拍失败")
+	}
+	retried := 0
+	for _, id := range ids {
+		if err := s.awardAuction(ctx, id); err != nil {
+			fmt.Printf("Warning: Failed to retry stale contribution auction (auction=%s): %v\n", id, err)
+			continue
+		}
+		retried++
+	}
+	return retried, nil
+}
+
+// settleAuction 结算竞拍：先在事务内认领（open→settling），再由 awardAuction 发放给最高出价者
+func (s *TeamContributionService) settleAuction(ctx context.Context, auctionID string) error {
+	// 1. 认领竞拍（无人出价时直接流拍）
+	auction, err := s.claimEndedAuction(ctx, auctionID)
+	if err != nil || auction == nil {
+		return err
+	}
+
+	// 2. 以队长名义通过仓库分配发放给最高出价者
+	return s.awardAuction(ctx, auction.ID)
+}
+
+// auctionDistributionError 竞拍物品发放失败（事务已回滚），需退还点数并标记竞拍失败
+type auctionDistributionError struct {
+	err error
+}
+
+func (e *auctionDistributionError) Error() string {
+	return fmt.Sprintf("竞拍物品发放失败: %v", e.err)
+}
+
+func (e *auctionDistributionError) Unwrap() error {
+	return e.err
+}
+
+// awardAuction 发放结算中的竞拍；发放失败时另起事务退还冻结的点数
+// 发放与结果回写在同一事务内提交，进程中断时竞拍停留在结算中，由 RetryStaleAuctions 重新结算
+func (s *TeamContributionService) awardAuction(ctx context.Context, auctionID string) error {
+	err := s.finishAuction(ctx, auctionID, nil)
+	var distErr *auctionDistributionError
+	if !errors.As(err, &distErr) {
+		return err
+	}
+	return s.finishAuction(ctx, auctionID, distErr.err)
+}
+
+// finishAuction 在事务内回写结算中竞拍的结果
+// failure 为空时发放物品，发放出错则回滚并返回 *auctionDistributionError；failure 非空时退还点数并标记失败
+func (s *TeamContributionService) finishAuction(ctx context.Context, auctionID string, failure error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			// 仅当 Rollback 失败且不是已提交的事务时，才表示有问题
+		}
+	}()
+
+	auction, err := s.contributionRepo.GetAuctionForUpdate(ctx, tx, auctionID)
+	if err != nil || auction == nil || auction.Status != interfaces.ContributionAuctionSettling {
+		return err
+	}
+	winnerID := *auction.HighestBidderHeroID
+	note := fmt.Sprintf("竞拍 %d 贡献点获得", auction.HighestBid)
+
+	var distribution *DistributeItemsRequest
+	auction.Status = interfaces.ContributionAuctionAwarded
+	if failure == nil {
+		distribution, err = s.warehouseService.distributeToMemberTx(ctx, tx, auction.TeamID, auction.WarehouseID, winnerID,
+			LootItem{ItemID: auction.ItemID, Quantity: auction.Quantity}, interfaces.LootPolicyAuction, note, auction.ID)
+		if err != nil {
+			return &auctionDistributionError{err: err}
+		}
+	} else {
+		if err := s.refundHighestBid(ctx, tx, auction, "发放失败退还"); err != nil {
+			return err
+		}
+		auction.Status = interfaces.ContributionAuctionFailed
+		note = fmt.Sprintf("%s；发放失败，点数已退还：%v", note, failure)
+	}
+	settledAt := s.now()
+	auction.ResultNote = &note
+	auction.SettledAt = &settledAt
+	if err := s.contributionRepo.UpdateAuction(ctx, tx, auction); err != nil {
+		return err
+	}
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, auctionActivity(interfaces.TeamActivityAuctionSettled, "", auction)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if distribution != nil {
+		_ = notify.PublishWarehouseEvent(ctx, notify.SubjectWarehouseDistributed, &DistributionEvent{
+			TeamID:      auction.TeamID,
+			WarehouseID: auction.WarehouseID,
+			Distributor: distribution.DistributorID,
+			ItemPayload: distribution.Distributions,
+			Result:      "success",
+		})
+	}
+	return nil
+}
+
+// claimEndedAuction 将到期竞拍置为结算中并返回；无人出价时标记流拍并返回 nil
+func (s *TeamContributionService) claimEndedAuction(ctx context.Context, auctionID string) (*interfaces.TeamContributionAuction, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			// 仅当 Rollback 失败且不是已提交的事务时，才表示有问题
+		}
+	}()
+
+	auction, err := s.contributionRepo.GetAuctionForUpdate(ctx, tx, auctionID)
+	if err != nil || auction == nil || auction.Status != interfaces.ContributionAuctionOpen {
+		return nil, err
+	}
+	now := s.now()
+	if now.Before(auction.EndsAt) {
+		return nil, nil
+	}
+
+	claimed := auction
+	if auction.HighestBidderHeroID == nil {
+		note := "无人出价，物品留在仓库"
+		auction.Status = interfaces.ContributionAuctionUnsold
+		auction.ResultNote = &note
+		auction.SettledAt = &now
+		claimed = nil
+	} else {
+		auction.Status = interfaces.ContributionAuctionSettling
+	}
+	if err := s.contributionRepo.UpdateAuction(ctx, tx, auction); err != nil {
+		return nil, err
+	}
+	if claimed == nil {
+		if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, auctionActivity(interfaces.TeamActivityAuctionSettled, "", auction)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// refundHighestBid 退还当前最高出价者冻结的点数
+func (s *TeamContributionService) refundHighestBid(ctx context.Context, tx *sql.Tx, auction *interfaces.TeamContributionAuction, note string) error {
+	if auction.HighestBidderHeroID == nil || auction.HighestBid <= 0 {
+		return nil
+	}
+	return s.applyEntry(ctx, tx, &interfaces.TeamContributionEntry{
+		TeamID:     auction.TeamID,
+		HeroID:     *auction.HighestBidderHeroID,
+		Amount:     auction.HighestBid,
+		SourceType: interfaces.ContributionSourceAuctionRefund,
+		SourceID:   &auction.ID,
+		Note:       &note,
+	})
+}
+
+// auctionActivity 竞拍动态，受影响者为当前最高出价者
+func auctionActivity(eventType, actorHeroID string, auction *interfaces.TeamContributionAuction) teamActivityEntry {
+	entry := teamActivityEntry{
+		TeamID:      auction.TeamID,
+		EventType:   eventType,
+		ActorHeroID: actorHeroID,
+		SourceID:    auction.ID,
+		Details: map[string]interface{}{
+			"item_id":  auction.ItemID,
+			"quantity": auction.Quantity,
+			"status":   auction.Status,
+		},
+	}
+	if auction.HighestBidderHeroID != nil {
+		entry.TargetHeroID = *auction.HighestBidderHeroID
+		entry.Details["highest_bid"] = auction.HighestBid
+	} else {
+		entry.Details["min_bid"] = auction.MinBid
+	}
+	if auction.ResultNote != nil {
+		entry.Details["note"] = *auction.ResultNote
+	}
+	return entry
+}
+
+// ==================== 内部方法 ====================
+
+func (s *TeamContributionService) applyEntry(ctx context.Context, tx *sql.Tx, entry *interfaces.TeamContributionEntry) error {
+	if err := s.contributionRepo.ApplyTx(ctx, tx, entry); err != nil {
+		if errors.Is(err, interfaces.ErrInsufficientContribution) {
+			msg := fmt.Sprintf("英雄 %s 的贡献点不足", entry.HeroID)
+			return xerrors.New(xerrors.CodeInsufficientResource, msg).WithMetadata("user_message", "贡献点不足")
+		}
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "更新贡献点失败")
+	}
+	return nil
+}
+
+func (s *TeamContributionService) lockAuction(ctx context.Context, tx *sql.Tx, teamID, auctionID string) (*interfaces.TeamContributionAuction, error) {
+	auction, err := s.contributionRepo.GetAuctionForUpdate(ctx, tx, auctionID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询竞拍失败")
+	}
+	if auction == nil || auction.TeamID != teamID {
+		return nil, xerrors.New(xerrors.CodeResourceNotFound, "竞拍不存在")
+	}
+	return auction, nil
+}
+
+func (s *TeamContributionService) loadSettings(ctx context.Context, teamID string) (*interfaces.TeamContributionSettings, error) {
+	settings, err := s.contributionRepo.GetSettings(ctx, teamID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询贡献点配置失败")
+	}
+	if settings == nil {
+		settings = &interfaces.TeamContributionSettings{
+			TeamID:                  teamID,
+			DungeonCompletionPoints: defaultDungeonCompletionPoints,
+			AuctionDurationSeconds:  defaultAuctionDurationSeconds,
+		}
+	}
+	return settings, nil
+}
+
+func (s *TeamContributionService) ensureMember(ctx context.Context, teamID, heroID string) (*game_runtime.TeamMember, error) {
+	if teamID == "" || heroID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+	member, err := s.teamMemberRepo.GetByTeamAndHero(ctx, teamID, heroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
+	}
+	return member, nil
+}
+
+func (s *TeamContributionService) ensurePermission(ctx context.Context, teamID, heroID, permission string) (*game_runtime.TeamMember, error) {
+	member, err := s.ensureMember(ctx, teamID, heroID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := requireTeamPermission(ctx, s.teamRankRepo, member, permission, "您没有执行该操作的权限"); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// nextMinimumBid 无人出价时为起拍价，否则需高于当前最高出价
+func nextMinimumBid(auction *interfaces.TeamContributionAuction) int64 {
+	if auction.HighestBidderHeroID == nil {
+		return auction.MinBid
+	}
+	return auction.HighestBid + 1
+}
+
+func validateAuctionDuration(seconds int) error {
+	if seconds < 60 || seconds > 604800 {
+		return xerrors.New(xerrors.CodeInvalidParams, "竞拍时长需在 1 分钟到 7 天之间")
+	}
+	return nil
+}
+
+// buildContributionStandings 合并当前成员与余额记录，按余额、累计获得倒序排列
+func buildContributionStandings(teamID string, members []*game_runtime.TeamMember, balances []*interfaces.TeamContributionBalance) []*interfaces.TeamContributionBalance {
+	byHero := make(map[string]*interfaces.TeamContributionBalance, len(balances))
+	for _, balance := range balances {
+		byHero[balance.HeroID] = balance
+	}
+	standings := make([]*interfaces.TeamContributionBalance, 0, len(members))
+	for _, member := range members {
+		balance, ok := byHero[member.HeroID]
+		if !ok {
+			balance = &interfaces.TeamContributionBalance{TeamID: teamID, HeroID: member.HeroID}
+		}
+		standings = append(standings, balance)
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		if standings[i].Balance != standings[j].Balance {
+			return standings[i].Balance > standings[j].Balance
+		}
+		return standings[i].LifetimeEarned > standings[j].LifetimeEarned
+	})
+	return standings
+}
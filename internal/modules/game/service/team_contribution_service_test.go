@@ -0,0 +1,56 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/repository/interfaces"
+)
+
+func TestNextMinimumBid(t *testing.T) {
+	auction := &interfaces.TeamContributionAuction{MinBid: 10}
+	require.Equal(t, int64(10), nextMinimumBid(auction), "无人出价时为起拍价")
+
+	bidder := "hero-a"
+	auction.HighestBid = 25
+	auction.HighestBidderHeroID = &bidder
+	require.Equal(t, int64(26), nextMinimumBid(auction), "需高于当前最高出价")
+}
+
+func TestValidateAuctionDuration(t *testing.T) {
+	require.NoError(t, validateAuctionDuration(60))
+	require.NoError(t, validateAuctionDuration(604800))
+	require.Error(t, validateAuctionDuration(59))
+	require.Error(t, validateAuctionDuration(604801))
+}
+
+func TestAuctionDistributionError(t *testing.T) {
+	cause := errors.New("背包已满")
+	err := fmt.Errorf("结算竞拍: %w", &auctionDistributionError{err: cause})
+
+	var distErr *auctionDistributionError
+	require.True(t, errors.As(err, &distErr), "发放失败需能与其他错误区分，以便退还点数")
+	require.ErrorIs(t, err, cause)
+	require.False(t, errors.As(errors.New("提交事务失败"), &distErr))
+}
+
+func TestBuildContributionStandings(t *testing.T) {
+	members := []*game_runtime.TeamMember{{HeroID: "a"}, {HeroID: "b"}, {HeroID: "c"}}
+	balances := []*interfaces.TeamContributionBalance{
+		{HeroID: "b", Balance: 50, LifetimeEarned: 80},
+		{HeroID: "c", Balance: 50, LifetimeEarned: 120},
+		{HeroID: "left-team", Balance: 999},
+	}
+
+	standings := buildContributionStandings("team", members, balances)
+	require.Len(t, standings, 3, "只列出当前成员")
+	require.Equal(t, "c", standings[0].HeroID, "余额相同按累计获得排序")
+	require.Equal(t, "b", standings[1].HeroID)
+	require.Equal(t, "a", standings[2].HeroID)
+	require.Equal(t, int64(0), standings[2].Balance)
+	require.Equal(t, "team", standings[2].TeamID)
+}
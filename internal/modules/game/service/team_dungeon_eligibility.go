@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// teamRosterEntry 团队成员及其英雄
+type teamRosterEntry struct {
+	Member *game_runtime.TeamMember
+	Hero   *game_runtime.Hero
+}
+
+// DungeonMemberEligibility 单个成员的进入资格
+type DungeonMemberEligibility struct {
+	HeroID   string
+	HeroName string
+	Level    int
+	Eligible bool
+	Reason   string
+}
+
+// DungeonEligibility 团队对某地城的进入资格报告
+type DungeonEligibility struct {
+	TeamID      string
+	Eligible    bool     // 人数、等级与剩余次数均满足
+	Reasons     []string // 不满足的原因
+	MemberCount int
+	MinMembers  int
+	Members     []DungeonMemberEligibility
+
+	AttemptsUsed      int
+	MaxAttempts       *int // nil 表示不限次数
+	RemainingAttempts *int
+}
+
+// loadTeamRoster 加载团队成员及英雄信息
+func (s *TeamDungeonService) loadTeamRoster(ctx context.Context, teamID string) ([]teamRosterEntry, error) {
+	members, err := s.teamMemberRepo.ListByTeam(ctx, teamID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询成员失败")
+	}
+
+	roster := make([]teamRosterEntry, 0, len(members))
+	for _, member := range members {
+		hero, err := s.heroRepo.GetByID(ctx, member.HeroID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询英雄信息失败")
+		}
+		roster = append(roster, teamRosterEntry{Member: member, Hero: hero})
+	}
+	return roster, nil
+}
+
+// evaluateTeamRequirements 按 min_members 与等级区间逐项评估团队资格（max_level 为 0 表示不限）
+func evaluateTeamRequirements(roster []teamRosterEntry, dungeon *game_config.Dungeon) *DungeonEligibility {
+	report := &DungeonEligibility{
+		Eligible:    true,
+		MemberCount: len(roster),
+		MinMembers:  minMembersFromDungeon(dungeon),
+		Members:     make([]DungeonMemberEligibility, 0, len(roster)),
+	}
+	if len(roster) == 0 {
+		report.fail("团队没有成员")
+	} else if len(roster) < report.MinMembers {
+		report.fail(fmt.Sprintf("团队人数不足，至少需要 %d 人", report.MinMembers))
+	}
+
+	for _, entry := range roster {
+		level := int(entry.Hero.CurrentLevel)
+		item := DungeonMemberEligibility{
+			HeroID:   entry.Member.HeroID,
+			HeroName: entry.Hero.HeroName,
+			Level:    level,
+			Eligible: true,
+		}
+		switch {
+		case level < int(dungeon.MinLevel):
+			item.Eligible = false
+			item.Reason = fmt.Sprintf("等级低于 %d", dungeon.MinLevel)
+			report.fail(fmt.Sprintf("成员 %s 未达到进入等级", entry.Member.HeroID))
+		case dungeon.MaxLevel > 0 && level > int(dungeon.MaxLevel):
+			item.Eligible = false
+			item.Reason = fmt.Sprintf("等级高于 %d", dungeon.MaxLevel)
+			report.fail(fmt.Sprintf("成员 %s 超过地城等级上限", entry.Member.HeroID))
+		}
+		report.Members = append(report.Members, item)
+	}
+	return report
+}
+
+// applyAttempts 写入剩余挑战次数，以地城当前配置的每日上限为准
+func (r *DungeonEligibility) applyAttempts(dungeon *game_config.Dungeon, record *game_runtime.TeamDungeonRecord) {
+	if record != nil {
+		r.AttemptsUsed = record.AttemptsCount
+	}
+
+	var maxAttempts *int
+	switch {
+	case dungeon.MaxAttemptsPerDay.Valid:
+		value := int(dungeon.MaxAttemptsPerDay.Int16)
+		maxAttempts = &value
+	case record != nil && record.MaxAttempts.Valid:
+		value := record.MaxAttempts.Int
+		maxAttempts = &value
+	}
+	if maxAttempts == nil {
+		return
+	}
+
+	remaining := max(*maxAttempts-r.AttemptsUsed, 0)
+	r.MaxAttempts = maxAttempts
+	r.RemainingAttempts = &remaining
+	if remaining == 0 {
+		r.fail("挑战次数已达上限")
+	}
+}
+
+func (r *DungeonEligibility) fail(reason string) {
+	r.Eligible = false
+	r.Reasons = append(r.Reasons, reason)
+}
+
+// requirementError 转换为首个不满足条件的错误
+func (r *DungeonEligibility) requirementError() error {
+	if r.Eligible || len(r.Reasons) == 0 {
+		return nil
+	}
+	return xerrors.New(xerrors.CodeInvalidParams, r.Reasons[0])
+}
+
+// EvaluateEligibility 批量评估团队对多个地城的进入资格（调用者需为团队成员）
+func (s *TeamDungeonService) EvaluateEligibility(ctx context.Context, teamID, heroID string, dungeons []*game_config.Dungeon) (map[string]*DungeonEligibility, error) {
+	if teamID == "" || heroID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+	if _, err := s.teamMemberRepo.GetByTeamAndHero(ctx, teamID, heroID); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodePermissionDenied, "您不是该团队成员")
+	}
+
+	roster, err := s.loadTeamRoster(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]*DungeonEligibility, len(dungeons))
+	for _, dungeon := range dungeons {
+		record, err := s.recordRepo.GetByTeamAndDungeon(ctx, teamID, dungeon.ID)
+		if err != nil {
+			if !errors.Is(err, interfaces.ErrTeamDungeonRecordNotFound) {
+				return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询地城记录失败")
+			}
+			record = nil
+		}
+
+		report := evaluateTeamRequirements(roster, dungeon)
+		report.TeamID = teamID
+		report.applyAttempts(dungeon, record)
+		result[dungeon.ID] = report
+	}
+	return result, nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/types"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/entity/game_runtime"
+)
+
+func rosterOf(levels ...int16) []teamRosterEntry {
+	roster := make([]teamRosterEntry, len(levels))
+	for i, level := range levels {
+		heroID := string(rune('a' + i))
+		roster[i] = teamRosterEntry{
+			Member: &game_runtime.TeamMember{TeamID: "team-1", HeroID: heroID},
+			Hero:   &game_runtime.Hero{ID: heroID, HeroName: "hero-" + heroID, CurrentLevel: level},
+		}
+	}
+	return roster
+}
+
+func TestEvaluateTeamRequirements(t *testing.T) {
+	dungeon := &game_config.Dungeon{
+		ID:           "d1",
+		MinLevel:     10,
+		MaxLevel:     20,
+		RoomSequence: types.JSON(`[{"room_id":"r1","sort":1,"min_members":2}]`),
+	}
+
+	report := evaluateTeamRequirements(rosterOf(10, 20), dungeon)
+	require.True(t, report.Eligible)
+	require.NoError(t, report.requirementError())
+	require.Equal(t, 2, report.MinMembers)
+
+	// 人数不足
+	report = evaluateTeamRequirements(rosterOf(15), dungeon)
+	require.False(t, report.Eligible)
+	require.ErrorContains(t, report.requirementError(), "团队人数不足")
+
+	// 等级低于下限、高于上限均逐个成员标记
+	report = evaluateTeamRequirements(rosterOf(9, 15, 21), dungeon)
+	require.False(t, report.Eligible)
+	require.Len(t, report.Reasons, 2)
+	require.False(t, report.Members[0].Eligible)
+	require.True(t, report.Members[1].Eligible)
+	require.False(t, report.Members[2].Eligible)
+	require.Contains(t, report.Members[2].Reason, "20")
+
+	// max_level 为 0 表示不限
+	unbounded := *dungeon
+	unbounded.MaxLevel = 0
+	require.True(t, evaluateTeamRequirements(rosterOf(10, 99), &unbounded).Eligible)
+}
+
+func TestDungeonEligibilityAttempts(t *testing.T) {
+	dungeon := &game_config.Dungeon{ID: "d1", MinLevel: 1, MaxAttemptsPerDay: null.Int16From(3)}
+
+	report := evaluateTeamRequirements(rosterOf(5), dungeon)
+	report.applyAttempts(dungeon, nil)
+	require.True(t, report.Eligible)
+	require.Equal(t, 3, *report.RemainingAttempts)
+
+	report = evaluateTeamRequirements(rosterOf(5), dungeon)
+	report.applyAttempts(dungeon, &game_runtime.TeamDungeonRecord{AttemptsCount: 3, MaxAttempts: null.IntFrom(2)})
+	require.False(t, report.Eligible)
+	require.Equal(t, 0, *report.RemainingAttempts)
+	require.Equal(t, 3, *report.MaxAttempts)
+
+	unlimited := &game_config.Dungeon{ID: "d2", MinLevel: 1}
+	report = evaluateTeamRequirements(rosterOf(5), unlimited)
+	report.applyAttempts(unlimited, &game_runtime.TeamDungeonRecord{AttemptsCount: 7})
+	require.True(t, report.Eligible)
+	require.Nil(t, report.RemainingAttempts)
+	require.Equal(t, 7, report.AttemptsUsed)
+}
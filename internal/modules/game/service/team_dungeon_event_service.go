@@ -0,0 +1,396 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// 事件奖励发放状态
+const (
+	EventRewardPending = "pending"
+	EventRewardGranted = "granted"
+	EventRewardFailed  = "failed"
+)
+
+// eventApplyEffect dungeon_events.apply_effects 单项
+type eventApplyEffect struct {
+	BuffCode    string                 `json:"buff_code"`
+	BuffParams  map[string]interface{} `json:"buff_params"`
+	CasterLevel int                    `json:"caster_level"`
+	Target      string                 `json:"target"`
+}
+
+// eventDropConfig dungeon_events.drop_config
+type eventDropConfig struct {
+	DropPoolID      *string `json:"drop_pool_id"`
+	GuaranteedItems []struct {
+		ItemCode string `json:"item_code"`
+		Quantity int    `json:"quantity"`
+	} `json:"guaranteed_items"`
+}
+
+// AppliedEventEffect 已施加到英雄身上的事件效果
+type AppliedEventEffect struct {
+	HeroID      string `json:"hero_id"`
+	BuffCode    string `json:"buff_code"`
+	CasterLevel int    `json:"caster_level"`
+}
+
+// eventLootItem 日志与结算详情中的掉落项
+type eventLootItem struct {
+	ItemID   string `json:"item_id"`
+	ItemType string `json:"item_type,omitempty"`
+	Quantity int    `json:"quantity"`
+}
+
+// roomRewards 房间结算时在同一事务内发放的奖励
+type roomRewards struct {
+	logID      string
+	loot       LootData
+	exp        int64
+	minLevel   int // 地城等级区间，用于经验等级差衰减
+	maxLevel   int
+	heroIDs    []string
+	currencies []CurrencyChange
+}
+
+// lootRequest 掉落入库请求
+func (r *roomRewards) lootRequest(progress *game_runtime.TeamDungeonProgress) *AddLootToWarehouseRequest {
+	return &AddLootToWarehouseRequest{
+		TeamID:          progress.TeamID,
+		SourceDungeonID: progress.DungeonID,
+		Gold:            r.loot.Gold,
+		Items:           r.loot.Items,
+	}
+}
+
+func (r *roomRewards) empty() bool {
+	return r.loot.Gold <= 0 && len(r.loot.Items) == 0 && (r.exp <= 0 || len(r.heroIDs) == 0) && len(r.currencies) == 0
+}
+
+// parseEventEffects 解析事件效果配置
+func parseEventEffects(raw []byte) ([]eventApplyEffect, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var effects []eventApplyEffect
+	if err := json.Unmarshal(raw, &effects); err != nil {
+		return nil, fmt.Errorf("解析事件效果失败: %w", err)
+	}
+	return effects, nil
+}
+
+// parseEventDropConfig 解析事件掉落配置
+func parseEventDropConfig(raw []byte) (*eventDropConfig, error) {
+	cfg := &eventDropConfig{}
+	if len(raw) == 0 {
+		return cfg, nil
+	}
+	if err := json.Unmarshal(raw, cfg); err != nil {
+		return nil, fmt.Errorf("解析事件掉落配置失败: %w", err)
+	}
+	return cfg, nil
+}
+
+// eventEffectTargets 根据作用对象确定受影响的英雄（team/allies/all 为全队，player 为操作者）
+func eventEffectTargets(target string, memberIDs []string, operatorID string) ([]string, error) {
+	switch strings.ToLower(target) {
+	case "", "team", "allies", "all":
+		return memberIDs, nil
+	case "player", "self":
+		if operatorID == "" {
+			return nil, nil
+		}
+		return []string{operatorID}, nil
+	default:
+		return nil, fmt.Errorf("不支持的效果作用对象: %s", target)
+	}
+}
+
+// resolveEventRoom 事件房间：施加效果、计算掉落与经验并写入事件日志，奖励随房间结算事务一起发放
+func (s *TeamDungeonService) resolveEventRoom(ctx context.Context, tx *sql.Tx, progress *game_runtime.TeamDungeonProgress, resolution *RoomResolution, operatorID string) (*RoomResolution, error) {
+	if resolution.TriggerID == "" || s.eventRepo == nil {
+		return resolution, nil
+	}
+	event, err := s.loadDungeonEvent(ctx, resolution.TriggerID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.teamMemberRepo.ListByTeam(ctx, progress.TeamID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询成员失败")
+	}
+	memberIDs := make([]string, 0, len(members))
+	totalLevel := 0
+	for _, member := range members {
+		hero, err := s.heroRepo.GetByID(ctx, member.HeroID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询英雄信息失败")
+		}
+		memberIDs = append(memberIDs, member.HeroID)
+		totalLevel += int(hero.CurrentLevel)
+	}
+	dropLevel := 1
+	if len(members) > 0 {
+		dropLevel = totalLevel / len(members)
+	}
+
+	applied, err := s.applyEventEffects(ctx, tx, progress, event, memberIDs, operatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	loot, err := s.rollEventDrops(ctx, event, dropLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	rewards := &roomRewards{loot: loot, heroIDs: memberIDs}
+	if event.RewardExp.Valid && event.RewardExp.Int > 0 {
+		rewards.exp = int64(event.RewardExp.Int)
+		if dungeon, err := s.dungeonRepo.GetByID(ctx, progress.DungeonID); err == nil {
+			rewards.minLevel, rewards.maxLevel = int(dungeon.MinLevel), int(dungeon.MaxLevel)
+		}
+	}
+	if s.currencyService != nil {
+		rewards.currencies, err = s.currencyService.rewardChanges(ctx, interfaces.CurrencyRewardEvent, event.ID, memberIDs, nil, CurrencyChange{
+			SourceType: CurrencySourceEvent,
+			SourceID:   event.ID,
+			Metadata:   map[string]interface{}{"team_id": progress.TeamID, "dungeon_id": progress.DungeonID, "progress_id": progress.ID},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lootItems := make([]eventLootItem, 0, len(loot.Items))
+	for _, item := range loot.Items {
+		lootItems = append(lootItems, eventLootItem{ItemID: item.ItemID, ItemType: item.ItemType, Quantity: item.Quantity})
+	}
+
+	log := &interfaces.TeamDungeonEventLog{
+		ProgressID:   progress.ID,
+		TeamID:       progress.TeamID,
+		DungeonID:    progress.DungeonID,
+		RoomID:       resolution.RoomID,
+		EventID:      &event.ID,
+		EventCode:    &event.EventCode,
+		LootGold:     loot.Gold,
+		RewardExp:    int(rewards.exp),
+		RewardStatus: EventRewardPending,
+	}
+	if operatorID != "" {
+		log.OperatorHeroID = &operatorID
+	}
+	if rewards.empty() {
+		log.RewardStatus = EventRewardGranted
+	}
+	if log.AppliedEffects, err = json.Marshal(applied); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "序列化事件效果失败")
+	}
+	if log.LootItems, err = json.Marshal(lootItems); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "序列化事件掉落失败")
+	}
+	rewarded := []string{}
+	if rewards.exp > 0 {
+		rewarded = memberIDs
+	}
+	if log.RewardedHeroes, err = json.Marshal(rewarded); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "序列化经验接收者失败")
+	}
+	if err := s.eventLogRepo.Create(ctx, tx, log); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "写入事件日志失败")
+	}
+
+	resolution.Detail = map[string]interface{}{
+		"event_code":      event.EventCode,
+		"event_log_id":    log.ID,
+		"applied_effects": applied,
+		"loot_items":      lootItems,
+		"reward_exp":      rewards.exp,
+		"reward_status":   log.RewardStatus,
+	}
+	if len(rewards.currencies) > 0 {
+		resolution.Detail["reward_currencies"] = currencyRewardSummary(rewards.currencies)
+	}
+	if event.EventDescription.Valid {
+		resolution.Detail["event_description"] = event.EventDescription.String
+	}
+	if event.EventEndDesc.Valid {
+		resolution.Detail["event_end_desc"] = event.EventEndDesc.String
+	}
+	if !rewards.empty() {
+		rewards.logID = log.ID
+		resolution.rewards = rewards
+	}
+	return resolution, nil
+}
+
+// applyEventEffects 将事件效果作为地城期间 Buff 施加给目标英雄
+func (s *TeamDungeonService) applyEventEffects(ctx context.Context, tx *sql.Tx, progress *game_runtime.TeamDungeonProgress, event *game_config.DungeonEvent, memberIDs []string, operatorID string) ([]AppliedEventEffect, error) {
+	effects, err := parseEventEffects(event.ApplyEffects)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, "事件效果配置错误")
+	}
+
+	applied := []AppliedEventEffect{}
+	for _, effect := range effects {
+		if effect.BuffCode == "" {
+			continue
+		}
+		targets, err := eventEffectTargets(effect.Target, memberIDs, operatorID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, "事件效果配置错误")
+		}
+
+		var buffID *string
+		if buff, err := s.buffRepo.GetByCode(ctx, effect.BuffCode); err == nil && buff != nil {
+			buffID = &buff.ID
+		}
+		params, err := json.Marshal(effect.BuffParams)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, "事件效果参数错误")
+		}
+		casterLevel := effect.CasterLevel
+		if casterLevel < 1 {
+			casterLevel = 1
+		}
+
+		for _, heroID := range targets {
+			buff := &interfaces.HeroActiveBuff{
+				HeroID:      heroID,
+				BuffID:      buffID,
+				BuffCode:    effect.BuffCode,
+				BuffParams:  params,
+				CasterLevel: casterLevel,
+				SourceType:  "dungeon_event",
+				SourceID:    &event.EventCode,
+				ProgressID:  &progress.ID,
+			}
+			if err := s.activeBuffRepo.Create(ctx, tx, buff); err != nil {
+				return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "施加事件效果失败")
+			}
+			applied = append(applied, AppliedEventEffect{HeroID: heroID, BuffCode: effect.BuffCode, CasterLevel: casterLevel})
+		}
+	}
+	return applied, nil
+}
+
+// rollEventDrops 计算事件掉落：掉落池随机结果 + 保底物品
+func (s *TeamDungeonService) rollEventDrops(ctx context.Context, event *game_config.DungeonEvent, level int) (LootData, error) {
+	var loot LootData
+	cfg, err := parseEventDropConfig(event.DropConfig)
+	if err != nil {
+		return loot, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, "事件掉落配置错误")
+	}
+
+	if cfg.DropPoolID != nil && *cfg.DropPoolID != "" && s.dropService != nil {
+		items, err := s.dropService.RollDropPool(ctx, *cfg.DropPoolID, level)
+		if err != nil {
+			return loot, err
+		}
+		loot.Items = mergeLootItems(loot.Items, items...)
+	}
+
+	for _, guaranteed := range cfg.GuaranteedItems {
+		if guaranteed.ItemCode == "" || guaranteed.Quantity <= 0 {
+			continue
+		}
+		item, err := s.itemRepo.GetByCode(ctx, guaranteed.ItemCode)
+		if err != nil {
+			return loot, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, fmt.Sprintf("保底物品 %s 不存在", guaranteed.ItemCode))
+		}
+		loot.Items = mergeLootItems(loot.Items, LootItem{ItemID: item.ID, ItemType: item.ItemType, Quantity: guaranteed.Quantity})
+	}
+	return loot, nil
+}
+
+// grantRoomRewardsTx 在房间结算事务内发放奖励（仓库入库 + 经验 + 货币），任一失败则整个房间结算回滚；
+// 返回入库的仓库ID，事务提交后交给 finishRoomRewards 做审计与通知
+func (s *TeamDungeonService) grantRoomRewardsTx(ctx context.Context, tx *sql.Tx, progress *game_runtime.TeamDungeonProgress, resolution *RoomResolution) (string, error) {
+	rewards := resolution.rewards
+	if rewards == nil {
+		return "", nil
+	}
+
+	var warehouseID string
+	if rewards.loot.Gold > 0 || len(rewards.loot.Items) > 0 {
+		id, err := s.teamWarehouseService.AddLootToWarehouseTx(ctx, tx, rewards.lootRequest(progress))
+		if err != nil {
+			return "", err
+		}
+		warehouseID = id
+	}
+	if rewards.exp > 0 && s.experienceService != nil && rewards.logID != "" {
+		_, err := s.experienceService.AwardTx(ctx, tx, &ExperienceAwardRequest{
+			SourceType: interfaces.ExperienceSourceEvent,
+			SourceID:   rewards.logID,
+			TotalXP:    rewards.exp,
+			MinLevel:   rewards.minLevel,
+			MaxLevel:   rewards.maxLevel,
+			HeroIDs:    rewards.heroIDs,
+		})
+		if err != nil {
+			return "", err
+		}
+	} else if rewards.exp > 0 && s.heroService != nil {
+		for _, heroID := range rewards.heroIDs {
+			if _, err := s.heroService.AddExperienceTx(ctx, tx, heroID, rewards.exp); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if s.currencyService != nil {
+		for _, change := range rewards.currencies {
+			if _, err := s.currencyService.ApplyTx(ctx, tx, change); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if rewards.logID != "" {
+		if err := s.eventLogRepo.UpdateRewardStatus(ctx, tx, rewards.logID, EventRewardGranted, nil); err != nil {
+			return "", xerrors.Wrap(err, xerrors.CodeInternalError, "更新事件奖励状态失败")
+		}
+	}
+	if resolution.Detail != nil {
+		resolution.Detail["reward_status"] = EventRewardGranted
+	}
+	return warehouseID, nil
+}
+
+// finishRoomRewards 房间结算事务提交后写入仓库审计日志、推送通知并执行分配策略
+func (s *TeamDungeonService) finishRoomRewards(ctx context.Context, progress *game_runtime.TeamDungeonProgress, resolution *RoomResolution, warehouseID string) {
+	if warehouseID == "" || resolution.rewards == nil {
+		return
+	}
+	s.teamWarehouseService.FinishLootStored(ctx, warehouseID, resolution.rewards.lootRequest(progress))
+}
+
+// mergeLootItems 合并同一物品的数量
+func mergeLootItems(items []LootItem, extra ...LootItem) []LootItem {
+	for _, item := range extra {
+		merged := false
+		for i := range items {
+			if items[i].ItemID == item.ItemID {
+				items[i].Quantity += item.Quantity
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEventConfig(t *testing.T) {
+	effects, err := parseEventEffects([]byte(`[
+		{"buff_code":"BUFF_BLESS","buff_params":{"value":5},"caster_level":10,"target":"team"},
+		{"buff_code":"BUFF_CURSE","caster_level":0,"target":"player"}
+	]`))
+	require.NoError(t, err)
+	require.Len(t, effects, 2)
+	require.Equal(t, "BUFF_BLESS", effects[0].BuffCode)
+	require.Equal(t, 10, effects[0].CasterLevel)
+	require.Equal(t, "player", effects[1].Target)
+
+	cfg, err := parseEventDropConfig([]byte(`{"drop_pool_id":"pool-1","guaranteed_items":[{"item_code":"ITEM_KEY","quantity":2}]}`))
+	require.NoError(t, err)
+	require.NotNil(t, cfg.DropPoolID)
+	require.Equal(t, "pool-1", *cfg.DropPoolID)
+	require.Len(t, cfg.GuaranteedItems, 1)
+	require.Equal(t, 2, cfg.GuaranteedItems[0].Quantity)
+
+	cfg, err = parseEventDropConfig(nil)
+	require.NoError(t, err)
+	require.Nil(t, cfg.DropPoolID)
+
+	_, err = parseEventEffects([]byte(`{"buff_code":"x"}`))
+	require.Error(t, err)
+}
+
+func TestEventEffectTargets(t *testing.T) {
+	members := []string{"h1", "h2"}
+
+	targets, err := eventEffectTargets("team", members, "h1")
+	require.NoError(t, err)
+	require.Equal(t, members, targets)
+
+	targets, err = eventEffectTargets("player", members, "h2")
+	require.NoError(t, err)
+	require.Equal(t, []string{"h2"}, targets)
+
+	_, err = eventEffectTargets("enemies", members, "h1")
+	require.Error(t, err)
+}
+
+func TestMergeLootItems(t *testing.T) {
+	items := mergeLootItems(nil,
+		LootItem{ItemID: "a", Quantity: 1},
+		LootItem{ItemID: "b", Quantity: 2},
+		LootItem{ItemID: "a", Quantity: 3},
+	)
+	require.Equal(t, []LootItem{{ItemID: "a", Quantity: 4}, {ItemID: "b", Quantity: 2}}, items)
+
+	rewards := &roomRewards{heroIDs: []string{"h1"}}
+	require.True(t, rewards.empty())
+	rewards.exp = 100
+	require.False(t, rewards.empty())
+}
@@ -0,0 +1,813 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/types"
+	"github.com/lib/pq"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// 房间类型
+const (
+	RoomTypeBattle   = "battle"
+	RoomTypeEvent    = "event"
+	RoomTypeTreasure = "treasure"
+	RoomTypeRest     = "rest"
+)
+
+// 房间结算结果
+const (
+	RoomOutcomeSuccess = "success"
+	RoomOutcomeFailure = "failure"
+)
+
+// maxRoomSkipChain 条件跳过的最大连续次数，防止配置异常导致死循环
+const maxRoomSkipChain = 64
+
+// AdvanceRoomRequest 前进到下一个房间请求
+type AdvanceRoomRequest struct {
+	TeamID string
+	HeroID string
+}
+
+// ResolveRoomRequest 结算当前房间请求
+type ResolveRoomRequest struct {
+	TeamID   string
+	HeroID   string
+	RoomID   string // 可选，用于校验客户端所在房间与服务端一致
+	BattleID string // 战斗房间必填：已结算的战斗ID，胜负以该战斗的战报为准
+}
+
+// RoomResolution 房间结算结果
+type RoomResolution struct {
+	RoomID    string                 `json:"room_id"`
+	RoomType  string                 `json:"room_type"`
+	TriggerID string                 `json:"trigger_id,omitempty"`
+	Outcome   string                 `json:"outcome"`
+	Detail    map[string]interface{} `json:"detail,omitempty"`
+
+	rewards *roomRewards
+}
+
+// RoomTraversalResult 房间推进/结算结果
+type RoomTraversalResult struct {
+	Progress       *game_runtime.TeamDungeonProgress
+	Room           *game_config.DungeonRoom
+	Resolution     *RoomResolution
+	SkippedRooms   []string
+	ReturnedTo     string
+	DungeonCleared bool
+}
+
+// dungeonRoomStep room_sequence 中的单个房间配置
+type dungeonRoomStep struct {
+	RoomID            string                 `json:"room_id"`
+	RoomCode          string                 `json:"room_code,omitempty"`
+	Sort              int                    `json:"sort"`
+	ConditionalSkip   map[string]interface{} `json:"conditional_skip,omitempty"`
+	ConditionalReturn map[string]interface{} `json:"conditional_return,omitempty"`
+}
+
+// key 返回房间在进度中的标识（优先房间ID，兼容旧配置的 room_code）
+func (s dungeonRoomStep) key() string {
+	if s.RoomID != "" {
+		return s.RoomID
+	}
+	return s.RoomCode
+}
+
+// roomSequence 按 sort 排序后的房间序列
+type roomSequence []dungeonRoomStep
+
+// parseRoomSequence 解析地城 room_sequence 配置
+func parseRoomSequence(raw types.JSON) (roomSequence, error) {
+	if len(raw) == 0 {
+		return roomSequence{}, nil
+	}
+	var steps []dungeonRoomStep
+	if err := json.Unmarshal(raw, &steps); err != nil {
+		return nil, fmt.Errorf("解析房间序列失败: %w", err)
+	}
+	result := make(roomSequence, 0, len(steps))
+	for i, step := range steps {
+		if step.key() == "" {
+			continue
+		}
+		if step.Sort <= 0 {
+			step.Sort = i + 1
+		}
+		result = append(result, step)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Sort < result[j].Sort })
+	return result, nil
+}
+
+// indexOf 返回房间在序列中的下标，不存在时返回 -1
+func (seq roomSequence) indexOf(key string) int {
+	for i, step := range seq {
+		if step.key() == key || (step.RoomCode != "" && step.RoomCode == key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexOfSort 返回指定 sort 的下标，不存在时返回 -1
+func (seq roomSequence) indexOfSort(sortValue int) int {
+	for i, step := range seq {
+		if step.Sort == sortValue {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolveTarget 解析跳转目标（支持 target_room 或按 sort 跳转的 jump_to/return_to）
+func (seq roomSequence) resolveTarget(rule map[string]interface{}, sortKey string) int {
+	if rule == nil {
+		return -1
+	}
+	if target, ok := rule["target_room"].(string); ok && target != "" {
+		return seq.indexOf(target)
+	}
+	if value, ok := rule[sortKey].(float64); ok {
+		return seq.indexOfSort(int(value))
+	}
+	return -1
+}
+
+// roomTraversalState 条件判定所需的进度上下文
+type roomTraversalState struct {
+	flags       map[string]bool
+	memberCount int
+	heroLevels  []int
+	itemChecker func(itemRef string) (bool, error)
+}
+
+func (st *roomTraversalState) hasFlag(flag string) bool {
+	return st.flags[flag]
+}
+
+// evaluateRoomCondition 判定单个条件是否满足，返回不满足的原因
+func evaluateRoomCondition(cond map[string]interface{}, st *roomTraversalState) (bool, string, error) {
+	if len(cond) == 0 {
+		return true, "", nil
+	}
+
+	condType, _ := cond["type"].(string)
+	switch condType {
+	case "":
+		// 兼容 {"condition": "flag"} 写法
+		if nested, ok := cond["condition"]; ok {
+			return evaluateConditionValue(nested, st)
+		}
+		return true, "", nil
+	case "all", "any":
+		list, _ := cond["conditions"].([]interface{})
+		for _, item := range list {
+			ok, reason, err := evaluateConditionValue(item, st)
+			if err != nil {
+				return false, "", err
+			}
+			if condType == "any" && ok {
+				return true, "", nil
+			}
+			if condType == "all" && !ok {
+				return false, reason, nil
+			}
+		}
+		if condType == "any" && len(list) > 0 {
+			return false, "未满足任一开启条件", nil
+		}
+		return true, "", nil
+	case "require_flag", "check_flag":
+		flag, _ := cond["flag"].(string)
+		if flag == "" || st.hasFlag(flag) {
+			return true, "", nil
+		}
+		return false, fmt.Sprintf("需要先达成条件 %s", flag), nil
+	case "require_room", "room_cleared":
+		room, _ := cond["room_id"].(string)
+		if room == "" {
+			room, _ = cond["room_code"].(string)
+		}
+		if room == "" || st.hasFlag(room) {
+			return true, "", nil
+		}
+		return false, fmt.Sprintf("需要先通过房间 %s", room), nil
+	case "require_item":
+		itemRef, _ := cond["item_code"].(string)
+		if itemRef == "" {
+			itemRef, _ = cond["item_id"].(string)
+		}
+		if itemRef == "" || st.itemChecker == nil {
+			return true, "", nil
+		}
+		ok, err := st.itemChecker(itemRef)
+		if err != nil {
+			return false, "", err
+		}
+		if !ok {
+			return false, fmt.Sprintf("团队需要持有物品 %s", itemRef), nil
+		}
+		return true, "", nil
+	case "min_members":
+		value, _ := cond["value"].(float64)
+		if st.memberCount >= int(value) {
+			return true, "", nil
+		}
+		return false, fmt.Sprintf("团队人数至少需要 %d 人", int(value)), nil
+	case "min_level":
+		value, _ := cond["value"].(float64)
+		for _, level := range st.heroLevels {
+			if level < int(value) {
+				return false, fmt.Sprintf("所有成员等级需达到 %d", int(value)), nil
+			}
+		}
+		return true, "", nil
+	case "max_level":
+		value, _ := cond["value"].(float64)
+		for _, level := range st.heroLevels {
+			if level > int(value) {
+				return false, fmt.Sprintf("成员等级不能超过 %d", int(value)), nil
+			}
+		}
+		return true, "", nil
+	default:
+		return false, "", fmt.Errorf("不支持的房间条件类型: %s", condType)
+	}
+}
+
+// evaluateConditionValue 判定条件值：字符串视为标记，对象按条件结构判定
+func evaluateConditionValue(value interface{}, st *roomTraversalState) (bool, string, error) {
+	switch v := value.(type) {
+	case nil:
+		return true, "", nil
+	case string:
+		if v == "" || st.hasFlag(v) {
+			return true, "", nil
+		}
+		return false, fmt.Sprintf("需要先达成条件 %s", v), nil
+	case map[string]interface{}:
+		return evaluateRoomCondition(v, st)
+	default:
+		return false, "", fmt.Errorf("无法识别的房间条件: %v", value)
+	}
+}
+
+// planNextRoom 计算当前房间之后应进入的房间，返回目标下标与被跳过的房间
+func planNextRoom(seq roomSequence, current int, st *roomTraversalState) (int, []string, error) {
+	next := current + 1
+	var skipped []string
+	for hops := 0; next < len(seq); hops++ {
+		if hops > maxRoomSkipChain {
+			return -1, nil, fmt.Errorf("房间条件跳过次数过多，请检查地城配置")
+		}
+		step := seq[next]
+		if step.ConditionalSkip == nil {
+			return next, skipped, nil
+		}
+		ok, _, err := evaluateConditionValue(step.ConditionalSkip["condition"], st)
+		if err != nil {
+			return -1, nil, err
+		}
+		if _, hasCond := step.ConditionalSkip["condition"]; !hasCond || !ok {
+			return next, skipped, nil
+		}
+
+		skipped = append(skipped, step.key())
+		target := seq.resolveTarget(step.ConditionalSkip, "jump_to")
+		if target <= next {
+			target = next + 1
+		}
+		next = target
+	}
+	return len(seq), skipped, nil
+}
+
+// AdvanceRoom 前进到下一个房间（需要 enter_dungeon 权限）
+func (s *TeamDungeonService) AdvanceRoom(ctx context.Context, req *AdvanceRoomRequest) (*RoomTraversalResult, error) {
+	if req.TeamID == "" || req.HeroID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+	if _, err := s.ensurePermission(ctx, req.TeamID, req.HeroID, TeamPermEnterDungeon); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer tx.Rollback()
+
+	progress, seq, err := s.loadTraversalContext(ctx, tx, req.TeamID)
+	if err != nil {
+		return nil, err
+	}
+
+	completed := decodeRoomList(progress.CompletedRooms)
+	current := -1
+	if progress.CurrentRoomID.Valid && progress.CurrentRoomID.String != "" {
+		current = seq.indexOf(progress.CurrentRoomID.String)
+		if current < 0 {
+			return nil, xerrors.New(xerrors.CodeDataIntegrityError, "当前房间不在地城房间序列中")
+		}
+		if !containsString(completed, seq[current].key()) {
+			return nil, xerrors.New(xerrors.CodeInvalidParams, "当前房间尚未结算")
+		}
+	}
+
+	state, err := s.buildTraversalState(ctx, req.TeamID, seq, completed)
+	if err != nil {
+		return nil, err
+	}
+
+	next, skipped, err := planNextRoom(seq, current, state)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, "计算下一房间失败")
+	}
+
+	result := &RoomTraversalResult{Progress: progress, SkippedRooms: skipped}
+	if next >= len(seq) {
+		// 剩余房间全部被跳过即通关，与房间进度在同一事务内完成地城
+		if err := s.saveCompletedRooms(ctx, tx, progress, appendRooms(completed, skipped), "completed_rooms"); err != nil {
+			return nil, err
+		}
+		if err := s.completeProgressTx(ctx, tx, progress, req.HeroID, LootData{}); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+		}
+		result.DungeonCleared = true
+		return result, nil
+	}
+
+	step := seq[next]
+	room, err := s.loadRoom(ctx, step)
+	if err != nil {
+		return nil, err
+	}
+	if !room.IsActive {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "房间暂未开放")
+	}
+	conditions, err := decodeJSONObject(room.OpenConditions)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, "房间开启条件配置错误")
+	}
+	ok, reason, err := evaluateRoomCondition(conditions, state)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, "房间开启条件配置错误")
+	}
+	if !ok {
+		msg := fmt.Sprintf("房间开启条件未满足：%s", reason)
+		return nil, xerrors.New(xerrors.CodeInvalidParams, msg).WithMetadata("user_message", msg)
+	}
+
+	// 被跳过的房间视为已通过，便于后续条件判定
+	progress.CurrentRoomID = null.StringFrom(step.key())
+	if err := s.saveCompletedRooms(ctx, tx, progress, appendRooms(completed, skipped), "current_room_id", "completed_rooms"); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+
+	result.Room = room
+	return result, nil
+}
+
+// ResolveRoom 结算当前房间并按房间类型分发处理（需要 enter_dungeon 权限），最后一个房间结算成功时自动完成地城
+func (s *TeamDungeonService) ResolveRoom(ctx context.Context, req *ResolveRoomRequest) (*RoomTraversalResult, error) {
+	if req.TeamID == "" || req.HeroID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+	if _, err := s.ensurePermission(ctx, req.TeamID, req.HeroID, TeamPermEnterDungeon); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer tx.Rollback()
+
+	progress, seq, err := s.loadTraversalContext(ctx, tx, req.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	if !progress.CurrentRoomID.Valid || progress.CurrentRoomID.String == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "请先进入房间")
+	}
+	current := seq.indexOf(progress.CurrentRoomID.String)
+	if current < 0 {
+		return nil, xerrors.New(xerrors.CodeDataIntegrityError, "当前房间不在地城房间序列中")
+	}
+	step := seq[current]
+	if req.RoomID != "" && req.RoomID != step.key() && req.RoomID != step.RoomCode {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "当前所在房间与请求不一致")
+	}
+
+	completed := decodeRoomList(progress.CompletedRooms)
+	if containsString(completed, step.key()) {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "当前房间已结算")
+	}
+
+	room, err := s.loadRoom(ctx, step)
+	if err != nil {
+		return nil, err
+	}
+
+	resolution, err := s.dispatchRoom(ctx, tx, progress, room, req)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RoomTraversalResult{Progress: progress, Room: room, Resolution: resolution}
+	switch resolution.Outcome {
+	case RoomOutcomeSuccess:
+		completed = appendUnique(completed, step.key())
+		result.DungeonCleared = current == len(seq)-1
+	case RoomOutcomeFailure:
+		state, err := s.buildTraversalState(ctx, req.TeamID, seq, completed)
+		if err != nil {
+			return nil, err
+		}
+		target, err := planReturnRoom(seq, current, state)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, "房间回退配置错误")
+		}
+		if target < 0 {
+			// 没有回退规则时本次挑战失败
+			progress.Status = "failed"
+			progress.CompletedAt = null.TimeFrom(time.Now())
+			if err := s.progressRepo.Update(ctx, tx, progress, "status", "completed_at"); err != nil {
+				return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新地城进度失败")
+			}
+			if err := tx.Commit(); err != nil {
+				return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+			}
+			return result, nil
+		}
+		completed = trimCompletedFrom(seq, completed, target)
+		progress.CurrentRoomID = null.StringFrom(seq[target].key())
+		result.ReturnedTo = seq[target].key()
+	}
+
+	if err := s.saveCompletedRooms(ctx, tx, progress, completed, "current_room_id", "completed_rooms"); err != nil {
+		return nil, err
+	}
+
+	// 房间奖励与房间进度同事务发放，避免提交后发放失败无从重试
+	var warehouseID string
+	if resolution.Outcome == RoomOutcomeSuccess {
+		if warehouseID, err = s.grantRoomRewardsTx(ctx, tx, progress, resolution); err != nil {
+			return nil, err
+		}
+	}
+
+	// 最后一个房间结算成功即通关，与房间进度在同一事务内完成地城
+	if result.DungeonCleared {
+		if err := s.completeProgressTx(ctx, tx, progress, req.HeroID, LootData{}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+
+	s.finishRoomRewards(ctx, progress, resolution, warehouseID)
+	return result, nil
+}
+
+// GetCurrentRoom 查看团队当前所在房间（团队成员）
+func (s *TeamDungeonService) GetCurrentRoom(ctx context.Context, teamID, heroID string) (*RoomTraversalResult, error) {
+	if teamID == "" || heroID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+	if _, err := s.teamMemberRepo.GetByTeamAndHero(ctx, teamID, heroID); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodePermissionDenied, "您不是该团队成员")
+	}
+
+	progress, err := s.progressRepo.GetActiveByTeam(ctx, teamID)
+	if err != nil {
+		if errors.Is(err, interfaces.ErrTeamDungeonProgressNotFound) {
+			return nil, xerrors.New(xerrors.CodeInvalidParams, "没有正在进行的地城")
+		}
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询地城进度失败")
+	}
+
+	result := &RoomTraversalResult{Progress: progress}
+	if !progress.CurrentRoomID.Valid || progress.CurrentRoomID.String == "" {
+		return result, nil
+	}
+
+	dungeon, err := s.dungeonRepo.GetByID(ctx, progress.DungeonID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "地城不存在")
+	}
+	seq, err := parseRoomSequence(dungeon.RoomSequence)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, "地城房间序列配置错误")
+	}
+	current := seq.indexOf(progress.CurrentRoomID.String)
+	if current < 0 {
+		return result, nil
+	}
+	room, err := s.loadRoom(ctx, seq[current])
+	if err != nil {
+		return nil, err
+	}
+	result.Room = room
+	result.DungeonCleared = current == len(seq)-1 && containsString(decodeRoomList(progress.CompletedRooms), seq[current].key())
+	return result, nil
+}
+
+// dispatchRoom 按房间类型分发结算逻辑
+func (s *TeamDungeonService) dispatchRoom(ctx context.Context, tx *sql.Tx, progress *game_runtime.TeamDungeonProgress, room *game_config.DungeonRoom, req *ResolveRoomRequest) (*RoomResolution, error) {
+	resolution := &RoomResolution{
+		RoomID:   room.ID,
+		RoomType: room.RoomType,
+		Outcome:  RoomOutcomeSuccess,
+	}
+	if room.TriggerID.Valid {
+		resolution.TriggerID = room.TriggerID.String
+	}
+
+	switch room.RoomType {
+	case RoomTypeBattle:
+		return s.resolveBattleRoom(ctx, tx, progress, room, resolution, req.BattleID)
+	case RoomTypeEvent:
+		return s.resolveEventRoom(ctx, tx, progress, resolution, req.HeroID)
+	case RoomTypeTreasure:
+		return s.resolveTreasureRoom(ctx, tx, progress, resolution, req.HeroID)
+	case RoomTypeRest:
+		return resolution, nil
+	default:
+		return nil, xerrors.New(xerrors.CodeDataIntegrityError, fmt.Sprintf("不支持的房间类型: %s", room.RoomType))
+	}
+}
+
+// resolveBattleRoom 战斗房间：按战斗引擎回调并已结算的战报判定成功或失败，同一场战斗只能结算一次房间
+func (s *TeamDungeonService) resolveBattleRoom(ctx context.Context, tx *sql.Tx, progress *game_runtime.TeamDungeonProgress, room *game_config.DungeonRoom, resolution *RoomResolution, battleID string) (*RoomResolution, error) {
+	if battleID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "战斗房间需要提供战斗ID")
+	}
+	report, err := s.battleReportRepo.GetSettledReport(ctx, battleID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询战斗结果失败")
+	}
+	if report == nil {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "战斗结果不存在或尚未结算")
+	}
+	if report.ProgressID != progress.ID || (report.RoomID != room.ID && report.RoomID != room.RoomCode) {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "战斗结果不属于当前房间")
+	}
+
+	marked, err := s.battleStepRepo.MarkStep(ctx, tx, battleID, interfaces.BattleStepRoomResolved, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "登记战斗结算步骤失败")
+	}
+	if !marked {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "该战斗结果已用于结算房间")
+	}
+
+	// 胜利即通过，失败、平局等均按房间失败处理
+	resolution.Outcome = RoomOutcomeFailure
+	if report.ResultStatus == "victory" {
+		resolution.Outcome = RoomOutcomeSuccess
+	}
+	resolution.Detail = map[string]interface{}{
+		"battle_id":     battleID,
+		"result_status": report.ResultStatus,
+	}
+	return resolution, nil
+}
+
+// resolveTreasureRoom 宝箱房间：trigger_id 指向的地城事件配置宝箱掉落与奖励，与事件房间同样结算并发放
+func (s *TeamDungeonService) resolveTreasureRoom(ctx context.Context, tx *sql.Tx, progress *game_runtime.TeamDungeonProgress, resolution *RoomResolution, operatorID string) (*RoomResolution, error) {
+	if resolution.TriggerID == "" || s.eventRepo == nil {
+		return nil, xerrors.New(xerrors.CodeDataIntegrityError, "宝箱房间未配置奖励事件")
+	}
+	return s.resolveEventRoom(ctx, tx, progress, resolution, operatorID)
+}
+
+// saveCompletedRooms 写入已完成房间列表，columns 为需要更新的进度字段
+func (s *TeamDungeonService) saveCompletedRooms(ctx context.Context, tx *sql.Tx, progress *game_runtime.TeamDungeonProgress, completed []string, columns ...string) error {
+	completedJSON, err := json.Marshal(completed)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "序列化已完成房间失败")
+	}
+	progress.CompletedRooms = types.JSON(completedJSON)
+	if err := s.progressRepo.Update(ctx, tx, progress, columns...); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "更新地城进度失败")
+	}
+	return nil
+}
+
+// loadDungeonEvent 根据房间 trigger_id 查询事件配置（兼容事件代码与事件ID）
+func (s *TeamDungeonService) loadDungeonEvent(ctx context.Context, triggerID string) (*game_config.DungeonEvent, error) {
+	event, err := s.eventRepo.GetByCode(ctx, triggerID)
+	if err != nil {
+		event, err = s.eventRepo.GetByID(ctx, triggerID)
+	}
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "房间事件配置不存在")
+	}
+	if !event.IsActive {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "房间事件暂未开放")
+	}
+	return event, nil
+}
+
+// loadTraversalContext 锁定进行中的进度并加载地城与房间序列
+func (s *TeamDungeonService) loadTraversalContext(ctx context.Context, tx *sql.Tx, teamID string) (*game_runtime.TeamDungeonProgress, roomSequence, error) {
+	progress, err := s.progressRepo.GetActiveByTeamForUpdate(ctx, tx, teamID)
+	if err != nil {
+		if errors.Is(err, interfaces.ErrTeamDungeonProgressNotFound) {
+			return nil, nil, xerrors.New(xerrors.CodeInvalidParams, "没有正在进行的地城")
+		}
+		return nil, nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询地城进度失败")
+	}
+	dungeon, err := s.dungeonRepo.GetByID(ctx, progress.DungeonID)
+	if err != nil {
+		return nil, nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "地城不存在")
+	}
+	seq, err := parseRoomSequence(dungeon.RoomSequence)
+	if err != nil {
+		return nil, nil, xerrors.Wrap(err, xerrors.CodeDataIntegrityError, "地城房间序列配置错误")
+	}
+	if len(seq) == 0 {
+		return nil, nil, xerrors.New(xerrors.CodeDataIntegrityError, "地城未配置房间序列")
+	}
+	return progress, seq, nil
+}
+
+// loadRoom 加载房间配置
+func (s *TeamDungeonService) loadRoom(ctx context.Context, step dungeonRoomStep) (*game_config.DungeonRoom, error) {
+	if s.roomRepo == nil {
+		return nil, xerrors.New(xerrors.CodeInternalError, "房间仓储未配置")
+	}
+	var (
+		room *game_config.DungeonRoom
+		err  error
+	)
+	if step.RoomID != "" {
+		room, err = s.roomRepo.GetByID(ctx, step.RoomID)
+	} else {
+		room, err = s.roomRepo.GetByCode(ctx, step.RoomCode)
+	}
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "房间配置不存在")
+	}
+	return room, nil
+}
+
+// buildTraversalState 构建条件判定上下文
+func (s *TeamDungeonService) buildTraversalState(ctx context.Context, teamID string, seq roomSequence, completed []string) (*roomTraversalState, error) {
+	members, err := s.teamMemberRepo.ListByTeam(ctx, teamID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询成员失败")
+	}
+
+	state := &roomTraversalState{
+		flags:       make(map[string]bool, len(completed)*2),
+		memberCount: len(members),
+	}
+	for _, key := range completed {
+		state.flags[key] = true
+		if idx := seq.indexOf(key); idx >= 0 && seq[idx].RoomCode != "" {
+			state.flags[seq[idx].RoomCode] = true
+		}
+	}
+
+	heroIDs := make([]string, 0, len(members))
+	for _, member := range members {
+		heroIDs = append(heroIDs, member.HeroID)
+		hero, err := s.heroRepo.GetByID(ctx, member.HeroID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询英雄信息失败")
+		}
+		state.heroLevels = append(state.heroLevels, int(hero.CurrentLevel))
+	}
+
+	state.itemChecker = func(itemRef string) (bool, error) {
+		return s.teamHoldsItem(ctx, heroIDs, itemRef)
+	}
+	return state, nil
+}
+
+// teamHoldsItem 判断团队成员是否持有指定物品（背包或已装备，支持物品代码或ID）
+func (s *TeamDungeonService) teamHoldsItem(ctx context.Context, heroIDs []string, itemRef string) (bool, error) {
+	if len(heroIDs) == 0 {
+		return false, nil
+	}
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `
+SELECT EXISTS (
+	SELECT 1
+	FROM game_runtime.player_items pi
+	JOIN game_config.items i ON i.id = pi.item_id
+	WHERE pi.hero_id = ANY($1::uuid[])
+	  AND pi.item_location IN ('backpack', 'equipped')
+	  AND pi.deleted_at IS NULL
+	  AND (i.item_code = $2 OR i.id::text = $2)
+)`, pq.Array(heroIDs), itemRef).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("查询团队物品失败: %w", err)
+	}
+	return exists, nil
+}
+
+// planReturnRoom 计算失败后的回退房间，无回退规则时返回 -1
+func planReturnRoom(seq roomSequence, current int, st *roomTraversalState) (int, error) {
+	rule := seq[current].ConditionalReturn
+	if rule == nil {
+		return -1, nil
+	}
+	if cond, ok := rule["condition"].(map[string]interface{}); ok {
+		matched, _, err := evaluateRoomCondition(cond, st)
+		if err != nil {
+			return -1, err
+		}
+		if !matched {
+			return -1, nil
+		}
+	}
+	target := seq.resolveTarget(rule, "return_to")
+	if target < 0 || target > current {
+		return -1, fmt.Errorf("回退目标房间无效")
+	}
+	return target, nil
+}
+
+// trimCompletedFrom 回退时移除目标房间及其之后的完成记录
+func trimCompletedFrom(seq roomSequence, completed []string, target int) []string {
+	result := make([]string, 0, len(completed))
+	for _, key := range completed {
+		if idx := seq.indexOf(key); idx >= target {
+			continue
+		}
+		result = append(result, key)
+	}
+	return result
+}
+
+func decodeRoomList(data types.JSON) []string {
+	rooms := []string{}
+	if len(data) == 0 {
+		return rooms
+	}
+	if err := json.Unmarshal(data, &rooms); err != nil {
+		return []string{}
+	}
+	return rooms
+}
+
+func decodeJSONObject(data types.JSON) (map[string]interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func containsString(list []string, target string) bool {
+	for _, item := range list {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUnique(list []string, value string) []string {
+	if containsString(list, value) {
+		return list
+	}
+	return append(list, value)
+}
+
+func appendRooms(list []string, values []string) []string {
+	for _, value := range values {
+		list = appendUnique(list, value)
+	}
+	return list
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aarondl/sqlboiler/v4/types"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/repository/interfaces"
+)
+
+func TestParseRoomSequence_SortsBySort(t *testing.T) {
+	seq, err := parseRoomSequence(types.JSON(`[
+		{"room_id":"r3","sort":3},
+		{"room_id":"r1","sort":1},
+		{"room_id":"","sort":4},
+		{"room_id":"r2","sort":2}
+	]`))
+	require.NoError(t, err)
+	require.Len(t, seq, 3)
+	require.Equal(t, "r1", seq[0].key())
+	require.Equal(t, "r2", seq[1].key())
+	require.Equal(t, "r3", seq[2].key())
+
+	_, err = parseRoomSequence(types.JSON(`{"room_id":"r1"}`))
+	require.Error(t, err)
+}
+
+func TestPlanNextRoom_ConditionalSkip(t *testing.T) {
+	seq, err := parseRoomSequence(types.JSON(`[
+		{"room_id":"r1","sort":1},
+		{"room_id":"r2","sort":2,"conditional_skip":{"condition":"has_key","jump_to":4}},
+		{"room_id":"r3","sort":3},
+		{"room_id":"r4","sort":4}
+	]`))
+	require.NoError(t, err)
+
+	state := &roomTraversalState{flags: map[string]bool{}}
+	next, skipped, err := planNextRoom(seq, 0, state)
+	require.NoError(t, err)
+	require.Equal(t, 1, next)
+	require.Empty(t, skipped)
+
+	state.flags["has_key"] = true
+	next, skipped, err = planNextRoom(seq, 0, state)
+	require.NoError(t, err)
+	require.Equal(t, 3, next)
+	require.Equal(t, []string{"r2"}, skipped)
+
+	next, _, err = planNextRoom(seq, 3, state)
+	require.NoError(t, err)
+	require.Equal(t, len(seq), next)
+}
+
+func TestPlanNextRoom_SkipByTargetRoom(t *testing.T) {
+	seq, err := parseRoomSequence(types.JSON(`[
+		{"room_id":"r1","sort":1,"conditional_skip":{"condition":{"type":"min_members","value":2},"target_room":"r3"}},
+		{"room_id":"r2","sort":2},
+		{"room_id":"r3","sort":3}
+	]`))
+	require.NoError(t, err)
+
+	next, skipped, err := planNextRoom(seq, -1, &roomTraversalState{memberCount: 3})
+	require.NoError(t, err)
+	require.Equal(t, 2, next)
+	require.Equal(t, []string{"r1"}, skipped)
+}
+
+func TestEvaluateRoomCondition(t *testing.T) {
+	state := &roomTraversalState{
+		flags:       map[string]bool{"boss_dead": true},
+		memberCount: 2,
+		heroLevels:  []int{10, 15},
+		itemChecker: func(itemRef string) (bool, error) { return itemRef == "key_gold", nil },
+	}
+
+	cases := []struct {
+		name string
+		cond map[string]interface{}
+		want bool
+	}{
+		{"empty", nil, true},
+		{"flag ok", map[string]interface{}{"type": "check_flag", "flag": "boss_dead"}, true},
+		{"flag missing", map[string]interface{}{"type": "require_flag", "flag": "altar"}, false},
+		{"item ok", map[string]interface{}{"type": "require_item", "item_code": "key_gold"}, true},
+		{"item missing", map[string]interface{}{"type": "require_item", "item_code": "key_silver"}, false},
+		{"min level", map[string]interface{}{"type": "min_level", "value": float64(12)}, false},
+		{"max level", map[string]interface{}{"type": "max_level", "value": float64(20)}, true},
+		{"min members", map[string]interface{}{"type": "min_members", "value": float64(3)}, false},
+		{"any", map[string]interface{}{"type": "any", "conditions": []interface{}{"altar", "boss_dead"}}, true},
+		{"all", map[string]interface{}{"type": "all", "conditions": []interface{}{"altar", "boss_dead"}}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, reason, err := evaluateRoomCondition(tc.cond, state)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, ok)
+			if !ok {
+				require.NotEmpty(t, reason)
+			}
+		})
+	}
+
+	_, _, err := evaluateRoomCondition(map[string]interface{}{"type": "unknown"}, state)
+	require.Error(t, err)
+}
+
+func TestPlanReturnRoom(t *testing.T) {
+	seq, err := parseRoomSequence(types.JSON(`[
+		{"room_id":"r1","sort":1},
+		{"room_id":"r2","sort":2},
+		{"room_id":"r3","sort":3,"conditional_return":{"return_to":2}},
+		{"room_id":"r4","sort":4,"conditional_return":{"return_to":5}}
+	]`))
+	require.NoError(t, err)
+	state := &roomTraversalState{flags: map[string]bool{}}
+
+	target, err := planReturnRoom(seq, 1, state)
+	require.NoError(t, err)
+	require.Equal(t, -1, target)
+
+	target, err = planReturnRoom(seq, 2, state)
+	require.NoError(t, err)
+	require.Equal(t, 1, target)
+	require.Equal(t, []string{"r1"}, trimCompletedFrom(seq, []string{"r1", "r2"}, target))
+
+	_, err = planReturnRoom(seq, 3, state)
+	require.Error(t, err)
+}
+
+func TestResolveBattleRoom_UsesSettledReport(t *testing.T) {
+	ctx := context.Background()
+	reports := &fakeBattleReportRepo{
+		reports: []*interfaces.BattleReport{
+			{BattleID: "battle-win", ProgressID: "progress-1", RoomID: "room-1", ResultStatus: "victory"},
+			{BattleID: "battle-lose", ProgressID: "progress-1", RoomID: "room-1", ResultStatus: "defeat"},
+			{BattleID: "battle-other", ProgressID: "progress-2", RoomID: "room-1", ResultStatus: "victory"},
+			{BattleID: "battle-pending", ProgressID: "progress-1", RoomID: "room-1", ResultStatus: "victory"},
+		},
+		outcomes: map[string]*interfaces.BattleOutcome{
+			"battle-win":     {Status: interfaces.BattleOutcomeCompleted},
+			"battle-lose":    {Status: interfaces.BattleOutcomeCompleted},
+			"battle-other":   {Status: interfaces.BattleOutcomeCompleted},
+			"battle-pending": {Status: interfaces.BattleOutcomeProcessing},
+		},
+	}
+	svc := &TeamDungeonService{battleReportRepo: reports, battleStepRepo: &fakeBattleStepRepo{}}
+	progress := &game_runtime.TeamDungeonProgress{ID: "progress-1"}
+	room := &game_config.DungeonRoom{ID: "room-1", RoomType: RoomTypeBattle}
+
+	resolve := func(battleID string) (*RoomResolution, error) {
+		return svc.resolveBattleRoom(ctx, nil, progress, room, &RoomResolution{RoomID: room.ID}, battleID)
+	}
+
+	resolution, err := resolve("battle-win")
+	require.NoError(t, err)
+	require.Equal(t, RoomOutcomeSuccess, resolution.Outcome)
+
+	resolution, err = resolve("battle-lose")
+	require.NoError(t, err)
+	require.Equal(t, RoomOutcomeFailure, resolution.Outcome)
+
+	_, err = resolve("battle-win")
+	require.Error(t, err, "同一场战斗只能结算一次房间")
+
+	for _, battleID := range []string{"", "battle-other", "battle-pending", "battle-missing"} {
+		_, err = resolve(battleID)
+		require.Error(t, err, battleID)
+	}
+}
+
+func TestResolveTreasureRoom_RequiresRewardEvent(t *testing.T) {
+	svc := &TeamDungeonService{}
+	_, err := svc.resolveTreasureRoom(context.Background(), nil, &game_runtime.TeamDungeonProgress{}, &RoomResolution{RoomType: RoomTypeTreasure}, "hero-1")
+	require.Error(t, err, "未配置奖励的宝箱房间不能结算")
+}
@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/aarondl/null/v8"
@@ -20,23 +19,56 @@ import (
 
 // TeamDungeonDependencies 注入 TeamDungeonService 所需依赖
 type TeamDungeonDependencies struct {
-	WarehouseService *TeamWarehouseService
-	TeamMemberRepo   interfaces.TeamMemberRepository
-	DungeonRepo      interfaces.DungeonRepository
-	ProgressRepo     interfaces.TeamDungeonProgressRepository
-	RecordRepo       interfaces.TeamDungeonRecordRepository
-	HeroRepo         interfaces.HeroRepository
+	WarehouseService  *TeamWarehouseService
+	TeamMemberRepo    interfaces.TeamMemberRepository
+	TeamRankRepo      interfaces.TeamRankRepository
+	DungeonRepo       interfaces.DungeonRepository
+	ScheduleRepo      interfaces.DungeonScheduleRepository
+	RoomRepo          interfaces.DungeonRoomRepository
+	EventRepo         interfaces.DungeonEventRepository
+	ProgressRepo      interfaces.TeamDungeonProgressRepository
+	RecordRepo        interfaces.TeamDungeonRecordRepository
+	HeroRepo          interfaces.HeroRepository
+	ItemRepo          interfaces.ItemRepository
+	BuffRepo          interfaces.BuffRepository
+	ActiveBuffRepo    interfaces.HeroActiveBuffRepository
+	EventLogRepo      interfaces.TeamDungeonEventLogRepository
+	ActivityRepo      interfaces.TeamActivityRepository
+	BattleStepRepo    interfaces.BattleResultStepRepository
+	BattleReportRepo  interfaces.BattleReportRepository
+	HeroService       *HeroService
+	DropService       *ItemDropService
+	CurrencyService   *CurrencyService
+	ExperienceService *ExperienceAwardService
+
+	ContributionService *TeamContributionService
 }
 
 // TeamDungeonService 团队地城服务
 type TeamDungeonService struct {
 	db                   *sql.DB
 	teamMemberRepo       interfaces.TeamMemberRepository
+	teamRankRepo         interfaces.TeamRankRepository
 	dungeonRepo          interfaces.DungeonRepository
+	scheduleRepo         interfaces.DungeonScheduleRepository
+	roomRepo             interfaces.DungeonRoomRepository
+	eventRepo            interfaces.DungeonEventRepository
 	progressRepo         interfaces.TeamDungeonProgressRepository
 	recordRepo           interfaces.TeamDungeonRecordRepository
 	heroRepo             interfaces.HeroRepository
+	itemRepo             interfaces.ItemRepository
+	buffRepo             interfaces.BuffRepository
+	activeBuffRepo       interfaces.HeroActiveBuffRepository
+	eventLogRepo         interfaces.TeamDungeonEventLogRepository
+	teamActivityRepo     interfaces.TeamActivityRepository
+	battleStepRepo       interfaces.BattleResultStepRepository
+	battleReportRepo     interfaces.BattleReportRepository
 	teamWarehouseService *TeamWarehouseService
+	heroService          *HeroService
+	dropService          *ItemDropService
+	currencyService      *CurrencyService
+	experienceService    *ExperienceAwardService
+	contributionService  *TeamContributionService
 }
 
 // NewTeamDungeonService 创建团队地城服务
@@ -47,9 +79,21 @@ func NewTeamDungeonService(db *sql.DB, deps *TeamDungeonDependencies) *TeamDunge
 	if deps.TeamMemberRepo == nil {
 		deps.TeamMemberRepo = impl.NewTeamMemberRepository(db)
 	}
+	if deps.TeamRankRepo == nil {
+		deps.TeamRankRepo = impl.NewTeamRankRepository(db)
+	}
 	if deps.DungeonRepo == nil {
 		deps.DungeonRepo = impl.NewDungeonRepository(db)
 	}
+	if deps.ScheduleRepo == nil {
+		deps.ScheduleRepo = impl.NewDungeonScheduleRepository(db)
+	}
+	if deps.RoomRepo == nil {
+		deps.RoomRepo = impl.NewDungeonRoomRepository(db)
+	}
+	if deps.EventRepo == nil {
+		deps.EventRepo = impl.NewDungeonEventRepository(db)
+	}
 	if deps.ProgressRepo == nil {
 		deps.ProgressRepo = impl.NewTeamDungeonProgressRepository(db)
 	}
@@ -59,28 +103,82 @@ func NewTeamDungeonService(db *sql.DB, deps *TeamDungeonDependencies) *TeamDunge
 	if deps.HeroRepo == nil {
 		deps.HeroRepo = impl.NewHeroRepository(db)
 	}
+	if deps.ItemRepo == nil {
+		deps.ItemRepo = impl.NewItemRepository(db)
+	}
+	if deps.BuffRepo == nil {
+		deps.BuffRepo = impl.NewBuffRepository(db)
+	}
+	if deps.ActiveBuffRepo == nil {
+		deps.ActiveBuffRepo = impl.NewHeroActiveBuffRepository(db)
+	}
+	if deps.EventLogRepo == nil {
+		deps.EventLogRepo = impl.NewTeamDungeonEventLogRepository(db)
+	}
+	if deps.ActivityRepo == nil {
+		deps.ActivityRepo = impl.NewTeamActivityRepository(db)
+	}
+	if deps.BattleStepRepo == nil {
+		deps.BattleStepRepo = impl.NewBattleResultStepRepository(db)
+	}
+	if deps.BattleReportRepo == nil {
+		deps.BattleReportRepo = impl.NewBattleReportRepository(db)
+	}
+	if deps.HeroService == nil {
+		deps.HeroService = NewHeroService(db)
+	}
+	if deps.DropService == nil {
+		deps.DropService = NewItemDropService(db)
+	}
+	if deps.CurrencyService == nil {
+		deps.CurrencyService = NewCurrencyService(db)
+	}
+	if deps.ExperienceService == nil {
+		deps.ExperienceService = NewExperienceAwardService(db)
+	}
 	if deps.WarehouseService == nil {
 		deps.WarehouseService = &TeamWarehouseService{
 			db:                    db,
 			teamMemberRepo:        deps.TeamMemberRepo,
+			teamRankRepo:          deps.TeamRankRepo,
 			teamWarehouseRepo:     impl.NewTeamWarehouseRepository(db),
 			teamWarehouseItemRepo: impl.NewTeamWarehouseItemRepository(db),
-			heroWalletRepo:        impl.NewHeroWalletRepository(db),
+			heroCurrencyRepo:      impl.NewHeroCurrencyRepository(db),
 			lootHistoryRepo:       impl.NewTeamLootHistoryRepository(db),
 			lootLogRepo:           impl.NewTeamWarehouseLootLogRepository(db),
-			itemRepo:              impl.NewItemRepository(db),
+			teamActivityRepo:      deps.ActivityRepo,
+			itemRepo:              deps.ItemRepo,
 			heroRepo:              deps.HeroRepo,
 		}
 	}
+	if deps.ContributionService == nil {
+		deps.ContributionService = NewTeamContributionService(db, deps.WarehouseService)
+	}
 
 	return &TeamDungeonService{
 		db:                   db,
 		teamMemberRepo:       deps.TeamMemberRepo,
+		teamRankRepo:         deps.TeamRankRepo,
 		dungeonRepo:          deps.DungeonRepo,
+		scheduleRepo:         deps.ScheduleRepo,
+		roomRepo:             deps.RoomRepo,
+		eventRepo:            deps.EventRepo,
 		progressRepo:         deps.ProgressRepo,
 		recordRepo:           deps.RecordRepo,
 		heroRepo:             deps.HeroRepo,
+		itemRepo:             deps.ItemRepo,
+		buffRepo:             deps.BuffRepo,
+		activeBuffRepo:       deps.ActiveBuffRepo,
+		eventLogRepo:         deps.EventLogRepo,
+		teamActivityRepo:     deps.ActivityRepo,
+		battleStepRepo:       deps.BattleStepRepo,
+		battleReportRepo:     deps.BattleReportRepo,
 		teamWarehouseService: deps.WarehouseService,
+		heroService:          deps.HeroService,
+		dropService:          deps.DropService,
+		currencyService:      deps.CurrencyService,
+		experienceService:    deps.ExperienceService,
+		contributionService:  deps.ContributionService,
 	}
 }
 
@@ -103,7 +201,8 @@ type CompleteDungeonRequest struct {
 	TeamID    string
 	HeroID    string
 	DungeonID string
-	Loot      LootData // 战利品
+	BattleID  string   // 战斗结算触发时非空，同一场战斗只通关一次
+	Loot      LootData // 战利品，仅战斗结算路径使用
 }
 
 // FailDungeonRequest 地城失败请求
@@ -128,13 +227,13 @@ type LootData struct {
 	Items []LootItem
 }
 
-// SelectDungeon 选择地城（队长或管理员）
+// SelectDungeon 选择地城（需要 select_dungeon 权限）
 func (s *TeamDungeonService) SelectDungeon(ctx context.Context, req *SelectDungeonRequest) (*game_runtime.TeamDungeonProgress, error) {
 	if req.TeamID == "" || req.HeroID == "" || req.DungeonID == "" {
 		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
 	}
 
-	if _, err := s.ensureLeaderOrAdmin(ctx, req.TeamID, req.HeroID); err != nil {
+	if _, err := s.ensurePermission(ctx, req.TeamID, req.HeroID, TeamPermSelectDungeon); err != nil {
 		return nil, err
 	}
 
@@ -145,7 +244,7 @@ func (s *TeamDungeonService) SelectDungeon(ctx context.Context, req *SelectDunge
 	if !dungeon.IsActive {
 		return nil, xerrors.New(xerrors.CodeInvalidParams, "地城暂未开放")
 	}
-	if err := s.checkDungeonWindow(dungeon); err != nil {
+	if err := s.checkDungeonWindow(ctx, dungeon); err != nil {
 		return nil, err
 	}
 	if err := s.checkTeamRequirements(ctx, req.TeamID, dungeon); err != nil {
@@ -202,6 +301,10 @@ func (s *TeamDungeonService) SelectDungeon(ctx context.Context, req *SelectDunge
 		}
 	}
 
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, dungeonActivity(interfaces.TeamActivityDungeonSelected, req.HeroID, progress)); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
 	}
@@ -214,7 +317,7 @@ func (s *TeamDungeonService) EnterDungeon(ctx context.Context, req *EnterDungeon
 	if req.TeamID == "" || req.HeroID == "" {
 		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
 	}
-	if _, err := s.ensureLeaderOrAdmin(ctx, req.TeamID, req.HeroID); err != nil {
+	if _, err := s.ensurePermission(ctx, req.TeamID, req.HeroID, TeamPermEnterDungeon); err != nil {
 		return nil, err
 	}
 
@@ -236,6 +339,14 @@ func (s *TeamDungeonService) EnterDungeon(ctx context.Context, req *EnterDungeon
 		return nil, xerrors.New(xerrors.CodeInvalidParams, "当前正在挑战其他地城")
 	}
 
+	dungeon, err := s.dungeonRepo.GetByID(ctx, progress.DungeonID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "地城不存在")
+	}
+	if err := s.checkDungeonWindow(ctx, dungeon); err != nil {
+		return nil, err
+	}
+
 	record, err := s.recordRepo.GetByTeamAndDungeonForUpdate(ctx, tx, req.TeamID, progress.DungeonID)
 	if err != nil && !errors.Is(err, interfaces.ErrTeamDungeonRecordNotFound) {
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询地城记录失败")
@@ -256,6 +367,10 @@ func (s *TeamDungeonService) EnterDungeon(ctx context.Context, req *EnterDungeon
 		}
 	}
 
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, dungeonActivity(interfaces.TeamActivityDungeonEntered, req.HeroID, progress)); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
 	}
@@ -286,10 +401,17 @@ func (s *TeamDungeonService) CompleteDungeon(ctx context.Context, req *CompleteD
 	if req.TeamID == "" || req.DungeonID == "" {
 		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
 	}
-	if req.HeroID != "" {
+	loot := req.Loot
+	if req.BattleID == "" {
+		// 玩家手动完成仅限队长/管理员，且不接受客户端战利品，掉落只由战斗结算在服务端计算
+		loot = LootData{}
 		if _, err := s.ensureLeaderOrAdmin(ctx, req.TeamID, req.HeroID); err != nil {
 			return nil, err
 		}
+	} else if req.HeroID != "" {
+		if _, err := s.ensurePermission(ctx, req.TeamID, req.HeroID, TeamPermEnterDungeon); err != nil {
+			return nil, err
+		}
 	}
 
 	tx, err := s.db.BeginTx(ctx, nil)
@@ -309,31 +431,73 @@ func (s *TeamDungeonService) CompleteDungeon(ctx context.Context, req *CompleteD
 		return nil, xerrors.New(xerrors.CodeInvalidParams, "当前地城与请求不一致")
 	}
 
-	progress.Status = "completed"
-	progress.CompletedAt = null.TimeFrom(time.Now())
-	if err := s.progressRepo.Update(ctx, tx, progress, "status", "completed_at"); err != nil {
-		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新地城进度失败")
+	if err := s.completeProgressTx(ctx, tx, progress, req.HeroID, loot); err != nil {
+		return nil, err
+	}
+
+	// 登记战斗结算步骤与通关在同一事务内提交，中断后重新结算时不会重复发放
+	if req.BattleID != "" && s.battleStepRepo != nil {
+		progressJSON, err := json.Marshal(progress)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "序列化地城进度失败")
+		}
+		marked, err := s.battleStepRepo.MarkStep(ctx, tx, req.BattleID, interfaces.BattleStepDungeonCompleted, progressJSON)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "登记战斗结算步骤失败")
+		}
+		if !marked {
+			return nil, xerrors.New(xerrors.CodeResourceLocked, "该战斗已完成地城结算")
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
 	}
 
-	if err := s.awardLoot(ctx, req); err != nil {
+	if err := s.awardLoot(ctx, req.TeamID, req.DungeonID, loot); err != nil {
 		return nil, err
 	}
 	return progress, nil
 }
 
+// completeProgressTx 在事务内将地城进度标记为通关并发放通关货币与贡献点，战利品由调用方提交后入库
+func (s *TeamDungeonService) completeProgressTx(ctx context.Context, tx *sql.Tx, progress *game_runtime.TeamDungeonProgress, heroID string, loot LootData) error {
+	progress.Status = "completed"
+	progress.CompletedAt = null.TimeFrom(time.Now())
+	if err := s.progressRepo.Update(ctx, tx, progress, "status", "completed_at"); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "更新地城进度失败")
+	}
+
+	// 通关货币奖励与进度完成在同一事务内发放
+	if err := s.grantCompletionCurrencies(ctx, tx, progress); err != nil {
+		return err
+	}
+
+	// 通关贡献点同样在事务内发放
+	if s.contributionService != nil {
+		if err := s.contributionService.GrantDungeonCompletionTx(ctx, tx, progress); err != nil {
+			return err
+		}
+	}
+
+	completed := dungeonActivity(interfaces.TeamActivityDungeonCompleted, heroID, progress)
+	completed.Details["loot_gold"] = loot.Gold
+	completed.Details["loot_item_kinds"] = len(loot.Items)
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, completed); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+	}
+	return nil
+}
+
 // FailDungeon 地城挑战失败
 func (s *TeamDungeonService) FailDungeon(ctx context.Context, req *FailDungeonRequest) (*game_runtime.TeamDungeonProgress, error) {
 	if req.TeamID == "" || req.DungeonID == "" {
 		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
 	}
-	if _, err := s.ensureLeaderOrAdmin(ctx, req.TeamID, req.HeroID); err != nil {
+	if _, err := s.ensurePermission(ctx, req.TeamID, req.HeroID, TeamPermEnterDungeon); err != nil {
 		return nil, err
 	}
-	return s.updateProgressStatus(ctx, req.TeamID, req.DungeonID, "failed")
+	return s.updateProgressStatus(ctx, req.TeamID, req.DungeonID, req.HeroID, "failed")
 }
 
 // AbandonDungeon 放弃地城
@@ -341,10 +505,10 @@ func (s *TeamDungeonService) AbandonDungeon(ctx context.Context, req *AbandonDun
 	if req.TeamID == "" || req.DungeonID == "" || req.HeroID == "" {
 		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
 	}
-	if _, err := s.ensureLeaderOrAdmin(ctx, req.TeamID, req.HeroID); err != nil {
+	if _, err := s.ensurePermission(ctx, req.TeamID, req.HeroID, TeamPermAbandonDungeon); err != nil {
 		return nil, err
 	}
-	return s.updateProgressStatus(ctx, req.TeamID, req.DungeonID, "abandoned")
+	return s.updateProgressStatus(ctx, req.TeamID, req.DungeonID, req.HeroID, "abandoned")
 }
 
 // GetChallengeHistory 查看历史
@@ -363,58 +527,54 @@ func (s *TeamDungeonService) GetChallengeHistory(ctx context.Context, teamID, he
 	return records, total, nil
 }
 
+func (s *TeamDungeonService) ensurePermission(ctx context.Context, teamID, heroID, permission string) (*game_runtime.TeamMember, error) {
+	member, err := s.teamMemberRepo.GetByTeamAndHero(ctx, teamID, heroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodePermissionDenied, "您不是该团队成员")
+	}
+	if _, err := requireTeamPermission(ctx, s.teamRankRepo, member, permission, "您没有执行该操作的权限"); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// ensureLeaderOrAdmin 要求队长或管理员身份（不随职级权限放宽）
 func (s *TeamDungeonService) ensureLeaderOrAdmin(ctx context.Context, teamID, heroID string) (*game_runtime.TeamMember, error) {
 	member, err := s.teamMemberRepo.GetByTeamAndHero(ctx, teamID, heroID)
 	if err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodePermissionDenied, "您不是该团队成员")
 	}
 	if member.Role != "leader" && member.Role != "admin" {
-		return nil, xerrors.New(xerrors.CodePermissionDenied, "只有队长和管理员可以执行该操作")
+		return nil, xerrors.New(xerrors.CodePermissionDenied, "需要管理员或队长权限")
 	}
 	return member, nil
 }
 
-func (s *TeamDungeonService) checkDungeonWindow(dungeon *game_config.Dungeon) error {
-	if dungeon.IsTimeLimited {
-		if !dungeon.TimeLimitStart.Valid || !dungeon.TimeLimitEnd.Valid {
-			return xerrors.New(xerrors.CodeInvalidParams, "地城时间配置不正确")
-		}
-		now := time.Now()
-		if now.Before(dungeon.TimeLimitStart.Time) || now.After(dungeon.TimeLimitEnd.Time) {
-			return xerrors.New(xerrors.CodeInvalidParams, "当前不在地城开放时间")
-		}
+// checkDungeonWindow 校验限时范围与周期开放时间表
+func (s *TeamDungeonService) checkDungeonWindow(ctx context.Context, dungeon *game_config.Dungeon) error {
+	sched, err := s.scheduleRepo.GetByDungeonID(ctx, dungeon.ID)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "查询地城开放时间表失败")
 	}
-	return nil
+	return evaluateDungeonOpening(dungeon, sched, time.Now()).openingError()
 }
 
+// checkTeamRequirements 校验团队人数与成员等级，返回首个不满足的条件
 func (s *TeamDungeonService) checkTeamRequirements(ctx context.Context, teamID string, dungeon *game_config.Dungeon) error {
-	members, err := s.teamMemberRepo.ListByTeam(ctx, teamID)
+	roster, err := s.loadTeamRoster(ctx, teamID)
 	if err != nil {
-		return xerrors.Wrap(err, xerrors.CodeInternalError, "查询成员失败")
-	}
-	if len(members) == 0 {
-		return xerrors.New(xerrors.CodeInvalidParams, "团队没有成员")
-	}
-
-	minMembers := minMembersFromDungeon(dungeon)
-	if len(members) < minMembers {
-		return xerrors.New(xerrors.CodeInvalidParams, fmt.Sprintf("团队人数不足，至少需要 %d 人", minMembers))
-	}
-
-	for _, member := range members {
-		hero, err := s.heroRepo.GetByID(ctx, member.HeroID)
-		if err != nil {
-			return xerrors.Wrap(err, xerrors.CodeInternalError, "查询英雄信息失败")
-		}
-		if int(hero.CurrentLevel) < int(dungeon.MinLevel) {
-			return xerrors.New(xerrors.CodeInvalidParams, fmt.Sprintf("成员 %s 未达到进入等级", member.HeroID))
-		}
+		return err
 	}
+	return evaluateTeamRequirements(roster, dungeon).requirementError()
+}
 
-	return nil
+// dungeonStatusActivities 地城结束状态对应的团队动态类型
+var dungeonStatusActivities = map[string]string{
+	"failed":    interfaces.TeamActivityDungeonFailed,
+	"abandoned": interfaces.TeamActivityDungeonAbandoned,
 }
 
-func (s *TeamDungeonService) updateProgressStatus(ctx context.Context, teamID, dungeonID, status string) (*game_runtime.TeamDungeonProgress, error) {
+func (s *TeamDungeonService) updateProgressStatus(ctx context.Context, teamID, dungeonID, heroID, status string) (*game_runtime.TeamDungeonProgress, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
@@ -437,6 +597,11 @@ func (s *TeamDungeonService) updateProgressStatus(ctx context.Context, teamID, d
 	if err := s.progressRepo.Update(ctx, tx, progress, "status", "completed_at"); err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新地城进度失败")
 	}
+	if eventType, ok := dungeonStatusActivities[status]; ok {
+		if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, dungeonActivity(eventType, heroID, progress)); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+		}
+	}
 
 	if err := tx.Commit(); err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
@@ -444,16 +609,57 @@ func (s *TeamDungeonService) updateProgressStatus(ctx context.Context, teamID, d
 	return progress, nil
 }
 
-func (s *TeamDungeonService) awardLoot(ctx context.Context, req *CompleteDungeonRequest) error {
+// dungeonActivity 地城进度动态，通关由回调触发时操作者为空
+func dungeonActivity(eventType, heroID string, progress *game_runtime.TeamDungeonProgress) teamActivityEntry {
+	return teamActivityEntry{
+		TeamID:      progress.TeamID,
+		EventType:   eventType,
+		ActorHeroID: heroID,
+		SourceID:    progress.ID,
+		Details:     map[string]interface{}{"dungeon_id": progress.DungeonID, "status": progress.Status},
+	}
+}
+
+// grantCompletionCurrencies 按 currency_rewards 配置为每名成员发放通关货币奖励
+func (s *TeamDungeonService) grantCompletionCurrencies(ctx context.Context, tx *sql.Tx, progress *game_runtime.TeamDungeonProgress) error {
+	if s.currencyService == nil {
+		return nil
+	}
+	members, err := s.teamMemberRepo.ListByTeam(ctx, progress.TeamID)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "查询成员失败")
+	}
+	heroIDs := make([]string, len(members))
+	for i, member := range members {
+		heroIDs[i] = member.HeroID
+	}
+
+	changes, err := s.currencyService.rewardChanges(ctx, interfaces.CurrencyRewardDungeon, progress.DungeonID, heroIDs, nil, CurrencyChange{
+		SourceType: CurrencySourceDungeon,
+		SourceID:   progress.DungeonID,
+		Metadata:   map[string]interface{}{"team_id": progress.TeamID, "progress_id": progress.ID},
+	})
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		if _, err := s.currencyService.ApplyTx(ctx, tx, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TeamDungeonService) awardLoot(ctx context.Context, teamID, dungeonID string, loot LootData) error {
 	if s.teamWarehouseService == nil {
 		return nil
 	}
-	if req.Loot.Gold <= 0 && len(req.Loot.Items) == 0 {
+	if loot.Gold <= 0 && len(loot.Items) == 0 {
 		return nil
 	}
 
-	items := make([]LootItem, 0, len(req.Loot.Items))
-	for _, item := range req.Loot.Items {
+	items := make([]LootItem, 0, len(loot.Items))
+	for _, item := range loot.Items {
 		if item.ItemID == "" || item.Quantity <= 0 {
 			continue
 		}
@@ -461,9 +667,9 @@ func (s *TeamDungeonService) awardLoot(ctx context.Context, req *CompleteDungeon
 	}
 
 	addReq := &AddLootToWarehouseRequest{
-		TeamID:          req.TeamID,
-		SourceDungeonID: req.DungeonID,
-		Gold:            req.Loot.Gold,
+		TeamID:          teamID,
+		SourceDungeonID: dungeonID,
+		Gold:            loot.Gold,
 	}
 	for _, item := range items {
 		addReq.Items = append(addReq.Items, LootItem{
@@ -495,15 +701,9 @@ func minMembersFromDungeon(dungeon *game_config.Dungeon) int {
 }
 
 func firstRoomCode(dungeon *game_config.Dungeon) string {
-	if len(dungeon.RoomSequence) == 0 {
-		return ""
-	}
-	var rooms []map[string]interface{}
-	if err := json.Unmarshal(dungeon.RoomSequence, &rooms); err != nil || len(rooms) == 0 {
+	seq, err := parseRoomSequence(dungeon.RoomSequence)
+	if err != nil || len(seq) == 0 {
 		return ""
 	}
-	if code, ok := rooms[0]["room_code"].(string); ok {
-		return code
-	}
-	return ""
+	return seq[0].key()
 }
@@ -0,0 +1,616 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/pkg/notify"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// lootRollExpireBatch 每次处理的超时投骰数量
+const lootRollExpireBatch = 100
+
+// lootRollResolvingStaleAfter 停留在 resolving 超过该时长视为结算中断，重新发放
+const lootRollResolvingStaleAfter = time.Minute
+
+// GetLootPolicy 查看团队仓库分配策略（团队成员可查看）
+func (s *TeamWarehouseService) GetLootPolicy(ctx context.Context, teamID, heroID string) (*interfaces.TeamLootPolicy, error) {
+	if teamID == "" || heroID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+	if _, err := s.teamMemberRepo.GetByTeamAndHero(ctx, teamID, heroID); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
+	}
+	return s.loadLootPolicy(ctx, teamID)
+}
+
+// UpdateLootPolicyRequest 更新分配策略请求
+type UpdateLootPolicyRequest struct {
+	TeamID             string
+	HeroID             string // 操作者英雄ID
+	GoldPolicy         string // manual/equal_split
+	ItemPolicy         string // manual/round_robin/need_greed/class_need
+	RollTimeoutSeconds int    // 投骰超时秒数，<=0 保持不变
+}
+
+// UpdateLootPolicy 更新团队仓库分配策略（需要 distribute_loot 权限）
+func (s *TeamWarehouseService) UpdateLootPolicy(ctx context.Context, req *UpdateLootPolicyRequest) (*interfaces.TeamLootPolicy, error) {
+	// 1. 验证参数
+	if req.TeamID == "" || req.HeroID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+	switch req.GoldPolicy {
+	case interfaces.LootPolicyManual, interfaces.LootPolicyEqualSplit:
+	default:
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "不支持的金币分配策略")
+	}
+	switch req.ItemPolicy {
+	case interfaces.LootPolicyManual, interfaces.LootPolicyRoundRobin, interfaces.LootPolicyNeedGreed, interfaces.LootPolicyClassNeed:
+	default:
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "不支持的物品分配策略")
+	}
+	if req.RollTimeoutSeconds > 0 && (req.RollTimeoutSeconds < 10 || req.RollTimeoutSeconds > 86400) {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "投骰超时需在 10 秒到 24 小时之间")
+	}
+
+	// 2. 检查权限
+	member, err := s.teamMemberRepo.GetByTeamAndHero(ctx, req.TeamID, req.HeroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
+	}
+	if _, err := requireTeamPermission(ctx, s.teamRankRepo, member, TeamPermDistributeLoot, "只有队长和管理员可以修改分配策略"); err != nil {
+		return nil, err
+	}
+
+	// 3. 更新策略
+	policy, err := s.loadLootPolicy(ctx, req.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	policy.GoldPolicy = req.GoldPolicy
+	policy.ItemPolicy = req.ItemPolicy
+	if req.RollTimeoutSeconds > 0 {
+		policy.RollTimeoutSeconds = req.RollTimeoutSeconds
+	}
+	if err := s.lootPolicyRepo.UpdatePolicy(ctx, policy); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新分配策略失败")
+	}
+	logTeamActivity(ctx, s.teamActivityRepo, teamActivityEntry{
+		TeamID:      req.TeamID,
+		EventType:   interfaces.TeamActivityLootPolicyUpdated,
+		ActorHeroID: req.HeroID,
+		Details: map[string]interface{}{
+			"gold_policy":          policy.GoldPolicy,
+			"item_policy":          policy.ItemPolicy,
+			"roll_timeout_seconds": policy.RollTimeoutSeconds,
+		},
+	})
+	return policy, nil
+}
+
+// applyLootPolicy 入库成功后按策略自动分配本次战利品；分配失败不影响入库，战利品留在仓库
+func (s *TeamWarehouseService) applyLootPolicy(ctx context.Context, req *AddLootToWarehouseRequest) {
+	if s.lootPolicyRepo == nil {
+		return
+	}
+	policy, err := s.lootPolicyRepo.GetPolicy(ctx, req.TeamID)
+	if err != nil || policy == nil {
+		if err != nil {
+			fmt.Printf("Warning: Failed to load loot policy (team=%s): %v\n", req.TeamID, err)
+		}
+		return
+	}
+	if policy.GoldPolicy == interfaces.LootPolicyManual && policy.ItemPolicy == interfaces.LootPolicyManual {
+		return
+	}
+
+	members, err := s.teamMemberRepo.ListByTeam(ctx, req.TeamID)
+	if err != nil || len(members) == 0 {
+		if err != nil {
+			fmt.Printf("Warning: Failed to list team members for loot policy (team=%s): %v\n", req.TeamID, err)
+		}
+		return
+	}
+	leaderID := lootDistributorID(members)
+
+	if req.Gold > 0 && policy.GoldPolicy == interfaces.LootPolicyEqualSplit {
+		if err := s.splitLootGold(ctx, req, members, leaderID); err != nil {
+			fmt.Printf("Warning: Failed to split loot gold (team=%s): %v\n", req.TeamID, err)
+		}
+	}
+	if len(req.Items) == 0 {
+		return
+	}
+	switch policy.ItemPolicy {
+	case interfaces.LootPolicyRoundRobin:
+		s.roundRobinLootItems(ctx, policy, req, members, leaderID)
+	case interfaces.LootPolicyNeedGreed, interfaces.LootPolicyClassNeed:
+		s.openLootRolls(ctx, policy, req)
+	}
+}
+
+// splitLootGold 金币按成员平分，余数留在仓库
+func (s *TeamWarehouseService) splitLootGold(ctx context.Context, req *AddLootToWarehouseRequest, members []*game_runtime.TeamMember, leaderID string) error {
+	each := req.Gold / int64(len(members))
+	if each <= 0 {
+		return nil
+	}
+	distributions := make(map[string]int64, len(members))
+	notes := make(map[string]string, len(members))
+	for _, member := range members {
+		distributions[member.HeroID] = each
+		notes[member.HeroID] = fmt.Sprintf("入库金币 %d 由 %d 名成员平分", req.Gold, len(members))
+	}
+	return s.DistributeGold(ctx, &DistributeGoldRequest{
+		TeamID:        req.TeamID,
+		DistributorID: leaderID,
+		Distributions: distributions,
+		Policy:        interfaces.LootPolicyEqualSplit,
+		Notes:         notes,
+	})
+}
+
+// roundRobinLootItems 物品按入队顺序轮流分配，每条战利品一个顺位；单条失败时留在仓库
+func (s *TeamWarehouseService) roundRobinLootItems(ctx context.Context, policy *interfaces.TeamLootPolicy, req *AddLootToWarehouseRequest, members []*game_runtime.TeamMember, leaderID string) {
+	start, err := s.lootPolicyRepo.ReserveRoundRobin(ctx, policy.WarehouseID, len(req.Items))
+	if err != nil {
+		fmt.Printf("Warning: Failed to reserve round robin slots (team=%s): %v\n", req.TeamID, err)
+		return
+	}
+	for i, item := range req.Items {
+		recipient := members[(start+i)%len(members)].HeroID
+		err := s.DistributeItems(ctx, &DistributeItemsRequest{
+			TeamID:        req.TeamID,
+			DistributorID: leaderID,
+			Distributions: map[string]map[string]int{recipient: {item.ItemID: item.Quantity}},
+			Policy:        interfaces.LootPolicyRoundRobin,
+			Notes:         map[string]string{recipient: fmt.Sprintf("轮流分配第 %d 顺位", start+i+1)},
+		})
+		if err != nil {
+			fmt.Printf("Warning: Failed to distribute round robin loot (team=%s, item=%s, hero=%s): %v\n", req.TeamID, item.ItemID, recipient, err)
+		}
+	}
+}
+
+// openLootRolls 为每条战利品发起投骰，物品留在仓库直至结算
+func (s *TeamWarehouseService) openLootRolls(ctx context.Context, policy *interfaces.TeamLootPolicy, req *AddLootToWarehouseRequest) {
+	expiresAt := time.Now().Add(time.Duration(policy.RollTimeoutSeconds) * time.Second)
+	for _, item := range req.Items {
+		roll := &interfaces.TeamLootRoll{
+			TeamID:      req.TeamID,
+			WarehouseID: policy.WarehouseID,
+			ItemID:      item.ItemID,
+			ItemType:    item.ItemType,
+			Quantity:    item.Quantity,
+			Policy:      policy.ItemPolicy,
+			Status:      interfaces.LootRollStatusOpen,
+			ExpiresAt:   expiresAt,
+		}
+		if err := s.lootPolicyRepo.CreateRoll(ctx, nil, roll); err != nil {
+			fmt.Printf("Warning: Failed to open loot roll (team=%s, item=%s): %v\n", req.TeamID, item.ItemID, err)
+		}
+	}
+}
+
+// VoteLootRollRequest 投骰请求
+type VoteLootRollRequest struct {
+	TeamID string
+	RollID string
+	HeroID string
+	Choice string // need/greed/pass
+}
+
+// VoteLootRoll 成员对战利品投骰；need/greed 由服务端生成 1-100 点数，全员投完即结算
+func (s *TeamWarehouseService) VoteLootRoll(ctx context.Context, req *VoteLootRollRequest) (*interfaces.TeamLootRollVote, error) {
+	// 1. 验证参数
+	if req.TeamID == "" || req.RollID == "" || req.HeroID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+	switch req.Choice {
+	case interfaces.LootRollNeed, interfaces.LootRollGreed, interfaces.LootRollPass:
+	default:
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "投骰选择必须是 need/greed/pass")
+	}
+	if s.lootPolicyRepo == nil {
+		return nil, xerrors.New(xerrors.CodeInternalError, "投骰存储未配置")
+	}
+
+	// 2. 检查成员身份
+	if _, err := s.teamMemberRepo.GetByTeamAndHero(ctx, req.TeamID, req.HeroID); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
+	}
+
+	// 3. 开启事务
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			// 仅当 Rollback 失败且不是已提交的事务时，才表示有问题
+		}
+	}()
+
+	// 4. 校验投骰状态
+	roll, err := s.lootPolicyRepo.GetRollForUpdate(ctx, tx, req.RollID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询投骰失败")
+	}
+	if roll == nil || roll.TeamID != req.TeamID {
+		return nil, xerrors.New(xerrors.CodeResourceNotFound, "投骰不存在")
+	}
+	if roll.Status != interfaces.LootRollStatusOpen || !time.Now().Before(roll.ExpiresAt) {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "投骰已结束")
+	}
+
+	// 5. 职业限制：只有物品可用职业才能选择需求
+	if req.Choice == interfaces.LootRollNeed && roll.Policy == interfaces.LootPolicyClassNeed {
+		eligible, err := s.canNeedLootItem(ctx, req.HeroID, roll.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		if !eligible {
+			return nil, xerrors.New(xerrors.CodeInvalidParams, "您的职业无法使用该物品，只能选择贪婪或放弃")
+		}
+	}
+
+	// 6. 记录选择
+	vote := &interfaces.TeamLootRollVote{RollID: roll.ID, HeroID: req.HeroID, Choice: req.Choice}
+	if req.Choice != interfaces.LootRollPass {
+		vote.RollValue = rand.Intn(100) + 1
+	}
+	saved, err := s.lootPolicyRepo.SaveVote(ctx, tx, vote)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "记录投骰失败")
+	}
+	if !saved {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "您已投骰")
+	}
+
+	// 7. 提交事务
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+
+	// 8. 全员投完立即结算
+	if err := s.resolveLootRoll(ctx, roll.ID, false); err != nil {
+		fmt.Printf("Warning: Failed to resolve loot roll (roll=%s): %v\n", roll.ID, err)
+	}
+	return vote, nil
+}
+
+// ListLootRolls 查看团队投骰（团队成员可查看）
+func (s *TeamWarehouseService) ListLootRolls(ctx context.Context, teamID, heroID, status string, limit, offset int) ([]*interfaces.TeamLootRoll, int64, error) {
+	if teamID == "" || heroID == "" {
+		return nil, 0, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+	if _, err := s.teamMemberRepo.GetByTeamAndHero(ctx, teamID, heroID); err != nil {
+		return nil, 0, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
+	}
+	if s.lootPolicyRepo == nil {
+		return nil, 0, xerrors.New(xerrors.CodeInternalError, "投骰存储未配置")
+	}
+	list, total, err := s.lootPolicyRepo.ListRolls(ctx, teamID, status, limit, offset)
+	if err != nil {
+		return nil, 0, xerrors.Wrap(err, xerrors.CodeInternalError, "查询投骰失败")
+	}
+	return list, total, nil
+}
+
+// ResolveExpiredLootRolls 结算已超时的投骰（未投骰视为放弃），返回处理数量
+func (s *TeamWarehouseService) ResolveExpiredLootRolls(ctx context.Context) (int, error) {
+	if s.lootPolicyRepo == nil {
+		return 0, nil
+	}
+	ids, err := s.lootPolicyRepo.ListExpiredRollIDs(ctx, time.Now(), lootRollExpireBatch)
+	if err != nil {
+		return 0, xerrors.Wrap(err, xerrors.CodeInternalError, "查询超时投骰失败")
+	}
+	resolved := 0
+	for _, id := range ids {
+		if err := s.resolveLootRoll(ctx, id, true); err != nil {
+			fmt.Printf("Warning: Failed to resolve expired loot roll (roll=%s): %v\n", id, err)
+			continue
+		}
+		resolved++
+	}
+	return resolved, nil
+}
+
+// RetryStaleLootRolls 重新发放结算中断（停留在 resolving）的投骰，返回处理数量
+func (s *TeamWarehouseService) RetryStaleLootRolls(ctx context.Context) (int, error) {
+	if s.lootPolicyRepo == nil {
+		return 0, nil
+	}
+	ids, err := s.lootPolicyRepo.ListStaleResolvingRollIDs(ctx, time.Now().Add(-lootRollResolvingStaleAfter), lootRollExpireBatch)
+	if err != nil {
+		return 0, xerrors.Wrap(err, xerrors.CodeInternalError, "查询中断的投骰失败")
+	}
+	retried := 0
+	for _, id := range ids {
+		if err := s.awardLootRoll(ctx, id); err != nil {
+			fmt.Printf("Warning: Failed to retry stale loot roll (roll=%s): %v\n", id, err)
+			continue
+		}
+		retried++
+	}
+	return retried, nil
+}
+
+// resolveLootRoll 结算投骰：先在事务内认领（open→resolving），再由 awardLootRoll 发放给胜者
+// expired 为 false 时只有全员投完才结算
+func (s *TeamWarehouseService) resolveLootRoll(ctx context.Context, rollID string, expired bool) error {
+	// 1. 认领投骰
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			// 仅当 Rollback 失败且不是已提交的事务时，才表示有问题
+		}
+	}()
+
+	roll, err := s.lootPolicyRepo.GetRollForUpdate(ctx, tx, rollID)
+	if err != nil || roll == nil || roll.Status != interfaces.LootRollStatusOpen {
+		return err
+	}
+	votes, err := s.lootPolicyRepo.ListVotes(ctx, tx, rollID)
+	if err != nil {
+		return err
+	}
+	members, err := s.teamMemberRepo.ListByTeam(ctx, roll.TeamID)
+	if err != nil {
+		return err
+	}
+	if !expired && countMemberVotes(votes, members) < len(members) {
+		return nil
+	}
+
+	now := time.Now()
+	roll.ResolvedAt = &now
+	winner := pickLootRollWinner(votes)
+	if winner == nil {
+		note := "全员放弃或未投骰，物品留在仓库"
+		roll.Status = interfaces.LootRollStatusPassed
+		roll.ResultNote = &note
+		if err := s.lootPolicyRepo.UpdateRollResult(ctx, tx, roll); err != nil {
+			return err
+		}
+		if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, lootRollActivity(roll)); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	roll.Status = interfaces.LootRollStatusResolving
+	roll.WinnerHeroID = &winner.HeroID
+	roll.WinningChoice = &winner.Choice
+	roll.WinningRoll = &winner.RollValue
+	if err := s.lootPolicyRepo.UpdateRollResult(ctx, tx, roll); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// 2. 发放给胜者
+	return s.awardLootRoll(ctx, roll.ID)
+}
+
+// awardLootRoll 将 resolving 状态的投骰发放给胜者并写入分配历史
+// 发放与结果回写在同一事务内提交，进程中断时投骰停留在 resolving，由 RetryStaleLootRolls 重新发放
+func (s *TeamWarehouseService) awardLootRoll(ctx context.Context, rollID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			// 仅当 Rollback 失败且不是已提交的事务时，才表示有问题
+		}
+	}()
+
+	roll, err := s.lootPolicyRepo.GetRollForUpdate(ctx, tx, rollID)
+	if err != nil || roll == nil || roll.Status != interfaces.LootRollStatusResolving {
+		return err
+	}
+	if roll.WinnerHeroID == nil || roll.WinningChoice == nil || roll.WinningRoll == nil {
+		return fmt.Errorf("投骰 %s 缺少胜者信息", rollID)
+	}
+	votes, err := s.lootPolicyRepo.ListVotes(ctx, tx, rollID)
+	if err != nil {
+		return err
+	}
+
+	note := fmt.Sprintf("%s %d 点胜出（%d 人投骰）", *roll.WinningChoice, *roll.WinningRoll, len(votes))
+	distribution, distErr := s.distributeToMemberTx(ctx, tx, roll.TeamID, roll.WarehouseID, *roll.WinnerHeroID,
+		LootItem{ItemID: roll.ItemID, ItemType: roll.ItemType, Quantity: roll.Quantity}, roll.Policy, note, "")
+	if distErr == nil {
+		roll.Status = interfaces.LootRollStatusAwarded
+		roll.ResultNote = &note
+		if err := s.lootPolicyRepo.UpdateRollResult(ctx, tx, roll); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		_ = notify.PublishWarehouseEvent(ctx, notify.SubjectWarehouseDistributed, &DistributionEvent{
+			TeamID:      roll.TeamID,
+			WarehouseID: roll.WarehouseID,
+			Distributor: distribution.DistributorID,
+			ItemPayload: distribution.Distributions,
+			Result:      "success",
+		})
+	} else {
+		// 发放失败：撤销本次发放，物品留在仓库
+		if err := tx.Rollback(); err != nil {
+			return err
+		}
+		roll.Status = interfaces.LootRollStatusFailed
+		note = fmt.Sprintf("%s；发放失败，物品留在仓库：%v", note, distErr)
+		roll.ResultNote = &note
+		if err := s.lootPolicyRepo.UpdateRollResult(ctx, nil, roll); err != nil {
+			return err
+		}
+	}
+	logTeamActivity(ctx, s.teamActivityRepo, lootRollActivity(roll))
+	return nil
+}
+
+// distributeToMemberTx 在事务内以队长名义把仓库物品发放给胜者（投骰、竞拍等自动分配），胜者须仍是团队成员
+// auctionID 为发放竞拍物品时的竞拍ID，库存校验不扣除该竞拍自身的预留
+func (s *TeamWarehouseService) distributeToMemberTx(ctx context.Context, tx *sql.Tx, teamID, warehouseID, winnerID string, item LootItem, policy, note, auctionID string) (*DistributeItemsRequest, error) {
+	members, err := s.teamMemberRepo.ListByTeam(ctx, teamID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询团队成员失败")
+	}
+	isMember := false
+	for _, member := range members {
+		if member.HeroID == winnerID {
+			isMember = true
+			break
+		}
+	}
+	if !isMember {
+		return nil, xerrors.New(xerrors.CodePermissionDenied, "胜者已不是团队成员")
+	}
+
+	distribution := &DistributeItemsRequest{
+		TeamID:        teamID,
+		DistributorID: lootDistributorID(members),
+		Distributions: map[string]map[string]int{winnerID: {item.ItemID: item.Quantity}},
+		Policy:        policy,
+		Notes:         map[string]string{winnerID: note},
+		auctionID:     auctionID,
+	}
+	if err := s.distributeItemsTx(ctx, tx, warehouseID, map[string]int{item.ItemID: item.Quantity}, distribution); err != nil {
+		return nil, err
+	}
+	return distribution, nil
+}
+
+// lootRollActivity 投骰结算动态，受影响者为胜者（无人胜出时为空）
+func lootRollActivity(roll *interfaces.TeamLootRoll) teamActivityEntry {
+	entry := teamActivityEntry{
+		TeamID:    roll.TeamID,
+		EventType: interfaces.TeamActivityLootRollResolved,
+		SourceID:  roll.ID,
+		Details: map[string]interface{}{
+			"item_id":  roll.ItemID,
+			"quantity": roll.Quantity,
+			"policy":   roll.Policy,
+			"status":   roll.Status,
+		},
+	}
+	if roll.WinnerHeroID != nil {
+		entry.TargetHeroID = *roll.WinnerHeroID
+	}
+	if roll.ResultNote != nil {
+		entry.Details["note"] = *roll.ResultNote
+	}
+	return entry
+}
+
+// canNeedLootItem 物品无职业关联视为通用；否则英雄职业需在关联列表中
+func (s *TeamWarehouseService) canNeedLootItem(ctx context.Context, heroID, itemID string) (bool, error) {
+	classIDs, err := s.lootPolicyRepo.ListItemClassIDs(ctx, itemID)
+	if err != nil {
+		return false, xerrors.Wrap(err, xerrors.CodeInternalError, "查询物品职业限制失败")
+	}
+	if len(classIDs) == 0 {
+		return true, nil
+	}
+	hero, err := s.heroRepo.GetByID(ctx, heroID)
+	if err != nil {
+		return false, xerrors.Wrap(err, xerrors.CodeHeroNotFound, "英雄不存在")
+	}
+	for _, classID := range classIDs {
+		if classID == hero.ClassID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// pickLootRollWinner 需求优先于贪婪，同档点数高者胜，点数相同先投者胜；无人需求/贪婪返回 nil
+func pickLootRollWinner(votes []*interfaces.TeamLootRollVote) *interfaces.TeamLootRollVote {
+	rank := func(choice string) int {
+		switch choice {
+		case interfaces.LootRollNeed:
+			return 2
+		case interfaces.LootRollGreed:
+			return 1
+		}
+		return 0
+	}
+
+	var winner *interfaces.TeamLootRollVote
+	for _, vote := range votes {
+		if rank(vote.Choice) == 0 {
+			continue
+		}
+		if winner == nil ||
+			rank(vote.Choice) > rank(winner.Choice) ||
+			rank(vote.Choice) == rank(winner.Choice) && vote.RollValue > winner.RollValue ||
+			rank(vote.Choice) == rank(winner.Choice) && vote.RollValue == winner.RollValue && vote.CreatedAt.Before(winner.CreatedAt) {
+			winner = vote
+		}
+	}
+	return winner
+}
+
+// countMemberVotes 统计当前成员的投骰数（已离队成员不计）
+func countMemberVotes(votes []*interfaces.TeamLootRollVote, members []*game_runtime.TeamMember) int {
+	memberIDs := make(map[string]bool, len(members))
+	for _, member := range members {
+		memberIDs[member.HeroID] = true
+	}
+	count := 0
+	for _, vote := range votes {
+		if memberIDs[vote.HeroID] {
+			count++
+		}
+	}
+	return count
+}
+
+// lootDistributorID 自动分配以队长名义记录，找不到队长时取首位成员
+func lootDistributorID(members []*game_runtime.TeamMember) string {
+	for _, member := range members {
+		if member.Role == "leader" {
+			return member.HeroID
+		}
+	}
+	return members[0].HeroID
+}
+
+// loadLootPolicy 获取仓库分配策略
+func (s *TeamWarehouseService) loadLootPolicy(ctx context.Context, teamID string) (*interfaces.TeamLootPolicy, error) {
+	if s.lootPolicyRepo == nil {
+		return nil, xerrors.New(xerrors.CodeInternalError, "分配策略存储未配置")
+	}
+	policy, err := s.lootPolicyRepo.GetPolicy(ctx, teamID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询分配策略失败")
+	}
+	if policy == nil {
+		return nil, xerrors.New(xerrors.CodeResourceNotFound, "团队仓库不存在")
+	}
+	return policy, nil
+}
+
+// distributionNote 取接收者的决策说明
+func distributionNote(notes map[string]string, heroID string) *string {
+	note, ok := notes[heroID]
+	if !ok || note == "" {
+		return nil
+	}
+	return &note
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/repository/interfaces"
+)
+
+func TestPickLootRollWinner(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	vote := func(hero, choice string, value int, offset time.Duration) *interfaces.TeamLootRollVote {
+		return &interfaces.TeamLootRollVote{HeroID: hero, Choice: choice, RollValue: value, CreatedAt: base.Add(offset)}
+	}
+
+	winner := pickLootRollWinner([]*interfaces.TeamLootRollVote{
+		vote("a", interfaces.LootRollGreed, 99, 0),
+		vote("b", interfaces.LootRollNeed, 12, time.Second),
+		vote("c", interfaces.LootRollNeed, 40, 2*time.Second),
+	})
+	require.Equal(t, "c", winner.HeroID, "需求优先于贪婪，同档点数高者胜")
+
+	winner = pickLootRollWinner([]*interfaces.TeamLootRollVote{
+		vote("a", interfaces.LootRollGreed, 50, 2*time.Second),
+		vote("b", interfaces.LootRollGreed, 50, time.Second),
+	})
+	require.Equal(t, "b", winner.HeroID, "点数相同先投者胜")
+
+	require.Nil(t, pickLootRollWinner([]*interfaces.TeamLootRollVote{vote("a", interfaces.LootRollPass, 0, 0)}))
+	require.Nil(t, pickLootRollWinner(nil))
+}
+
+func TestLootRollHelpers(t *testing.T) {
+	members := []*game_runtime.TeamMember{
+		{HeroID: "a", Role: "member"},
+		{HeroID: "b", Role: "leader"},
+	}
+	require.Equal(t, "b", lootDistributorID(members))
+	require.Equal(t, "a", lootDistributorID(members[:1]), "无队长时取首位成员")
+
+	votes := []*interfaces.TeamLootRollVote{{HeroID: "a"}, {HeroID: "left-team"}}
+	require.Equal(t, 1, countMemberVotes(votes, members), "已离队成员不计入")
+
+	require.Nil(t, distributionNote(nil, "a"))
+	require.Nil(t, distributionNote(map[string]string{"a": ""}, "a"))
+	require.Equal(t, "need 87", *distributionNote(map[string]string{"a": "need 87"}, "a"))
+}
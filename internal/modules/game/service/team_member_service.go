@@ -20,6 +20,8 @@ type TeamMemberService struct {
 	teamJoinRequestRepo   interfaces.TeamJoinRequestRepository
 	teamInvitationRepo    interfaces.TeamInvitationRepository
 	teamKickedRecordRepo  interfaces.TeamKickedRecordRepository
+	teamRankRepo          interfaces.TeamRankRepository
+	teamActivityRepo      interfaces.TeamActivityRepository
 	heroRepo              interfaces.HeroRepository
 	teamPermissionService *TeamPermissionService
 }
@@ -33,6 +35,8 @@ func NewTeamMemberService(db *sql.DB, teamPermissionService *TeamPermissionServi
 		teamJoinRequestRepo:   impl.NewTeamJoinRequestRepository(db),
 		teamInvitationRepo:    impl.NewTeamInvitationRepository(db),
 		teamKickedRecordRepo:  impl.NewTeamKickedRecordRepository(db),
+		teamRankRepo:          impl.NewTeamRankRepository(db),
+		teamActivityRepo:      impl.NewTeamActivityRepository(db),
 		heroRepo:              impl.NewHeroRepository(db),
 		teamPermissionService: teamPermissionService,
 	}
@@ -103,6 +107,12 @@ func (s *TeamMemberService) ApplyToJoin(ctx context.Context, req *ApplyToJoinReq
 	if err := s.teamJoinRequestRepo.Create(ctx, joinRequest); err != nil {
 		return "", xerrors.Wrap(err, xerrors.CodeInternalError, "创建申请失败")
 	}
+	logTeamActivity(ctx, s.teamActivityRepo, teamActivityEntry{
+		TeamID:      req.TeamID,
+		EventType:   interfaces.TeamActivityJoinRequested,
+		ActorHeroID: req.HeroID,
+		SourceID:    joinRequest.ID,
+	})
 
 	// TODO: 通知队长和管理员
 
@@ -129,13 +139,13 @@ func (s *TeamMemberService) ApproveJoinRequest(ctx context.Context, req *Approve
 		return xerrors.New(xerrors.CodeInvalidParams, "申请已被处理")
 	}
 
-	// 3. 检查审批人权限（队长或管理员）
+	// 3. 检查审批人权限
 	member, err := s.teamMemberRepo.GetByTeamAndHero(ctx, joinRequest.TeamID, req.HeroID)
 	if err != nil {
 		return xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
 	}
-	if member.Role != "leader" && member.Role != "admin" {
-		return xerrors.New(xerrors.CodePermissionDenied, "只有队长和管理员可以审批申请")
+	if _, err := requireTeamPermission(ctx, s.teamRankRepo, member, TeamPermApproveJoinRequest, "只有队长和管理员可以审批申请"); err != nil {
+		return err
 	}
 
 	// 4. 更新申请状态
@@ -147,22 +157,47 @@ func (s *TeamMemberService) ApproveJoinRequest(ctx context.Context, req *Approve
 	joinRequest.ReviewedByHeroID.SetValid(req.HeroID)
 	joinRequest.ReviewedAt.SetValid(time.Now())
 
-	if err := s.teamJoinRequestRepo.Update(ctx, s.db, joinRequest); err != nil {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer tx.Rollback()
+
+	if err := s.teamJoinRequestRepo.Update(ctx, tx, joinRequest); err != nil {
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "更新申请状态失败")
 	}
 
 	// 5. 如果批准，创建成员记录
+	var newMember *game_runtime.TeamMember
+	eventType := interfaces.TeamActivityJoinRejected
 	if req.Approved {
-		newMember := &game_runtime.TeamMember{
+		newMember = &game_runtime.TeamMember{
 			TeamID: joinRequest.TeamID,
 			HeroID: joinRequest.HeroID,
 			UserID: joinRequest.UserID,
 			Role:   "member",
 		}
-		if err := s.teamMemberRepo.Create(ctx, s.db, newMember); err != nil {
+		if err := s.teamMemberRepo.Create(ctx, tx, newMember); err != nil {
 			return xerrors.Wrap(err, xerrors.CodeInternalError, "创建成员记录失败")
 		}
+		eventType = interfaces.TeamActivityJoinApproved
+	}
+
+	// 6. 记录团队动态并提交
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, teamActivityEntry{
+		TeamID:       joinRequest.TeamID,
+		EventType:    eventType,
+		ActorHeroID:  req.HeroID,
+		TargetHeroID: joinRequest.HeroID,
+		SourceID:     joinRequest.ID,
+	}); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+	}
+	if err := tx.Commit(); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
 
+	if newMember != nil {
 		// 同步权限到 Keto
 		if s.teamPermissionService != nil {
 			if err := s.teamPermissionService.SyncMemberToKeto(ctx, newMember); err != nil {
@@ -191,10 +226,14 @@ func (s *TeamMemberService) InviteMember(ctx context.Context, req *InviteMemberR
 		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
 	}
 
-	// 2. 检查邀请人是否是团队成员
-	if _, err := s.teamMemberRepo.GetByTeamAndHero(ctx, req.TeamID, req.InviterHeroID); err != nil {
+	// 2. 检查邀请人是否是团队成员且可以邀请
+	inviter, err := s.teamMemberRepo.GetByTeamAndHero(ctx, req.TeamID, req.InviterHeroID)
+	if err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
 	}
+	if _, err := requireTeamPermission(ctx, s.teamRankRepo, inviter, TeamPermInviteMember, "您没有邀请成员的权限"); err != nil {
+		return nil, err
+	}
 
 	// 3. 检查被邀请人是否已是成员
 	existingMember, _ := s.teamMemberRepo.GetByTeamAndHero(ctx, req.TeamID, req.InviteeHeroID)
@@ -238,6 +277,13 @@ func (s *TeamMemberService) InviteMember(ctx context.Context, req *InviteMemberR
 	if err := s.teamInvitationRepo.Create(ctx, invitation); err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "创建邀请失败")
 	}
+	logTeamActivity(ctx, s.teamActivityRepo, teamActivityEntry{
+		TeamID:       req.TeamID,
+		EventType:    interfaces.TeamActivityMemberInvited,
+		ActorHeroID:  req.InviterHeroID,
+		TargetHeroID: req.InviteeHeroID,
+		SourceID:     invitation.ID,
+	})
 
 	// TODO: 通知队长和管理员审批
 
@@ -264,27 +310,47 @@ func (s *TeamMemberService) ApproveInvitation(ctx context.Context, req *ApproveI
 		return xerrors.New(xerrors.CodeInvalidParams, "邀请已被处理")
 	}
 
-	// 3. 检查审批人权限（队长或管理员）
+	// 3. 检查审批人权限
 	member, err := s.teamMemberRepo.GetByTeamAndHero(ctx, invitation.TeamID, req.HeroID)
 	if err != nil {
 		return xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
 	}
-	if member.Role != "leader" && member.Role != "admin" {
-		return xerrors.New(xerrors.CodePermissionDenied, "只有队长和管理员可以审批邀请")
+	if _, err := requireTeamPermission(ctx, s.teamRankRepo, member, TeamPermApproveInvitation, "只有队长和管理员可以审批邀请"); err != nil {
+		return err
 	}
 
 	// 4. 更新邀请状态
+	eventType := interfaces.TeamActivityInvitationApproved
 	if req.Approved {
 		invitation.Status = "pending_accept" // 等待被邀请人接受
 	} else {
 		invitation.Status = "rejected"
+		eventType = interfaces.TeamActivityInvitationRejected
 	}
 	invitation.ApprovedByHeroID.SetValid(req.HeroID)
 	invitation.ApprovedAt.SetValid(time.Now())
 
-	if err := s.teamInvitationRepo.Update(ctx, s.db, invitation); err != nil {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer tx.Rollback()
+
+	if err := s.teamInvitationRepo.Update(ctx, tx, invitation); err != nil {
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "更新邀请状态失败")
 	}
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, teamActivityEntry{
+		TeamID:       invitation.TeamID,
+		EventType:    eventType,
+		ActorHeroID:  req.HeroID,
+		TargetHeroID: invitation.InviteeHeroID,
+		SourceID:     invitation.ID,
+	}); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+	}
+	if err := tx.Commit(); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
 
 	// TODO: 通知被邀请人或邀请人
 
@@ -347,6 +413,15 @@ func (s *TeamMemberService) AcceptInvitation(ctx context.Context, req *AcceptInv
 	if err := s.teamMemberRepo.Create(ctx, tx, newMember); err != nil {
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "创建成员记录失败")
 	}
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, teamActivityEntry{
+		TeamID:       invitation.TeamID,
+		EventType:    interfaces.TeamActivityInvitationAccepted,
+		ActorHeroID:  req.HeroID,
+		TargetHeroID: invitation.InviterHeroID,
+		SourceID:     invitation.ID,
+	}); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+	}
 
 	// 8. 提交事务
 	if err := tx.Commit(); err != nil {
@@ -384,11 +459,29 @@ func (s *TeamMemberService) RejectInvitation(ctx context.Context, invitationID,
 	}
 
 	// 4. 更新邀请状态
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer tx.Rollback()
+
 	invitation.Status = "rejected"
 	invitation.RespondedAt.SetValid(time.Now())
-	if err := s.teamInvitationRepo.Update(ctx, s.db, invitation); err != nil {
+	if err := s.teamInvitationRepo.Update(ctx, tx, invitation); err != nil {
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "更新邀请状态失败")
 	}
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, teamActivityEntry{
+		TeamID:       invitation.TeamID,
+		EventType:    interfaces.TeamActivityInvitationDeclined,
+		ActorHeroID:  heroID,
+		TargetHeroID: invitation.InviterHeroID,
+		SourceID:     invitation.ID,
+	}); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+	}
+	if err := tx.Commit(); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
 
 	// TODO: 通知邀请人
 
@@ -422,18 +515,19 @@ func (s *TeamMemberService) KickMember(ctx context.Context, req *KickMemberReque
 		return xerrors.Wrap(err, xerrors.CodeResourceNotFound, "目标成员不存在")
 	}
 
-	// 4. 检查权限
-	if kicker.Role == "member" {
-		return xerrors.New(xerrors.CodePermissionDenied, "普通成员不能踢出其他成员")
-	}
-	if kicker.Role == "admin" && (target.Role == "leader" || target.Role == "admin") {
-		return xerrors.New(xerrors.CodePermissionDenied, "管理员不能踢出队长和其他管理员")
+	// 4. 检查权限（只能踢出等级低于自己的成员）
+	kickerAuthority, err := requireTeamPermission(ctx, s.teamRankRepo, kicker, TeamPermKickMember, "您没有踢出成员的权限")
+	if err != nil {
+		return err
 	}
 
 	// 5. 不能踢出自己
 	if req.TargetHeroID == req.KickerHeroID {
 		return xerrors.New(xerrors.CodeInvalidParams, "不能踢出自己")
 	}
+	if err := s.ensureOutranks(ctx, kickerAuthority, target, "只能踢出等级低于自己的成员"); err != nil {
+		return err
+	}
 
 	// 6. 开启事务
 	tx, err := s.db.BeginTx(ctx, nil)
@@ -461,6 +555,20 @@ func (s *TeamMemberService) KickMember(ctx context.Context, req *KickMemberReque
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "创建踢出记录失败")
 	}
 
+	kickDetails := map[string]interface{}{"role": target.Role}
+	if req.Reason != "" {
+		kickDetails["reason"] = req.Reason
+	}
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, teamActivityEntry{
+		TeamID:       req.TeamID,
+		EventType:    interfaces.TeamActivityMemberKicked,
+		ActorHeroID:  req.KickerHeroID,
+		TargetHeroID: req.TargetHeroID,
+		Details:      kickDetails,
+	}); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+	}
+
 	// 9. 提交事务
 	if err := tx.Commit(); err != nil {
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
@@ -492,13 +600,18 @@ func (s *TeamMemberService) PromoteToAdmin(ctx context.Context, req *PromoteToAd
 		return xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
 	}
 
-	// 2. 检查操作者是否是队长
+	// 2. 检查操作者权限
 	leader, err := s.teamMemberRepo.GetByTeamAndHero(ctx, req.TeamID, req.LeaderHeroID)
 	if err != nil {
 		return xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
 	}
-	if leader.Role != "leader" {
-		return xerrors.New(xerrors.CodePermissionDenied, "只有队长可以任命管理员")
+	operator, err := requireTeamPermission(ctx, s.teamRankRepo, leader, TeamPermAppointAdmin, "只有队长可以任命管理员")
+	if err != nil {
+		return err
+	}
+	// 未分配职阶的管理员拥有全部默认管理权限，操作者须已持有这些权限，避免借任命扩大权限
+	if !operator.HasAll(teamRolePermissions["admin"]) {
+		return xerrors.New(xerrors.CodePermissionDenied, "您的权限不足以任命管理员")
 	}
 
 	// 3. 获取目标成员记录
@@ -511,9 +624,12 @@ func (s *TeamMemberService) PromoteToAdmin(ctx context.Context, req *PromoteToAd
 	if target.Role != "member" {
 		return xerrors.New(xerrors.CodeInvalidParams, "只能任命普通成员为管理员")
 	}
+	if err := s.ensureOutranks(ctx, operator, target, "只能任命等级低于自己的成员"); err != nil {
+		return err
+	}
 
 	// 5. 更新角色
-	if err := s.teamMemberRepo.UpdateRole(ctx, s.db, req.TeamID, req.TargetHeroID, "admin"); err != nil {
+	if err := s.updateRoleWithActivity(ctx, req.TeamID, req.TargetHeroID, req.LeaderHeroID, "admin", interfaces.TeamActivityAdminPromoted); err != nil {
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "任命管理员失败")
 	}
 
@@ -536,13 +652,14 @@ func (s *TeamMemberService) DemoteAdmin(ctx context.Context, teamID, targetHeroI
 		return xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
 	}
 
-	// 2. 检查操作者是否是队长
+	// 2. 检查操作者权限
 	leader, err := s.teamMemberRepo.GetByTeamAndHero(ctx, teamID, leaderHeroID)
 	if err != nil {
 		return xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
 	}
-	if leader.Role != "leader" {
-		return xerrors.New(xerrors.CodePermissionDenied, "只有队长可以撤销管理员")
+	operator, err := requireTeamPermission(ctx, s.teamRankRepo, leader, TeamPermDemoteAdmin, "只有队长可以撤销管理员")
+	if err != nil {
+		return err
 	}
 
 	// 3. 获取目标成员记录
@@ -555,9 +672,12 @@ func (s *TeamMemberService) DemoteAdmin(ctx context.Context, teamID, targetHeroI
 	if target.Role != "admin" {
 		return xerrors.New(xerrors.CodeInvalidParams, "目标成员不是管理员")
 	}
+	if err := s.ensureOutranks(ctx, operator, target, "只能撤销等级低于自己的管理员"); err != nil {
+		return err
+	}
 
 	// 5. 更新角色
-	if err := s.teamMemberRepo.UpdateRole(ctx, s.db, teamID, targetHeroID, "member"); err != nil {
+	if err := s.updateRoleWithActivity(ctx, teamID, targetHeroID, leaderHeroID, "member", interfaces.TeamActivityAdminDemoted); err != nil {
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "撤销管理员失败")
 	}
 
@@ -572,3 +692,37 @@ func (s *TeamMemberService) DemoteAdmin(ctx context.Context, teamID, targetHeroI
 
 	return nil
 }
+
+// ensureOutranks 确认操作者等级高于目标成员
+func (s *TeamMemberService) ensureOutranks(ctx context.Context, operator *teamAuthority, target *game_runtime.TeamMember, deniedMsg string) error {
+	targetAuthority, err := loadMemberAuthority(ctx, s.teamRankRepo, target)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "查询成员权限失败")
+	}
+	if targetAuthority.Level >= operator.Level {
+		return xerrors.New(xerrors.CodePermissionDenied, deniedMsg)
+	}
+	return nil
+}
+
+// updateRoleWithActivity 在同一事务内更新成员角色并记录团队动态
+func (s *TeamMemberService) updateRoleWithActivity(ctx context.Context, teamID, targetHeroID, operatorHeroID, role, eventType string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.teamMemberRepo.UpdateRole(ctx, tx, teamID, targetHeroID, role); err != nil {
+		return err
+	}
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, teamActivityEntry{
+		TeamID:       teamID,
+		EventType:    eventType,
+		ActorHeroID:  operatorHeroID,
+		TargetHeroID: targetHeroID,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -12,6 +12,7 @@ import (
 
 	"tsu-self/internal/entity/game_runtime"
 	"tsu-self/internal/modules/auth/client"
+	"tsu-self/internal/pkg/xerrors"
 	"tsu-self/internal/repository/impl"
 	"tsu-self/internal/repository/interfaces"
 )
@@ -23,10 +24,169 @@ type ketoPermissionClient interface {
 	UpdateTeamMemberRole(ctx context.Context, teamID, heroID, oldRole, newRole string) error
 	CheckTeamPermission(ctx context.Context, teamID, permission, heroID string) (bool, error)
 	CheckTeamMemberRole(ctx context.Context, teamID, heroID string) (string, bool, error)
+	SyncTeamMemberPermissions(ctx context.Context, teamID, heroID string, permissions []string) (bool, error)
 }
 
 const permissionCacheTTL = 5 * time.Minute
 
+// 团队权限
+const (
+	TeamPermSelectDungeon      = "select_dungeon"
+	TeamPermEnterDungeon       = "enter_dungeon"
+	TeamPermAbandonDungeon     = "abandon_dungeon"
+	TeamPermInviteMember       = "invite_member"
+	TeamPermKickMember         = "kick_member"
+	TeamPermApproveJoinRequest = "approve_join_request"
+	TeamPermApproveInvitation  = "approve_invitation"
+	TeamPermAppointAdmin       = "appoint_admin"
+	TeamPermDemoteAdmin        = "demote_admin"
+	TeamPermViewWarehouse      = "view_warehouse"
+	TeamPermDistributeLoot     = "distribute_loot"
+	TeamPermManageContribution = "manage_contribution"
+	TeamPermManageRanks        = "manage_ranks"
+	TeamPermUpdateTeamInfo     = "update_team_info"
+	TeamPermViewTeamInfo       = "view_team_info"
+	TeamPermLeaveTeam          = "leave_team"
+	TeamPermDisbandTeam        = "disband_team"
+)
+
+// 成员权限等级：只能管理等级低于自己的成员，职阶等级限定在 1-99
+const (
+	teamLeaderLevel = 100
+	teamAdminLevel  = 50
+	teamMemberLevel = 10
+)
+
+// teamPermissionCatalog 全部团队权限，队长拥有全部
+var teamPermissionCatalog = []string{
+	TeamPermSelectDungeon, TeamPermEnterDungeon, TeamPermAbandonDungeon,
+	TeamPermInviteMember, TeamPermKickMember, TeamPermApproveJoinRequest, TeamPermApproveInvitation,
+	TeamPermAppointAdmin, TeamPermDemoteAdmin,
+	TeamPermViewWarehouse, TeamPermDistributeLoot, TeamPermManageContribution,
+	TeamPermManageRanks, TeamPermUpdateTeamInfo, TeamPermViewTeamInfo, TeamPermLeaveTeam,
+	TeamPermDisbandTeam,
+}
+
+// teamBasePermissions 所有成员固有的权限，职阶无需也无法移除
+var teamBasePermissions = []string{TeamPermViewTeamInfo, TeamPermLeaveTeam}
+
+// teamRolePermissions 未分配职阶时按角色的默认权限
+var teamRolePermissions = map[string][]string{
+	"admin": {
+		TeamPermSelectDungeon, TeamPermEnterDungeon, TeamPermAbandonDungeon,
+		TeamPermInviteMember, TeamPermKickMember, TeamPermApproveJoinRequest, TeamPermApproveInvitation,
+		TeamPermViewWarehouse, TeamPermDistributeLoot, TeamPermManageContribution,
+	},
+	"member": {
+		TeamPermInviteMember,
+	},
+}
+
+// isRankGrantablePermission 职阶可授予的权限（解散团队仅限队长）
+func isRankGrantablePermission(permission string) bool {
+	if permission == TeamPermDisbandTeam {
+		return false
+	}
+	for _, p := range teamPermissionCatalog {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// teamAuthority 成员在团队内的有效权限
+type teamAuthority struct {
+	Level       int
+	Permissions map[string]bool
+}
+
+// Has 是否拥有权限
+func (a *teamAuthority) Has(permission string) bool {
+	return a.Permissions[permission]
+}
+
+// HasAll 是否拥有全部给定权限
+func (a *teamAuthority) HasAll(permissions []string) bool {
+	for _, p := range permissions {
+		if !a.Permissions[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// List 按权限目录顺序返回拥有的权限
+func (a *teamAuthority) List() []string {
+	perms := make([]string, 0, len(a.Permissions))
+	for _, p := range teamPermissionCatalog {
+		if a.Permissions[p] {
+			perms = append(perms, p)
+		}
+	}
+	return perms
+}
+
+// resolveMemberAuthority 计算有效权限：队长拥有全部权限，已分配职阶按职阶，否则按角色默认
+func resolveMemberAuthority(role string, rank *interfaces.TeamRank) *teamAuthority {
+	authority := &teamAuthority{Permissions: make(map[string]bool)}
+	if role == "leader" {
+		authority.Level = teamLeaderLevel
+		for _, p := range teamPermissionCatalog {
+			authority.Permissions[p] = true
+		}
+		return authority
+	}
+
+	for _, p := range teamBasePermissions {
+		authority.Permissions[p] = true
+	}
+
+	var granted []string
+	switch {
+	case rank != nil:
+		authority.Level = rank.Level
+		granted = rank.Permissions
+	case role == "admin":
+		authority.Level = teamAdminLevel
+		granted = teamRolePermissions["admin"]
+	default:
+		authority.Level = teamMemberLevel
+		granted = teamRolePermissions["member"]
+	}
+	for _, p := range granted {
+		if isRankGrantablePermission(p) {
+			authority.Permissions[p] = true
+		}
+	}
+	return authority
+}
+
+// loadMemberAuthority 从数据库读取成员职阶并计算有效权限，rankRepo 为空时按角色默认
+func loadMemberAuthority(ctx context.Context, rankRepo interfaces.TeamRankRepository, member *game_runtime.TeamMember) (*teamAuthority, error) {
+	var rank *interfaces.TeamRank
+	if rankRepo != nil && member.Role != "leader" {
+		var err error
+		rank, err = rankRepo.GetMemberRank(ctx, member.TeamID, member.HeroID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resolveMemberAuthority(member.Role, rank), nil
+}
+
+// requireTeamPermission 校验已加载成员的团队权限，deniedMsg 为无权限时的提示
+func requireTeamPermission(ctx context.Context, rankRepo interfaces.TeamRankRepository, member *game_runtime.TeamMember, permission, deniedMsg string) (*teamAuthority, error) {
+	authority, err := loadMemberAuthority(ctx, rankRepo, member)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询成员权限失败")
+	}
+	if !authority.Has(permission) {
+		return nil, xerrors.New(xerrors.CodePermissionDenied, deniedMsg)
+	}
+	return authority, nil
+}
+
 var errPermissionCacheMiss = errors.New("permission cache miss")
 
 type permissionCacheClient interface {
@@ -39,6 +199,7 @@ type permissionCacheClient interface {
 type TeamPermissionService struct {
 	db              *sql.DB
 	teamMemberRepo  interfaces.TeamMemberRepository
+	teamRankRepo    interfaces.TeamRankRepository // 可选，未配置时按角色默认权限
 	ketoClient      ketoPermissionClient
 	permissionCache permissionCacheClient
 }
@@ -53,6 +214,7 @@ func NewTeamPermissionService(db *sql.DB, ketoClient *client.KetoClient, cache p
 	return &TeamPermissionService{
 		db:              db,
 		teamMemberRepo:  impl.NewTeamMemberRepository(db),
+		teamRankRepo:    impl.NewTeamRankRepository(db),
 		ketoClient:      kc,
 		permissionCache: cache,
 	}
@@ -70,7 +232,33 @@ func (s *TeamPermissionService) SyncMemberToKeto(ctx context.Context, member *ga
 	}
 
 	// 添加成员关系到 Keto
-	return s.ketoClient.AddTeamMember(ctx, member.TeamID, member.HeroID, member.Role)
+	if err := s.ketoClient.AddTeamMember(ctx, member.TeamID, member.HeroID, member.Role); err != nil {
+		return err
+	}
+
+	// 同步角色与职阶计算出的有效权限
+	_, err := s.syncMemberPermissions(ctx, member)
+	return err
+}
+
+// ResyncMemberPermissions 职阶或权限变更后重新同步成员权限
+func (s *TeamPermissionService) ResyncMemberPermissions(ctx context.Context, teamID, heroID string) error {
+	s.invalidatePermissionCache(ctx, teamID, heroID)
+
+	if s.ketoClient == nil {
+		return nil
+	}
+
+	member, err := s.teamMemberRepo.GetByTeamAndHero(ctx, teamID, heroID)
+	if err != nil {
+		if errors.Is(err, interfaces.ErrTeamMemberNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	_, err = s.syncMemberPermissions(ctx, member)
+	return err
 }
 
 // DeleteMemberFromKeto 从 Keto 删除成员权限
@@ -88,6 +276,10 @@ func (s *TeamPermissionService) DeleteMemberFromKeto(ctx context.Context, teamID
 		_ = s.ketoClient.RemoveTeamMember(ctx, teamID, heroID, role)
 	}
 
+	if _, err := s.ketoClient.SyncTeamMemberPermissions(ctx, teamID, heroID, nil); err != nil {
+		fmt.Printf("Warning: Failed to revoke team permissions in Keto (team=%s hero=%s): %v\n", teamID, heroID, err)
+	}
+
 	return nil
 }
 
@@ -100,7 +292,12 @@ func (s *TeamPermissionService) UpdateMemberRoleInKeto(ctx context.Context, team
 	}
 
 	// 使用 Keto Client 的更新方法
-	return s.ketoClient.UpdateTeamMemberRole(ctx, teamID, heroID, oldRole, newRole)
+	if err := s.ketoClient.UpdateTeamMemberRole(ctx, teamID, heroID, oldRole, newRole); err != nil {
+		return err
+	}
+
+	_, err := s.syncMemberPermissions(ctx, &game_runtime.TeamMember{TeamID: teamID, HeroID: heroID, Role: newRole})
+	return err
 }
 
 // GetMemberRole 获取成员角色（优先 Keto，失败则回退到数据库）
@@ -133,57 +330,41 @@ func (s *TeamPermissionService) GetMemberRole(ctx context.Context, teamID, heroI
 }
 
 // CheckPermission 检查权限
+// 始终以数据库中的角色与职阶为准：Keto 的授予与撤销都可能同步失败或滞后，不能作为判定依据
 func (s *TeamPermissionService) CheckPermission(ctx context.Context, teamID, heroID, permission string) (bool, error) {
-	// 如果 Keto 客户端可用,使用 Keto 检查
-	if s.ketoClient != nil {
-		allowed, err := s.ketoClient.CheckTeamPermission(ctx, teamID, permission, heroID)
-		if err != nil {
-			// Keto 检查失败,降级到数据库检查
-			fmt.Printf("Warning: Keto permission check failed, falling back to database: %v\n", err)
-		} else {
-			return allowed, nil
+	// 按成员角色与职阶计算有效权限
+	member, err := s.teamMemberRepo.GetByTeamAndHero(ctx, teamID, heroID)
+	if err != nil {
+		if errors.Is(err, interfaces.ErrTeamMemberNotFound) {
+			return false, nil
 		}
+		return false, err
 	}
 
-	// 降级方案：查询成员角色
-	role, exists, err := s.GetMemberRole(ctx, teamID, heroID)
+	authority, err := loadMemberAuthority(ctx, s.teamRankRepo, member)
 	if err != nil {
 		return false, err
 	}
-	if !exists {
-		return false, nil
-	}
 
-	// 简单的权限映射
-	permissions := map[string][]string{
-		"leader": {
-			"select_dungeon", "enter_dungeon", "abandon_dungeon",
-			"kick_member", "kick_admin", "appoint_admin", "demote_admin",
-			"view_warehouse", "distribute_loot", "update_team_info", "disband_team",
-			"approve_join_request", "approve_invitation",
-		},
-		"admin": {
-			"select_dungeon", "enter_dungeon", "abandon_dungeon",
-			"kick_member", "view_warehouse", "distribute_loot",
-			"approve_join_request", "approve_invitation",
-		},
-		"member": {
-			"invite_member", "view_team_info", "leave_team",
-		},
-	}
+	return authority.Has(permission), nil
+}
 
-	rolePermissions, ok := permissions[role]
-	if !ok {
-		return false, nil
+// GetMemberPermissions 获取成员在团队内的有效权限（以数据库为准）
+func (s *TeamPermissionService) GetMemberPermissions(ctx context.Context, teamID, heroID string) ([]string, int, bool, error) {
+	member, err := s.teamMemberRepo.GetByTeamAndHero(ctx, teamID, heroID)
+	if err != nil {
+		if errors.Is(err, interfaces.ErrTeamMemberNotFound) {
+			return nil, 0, false, nil
+		}
+		return nil, 0, false, err
 	}
 
-	for _, p := range rolePermissions {
-		if p == permission {
-			return true, nil
-		}
+	authority, err := loadMemberAuthority(ctx, s.teamRankRepo, member)
+	if err != nil {
+		return nil, 0, false, err
 	}
 
-	return false, nil
+	return authority.List(), authority.Level, true, nil
 }
 
 // CheckPermissionWithCache 带缓存的权限检查
@@ -259,6 +440,19 @@ func (s *TeamPermissionService) CheckConsistency(ctx context.Context) error {
 				continue
 			}
 			repaired++
+			continue
+		}
+
+		// 角色一致时仍需核对职阶权限关系
+		changed, err := s.syncMemberPermissions(ctx, member)
+		if err != nil {
+			failed++
+			fmt.Printf("[TeamPermissionService] 同步成员权限失败 team=%s hero=%s: %v\n", member.TeamID, member.HeroID, err)
+			continue
+		}
+		if changed {
+			s.invalidatePermissionCache(ctx, member.TeamID, member.HeroID)
+			repaired++
 		}
 	}
 
@@ -293,6 +487,15 @@ func (s *TeamPermissionService) SyncAllMembersToKeto(ctx context.Context, teamID
 	return nil
 }
 
+// syncMemberPermissions 按角色与职阶计算有效权限并写入 Keto，返回是否有变更
+func (s *TeamPermissionService) syncMemberPermissions(ctx context.Context, member *game_runtime.TeamMember) (bool, error) {
+	authority, err := loadMemberAuthority(ctx, s.teamRankRepo, member)
+	if err != nil {
+		return false, fmt.Errorf("计算成员权限失败: %w", err)
+	}
+	return s.ketoClient.SyncTeamMemberPermissions(ctx, member.TeamID, member.HeroID, authority.List())
+}
+
 func (s *TeamPermissionService) loadPermissionCache(ctx context.Context, cacheKey string) (map[string]bool, error) {
 	if s.permissionCache == nil {
 		return nil, errPermissionCacheMiss
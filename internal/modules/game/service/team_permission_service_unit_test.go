@@ -41,6 +41,12 @@ type ketoRoleCall struct {
 	heroID string
 }
 
+type ketoSyncCall struct {
+	teamID      string
+	heroID      string
+	permissions []string
+}
+
 type fakeKetoClient struct {
 	addCalls      []ketoCall
 	removeCalls   []ketoCall
@@ -53,6 +59,9 @@ type fakeKetoClient struct {
 	roleResp      string
 	roleExists    bool
 	roleErr       error
+	syncCalls     []ketoSyncCall
+	syncChanged   bool
+	syncErr       error
 }
 
 func (f *fakeKetoClient) AddTeamMember(_ context.Context, teamID, heroID, role string) error {
@@ -95,6 +104,14 @@ func (f *fakeKetoClient) CheckTeamMemberRole(_ context.Context, teamID, heroID s
 	return f.roleResp, f.roleExists, nil
 }
 
+func (f *fakeKetoClient) SyncTeamMemberPermissions(_ context.Context, teamID, heroID string, permissions []string) (bool, error) {
+	f.syncCalls = append(f.syncCalls, ketoSyncCall{teamID: teamID, heroID: heroID, permissions: permissions})
+	if f.syncErr != nil {
+		return false, f.syncErr
+	}
+	return f.syncChanged, nil
+}
+
 type fakeTeamMemberRepo struct {
 	members    map[string]*game_runtime.TeamMember
 	listByTeam map[string][]*game_runtime.TeamMember
@@ -296,7 +313,7 @@ func TestTeamPermissionService_CheckPermissionFallback(t *testing.T) {
 	assert.Error(t, err)
 }
 
-func TestTeamPermissionService_CheckPermission_UsesKeto(t *testing.T) {
+func TestTeamPermissionService_CheckPermission_DatabaseDecides(t *testing.T) {
 	ctx := context.Background()
 	repo := &fakeTeamMemberRepo{
 		members: map[string]*game_runtime.TeamMember{
@@ -305,21 +322,25 @@ func TestTeamPermissionService_CheckPermission_UsesKeto(t *testing.T) {
 		},
 	}
 
-	t.Run("uses keto result when available", func(t *testing.T) {
+	t.Run("stale keto allow does not grant access", func(t *testing.T) {
 		keto := &fakeKetoClient{checkResp: true}
 		svc := &TeamPermissionService{
 			teamMemberRepo: repo,
 			ketoClient:     keto,
 		}
 
-		allowed, err := svc.CheckPermission(ctx, "team-1", "hero-admin", "kick_member")
+		allowed, err := svc.CheckPermission(ctx, "team-1", "hero-member", "kick_member")
+		require.NoError(t, err)
+		assert.False(t, allowed, "撤销同步失败时 Keto 残留的授权不生效")
+		assert.Equal(t, 1, repo.getCalls)
+
+		allowed, err = svc.CheckPermission(ctx, "team-1", "hero-admin", "kick_member")
 		require.NoError(t, err)
 		assert.True(t, allowed)
-		assert.Equal(t, 0, repo.getCalls) // 未触发数据库回退
-		assert.Len(t, keto.checkCalls, 1)
+		assert.Empty(t, keto.checkCalls)
 	})
 
-	t.Run("falls back when keto fails", func(t *testing.T) {
+	t.Run("ignores keto failures", func(t *testing.T) {
 		keto := &fakeKetoClient{
 			checkErr: errors.New("keto down"),
 			roleErr:  errors.New("keto membership down"),
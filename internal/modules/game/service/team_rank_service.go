@@ -0,0 +1,473 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/impl"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// maxTeamRanks 每个团队最多可创建的职阶数
+const maxTeamRanks = 20
+
+// maxTeamRankNameLength 职阶名称最大长度（字符）
+const maxTeamRankNameLength = 32
+
+// TeamRankService 团队职阶服务
+type TeamRankService struct {
+	db                    *sql.DB
+	teamMemberRepo        interfaces.TeamMemberRepository
+	teamRankRepo          interfaces.TeamRankRepository
+	teamActivityRepo      interfaces.TeamActivityRepository
+	teamPermissionService *TeamPermissionService
+}
+
+// NewTeamRankService 创建团队职阶服务
+func NewTeamRankService(db *sql.DB, teamPermissionService *TeamPermissionService) *TeamRankService {
+	return &TeamRankService{
+		db:                    db,
+		teamMemberRepo:        impl.NewTeamMemberRepository(db),
+		teamRankRepo:          impl.NewTeamRankRepository(db),
+		teamActivityRepo:      impl.NewTeamActivityRepository(db),
+		teamPermissionService: teamPermissionService,
+	}
+}
+
+// SaveTeamRankRequest 创建/更新职阶请求
+type SaveTeamRankRequest struct {
+	TeamID      string
+	HeroID      string // 操作者英雄ID
+	RankID      string // 更新时必填
+	Name        string
+	Level       int
+	Permissions []string
+}
+
+// AssignTeamRankRequest 分配职阶请求
+type AssignTeamRankRequest struct {
+	TeamID       string
+	HeroID       string // 操作者英雄ID
+	TargetHeroID string
+	RankID       string // 为空表示取消职阶，恢复角色默认权限
+}
+
+// TeamMemberPermissions 成员有效权限
+type TeamMemberPermissions struct {
+	Role        string
+	Rank        *interfaces.TeamRank
+	Level       int
+	Permissions []string
+}
+
+// ListRanks 查看团队职阶（团队成员可查看）
+func (s *TeamRankService) ListRanks(ctx context.Context, teamID, heroID string) ([]*interfaces.TeamRank, error) {
+	if teamID == "" || heroID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+	if _, err := s.teamMemberRepo.GetByTeamAndHero(ctx, teamID, heroID); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
+	}
+
+	ranks, err := s.teamRankRepo.ListByTeam(ctx, teamID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询团队职阶失败")
+	}
+	return ranks, nil
+}
+
+// CreateRank 创建职阶（需要 manage_ranks 权限）
+func (s *TeamRankService) CreateRank(ctx context.Context, req *SaveTeamRankRequest) (*interfaces.TeamRank, error) {
+	// 1. 验证参数
+	if req.TeamID == "" || req.HeroID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+
+	// 2. 检查权限
+	_, operator, err := s.requireRankManager(ctx, req.TeamID, req.HeroID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 校验职阶定义
+	name, permissions, err := validateRankDefinition(req.Name, req.Level, req.Permissions, operator)
+	if err != nil {
+		return nil, err
+	}
+
+	ranks, err := s.teamRankRepo.ListByTeam(ctx, req.TeamID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询团队职阶失败")
+	}
+	if len(ranks) >= maxTeamRanks {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, fmt.Sprintf("每个团队最多创建 %d 个职阶", maxTeamRanks))
+	}
+	if findRankByName(ranks, name, "") != nil {
+		return nil, xerrors.New(xerrors.CodeDuplicateResource, "职阶名称已存在")
+	}
+
+	// 4. 创建职阶
+	rank := &interfaces.TeamRank{
+		TeamID:      req.TeamID,
+		Name:        name,
+		Level:       req.Level,
+		Permissions: permissions,
+	}
+	err = s.withActivity(ctx, func(tx *sql.Tx) error {
+		return s.teamRankRepo.Create(ctx, tx, rank)
+	}, func() teamActivityEntry {
+		return rankActivity(interfaces.TeamActivityRankCreated, req.HeroID, rank)
+	})
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "创建团队职阶失败")
+	}
+	return rank, nil
+}
+
+// UpdateRank 更新职阶（需要 manage_ranks 权限，且只能修改低于自身等级的职阶）
+func (s *TeamRankService) UpdateRank(ctx context.Context, req *SaveTeamRankRequest) (*interfaces.TeamRank, error) {
+	// 1. 验证参数
+	if req.TeamID == "" || req.HeroID == "" || req.RankID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+
+	// 2. 检查权限
+	_, operator, err := s.requireRankManager(ctx, req.TeamID, req.HeroID)
+	if err != nil {
+		return nil, err
+	}
+
+	rank, err := s.loadManageableRank(ctx, req.TeamID, req.RankID, operator)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 校验职阶定义
+	name, permissions, err := validateRankDefinition(req.Name, req.Level, req.Permissions, operator)
+	if err != nil {
+		return nil, err
+	}
+
+	ranks, err := s.teamRankRepo.ListByTeam(ctx, req.TeamID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询团队职阶失败")
+	}
+	if findRankByName(ranks, name, rank.ID) != nil {
+		return nil, xerrors.New(xerrors.CodeDuplicateResource, "职阶名称已存在")
+	}
+
+	// 4. 更新职阶
+	rank.Name = name
+	rank.Level = req.Level
+	rank.Permissions = permissions
+	err = s.withActivity(ctx, func(tx *sql.Tx) error {
+		return s.teamRankRepo.Update(ctx, tx, rank)
+	}, func() teamActivityEntry {
+		return rankActivity(interfaces.TeamActivityRankUpdated, req.HeroID, rank)
+	})
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新团队职阶失败")
+	}
+
+	// 5. 重新同步持有该职阶的成员
+	s.resyncRankMembers(ctx, req.TeamID, rank.ID)
+
+	return rank, nil
+}
+
+// DeleteRank 删除职阶（需要 manage_ranks 权限），原持有成员恢复角色默认权限，恢复后的权限不能超出操作者
+func (s *TeamRankService) DeleteRank(ctx context.Context, teamID, heroID, rankID string) error {
+	// 1. 验证参数
+	if teamID == "" || heroID == "" || rankID == "" {
+		return xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+
+	// 2. 检查权限
+	_, operator, err := s.requireRankManager(ctx, teamID, heroID)
+	if err != nil {
+		return err
+	}
+
+	rank, err := s.loadManageableRank(ctx, teamID, rankID, operator)
+	if err != nil {
+		return err
+	}
+
+	// 3. 删除前记录持有成员，删除后由外键置空职阶
+	heroIDs, err := s.teamRankRepo.ListMemberHeroIDs(ctx, teamID, rank.ID)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "查询职阶成员失败")
+	}
+
+	// 持有成员将恢复角色默认权限（管理员恢复完整管理权限），恢复后的权限不能超出操作者
+	for _, memberHeroID := range heroIDs {
+		member, err := s.teamMemberRepo.GetByTeamAndHero(ctx, teamID, memberHeroID)
+		if err != nil {
+			return xerrors.Wrap(err, xerrors.CodeInternalError, "查询职阶成员失败")
+		}
+		if err := requireAuthorityWithin(operator, resolveMemberAuthority(member.Role, nil)); err != nil {
+			return err
+		}
+	}
+
+	err = s.withActivity(ctx, func(tx *sql.Tx) error {
+		return s.teamRankRepo.Delete(ctx, tx, teamID, rank.ID)
+	}, func() teamActivityEntry {
+		entry := rankActivity(interfaces.TeamActivityRankDeleted, heroID, rank)
+		entry.Details["affected_members"] = len(heroIDs)
+		return entry
+	})
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "删除团队职阶失败")
+	}
+
+	// 4. 重新同步受影响成员
+	for _, memberHeroID := range heroIDs {
+		s.resyncMember(ctx, teamID, memberHeroID)
+	}
+
+	return nil
+}
+
+// AssignRank 为成员分配或取消职阶（需要 manage_ranks 权限，只能管理低于自身等级的成员）
+func (s *TeamRankService) AssignRank(ctx context.Context, req *AssignTeamRankRequest) error {
+	// 1. 验证参数
+	if req.TeamID == "" || req.HeroID == "" || req.TargetHeroID == "" {
+		return xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+
+	// 2. 检查权限
+	_, operator, err := s.requireRankManager(ctx, req.TeamID, req.HeroID)
+	if err != nil {
+		return err
+	}
+
+	// 3. 检查目标成员
+	target, err := s.teamMemberRepo.GetByTeamAndHero(ctx, req.TeamID, req.TargetHeroID)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeResourceNotFound, "目标英雄不是团队成员")
+	}
+	if target.Role == "leader" {
+		return xerrors.New(xerrors.CodeInvalidParams, "队长不能分配职阶")
+	}
+	targetAuthority, err := loadMemberAuthority(ctx, s.teamRankRepo, target)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "查询成员权限失败")
+	}
+	if targetAuthority.Level >= operator.Level {
+		return xerrors.New(xerrors.CodePermissionDenied, "只能管理等级低于自己的成员")
+	}
+
+	// 4. 检查新职阶
+	var rankID *string
+	var newRank *interfaces.TeamRank
+	details := map[string]interface{}{}
+	if req.RankID != "" {
+		newRank, err = s.loadManageableRank(ctx, req.TeamID, req.RankID, operator)
+		if err != nil {
+			return err
+		}
+		rankID = &newRank.ID
+		details["rank_name"] = newRank.Name
+		details["level"] = newRank.Level
+	}
+
+	// 变更后的有效权限不能超出操作者（取消职阶时管理员会恢复完整管理权限）
+	if err := requireAuthorityWithin(operator, resolveMemberAuthority(target.Role, newRank)); err != nil {
+		return err
+	}
+
+	// 5. 更新成员职阶
+	err = s.withActivity(ctx, func(tx *sql.Tx) error {
+		return s.teamRankRepo.SetMemberRank(ctx, tx, req.TeamID, req.TargetHeroID, rankID)
+	}, func() teamActivityEntry {
+		return teamActivityEntry{
+			TeamID:       req.TeamID,
+			EventType:    interfaces.TeamActivityRankAssigned,
+			ActorHeroID:  req.HeroID,
+			TargetHeroID: req.TargetHeroID,
+			SourceID:     req.RankID,
+			Details:      details,
+		}
+	})
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "设置成员职阶失败")
+	}
+
+	// 6. 同步到 Keto
+	s.resyncMember(ctx, req.TeamID, req.TargetHeroID)
+
+	return nil
+}
+
+// GetMemberPermissions 查询成员自己的职阶与有效权限
+func (s *TeamRankService) GetMemberPermissions(ctx context.Context, teamID, heroID string) (*TeamMemberPermissions, error) {
+	if teamID == "" || heroID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+
+	member, err := s.teamMemberRepo.GetByTeamAndHero(ctx, teamID, heroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
+	}
+
+	var rank *interfaces.TeamRank
+	if member.Role != "leader" {
+		rank, err = s.teamRankRepo.GetMemberRank(ctx, teamID, heroID)
+		if err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询成员职阶失败")
+		}
+	}
+
+	authority := resolveMemberAuthority(member.Role, rank)
+	return &TeamMemberPermissions{
+		Role:        member.Role,
+		Rank:        rank,
+		Level:       authority.Level,
+		Permissions: authority.List(),
+	}, nil
+}
+
+// withActivity 在同一事务内执行职阶变更并记录团队动态，entry 在变更成功后构造（可读取新生成的ID）
+func (s *TeamRankService) withActivity(ctx context.Context, apply func(tx *sql.Tx) error, entry func() teamActivityEntry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := apply(tx); err != nil {
+		return err
+	}
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, entry()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// rankActivity 职阶定义变更动态
+func rankActivity(eventType, operatorHeroID string, rank *interfaces.TeamRank) teamActivityEntry {
+	return teamActivityEntry{
+		TeamID:      rank.TeamID,
+		EventType:   eventType,
+		ActorHeroID: operatorHeroID,
+		SourceID:    rank.ID,
+		Details: map[string]interface{}{
+			"name":        rank.Name,
+			"level":       rank.Level,
+			"permissions": rank.Permissions,
+		},
+	}
+}
+
+// requireRankManager 检查操作者拥有 manage_ranks 权限
+func (s *TeamRankService) requireRankManager(ctx context.Context, teamID, heroID string) (*game_runtime.TeamMember, *teamAuthority, error) {
+	member, err := s.teamMemberRepo.GetByTeamAndHero(ctx, teamID, heroID)
+	if err != nil {
+		return nil, nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
+	}
+	authority, err := requireTeamPermission(ctx, s.teamRankRepo, member, TeamPermManageRanks, "您没有管理职阶的权限")
+	if err != nil {
+		return nil, nil, err
+	}
+	return member, authority, nil
+}
+
+// requireAuthorityWithin 成员变更后的有效权限需低于操作者等级，且不超出操作者拥有的权限
+func requireAuthorityWithin(operator, result *teamAuthority) error {
+	if result.Level >= operator.Level {
+		return xerrors.New(xerrors.CodePermissionDenied, "变更后成员的等级不能高于或等于自己")
+	}
+	if !operator.HasAll(result.List()) {
+		return xerrors.New(xerrors.CodePermissionDenied, "变更后成员的权限不能超出自己的权限")
+	}
+	return nil
+}
+
+// loadManageableRank 获取职阶并确认其等级低于操作者
+func (s *TeamRankService) loadManageableRank(ctx context.Context, teamID, rankID string, operator *teamAuthority) (*interfaces.TeamRank, error) {
+	rank, err := s.teamRankRepo.GetByID(ctx, teamID, rankID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询团队职阶失败")
+	}
+	if rank == nil {
+		return nil, xerrors.New(xerrors.CodeResourceNotFound, "职阶不存在")
+	}
+	if rank.Level >= operator.Level {
+		return nil, xerrors.New(xerrors.CodePermissionDenied, "只能管理等级低于自己的职阶")
+	}
+	return rank, nil
+}
+
+func (s *TeamRankService) resyncRankMembers(ctx context.Context, teamID, rankID string) {
+	heroIDs, err := s.teamRankRepo.ListMemberHeroIDs(ctx, teamID, rankID)
+	if err != nil {
+		fmt.Printf("Warning: Failed to list rank members (team=%s rank=%s): %v\n", teamID, rankID, err)
+		return
+	}
+	for _, heroID := range heroIDs {
+		s.resyncMember(ctx, teamID, heroID)
+	}
+}
+
+// resyncMember 同步失败不影响主流程，由权限一致性任务兜底
+func (s *TeamRankService) resyncMember(ctx context.Context, teamID, heroID string) {
+	if s.teamPermissionService == nil {
+		return
+	}
+	if err := s.teamPermissionService.ResyncMemberPermissions(ctx, teamID, heroID); err != nil {
+		fmt.Printf("Warning: Failed to sync member permissions to Keto (team=%s hero=%s): %v\n", teamID, heroID, err)
+	}
+}
+
+// validateRankDefinition 校验职阶名称、等级与权限，返回规范化后的名称和去重排序后的权限
+// 职阶等级必须低于操作者，且只能授予操作者自身拥有的权限
+func validateRankDefinition(name string, level int, permissions []string, operator *teamAuthority) (string, []string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, xerrors.New(xerrors.CodeInvalidParams, "职阶名称不能为空")
+	}
+	if utf8.RuneCountInString(name) > maxTeamRankNameLength {
+		return "", nil, xerrors.New(xerrors.CodeInvalidParams, fmt.Sprintf("职阶名称不能超过 %d 个字符", maxTeamRankNameLength))
+	}
+	if level < 1 || level >= teamLeaderLevel {
+		return "", nil, xerrors.New(xerrors.CodeInvalidParams, "职阶等级需在 1-99 之间")
+	}
+	if level >= operator.Level {
+		return "", nil, xerrors.New(xerrors.CodePermissionDenied, "职阶等级必须低于您自己的等级")
+	}
+
+	granted := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		if !isRankGrantablePermission(permission) {
+			return "", nil, xerrors.New(xerrors.CodeInvalidParams, fmt.Sprintf("不支持授予的权限: %s", permission))
+		}
+		if !operator.Has(permission) {
+			return "", nil, xerrors.New(xerrors.CodePermissionDenied, fmt.Sprintf("不能授予自己没有的权限: %s", permission))
+		}
+		granted[permission] = true
+	}
+
+	normalized := make([]string, 0, len(granted))
+	for _, permission := range teamPermissionCatalog {
+		if granted[permission] {
+			normalized = append(normalized, permission)
+		}
+	}
+	return name, normalized, nil
+}
+
+// findRankByName 按名称查找职阶（忽略 excludeID）
+func findRankByName(ranks []*interfaces.TeamRank, name, excludeID string) *interfaces.TeamRank {
+	for _, rank := range ranks {
+		if rank.ID != excludeID && rank.Name == name {
+			return rank
+		}
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/repository/interfaces"
+)
+
+type fakeTeamRankRepo struct {
+	memberRanks map[string]*interfaces.TeamRank
+	ranks       map[string]*interfaces.TeamRank // team_id:rank_id -> 职阶
+	getErr      error
+}
+
+func (f *fakeTeamRankRepo) Create(context.Context, boil.ContextExecutor, *interfaces.TeamRank) error {
+	panic("not implemented")
+}
+
+func (f *fakeTeamRankRepo) Update(context.Context, boil.ContextExecutor, *interfaces.TeamRank) error {
+	panic("not implemented")
+}
+
+func (f *fakeTeamRankRepo) Delete(context.Context, boil.ContextExecutor, string, string) error {
+	panic("not implemented")
+}
+
+func (f *fakeTeamRankRepo) GetByID(_ context.Context, teamID, rankID string) (*interfaces.TeamRank, error) {
+	return f.ranks[teamID+":"+rankID], nil
+}
+
+func (f *fakeTeamRankRepo) ListByTeam(context.Context, string) ([]*interfaces.TeamRank, error) {
+	panic("not implemented")
+}
+
+func (f *fakeTeamRankRepo) CountByTeam(context.Context, string) (int, error) {
+	panic("not implemented")
+}
+
+func (f *fakeTeamRankRepo) GetMemberRank(_ context.Context, teamID, heroID string) (*interfaces.TeamRank, error) {
+	if f.getErr != nil {
+		return nil, f.getErr
+	}
+	return f.memberRanks[teamID+":"+heroID], nil
+}
+
+func (f *fakeTeamRankRepo) SetMemberRank(context.Context, boil.ContextExecutor, string, string, *string) error {
+	panic("not implemented")
+}
+
+func (f *fakeTeamRankRepo) ListMemberHeroIDs(_ context.Context, teamID, rankID string) ([]string, error) {
+	var heroIDs []string
+	for key, rank := range f.memberRanks {
+		if rank.ID == rankID && strings.HasPrefix(key, teamID+":") {
+			heroIDs = append(heroIDs, strings.TrimPrefix(key, teamID+":"))
+		}
+	}
+	return heroIDs, nil
+}
+
+func TestResolveMemberAuthority(t *testing.T) {
+	leader := resolveMemberAuthority("leader", &interfaces.TeamRank{Level: 5})
+	assert.Equal(t, teamLeaderLevel, leader.Level, "队长忽略职阶")
+	assert.Equal(t, teamPermissionCatalog, leader.List())
+
+	admin := resolveMemberAuthority("admin", nil)
+	assert.Equal(t, teamAdminLevel, admin.Level)
+	assert.True(t, admin.Has(TeamPermDistributeLoot))
+	assert.True(t, admin.Has(TeamPermViewTeamInfo))
+	assert.False(t, admin.Has(TeamPermDisbandTeam))
+	assert.False(t, admin.Has(TeamPermManageRanks))
+
+	member := resolveMemberAuthority("member", nil)
+	assert.Equal(t, teamMemberLevel, member.Level)
+	assert.Equal(t, []string{TeamPermInviteMember, TeamPermViewTeamInfo, TeamPermLeaveTeam}, member.List())
+
+	quartermaster := resolveMemberAuthority("member", &interfaces.TeamRank{
+		Level:       40,
+		Permissions: []string{TeamPermDistributeLoot, TeamPermDisbandTeam, "unknown"},
+	})
+	assert.Equal(t, 40, quartermaster.Level)
+	assert.Equal(t, []string{TeamPermDistributeLoot, TeamPermViewTeamInfo, TeamPermLeaveTeam}, quartermaster.List(),
+		"职阶权限替换角色默认权限，且不能包含解散团队与未知权限")
+
+	recruit := resolveMemberAuthority("admin", &interfaces.TeamRank{Level: 5})
+	assert.Equal(t, 5, recruit.Level, "已分配职阶的管理员按职阶判定")
+	assert.False(t, recruit.Has(TeamPermKickMember))
+}
+
+func TestValidateRankDefinition(t *testing.T) {
+	leader := resolveMemberAuthority("leader", nil)
+	officer := resolveMemberAuthority("member", &interfaces.TeamRank{
+		Level:       60,
+		Permissions: []string{TeamPermManageRanks, TeamPermKickMember, TeamPermInviteMember},
+	})
+
+	name, perms, err := validateRankDefinition("  军需官 ", 40,
+		[]string{TeamPermViewWarehouse, TeamPermDistributeLoot, TeamPermViewWarehouse}, leader)
+	require.NoError(t, err)
+	assert.Equal(t, "军需官", name)
+	assert.Equal(t, []string{TeamPermViewWarehouse, TeamPermDistributeLoot}, perms, "去重并按权限目录排序")
+
+	_, perms, err = validateRankDefinition("新兵", 1, nil, officer)
+	require.NoError(t, err)
+	assert.Empty(t, perms)
+
+	tests := []struct {
+		name     string
+		rankName string
+		level    int
+		perms    []string
+		operator *teamAuthority
+	}{
+		{"empty name", "  ", 10, nil, leader},
+		{"name too long", "一二三四五六七八九十一二三四五六七八九十一二三四五六七八九十一二三", 10, nil, leader},
+		{"level out of range", "军官", 100, nil, leader},
+		{"level not below operator", "军官", 60, nil, officer},
+		{"disband not grantable", "军官", 10, []string{TeamPermDisbandTeam}, leader},
+		{"unknown permission", "军官", 10, []string{"fly"}, leader},
+		{"grant permission not held", "军官", 10, []string{TeamPermDistributeLoot}, officer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := validateRankDefinition(tt.rankName, tt.level, tt.perms, tt.operator)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRequireTeamPermission_UsesRank(t *testing.T) {
+	ctx := context.Background()
+	ranks := &fakeTeamRankRepo{memberRanks: map[string]*interfaces.TeamRank{
+		"team-1:hero-qm": {Level: 40, Permissions: []string{TeamPermDistributeLoot}},
+	}}
+	quartermaster := &game_runtime.TeamMember{TeamID: "team-1", HeroID: "hero-qm", Role: "member"}
+	member := &game_runtime.TeamMember{TeamID: "team-1", HeroID: "hero-member", Role: "member"}
+
+	authority, err := requireTeamPermission(ctx, ranks, quartermaster, TeamPermDistributeLoot, "denied")
+	require.NoError(t, err)
+	assert.Equal(t, 40, authority.Level)
+
+	_, err = requireTeamPermission(ctx, ranks, member, TeamPermDistributeLoot, "denied")
+	assert.ErrorContains(t, err, "denied")
+
+	_, err = requireTeamPermission(ctx, nil, member, TeamPermInviteMember, "denied")
+	assert.NoError(t, err, "未配置职阶仓储时按角色默认权限")
+
+	ranks.getErr = errors.New("db down")
+	_, err = requireTeamPermission(ctx, ranks, quartermaster, TeamPermDistributeLoot, "denied")
+	assert.Error(t, err)
+}
+
+func TestTeamMemberService_PromoteToAdminRequiresAdminPermissions(t *testing.T) {
+	ctx := context.Background()
+	members := &fakeTeamMemberRepo{members: map[string]*game_runtime.TeamMember{
+		"team-1:hero-officer": {TeamID: "team-1", HeroID: "hero-officer", Role: "member"},
+		"team-1:hero-member":  {TeamID: "team-1", HeroID: "hero-member", Role: "member"},
+	}}
+	ranks := &fakeTeamRankRepo{memberRanks: map[string]*interfaces.TeamRank{
+		"team-1:hero-officer": {Level: 60, Permissions: []string{TeamPermAppointAdmin}},
+	}}
+	svc := &TeamMemberService{teamMemberRepo: members, teamRankRepo: ranks}
+
+	err := svc.PromoteToAdmin(ctx, &PromoteToAdminRequest{
+		TeamID:       "team-1",
+		TargetHeroID: "hero-member",
+		LeaderHeroID: "hero-officer",
+	})
+	assert.ErrorContains(t, err, "权限不足", "仅持有任命权限的职阶不能任命拥有完整管理权限的管理员")
+
+	assert.True(t, resolveMemberAuthority("leader", nil).HasAll(teamRolePermissions["admin"]))
+}
+
+func TestTeamRankService_RevertingRankCannotEscalateAdmin(t *testing.T) {
+	ctx := context.Background()
+	restricted := &interfaces.TeamRank{ID: "rank-restricted", TeamID: "team-1", Name: "见习管理", Level: 40}
+	members := &fakeTeamMemberRepo{members: map[string]*game_runtime.TeamMember{
+		"team-1:hero-officer": {TeamID: "team-1", HeroID: "hero-officer", Role: "member"},
+		"team-1:hero-admin":   {TeamID: "team-1", HeroID: "hero-admin", Role: "admin"},
+	}}
+	ranks := &fakeTeamRankRepo{
+		memberRanks: map[string]*interfaces.TeamRank{
+			"team-1:hero-officer": {ID: "rank-officer", Level: 60, Permissions: []string{TeamPermManageRanks}},
+			"team-1:hero-admin":   restricted,
+		},
+		ranks: map[string]*interfaces.TeamRank{"team-1:rank-restricted": restricted},
+	}
+	svc := &TeamRankService{teamMemberRepo: members, teamRankRepo: ranks}
+
+	// 取消职阶会让受限管理员恢复完整管理权限，超出操作者权限
+	err := svc.AssignRank(ctx, &AssignTeamRankRequest{TeamID: "team-1", HeroID: "hero-officer", TargetHeroID: "hero-admin"})
+	assert.ErrorContains(t, err, "权限不能超出")
+
+	// 删除职阶同理
+	err = svc.DeleteRank(ctx, "team-1", "hero-officer", "rank-restricted")
+	assert.ErrorContains(t, err, "权限不能超出")
+
+	leader := resolveMemberAuthority("leader", nil)
+	assert.NoError(t, requireAuthorityWithin(leader, resolveMemberAuthority("admin", nil)))
+	assert.Error(t, requireAuthorityWithin(resolveMemberAuthority("admin", nil), resolveMemberAuthority("admin", nil)))
+}
+
+func TestTeamPermissionService_RankPermissions(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeTeamMemberRepo{
+		members: map[string]*game_runtime.TeamMember{
+			"team-1:hero-qm": {TeamID: "team-1", HeroID: "hero-qm", Role: "member"},
+		},
+	}
+	ranks := &fakeTeamRankRepo{memberRanks: map[string]*interfaces.TeamRank{
+		"team-1:hero-qm": {Level: 40, Permissions: []string{TeamPermDistributeLoot}},
+	}}
+
+	t.Run("keto deny is confirmed against database", func(t *testing.T) {
+		keto := &fakeKetoClient{checkResp: false}
+		svc := &TeamPermissionService{teamMemberRepo: repo, teamRankRepo: ranks, ketoClient: keto}
+
+		allowed, err := svc.CheckPermission(ctx, "team-1", "hero-qm", TeamPermDistributeLoot)
+		require.NoError(t, err)
+		assert.True(t, allowed, "Keto 尚未同步时以数据库职阶为准")
+
+		allowed, err = svc.CheckPermission(ctx, "team-1", "hero-qm", TeamPermInviteMember)
+		require.NoError(t, err)
+		assert.False(t, allowed, "职阶未授予邀请权限")
+	})
+
+	t.Run("sync writes effective permissions", func(t *testing.T) {
+		keto := &fakeKetoClient{}
+		svc := &TeamPermissionService{teamMemberRepo: repo, teamRankRepo: ranks, ketoClient: keto}
+
+		require.NoError(t, svc.SyncMemberToKeto(ctx, repo.members["team-1:hero-qm"]))
+		require.NoError(t, svc.UpdateMemberRoleInKeto(ctx, "team-1", "hero-leader", "admin", "leader"))
+		require.NoError(t, svc.DeleteMemberFromKeto(ctx, "team-1", "hero-gone"))
+
+		assert.Equal(t, []ketoSyncCall{
+			{teamID: "team-1", heroID: "hero-qm", permissions: []string{TeamPermDistributeLoot, TeamPermViewTeamInfo, TeamPermLeaveTeam}},
+			{teamID: "team-1", heroID: "hero-leader", permissions: teamPermissionCatalog},
+			{teamID: "team-1", heroID: "hero-gone", permissions: nil},
+		}, keto.syncCalls)
+	})
+
+	t.Run("resync skips members who left", func(t *testing.T) {
+		keto := &fakeKetoClient{}
+		svc := &TeamPermissionService{teamMemberRepo: repo, teamRankRepo: ranks, ketoClient: keto}
+
+		require.NoError(t, svc.ResyncMemberPermissions(ctx, "team-1", "hero-missing"))
+		assert.Empty(t, keto.syncCalls)
+	})
+}
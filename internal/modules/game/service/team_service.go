@@ -18,6 +18,8 @@ type TeamService struct {
 	teamRepo              interfaces.TeamRepository
 	teamMemberRepo        interfaces.TeamMemberRepository
 	teamWarehouseRepo     interfaces.TeamWarehouseRepository
+	teamRankRepo          interfaces.TeamRankRepository
+	teamActivityRepo      interfaces.TeamActivityRepository
 	heroRepo              interfaces.HeroRepository
 	teamPermissionService *TeamPermissionService
 }
@@ -29,6 +31,8 @@ func NewTeamService(db *sql.DB, teamPermissionService *TeamPermissionService) *T
 		teamRepo:              impl.NewTeamRepository(db),
 		teamMemberRepo:        impl.NewTeamMemberRepository(db),
 		teamWarehouseRepo:     impl.NewTeamWarehouseRepository(db),
+		teamRankRepo:          impl.NewTeamRankRepository(db),
+		teamActivityRepo:      impl.NewTeamActivityRepository(db),
 		heroRepo:              impl.NewHeroRepository(db),
 		teamPermissionService: teamPermissionService,
 	}
@@ -126,6 +130,14 @@ func (s *TeamService) CreateTeam(ctx context.Context, req *CreateTeamRequest) (*
 	if err := s.teamWarehouseRepo.Create(ctx, tx, warehouse); err != nil {
 		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "创建团队仓库失败")
 	}
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, teamActivityEntry{
+		TeamID:      team.ID,
+		EventType:   interfaces.TeamActivityTeamCreated,
+		ActorHeroID: req.HeroID,
+		Details:     map[string]interface{}{"name": team.Name},
+	}); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+	}
 
 	// 9. 提交事务
 	if err := tx.Commit(); err != nil {
@@ -160,7 +172,7 @@ type UpdateTeamInfoRequest struct {
 	Description *string
 }
 
-// UpdateTeamInfo 更新团队信息（只有队长可以操作）
+// UpdateTeamInfo 更新团队信息（需要 update_team_info 权限）
 func (s *TeamService) UpdateTeamInfo(ctx context.Context, req *UpdateTeamInfoRequest) error {
 	// 1. 验证参数
 	if req.TeamID == "" {
@@ -170,13 +182,13 @@ func (s *TeamService) UpdateTeamInfo(ctx context.Context, req *UpdateTeamInfoReq
 		return xerrors.New(xerrors.CodeInvalidParams, "英雄ID不能为空")
 	}
 
-	// 2. 检查是否是队长
+	// 2. 检查权限
 	member, err := s.teamMemberRepo.GetByTeamAndHero(ctx, req.TeamID, req.HeroID)
 	if err != nil {
 		return xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
 	}
-	if member.Role != "leader" {
-		return xerrors.New(xerrors.CodePermissionDenied, "只有队长可以更新团队信息")
+	if _, err := requireTeamPermission(ctx, s.teamRankRepo, member, TeamPermUpdateTeamInfo, "只有队长可以更新团队信息"); err != nil {
+		return err
 	}
 
 	// 3. 获取团队
@@ -208,6 +220,18 @@ func (s *TeamService) UpdateTeamInfo(ctx context.Context, req *UpdateTeamInfoReq
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "更新团队信息失败")
 	}
 
+	// 6. 记录团队动态
+	details := map[string]interface{}{"name": team.Name}
+	if req.Description != nil {
+		details["description_changed"] = true
+	}
+	logTeamActivity(ctx, s.teamActivityRepo, teamActivityEntry{
+		TeamID:      req.TeamID,
+		EventType:   interfaces.TeamActivityTeamUpdated,
+		ActorHeroID: req.HeroID,
+		Details:     details,
+	})
+
 	return nil
 }
 
@@ -247,12 +271,29 @@ func (s *TeamService) DisbandTeam(ctx context.Context, teamID, heroID string) er
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "获取成员列表失败")
 	}
 
-	// 4.1 删除团队成员记录，避免软删除团队后仍存在成员关联
+	// 4.1 删除团队成员记录，避免软删除团队后仍存在成员关联（与解散动态同一事务）
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer tx.Rollback()
+
 	for _, m := range members {
-		if err := s.teamMemberRepo.Delete(ctx, s.db, m.ID); err != nil {
+		if err := s.teamMemberRepo.Delete(ctx, tx, m.ID); err != nil {
 			return xerrors.Wrap(err, xerrors.CodeInternalError, "删除团队成员失败")
 		}
 	}
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, teamActivityEntry{
+		TeamID:      teamID,
+		EventType:   interfaces.TeamActivityTeamDisbanded,
+		ActorHeroID: heroID,
+		Details:     map[string]interface{}{"member_count": len(members)},
+	}); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+	}
+	if err := tx.Commit(); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
 
 	// 5. 软删除团队
 	if err := s.teamRepo.Delete(ctx, teamID); err != nil {
@@ -292,10 +333,27 @@ func (s *TeamService) LeaveTeam(ctx context.Context, teamID, heroID string) erro
 		return xerrors.New(xerrors.CodePermissionDenied, "队长不能离开团队，请先转移队长或解散团队")
 	}
 
-	// 4. 删除成员记录
-	if err := s.teamMemberRepo.Delete(ctx, s.db, member.ID); err != nil {
+	// 4. 删除成员记录并记录动态
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer tx.Rollback()
+
+	if err := s.teamMemberRepo.Delete(ctx, tx, member.ID); err != nil {
 		return xerrors.Wrap(err, xerrors.CodeInternalError, "离开团队失败")
 	}
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, teamActivityEntry{
+		TeamID:      teamID,
+		EventType:   interfaces.TeamActivityMemberLeft,
+		ActorHeroID: heroID,
+		Details:     map[string]interface{}{"role": member.Role},
+	}); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+	}
+	if err := tx.Commit(); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
 
 	// 5. 删除 Keto 权限关系
 	if s.teamPermissionService != nil {
@@ -371,6 +429,14 @@ func (s *TeamService) transferLeader(ctx context.Context, team *game_runtime.Tea
 	if err := s.teamMemberRepo.UpdateRole(ctx, tx, team.ID, newLeaderCandidate.HeroID, "leader"); err != nil {
 		return fmt.Errorf("更新新队长角色失败: %w", err)
 	}
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, teamActivityEntry{
+		TeamID:       team.ID,
+		EventType:    interfaces.TeamActivityLeaderTransferred,
+		TargetHeroID: newLeaderCandidate.HeroID,
+		Details:      map[string]interface{}{"previous_leader_hero_id": oldLeaderHeroID, "reason": "inactive"},
+	}); err != nil {
+		return fmt.Errorf("记录团队动态失败: %w", err)
+	}
 
 	// 6. 提交事务
 	if err := tx.Commit(); err != nil {
@@ -0,0 +1,713 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/pkg/notify"
+	"tsu-self/internal/pkg/xerrors"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// 仓库容量未配置时的兜底值（与战利品入库一致）
+const (
+	defaultWarehouseMaxSlots = 100
+	defaultWarehouseMaxStack = 999
+)
+
+// maxPendingWithdrawals 每名成员同时待审批的取用申请上限
+const maxPendingWithdrawals = 5
+
+// 取用申请在仓库日志中的结果（success/failed 之外）
+const (
+	warehouseLogResultPending   = "pending"
+	warehouseLogResultDenied    = "denied"
+	warehouseLogResultCancelled = "cancelled"
+)
+
+// DepositGoldRequest 成员捐献金币请求
+type DepositGoldRequest struct {
+	TeamID string
+	HeroID string
+	Amount int64
+}
+
+// DepositGold 成员从钱包捐献金币到团队仓库（扣款、入库、记日志在同一事务内）
+func (s *TeamWarehouseService) DepositGold(ctx context.Context, req *DepositGoldRequest) error {
+	// 1. 验证参数
+	if req.TeamID == "" || req.HeroID == "" {
+		return xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+	if req.Amount <= 0 {
+		return xerrors.New(xerrors.CodeInvalidParams, "捐献金币数量必须大于0")
+	}
+
+	// 2. 检查权限（团队成员即可）
+	if _, err := s.teamMemberRepo.GetByTeamAndHero(ctx, req.TeamID, req.HeroID); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
+	}
+
+	// 3. 获取仓库
+	warehouse, err := s.teamWarehouseRepo.GetByTeamID(ctx, req.TeamID)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeResourceNotFound, "团队仓库不存在")
+	}
+
+	// 4. 开启事务
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer tx.Rollback()
+
+	// 5. 扣除英雄金币（余额不足时返回 CodeInsufficientResource）
+	if _, err := applyCurrencyTx(ctx, s.heroCurrencyRepo, tx, CurrencyChange{
+		HeroID:     req.HeroID,
+		Currency:   interfaces.CurrencyGold,
+		Amount:     -req.Amount,
+		SourceType: CurrencySourceWarehouse,
+		SourceID:   warehouse.ID,
+		Note:       "捐献到团队仓库",
+		Metadata:   map[string]interface{}{"team_id": req.TeamID, "action": "deposit"},
+	}); err != nil {
+		return err
+	}
+
+	// 6. 增加仓库金币
+	if err := s.teamWarehouseRepo.AddGold(ctx, tx, warehouse.ID, req.Amount); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "增加仓库金币失败")
+	}
+
+	// 7. 写入仓库日志
+	if err := s.logWarehouseEntry(ctx, tx, &interfaces.TeamWarehouseLootLog{
+		TeamID:      req.TeamID,
+		WarehouseID: warehouse.ID,
+		GoldAmount:  req.Amount,
+		ItemsJSON:   "[]",
+		Result:      "success",
+		EntryType:   interfaces.WarehouseLogEntryDeposit,
+		HeroID:      &req.HeroID,
+	}); err != nil {
+		return err
+	}
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, teamActivityEntry{
+		TeamID:      req.TeamID,
+		EventType:   interfaces.TeamActivityGoldDeposited,
+		ActorHeroID: req.HeroID,
+		Details:     map[string]interface{}{"amount": req.Amount},
+	}); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+	}
+
+	// 8. 提交事务
+	if err := tx.Commit(); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	return nil
+}
+
+// DepositItemsRequest 成员捐献背包物品请求
+type DepositItemsRequest struct {
+	TeamID string
+	HeroID string
+	Items  map[string]int // 物品ID -> 数量
+}
+
+// DepositItems 成员从背包捐献物品到团队仓库
+// 仓库按物品配置存放，已绑定、强化、镶嵌或耐久受损的物品实例不可捐献
+func (s *TeamWarehouseService) DepositItems(ctx context.Context, req *DepositItemsRequest) error {
+	// 1. 验证参数
+	if req.TeamID == "" || req.HeroID == "" {
+		return xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+	if len(req.Items) == 0 {
+		return xerrors.New(xerrors.CodeInvalidParams, "捐献物品不能为空")
+	}
+	for itemID, quantity := range req.Items {
+		if quantity <= 0 {
+			msg := fmt.Sprintf("物品 %s 的捐献数量必须大于0", itemID)
+			return xerrors.New(xerrors.CodeInvalidParams, msg).WithMetadata("user_message", msg)
+		}
+	}
+
+	// 2. 检查权限（团队成员即可）
+	if _, err := s.teamMemberRepo.GetByTeamAndHero(ctx, req.TeamID, req.HeroID); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
+	}
+
+	// 3. 校验物品配置
+	configs := make(map[string]*game_config.Item, len(req.Items))
+	for itemID := range req.Items {
+		itemCfg, err := s.itemRepo.GetByID(ctx, itemID)
+		if err != nil {
+			return xerrors.Wrap(err, xerrors.CodeResourceNotFound, "物品配置不存在")
+		}
+		if itemCfg.IsTradable.Valid && !itemCfg.IsTradable.Bool {
+			msg := fmt.Sprintf("物品 %s 不可交易，无法捐献", itemCfg.ItemName)
+			return xerrors.New(xerrors.CodeInvalidParams, msg).WithMetadata("user_message", msg)
+		}
+		configs[itemID] = itemCfg
+	}
+	maxSlots, maxStack, err := s.getLocationCapacity(ctx, "warehouse")
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "查询仓库容量失败")
+	}
+
+	// 4. 开启事务
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer tx.Rollback()
+
+	// 5. 锁定仓库后检查容量（inventory_capacities 中的 warehouse 配置），并发捐献时串行校验
+	warehouse, err := s.teamWarehouseRepo.GetByTeamIDForUpdate(ctx, tx, req.TeamID)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeResourceNotFound, "团队仓库不存在")
+	}
+	distinct, err := s.teamWarehouseItemRepo.CountDistinctItems(ctx, warehouse.ID)
+	if err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "统计仓库物品种类失败")
+	}
+	existing := make(map[string]int, len(req.Items))
+	for itemID := range req.Items {
+		count, err := s.teamWarehouseItemRepo.GetItemCount(ctx, warehouse.ID, itemID)
+		if err != nil {
+			return xerrors.Wrap(err, xerrors.CodeInternalError, "查询物品库存失败")
+		}
+		existing[itemID] = count
+	}
+	if err := checkWarehouseCapacity(distinct, existing, req.Items, maxSlots, maxStack); err != nil {
+		// 先释放仓库行锁，失败日志在事务外写入
+		_ = tx.Rollback()
+		s.logDepositFailure(ctx, warehouse.ID, req, err)
+		return err
+	}
+
+	// 6. 扣除背包物品并加入仓库
+	for itemID, quantity := range req.Items {
+		itemCfg := configs[itemID]
+		stacks, err := s.playerItemRepo.ListBackpackStacksForUpdate(ctx, tx, req.HeroID, itemID)
+		if err != nil {
+			return xerrors.Wrap(err, xerrors.CodeInternalError, "查询背包物品失败")
+		}
+		depositable := filterDepositableStacks(stacks, itemCfg)
+		if available := countStackQuantity(depositable); available < quantity {
+			msg := fmt.Sprintf("背包中可捐献的 %s 不足：可捐献 %d，需要 %d", itemCfg.ItemName, available, quantity)
+			return xerrors.New(xerrors.CodeInsufficientResource, msg).WithMetadata("user_message", msg)
+		}
+		plan, err := planStackConsumption(depositable, quantity)
+		if err != nil {
+			return err
+		}
+		if err := applyStackConsumption(ctx, s.playerItemRepo, tx, plan); err != nil {
+			return err
+		}
+		if err := s.teamWarehouseItemRepo.AddItem(ctx, tx, warehouse.ID, itemID, itemCfg.ItemType, quantity, nil); err != nil {
+			return xerrors.Wrap(err, xerrors.CodeInternalError, "添加仓库物品失败")
+		}
+	}
+
+	// 7. 写入仓库日志
+	if err := s.logWarehouseEntry(ctx, tx, &interfaces.TeamWarehouseLootLog{
+		TeamID:      req.TeamID,
+		WarehouseID: warehouse.ID,
+		ItemsJSON:   warehouseLogItemsJSON(req.Items, configs),
+		Result:      "success",
+		EntryType:   interfaces.WarehouseLogEntryDeposit,
+		HeroID:      &req.HeroID,
+	}); err != nil {
+		return err
+	}
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, teamActivityEntry{
+		TeamID:      req.TeamID,
+		EventType:   interfaces.TeamActivityItemsDeposited,
+		ActorHeroID: req.HeroID,
+		Details:     map[string]interface{}{"items": req.Items},
+	}); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+	}
+
+	// 8. 提交事务
+	if err := tx.Commit(); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	return nil
+}
+
+// CreateWithdrawalRequest 取用申请请求
+type CreateWithdrawalRequest struct {
+	TeamID   string
+	HeroID   string
+	ItemID   string
+	Quantity int
+	Note     string
+}
+
+// CreateWithdrawalRequest 成员申请从仓库取用物品，等待拥有分配权限的成员审批
+func (s *TeamWarehouseService) CreateWithdrawalRequest(ctx context.Context, req *CreateWithdrawalRequest) (*interfaces.TeamWarehouseWithdrawal, error) {
+	// 1. 验证参数
+	if req.TeamID == "" || req.HeroID == "" || req.ItemID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+	if req.Quantity <= 0 {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "申请数量必须大于0")
+	}
+
+	// 2. 检查权限（团队成员即可）
+	if _, err := s.teamMemberRepo.GetByTeamAndHero(ctx, req.TeamID, req.HeroID); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
+	}
+
+	// 3. 获取仓库并检查库存
+	warehouse, err := s.teamWarehouseRepo.GetByTeamID(ctx, req.TeamID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "团队仓库不存在")
+	}
+	stock, reserved, err := s.availableStock(ctx, nil, warehouse.ID, req.ItemID, "")
+	if err != nil {
+		return nil, err
+	}
+	if stock-reserved < req.Quantity {
+		msg := fmt.Sprintf("仓库物品不足：现有 %d，竞拍中 %d，申请 %d", stock, reserved, req.Quantity)
+		return nil, xerrors.New(xerrors.CodeInvalidParams, msg).WithMetadata("user_message", msg)
+	}
+
+	// 4. 限制待审批申请数量
+	pending, err := s.withdrawalRepo.CountPendingByHero(ctx, req.TeamID, req.HeroID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "统计待审批申请失败")
+	}
+	if pending >= maxPendingWithdrawals {
+		msg := fmt.Sprintf("待审批的取用申请最多 %d 个，请等待审批或撤回后再申请", maxPendingWithdrawals)
+		return nil, xerrors.New(xerrors.CodeInvalidParams, msg).WithMetadata("user_message", msg)
+	}
+
+	// 5. 创建申请并写入仓库日志
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer tx.Rollback()
+
+	withdrawal := &interfaces.TeamWarehouseWithdrawal{
+		TeamID:      req.TeamID,
+		WarehouseID: warehouse.ID,
+		HeroID:      req.HeroID,
+		ItemID:      req.ItemID,
+		Quantity:    req.Quantity,
+		Status:      interfaces.WithdrawalStatusPending,
+	}
+	if req.Note != "" {
+		withdrawal.Note = &req.Note
+	}
+	if err := s.withdrawalRepo.Create(ctx, tx, withdrawal); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "创建取用申请失败")
+	}
+	if err := s.logWithdrawal(ctx, tx, withdrawal, warehouseLogResultPending, ""); err != nil {
+		return nil, err
+	}
+	if err := s.recordWithdrawalActivity(ctx, tx, withdrawal, interfaces.TeamActivityWithdrawalRequested, req.HeroID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	return withdrawal, nil
+}
+
+// ListWithdrawalRequests 查询取用申请：拥有分配权限的成员可查看全部，其他成员只能查看自己的
+func (s *TeamWarehouseService) ListWithdrawalRequests(ctx context.Context, teamID, heroID, status string, limit, offset int) ([]*interfaces.TeamWarehouseWithdrawal, int64, error) {
+	if teamID == "" || heroID == "" {
+		return nil, 0, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+	if status != "" && !isWithdrawalStatus(status) {
+		return nil, 0, xerrors.New(xerrors.CodeInvalidParams, "无效的申请状态")
+	}
+	member, err := s.teamMemberRepo.GetByTeamAndHero(ctx, teamID, heroID)
+	if err != nil {
+		return nil, 0, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
+	}
+
+	// 拥有分配权限的成员可查看全部申请，其他成员只看自己的
+	authority, err := loadMemberAuthority(ctx, s.teamRankRepo, member)
+	if err != nil {
+		return nil, 0, xerrors.Wrap(err, xerrors.CodeInternalError, "查询成员权限失败")
+	}
+	filterHeroID := heroID
+	if authority.Has(TeamPermDistributeLoot) {
+		filterHeroID = ""
+	}
+	list, total, err := s.withdrawalRepo.List(ctx, teamID, status, filterHeroID, limit, offset)
+	if err != nil {
+		return nil, 0, xerrors.Wrap(err, xerrors.CodeInternalError, "查询取用申请失败")
+	}
+	return list, total, nil
+}
+
+// ReviewWithdrawalRequest 审批取用申请请求
+type ReviewWithdrawalRequest struct {
+	TeamID     string
+	RequestID  string
+	ReviewerID string // 审批人英雄ID
+	Approve    bool
+	Note       string
+}
+
+// ReviewWithdrawalRequest 拥有分配权限的成员审批取用申请，批准时在同一事务内扣除仓库物品并发放到申请人背包
+func (s *TeamWarehouseService) ReviewWithdrawalRequest(ctx context.Context, req *ReviewWithdrawalRequest) (*interfaces.TeamWarehouseWithdrawal, error) {
+	// 1. 验证参数
+	if req.TeamID == "" || req.RequestID == "" || req.ReviewerID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+
+	// 2. 检查权限
+	reviewer, err := s.teamMemberRepo.GetByTeamAndHero(ctx, req.TeamID, req.ReviewerID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
+	}
+	if _, err := requireTeamPermission(ctx, s.teamRankRepo, reviewer, TeamPermDistributeLoot, "只有队长和管理员可以审批取用申请"); err != nil {
+		return nil, err
+	}
+
+	// 3. 获取仓库
+	warehouse, err := s.teamWarehouseRepo.GetByTeamID(ctx, req.TeamID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "团队仓库不存在")
+	}
+
+	// 4. 开启事务并锁定申请
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer tx.Rollback()
+
+	withdrawal, err := s.lockPendingWithdrawal(ctx, tx, req.TeamID, req.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	if withdrawal.HeroID == req.ReviewerID {
+		return nil, xerrors.New(xerrors.CodePermissionDenied, "不能审批自己的取用申请")
+	}
+
+	now := time.Now()
+	withdrawal.ReviewerHeroID = &req.ReviewerID
+	withdrawal.ReviewedAt = &now
+	if req.Note != "" {
+		withdrawal.ReviewNote = &req.Note
+	}
+
+	// 5. 拒绝：仅更新状态
+	if !req.Approve {
+		withdrawal.Status = interfaces.WithdrawalStatusDenied
+		if err := s.withdrawalRepo.UpdateReview(ctx, tx, withdrawal); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新取用申请失败")
+		}
+		if err := s.logWithdrawal(ctx, tx, withdrawal, warehouseLogResultDenied, req.Note); err != nil {
+			return nil, err
+		}
+		if err := s.recordWithdrawalActivity(ctx, tx, withdrawal, interfaces.TeamActivityWithdrawalRejected, req.ReviewerID); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+		}
+		return withdrawal, nil
+	}
+
+	// 6. 批准：申请人需仍在团队中，且库存充足
+	if _, err := s.teamMemberRepo.GetByTeamAndHero(ctx, req.TeamID, withdrawal.HeroID); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInvalidParams, "申请人已不是团队成员")
+	}
+	stock, reserved, err := s.availableStock(ctx, tx, warehouse.ID, withdrawal.ItemID, "")
+	if err != nil {
+		return nil, err
+	}
+	if stock-reserved < withdrawal.Quantity {
+		msg := fmt.Sprintf("仓库物品不足：现有 %d，竞拍中 %d，申请 %d", stock, reserved, withdrawal.Quantity)
+		return nil, xerrors.New(xerrors.CodeInvalidParams, msg).WithMetadata("user_message", msg)
+	}
+
+	// 7. 扣除仓库物品并发放到申请人背包
+	distribution := &DistributeItemsRequest{
+		TeamID:        req.TeamID,
+		DistributorID: req.ReviewerID,
+		Distributions: map[string]map[string]int{withdrawal.HeroID: {withdrawal.ItemID: withdrawal.Quantity}},
+		Policy:        interfaces.LootPolicyWithdrawal,
+		Notes:         map[string]string{withdrawal.HeroID: "取用申请 " + withdrawal.ID},
+	}
+	if err := s.distributeItemsTx(ctx, tx, warehouse.ID, map[string]int{withdrawal.ItemID: withdrawal.Quantity}, distribution); err != nil {
+		tx.Rollback()
+		_ = s.logWithdrawal(ctx, nil, withdrawal, "failed", err.Error())
+		return nil, err
+	}
+
+	// 8. 更新申请状态并写入仓库日志
+	withdrawal.Status = interfaces.WithdrawalStatusApproved
+	if err := s.withdrawalRepo.UpdateReview(ctx, tx, withdrawal); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新取用申请失败")
+	}
+	if err := s.logWithdrawal(ctx, tx, withdrawal, "success", req.Note); err != nil {
+		return nil, err
+	}
+	if err := s.recordWithdrawalActivity(ctx, tx, withdrawal, interfaces.TeamActivityWithdrawalApproved, req.ReviewerID); err != nil {
+		return nil, err
+	}
+
+	// 9. 提交事务
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+
+	event := &DistributionEvent{
+		TeamID:      req.TeamID,
+		WarehouseID: warehouse.ID,
+		Distributor: req.ReviewerID,
+		ItemPayload: distribution.Distributions,
+		Result:      "success",
+	}
+	_ = notify.PublishWarehouseEvent(ctx, notify.SubjectWarehouseDistributed, event)
+
+	return withdrawal, nil
+}
+
+// CancelWithdrawalRequest 申请人撤回待审批的取用申请
+func (s *TeamWarehouseService) CancelWithdrawalRequest(ctx context.Context, teamID, requestID, heroID string) (*interfaces.TeamWarehouseWithdrawal, error) {
+	if teamID == "" || requestID == "" || heroID == "" {
+		return nil, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer tx.Rollback()
+
+	withdrawal, err := s.lockPendingWithdrawal(ctx, tx, teamID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if withdrawal.HeroID != heroID {
+		return nil, xerrors.New(xerrors.CodePermissionDenied, "只能撤回自己的取用申请")
+	}
+
+	now := time.Now()
+	withdrawal.Status = interfaces.WithdrawalStatusCancelled
+	withdrawal.ReviewedAt = &now
+	if err := s.withdrawalRepo.UpdateReview(ctx, tx, withdrawal); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "更新取用申请失败")
+	}
+	if err := s.logWithdrawal(ctx, tx, withdrawal, warehouseLogResultCancelled, ""); err != nil {
+		return nil, err
+	}
+	if err := s.recordWithdrawalActivity(ctx, tx, withdrawal, interfaces.TeamActivityWithdrawalCancelled, heroID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+	return withdrawal, nil
+}
+
+// lockPendingWithdrawal 锁定团队内待审批的申请
+func (s *TeamWarehouseService) lockPendingWithdrawal(ctx context.Context, tx *sql.Tx, teamID, requestID string) (*interfaces.TeamWarehouseWithdrawal, error) {
+	withdrawal, err := s.withdrawalRepo.GetForUpdate(ctx, tx, requestID)
+	if err != nil {
+		return nil, xerrors.Wrap(err, xerrors.CodeInternalError, "查询取用申请失败")
+	}
+	if withdrawal == nil || withdrawal.TeamID != teamID {
+		return nil, xerrors.New(xerrors.CodeResourceNotFound, "取用申请不存在")
+	}
+	if withdrawal.Status != interfaces.WithdrawalStatusPending {
+		msg := "该取用申请已处理"
+		return nil, xerrors.New(xerrors.CodeInvalidParams, msg).WithMetadata("user_message", msg)
+	}
+	return withdrawal, nil
+}
+
+// getLocationCapacity 读取位置的槽位与堆叠上限，未配置时使用仓库兜底值
+func (s *TeamWarehouseService) getLocationCapacity(ctx context.Context, location string) (int64, int64, error) {
+	var maxSlots, maxStack int64
+	err := s.db.QueryRowContext(ctx, `SELECT max_slots, max_stack FROM game_config.inventory_capacities WHERE location = $1`, location).Scan(&maxSlots, &maxStack)
+	if err == sql.ErrNoRows {
+		return defaultWarehouseMaxSlots, defaultWarehouseMaxStack, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	return maxSlots, maxStack, nil
+}
+
+// logWarehouseEntry 写入仓库日志，execer 为事务时日志与业务同时提交
+func (s *TeamWarehouseService) logWarehouseEntry(ctx context.Context, execer boil.ContextExecutor, entry *interfaces.TeamWarehouseLootLog) error {
+	if s.lootLogRepo == nil {
+		return nil
+	}
+	if err := s.lootLogRepo.Log(ctx, execer, entry); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "写入仓库日志失败")
+	}
+	return nil
+}
+
+// logDepositFailure 记录被拒绝的捐献（最佳努力）
+func (s *TeamWarehouseService) logDepositFailure(ctx context.Context, warehouseID string, req *DepositItemsRequest, cause error) {
+	reason := cause.Error()
+	_ = s.logWarehouseEntry(ctx, nil, &interfaces.TeamWarehouseLootLog{
+		TeamID:      req.TeamID,
+		WarehouseID: warehouseID,
+		ItemsJSON:   warehouseLogItemsJSON(req.Items, nil),
+		Result:      "failed",
+		Reason:      &reason,
+		EntryType:   interfaces.WarehouseLogEntryDeposit,
+		HeroID:      &req.HeroID,
+	})
+}
+
+// logWithdrawal 记录取用申请的状态变化
+func (s *TeamWarehouseService) logWithdrawal(ctx context.Context, execer boil.ContextExecutor, withdrawal *interfaces.TeamWarehouseWithdrawal, result, reason string) error {
+	entry := &interfaces.TeamWarehouseLootLog{
+		TeamID:      withdrawal.TeamID,
+		WarehouseID: withdrawal.WarehouseID,
+		ItemsJSON:   warehouseLogItemsJSON(map[string]int{withdrawal.ItemID: withdrawal.Quantity}, nil),
+		Result:      result,
+		EntryType:   interfaces.WarehouseLogEntryWithdrawal,
+		HeroID:      &withdrawal.HeroID,
+		RequestID:   &withdrawal.ID,
+	}
+	if reason != "" {
+		entry.Reason = &reason
+	}
+	return s.logWarehouseEntry(ctx, execer, entry)
+}
+
+// recordWithdrawalActivity 在事务内记录取用申请的团队动态，受影响者为申请人
+func (s *TeamWarehouseService) recordWithdrawalActivity(ctx context.Context, tx *sql.Tx, withdrawal *interfaces.TeamWarehouseWithdrawal, eventType, actorHeroID string) error {
+	details := map[string]interface{}{"item_id": withdrawal.ItemID, "quantity": withdrawal.Quantity}
+	if withdrawal.ReviewNote != nil {
+		details["review_note"] = *withdrawal.ReviewNote
+	}
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, teamActivityEntry{
+		TeamID:       withdrawal.TeamID,
+		EventType:    eventType,
+		ActorHeroID:  actorHeroID,
+		TargetHeroID: withdrawal.HeroID,
+		SourceID:     withdrawal.ID,
+		Details:      details,
+	}); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+	}
+	return nil
+}
+
+// checkWarehouseCapacity 校验捐献后仓库的物品种类数与单种堆叠上限
+func checkWarehouseCapacity(distinct int64, existing, deposits map[string]int, maxSlots, maxStack int64) error {
+	newTypes := 0
+	for itemID, quantity := range deposits {
+		current := existing[itemID]
+		if current == 0 {
+			newTypes++
+		}
+		if int64(current+quantity) > maxStack {
+			msg := fmt.Sprintf("仓库物品堆叠超限：%s 现有 %d，捐献 %d，上限 %d", itemID, current, quantity, maxStack)
+			return xerrors.New(xerrors.CodeInvalidParams, msg).WithMetadata("user_message", msg)
+		}
+	}
+	if distinct+int64(newTypes) > maxSlots {
+		msg := fmt.Sprintf("仓库已满：已有 %d 种，本次新增 %d 种会超出 %d 上限", distinct, newTypes, maxSlots)
+		return xerrors.New(xerrors.CodeInvalidParams, msg).WithMetadata("user_message", msg)
+	}
+	return nil
+}
+
+// filterDepositableStacks 过滤出可捐献的背包物品实例
+// 仓库只记录物品配置，带有实例状态（绑定、强化、镶嵌、耐久损耗）的物品存入后会丢失状态
+func filterDepositableStacks(stacks []*game_runtime.PlayerItem, itemCfg *game_config.Item) []*game_runtime.PlayerItem {
+	var result []*game_runtime.PlayerItem
+	for _, stack := range stacks {
+		if stack.IsBound.Valid && stack.IsBound.Bool {
+			continue
+		}
+		if stack.EnhancementLevel.Valid && stack.EnhancementLevel.Int16 > 0 {
+			continue
+		}
+		if hasSocketedGems(stack) {
+			continue
+		}
+		if stack.CurrentDurability.Valid {
+			maxDurability := 0
+			if stack.MaxDurabilityOverride.Valid {
+				maxDurability = stack.MaxDurabilityOverride.Int
+			} else if itemCfg != nil && itemCfg.MaxDurability.Valid {
+				maxDurability = itemCfg.MaxDurability.Int
+			}
+			if stack.CurrentDurability.Int < maxDurability {
+				continue
+			}
+		}
+		result = append(result, stack)
+	}
+	return result
+}
+
+func hasSocketedGems(item *game_runtime.PlayerItem) bool {
+	if !item.SocketedGems.Valid || len(item.SocketedGems.JSON) == 0 {
+		return false
+	}
+	var gems []json.RawMessage
+	if err := json.Unmarshal(item.SocketedGems.JSON, &gems); err != nil {
+		// 无法解析时按已镶嵌处理，避免丢失宝石
+		return true
+	}
+	return len(gems) > 0
+}
+
+// countStackQuantity 统计堆叠总数量（未设置堆叠数视为 1）
+func countStackQuantity(stacks []*game_runtime.PlayerItem) int {
+	total := 0
+	for _, stack := range stacks {
+		if stack.StackCount.Valid {
+			total += stack.StackCount.Int
+		} else {
+			total++
+		}
+	}
+	return total
+}
+
+// warehouseLogItemsJSON 生成日志物品明细，按物品ID排序
+func warehouseLogItemsJSON(items map[string]int, configs map[string]*game_config.Item) string {
+	list := make([]LootItem, 0, len(items))
+	for itemID, quantity := range items {
+		item := LootItem{ItemID: itemID, Quantity: quantity}
+		if cfg, ok := configs[itemID]; ok {
+			item.ItemType = cfg.ItemType
+		}
+		list = append(list, item)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ItemID < list[j].ItemID })
+	raw, err := json.Marshal(list)
+	if err != nil {
+		return "[]"
+	}
+	return string(raw)
+}
+
+func isWithdrawalStatus(status string) bool {
+	switch status {
+	case interfaces.WithdrawalStatusPending, interfaces.WithdrawalStatusApproved,
+		interfaces.WithdrawalStatusDenied, interfaces.WithdrawalStatusCancelled:
+		return true
+	}
+	return false
+}
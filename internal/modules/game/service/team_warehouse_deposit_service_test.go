@@ -0,0 +1,55 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/aarondl/null/v8"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/repository/interfaces"
+)
+
+func TestCheckWarehouseCapacity(t *testing.T) {
+	existing := map[string]int{"potion": 990}
+
+	require.NoError(t, checkWarehouseCapacity(2, existing, map[string]int{"potion": 9}, 3, 999))
+	require.NoError(t, checkWarehouseCapacity(2, existing, map[string]int{"ore": 5}, 3, 999))
+
+	require.Error(t, checkWarehouseCapacity(2, existing, map[string]int{"potion": 10}, 3, 999), "超出堆叠上限")
+	require.Error(t, checkWarehouseCapacity(3, existing, map[string]int{"ore": 1}, 3, 999), "新增种类超出槽位")
+	require.NoError(t, checkWarehouseCapacity(3, existing, map[string]int{"potion": 1}, 3, 999), "已有物品不占新槽位")
+}
+
+func TestFilterDepositableStacks(t *testing.T) {
+	cfg := &game_config.Item{MaxDurability: null.IntFrom(100)}
+	stacks := []*game_runtime.PlayerItem{
+		{ID: "plain", StackCount: null.IntFrom(3)},
+		{ID: "bound", IsBound: null.BoolFrom(true)},
+		{ID: "enhanced", EnhancementLevel: null.Int16From(2)},
+		{ID: "socketed", SocketedGems: null.JSONFrom([]byte(`[{"socket_index":0,"gem_item_id":"g"}]`))},
+		{ID: "empty-sockets", SocketedGems: null.JSONFrom([]byte(`[]`))},
+		{ID: "worn", CurrentDurability: null.IntFrom(60)},
+		{ID: "full", CurrentDurability: null.IntFrom(100)},
+		{ID: "override", CurrentDurability: null.IntFrom(80), MaxDurabilityOverride: null.IntFrom(80)},
+	}
+
+	var ids []string
+	for _, stack := range filterDepositableStacks(stacks, cfg) {
+		ids = append(ids, stack.ID)
+	}
+	require.Equal(t, []string{"plain", "empty-sockets", "full", "override"}, ids)
+	require.Equal(t, 6, countStackQuantity(filterDepositableStacks(stacks, cfg)), "未设置堆叠数视为 1")
+}
+
+func TestWarehouseLogItemsJSON(t *testing.T) {
+	configs := map[string]*game_config.Item{"b": {ItemType: "material"}}
+	require.Equal(t,
+		`[{"ItemID":"a","ItemType":"","Quantity":2},{"ItemID":"b","ItemType":"material","Quantity":1}]`,
+		warehouseLogItemsJSON(map[string]int{"b": 1, "a": 2}, configs))
+	require.Equal(t, "[]", warehouseLogItemsJSON(nil, nil))
+
+	require.True(t, isWithdrawalStatus(interfaces.WithdrawalStatusPending))
+	require.False(t, isWithdrawalStatus("open"))
+}
@@ -23,9 +23,14 @@ type TeamWarehouseService struct {
 	teamMemberRepo        interfaces.TeamMemberRepository
 	teamWarehouseRepo     interfaces.TeamWarehouseRepository
 	teamWarehouseItemRepo interfaces.TeamWarehouseItemRepository
-	heroWalletRepo        interfaces.HeroWalletRepository
+	heroCurrencyRepo      interfaces.HeroCurrencyRepository
 	lootHistoryRepo       interfaces.TeamLootHistoryRepository
 	lootLogRepo           interfaces.TeamWarehouseLootLogRepository
+	lootPolicyRepo        interfaces.TeamLootPolicyRepository   // 可选，未配置时入库后不自动分配
+	contributionRepo      interfaces.TeamContributionRepository // 可选，未配置时分配不扣除竞拍中的物品
+	withdrawalRepo        interfaces.TeamWarehouseWithdrawalRepository
+	teamRankRepo          interfaces.TeamRankRepository     // 可选，未配置时按角色默认权限
+	teamActivityRepo      interfaces.TeamActivityRepository // 可选，未配置时不记录团队动态
 	itemRepo              interfaces.ItemRepository
 	heroRepo              interfaces.HeroRepository
 	playerItemRepo        interfaces.PlayerItemRepository
@@ -38,9 +43,14 @@ func NewTeamWarehouseService(db *sql.DB) *TeamWarehouseService {
 		teamMemberRepo:        impl.NewTeamMemberRepository(db),
 		teamWarehouseRepo:     impl.NewTeamWarehouseRepository(db),
 		teamWarehouseItemRepo: impl.NewTeamWarehouseItemRepository(db),
-		heroWalletRepo:        impl.NewHeroWalletRepository(db),
+		heroCurrencyRepo:      impl.NewHeroCurrencyRepository(db),
 		lootHistoryRepo:       impl.NewTeamLootHistoryRepository(db),
 		lootLogRepo:           impl.NewTeamWarehouseLootLogRepository(db),
+		lootPolicyRepo:        impl.NewTeamLootPolicyRepository(db),
+		contributionRepo:      impl.NewTeamContributionRepository(db),
+		withdrawalRepo:        impl.NewTeamWarehouseWithdrawalRepository(db),
+		teamRankRepo:          impl.NewTeamRankRepository(db),
+		teamActivityRepo:      impl.NewTeamActivityRepository(db),
 		itemRepo:              impl.NewItemRepository(db),
 		heroRepo:              impl.NewHeroRepository(db),
 		playerItemRepo:        impl.NewPlayerItemRepository(db),
@@ -71,8 +81,10 @@ func (s *TeamWarehouseService) GetWarehouse(ctx context.Context, teamID, heroID
 // DistributeGoldRequest 分配金币请求
 type DistributeGoldRequest struct {
 	TeamID        string
-	DistributorID string           // 分配者英雄ID
-	Distributions map[string]int64 // 接收者英雄ID -> 金币数量
+	DistributorID string            // 分配者英雄ID
+	Distributions map[string]int64  // 接收者英雄ID -> 金币数量
+	Policy        string            // 分配方式，为空视为手动
+	Notes         map[string]string // 接收者英雄ID -> 决策说明（可选）
 }
 
 // DistributeGold 分配金币
@@ -85,13 +97,13 @@ func (s *TeamWarehouseService) DistributeGold(ctx context.Context, req *Distribu
 		return xerrors.New(xerrors.CodeInvalidParams, "分配列表不能为空")
 	}
 
-	// 2. 检查权限（队长或管理员）
+	// 2. 检查权限
 	distributor, err := s.teamMemberRepo.GetByTeamAndHero(ctx, req.TeamID, req.DistributorID)
 	if err != nil {
 		return xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
 	}
-	if distributor.Role != "leader" && distributor.Role != "admin" {
-		return xerrors.New(xerrors.CodePermissionDenied, "只有队长和管理员可以分配金币")
+	if _, err := requireTeamPermission(ctx, s.teamRankRepo, distributor, TeamPermDistributeLoot, "只有队长和管理员可以分配金币"); err != nil {
+		return err
 	}
 
 	// 3. 获取仓库
@@ -137,8 +149,15 @@ func (s *TeamWarehouseService) DistributeGold(ctx context.Context, req *Distribu
 
 	// 8. 分配金币给成员（写入英雄钱包）并记录分配历史
 	for heroID, amount := range req.Distributions {
-		if err := s.heroWalletRepo.AddGoldTx(ctx, tx, heroID, amount); err != nil {
-			return xerrors.Wrap(err, xerrors.CodeInternalError, "发放金币到英雄钱包失败")
+		if _, err := applyCurrencyTx(ctx, s.heroCurrencyRepo, tx, CurrencyChange{
+			HeroID:     heroID,
+			Currency:   interfaces.CurrencyGold,
+			Amount:     amount,
+			SourceType: CurrencySourceWarehouse,
+			SourceID:   warehouse.ID,
+			Metadata:   map[string]interface{}{"team_id": req.TeamID, "distributor_hero_id": req.DistributorID},
+		}); err != nil {
+			return err
 		}
 		if s.lootHistoryRepo != nil {
 			if err := s.lootHistoryRepo.CreateDistribution(ctx, tx, &interfaces.TeamLootHistoryCreateReq{
@@ -148,10 +167,21 @@ func (s *TeamWarehouseService) DistributeGold(ctx context.Context, req *Distribu
 				RecipientHeroID:   heroID,
 				ItemType:          "gold",
 				Quantity:          amount,
+				Policy:            req.Policy,
+				DecisionNote:      distributionNote(req.Notes, heroID),
 			}); err != nil {
 				return xerrors.Wrap(err, xerrors.CodeInternalError, "写入分配历史失败")
 			}
 		}
+		if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, teamActivityEntry{
+			TeamID:       req.TeamID,
+			EventType:    interfaces.TeamActivityGoldDistributed,
+			ActorHeroID:  req.DistributorID,
+			TargetHeroID: heroID,
+			Details:      map[string]interface{}{"amount": amount, "policy": distributionPolicy(req.Policy)},
+		}); err != nil {
+			return xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+		}
 	}
 
 	// 10. 提交事务
@@ -177,6 +207,10 @@ type DistributeItemsRequest struct {
 	TeamID        string
 	DistributorID string                    // 分配者英雄ID
 	Distributions map[string]map[string]int // 接收者英雄ID -> (物品ID -> 数量)
+	Policy        string                    // 分配方式，为空视为手动
+	Notes         map[string]string         // 接收者英雄ID -> 决策说明（可选）
+
+	auctionID string // 发放竞拍物品时为该竞拍ID，库存校验不扣除其自身的预留
 }
 
 // DistributeItems 分配物品
@@ -189,13 +223,13 @@ func (s *TeamWarehouseService) DistributeItems(ctx context.Context, req *Distrib
 		return xerrors.New(xerrors.CodeInvalidParams, "分配列表不能为空")
 	}
 
-	// 2. 检查权限（队长或管理员）
+	// 2. 检查权限
 	distributor, err := s.teamMemberRepo.GetByTeamAndHero(ctx, req.TeamID, req.DistributorID)
 	if err != nil {
 		return xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
 	}
-	if distributor.Role != "leader" && distributor.Role != "admin" {
-		return xerrors.New(xerrors.CodePermissionDenied, "只有队长和管理员可以分配物品")
+	if _, err := requireTeamPermission(ctx, s.teamRankRepo, distributor, TeamPermDistributeLoot, "只有队长和管理员可以分配物品"); err != nil {
+		return err
 	}
 
 	// 3. 获取仓库
@@ -242,14 +276,66 @@ func (s *TeamWarehouseService) DistributeItems(ctx context.Context, req *Distrib
 	}
 	defer tx.Rollback()
 
-	// 7. 扣除仓库物品
+	// 7. 扣除仓库物品并发放到成员背包
+	if err := s.distributeItemsTx(ctx, tx, warehouse.ID, itemTotals, req); err != nil {
+		return err
+	}
+
+	// 8. 按接收者记录团队动态
+	for heroID, items := range req.Distributions {
+		if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, teamActivityEntry{
+			TeamID:       req.TeamID,
+			EventType:    interfaces.TeamActivityItemsDistributed,
+			ActorHeroID:  req.DistributorID,
+			TargetHeroID: heroID,
+			Details:      map[string]interface{}{"items": items, "policy": distributionPolicy(req.Policy)},
+		}); err != nil {
+			return xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
+		}
+	}
+
+	// 9. 提交事务
+	if err := tx.Commit(); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+
+	event := &DistributionEvent{
+		TeamID:      req.TeamID,
+		WarehouseID: warehouse.ID,
+		Distributor: req.DistributorID,
+		ItemPayload: req.Distributions,
+		Result:      "success",
+	}
+	_ = notify.PublishWarehouseEvent(ctx, notify.SubjectWarehouseDistributed, event)
+
+	return nil
+}
+
+// distributeItemsTx 在事务内扣除仓库物品并发放到接收者背包，同时写入分配历史
+func (s *TeamWarehouseService) distributeItemsTx(ctx context.Context, tx *sql.Tx, warehouseID string, itemTotals map[string]int, req *DistributeItemsRequest) error {
+	// 0. 锁定仓库后校验可分配数量（竞拍中的物品已被预留），与上架竞拍串行
+	if _, err := s.teamWarehouseRepo.GetByTeamIDForUpdate(ctx, tx, req.TeamID); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeResourceNotFound, "团队仓库不存在")
+	}
 	for itemID, totalQuantity := range itemTotals {
-		if err := s.teamWarehouseItemRepo.DeductItem(ctx, tx, warehouse.ID, itemID, totalQuantity); err != nil {
+		stock, reserved, err := s.availableStock(ctx, tx, warehouseID, itemID, req.auctionID)
+		if err != nil {
+			return err
+		}
+		if stock-reserved < totalQuantity {
+			msg := fmt.Sprintf("仓库物品不足：%s 现有 %d，竞拍中 %d，需要 %d", itemID, stock, reserved, totalQuantity)
+			return xerrors.New(xerrors.CodeInvalidParams, msg).WithMetadata("user_message", msg)
+		}
+	}
+
+	// 1. 扣除仓库物品
+	for itemID, totalQuantity := range itemTotals {
+		if err := s.teamWarehouseItemRepo.DeductItem(ctx, tx, warehouseID, itemID, totalQuantity); err != nil {
 			return xerrors.Wrap(err, xerrors.CodeInternalError, "扣除仓库物品失败")
 		}
 	}
 
-	// 8. 分配物品给成员（发放到英雄背包）
+	// 2. 分配物品给成员（发放到英雄背包）
 	// 简单容量校验：按背包槽位数量检查
 	maxSlots, err := s.getLocationMaxSlots(ctx, "backpack")
 	if err != nil {
@@ -302,12 +388,14 @@ func (s *TeamWarehouseService) DistributeItems(ctx context.Context, req *Distrib
 			if s.lootHistoryRepo != nil {
 				if err := s.lootHistoryRepo.CreateDistribution(ctx, tx, &interfaces.TeamLootHistoryCreateReq{
 					TeamID:            req.TeamID,
-					WarehouseID:       warehouse.ID,
+					WarehouseID:       warehouseID,
 					DistributorHeroID: req.DistributorID,
 					RecipientHeroID:   heroID,
 					ItemType:          "item",
 					ItemID:            &itemID,
 					Quantity:          int64(qty),
+					Policy:            req.Policy,
+					DecisionNote:      distributionNote(req.Notes, heroID),
 				}); err != nil {
 					return xerrors.Wrap(err, xerrors.CodeInternalError, "写入物品分配历史失败")
 				}
@@ -315,21 +403,23 @@ func (s *TeamWarehouseService) DistributeItems(ctx context.Context, req *Distrib
 		}
 	}
 
-	// 10. 提交事务
-	if err := tx.Commit(); err != nil {
-		return xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
-	}
+	return nil
+}
 
-	event := &DistributionEvent{
-		TeamID:      req.TeamID,
-		WarehouseID: warehouse.ID,
-		Distributor: req.DistributorID,
-		ItemPayload: req.Distributions,
-		Result:      "success",
+// availableStock 查询物品库存与正在竞拍预留的数量；excludeAuctionID 非空时不计该竞拍的预留
+func (s *TeamWarehouseService) availableStock(ctx context.Context, execer boil.ContextExecutor, warehouseID, itemID, excludeAuctionID string) (int, int, error) {
+	stock, err := s.teamWarehouseItemRepo.GetItemCount(ctx, warehouseID, itemID)
+	if err != nil {
+		return 0, 0, xerrors.Wrap(err, xerrors.CodeInternalError, "查询物品库存失败")
 	}
-	_ = notify.PublishWarehouseEvent(ctx, notify.SubjectWarehouseDistributed, event)
-
-	return nil
+	if s.contributionRepo == nil {
+		return stock, 0, nil
+	}
+	reserved, err := s.contributionRepo.SumOpenAuctionQuantity(ctx, execer, warehouseID, itemID, excludeAuctionID)
+	if err != nil {
+		return 0, 0, xerrors.Wrap(err, xerrors.CodeInternalError, "查询竞拍中物品失败")
+	}
+	return stock, reserved, nil
 }
 
 // AddLootToWarehouseRequest 战利品入库请求
@@ -361,73 +451,117 @@ func (s *TeamWarehouseService) AddLootToWarehouse(ctx context.Context, req *AddL
 	}
 
 	// 3. 检查仓库容量
-	currentItemCount, err := s.teamWarehouseItemRepo.CountDistinctItems(ctx, warehouse.ID)
+	reason, err := s.checkLootCapacity(ctx, warehouse.ID, req)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		fmt.Printf("[AddLoot] rejected: %s\n", reason)
+		_ = s.logLoot(ctx, warehouse.ID, req, "failed", reason)
+		return xerrors.New(xerrors.CodeInvalidParams, reason).WithMetadata("user_message", reason)
+	}
+
+	// 4. 开启事务
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return xerrors.Wrap(err, xerrors.CodeInternalError, "统计仓库物品种类失败")
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+	}
+	defer tx.Rollback()
+
+	// 5. 写入金币、物品与团队动态
+	if err := s.storeLootTx(ctx, tx, warehouse.ID, req); err != nil {
+		return err
+	}
+
+	// 6. 提交事务
+	if err := tx.Commit(); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	}
+
+	s.FinishLootStored(ctx, warehouse.ID, req)
+	return nil
+}
+
+// AddLootToWarehouseTx 在调用方事务内入库战利品，返回仓库ID；
+// 容量不足时返回错误由调用方整体回滚，提交后需调用 FinishLootStored
+func (s *TeamWarehouseService) AddLootToWarehouseTx(ctx context.Context, tx *sql.Tx, req *AddLootToWarehouseRequest) (string, error) {
+	if req.TeamID == "" {
+		return "", xerrors.New(xerrors.CodeInvalidParams, "团队ID不能为空")
+	}
+	warehouse, err := s.teamWarehouseRepo.GetByTeamID(ctx, req.TeamID)
+	if err != nil {
+		return "", xerrors.Wrap(err, xerrors.CodeResourceNotFound, "团队仓库不存在")
+	}
+	reason, err := s.checkLootCapacity(ctx, warehouse.ID, req)
+	if err != nil {
+		return "", err
+	}
+	if reason != "" {
+		return "", xerrors.New(xerrors.CodeInvalidParams, reason).WithMetadata("user_message", reason)
+	}
+	if err := s.storeLootTx(ctx, tx, warehouse.ID, req); err != nil {
+		return "", err
+	}
+	return warehouse.ID, nil
+}
+
+// FinishLootStored 入库事务提交后：写审计日志、推送通知，并按团队分配策略自动分配（失败时战利品留在仓库，可手动分配）
+func (s *TeamWarehouseService) FinishLootStored(ctx context.Context, warehouseID string, req *AddLootToWarehouseRequest) {
+	_ = s.logLoot(ctx, warehouseID, req, "success", "")
+	s.notifyLoot(ctx, warehouseID, req, "success", "")
+	s.applyLootPolicy(ctx, req)
+}
+
+// checkLootCapacity 检查仓库种类与堆叠上限，返回拒绝原因（为空表示可以入库）
+func (s *TeamWarehouseService) checkLootCapacity(ctx context.Context, warehouseID string, req *AddLootToWarehouseRequest) (string, error) {
+	currentItemCount, err := s.teamWarehouseItemRepo.CountDistinctItems(ctx, warehouseID)
+	if err != nil {
+		return "", xerrors.Wrap(err, xerrors.CodeInternalError, "统计仓库物品种类失败")
 	}
 
 	// 已满直接拒绝
 	if currentItemCount >= 100 {
-		msg := fmt.Sprintf("仓库已满：当前已有 %d 种，最多 100 种，请先分配/清理", currentItemCount)
-		fmt.Printf("[AddLoot] full-existing: %s\n", msg)
-		_ = s.logLoot(ctx, warehouse.ID, req, "failed", msg)
-		return xerrors.New(xerrors.CodeInvalidParams, msg).WithMetadata("user_message", msg)
+		return fmt.Sprintf("仓库已满：当前已有 %d 种，最多 100 种，请先分配/清理", currentItemCount), nil
 	}
 
-	// 计算新增物品种类数
+	// 计算新增物品种类数，并检查堆叠上限
 	newItemTypes := make(map[string]bool)
 	for _, item := range req.Items {
-		// 检查是否已存在
-		existingCount, _ := s.teamWarehouseItemRepo.GetItemCount(ctx, warehouse.ID, item.ItemID)
+		existingCount, _ := s.teamWarehouseItemRepo.GetItemCount(ctx, warehouseID, item.ItemID)
 		if existingCount == 0 {
 			newItemTypes[item.ItemID] = true
 		}
+		if existingCount+item.Quantity > 999 {
+			return "仓库物品堆叠超限", nil
+		}
 	}
 
 	if currentItemCount+int64(len(newItemTypes)) > 100 {
-		msg := fmt.Sprintf("仓库已满：已有 %d 种，本次新增 %d 种会超出 100 上限", currentItemCount, len(newItemTypes))
-		fmt.Printf("[AddLoot] full-new: %s\n", msg)
-		_ = s.logLoot(ctx, warehouse.ID, req, "failed", msg)
-		return xerrors.New(xerrors.CodeInvalidParams, msg).WithMetadata("user_message", msg)
-	}
-
-	// 4. 开启事务
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return xerrors.Wrap(err, xerrors.CodeInternalError, "开启事务失败")
+		return fmt.Sprintf("仓库已满：已有 %d 种，本次新增 %d 种会超出 100 上限", currentItemCount, len(newItemTypes)), nil
 	}
-	defer tx.Rollback()
+	return "", nil
+}
 
-	// 5. 添加金币
+// storeLootTx 在事务内写入金币、物品并记录团队动态
+func (s *TeamWarehouseService) storeLootTx(ctx context.Context, tx *sql.Tx, warehouseID string, req *AddLootToWarehouseRequest) error {
 	if req.Gold > 0 {
-		if err := s.teamWarehouseRepo.AddGold(ctx, tx, warehouse.ID, req.Gold); err != nil {
+		if err := s.teamWarehouseRepo.AddGold(ctx, tx, warehouseID, req.Gold); err != nil {
 			return xerrors.Wrap(err, xerrors.CodeInternalError, "添加金币失败")
 		}
 	}
-
-	// 6. 添加物品
 	for _, item := range req.Items {
-		// 检查堆叠上限
-		existingCount, _ := s.teamWarehouseItemRepo.GetItemCount(ctx, warehouse.ID, item.ItemID)
-		if existingCount+item.Quantity > 999 {
-			tx.Rollback()
-			_ = s.logLoot(ctx, warehouse.ID, req, "failed", "仓库物品堆叠超限")
-			return xerrors.New(xerrors.CodeInvalidParams, "仓库物品堆叠超限")
-		}
-
-		if err := s.teamWarehouseItemRepo.AddItem(ctx, tx, warehouse.ID, item.ItemID, item.ItemType, item.Quantity, &req.SourceDungeonID); err != nil {
+		if err := s.teamWarehouseItemRepo.AddItem(ctx, tx, warehouseID, item.ItemID, item.ItemType, item.Quantity, &req.SourceDungeonID); err != nil {
 			return xerrors.Wrap(err, xerrors.CodeInternalError, "添加物品失败")
 		}
 	}
-
-	// 7. 提交事务
-	if err := tx.Commit(); err != nil {
-		return xerrors.Wrap(err, xerrors.CodeInternalError, "提交事务失败")
+	if err := recordTeamActivity(ctx, s.teamActivityRepo, tx, teamActivityEntry{
+		TeamID:    req.TeamID,
+		EventType: interfaces.TeamActivityLootStored,
+		SourceID:  req.SourceDungeonID,
+		Details:   map[string]interface{}{"gold": req.Gold, "items": lootItemQuantities(req.Items)},
+	}); err != nil {
+		return xerrors.Wrap(err, xerrors.CodeInternalError, "记录团队动态失败")
 	}
-
-	_ = s.logLoot(ctx, warehouse.ID, req, "success", "")
-	s.notifyLoot(ctx, warehouse.ID, req, "success", "")
-
 	return nil
 }
 
@@ -463,13 +597,13 @@ func (s *TeamWarehouseService) GetDistributionHistory(ctx context.Context, teamI
 	if teamID == "" || heroID == "" {
 		return nil, 0, xerrors.New(xerrors.CodeInvalidParams, "参数不能为空")
 	}
-	// 权限：队长或管理员
+	// 权限：view_warehouse
 	member, err := s.teamMemberRepo.GetByTeamAndHero(ctx, teamID, heroID)
 	if err != nil {
 		return nil, 0, xerrors.Wrap(err, xerrors.CodeResourceNotFound, "您不是该团队成员")
 	}
-	if member.Role != "leader" && member.Role != "admin" {
-		return nil, 0, xerrors.New(xerrors.CodePermissionDenied, "只有队长和管理员可以查看分配历史")
+	if _, err := requireTeamPermission(ctx, s.teamRankRepo, member, TeamPermViewWarehouse, "只有队长和管理员可以查看分配历史"); err != nil {
+		return nil, 0, err
 	}
 	if s.lootHistoryRepo == nil {
 		return nil, 0, xerrors.New(xerrors.CodeInternalError, "分配历史存储未配置")
@@ -533,3 +667,20 @@ func (s *TeamWarehouseService) notifyLoot(ctx context.Context, warehouseID strin
 	}
 	_ = notify.PublishWarehouseEvent(ctx, notify.SubjectWarehouseLoot, event)
 }
+
+// distributionPolicy 分配方式，为空视为手动分配
+func distributionPolicy(policy string) string {
+	if policy == "" {
+		return interfaces.LootPolicyManual
+	}
+	return policy
+}
+
+// lootItemQuantities 汇总战利品物品数量（物品ID -> 数量）
+func lootItemQuantities(items []LootItem) map[string]int {
+	quantities := make(map[string]int, len(items))
+	for _, item := range items {
+		quantities[item.ItemID] += item.Quantity
+	}
+	return quantities
+}
@@ -0,0 +1,250 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"tsu-self/internal/pkg/log"
+	"tsu-self/internal/repository/impl"
+	"tsu-self/internal/repository/interfaces"
+)
+
+// ResetPeriod 重置周期
+type ResetPeriod string
+
+const (
+	ResetHourly ResetPeriod = "hourly" // 每小时整点
+	ResetDaily  ResetPeriod = "daily"  // 每天重置时刻
+	ResetWeekly ResetPeriod = "weekly" // 每周指定星期的重置时刻
+)
+
+// 默认重置时刻：东八区每天凌晨5点，每周一
+const (
+	defaultResetHour     = 5
+	defaultResetTimezone = "Asia/Shanghai"
+	defaultResetWeekday  = time.Monday
+)
+
+// ResetSchedule 重置时刻配置
+type ResetSchedule struct {
+	Hour     int            // 每日重置的小时（0-23）
+	Weekday  time.Weekday   // 每周重置的星期
+	Location *time.Location // 计算周期所用时区
+}
+
+// LoadResetSchedule 从环境变量读取重置配置（GAME_RESET_HOUR / GAME_RESET_TIMEZONE / GAME_RESET_WEEKDAY）
+// 配置非法时返回错误，同时返回使用默认值兜底的配置
+func LoadResetSchedule() (ResetSchedule, error) {
+	schedule := ResetSchedule{Hour: defaultResetHour, Weekday: defaultResetWeekday, Location: time.UTC}
+	if loc, err := time.LoadLocation(defaultResetTimezone); err == nil {
+		schedule.Location = loc
+	}
+
+	if v := os.Getenv("GAME_RESET_HOUR"); v != "" {
+		hour, err := strconv.Atoi(v)
+		if err != nil || hour < 0 || hour > 23 {
+			return schedule, fmt.Errorf("GAME_RESET_HOUR 非法: %s", v)
+		}
+		schedule.Hour = hour
+	}
+	if v := os.Getenv("GAME_RESET_WEEKDAY"); v != "" {
+		weekday, err := strconv.Atoi(v)
+		if err != nil || weekday < 0 || weekday > 6 {
+			return schedule, fmt.Errorf("GAME_RESET_WEEKDAY 非法（0=周日...6=周六）: %s", v)
+		}
+		schedule.Weekday = time.Weekday(weekday)
+	}
+	if v := os.Getenv("GAME_RESET_TIMEZONE"); v != "" {
+		loc, err := time.LoadLocation(v)
+		if err != nil {
+			return schedule, fmt.Errorf("GAME_RESET_TIMEZONE 非法: %s", v)
+		}
+		schedule.Location = loc
+	}
+	return schedule, nil
+}
+
+// PeriodStart 计算 now 所在周期的起点
+func (s ResetSchedule) PeriodStart(period ResetPeriod, now time.Time) time.Time {
+	loc := s.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+
+	if period == ResetHourly {
+		return time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, loc)
+	}
+
+	start := time.Date(local.Year(), local.Month(), local.Day(), s.Hour, 0, 0, 0, loc)
+	if local.Before(start) {
+		start = time.Date(local.Year(), local.Month(), local.Day()-1, s.Hour, 0, 0, 0, loc)
+	}
+	if period == ResetWeekly {
+		back := (int(start.Weekday()) - int(s.Weekday) + 7) % 7
+		start = time.Date(start.Year(), start.Month(), start.Day()-back, s.Hour, 0, 0, 0, loc)
+	}
+	return start
+}
+
+// periodResetJob 单个周期重置项；run 在登记周期的同一事务内执行
+type periodResetJob struct {
+	key    string
+	period ResetPeriod
+	run    func(ctx context.Context, tx *sql.Tx, periodStart time.Time) (int64, error)
+}
+
+// PeriodResetTask 日/周周期重置定时任务
+// 每小时检查一次，各重置项按 (reset_key, period_start) 幂等执行；启动时立即补执行停机期间错过的周期
+type PeriodResetTask struct {
+	db        *sql.DB
+	resetRepo interfaces.PeriodResetRepository
+	schedule  ResetSchedule
+	jobs      []periodResetJob
+	logger    log.Logger
+	cron      *cron.Cron
+	now       func() time.Time
+}
+
+// NewPeriodResetTask 创建周期重置任务实例
+func NewPeriodResetTask(db *sql.DB, schedule ResetSchedule, logger log.Logger) *PeriodResetTask {
+	recordRepo := impl.NewTeamDungeonRecordRepository(db)
+	dropStatsRepo := impl.NewWorldDropStatsRepository(db)
+
+	return &PeriodResetTask{
+		db:        db,
+		resetRepo: impl.NewPeriodResetRepository(db),
+		schedule:  schedule,
+		logger:    logger,
+		now:       time.Now,
+		// 新的周期限制在此登记即可纳入统一重置
+		jobs: []periodResetJob{
+			{key: "dungeon_attempts", period: ResetDaily, run: func(ctx context.Context, tx *sql.Tx, _ time.Time) (int64, error) {
+				return recordRepo.ResetAttempts(ctx, tx)
+			}},
+			{key: "world_drop_daily", period: ResetDaily, run: func(ctx context.Context, tx *sql.Tx, periodStart time.Time) (int64, error) {
+				return dropStatsRepo.ResetAllDaily(ctx, tx, periodStart)
+			}},
+			{key: "world_drop_hourly", period: ResetHourly, run: func(ctx context.Context, tx *sql.Tx, periodStart time.Time) (int64, error) {
+				return dropStatsRepo.ResetAllHourly(ctx, tx, periodStart)
+			}},
+		},
+	}
+}
+
+// Start 启动定时任务
+func (t *PeriodResetTask) Start() {
+	// 创建 cron 调度器
+	t.cron = cron.New(cron.WithSeconds(), cron.WithLocation(t.schedule.Location))
+
+	// 每小时整点后1秒执行，周期是否到期由 PeriodStart 判断
+	// Cron 表达式: 秒 分 时 日 月 周
+	_, err := t.cron.AddFunc("1 0 * * * *", func() {
+		t.runDueResets()
+	})
+
+	if err != nil {
+		t.logger.Error("【定时任务】添加周期重置任务失败", err)
+		return
+	}
+
+	// 启动时先补执行一次（停机期间错过的周期）
+	go t.runDueResets()
+
+	// 启动调度器
+	t.cron.Start()
+	t.logger.Info("【定时任务】周期重置任务已启动",
+		"reset_hour", t.schedule.Hour,
+		"reset_weekday", t.schedule.Weekday.String(),
+		"timezone", t.schedule.Location.String())
+}
+
+// runDueResets 执行所有到期的重置项
+func (t *PeriodResetTask) runDueResets() {
+	ctx := context.Background()
+	now := t.now()
+
+	for _, job := range t.jobs {
+		periodStart := t.schedule.PeriodStart(job.period, now)
+		executed, affected, err := t.runJob(ctx, job, periodStart)
+		if err != nil {
+			t.logger.Error("【定时任务】周期重置失败", err, "reset_key", job.key, "period_start", periodStart.Format(time.RFC3339))
+			continue
+		}
+		if executed {
+			t.logger.Info("【定时任务】周期重置完成",
+				"reset_key", job.key,
+				"period_start", periodStart.Format(time.RFC3339),
+				"affected_rows", affected)
+		}
+	}
+}
+
+// runJob 在一个事务内登记周期并执行重置；该周期已执行过则跳过。
+// 重置项首次运行（从未登记过周期）时只登记当前周期，不清空本周期内已产生的次数与计数
+func (t *PeriodResetTask) runJob(ctx context.Context, job periodResetJob, periodStart time.Time) (bool, int64, error) {
+	last, err := t.resetRepo.GetLastPeriod(ctx, job.key)
+	if err != nil {
+		return false, 0, err
+	}
+	seed := last == nil
+	if last != nil && !last.Before(periodStart) {
+		return false, 0, nil
+	}
+	if last != nil && t.schedule.PeriodStart(job.period, periodStart.Add(-time.Second)).After(*last) {
+		t.logger.Warn("【定时任务】检测到错过的重置周期，补执行",
+			"reset_key", job.key,
+			"last_period", last.Format(time.RFC3339),
+			"period_start", periodStart.Format(time.RFC3339))
+	}
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, 0, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			// 仅当 Rollback 失败且不是已提交的事务时，才表示有问题
+		}
+	}()
+
+	claimed, err := t.resetRepo.ClaimPeriod(ctx, tx, job.key, periodStart)
+	if err != nil || !claimed {
+		return false, 0, err
+	}
+	var affected int64
+	if !seed {
+		if affected, err = job.run(ctx, tx, periodStart); err != nil {
+			return false, 0, err
+		}
+	}
+	if err := t.resetRepo.FinishPeriod(ctx, tx, job.key, periodStart, affected); err != nil {
+		return false, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, 0, err
+	}
+	if seed {
+		t.logger.Info("【定时任务】重置项首次运行，登记当前周期，下个周期起开始重置",
+			"reset_key", job.key,
+			"period_start", periodStart.Format(time.RFC3339))
+		return false, 0, nil
+	}
+	return true, affected, nil
+}
+
+// Stop 停止定时任务（优雅关闭）
+func (t *PeriodResetTask) Stop() {
+	if t.cron != nil {
+		t.logger.Info("【定时任务】正在停止周期重置任务...")
+		ctx := t.cron.Stop()
+		<-ctx.Done()
+		t.logger.Info("【定时任务】周期重置任务已停止")
+	}
+}
@@ -0,0 +1,118 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"tsu-self/internal/pkg/log"
+)
+
+func TestResetSchedulePeriodStart(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	schedule := ResetSchedule{Hour: 5, Weekday: time.Monday, Location: loc}
+
+	// 2026-09-16 是周三
+	now := time.Date(2026, 9, 16, 10, 30, 0, 0, loc)
+	require.Equal(t, time.Date(2026, 9, 16, 10, 0, 0, 0, loc), schedule.PeriodStart(ResetHourly, now))
+	require.Equal(t, time.Date(2026, 9, 16, 5, 0, 0, 0, loc), schedule.PeriodStart(ResetDaily, now))
+	require.Equal(t, time.Date(2026, 9, 14, 5, 0, 0, 0, loc), schedule.PeriodStart(ResetWeekly, now))
+
+	// 重置时刻之前仍属于前一天的周期
+	early := time.Date(2026, 9, 16, 4, 59, 0, 0, loc)
+	require.Equal(t, time.Date(2026, 9, 15, 5, 0, 0, 0, loc), schedule.PeriodStart(ResetDaily, early))
+
+	// 周一重置时刻前属于上一周
+	mondayEarly := time.Date(2026, 9, 14, 3, 0, 0, 0, loc)
+	require.Equal(t, time.Date(2026, 9, 7, 5, 0, 0, 0, loc), schedule.PeriodStart(ResetWeekly, mondayEarly))
+
+	// 输入时区不影响周期计算
+	require.True(t, schedule.PeriodStart(ResetDaily, now.UTC()).Equal(schedule.PeriodStart(ResetDaily, now)))
+}
+
+func TestLoadResetSchedule(t *testing.T) {
+	t.Setenv("GAME_RESET_HOUR", "6")
+	t.Setenv("GAME_RESET_WEEKDAY", "0")
+	t.Setenv("GAME_RESET_TIMEZONE", "UTC")
+	schedule, err := LoadResetSchedule()
+	require.NoError(t, err)
+	require.Equal(t, 6, schedule.Hour)
+	require.Equal(t, time.Sunday, schedule.Weekday)
+	require.Equal(t, time.UTC, schedule.Location)
+
+	t.Setenv("GAME_RESET_HOUR", "24")
+	schedule, err = LoadResetSchedule()
+	require.Error(t, err)
+	require.Equal(t, defaultResetHour, schedule.Hour)
+}
+
+type fakePeriodResetRepo struct {
+	last     *time.Time
+	finished map[string]int64
+}
+
+func (f *fakePeriodResetRepo) ClaimPeriod(ctx context.Context, tx *sql.Tx, resetKey string, periodStart time.Time) (bool, error) {
+	return f.last == nil || f.last.Before(periodStart), nil
+}
+
+func (f *fakePeriodResetRepo) FinishPeriod(ctx context.Context, tx *sql.Tx, resetKey string, periodStart time.Time, affected int64) error {
+	f.last = &periodStart
+	f.finished[periodStart.Format(time.RFC3339)] = affected
+	return nil
+}
+
+func (f *fakePeriodResetRepo) GetLastPeriod(ctx context.Context, resetKey string) (*time.Time, error) {
+	return f.last, nil
+}
+
+func TestPeriodResetTaskSeedsFirstPeriod(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	loc := time.FixedZone("UTC+8", 8*3600)
+	repo := &fakePeriodResetRepo{finished: map[string]int64{}}
+	resets := 0
+	task := &PeriodResetTask{
+		db:        db,
+		resetRepo: repo,
+		schedule:  ResetSchedule{Hour: 5, Location: loc},
+		logger:    log.NewLogger(slog.NewTextHandler(io.Discard, nil)),
+	}
+	job := periodResetJob{key: "dungeon_attempts", period: ResetDaily, run: func(ctx context.Context, tx *sql.Tx, _ time.Time) (int64, error) {
+		resets++
+		return 3, nil
+	}}
+
+	// 首次部署：只登记当前周期，不清空当天已用的次数
+	today := time.Date(2026, 9, 16, 5, 0, 0, 0, loc)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	executed, _, err := task.runJob(context.Background(), job, today)
+	require.NoError(t, err)
+	require.False(t, executed)
+	require.Zero(t, resets)
+	require.Equal(t, int64(0), repo.finished[today.Format(time.RFC3339)])
+
+	// 同一周期不再执行
+	executed, _, err = task.runJob(context.Background(), job, today)
+	require.NoError(t, err)
+	require.False(t, executed)
+
+	// 下个周期照常重置
+	tomorrow := today.AddDate(0, 0, 1)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	executed, affected, err := task.runJob(context.Background(), job, tomorrow)
+	require.NoError(t, err)
+	require.True(t, executed)
+	require.Equal(t, int64(3), affected)
+	require.Equal(t, 1, resets)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,92 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"tsu-self/internal/modules/game/service"
+	"tsu-self/internal/pkg/log"
+)
+
+// 团队动态默认保留 90 天
+const defaultActivityRetentionDays = 90
+
+// LoadActivityRetention 从环境变量 TEAM_ACTIVITY_RETENTION_DAYS 读取团队动态保留时长
+// 配置非法时返回错误，同时返回默认保留时长
+func LoadActivityRetention() (time.Duration, error) {
+	retention := defaultActivityRetentionDays * 24 * time.Hour
+	if v := os.Getenv("TEAM_ACTIVITY_RETENTION_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days <= 0 {
+			return retention, fmt.Errorf("TEAM_ACTIVITY_RETENTION_DAYS 非法: %s", v)
+		}
+		retention = time.Duration(days) * 24 * time.Hour
+	}
+	return retention, nil
+}
+
+// TeamActivityCleanupTask 团队动态清理定时任务
+// 每天凌晨3点30分执行，删除超过保留时长的团队动态
+type TeamActivityCleanupTask struct {
+	activityService *service.TeamActivityService
+	retention       time.Duration
+	logger          log.Logger
+	cron            *cron.Cron
+}
+
+// NewTeamActivityCleanupTask 创建团队动态清理任务实例
+func NewTeamActivityCleanupTask(activityService *service.TeamActivityService, retention time.Duration, logger log.Logger) *TeamActivityCleanupTask {
+	return &TeamActivityCleanupTask{
+		activityService: activityService,
+		retention:       retention,
+		logger:          logger,
+	}
+}
+
+// Start 启动定时任务
+func (t *TeamActivityCleanupTask) Start() {
+	// 创建 cron 调度器
+	t.cron = cron.New(cron.WithSeconds())
+
+	// 每天凌晨3点30分执行
+	// Cron 表达式: 秒 分 时 日 月 周
+	_, err := t.cron.AddFunc("0 30 3 * * *", func() {
+		t.purgeExpiredActivities()
+	})
+
+	if err != nil {
+		t.logger.Error("【团队定时任务】添加团队动态清理任务失败", err)
+		return
+	}
+
+	// 启动调度器
+	t.cron.Start()
+	t.logger.Info("【团队定时任务】团队动态清理任务已启动 - 每天凌晨3点30分执行", "retention", t.retention.String())
+}
+
+// purgeExpiredActivities 清理过期团队动态
+func (t *TeamActivityCleanupTask) purgeExpiredActivities() {
+	deleted, err := t.activityService.PurgeExpired(context.Background(), t.retention)
+	if err != nil {
+		t.logger.Error("【团队定时任务】团队动态清理失败", err, "deleted", deleted)
+		return
+	}
+	if deleted > 0 {
+		t.logger.Info("【团队定时任务】团队动态清理完成", "deleted", deleted)
+	}
+}
+
+// Stop 停止定时任务（优雅关闭）
+func (t *TeamActivityCleanupTask) Stop() {
+	if t.cron != nil {
+		t.logger.Info("【团队定时任务】正在停止团队动态清理任务...")
+		ctx := t.cron.Stop()
+		<-ctx.Done()
+		t.logger.Info("【团队定时任务】团队动态清理任务已停止")
+	}
+}
@@ -0,0 +1,79 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/robfig/cron/v3"
+
+	"tsu-self/internal/modules/game/service"
+	"tsu-self/internal/pkg/log"
+)
+
+// TeamContributionAuctionTask 贡献点竞拍结算定时任务
+// 每分钟检查一次，到期竞拍由最高出价者获得物品，无人出价则流拍；结算中断的竞拍重新结算
+type TeamContributionAuctionTask struct {
+	contributionService *service.TeamContributionService
+	logger              log.Logger
+	cron                *cron.Cron
+}
+
+// NewTeamContributionAuctionTask 创建竞拍结算任务实例
+func NewTeamContributionAuctionTask(db *sql.DB, contributionService *service.TeamContributionService, logger log.Logger) *TeamContributionAuctionTask {
+	return &TeamContributionAuctionTask{
+		contributionService: contributionService,
+		logger:              logger,
+	}
+}
+
+// Start 启动定时任务
+func (t *TeamContributionAuctionTask) Start() {
+	// 创建 cron 调度器
+	t.cron = cron.New(cron.WithSeconds())
+
+	// 每分钟执行一次
+	// Cron 表达式: 秒 分 时 日 月 周
+	_, err := t.cron.AddFunc("45 * * * * *", func() {
+		t.settleEndedAuctions()
+	})
+
+	if err != nil {
+		t.logger.Error("【团队定时任务】添加竞拍结算任务失败", err)
+		return
+	}
+
+	// 启动调度器
+	t.cron.Start()
+	t.logger.Info("【团队定时任务】贡献点竞拍结算任务已启动 - 每分钟执行一次")
+}
+
+// settleEndedAuctions 结算到期竞拍
+func (t *TeamContributionAuctionTask) settleEndedAuctions() {
+	settled, err := t.contributionService.SettleEndedAuctions(context.Background())
+	if err != nil {
+		t.logger.Error("【团队定时任务】竞拍结算失败", err)
+		return
+	}
+	if settled > 0 {
+		t.logger.Info("【团队定时任务】竞拍结算完成", "settled", settled)
+	}
+
+	retried, err := t.contributionService.RetryStaleAuctions(context.Background())
+	if err != nil {
+		t.logger.Error("【团队定时任务】中断竞拍重新结算失败", err)
+		return
+	}
+	if retried > 0 {
+		t.logger.Info("【团队定时任务】中断竞拍重新结算完成", "retried", retried)
+	}
+}
+
+// Stop 停止定时任务（优雅关闭）
+func (t *TeamContributionAuctionTask) Stop() {
+	if t.cron != nil {
+		t.logger.Info("【团队定时任务】正在停止竞拍结算任务...")
+		ctx := t.cron.Stop()
+		<-ctx.Done()
+		t.logger.Info("【团队定时任务】竞拍结算任务已停止")
+	}
+}
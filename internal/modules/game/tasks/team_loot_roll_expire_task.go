@@ -0,0 +1,79 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/robfig/cron/v3"
+
+	"tsu-self/internal/modules/game/service"
+	"tsu-self/internal/pkg/log"
+)
+
+// TeamLootRollExpireTask 战利品投骰超时结算定时任务
+// 每分钟检查一次，超时未投骰的成员视为放弃，按已有选择结算；同时重新发放结算中断的投骰
+type TeamLootRollExpireTask struct {
+	warehouseService *service.TeamWarehouseService
+	logger           log.Logger
+	cron             *cron.Cron
+}
+
+// NewTeamLootRollExpireTask 创建投骰超时结算任务实例
+func NewTeamLootRollExpireTask(db *sql.DB, warehouseService *service.TeamWarehouseService, logger log.Logger) *TeamLootRollExpireTask {
+	return &TeamLootRollExpireTask{
+		warehouseService: warehouseService,
+		logger:           logger,
+	}
+}
+
+// Start 启动定时任务
+func (t *TeamLootRollExpireTask) Start() {
+	// 创建 cron 调度器
+	t.cron = cron.New(cron.WithSeconds())
+
+	// 每分钟执行一次
+	// Cron 表达式: 秒 分 时 日 月 周
+	_, err := t.cron.AddFunc("30 * * * * *", func() {
+		t.resolveExpiredRolls()
+	})
+
+	if err != nil {
+		t.logger.Error("【团队定时任务】添加投骰超时结算任务失败", err)
+		return
+	}
+
+	// 启动调度器
+	t.cron.Start()
+	t.logger.Info("【团队定时任务】投骰超时结算任务已启动 - 每分钟执行一次")
+}
+
+// resolveExpiredRolls 结算超时投骰
+func (t *TeamLootRollExpireTask) resolveExpiredRolls() {
+	resolved, err := t.warehouseService.ResolveExpiredLootRolls(context.Background())
+	if err != nil {
+		t.logger.Error("【团队定时任务】投骰超时结算失败", err)
+		return
+	}
+	if resolved > 0 {
+		t.logger.Info("【团队定时任务】投骰超时结算完成", "resolved", resolved)
+	}
+
+	retried, err := t.warehouseService.RetryStaleLootRolls(context.Background())
+	if err != nil {
+		t.logger.Error("【团队定时任务】中断投骰重新发放失败", err)
+		return
+	}
+	if retried > 0 {
+		t.logger.Info("【团队定时任务】中断投骰重新发放完成", "retried", retried)
+	}
+}
+
+// Stop 停止定时任务（优雅关闭）
+func (t *TeamLootRollExpireTask) Stop() {
+	if t.cron != nil {
+		t.logger.Info("【团队定时任务】正在停止投骰超时结算任务...")
+		ctx := t.cron.Stop()
+		<-ctx.Done()
+		t.logger.Info("【团队定时任务】投骰超时结算任务已停止")
+	}
+}
@@ -0,0 +1,164 @@
+package formula
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Type 变量类型
+type Type string
+
+const (
+	TypeNumber Type = "number"
+	TypeString Type = "string"
+	TypeBool   Type = "boolean"
+	TypeObject Type = "object"
+)
+
+// TargetPrefix 目标属性前缀，target.VIT 表示目标的体质
+const TargetPrefix = "TARGET."
+
+// TypeOfDataType 将 metadata_dictionary.data_type 映射为变量类型
+func TypeOfDataType(dataType string) Type {
+	switch strings.ToLower(strings.TrimSpace(dataType)) {
+	case "integer", "int", "decimal", "float", "number":
+		return TypeNumber
+	case "boolean", "bool":
+		return TypeBool
+	case "string":
+		return TypeString
+	}
+	return TypeObject
+}
+
+// Schema 可用变量表（键为 Normalize 后的变量名）
+type Schema map[string]Type
+
+// BuiltinVariables 战斗引擎内置变量，任何公式均可使用
+func BuiltinVariables() Schema {
+	return Schema{
+		"LEVEL":       TypeNumber,
+		"SKILL_LEVEL": TypeNumber,
+		"STACKS":      TypeNumber,
+		"HP":          TypeNumber,
+		"MAX_HP":      TypeNumber,
+		"MP":          TypeNumber,
+		"MAX_MP":      TypeNumber,
+	}
+}
+
+// Add 登记变量
+func (s Schema) Add(name string, t Type) {
+	s[Normalize(name)] = t
+}
+
+// Resolve 查找变量类型，target.<变量> 按同名变量处理
+func (s Schema) Resolve(name string) (Type, bool) {
+	key := Normalize(name)
+	key = strings.TrimPrefix(key, TargetPrefix)
+	t, ok := s[key]
+	return t, ok
+}
+
+// CheckError 类型检查失败，Problems 逐条列出问题
+type CheckError struct {
+	Formula  string
+	Problems []string
+}
+
+func (e *CheckError) Error() string {
+	return fmt.Sprintf("公式 %q 校验失败: %s", e.Formula, strings.Join(e.Problems, "; "))
+}
+
+// Check 按变量表做类型检查：变量必须已登记且为数值类型，除数不能为常量 0
+func (e *Expr) Check(schema Schema) error {
+	var problems []string
+	seen := make(map[string]bool)
+	e.root.walk(func(n node) {
+		switch v := n.(type) {
+		case variableNode:
+			name := string(v)
+			if seen[name] {
+				return
+			}
+			seen[name] = true
+			t, ok := schema.Resolve(name)
+			if !ok {
+				problems = append(problems, fmt.Sprintf("未知变量 %s", name))
+			} else if t != TypeNumber {
+				problems = append(problems, fmt.Sprintf("变量 %s 的类型为 %s，不能参与数值运算", name, t))
+			}
+		case binaryNode:
+			if num, ok := v.right.(numberNode); ok && v.op == '/' && num == 0 {
+				problems = append(problems, "除数为常量 0")
+			}
+		}
+	})
+	if len(problems) > 0 {
+		return &CheckError{Formula: e.source, Problems: problems}
+	}
+	return nil
+}
+
+// ParseAndCheck 解析并类型检查
+func ParseAndCheck(source string, schema Schema) (*Expr, error) {
+	expr, err := Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	if err := expr.Check(schema); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// DiceFunc 掷骰函数
+type DiceFunc func(count, sides int) int
+
+// MinDice 每颗骰子取 1
+func MinDice(count, sides int) int { return count }
+
+// MaxDice 每颗骰子取最大面
+func MaxDice(count, sides int) int { return count * sides }
+
+// SeededDice 固定种子的随机掷骰，相同种子序列相同
+func SeededDice(seed int64) DiceFunc {
+	rng := rand.New(rand.NewSource(seed))
+	return func(count, sides int) int {
+		total := 0
+		for i := 0; i < count; i++ {
+			total += rng.Intn(sides) + 1
+		}
+		return total
+	}
+}
+
+// MapEnv 基于变量表的求值环境，变量名不区分大小写
+type MapEnv struct {
+	Vars map[string]float64 // 键为 Normalize 后的变量名，目标属性使用 TARGET. 前缀
+	Dice DiceFunc           // 为空时按 MinDice 处理
+}
+
+// NewMapEnv 创建求值环境
+func NewMapEnv(vars map[string]float64, dice DiceFunc) *MapEnv {
+	env := &MapEnv{Vars: make(map[string]float64, len(vars)), Dice: dice}
+	for k, v := range vars {
+		env.Vars[Normalize(k)] = v
+	}
+	return env
+}
+
+// Lookup 实现 Env
+func (e *MapEnv) Lookup(name string) (float64, bool) {
+	v, ok := e.Vars[Normalize(name)]
+	return v, ok
+}
+
+// RollDice 实现 Env
+func (e *MapEnv) RollDice(count, sides int) int {
+	if e.Dice == nil {
+		return MinDice(count, sides)
+	}
+	return e.Dice(count, sides)
+}
@@ -0,0 +1,460 @@
+// Package formula 配置公式表达式引擎
+//
+// 用于 effects.calculation_formula、actions.mana_cost_formula、属性派生公式等配置字符串。
+// 支持：数字、变量（可带 @ 前缀，如 @INT；target.<属性> 表示目标属性）、骰子（2d6 / d20，最多 100 颗、1000 面）、
+// 四则运算、括号，以及 min/max/floor/ceil/round/abs 函数。
+// 求值只依赖 Env 提供的变量与骰子，相同输入得到相同结果。
+package formula
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Env 公式求值环境
+type Env interface {
+	// Lookup 查找变量值
+	Lookup(name string) (float64, bool)
+	// RollDice 掷 count 个 sides 面骰子并返回点数之和
+	RollDice(count, sides int) int
+}
+
+// 骰子上限，防止异常配置（如 2000000000d6）导致掷骰循环失控
+const (
+	MaxDiceCount = 100
+	MaxDiceSides = 1000
+)
+
+// Expr 解析后的公式
+type Expr struct {
+	source string
+	root   node
+}
+
+type node interface {
+	eval(env Env) (float64, error)
+	walk(fn func(node))
+}
+
+type numberNode float64
+
+func (n numberNode) eval(Env) (float64, error) { return float64(n), nil }
+func (n numberNode) walk(fn func(node))        { fn(n) }
+
+type variableNode string
+
+func (n variableNode) eval(env Env) (float64, error) {
+	if v, ok := env.Lookup(string(n)); ok {
+		return v, nil
+	}
+	if v, ok := env.Lookup(Normalize(string(n))); ok {
+		return v, nil
+	}
+	return 0, fmt.Errorf("未知变量 %s", string(n))
+}
+
+func (n variableNode) walk(fn func(node)) { fn(n) }
+
+type diceNode struct {
+	count int
+	sides int
+}
+
+func (n diceNode) eval(env Env) (float64, error) {
+	return float64(env.RollDice(n.count, n.sides)), nil
+}
+
+func (n diceNode) walk(fn func(node)) { fn(n) }
+
+type unaryNode struct {
+	operand node
+}
+
+func (n unaryNode) eval(env Env) (float64, error) {
+	v, err := n.operand.eval(env)
+	return -v, err
+}
+
+func (n unaryNode) walk(fn func(node)) {
+	fn(n)
+	n.operand.walk(fn)
+}
+
+type binaryNode struct {
+	op          byte
+	left, right node
+}
+
+func (n binaryNode) eval(env Env) (float64, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	r, err := n.right.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	case '/':
+		if r == 0 {
+			return 0, fmt.Errorf("除数为 0")
+		}
+		return l / r, nil
+	}
+	return 0, fmt.Errorf("不支持的运算符 %c", n.op)
+}
+
+func (n binaryNode) walk(fn func(node)) {
+	fn(n)
+	n.left.walk(fn)
+	n.right.walk(fn)
+}
+
+type callNode struct {
+	name string
+	args []node
+}
+
+// funcArity 支持的函数及参数个数，-1 表示至少一个
+var funcArity = map[string]int{
+	"min": -1, "max": -1, "floor": 1, "ceil": 1, "round": 1, "abs": 1,
+}
+
+func (n callNode) eval(env Env) (float64, error) {
+	values := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return 0, err
+		}
+		values[i] = v
+	}
+	switch n.name {
+	case "min", "max":
+		result := values[0]
+		for _, v := range values[1:] {
+			if (n.name == "min" && v < result) || (n.name == "max" && v > result) {
+				result = v
+			}
+		}
+		return result, nil
+	case "floor":
+		return math.Floor(values[0]), nil
+	case "ceil":
+		return math.Ceil(values[0]), nil
+	case "round":
+		return math.Round(values[0]), nil
+	case "abs":
+		return math.Abs(values[0]), nil
+	}
+	return 0, fmt.Errorf("未知函数 %s", n.name)
+}
+
+func (n callNode) walk(fn func(node)) {
+	fn(n)
+	for _, arg := range n.args {
+		arg.walk(fn)
+	}
+}
+
+// Normalize 变量名归一化：去掉 @ 前缀并转为大写（target.int -> TARGET.INT）
+func Normalize(name string) string {
+	return strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(name), "@"))
+}
+
+// Parse 解析公式
+func Parse(source string) (*Expr, error) {
+	p := &parser{src: source}
+	p.next()
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, fmt.Errorf("公式 %q 解析失败: %w", source, err)
+	}
+	if p.tok.kind != tokEOF {
+		return nil, fmt.Errorf("公式 %q 解析失败: 位置 %d 存在多余内容", source, p.tok.pos)
+	}
+	return &Expr{source: source, root: root}, nil
+}
+
+// String 返回原始公式
+func (e *Expr) String() string { return e.source }
+
+// Eval 求值
+func (e *Expr) Eval(env Env) (float64, error) {
+	v, err := e.root.eval(env)
+	if err != nil {
+		return 0, fmt.Errorf("公式 %q 求值失败: %w", e.source, err)
+	}
+	return v, nil
+}
+
+// Variables 公式引用的变量（按书写形式去重并排序，不含 @ 前缀）
+func (e *Expr) Variables() []string {
+	seen := make(map[string]bool)
+	var names []string
+	e.root.walk(func(n node) {
+		if v, ok := n.(variableNode); ok && !seen[string(v)] {
+			seen[string(v)] = true
+			names = append(names, string(v))
+		}
+	})
+	sort.Strings(names)
+	return names
+}
+
+// HasDice 公式是否包含骰子（结果带随机性）
+func (e *Expr) HasDice() bool {
+	found := false
+	e.root.walk(func(n node) {
+		if _, ok := n.(diceNode); ok {
+			found = true
+		}
+	})
+	return found
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokDice
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+	tokInvalid
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	num   float64
+	dice  diceNode
+	pos   int
+	opVal byte
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+func (p *parser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+
+	ch := p.src[p.pos]
+	switch {
+	case ch == '+' || ch == '-' || ch == '*' || ch == '/':
+		p.pos++
+		p.tok = token{kind: tokOp, opVal: ch, pos: start}
+	case ch == '(':
+		p.pos++
+		p.tok = token{kind: tokLParen, pos: start}
+	case ch == ')':
+		p.pos++
+		p.tok = token{kind: tokRParen, pos: start}
+	case ch == ',':
+		p.pos++
+		p.tok = token{kind: tokComma, pos: start}
+	case isDigit(ch) || ch == '.':
+		for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		text := p.src[start:p.pos]
+		// NdM 骰子
+		if p.pos+1 < len(p.src) && (p.src[p.pos] == 'd' || p.src[p.pos] == 'D') && isDigit(p.src[p.pos+1]) {
+			count, err := strconv.Atoi(text)
+			if err != nil {
+				p.tok = token{kind: tokInvalid, text: text, pos: start}
+				return
+			}
+			p.pos++
+			p.tok = p.readDiceSides(start, count)
+			return
+		}
+		num, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			p.tok = token{kind: tokInvalid, text: text, pos: start}
+			return
+		}
+		p.tok = token{kind: tokNumber, num: num, pos: start}
+	case ch == '@' || ch == '_' || isLetter(ch):
+		if ch == '@' {
+			p.pos++
+		}
+		identStart := p.pos
+		for p.pos < len(p.src) && (isLetter(p.src[p.pos]) || isDigit(p.src[p.pos]) || p.src[p.pos] == '_' || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		text := p.src[identStart:p.pos]
+		if text == "" {
+			p.tok = token{kind: tokInvalid, text: "@", pos: start}
+			return
+		}
+		// d6 视为 1d6
+		if ch != '@' && (text[0] == 'd' || text[0] == 'D') && len(text) > 1 && allDigits(text[1:]) {
+			p.pos = identStart + 1
+			p.tok = p.readDiceSides(start, 1)
+			return
+		}
+		p.tok = token{kind: tokIdent, text: text, pos: start}
+	default:
+		p.pos++
+		p.tok = token{kind: tokInvalid, text: string(ch), pos: start}
+	}
+}
+
+func (p *parser) readDiceSides(start, count int) token {
+	sidesStart := p.pos
+	for p.pos < len(p.src) && isDigit(p.src[p.pos]) {
+		p.pos++
+	}
+	sides, err := strconv.Atoi(p.src[sidesStart:p.pos])
+	if err != nil || count <= 0 || sides <= 0 || count > MaxDiceCount || sides > MaxDiceSides {
+		return token{kind: tokInvalid, text: p.src[start:p.pos], pos: start}
+	}
+	return token{kind: tokDice, dice: diceNode{count: count, sides: sides}, pos: start}
+}
+
+func (p *parser) parseExpr() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp && (p.tok.opVal == '+' || p.tok.opVal == '-') {
+		op := p.tok.opVal
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseTerm() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp && (p.tok.opVal == '*' || p.tok.opVal == '/') {
+		op := p.tok.opVal
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.tok.kind == tokOp && (p.tok.opVal == '-' || p.tok.opVal == '+') {
+		negative := p.tok.opVal == '-'
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if negative {
+			return unaryNode{operand: operand}, nil
+		}
+		return operand, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokNumber:
+		p.next()
+		return numberNode(tok.num), nil
+	case tokDice:
+		p.next()
+		return tok.dice, nil
+	case tokIdent:
+		p.next()
+		if p.tok.kind != tokLParen {
+			return variableNode(tok.text), nil
+		}
+		return p.parseCall(tok)
+	case tokLParen:
+		p.next()
+		inner, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, fmt.Errorf("位置 %d 缺少右括号", p.tok.pos)
+		}
+		p.next()
+		return inner, nil
+	case tokEOF:
+		return nil, fmt.Errorf("表达式不完整")
+	}
+	return nil, fmt.Errorf("位置 %d 存在非法内容 %q", tok.pos, tok.text)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn := strings.ToLower(name.text)
+	arity, ok := funcArity[fn]
+	if !ok {
+		return nil, fmt.Errorf("未知函数 %s", name.text)
+	}
+	p.next() // (
+	var args []node
+	for p.tok.kind != tokRParen {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.tok.kind == tokComma {
+			p.next()
+			continue
+		}
+		if p.tok.kind != tokRParen {
+			return nil, fmt.Errorf("位置 %d 缺少右括号", p.tok.pos)
+		}
+	}
+	p.next()
+	if (arity < 0 && len(args) == 0) || (arity > 0 && len(args) != arity) {
+		return nil, fmt.Errorf("函数 %s 参数个数错误", name.text)
+	}
+	return callNode{name: fn, args: args}, nil
+}
+
+func isDigit(ch byte) bool  { return ch >= '0' && ch <= '9' }
+func isLetter(ch byte) bool { return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') }
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,86 @@
+package formula
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormulaEval(t *testing.T) {
+	fixed := func(count, sides int) int { return count * 4 }
+	env := NewMapEnv(map[string]float64{"INT": 12, "AGI": 5, "WIS": 3, "skill_level": 2, "target.VIT": 7}, fixed)
+
+	cases := map[string]float64{
+		"1 + 2 * 3":          7,
+		"(1 + 2) * 3":        9,
+		"-2 + 5":             3,
+		"AGI*2+WIS":          13,
+		"2d6 + @INT":         20,
+		"d20":                4,
+		"50+2*skill_level":   54,
+		"max(1, INT / 4, 2)": 3,
+		"floor(7 / 2)":       3,
+		"int * 2":            24, // 变量名大小写不敏感
+		"INT - target.vit":   5,
+	}
+	for source, want := range cases {
+		expr, err := Parse(source)
+		require.NoError(t, err, source)
+		got, err := expr.Eval(env)
+		require.NoError(t, err, source)
+		require.InDelta(t, want, got, 1e-9, source)
+	}
+}
+
+func TestFormulaErrors(t *testing.T) {
+	for _, source := range []string{"1 +", "(1 + 2", "2 # 3", "foo(1)", "min()", "0d6", "floor(1, 2)", "101d6", "d1001", "2000000000d6"} {
+		_, err := Parse(source)
+		require.Error(t, err, source)
+	}
+
+	expr, err := Parse("STR + 1")
+	require.NoError(t, err)
+	_, err = expr.Eval(NewMapEnv(nil, nil))
+	require.Error(t, err)
+
+	expr, err = Parse("1 / AGI")
+	require.NoError(t, err)
+	_, err = expr.Eval(NewMapEnv(map[string]float64{"AGI": 0}, nil))
+	require.Error(t, err)
+}
+
+func TestFormulaCheck(t *testing.T) {
+	schema := BuiltinVariables()
+	schema.Add("INT", TypeNumber)
+	schema.Add("VIT", TypeNumber)
+	schema.Add("damage_type", TypeOfDataType("string"))
+
+	_, err := ParseAndCheck("2d6 + @int + target.VIT * skill_level", schema)
+	require.NoError(t, err)
+
+	_, err = ParseAndCheck("STRR * 2 + damage_type + 1 / 0", schema)
+	var checkErr *CheckError
+	require.True(t, errors.As(err, &checkErr))
+	require.Len(t, checkErr.Problems, 3)
+}
+
+func TestFormulaInspection(t *testing.T) {
+	expr, err := Parse("2d6 + @INT + INT + target.VIT")
+	require.NoError(t, err)
+	require.Equal(t, []string{"INT", "target.VIT"}, expr.Variables())
+	require.True(t, expr.HasDice())
+
+	expr, err = Parse("AGI * 2")
+	require.NoError(t, err)
+	require.False(t, expr.HasDice())
+}
+
+func TestSeededDice(t *testing.T) {
+	a, b := SeededDice(9), SeededDice(9)
+	for i := 0; i < 10; i++ {
+		require.Equal(t, a(3, 6), b(3, 6))
+	}
+	require.Equal(t, 3, MinDice(3, 6))
+	require.Equal(t, 18, MaxDice(3, 6))
+}
@@ -23,6 +23,11 @@ func SetNatsConn(conn *nats.Conn) {
 
 // PublishWarehouseEvent 发布仓库相关事件
 func PublishWarehouseEvent(ctx context.Context, subject string, payload interface{}) error {
+	return Publish(ctx, subject, payload)
+}
+
+// Publish 以 JSON 发布事件到指定主题
+func Publish(ctx context.Context, subject string, payload interface{}) error {
 	ncMu.RLock()
 	conn := nc
 	ncMu.RUnlock()
@@ -31,7 +36,7 @@ func PublishWarehouseEvent(ctx context.Context, subject string, payload interfac
 	}
 	data, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("marshal warehouse event failed: %w", err)
+		return fmt.Errorf("marshal event failed: %w", err)
 	}
 	return conn.Publish(subject, data)
 }
@@ -40,4 +45,5 @@ func PublishWarehouseEvent(ctx context.Context, subject string, payload interfac
 const (
 	SubjectWarehouseDistributed = "warehouse.distribution"
 	SubjectWarehouseLoot        = "warehouse.loot"
+	SubjectItemEnhanced         = "item.enhanced"
 )
@@ -0,0 +1,117 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// cronExpr 5 段 cron 表达式：分 时 日 月 周
+type cronExpr struct {
+	minutes    bitset
+	hours      bitset
+	days       bitset
+	months     bitset
+	weekdays   bitset
+	anyDay     bool
+	anyWeekday bool
+}
+
+type bitset uint64
+
+func (b bitset) has(n int) bool { return b&(1<<uint(n)) != 0 }
+
+func parseCron(spec string) (*cronExpr, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron 表达式 %q 需要 5 段（分 时 日 月 周）", spec)
+	}
+
+	expr := &cronExpr{}
+	var err error
+	if expr.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if expr.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if expr.days, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if expr.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if expr.weekdays, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	// 7 与 0 都表示周日
+	if expr.weekdays.has(7) {
+		expr.weekdays |= 1
+	}
+	expr.anyDay = fields[2] == "*" || fields[2] == "?"
+	expr.anyWeekday = fields[4] == "*" || fields[4] == "?"
+	return expr, nil
+}
+
+// matchesDay 日与周同时受限时满足其一即可（与标准 cron 一致）
+func (e *cronExpr) matchesDay(d date, weekday int) bool {
+	if !e.months.has(int(d.month)) {
+		return false
+	}
+	dayOK, weekdayOK := e.days.has(d.day), e.weekdays.has(weekday)
+	switch {
+	case e.anyDay && e.anyWeekday:
+		return true
+	case e.anyDay:
+		return weekdayOK
+	case e.anyWeekday:
+		return dayOK
+	default:
+		return dayOK || weekdayOK
+	}
+}
+
+// parseCronField 支持 *、a、a-b、*/n、a-b/n 以及逗号列表
+func parseCronField(field string, min, max int) (bitset, error) {
+	var set bitset
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("cron 字段 %q 步长无效", field)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			a, b, _ := strings.Cut(rangePart, "-")
+			var errA, errB error
+			lo, errA = strconv.Atoi(a)
+			hi, errB = strconv.Atoi(b)
+			if errA != nil || errB != nil {
+				return 0, fmt.Errorf("cron 字段 %q 无效", field)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("cron 字段 %q 无效", field)
+			}
+			lo, hi = n, n
+			if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("cron 字段 %q 超出范围 %d-%d", field, min, max)
+		}
+		for n := lo; n <= hi; n += step {
+			set |= 1 << uint(n)
+		}
+	}
+	return set, nil
+}
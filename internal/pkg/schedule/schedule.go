@@ -0,0 +1,419 @@
+// Package schedule 周期性开放时间表：按周、按月、cron 规则生成开放时段，支持时区与停开日期。
+//
+// 配置示例（周二、周四 20:00-22:00，国庆停开）：
+//
+//	{
+//	  "timezone": "Asia/Shanghai",
+//	  "rules": [{"type": "weekly", "weekdays": [2, 4], "start_time": "20:00", "end_time": "22:00"}],
+//	  "blackout_dates": ["2026-10-01~2026-10-07"]
+//	}
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 规则类型
+const (
+	RuleWeekly  = "weekly"  // 每周指定星期几
+	RuleMonthly = "monthly" // 每月指定日期，或第 N 个星期几
+	RuleCron    = "cron"    // 5 段 cron 表达式 + 持续分钟数
+)
+
+// maxLookaheadDays 计算下次开放时最多向后查找的天数
+const maxLookaheadDays = 400
+
+const dateLayout = "2006-01-02"
+
+// Rule 开放规则
+type Rule struct {
+	Type            string `json:"type"`
+	Weekdays        []int  `json:"weekdays,omitempty"`         // 0=周日 ... 6=周六，7 也表示周日
+	Days            []int  `json:"days,omitempty"`             // 月内日期 1-31，-1 表示月末
+	Weeks           []int  `json:"weeks,omitempty"`            // 月内第几个星期几（1-5，-1 表示最后一个），配合 weekdays
+	StartTime       string `json:"start_time,omitempty"`       // HH:MM
+	EndTime         string `json:"end_time,omitempty"`         // HH:MM，不晚于 start_time 时视为跨天，允许 24:00
+	Cron            string `json:"cron,omitempty"`             // 分 时 日 月 周
+	DurationMinutes int    `json:"duration_minutes,omitempty"` // cron 每次开放的分钟数
+	ValidFrom       string `json:"valid_from,omitempty"`       // 生效日期 YYYY-MM-DD（含）
+	ValidUntil      string `json:"valid_until,omitempty"`      // 失效日期 YYYY-MM-DD（含）
+}
+
+// Schedule 开放时间表
+type Schedule struct {
+	Timezone      string   `json:"timezone"`
+	Rules         []Rule   `json:"rules"`
+	BlackoutDates []string `json:"blackout_dates,omitempty"` // YYYY-MM-DD 或 YYYY-MM-DD~YYYY-MM-DD
+}
+
+// Window 一个开放时段 [Start, End)
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Contains 时刻是否位于时段内
+func (w Window) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// Compiled 已校验、可计算的时间表
+type Compiled struct {
+	loc       *time.Location
+	rules     []compiledRule
+	blackouts []dateRange
+}
+
+type dateRange struct {
+	from, until date
+}
+
+type compiledRule struct {
+	kind       string
+	weekdays   uint8
+	days       []int
+	weeks      []int
+	startMin   int
+	endMin     int
+	cron       *cronExpr
+	duration   int
+	validFrom  date
+	validUntil date
+}
+
+// Compile 校验并编译时间表
+func Compile(s Schedule) (*Compiled, error) {
+	tz := strings.TrimSpace(s.Timezone)
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("无效的时区 %q", s.Timezone)
+	}
+	if len(s.Rules) == 0 {
+		return nil, fmt.Errorf("至少需要一条开放规则")
+	}
+
+	c := &Compiled{loc: loc}
+	for i, rule := range s.Rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("规则 %d: %w", i+1, err)
+		}
+		c.rules = append(c.rules, compiled)
+	}
+	for _, raw := range s.BlackoutDates {
+		r, err := parseDateRange(raw)
+		if err != nil {
+			return nil, err
+		}
+		c.blackouts = append(c.blackouts, r)
+	}
+	return c, nil
+}
+
+// Validate 校验时间表
+func (s Schedule) Validate() error {
+	_, err := Compile(s)
+	return err
+}
+
+// Location 时间表所在时区
+func (c *Compiled) Location() *time.Location {
+	return c.loc
+}
+
+// Current 返回包含 t 的开放时段
+func (c *Compiled) Current(t time.Time) (Window, bool) {
+	day := dateOf(t.In(c.loc))
+	// 时段最长 24 小时，只需检查前一天与当天
+	for _, d := range []date{day.addDays(-1), day} {
+		for _, w := range c.windowsOn(d) {
+			if w.Contains(t) {
+				return w, true
+			}
+		}
+	}
+	return Window{}, false
+}
+
+// IsOpen 时刻 t 是否开放
+func (c *Compiled) IsOpen(t time.Time) bool {
+	_, ok := c.Current(t)
+	return ok
+}
+
+// Next 返回结束时间晚于 t 的最早时段（正在开放时即当前时段）
+func (c *Compiled) Next(t time.Time) (Window, bool) {
+	day := dateOf(t.In(c.loc)).addDays(-1)
+	var best Window
+	found := false
+	for i := 0; i <= maxLookaheadDays; i++ {
+		d := day.addDays(i)
+		if found && !d.start(c.loc).Before(best.Start) {
+			break
+		}
+		for _, w := range c.windowsOn(d) {
+			if w.End.After(t) && (!found || w.Start.Before(best.Start)) {
+				best, found = w, true
+			}
+		}
+	}
+	return best, found
+}
+
+// NextOpening 返回 t 之后（不含正在进行的时段）最早的开放开始时间
+func (c *Compiled) NextOpening(t time.Time) (Window, bool) {
+	w, ok := c.Next(t)
+	for ok && !w.Start.After(t) {
+		w, ok = c.Next(w.End)
+	}
+	return w, ok
+}
+
+// Upcoming 返回从 t 起的 n 个时段（含正在进行的时段）
+func (c *Compiled) Upcoming(t time.Time, n int) []Window {
+	windows := make([]Window, 0, n)
+	for len(windows) < n {
+		w, ok := c.Next(t)
+		if !ok {
+			break
+		}
+		windows = append(windows, w)
+		t = w.End
+	}
+	return windows
+}
+
+// windowsOn 某个本地日期开始的所有时段
+func (c *Compiled) windowsOn(d date) []Window {
+	for _, b := range c.blackouts {
+		if !d.before(b.from) && !b.until.before(d) {
+			return nil
+		}
+	}
+	var windows []Window
+	for _, rule := range c.rules {
+		windows = append(windows, rule.windowsOn(d, c.loc)...)
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+	return windows
+}
+
+func compileRule(rule Rule) (compiledRule, error) {
+	r := compiledRule{kind: strings.ToLower(strings.TrimSpace(rule.Type))}
+
+	var err error
+	if rule.ValidFrom != "" {
+		if r.validFrom, err = parseDate(rule.ValidFrom); err != nil {
+			return r, err
+		}
+	}
+	if rule.ValidUntil != "" {
+		if r.validUntil, err = parseDate(rule.ValidUntil); err != nil {
+			return r, err
+		}
+		if !r.validFrom.isZero() && r.validUntil.before(r.validFrom) {
+			return r, fmt.Errorf("valid_until 早于 valid_from")
+		}
+	}
+
+	switch r.kind {
+	case RuleWeekly, RuleMonthly:
+		for _, wd := range rule.Weekdays {
+			if wd < 0 || wd > 7 {
+				return r, fmt.Errorf("无效的星期 %d", wd)
+			}
+			r.weekdays |= 1 << uint(wd%7)
+		}
+		if r.startMin, err = parseClock(rule.StartTime, false); err != nil {
+			return r, err
+		}
+		if r.endMin, err = parseClock(rule.EndTime, true); err != nil {
+			return r, err
+		}
+		if r.kind == RuleWeekly {
+			if r.weekdays == 0 {
+				return r, fmt.Errorf("weekly 规则需要 weekdays")
+			}
+			return r, nil
+		}
+		for _, day := range rule.Days {
+			if day == 0 || day < -1 || day > 31 {
+				return r, fmt.Errorf("无效的日期 %d", day)
+			}
+		}
+		for _, week := range rule.Weeks {
+			if week == 0 || week < -1 || week > 5 {
+				return r, fmt.Errorf("无效的周序号 %d", week)
+			}
+		}
+		if len(rule.Days) == 0 && (len(rule.Weeks) == 0 || r.weekdays == 0) {
+			return r, fmt.Errorf("monthly 规则需要 days，或同时配置 weeks 与 weekdays")
+		}
+		r.days, r.weeks = rule.Days, rule.Weeks
+	case RuleCron:
+		if r.cron, err = parseCron(rule.Cron); err != nil {
+			return r, err
+		}
+		if rule.DurationMinutes <= 0 || rule.DurationMinutes > 24*60 {
+			return r, fmt.Errorf("cron 规则的 duration_minutes 必须在 1-1440 之间")
+		}
+		r.duration = rule.DurationMinutes
+	default:
+		return r, fmt.Errorf("未知的规则类型 %q", rule.Type)
+	}
+	return r, nil
+}
+
+func (r compiledRule) windowsOn(d date, loc *time.Location) []Window {
+	if !r.validFrom.isZero() && d.before(r.validFrom) {
+		return nil
+	}
+	if !r.validUntil.isZero() && r.validUntil.before(d) {
+		return nil
+	}
+
+	midnight := d.start(loc)
+	weekday := int(midnight.Weekday())
+	switch r.kind {
+	case RuleWeekly:
+		if r.weekdays&(1<<uint(weekday)) == 0 {
+			return nil
+		}
+		return []Window{r.rangeWindow(d, loc)}
+	case RuleMonthly:
+		if !r.matchesMonthDay(d, weekday) {
+			return nil
+		}
+		return []Window{r.rangeWindow(d, loc)}
+	case RuleCron:
+		if !r.cron.matchesDay(d, weekday) {
+			return nil
+		}
+		var windows []Window
+		for hour := 0; hour < 24; hour++ {
+			if !r.cron.hours.has(hour) {
+				continue
+			}
+			for minute := 0; minute < 60; minute++ {
+				if r.cron.minutes.has(minute) {
+					start := time.Date(d.year, d.month, d.day, hour, minute, 0, 0, loc)
+					windows = append(windows, Window{Start: start, End: start.Add(time.Duration(r.duration) * time.Minute)})
+				}
+			}
+		}
+		return windows
+	}
+	return nil
+}
+
+// rangeWindow 按 start_time/end_time 生成时段，结束不晚于开始时跨到次日
+func (r compiledRule) rangeWindow(d date, loc *time.Location) Window {
+	start := time.Date(d.year, d.month, d.day, 0, r.startMin, 0, 0, loc)
+	endDay := d
+	if r.endMin <= r.startMin {
+		endDay = d.addDays(1)
+	}
+	end := time.Date(endDay.year, endDay.month, endDay.day, 0, r.endMin, 0, 0, loc)
+	return Window{Start: start, End: end}
+}
+
+func (r compiledRule) matchesMonthDay(d date, weekday int) bool {
+	last := d.daysInMonth()
+	for _, day := range r.days {
+		if day == d.day || (day == -1 && d.day == last) {
+			return true
+		}
+	}
+	if len(r.weeks) == 0 || r.weekdays&(1<<uint(weekday)) == 0 {
+		return false
+	}
+	for _, week := range r.weeks {
+		if (week > 0 && (d.day-1)/7+1 == week) || (week == -1 && d.day+7 > last) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock 解析 HH:MM 为当天分钟数
+func parseClock(value string, allowEndOfDay bool) (int, error) {
+	hh, mm, ok := strings.Cut(strings.TrimSpace(value), ":")
+	h, errH := strconv.Atoi(hh)
+	m, errM := strconv.Atoi(mm)
+	if !ok || errH != nil || errM != nil || h < 0 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("无效的时间 %q，格式应为 HH:MM", value)
+	}
+	if h > 23 && !(allowEndOfDay && h == 24 && m == 0) {
+		return 0, fmt.Errorf("无效的时间 %q", value)
+	}
+	return h*60 + m, nil
+}
+
+func parseDateRange(raw string) (dateRange, error) {
+	fromRaw, untilRaw, isRange := strings.Cut(strings.TrimSpace(raw), "~")
+	from, err := parseDate(fromRaw)
+	if err != nil {
+		return dateRange{}, err
+	}
+	until := from
+	if isRange {
+		if until, err = parseDate(untilRaw); err != nil {
+			return dateRange{}, err
+		}
+		if until.before(from) {
+			return dateRange{}, fmt.Errorf("停开日期范围 %q 结束早于开始", raw)
+		}
+	}
+	return dateRange{from: from, until: until}, nil
+}
+
+// date 不含时区的日历日期
+type date struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+func parseDate(value string) (date, error) {
+	t, err := time.Parse(dateLayout, strings.TrimSpace(value))
+	if err != nil {
+		return date{}, fmt.Errorf("无效的日期 %q，格式应为 YYYY-MM-DD", value)
+	}
+	return dateOf(t), nil
+}
+
+func dateOf(t time.Time) date {
+	y, m, d := t.Date()
+	return date{year: y, month: m, day: d}
+}
+
+func (d date) isZero() bool { return d.year == 0 }
+
+func (d date) start(loc *time.Location) time.Time {
+	return time.Date(d.year, d.month, d.day, 0, 0, 0, 0, loc)
+}
+
+func (d date) addDays(n int) date {
+	return dateOf(time.Date(d.year, d.month, d.day+n, 0, 0, 0, 0, time.UTC))
+}
+
+func (d date) before(other date) bool {
+	if d.year != other.year {
+		return d.year < other.year
+	}
+	if d.month != other.month {
+		return d.month < other.month
+	}
+	return d.day < other.day
+}
+
+func (d date) daysInMonth() int {
+	return time.Date(d.year, d.month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
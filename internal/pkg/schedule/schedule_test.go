@@ -0,0 +1,94 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mustCompile(t *testing.T, s Schedule) *Compiled {
+	t.Helper()
+	c, err := Compile(s)
+	require.NoError(t, err)
+	return c
+}
+
+func TestWeeklySchedule(t *testing.T) {
+	c := mustCompile(t, Schedule{
+		Timezone:      "Asia/Shanghai",
+		Rules:         []Rule{{Type: RuleWeekly, Weekdays: []int{2, 4}, StartTime: "20:00", EndTime: "22:00"}},
+		BlackoutDates: []string{"2026-10-01~2026-10-07"},
+	})
+	loc := c.Location()
+
+	// 2026-09-15 周二
+	require.True(t, c.IsOpen(time.Date(2026, 9, 15, 21, 0, 0, 0, loc)))
+	require.False(t, c.IsOpen(time.Date(2026, 9, 15, 22, 0, 0, 0, loc)))
+	require.False(t, c.IsOpen(time.Date(2026, 9, 16, 21, 0, 0, 0, loc)))
+	// 时区换算：北京 20:30 = UTC 12:30
+	require.True(t, c.IsOpen(time.Date(2026, 9, 15, 12, 30, 0, 0, time.UTC)))
+
+	w, ok := c.NextOpening(time.Date(2026, 9, 15, 21, 0, 0, 0, loc))
+	require.True(t, ok)
+	require.Equal(t, time.Date(2026, 9, 17, 20, 0, 0, 0, loc), w.Start)
+
+	// 停开期间（10-01 周四、10-06 周二）跳过
+	w, ok = c.NextOpening(time.Date(2026, 9, 30, 0, 0, 0, 0, loc))
+	require.True(t, ok)
+	require.Equal(t, time.Date(2026, 10, 8, 20, 0, 0, 0, loc), w.Start)
+}
+
+func TestOvernightAndMonthlySchedule(t *testing.T) {
+	c := mustCompile(t, Schedule{
+		Timezone: "UTC",
+		Rules: []Rule{
+			{Type: RuleMonthly, Days: []int{-1}, StartTime: "22:00", EndTime: "02:00"},
+			{Type: RuleMonthly, Weekdays: []int{6}, Weeks: []int{1}, StartTime: "10:00", EndTime: "12:00", ValidFrom: "2026-03-01"},
+		},
+	})
+
+	// 2 月最后一天跨到 3 月 1 日凌晨
+	require.True(t, c.IsOpen(time.Date(2026, 3, 1, 1, 0, 0, 0, time.UTC)))
+	require.False(t, c.IsOpen(time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)))
+
+	// 2026-02-07 是 2 月第一个周六，但规则 3 月才生效；3 月第一个周六为 03-07
+	require.False(t, c.IsOpen(time.Date(2026, 2, 7, 11, 0, 0, 0, time.UTC)))
+	w, ok := c.NextOpening(time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	require.Equal(t, time.Date(2026, 3, 7, 10, 0, 0, 0, time.UTC), w.Start)
+}
+
+func TestCronSchedule(t *testing.T) {
+	c := mustCompile(t, Schedule{
+		Rules: []Rule{{Type: RuleCron, Cron: "30 12,18 * * 1-5", DurationMinutes: 60}},
+	})
+
+	// 2026-09-14 周一
+	require.True(t, c.IsOpen(time.Date(2026, 9, 14, 13, 0, 0, 0, time.UTC)))
+	require.False(t, c.IsOpen(time.Date(2026, 9, 13, 13, 0, 0, 0, time.UTC)))
+
+	windows := c.Upcoming(time.Date(2026, 9, 18, 19, 0, 0, 0, time.UTC), 3)
+	require.Len(t, windows, 3)
+	require.Equal(t, time.Date(2026, 9, 18, 18, 30, 0, 0, time.UTC), windows[0].Start) // 正在进行
+	require.Equal(t, time.Date(2026, 9, 21, 12, 30, 0, 0, time.UTC), windows[1].Start) // 跳过周末
+	require.Equal(t, time.Date(2026, 9, 21, 18, 30, 0, 0, time.UTC), windows[2].Start)
+}
+
+func TestCompileErrors(t *testing.T) {
+	invalid := []Schedule{
+		{Timezone: "Mars/Base", Rules: []Rule{{Type: RuleWeekly, Weekdays: []int{1}, StartTime: "10:00", EndTime: "11:00"}}},
+		{Rules: nil},
+		{Rules: []Rule{{Type: RuleWeekly, StartTime: "10:00", EndTime: "11:00"}}},
+		{Rules: []Rule{{Type: RuleWeekly, Weekdays: []int{1}, StartTime: "25:00", EndTime: "11:00"}}},
+		{Rules: []Rule{{Type: RuleMonthly, Weeks: []int{1}, StartTime: "10:00", EndTime: "11:00"}}},
+		{Rules: []Rule{{Type: RuleCron, Cron: "* * *", DurationMinutes: 10}}},
+		{Rules: []Rule{{Type: RuleCron, Cron: "0 25 * * *", DurationMinutes: 10}}},
+		{Rules: []Rule{{Type: RuleCron, Cron: "0 20 * * *"}}},
+		{Rules: []Rule{{Type: "daily"}}},
+		{Rules: []Rule{{Type: RuleWeekly, Weekdays: []int{1}, StartTime: "10:00", EndTime: "11:00"}}, BlackoutDates: []string{"2026-13-01"}},
+	}
+	for i, s := range invalid {
+		require.Error(t, s.Validate(), "case %d", i)
+	}
+}
@@ -0,0 +1,178 @@
+package security
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 签名请求头
+const (
+	HeaderSignatureKeyID     = "X-Signature-Key-Id"
+	HeaderSignatureTimestamp = "X-Signature-Timestamp"
+	HeaderSignatureNonce     = "X-Signature-Nonce"
+	HeaderSignature          = "X-Signature"
+)
+
+// DefaultSignatureMaxSkew 默认允许的时间偏差
+const DefaultSignatureMaxSkew = 5 * time.Minute
+
+// 签名校验错误
+var (
+	ErrSignatureMissing    = errors.New("缺少签名请求头")
+	ErrSignatureUnknownKey = errors.New("未知的签名密钥")
+	ErrSignatureExpired    = errors.New("签名时间戳超出允许范围")
+	ErrSignatureInvalid    = errors.New("签名不匹配")
+	ErrSignatureReplayed   = errors.New("nonce 已被使用")
+)
+
+// NonceStore 记录已使用的 nonce，用于防重放
+type NonceStore interface {
+	// Reserve 登记 nonce，已存在时返回 false
+	Reserve(ctx context.Context, keyID, nonce string, expiresAt time.Time) (bool, error)
+}
+
+// HMACVerifier 基于 HMAC-SHA256 的请求签名校验，支持多把密钥同时生效以便轮换
+type HMACVerifier struct {
+	keys    map[string][]byte
+	maxSkew time.Duration
+	nonces  NonceStore
+	now     func() time.Time
+}
+
+// NewHMACVerifier 创建签名校验器；keys 为 key_id -> secret
+func NewHMACVerifier(keys map[string]string, maxSkew time.Duration, nonces NonceStore) *HMACVerifier {
+	if maxSkew <= 0 {
+		maxSkew = DefaultSignatureMaxSkew
+	}
+	if nonces == nil {
+		nonces = NewMemoryNonceStore()
+	}
+	v := &HMACVerifier{
+		keys:    make(map[string][]byte, len(keys)),
+		maxSkew: maxSkew,
+		nonces:  nonces,
+		now:     time.Now,
+	}
+	for id, secret := range keys {
+		v.keys[id] = []byte(secret)
+	}
+	return v
+}
+
+// ParseHMACKeys 解析密钥配置，格式: "key_id:secret,key_id2:secret2"
+func ParseHMACKeys(spec string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(part, ":")
+		id, secret = strings.TrimSpace(id), strings.TrimSpace(secret)
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("密钥配置格式错误: %q", part)
+		}
+		if _, exists := keys[id]; exists {
+			return nil, fmt.Errorf("密钥 ID 重复: %s", id)
+		}
+		keys[id] = secret
+	}
+	return keys, nil
+}
+
+// Enabled 是否配置了密钥
+func (v *HMACVerifier) Enabled() bool {
+	return v != nil && len(v.keys) > 0
+}
+
+// Verify 校验请求签名、时间戳与 nonce；body 为已读取的请求体
+func (v *HMACVerifier) Verify(ctx context.Context, r *http.Request, body []byte) error {
+	keyID := r.Header.Get(HeaderSignatureKeyID)
+	timestamp := r.Header.Get(HeaderSignatureTimestamp)
+	nonce := r.Header.Get(HeaderSignatureNonce)
+	signature := r.Header.Get(HeaderSignature)
+	if keyID == "" || timestamp == "" || nonce == "" || signature == "" {
+		return ErrSignatureMissing
+	}
+
+	secret, ok := v.keys[keyID]
+	if !ok {
+		return ErrSignatureUnknownKey
+	}
+
+	// 1. 时间窗口
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrSignatureExpired
+	}
+	signedAt := time.Unix(unix, 0)
+	now := v.now()
+	if signedAt.Before(now.Add(-v.maxSkew)) || signedAt.After(now.Add(v.maxSkew)) {
+		return ErrSignatureExpired
+	}
+
+	// 2. 签名比对
+	expected := SignRequest(secret, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+	given, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || !hmac.Equal(given, expected) {
+		return ErrSignatureInvalid
+	}
+
+	// 3. nonce 在时间窗口内只能使用一次（签名通过后再登记，避免伪造请求占用 nonce）
+	reserved, err := v.nonces.Reserve(ctx, keyID, nonce, signedAt.Add(v.maxSkew))
+	if err != nil {
+		return fmt.Errorf("登记 nonce 失败: %w", err)
+	}
+	if !reserved {
+		return ErrSignatureReplayed
+	}
+	return nil
+}
+
+// SignRequest 计算签名：HMAC-SHA256(secret, METHOD\nREQUEST_URI\nTIMESTAMP\nNONCE\nhex(sha256(body)))
+func SignRequest(secret []byte, method, requestURI, timestamp, nonce string, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.ToUpper(method) + "\n" + requestURI + "\n" + timestamp + "\n" + nonce + "\n" + hex.EncodeToString(bodyHash[:])))
+	return mac.Sum(nil)
+}
+
+// MemoryNonceStore 进程内 nonce 存储，仅适用于单实例或测试
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	now    func() time.Time
+}
+
+// NewMemoryNonceStore 创建进程内 nonce 存储
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: make(map[string]time.Time), now: time.Now}
+}
+
+// Reserve 实现 NonceStore
+func (s *MemoryNonceStore) Reserve(ctx context.Context, keyID, nonce string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for k, exp := range s.nonces {
+		if exp.Before(now) {
+			delete(s.nonces, k)
+		}
+	}
+	key := keyID + ":" + nonce
+	if _, exists := s.nonces[key]; exists {
+		return false, nil
+	}
+	s.nonces[key] = expiresAt
+	return true, nil
+}
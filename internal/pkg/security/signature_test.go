@@ -0,0 +1,63 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func signedRequest(t *testing.T, keyID, secret string, ts time.Time, nonce string, body []byte) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/internal/battles/result", bytes.NewReader(body))
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	req.Header.Set(HeaderSignatureKeyID, keyID)
+	req.Header.Set(HeaderSignatureTimestamp, timestamp)
+	req.Header.Set(HeaderSignatureNonce, nonce)
+	req.Header.Set(HeaderSignature, hex.EncodeToString(SignRequest([]byte(secret), req.Method, req.URL.RequestURI(), timestamp, nonce, body)))
+	return req
+}
+
+func TestHMACVerifier(t *testing.T) {
+	now := time.Now()
+	verifier := NewHMACVerifier(map[string]string{"old": "secret-old", "new": "secret-new"}, time.Minute, nil)
+	ctx := context.Background()
+	body := []byte(`{"battle_id":"b-1"}`)
+
+	// 轮换期间新旧密钥均可用
+	require.NoError(t, verifier.Verify(ctx, signedRequest(t, "old", "secret-old", now, "n-1", body), body))
+	require.NoError(t, verifier.Verify(ctx, signedRequest(t, "new", "secret-new", now, "n-2", body), body))
+
+	// 重放
+	require.ErrorIs(t, verifier.Verify(ctx, signedRequest(t, "new", "secret-new", now, "n-2", body), body), ErrSignatureReplayed)
+
+	// 篡改 body
+	req := signedRequest(t, "new", "secret-new", now, "n-3", body)
+	require.ErrorIs(t, verifier.Verify(ctx, req, []byte(`{"battle_id":"b-2"}`)), ErrSignatureInvalid)
+
+	// 签名失败的请求不占用 nonce
+	require.NoError(t, verifier.Verify(ctx, signedRequest(t, "new", "secret-new", now, "n-3", body), body))
+
+	// 错误密钥、未知密钥、过期、缺少请求头
+	require.ErrorIs(t, verifier.Verify(ctx, signedRequest(t, "new", "secret-old", now, "n-4", body), body), ErrSignatureInvalid)
+	require.ErrorIs(t, verifier.Verify(ctx, signedRequest(t, "retired", "secret-old", now, "n-5", body), body), ErrSignatureUnknownKey)
+	require.ErrorIs(t, verifier.Verify(ctx, signedRequest(t, "new", "secret-new", now.Add(-2*time.Minute), "n-6", body), body), ErrSignatureExpired)
+	require.ErrorIs(t, verifier.Verify(ctx, httptest.NewRequest(http.MethodPost, "/", nil), nil), ErrSignatureMissing)
+}
+
+func TestParseHMACKeys(t *testing.T) {
+	keys, err := ParseHMACKeys(" k1:s1, k2:s2 ,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"k1": "s1", "k2": "s2"}, keys)
+
+	_, err = ParseHMACKeys("k1")
+	require.Error(t, err)
+	_, err = ParseHMACKeys("k1:a,k1:b")
+	require.Error(t, err)
+}
@@ -3,7 +3,13 @@ package impl
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/google/uuid"
 
 	"tsu-self/internal/repository/interfaces"
 )
@@ -24,13 +30,15 @@ func (r *battleReportRepositoryImpl) Create(ctx context.Context, report *interfa
 
 	query := `
 		INSERT INTO game_runtime.battle_reports (
-			battle_id, battle_code, team_id, dungeon_id, result_status,
+			battle_id, battle_code, team_id, dungeon_id, progress_id, room_id, result_status,
 			loot_gold, loot_items, participants, events, raw_payload
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
 		ON CONFLICT (battle_id) DO UPDATE SET
 			battle_code   = EXCLUDED.battle_code,
 			team_id       = EXCLUDED.team_id,
 			dungeon_id    = EXCLUDED.dungeon_id,
+			progress_id   = EXCLUDED.progress_id,
+			room_id       = EXCLUDED.room_id,
 			result_status = EXCLUDED.result_status,
 			loot_gold     = EXCLUDED.loot_gold,
 			loot_items    = EXCLUDED.loot_items,
@@ -47,6 +55,8 @@ func (r *battleReportRepositoryImpl) Create(ctx context.Context, report *interfa
 		nullString(report.BattleCode),
 		nullString(report.TeamID),
 		nullString(report.DungeonID),
+		nullString(report.ProgressID),
+		nullString(report.RoomID),
 		report.ResultStatus,
 		report.LootGold,
 		nullJSON(report.LootItems),
@@ -60,6 +70,170 @@ func (r *battleReportRepositoryImpl) Create(ctx context.Context, report *interfa
 	return nil
 }
 
+func (r *battleReportRepositoryImpl) GetSettledReport(ctx context.Context, battleID string) (*interfaces.BattleReport, error) {
+	report := &interfaces.BattleReport{BattleID: battleID}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(r.battle_code, ''), COALESCE(r.team_id::text, ''), COALESCE(r.dungeon_id::text, ''),
+		       COALESCE(r.progress_id::text, ''), COALESCE(r.room_id, ''), r.result_status
+		FROM game_runtime.battle_reports r
+		JOIN game_runtime.battle_result_outcomes o ON o.battle_id = r.battle_id
+		WHERE r.battle_id = $1 AND o.status = 'completed'
+	`, battleID).Scan(&report.BattleCode, &report.TeamID, &report.DungeonID, &report.ProgressID, &report.RoomID, &report.ResultStatus)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询战报失败: %w", err)
+	}
+	return report, nil
+}
+
+func (r *battleReportRepositoryImpl) ClaimOutcome(ctx context.Context, battleID string, staleAfter time.Duration) (string, *interfaces.BattleOutcome, error) {
+	// 新 battle_id 直接插入；失败或处理超时的记录重新置为 processing 并更换认领令牌
+	query := `
+		INSERT INTO game_runtime.battle_result_outcomes (battle_id, status, claim_token)
+		VALUES ($1, 'processing', $3)
+		ON CONFLICT (battle_id) DO UPDATE SET
+			status        = 'processing',
+			claim_token   = EXCLUDED.claim_token,
+			error_message = NULL
+		WHERE battle_result_outcomes.status = 'failed'
+		   OR (battle_result_outcomes.status = 'processing' AND battle_result_outcomes.updated_at < NOW() - make_interval(secs => $2))
+		RETURNING claim_token
+	`
+	var claimToken string
+	err := r.db.QueryRowContext(ctx, query, battleID, staleAfter.Seconds(), uuid.NewString()).Scan(&claimToken)
+	if err == nil {
+		return claimToken, nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", nil, fmt.Errorf("抢占战斗结算失败: %w", err)
+	}
+
+	existing := &interfaces.BattleOutcome{BattleID: battleID}
+	var outcome []byte
+	err = r.db.QueryRowContext(ctx,
+		`SELECT status, outcome FROM game_runtime.battle_result_outcomes WHERE battle_id = $1`,
+		battleID,
+	).Scan(&existing.Status, &outcome)
+	if err != nil {
+		return "", nil, fmt.Errorf("查询战斗结算记录失败: %w", err)
+	}
+	existing.Outcome = outcome
+	return "", existing, nil
+}
+
+func (r *battleReportRepositoryImpl) CompleteOutcome(ctx context.Context, battleID, claimToken string, outcome json.RawMessage) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE game_runtime.battle_result_outcomes
+		SET status = 'completed', outcome = $3, error_message = NULL
+		WHERE battle_id = $1 AND claim_token = $2 AND status = 'processing'
+	`, battleID, claimToken, nullJSON(outcome))
+	if err != nil {
+		return fmt.Errorf("更新战斗结算结果失败: %w", err)
+	}
+	return claimedRowAffected(result)
+}
+
+func (r *battleReportRepositoryImpl) FailOutcome(ctx context.Context, battleID, claimToken, reason string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE game_runtime.battle_result_outcomes
+		SET status = 'failed', error_message = $3
+		WHERE battle_id = $1 AND claim_token = $2 AND status = 'processing'
+	`, battleID, claimToken, nullString(reason))
+	if err != nil {
+		return fmt.Errorf("标记战斗结算失败: %w", err)
+	}
+	return claimedRowAffected(result)
+}
+
+// claimedRowAffected 认领令牌不匹配（已被重新认领）时没有行被更新
+func claimedRowAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取影响行数失败: %w", err)
+	}
+	if affected == 0 {
+		return interfaces.ErrBattleOutcomeClaimLost
+	}
+	return nil
+}
+
+type battleResultStepRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewBattleResultStepRepository 创建战斗结算步骤仓储实例。
+func NewBattleResultStepRepository(db *sql.DB) interfaces.BattleResultStepRepository {
+	return &battleResultStepRepositoryImpl{db: db}
+}
+
+func (r *battleResultStepRepositoryImpl) MarkStep(ctx context.Context, execer boil.ContextExecutor, battleID, step string, result json.RawMessage) (bool, error) {
+	if execer == nil {
+		execer = r.db
+	}
+	res, err := execer.ExecContext(ctx, `
+		INSERT INTO game_runtime.battle_result_steps (battle_id, step, result)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (battle_id, step) DO NOTHING
+	`, battleID, step, nullJSON(result))
+	if err != nil {
+		return false, fmt.Errorf("登记战斗结算步骤失败: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("登记战斗结算步骤失败: %w", err)
+	}
+	return affected == 1, nil
+}
+
+func (r *battleResultStepRepositoryImpl) GetStep(ctx context.Context, battleID, step string) (json.RawMessage, bool, error) {
+	var result []byte
+	err := r.db.QueryRowContext(ctx,
+		`SELECT result FROM game_runtime.battle_result_steps WHERE battle_id = $1 AND step = $2`,
+		battleID, step,
+	).Scan(&result)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("查询战斗结算步骤失败: %w", err)
+	}
+	return result, true, nil
+}
+
+type battleCallbackNonceRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewBattleCallbackNonceRepository 创建战斗回调 nonce 仓储实例。
+func NewBattleCallbackNonceRepository(db *sql.DB) interfaces.BattleCallbackNonceRepository {
+	return &battleCallbackNonceRepositoryImpl{db: db}
+}
+
+func (r *battleCallbackNonceRepositoryImpl) Reserve(ctx context.Context, keyID, nonce string, expiresAt time.Time) (bool, error) {
+	// 顺带清理已过期的 nonce，过期记录允许被同名 nonce 覆盖
+	if _, err := r.db.ExecContext(ctx,
+		`DELETE FROM game_runtime.battle_callback_nonces WHERE expires_at < NOW()`,
+	); err != nil {
+		return false, fmt.Errorf("清理过期 nonce 失败: %w", err)
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO game_runtime.battle_callback_nonces (key_id, nonce, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key_id, nonce) DO NOTHING
+	`, keyID, nonce, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("登记 nonce 失败: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("登记 nonce 失败: %w", err)
+	}
+	return affected == 1, nil
+}
+
 func nullString(val string) interface{} {
 	if val == "" {
 		return sql.NullString{}
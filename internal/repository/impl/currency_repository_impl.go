@@ -0,0 +1,150 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"tsu-self/internal/repository/interfaces"
+)
+
+type currencyRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewCurrencyRepository 创建货币定义仓储实例
+func NewCurrencyRepository(db *sql.DB) interfaces.CurrencyRepository {
+	return &currencyRepositoryImpl{db: db}
+}
+
+const currencyColumns = `currency_code, currency_name, description, is_system, is_active, created_at, updated_at`
+
+func scanCurrency(scanner interface{ Scan(...interface{}) error }) (*interfaces.Currency, error) {
+	var (
+		c    interfaces.Currency
+		desc sql.NullString
+	)
+	if err := scanner.Scan(&c.Code, &c.Name, &desc, &c.IsSystem, &c.IsActive, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if desc.Valid {
+		c.Description = &desc.String
+	}
+	return &c, nil
+}
+
+func (r *currencyRepositoryImpl) GetByCode(ctx context.Context, code string) (*interfaces.Currency, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+currencyColumns+` FROM game_config.currencies WHERE currency_code = $1`, code)
+	c, err := scanCurrency(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询货币失败: %w", err)
+	}
+	return c, nil
+}
+
+func (r *currencyRepositoryImpl) List(ctx context.Context, includeInactive bool) ([]*interfaces.Currency, error) {
+	query := `SELECT ` + currencyColumns + ` FROM game_config.currencies`
+	if !includeInactive {
+		query += ` WHERE is_active`
+	}
+	query += ` ORDER BY is_system DESC, currency_code`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("查询货币列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*interfaces.Currency
+	for rows.Next() {
+		c, err := scanCurrency(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描货币失败: %w", err)
+		}
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
+func (r *currencyRepositoryImpl) Create(ctx context.Context, c *interfaces.Currency) error {
+	err := r.db.QueryRowContext(ctx, `
+INSERT INTO game_config.currencies (currency_code, currency_name, description, is_system, is_active)
+VALUES ($1, $2, $3, FALSE, $4)
+RETURNING created_at, updated_at
+`, c.Code, c.Name, c.Description, c.IsActive).Scan(&c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("创建货币失败: %w", err)
+	}
+	return nil
+}
+
+func (r *currencyRepositoryImpl) Update(ctx context.Context, c *interfaces.Currency) error {
+	err := r.db.QueryRowContext(ctx, `
+UPDATE game_config.currencies
+SET currency_name = $2, description = $3, is_active = $4
+WHERE currency_code = $1
+RETURNING updated_at
+`, c.Code, c.Name, c.Description, c.IsActive).Scan(&c.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("更新货币失败: %w", err)
+	}
+	return nil
+}
+
+func (r *currencyRepositoryImpl) Delete(ctx context.Context, code string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM game_config.currencies WHERE currency_code = $1 AND NOT is_system`, code); err != nil {
+		return fmt.Errorf("删除货币失败: %w", err)
+	}
+	return nil
+}
+
+func (r *currencyRepositoryImpl) ListRewards(ctx context.Context, sourceType, sourceID string) ([]*interfaces.CurrencyReward, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT cr.source_type, cr.source_id::text, cr.currency_code, cr.amount
+FROM game_config.currency_rewards cr
+JOIN game_config.currencies c ON c.currency_code = cr.currency_code
+WHERE cr.source_type = $1 AND cr.source_id::text = $2 AND c.is_active
+ORDER BY cr.currency_code
+`, sourceType, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("查询货币奖励失败: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*interfaces.CurrencyReward
+	for rows.Next() {
+		var reward interfaces.CurrencyReward
+		if err := rows.Scan(&reward.SourceType, &reward.SourceID, &reward.CurrencyCode, &reward.Amount); err != nil {
+			return nil, fmt.Errorf("扫描货币奖励失败: %w", err)
+		}
+		result = append(result, &reward)
+	}
+	return result, rows.Err()
+}
+
+func (r *currencyRepositoryImpl) ReplaceRewards(ctx context.Context, sourceType, sourceID string, rewards []*interfaces.CurrencyReward) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM game_config.currency_rewards WHERE source_type = $1 AND source_id::text = $2`, sourceType, sourceID); err != nil {
+		return fmt.Errorf("清理货币奖励失败: %w", err)
+	}
+	for _, reward := range rewards {
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO game_config.currency_rewards (source_type, source_id, currency_code, amount)
+VALUES ($1, $2, $3, $4)
+`, sourceType, sourceID, reward.CurrencyCode, reward.Amount); err != nil {
+			return fmt.Errorf("写入货币奖励失败: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
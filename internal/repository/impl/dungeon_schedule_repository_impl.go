@@ -0,0 +1,124 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"tsu-self/internal/repository/interfaces"
+)
+
+type dungeonScheduleRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewDungeonScheduleRepository 创建地城开放时间表仓储实例
+func NewDungeonScheduleRepository(db *sql.DB) interfaces.DungeonScheduleRepository {
+	return &dungeonScheduleRepositoryImpl{db: db}
+}
+
+const dungeonScheduleColumns = `dungeon_id, timezone, rules, blackout_dates, is_active, updated_at`
+
+func scanDungeonSchedule(scanner interface{ Scan(...interface{}) error }) (*interfaces.DungeonSchedule, error) {
+	var (
+		s             interfaces.DungeonSchedule
+		rules, blacks []byte
+	)
+	if err := scanner.Scan(&s.DungeonID, &s.Schedule.Timezone, &rules, &blacks, &s.IsActive, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(rules, &s.Schedule.Rules); err != nil {
+		return nil, fmt.Errorf("解析开放规则失败: %w", err)
+	}
+	if err := json.Unmarshal(blacks, &s.Schedule.BlackoutDates); err != nil {
+		return nil, fmt.Errorf("解析停开日期失败: %w", err)
+	}
+	return &s, nil
+}
+
+func (r *dungeonScheduleRepositoryImpl) GetByDungeonID(ctx context.Context, dungeonID string) (*interfaces.DungeonSchedule, error) {
+	row := r.db.QueryRowContext(ctx, `
+SELECT `+dungeonScheduleColumns+`
+FROM game_config.dungeon_schedules
+WHERE dungeon_id::text = $1
+`, dungeonID)
+	s, err := scanDungeonSchedule(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询地城开放时间表失败: %w", err)
+	}
+	return s, nil
+}
+
+func (r *dungeonScheduleRepositoryImpl) ListByDungeonIDs(ctx context.Context, dungeonIDs []string) (map[string]*interfaces.DungeonSchedule, error) {
+	result := make(map[string]*interfaces.DungeonSchedule, len(dungeonIDs))
+	if len(dungeonIDs) == 0 {
+		return result, nil
+	}
+	rows, err := r.db.QueryContext(ctx, `
+SELECT `+dungeonScheduleColumns+`
+FROM game_config.dungeon_schedules
+WHERE dungeon_id::text = ANY($1)
+`, pq.Array(dungeonIDs))
+	if err != nil {
+		return nil, fmt.Errorf("查询地城开放时间表失败: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s, err := scanDungeonSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取地城开放时间表失败: %w", err)
+		}
+		result[s.DungeonID] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取地城开放时间表失败: %w", err)
+	}
+	return result, nil
+}
+
+func (r *dungeonScheduleRepositoryImpl) Upsert(ctx context.Context, s *interfaces.DungeonSchedule) error {
+	if s == nil {
+		return fmt.Errorf("dungeon schedule is nil")
+	}
+	rules, err := json.Marshal(s.Schedule.Rules)
+	if err != nil {
+		return fmt.Errorf("序列化开放规则失败: %w", err)
+	}
+	blackouts := s.Schedule.BlackoutDates
+	if blackouts == nil {
+		blackouts = []string{}
+	}
+	blacks, err := json.Marshal(blackouts)
+	if err != nil {
+		return fmt.Errorf("序列化停开日期失败: %w", err)
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+INSERT INTO game_config.dungeon_schedules (dungeon_id, timezone, rules, blackout_dates, is_active)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (dungeon_id) DO UPDATE SET
+	timezone       = EXCLUDED.timezone,
+	rules          = EXCLUDED.rules,
+	blackout_dates = EXCLUDED.blackout_dates,
+	is_active      = EXCLUDED.is_active
+RETURNING updated_at
+`, s.DungeonID, s.Schedule.Timezone, rules, blacks, s.IsActive).Scan(&s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("保存地城开放时间表失败: %w", err)
+	}
+	return nil
+}
+
+func (r *dungeonScheduleRepositoryImpl) Delete(ctx context.Context, dungeonID string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM game_config.dungeon_schedules WHERE dungeon_id::text = $1`, dungeonID); err != nil {
+		return fmt.Errorf("删除地城开放时间表失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"tsu-self/internal/repository/interfaces"
+)
+
+type equipmentDurabilityConfigRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewEquipmentDurabilityConfigRepository 创建装备耐久配置仓储实例
+func NewEquipmentDurabilityConfigRepository(db *sql.DB) interfaces.EquipmentDurabilityConfigRepository {
+	return &equipmentDurabilityConfigRepositoryImpl{db: db}
+}
+
+func (r *equipmentDurabilityConfigRepositoryImpl) GetWearConfig(ctx context.Context, dungeonID string) (*interfaces.DurabilityWearConfig, error) {
+	var (
+		config    interfaces.DurabilityWearConfig
+		dungeonFK sql.NullString
+	)
+	err := r.db.QueryRowContext(ctx, `
+SELECT dungeon_id, victory_loss, defeat_loss, death_extra_loss
+FROM game_config.dungeon_durability_configs
+WHERE dungeon_id IS NULL OR dungeon_id::text = $1
+ORDER BY dungeon_id NULLS LAST
+LIMIT 1
+`, dungeonID).Scan(&dungeonFK, &config.VictoryLoss, &config.DefeatLoss, &config.DeathExtraLoss)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询耐久磨损配置失败: %w", err)
+	}
+	if dungeonFK.Valid {
+		config.DungeonID = &dungeonFK.String
+	}
+	return &config, nil
+}
+
+func (r *equipmentDurabilityConfigRepositoryImpl) GetRepairGoldPerPoint(ctx context.Context, quality string) (int, bool, error) {
+	var goldPerPoint int
+	err := r.db.QueryRowContext(ctx, `
+SELECT gold_per_point FROM game_config.equipment_repair_costs WHERE item_quality::text = $1
+`, quality).Scan(&goldPerPoint)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("查询修理价格失败: %w", err)
+	}
+	return goldPerPoint, true, nil
+}
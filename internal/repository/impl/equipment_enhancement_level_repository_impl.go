@@ -0,0 +1,75 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"tsu-self/internal/repository/interfaces"
+)
+
+type equipmentEnhancementLevelRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewEquipmentEnhancementLevelRepository 创建装备强化等级配置仓储实例
+func NewEquipmentEnhancementLevelRepository(db *sql.DB) interfaces.EquipmentEnhancementLevelRepository {
+	return &equipmentEnhancementLevelRepositoryImpl{db: db}
+}
+
+const equipmentEnhancementLevelColumns = `id, target_level, success_rate, downgrade_rate, downgrade_levels, material_quantity, gold_multiplier`
+
+type enhancementLevelScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEquipmentEnhancementLevel(row enhancementLevelScanner) (*interfaces.EquipmentEnhancementLevel, error) {
+	var level interfaces.EquipmentEnhancementLevel
+	if err := row.Scan(&level.ID, &level.TargetLevel, &level.SuccessRate, &level.DowngradeRate,
+		&level.DowngradeLevels, &level.MaterialQuantity, &level.GoldMultiplier); err != nil {
+		return nil, err
+	}
+	return &level, nil
+}
+
+func (r *equipmentEnhancementLevelRepositoryImpl) GetByTargetLevel(ctx context.Context, targetLevel int) (*interfaces.EquipmentEnhancementLevel, error) {
+	row := r.db.QueryRowContext(ctx, `
+SELECT `+equipmentEnhancementLevelColumns+`
+FROM game_config.equipment_enhancement_levels
+WHERE target_level = $1 AND is_active = TRUE
+`, targetLevel)
+	level, err := scanEquipmentEnhancementLevel(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询强化等级配置失败: %w", err)
+	}
+	return level, nil
+}
+
+func (r *equipmentEnhancementLevelRepositoryImpl) List(ctx context.Context) ([]*interfaces.EquipmentEnhancementLevel, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT `+equipmentEnhancementLevelColumns+`
+FROM game_config.equipment_enhancement_levels
+WHERE is_active = TRUE
+ORDER BY target_level
+`)
+	if err != nil {
+		return nil, fmt.Errorf("查询强化等级配置失败: %w", err)
+	}
+	defer rows.Close()
+
+	var levels []*interfaces.EquipmentEnhancementLevel
+	for rows.Next() {
+		level, err := scanEquipmentEnhancementLevel(rows)
+		if err != nil {
+			return nil, fmt.Errorf("解析强化等级配置失败: %w", err)
+		}
+		levels = append(levels, level)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历强化等级配置失败: %w", err)
+	}
+	return levels, nil
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/aarondl/sqlboiler/v4/queries/qm"
+	"github.com/lib/pq"
 
 	"tsu-self/internal/entity/game_config"
 	"tsu-self/internal/entity/game_runtime"
@@ -189,3 +190,63 @@ func (r *equipmentSlotRepositoryImpl) GetSlotsAddedByItem(ctx context.Context, i
 	return slots, nil
 }
 
+
+// ListSlotsByTypeForUpdate 查询英雄特定类型的槽位（带行锁）
+func (r *equipmentSlotRepositoryImpl) ListSlotsByTypeForUpdate(ctx context.Context, tx *sql.Tx, heroID string, slotType string) ([]*game_runtime.HeroEquipmentSlot, error) {
+	slots, err := game_runtime.HeroEquipmentSlots(
+		qm.Where("hero_id = ? AND slot_type = ?", heroID, slotType),
+		qm.OrderBy("slot_index"),
+		qm.For("UPDATE"),
+	).All(ctx, tx)
+
+	if err != nil {
+		return nil, fmt.Errorf("查询特定类型槽位失败（带锁）: %w", err)
+	}
+
+	return slots, nil
+}
+
+// GetSlotByEquippedItem 查询装备所在槽位
+func (r *equipmentSlotRepositoryImpl) GetSlotByEquippedItem(ctx context.Context, execer boil.ContextExecutor, itemInstanceID string) (*game_runtime.HeroEquipmentSlot, error) {
+	if execer == nil {
+		execer = r.db
+	}
+
+	slot, err := game_runtime.HeroEquipmentSlots(
+		qm.Where("equipped_item_id = ?", itemInstanceID),
+	).One(ctx, execer)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询装备所在槽位失败: %w", err)
+	}
+
+	return slot, nil
+}
+
+// CountEquippedByItemConfig 统计指定英雄范围内已穿戴某装备配置的数量
+func (r *equipmentSlotRepositoryImpl) CountEquippedByItemConfig(ctx context.Context, execer boil.ContextExecutor, itemConfigID string, heroIDs []string, excludeSlotIDs []string) (int64, error) {
+	if len(heroIDs) == 0 {
+		return 0, nil
+	}
+	if execer == nil {
+		execer = r.db
+	}
+
+	query := `
+		SELECT COUNT(*)
+		FROM game_runtime.hero_equipment_slots s
+		JOIN game_runtime.player_items pi ON pi.id = s.equipped_item_id AND pi.deleted_at IS NULL
+		WHERE pi.item_id = $1
+		  AND s.hero_id = ANY($2)
+		  AND NOT (s.id = ANY($3))`
+
+	var count int64
+	if err := execer.QueryRowContext(ctx, query, itemConfigID, pq.Array(heroIDs), pq.Array(excludeSlotIDs)).Scan(&count); err != nil {
+		return 0, fmt.Errorf("统计已穿戴装备失败: %w", err)
+	}
+
+	return count, nil
+}
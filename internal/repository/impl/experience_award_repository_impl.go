@@ -0,0 +1,119 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"tsu-self/internal/repository/interfaces"
+)
+
+type experienceAwardRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewExperienceAwardRepository 创建经验发放仓储实例
+func NewExperienceAwardRepository(db *sql.DB) interfaces.ExperienceAwardRepository {
+	return &experienceAwardRepositoryImpl{db: db}
+}
+
+func (r *experienceAwardRepositoryImpl) GetRule(ctx context.Context, sourceType string) (*interfaces.ExperienceAwardRule, error) {
+	var rule interfaces.ExperienceAwardRule
+	err := r.db.QueryRowContext(ctx, `
+SELECT source_type, split_mode, level_penalty_grace, level_penalty_step, level_penalty_floor,
+       rested_bonus_rate, rested_xp_per_hour, rested_xp_cap
+FROM game_config.experience_award_rules
+WHERE source_type = $1
+`, sourceType).Scan(&rule.SourceType, &rule.SplitMode, &rule.LevelPenaltyGrace, &rule.LevelPenaltyStep, &rule.LevelPenaltyFloor,
+		&rule.RestedBonusRate, &rule.RestedXPPerHour, &rule.RestedXPCap)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询经验分配规则失败: %w", err)
+	}
+	return &rule, nil
+}
+
+func (r *experienceAwardRepositoryImpl) GetRestedForUpdate(ctx context.Context, tx *sql.Tx, heroID string) (*interfaces.HeroRestedExperience, error) {
+	var rested interfaces.HeroRestedExperience
+	err := tx.QueryRowContext(ctx, `
+SELECT hero_id, rested_xp, accrued_at
+FROM game_runtime.hero_rested_experience
+WHERE hero_id = $1
+FOR UPDATE
+`, heroID).Scan(&rested.HeroID, &rested.RestedXP, &rested.AccruedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询休息经验失败: %w", err)
+	}
+	return &rested, nil
+}
+
+func (r *experienceAwardRepositoryImpl) SaveRested(ctx context.Context, tx *sql.Tx, rested *interfaces.HeroRestedExperience) error {
+	_, err := tx.ExecContext(ctx, `
+INSERT INTO game_runtime.hero_rested_experience (hero_id, rested_xp, accrued_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (hero_id) DO UPDATE SET rested_xp = EXCLUDED.rested_xp, accrued_at = EXCLUDED.accrued_at
+`, rested.HeroID, rested.RestedXP, rested.AccruedAt)
+	if err != nil {
+		return fmt.Errorf("更新休息经验失败: %w", err)
+	}
+	return nil
+}
+
+func (r *experienceAwardRepositoryImpl) ClaimAward(ctx context.Context, tx *sql.Tx, record *interfaces.ExperienceAwardRecord) (bool, error) {
+	err := tx.QueryRowContext(ctx, `
+INSERT INTO game_runtime.hero_experience_awards
+    (hero_id, source_type, source_id, hero_level, base_xp, penalty_rate, rested_bonus, awarded_xp)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (source_type, source_id, hero_id) DO NOTHING
+RETURNING id, created_at
+`, record.HeroID, record.SourceType, record.SourceID, record.HeroLevel, record.BaseXP, record.PenaltyRate,
+		record.RestedBonus, record.AwardedXP).Scan(&record.ID, &record.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("写入经验发放记录失败: %w", err)
+	}
+	return true, nil
+}
+
+func (r *experienceAwardRepositoryImpl) ListAwards(ctx context.Context, heroID string, limit, offset int) ([]*interfaces.ExperienceAwardRecord, int64, error) {
+	var total int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM game_runtime.hero_experience_awards WHERE hero_id = $1`, heroID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("统计经验发放记录失败: %w", err)
+	}
+
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id, hero_id, source_type, source_id, hero_level, base_xp, penalty_rate, rested_bonus, awarded_xp, created_at
+FROM game_runtime.hero_experience_awards
+WHERE hero_id = $1
+ORDER BY created_at DESC, id
+LIMIT $2 OFFSET $3
+`, heroID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询经验发放记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*interfaces.ExperienceAwardRecord
+	for rows.Next() {
+		var record interfaces.ExperienceAwardRecord
+		if err := rows.Scan(&record.ID, &record.HeroID, &record.SourceType, &record.SourceID, &record.HeroLevel,
+			&record.BaseXP, &record.PenaltyRate, &record.RestedBonus, &record.AwardedXP, &record.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("扫描经验发放记录失败: %w", err)
+		}
+		result = append(result, &record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("遍历经验发放记录失败: %w", err)
+	}
+	return result, total, nil
+}
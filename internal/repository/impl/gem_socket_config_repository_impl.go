@@ -0,0 +1,45 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+
+	"tsu-self/internal/entity/game_config"
+	"tsu-self/internal/repository/interfaces"
+)
+
+type gemSocketConfigRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewGemSocketConfigRepository 创建宝石镶嵌配置仓储实例
+func NewGemSocketConfigRepository(db *sql.DB) interfaces.GemSocketConfigRepository {
+	return &gemSocketConfigRepositoryImpl{db: db}
+}
+
+func (r *gemSocketConfigRepositoryImpl) GetSocketTypeByCode(ctx context.Context, code string) (*game_config.SocketTypeConfig, error) {
+	config, err := game_config.SocketTypeConfigs(
+		qm.Where("socket_type_code = ? AND deleted_at IS NULL", code),
+	).One(ctx, r.db)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询孔位类型配置失败: %w", err)
+	}
+	return config, nil
+}
+
+func (r *gemSocketConfigRepositoryImpl) ListActiveGemEffects(ctx context.Context) ([]*game_config.GemEffectConfig, error) {
+	configs, err := game_config.GemEffectConfigs(
+		qm.Where("is_active = TRUE AND deleted_at IS NULL"),
+		qm.OrderBy("created_at"),
+	).All(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("查询宝石效果配置失败: %w", err)
+	}
+	return configs, nil
+}
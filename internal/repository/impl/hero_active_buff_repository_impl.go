@@ -0,0 +1,107 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/google/uuid"
+
+	"tsu-self/internal/repository/interfaces"
+)
+
+type heroActiveBuffRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewHeroActiveBuffRepository 创建英雄 Buff 仓储实例
+func NewHeroActiveBuffRepository(db *sql.DB) interfaces.HeroActiveBuffRepository {
+	return &heroActiveBuffRepositoryImpl{db: db}
+}
+
+func (r *heroActiveBuffRepositoryImpl) Create(ctx context.Context, execer boil.ContextExecutor, buff *interfaces.HeroActiveBuff) error {
+	if buff == nil {
+		return fmt.Errorf("buff 不能为空")
+	}
+	if execer == nil {
+		execer = r.db
+	}
+	if buff.ID == "" {
+		buff.ID = uuid.NewString()
+	}
+	if buff.CasterLevel < 1 {
+		buff.CasterLevel = 1
+	}
+	params := buff.BuffParams
+	if len(params) == 0 {
+		params = json.RawMessage(`{}`)
+	}
+
+	err := execer.QueryRowContext(ctx, `
+INSERT INTO game_runtime.hero_active_buffs
+    (id, hero_id, buff_id, buff_code, buff_params, caster_level, source_type, source_id, progress_id, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING created_at
+`, buff.ID, buff.HeroID, buff.BuffID, buff.BuffCode, []byte(params), buff.CasterLevel,
+		buff.SourceType, buff.SourceID, buff.ProgressID, buff.ExpiresAt).Scan(&buff.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("写入英雄Buff失败: %w", err)
+	}
+	return nil
+}
+
+func (r *heroActiveBuffRepositoryImpl) ListActiveByHero(ctx context.Context, heroID string) ([]*interfaces.HeroActiveBuff, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT b.id, b.hero_id, b.buff_id, b.buff_code, b.buff_params, b.caster_level,
+       b.source_type, b.source_id, b.progress_id, b.expires_at, b.created_at
+FROM game_runtime.hero_active_buffs b
+LEFT JOIN game_runtime.team_dungeon_progress p ON p.id = b.progress_id
+WHERE b.hero_id = $1
+  AND (b.expires_at IS NULL OR b.expires_at > NOW())
+  AND (b.progress_id IS NULL OR p.status = 'in_progress')
+ORDER BY b.created_at
+`, heroID)
+	if err != nil {
+		return nil, fmt.Errorf("查询英雄Buff失败: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*interfaces.HeroActiveBuff
+	for rows.Next() {
+		var (
+			buff       interfaces.HeroActiveBuff
+			buffID     sql.NullString
+			sourceID   sql.NullString
+			progressID sql.NullString
+			expiresAt  sql.NullTime
+			params     []byte
+		)
+		if err := rows.Scan(&buff.ID, &buff.HeroID, &buffID, &buff.BuffCode, &params, &buff.CasterLevel,
+			&buff.SourceType, &sourceID, &progressID, &expiresAt, &buff.CreatedAt); err != nil {
+			return nil, fmt.Errorf("解析英雄Buff失败: %w", err)
+		}
+		buff.BuffParams = params
+		buff.BuffID = nullStringPtr(buffID)
+		buff.SourceID = nullStringPtr(sourceID)
+		buff.ProgressID = nullStringPtr(progressID)
+		if expiresAt.Valid {
+			t := expiresAt.Time
+			buff.ExpiresAt = &t
+		}
+		result = append(result, &buff)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历英雄Buff失败: %w", err)
+	}
+	return result, nil
+}
+
+func nullStringPtr(val sql.NullString) *string {
+	if !val.Valid {
+		return nil
+	}
+	s := val.String
+	return &s
+}
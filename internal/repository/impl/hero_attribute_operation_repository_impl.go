@@ -83,6 +83,18 @@ func (r *heroAttributeOperationRepositoryImpl) MarkAsRolledBack(ctx context.Cont
 	return nil
 }
 
+// ExpireRollbackable 使英雄所有尚可回退的属性操作立即过期
+func (r *heroAttributeOperationRepositoryImpl) ExpireRollbackable(ctx context.Context, execer boil.ContextExecutor, heroID string) error {
+	now := time.Now()
+	_, err := game_runtime.HeroAttributeOperations(
+		qm.Where("hero_id = ? AND rolled_back_at IS NULL AND rollback_deadline > ?", heroID, now),
+	).UpdateAll(ctx, execer, game_runtime.M{"rollback_deadline": now})
+	if err != nil {
+		return fmt.Errorf("使属性操作过期失败: %w", err)
+	}
+	return nil
+}
+
 // GetTotalSpentXP 获取英雄在所有属性上花费的总经验（未回退的）
 func (r *heroAttributeOperationRepositoryImpl) GetTotalSpentXP(ctx context.Context, heroID string) (int, error) {
 	type Result struct {
@@ -0,0 +1,195 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+
+	"tsu-self/internal/repository/interfaces"
+)
+
+type heroCurrencyRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewHeroCurrencyRepository 创建英雄货币账本仓储实例
+func NewHeroCurrencyRepository(db *sql.DB) interfaces.HeroCurrencyRepository {
+	return &heroCurrencyRepositoryImpl{db: db}
+}
+
+func (r *heroCurrencyRepositoryImpl) ApplyTx(ctx context.Context, execer boil.ContextExecutor, txn *interfaces.CurrencyTransaction) error {
+	if txn.HeroID == "" || txn.CurrencyCode == "" || txn.SourceType == "" {
+		return fmt.Errorf("hero_id、currency_code、source_type 不能为空")
+	}
+	if txn.Amount == 0 {
+		return fmt.Errorf("货币变动数量不能为 0")
+	}
+
+	// 1. 调整余额：金币沿用 hero_wallets，其余货币写入 hero_currency_balances；扣减使用条件更新避免透支
+	var query string
+	args := []interface{}{txn.HeroID, txn.Amount}
+	switch {
+	case txn.CurrencyCode == interfaces.CurrencyGold && txn.Amount > 0:
+		query = `
+INSERT INTO game_runtime.hero_wallets (hero_id, gold_amount)
+VALUES ($1, $2)
+ON CONFLICT (hero_id) DO UPDATE
+SET gold_amount = game_runtime.hero_wallets.gold_amount + $2,
+    updated_at = NOW()
+RETURNING gold_amount`
+	case txn.CurrencyCode == interfaces.CurrencyGold:
+		query = `
+UPDATE game_runtime.hero_wallets
+SET gold_amount = gold_amount + $2,
+    updated_at = NOW()
+WHERE hero_id = $1 AND gold_amount + $2 >= 0
+RETURNING gold_amount`
+	case txn.Amount > 0:
+		query = `
+INSERT INTO game_runtime.hero_currency_balances (hero_id, currency_code, balance)
+VALUES ($1, $3, $2)
+ON CONFLICT (hero_id, currency_code) DO UPDATE
+SET balance = game_runtime.hero_currency_balances.balance + $2
+RETURNING balance`
+		args = append(args, txn.CurrencyCode)
+	default:
+		query = `
+UPDATE game_runtime.hero_currency_balances
+SET balance = balance + $2
+WHERE hero_id = $1 AND currency_code = $3 AND balance + $2 >= 0
+RETURNING balance`
+		args = append(args, txn.CurrencyCode)
+	}
+
+	err := execer.QueryRowContext(ctx, query, args...).Scan(&txn.BalanceAfter)
+	if err == sql.ErrNoRows {
+		return interfaces.ErrInsufficientCurrency
+	}
+	if err != nil {
+		return fmt.Errorf("更新货币余额失败: %w", err)
+	}
+
+	// 2. 追加流水
+	metadata := []byte(txn.Metadata)
+	if len(metadata) == 0 {
+		metadata = []byte("{}")
+	}
+	err = execer.QueryRowContext(ctx, `
+INSERT INTO game_runtime.hero_currency_transactions
+    (hero_id, currency_code, amount, balance_after, source_type, source_id, note, metadata)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, created_at
+`, txn.HeroID, txn.CurrencyCode, txn.Amount, txn.BalanceAfter, txn.SourceType, txn.SourceID, txn.Note, metadata).Scan(&txn.ID, &txn.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("写入货币流水失败: %w", err)
+	}
+	return nil
+}
+
+func (r *heroCurrencyRepositoryImpl) GetBalance(ctx context.Context, heroID, currencyCode string) (int64, error) {
+	query := `SELECT balance FROM game_runtime.hero_currency_balances WHERE hero_id = $1 AND currency_code = $2`
+	args := []interface{}{heroID, currencyCode}
+	if currencyCode == interfaces.CurrencyGold {
+		query = `SELECT gold_amount FROM game_runtime.hero_wallets WHERE hero_id = $1`
+		args = args[:1]
+	}
+
+	var balance int64
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("查询货币余额失败: %w", err)
+	}
+	return balance, nil
+}
+
+func (r *heroCurrencyRepositoryImpl) GetBalances(ctx context.Context, heroID string) (map[string]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT 'gold', gold_amount FROM game_runtime.hero_wallets WHERE hero_id = $1
+UNION ALL
+SELECT currency_code, balance FROM game_runtime.hero_currency_balances WHERE hero_id = $1
+`, heroID)
+	if err != nil {
+		return nil, fmt.Errorf("查询货币余额失败: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]int64)
+	for rows.Next() {
+		var (
+			code    string
+			balance int64
+		)
+		if err := rows.Scan(&code, &balance); err != nil {
+			return nil, fmt.Errorf("扫描货币余额失败: %w", err)
+		}
+		result[code] = balance
+	}
+	return result, rows.Err()
+}
+
+func (r *heroCurrencyRepositoryImpl) ListTransactions(ctx context.Context, query interfaces.CurrencyTransactionQuery) ([]*interfaces.CurrencyTransaction, int64, error) {
+	conditions := []string{"hero_id = $1"}
+	args := []interface{}{query.HeroID}
+	if query.CurrencyCode != "" {
+		args = append(args, query.CurrencyCode)
+		conditions = append(conditions, fmt.Sprintf("currency_code = $%d", len(args)))
+	}
+	if query.SourceType != "" {
+		args = append(args, query.SourceType)
+		conditions = append(conditions, fmt.Sprintf("source_type = $%d", len(args)))
+	}
+	if query.SourceID != "" {
+		args = append(args, query.SourceID)
+		conditions = append(conditions, fmt.Sprintf("source_id = $%d", len(args)))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM game_runtime.hero_currency_transactions WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("统计货币流水失败: %w", err)
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	args = append(args, limit, query.Offset)
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+SELECT id, hero_id, currency_code, amount, balance_after, source_type, source_id, note, metadata, created_at
+FROM game_runtime.hero_currency_transactions
+WHERE %s
+ORDER BY created_at DESC, id
+LIMIT $%d OFFSET $%d
+`, where, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询货币流水失败: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*interfaces.CurrencyTransaction
+	for rows.Next() {
+		var (
+			txn            interfaces.CurrencyTransaction
+			sourceID, note sql.NullString
+			metadata       []byte
+		)
+		if err := rows.Scan(&txn.ID, &txn.HeroID, &txn.CurrencyCode, &txn.Amount, &txn.BalanceAfter, &txn.SourceType, &sourceID, &note, &metadata, &txn.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("扫描货币流水失败: %w", err)
+		}
+		if sourceID.Valid {
+			txn.SourceID = &sourceID.String
+		}
+		if note.Valid {
+			txn.Note = &note.String
+		}
+		txn.Metadata = metadata
+		result = append(result, &txn)
+	}
+	return result, total, rows.Err()
+}
@@ -0,0 +1,111 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"tsu-self/internal/repository/interfaces"
+)
+
+type heroRespecRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewHeroRespecRepository 创建英雄洗点仓储实例
+func NewHeroRespecRepository(db *sql.DB) interfaces.HeroRespecRepository {
+	return &heroRespecRepositoryImpl{db: db}
+}
+
+func (r *heroRespecRepositoryImpl) GetCostForLevel(ctx context.Context, level int) (*interfaces.HeroRespecCost, error) {
+	var (
+		cost   interfaces.HeroRespecCost
+		itemID sql.NullString
+	)
+	err := r.db.QueryRowContext(ctx, `
+SELECT min_level, cost_gold, item_id, item_count
+FROM game_config.hero_respec_costs
+WHERE min_level <= $1
+ORDER BY min_level DESC
+LIMIT 1
+`, level).Scan(&cost.MinLevel, &cost.CostGold, &itemID, &cost.ItemCount)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询洗点消耗配置失败: %w", err)
+	}
+	if itemID.Valid {
+		cost.ItemID = &itemID.String
+	}
+	return &cost, nil
+}
+
+func (r *heroRespecRepositoryImpl) CreateOperation(ctx context.Context, tx *sql.Tx, op *interfaces.HeroRespecOperation) error {
+	itemsSpent := op.ItemsSpent
+	if len(itemsSpent) == 0 {
+		itemsSpent = []byte("[]")
+	}
+	snapshot := op.Snapshot
+	if len(snapshot) == 0 {
+		snapshot = []byte("{}")
+	}
+	err := tx.QueryRowContext(ctx, `
+INSERT INTO game_runtime.hero_respec_operations
+    (id, hero_id, payment_method, gold_spent, item_id, items_spent, refunded_xp,
+     attributes_reset, skills_removed, skills_reset, snapshot)
+VALUES (COALESCE(NULLIF($1, '')::uuid, gen_random_uuid()), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id, created_at
+`, op.ID, op.HeroID, op.PaymentMethod, op.GoldSpent, op.ItemID, []byte(itemsSpent), op.RefundedXP,
+		op.AttributesReset, op.SkillsRemoved, op.SkillsReset, []byte(snapshot)).Scan(&op.ID, &op.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("写入洗点记录失败: %w", err)
+	}
+	return nil
+}
+
+func (r *heroRespecRepositoryImpl) ListOperations(ctx context.Context, heroID string, limit, offset int) ([]*interfaces.HeroRespecOperation, int64, error) {
+	var total int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM game_runtime.hero_respec_operations WHERE hero_id = $1`, heroID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("统计洗点记录失败: %w", err)
+	}
+
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id, hero_id, payment_method, gold_spent, item_id, items_spent, refunded_xp,
+       attributes_reset, skills_removed, skills_reset, snapshot, created_at
+FROM game_runtime.hero_respec_operations
+WHERE hero_id = $1
+ORDER BY created_at DESC, id
+LIMIT $2 OFFSET $3
+`, heroID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询洗点记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*interfaces.HeroRespecOperation
+	for rows.Next() {
+		var (
+			op                   interfaces.HeroRespecOperation
+			itemID               sql.NullString
+			itemsSpent, snapshot []byte
+		)
+		if err := rows.Scan(&op.ID, &op.HeroID, &op.PaymentMethod, &op.GoldSpent, &itemID, &itemsSpent, &op.RefundedXP,
+			&op.AttributesReset, &op.SkillsRemoved, &op.SkillsReset, &snapshot, &op.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("扫描洗点记录失败: %w", err)
+		}
+		if itemID.Valid {
+			op.ItemID = &itemID.String
+		}
+		op.ItemsSpent = itemsSpent
+		op.Snapshot = snapshot
+		result = append(result, &op)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("遍历洗点记录失败: %w", err)
+	}
+	return result, total, nil
+}
@@ -83,6 +83,18 @@ func (r *heroSkillOperationRepositoryImpl) MarkAsRolledBack(ctx context.Context,
 	return nil
 }
 
+// ExpireRollbackable 使技能所有尚可回退的操作立即过期
+func (r *heroSkillOperationRepositoryImpl) ExpireRollbackable(ctx context.Context, execer boil.ContextExecutor, heroSkillID string) error {
+	now := time.Now()
+	_, err := game_runtime.HeroSkillOperations(
+		qm.Where("hero_skill_id = ? AND rolled_back_at IS NULL AND rollback_deadline > ?", heroSkillID, now),
+	).UpdateAll(ctx, execer, game_runtime.M{"rollback_deadline": now})
+	if err != nil {
+		return fmt.Errorf("使技能操作过期失败: %w", err)
+	}
+	return nil
+}
+
 // GetTotalSpentXPByHeroID 获取英雄在所有技能上花费的总经验（未回退的）
 func (r *heroSkillOperationRepositoryImpl) GetTotalSpentXPByHeroID(ctx context.Context, heroID string) (int, error) {
 	type Result struct {
@@ -0,0 +1,49 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+
+	"tsu-self/internal/entity/game_runtime"
+	"tsu-self/internal/repository/interfaces"
+)
+
+type itemOperationLogRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewItemOperationLogRepository 创建物品操作日志仓储实例
+func NewItemOperationLogRepository(db *sql.DB) interfaces.ItemOperationLogRepository {
+	return &itemOperationLogRepositoryImpl{db: db}
+}
+
+// Create 写入操作日志
+func (r *itemOperationLogRepositoryImpl) Create(ctx context.Context, execer boil.ContextExecutor, log *game_runtime.ItemOperationLog) error {
+	if execer == nil {
+		execer = r.db
+	}
+	if err := log.Insert(ctx, execer, boil.Infer()); err != nil {
+		return fmt.Errorf("写入物品操作日志失败: %w", err)
+	}
+	return nil
+}
+
+// ListByItemInstance 查询物品实例的操作日志
+func (r *itemOperationLogRepositoryImpl) ListByItemInstance(ctx context.Context, itemInstanceID string, limit int) ([]*game_runtime.ItemOperationLog, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	logs, err := game_runtime.ItemOperationLogs(
+		qm.Where("item_instance_id = ?", itemInstanceID),
+		qm.OrderBy("operated_at DESC"),
+		qm.Limit(limit),
+	).All(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("查询物品操作日志失败: %w", err)
+	}
+	return logs, nil
+}
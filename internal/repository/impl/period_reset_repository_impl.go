@@ -0,0 +1,61 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"tsu-self/internal/repository/interfaces"
+)
+
+type periodResetRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewPeriodResetRepository 创建周期重置记录仓储实例
+func NewPeriodResetRepository(db *sql.DB) interfaces.PeriodResetRepository {
+	return &periodResetRepositoryImpl{db: db}
+}
+
+func (r *periodResetRepositoryImpl) ClaimPeriod(ctx context.Context, tx *sql.Tx, resetKey string, periodStart time.Time) (bool, error) {
+	result, err := tx.ExecContext(ctx, `
+INSERT INTO game_runtime.period_reset_runs (reset_key, period_start)
+VALUES ($1, $2)
+ON CONFLICT (reset_key, period_start) DO NOTHING
+`, resetKey, periodStart)
+	if err != nil {
+		return false, fmt.Errorf("登记周期重置失败: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("登记周期重置失败: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func (r *periodResetRepositoryImpl) FinishPeriod(ctx context.Context, tx *sql.Tx, resetKey string, periodStart time.Time, affected int64) error {
+	_, err := tx.ExecContext(ctx, `
+UPDATE game_runtime.period_reset_runs
+SET affected_rows = $3, executed_at = NOW()
+WHERE reset_key = $1 AND period_start = $2
+`, resetKey, periodStart, affected)
+	if err != nil {
+		return fmt.Errorf("更新周期重置记录失败: %w", err)
+	}
+	return nil
+}
+
+func (r *periodResetRepositoryImpl) GetLastPeriod(ctx context.Context, resetKey string) (*time.Time, error) {
+	var last sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+SELECT MAX(period_start) FROM game_runtime.period_reset_runs WHERE reset_key = $1
+`, resetKey).Scan(&last)
+	if err != nil {
+		return nil, fmt.Errorf("查询周期重置记录失败: %w", err)
+	}
+	if !last.Valid {
+		return nil, nil
+	}
+	return &last.Time, nil
+}
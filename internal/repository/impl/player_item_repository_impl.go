@@ -8,6 +8,7 @@ import (
 	"github.com/aarondl/null/v8"
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/aarondl/sqlboiler/v4/queries/qm"
+	"github.com/lib/pq"
 
 	"tsu-self/internal/entity/game_runtime"
 	"tsu-self/internal/repository/interfaces"
@@ -63,6 +64,81 @@ func (r *playerItemRepositoryImpl) GetByIDForUpdate(ctx context.Context, tx *sql
 	return item, nil
 }
 
+// ListBackpackStacksForUpdate 锁定英雄背包中指定物品配置的全部堆叠
+func (r *playerItemRepositoryImpl) ListBackpackStacksForUpdate(ctx context.Context, tx *sql.Tx, heroID, itemID string) ([]*game_runtime.PlayerItem, error) {
+	items, err := game_runtime.PlayerItems(
+		qm.Where("hero_id = ? AND item_id = ? AND item_location = ? AND deleted_at IS NULL", heroID, itemID, "backpack"),
+		qm.OrderBy("COALESCE(stack_count, 1) ASC, created_at ASC"),
+		qm.For("UPDATE"),
+	).All(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("查询背包物品堆叠失败（带锁）: %w", err)
+	}
+	return items, nil
+}
+
+// ListEquippedByItemForUpdate 锁定英雄已穿戴的指定物品配置实例
+func (r *playerItemRepositoryImpl) ListEquippedByItemForUpdate(ctx context.Context, tx *sql.Tx, heroID, itemID string) ([]*game_runtime.PlayerItem, error) {
+	items, err := game_runtime.PlayerItems(
+		qm.Where("hero_id = ? AND item_id = ? AND item_location = ? AND deleted_at IS NULL", heroID, itemID, "equipped"),
+		qm.OrderBy("created_at ASC"),
+		qm.For("UPDATE"),
+	).All(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("查询已穿戴物品失败（带锁）: %w", err)
+	}
+	return items, nil
+}
+
+// CountHeroItems 统计英雄在指定位置中指定物品配置的数量
+func (r *playerItemRepositoryImpl) CountHeroItems(ctx context.Context, heroID string, itemIDs []string, locations []string) (map[string]int, error) {
+	result := make(map[string]int, len(itemIDs))
+	if len(itemIDs) == 0 || len(locations) == 0 {
+		return result, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT item_id, COALESCE(SUM(COALESCE(stack_count, 1)), 0)
+FROM game_runtime.player_items
+WHERE hero_id = $1
+  AND item_id = ANY($2)
+  AND item_location = ANY($3)
+  AND deleted_at IS NULL
+GROUP BY item_id
+`, heroID, pq.Array(itemIDs), pq.Array(locations))
+	if err != nil {
+		return nil, fmt.Errorf("统计英雄物品数量失败: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			itemID string
+			count  int
+		)
+		if err := rows.Scan(&itemID, &count); err != nil {
+			return nil, fmt.Errorf("扫描英雄物品数量失败: %w", err)
+		}
+		result[itemID] = count
+	}
+	return result, rows.Err()
+}
+
+// GetByIDIncludingDeletedForUpdate 根据ID获取物品实例（含已软删除，带行锁）
+func (r *playerItemRepositoryImpl) GetByIDIncludingDeletedForUpdate(ctx context.Context, tx *sql.Tx, itemInstanceID string) (*game_runtime.PlayerItem, error) {
+	item, err := game_runtime.PlayerItems(
+		qm.Where("id = ?", itemInstanceID),
+		qm.For("UPDATE"),
+	).One(ctx, tx)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询物品实例失败（带锁）: %w", err)
+	}
+	return item, nil
+}
+
 // GetByOwner 查询玩家的装备实例列表
 func (r *playerItemRepositoryImpl) GetByOwner(ctx context.Context, ownerID string, location *string) ([]*game_runtime.PlayerItem, error) {
 	mods := []qm.QueryMod{
@@ -184,6 +260,43 @@ func (r *playerItemRepositoryImpl) Delete(ctx context.Context, itemInstanceID st
 	return nil
 }
 
+// WearEquippedDurability 扣减英雄已穿戴装备的耐久，未配置耐久或已损坏的装备不受影响
+func (r *playerItemRepositoryImpl) WearEquippedDurability(ctx context.Context, execer boil.ContextExecutor, heroID string, amount int) ([]interfaces.DurabilityUpdate, error) {
+	if amount <= 0 {
+		return nil, nil
+	}
+	if execer == nil {
+		execer = r.db
+	}
+	rows, err := execer.QueryContext(ctx, `
+UPDATE game_runtime.player_items
+SET current_durability = GREATEST(current_durability - $2, 0),
+    updated_at = NOW()
+WHERE hero_id = $1
+  AND item_location = 'equipped'
+  AND deleted_at IS NULL
+  AND current_durability > 0
+RETURNING id, current_durability
+`, heroID, amount)
+	if err != nil {
+		return nil, fmt.Errorf("扣减装备耐久失败: %w", err)
+	}
+	defer rows.Close()
+
+	var updates []interfaces.DurabilityUpdate
+	for rows.Next() {
+		var update interfaces.DurabilityUpdate
+		if err := rows.Scan(&update.ItemInstanceID, &update.Durability); err != nil {
+			return nil, fmt.Errorf("解析装备耐久失败: %w", err)
+		}
+		updates = append(updates, update)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历装备耐久失败: %w", err)
+	}
+	return updates, nil
+}
+
 // BatchUpdateDurability 批量更新装备耐久度
 func (r *playerItemRepositoryImpl) BatchUpdateDurability(ctx context.Context, execer boil.ContextExecutor, updates []interfaces.DurabilityUpdate) error {
 	if len(updates) == 0 {
@@ -0,0 +1,128 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/google/uuid"
+
+	"tsu-self/internal/repository/interfaces"
+)
+
+type teamActivityRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewTeamActivityRepository 创建团队动态仓储实例
+func NewTeamActivityRepository(db *sql.DB) interfaces.TeamActivityRepository {
+	return &teamActivityRepositoryImpl{db: db}
+}
+
+func (r *teamActivityRepositoryImpl) Create(ctx context.Context, execer boil.ContextExecutor, activity *interfaces.TeamActivity) error {
+	if activity == nil {
+		return fmt.Errorf("团队动态不能为空")
+	}
+	if execer == nil {
+		execer = r.db
+	}
+	if activity.ID == "" {
+		activity.ID = uuid.NewString()
+	}
+
+	err := execer.QueryRowContext(ctx, `
+INSERT INTO game_runtime.team_activity_logs (
+    id, team_id, category, event_type, actor_hero_id, target_hero_id, source_id, details
+) VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8, '{}'::jsonb))
+RETURNING created_at
+`, activity.ID, activity.TeamID, activity.Category, activity.EventType, activity.ActorHeroID, activity.TargetHeroID,
+		activity.SourceID, nullJSON(activity.Details)).Scan(&activity.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("写入团队动态失败: %w", err)
+	}
+	return nil
+}
+
+func (r *teamActivityRepositoryImpl) List(ctx context.Context, query interfaces.TeamActivityQuery) ([]*interfaces.TeamActivity, int64, error) {
+	limit, offset := query.Limit, query.Offset
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	const where = `
+WHERE team_id = $1
+  AND ($2::text = '' OR category = $2)
+  AND ($3::text = '' OR event_type = $3)
+  AND ($4::text = '' OR actor_hero_id::text = $4 OR target_hero_id::text = $4)
+  AND ($5::timestamptz IS NULL OR created_at >= $5)
+  AND ($6::timestamptz IS NULL OR created_at < $6)`
+	args := []interface{}{query.TeamID, query.Category, query.EventType, query.HeroID, query.Since, query.Until}
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM game_runtime.team_activity_logs`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("统计团队动态失败: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id, team_id, category, event_type, actor_hero_id, target_hero_id, source_id, details, created_at
+FROM game_runtime.team_activity_logs`+where+`
+ORDER BY created_at DESC, id DESC
+LIMIT $7 OFFSET $8
+`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询团队动态失败: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*interfaces.TeamActivity
+	for rows.Next() {
+		var (
+			activity interfaces.TeamActivity
+			actorID  sql.NullString
+			targetID sql.NullString
+			sourceID sql.NullString
+			details  []byte
+		)
+		if err := rows.Scan(&activity.ID, &activity.TeamID, &activity.Category, &activity.EventType, &actorID, &targetID,
+			&sourceID, &details, &activity.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("扫描团队动态失败: %w", err)
+		}
+		if actorID.Valid {
+			activity.ActorHeroID = &actorID.String
+		}
+		if targetID.Valid {
+			activity.TargetHeroID = &targetID.String
+		}
+		if sourceID.Valid {
+			activity.SourceID = &sourceID.String
+		}
+		activity.Details = details
+		list = append(list, &activity)
+	}
+	return list, total, rows.Err()
+}
+
+func (r *teamActivityRepositoryImpl) DeleteBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	if limit <= 0 {
+		limit = 1000
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+DELETE FROM game_runtime.team_activity_logs
+WHERE id IN (
+    SELECT id FROM game_runtime.team_activity_logs
+    WHERE created_at < $1
+    ORDER BY created_at
+    LIMIT $2
+)
+`, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("清理过期团队动态失败: %w", err)
+	}
+	return result.RowsAffected()
+}
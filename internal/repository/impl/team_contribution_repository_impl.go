@@ -0,0 +1,411 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+
+	"tsu-self/internal/repository/interfaces"
+)
+
+type teamContributionRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewTeamContributionRepository 创建团队贡献点仓储实例
+func NewTeamContributionRepository(db *sql.DB) interfaces.TeamContributionRepository {
+	return &teamContributionRepositoryImpl{db: db}
+}
+
+const teamContributionAuctionColumns = `id, team_id, warehouse_id, item_id, quantity, min_bid, status, created_by, ends_at,
+       highest_bid, highest_bidder_hero_id, result_note, created_at, settled_at`
+
+func scanTeamContributionAuction(scanner interface{ Scan(dest ...any) error }) (*interfaces.TeamContributionAuction, error) {
+	var (
+		auction    interfaces.TeamContributionAuction
+		bidderID   sql.NullString
+		resultNote sql.NullString
+		settledAt  sql.NullTime
+	)
+	if err := scanner.Scan(&auction.ID, &auction.TeamID, &auction.WarehouseID, &auction.ItemID, &auction.Quantity,
+		&auction.MinBid, &auction.Status, &auction.CreatedBy, &auction.EndsAt, &auction.HighestBid, &bidderID,
+		&resultNote, &auction.CreatedAt, &settledAt); err != nil {
+		return nil, err
+	}
+	if bidderID.Valid {
+		auction.HighestBidderHeroID = &bidderID.String
+	}
+	if resultNote.Valid {
+		auction.ResultNote = &resultNote.String
+	}
+	if settledAt.Valid {
+		auction.SettledAt = &settledAt.Time
+	}
+	return &auction, nil
+}
+
+func (r *teamContributionRepositoryImpl) GetSettings(ctx context.Context, teamID string) (*interfaces.TeamContributionSettings, error) {
+	var settings interfaces.TeamContributionSettings
+	err := r.db.QueryRowContext(ctx, `
+SELECT team_id, dungeon_completion_points, auction_duration_seconds, updated_at
+FROM game_runtime.team_contribution_settings
+WHERE team_id = $1
+`, teamID).Scan(&settings.TeamID, &settings.DungeonCompletionPoints, &settings.AuctionDurationSeconds, &settings.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询贡献点配置失败: %w", err)
+	}
+	return &settings, nil
+}
+
+func (r *teamContributionRepositoryImpl) UpsertSettings(ctx context.Context, settings *interfaces.TeamContributionSettings) error {
+	err := r.db.QueryRowContext(ctx, `
+INSERT INTO game_runtime.team_contribution_settings (team_id, dungeon_completion_points, auction_duration_seconds)
+VALUES ($1, $2, $3)
+ON CONFLICT (team_id) DO UPDATE
+SET dungeon_completion_points = EXCLUDED.dungeon_completion_points,
+    auction_duration_seconds = EXCLUDED.auction_duration_seconds,
+    updated_at = NOW()
+RETURNING updated_at
+`, settings.TeamID, settings.DungeonCompletionPoints, settings.AuctionDurationSeconds).Scan(&settings.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("保存贡献点配置失败: %w", err)
+	}
+	return nil
+}
+
+func (r *teamContributionRepositoryImpl) ApplyTx(ctx context.Context, execer boil.ContextExecutor, entry *interfaces.TeamContributionEntry) error {
+	if entry.TeamID == "" || entry.HeroID == "" || entry.SourceType == "" {
+		return fmt.Errorf("team_id、hero_id、source_type 不能为空")
+	}
+	if entry.Amount == 0 {
+		return fmt.Errorf("贡献点变动数量不能为 0")
+	}
+	if execer == nil {
+		execer = r.db
+	}
+
+	// 1. 调整余额：增加时累计获得（退还不计入），扣减使用条件更新避免透支
+	var query string
+	args := []interface{}{entry.TeamID, entry.HeroID, entry.Amount}
+	if entry.Amount > 0 {
+		earned := entry.Amount
+		if entry.SourceType == interfaces.ContributionSourceAuctionRefund {
+			earned = 0
+		}
+		query = `
+INSERT INTO game_runtime.team_contribution_balances (team_id, hero_id, balance, lifetime_earned)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (team_id, hero_id) DO UPDATE
+SET balance = game_runtime.team_contribution_balances.balance + $3,
+    lifetime_earned = game_runtime.team_contribution_balances.lifetime_earned + $4,
+    updated_at = NOW()
+RETURNING balance`
+		args = append(args, earned)
+	} else {
+		query = `
+UPDATE game_runtime.team_contribution_balances
+SET balance = balance + $3,
+    updated_at = NOW()
+WHERE team_id = $1 AND hero_id = $2 AND balance + $3 >= 0
+RETURNING balance`
+	}
+
+	err := execer.QueryRowContext(ctx, query, args...).Scan(&entry.BalanceAfter)
+	if err == sql.ErrNoRows {
+		return interfaces.ErrInsufficientContribution
+	}
+	if err != nil {
+		return fmt.Errorf("更新贡献点余额失败: %w", err)
+	}
+
+	// 2. 追加流水
+	err = execer.QueryRowContext(ctx, `
+INSERT INTO game_runtime.team_contribution_ledger
+    (team_id, hero_id, amount, balance_after, source_type, source_id, operator_hero_id, note)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, created_at
+`, entry.TeamID, entry.HeroID, entry.Amount, entry.BalanceAfter, entry.SourceType, entry.SourceID,
+		entry.OperatorHeroID, entry.Note).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("写入贡献点流水失败: %w", err)
+	}
+	return nil
+}
+
+func (r *teamContributionRepositoryImpl) GetBalance(ctx context.Context, teamID, heroID string) (int64, error) {
+	var balance int64
+	err := r.db.QueryRowContext(ctx, `
+SELECT balance FROM game_runtime.team_contribution_balances WHERE team_id = $1 AND hero_id = $2
+`, teamID, heroID).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("查询贡献点余额失败: %w", err)
+	}
+	return balance, nil
+}
+
+func (r *teamContributionRepositoryImpl) ListBalances(ctx context.Context, teamID string) ([]*interfaces.TeamContributionBalance, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT team_id, hero_id, balance, lifetime_earned, updated_at
+FROM game_runtime.team_contribution_balances
+WHERE team_id = $1
+ORDER BY balance DESC, lifetime_earned DESC, hero_id
+`, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("查询贡献点排行失败: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*interfaces.TeamContributionBalance
+	for rows.Next() {
+		balance := &interfaces.TeamContributionBalance{}
+		if err := rows.Scan(&balance.TeamID, &balance.HeroID, &balance.Balance, &balance.LifetimeEarned, &balance.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("扫描贡献点余额失败: %w", err)
+		}
+		list = append(list, balance)
+	}
+	return list, rows.Err()
+}
+
+func (r *teamContributionRepositoryImpl) ListEntries(ctx context.Context, teamID, heroID string, limit, offset int) ([]*interfaces.TeamContributionEntry, int64, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM game_runtime.team_contribution_ledger WHERE team_id = $1 AND ($2::text = '' OR hero_id::text = $2)
+`, teamID, heroID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("统计贡献点流水失败: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id, team_id, hero_id, amount, balance_after, source_type, source_id, operator_hero_id, note, created_at
+FROM game_runtime.team_contribution_ledger
+WHERE team_id = $1 AND ($2::text = '' OR hero_id::text = $2)
+ORDER BY created_at DESC, id
+LIMIT $3 OFFSET $4
+`, teamID, heroID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询贡献点流水失败: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*interfaces.TeamContributionEntry
+	for rows.Next() {
+		var (
+			entry      interfaces.TeamContributionEntry
+			sourceID   sql.NullString
+			operatorID sql.NullString
+			note       sql.NullString
+		)
+		if err := rows.Scan(&entry.ID, &entry.TeamID, &entry.HeroID, &entry.Amount, &entry.BalanceAfter, &entry.SourceType,
+			&sourceID, &operatorID, &note, &entry.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("扫描贡献点流水失败: %w", err)
+		}
+		if sourceID.Valid {
+			entry.SourceID = &sourceID.String
+		}
+		if operatorID.Valid {
+			entry.OperatorHeroID = &operatorID.String
+		}
+		if note.Valid {
+			entry.Note = &note.String
+		}
+		list = append(list, &entry)
+	}
+	return list, total, rows.Err()
+}
+
+func (r *teamContributionRepositoryImpl) CreateAuction(ctx context.Context, execer boil.ContextExecutor, auction *interfaces.TeamContributionAuction) error {
+	if execer == nil {
+		execer = r.db
+	}
+	err := execer.QueryRowContext(ctx, `
+INSERT INTO game_runtime.team_contribution_auctions (team_id, warehouse_id, item_id, quantity, min_bid, status, created_by, ends_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, created_at
+`, auction.TeamID, auction.WarehouseID, auction.ItemID, auction.Quantity, auction.MinBid, auction.Status,
+		auction.CreatedBy, auction.EndsAt).Scan(&auction.ID, &auction.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("创建竞拍失败: %w", err)
+	}
+	return nil
+}
+
+func (r *teamContributionRepositoryImpl) GetAuctionForUpdate(ctx context.Context, tx *sql.Tx, auctionID string) (*interfaces.TeamContributionAuction, error) {
+	auction, err := scanTeamContributionAuction(tx.QueryRowContext(ctx, `
+SELECT `+teamContributionAuctionColumns+`
+FROM game_runtime.team_contribution_auctions
+WHERE id = $1
+FOR UPDATE
+`, auctionID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询竞拍失败: %w", err)
+	}
+	return auction, nil
+}
+
+func (r *teamContributionRepositoryImpl) UpdateAuction(ctx context.Context, execer boil.ContextExecutor, auction *interfaces.TeamContributionAuction) error {
+	if execer == nil {
+		execer = r.db
+	}
+	_, err := execer.ExecContext(ctx, `
+UPDATE game_runtime.team_contribution_auctions
+SET status = $2, highest_bid = $3, highest_bidder_hero_id = $4, result_note = $5, settled_at = $6
+WHERE id = $1
+`, auction.ID, auction.Status, auction.HighestBid, auction.HighestBidderHeroID, auction.ResultNote, auction.SettledAt)
+	if err != nil {
+		return fmt.Errorf("更新竞拍失败: %w", err)
+	}
+	return nil
+}
+
+func (r *teamContributionRepositoryImpl) SumOpenAuctionQuantity(ctx context.Context, execer boil.ContextExecutor, warehouseID, itemID, excludeAuctionID string) (int, error) {
+	if execer == nil {
+		execer = r.db
+	}
+	var quantity int
+	err := execer.QueryRowContext(ctx, `
+SELECT COALESCE(SUM(quantity), 0)
+FROM game_runtime.team_contribution_auctions
+WHERE warehouse_id = $1 AND item_id = $2 AND status IN ('open', 'settling') AND id::text <> $3
+`, warehouseID, itemID, excludeAuctionID).Scan(&quantity)
+	if err != nil {
+		return 0, fmt.Errorf("统计竞拍中物品数量失败: %w", err)
+	}
+	return quantity, nil
+}
+
+func (r *teamContributionRepositoryImpl) ListAuctions(ctx context.Context, teamID, status string, limit, offset int) ([]*interfaces.TeamContributionAuction, int64, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM game_runtime.team_contribution_auctions WHERE team_id = $1 AND ($2::text = '' OR status = $2)
+`, teamID, status).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("统计竞拍失败: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT `+teamContributionAuctionColumns+`
+FROM game_runtime.team_contribution_auctions
+WHERE team_id = $1 AND ($2::text = '' OR status = $2)
+ORDER BY created_at DESC
+LIMIT $3 OFFSET $4
+`, teamID, status, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询竞拍失败: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*interfaces.TeamContributionAuction
+	for rows.Next() {
+		auction, err := scanTeamContributionAuction(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("扫描竞拍失败: %w", err)
+		}
+		list = append(list, auction)
+	}
+	return list, total, rows.Err()
+}
+
+func (r *teamContributionRepositoryImpl) ListEndedAuctionIDs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id FROM game_runtime.team_contribution_auctions
+WHERE status = 'open' AND ends_at <= $1
+ORDER BY ends_at
+LIMIT $2
+`, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询到期竞拍失败: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("扫描到期竞拍失败: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *teamContributionRepositoryImpl) ListStaleSettlingAuctionIDs(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id FROM game_runtime.team_contribution_auctions
+WHERE status = 'settling' AND ends_at <= $1
+ORDER BY ends_at
+LIMIT $2
+`, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询中断的竞拍失败: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("扫描中断的竞拍失败: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *teamContributionRepositoryImpl) CreateBid(ctx context.Context, tx *sql.Tx, bid *interfaces.TeamContributionBid) error {
+	err := tx.QueryRowContext(ctx, `
+INSERT INTO game_runtime.team_contribution_bids (auction_id, hero_id, amount)
+VALUES ($1, $2, $3)
+RETURNING id, created_at
+`, bid.AuctionID, bid.HeroID, bid.Amount).Scan(&bid.ID, &bid.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("记录出价失败: %w", err)
+	}
+	return nil
+}
+
+func (r *teamContributionRepositoryImpl) ListBids(ctx context.Context, auctionID string) ([]*interfaces.TeamContributionBid, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id, auction_id, hero_id, amount, created_at
+FROM game_runtime.team_contribution_bids
+WHERE auction_id = $1
+ORDER BY created_at DESC
+`, auctionID)
+	if err != nil {
+		return nil, fmt.Errorf("查询出价记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	var bids []*interfaces.TeamContributionBid
+	for rows.Next() {
+		bid := &interfaces.TeamContributionBid{}
+		if err := rows.Scan(&bid.ID, &bid.AuctionID, &bid.HeroID, &bid.Amount, &bid.CreatedAt); err != nil {
+			return nil, fmt.Errorf("扫描出价记录失败: %w", err)
+		}
+		bids = append(bids, bid)
+	}
+	return bids, rows.Err()
+}
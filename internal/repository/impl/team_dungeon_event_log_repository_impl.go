@@ -0,0 +1,67 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/google/uuid"
+
+	"tsu-self/internal/repository/interfaces"
+)
+
+type teamDungeonEventLogRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewTeamDungeonEventLogRepository 创建地城事件日志仓储实例
+func NewTeamDungeonEventLogRepository(db *sql.DB) interfaces.TeamDungeonEventLogRepository {
+	return &teamDungeonEventLogRepositoryImpl{db: db}
+}
+
+func (r *teamDungeonEventLogRepositoryImpl) Create(ctx context.Context, execer boil.ContextExecutor, log *interfaces.TeamDungeonEventLog) error {
+	if log == nil {
+		return fmt.Errorf("事件日志不能为空")
+	}
+	if execer == nil {
+		execer = r.db
+	}
+	if log.ID == "" {
+		log.ID = uuid.NewString()
+	}
+	if log.RewardStatus == "" {
+		log.RewardStatus = "pending"
+	}
+
+	_, err := execer.ExecContext(ctx, `
+INSERT INTO game_runtime.team_dungeon_event_logs (
+    id, progress_id, team_id, dungeon_id, room_id, event_id, event_code, operator_hero_id,
+    applied_effects, loot_gold, loot_items, reward_exp, rewarded_heroes, reward_status, reward_error
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8,
+    COALESCE($9, '[]'::jsonb), $10, COALESCE($11, '[]'::jsonb), $12, COALESCE($13, '[]'::jsonb), $14, $15
+)
+`, log.ID, log.ProgressID, log.TeamID, log.DungeonID, log.RoomID, log.EventID, log.EventCode, log.OperatorHeroID,
+		nullJSON(log.AppliedEffects), log.LootGold, nullJSON(log.LootItems), log.RewardExp, nullJSON(log.RewardedHeroes),
+		log.RewardStatus, log.RewardError)
+	if err != nil {
+		return fmt.Errorf("写入地城事件日志失败: %w", err)
+	}
+	return nil
+}
+
+func (r *teamDungeonEventLogRepositoryImpl) UpdateRewardStatus(ctx context.Context, execer boil.ContextExecutor, id, status string, rewardErr *string) error {
+	if execer == nil {
+		execer = r.db
+	}
+	_, err := execer.ExecContext(ctx, `
+UPDATE game_runtime.team_dungeon_event_logs
+SET reward_status = $2, reward_error = $3
+WHERE id = $1
+`, id, status, rewardErr)
+	if err != nil {
+		return fmt.Errorf("更新地城事件日志失败: %w", err)
+	}
+	return nil
+}
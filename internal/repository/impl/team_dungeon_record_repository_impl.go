@@ -121,3 +121,15 @@ func (r *teamDungeonRecordRepositoryImpl) ListByTeam(ctx context.Context, teamID
 
 	return list, total, nil
 }
+
+func (r *teamDungeonRecordRepositoryImpl) ResetAttempts(ctx context.Context, execer boil.ContextExecutor) (int64, error) {
+	result, err := execer.ExecContext(ctx, `
+UPDATE game_runtime.team_dungeon_records
+SET attempts_count = 0, updated_at = NOW()
+WHERE attempts_count > 0
+`)
+	if err != nil {
+		return 0, fmt.Errorf("重置地城挑战次数失败: %w", err)
+	}
+	return result.RowsAffected()
+}
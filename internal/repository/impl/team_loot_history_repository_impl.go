@@ -9,7 +9,6 @@ import (
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/google/uuid"
 
-	"tsu-self/internal/entity/game_runtime"
 	"tsu-self/internal/repository/interfaces"
 )
 
@@ -30,20 +29,19 @@ func (r *teamLootHistoryRepositoryImpl) CreateDistribution(ctx context.Context,
 		execer = r.db
 	}
 
-	record := &game_runtime.TeamLootDistributionHistory{
-		ID:                uuid.NewString(),
-		TeamID:            req.TeamID,
-		WarehouseID:       req.WarehouseID,
-		DistributorHeroID: req.DistributorHeroID,
-		RecipientHeroID:   req.RecipientHeroID,
-		ItemType:          req.ItemType,
-		Quantity:          req.Quantity,
-	}
-	if req.ItemID != nil {
-		record.ItemID.SetValid(*req.ItemID)
+	policy := req.Policy
+	if policy == "" {
+		policy = "manual"
 	}
 
-	if err := record.Insert(ctx, execer, boil.Infer()); err != nil {
+	// 实体未包含 policy/decision_note 列，直接写 SQL
+	_, err := execer.ExecContext(ctx, `
+INSERT INTO game_runtime.team_loot_distribution_history
+    (id, team_id, warehouse_id, distributor_hero_id, recipient_hero_id, item_type, item_id, quantity, policy, decision_note)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+`, uuid.NewString(), req.TeamID, req.WarehouseID, req.DistributorHeroID, req.RecipientHeroID, req.ItemType, req.ItemID,
+		req.Quantity, policy, req.DecisionNote)
+	if err != nil {
 		return fmt.Errorf("写入分配历史失败: %w", err)
 	}
 	return nil
@@ -81,7 +79,7 @@ func (r *teamLootHistoryRepositoryImpl) ListByTeam(ctx context.Context, teamID s
 	}
 
 	querySQL := fmt.Sprintf(`
-SELECT id, team_id, warehouse_id, distributor_hero_id, recipient_hero_id, item_type, item_id, quantity, distributed_at
+SELECT id, team_id, warehouse_id, distributor_hero_id, recipient_hero_id, item_type, item_id, quantity, policy, decision_note, distributed_at
 FROM game_runtime.team_loot_distribution_history
 WHERE %s
 ORDER BY distributed_at DESC
@@ -97,15 +95,18 @@ LIMIT %d OFFSET %d
 	var result []*interfaces.TeamLootHistoryRow
 	for rows.Next() {
 		row := &interfaces.TeamLootHistoryRow{}
-		var itemID sql.NullString
+		var itemID, decisionNote sql.NullString
 		var distributedAt time.Time
-		if err := rows.Scan(&row.ID, &row.TeamID, &row.WarehouseID, &row.DistributorHeroID, &row.RecipientHeroID, &row.ItemType, &itemID, &row.Quantity, &distributedAt); err != nil {
+		if err := rows.Scan(&row.ID, &row.TeamID, &row.WarehouseID, &row.DistributorHeroID, &row.RecipientHeroID, &row.ItemType, &itemID, &row.Quantity, &row.Policy, &decisionNote, &distributedAt); err != nil {
 			return nil, 0, fmt.Errorf("扫描分配历史失败: %w", err)
 		}
 		if itemID.Valid {
 			val := itemID.String
 			row.ItemID = &val
 		}
+		if decisionNote.Valid {
+			row.DecisionNote = &decisionNote.String
+		}
 		row.DistributedAt = distributedAt.Format(time.RFC3339)
 		result = append(result, row)
 	}
@@ -0,0 +1,292 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+
+	"tsu-self/internal/repository/interfaces"
+)
+
+type teamLootPolicyRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewTeamLootPolicyRepository 创建团队战利品分配策略仓储实例
+func NewTeamLootPolicyRepository(db *sql.DB) interfaces.TeamLootPolicyRepository {
+	return &teamLootPolicyRepositoryImpl{db: db}
+}
+
+const teamLootRollColumns = `id, team_id, warehouse_id, item_id, item_type, quantity, policy, status, expires_at,
+       winner_hero_id, winning_choice, winning_roll, result_note, created_at, resolved_at`
+
+func scanTeamLootRoll(scanner interface{ Scan(dest ...any) error }) (*interfaces.TeamLootRoll, error) {
+	var (
+		roll          interfaces.TeamLootRoll
+		winnerHeroID  sql.NullString
+		winningChoice sql.NullString
+		winningRoll   sql.NullInt64
+		resultNote    sql.NullString
+		resolvedAt    sql.NullTime
+	)
+	if err := scanner.Scan(&roll.ID, &roll.TeamID, &roll.WarehouseID, &roll.ItemID, &roll.ItemType, &roll.Quantity,
+		&roll.Policy, &roll.Status, &roll.ExpiresAt, &winnerHeroID, &winningChoice, &winningRoll, &resultNote,
+		&roll.CreatedAt, &resolvedAt); err != nil {
+		return nil, err
+	}
+	if winnerHeroID.Valid {
+		roll.WinnerHeroID = &winnerHeroID.String
+	}
+	if winningChoice.Valid {
+		roll.WinningChoice = &winningChoice.String
+	}
+	if winningRoll.Valid {
+		value := int(winningRoll.Int64)
+		roll.WinningRoll = &value
+	}
+	if resultNote.Valid {
+		roll.ResultNote = &resultNote.String
+	}
+	if resolvedAt.Valid {
+		roll.ResolvedAt = &resolvedAt.Time
+	}
+	return &roll, nil
+}
+
+func (r *teamLootPolicyRepositoryImpl) GetPolicy(ctx context.Context, teamID string) (*interfaces.TeamLootPolicy, error) {
+	var policy interfaces.TeamLootPolicy
+	err := r.db.QueryRowContext(ctx, `
+SELECT id, team_id, gold_policy, item_policy, roll_timeout_seconds
+FROM game_runtime.team_warehouses
+WHERE team_id = $1
+`, teamID).Scan(&policy.WarehouseID, &policy.TeamID, &policy.GoldPolicy, &policy.ItemPolicy, &policy.RollTimeoutSeconds)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询仓库分配策略失败: %w", err)
+	}
+	return &policy, nil
+}
+
+func (r *teamLootPolicyRepositoryImpl) UpdatePolicy(ctx context.Context, policy *interfaces.TeamLootPolicy) error {
+	_, err := r.db.ExecContext(ctx, `
+UPDATE game_runtime.team_warehouses
+SET gold_policy = $2, item_policy = $3, roll_timeout_seconds = $4
+WHERE id = $1
+`, policy.WarehouseID, policy.GoldPolicy, policy.ItemPolicy, policy.RollTimeoutSeconds)
+	if err != nil {
+		return fmt.Errorf("更新仓库分配策略失败: %w", err)
+	}
+	return nil
+}
+
+func (r *teamLootPolicyRepositoryImpl) ReserveRoundRobin(ctx context.Context, warehouseID string, count int) (int, error) {
+	var start int
+	err := r.db.QueryRowContext(ctx, `
+UPDATE game_runtime.team_warehouses
+SET round_robin_cursor = round_robin_cursor + $2
+WHERE id = $1
+RETURNING round_robin_cursor - $2
+`, warehouseID, count).Scan(&start)
+	if err != nil {
+		return 0, fmt.Errorf("更新轮流分配游标失败: %w", err)
+	}
+	return start, nil
+}
+
+func (r *teamLootPolicyRepositoryImpl) CreateRoll(ctx context.Context, execer boil.ContextExecutor, roll *interfaces.TeamLootRoll) error {
+	if execer == nil {
+		execer = r.db
+	}
+	err := execer.QueryRowContext(ctx, `
+INSERT INTO game_runtime.team_loot_rolls (team_id, warehouse_id, item_id, item_type, quantity, policy, status, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, created_at
+`, roll.TeamID, roll.WarehouseID, roll.ItemID, roll.ItemType, roll.Quantity, roll.Policy, roll.Status, roll.ExpiresAt).
+		Scan(&roll.ID, &roll.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("创建投骰失败: %w", err)
+	}
+	return nil
+}
+
+func (r *teamLootPolicyRepositoryImpl) GetRollForUpdate(ctx context.Context, tx *sql.Tx, rollID string) (*interfaces.TeamLootRoll, error) {
+	roll, err := scanTeamLootRoll(tx.QueryRowContext(ctx, `
+SELECT `+teamLootRollColumns+`
+FROM game_runtime.team_loot_rolls
+WHERE id = $1
+FOR UPDATE
+`, rollID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询投骰失败: %w", err)
+	}
+	return roll, nil
+}
+
+func (r *teamLootPolicyRepositoryImpl) UpdateRollResult(ctx context.Context, execer boil.ContextExecutor, roll *interfaces.TeamLootRoll) error {
+	if execer == nil {
+		execer = r.db
+	}
+	_, err := execer.ExecContext(ctx, `
+UPDATE game_runtime.team_loot_rolls
+SET status = $2, winner_hero_id = $3, winning_choice = $4, winning_roll = $5, result_note = $6, resolved_at = $7
+WHERE id = $1
+`, roll.ID, roll.Status, roll.WinnerHeroID, roll.WinningChoice, roll.WinningRoll, roll.ResultNote, roll.ResolvedAt)
+	if err != nil {
+		return fmt.Errorf("更新投骰结果失败: %w", err)
+	}
+	return nil
+}
+
+func (r *teamLootPolicyRepositoryImpl) ListRolls(ctx context.Context, teamID, status string, limit, offset int) ([]*interfaces.TeamLootRoll, int64, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM game_runtime.team_loot_rolls WHERE team_id = $1 AND ($2::text = '' OR status = $2)
+`, teamID, status).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("统计投骰失败: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT `+teamLootRollColumns+`
+FROM game_runtime.team_loot_rolls
+WHERE team_id = $1 AND ($2::text = '' OR status = $2)
+ORDER BY created_at DESC
+LIMIT $3 OFFSET $4
+`, teamID, status, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询投骰失败: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*interfaces.TeamLootRoll
+	for rows.Next() {
+		roll, err := scanTeamLootRoll(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("扫描投骰失败: %w", err)
+		}
+		list = append(list, roll)
+	}
+	return list, total, rows.Err()
+}
+
+func (r *teamLootPolicyRepositoryImpl) ListExpiredRollIDs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id FROM game_runtime.team_loot_rolls
+WHERE status = 'open' AND expires_at <= $1
+ORDER BY expires_at
+LIMIT $2
+`, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询超时投骰失败: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("扫描超时投骰失败: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *teamLootPolicyRepositoryImpl) ListStaleResolvingRollIDs(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id FROM game_runtime.team_loot_rolls
+WHERE status = 'resolving' AND resolved_at <= $1
+ORDER BY resolved_at
+LIMIT $2
+`, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询中断的投骰失败: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("扫描中断的投骰失败: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *teamLootPolicyRepositoryImpl) SaveVote(ctx context.Context, tx *sql.Tx, vote *interfaces.TeamLootRollVote) (bool, error) {
+	err := tx.QueryRowContext(ctx, `
+INSERT INTO game_runtime.team_loot_roll_votes (roll_id, hero_id, choice, roll_value)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (roll_id, hero_id) DO NOTHING
+RETURNING created_at
+`, vote.RollID, vote.HeroID, vote.Choice, vote.RollValue).Scan(&vote.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("记录投骰选择失败: %w", err)
+	}
+	return true, nil
+}
+
+func (r *teamLootPolicyRepositoryImpl) ListVotes(ctx context.Context, execer boil.ContextExecutor, rollID string) ([]*interfaces.TeamLootRollVote, error) {
+	if execer == nil {
+		execer = r.db
+	}
+	rows, err := execer.QueryContext(ctx, `
+SELECT roll_id, hero_id, choice, roll_value, created_at
+FROM game_runtime.team_loot_roll_votes
+WHERE roll_id = $1
+ORDER BY created_at
+`, rollID)
+	if err != nil {
+		return nil, fmt.Errorf("查询投骰选择失败: %w", err)
+	}
+	defer rows.Close()
+
+	var votes []*interfaces.TeamLootRollVote
+	for rows.Next() {
+		vote := &interfaces.TeamLootRollVote{}
+		if err := rows.Scan(&vote.RollID, &vote.HeroID, &vote.Choice, &vote.RollValue, &vote.CreatedAt); err != nil {
+			return nil, fmt.Errorf("扫描投骰选择失败: %w", err)
+		}
+		votes = append(votes, vote)
+	}
+	return votes, rows.Err()
+}
+
+func (r *teamLootPolicyRepositoryImpl) ListItemClassIDs(ctx context.Context, itemID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT class_id FROM game_config.item_class_relations WHERE item_id = $1
+`, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("查询物品职业限制失败: %w", err)
+	}
+	defer rows.Close()
+
+	var classIDs []string
+	for rows.Next() {
+		var classID string
+		if err := rows.Scan(&classID); err != nil {
+			return nil, fmt.Errorf("扫描物品职业限制失败: %w", err)
+		}
+		classIDs = append(classIDs, classID)
+	}
+	return classIDs, rows.Err()
+}
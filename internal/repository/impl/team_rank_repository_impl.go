@@ -0,0 +1,181 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/lib/pq"
+
+	"tsu-self/internal/repository/interfaces"
+)
+
+type teamRankRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewTeamRankRepository 创建团队职阶仓储实例
+func NewTeamRankRepository(db *sql.DB) interfaces.TeamRankRepository {
+	return &teamRankRepositoryImpl{db: db}
+}
+
+const teamRankColumns = `id, team_id, name, level, permissions, created_at, updated_at`
+
+func scanTeamRank(scanner interface{ Scan(dest ...any) error }) (*interfaces.TeamRank, error) {
+	var rank interfaces.TeamRank
+	if err := scanner.Scan(&rank.ID, &rank.TeamID, &rank.Name, &rank.Level, pq.Array(&rank.Permissions),
+		&rank.CreatedAt, &rank.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &rank, nil
+}
+
+func (r *teamRankRepositoryImpl) Create(ctx context.Context, execer boil.ContextExecutor, rank *interfaces.TeamRank) error {
+	if execer == nil {
+		execer = r.db
+	}
+	err := execer.QueryRowContext(ctx, `
+INSERT INTO game_runtime.team_ranks (team_id, name, level, permissions)
+VALUES ($1, $2, $3, $4)
+RETURNING id, created_at, updated_at
+`, rank.TeamID, rank.Name, rank.Level, pq.Array(rank.Permissions)).Scan(&rank.ID, &rank.CreatedAt, &rank.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("创建团队职阶失败: %w", err)
+	}
+	return nil
+}
+
+func (r *teamRankRepositoryImpl) Update(ctx context.Context, execer boil.ContextExecutor, rank *interfaces.TeamRank) error {
+	if execer == nil {
+		execer = r.db
+	}
+	err := execer.QueryRowContext(ctx, `
+UPDATE game_runtime.team_ranks
+SET name = $3, level = $4, permissions = $5, updated_at = NOW()
+WHERE id = $1 AND team_id = $2
+RETURNING updated_at
+`, rank.ID, rank.TeamID, rank.Name, rank.Level, pq.Array(rank.Permissions)).Scan(&rank.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("更新团队职阶失败: %w", err)
+	}
+	return nil
+}
+
+func (r *teamRankRepositoryImpl) Delete(ctx context.Context, execer boil.ContextExecutor, teamID, rankID string) error {
+	if execer == nil {
+		execer = r.db
+	}
+	if _, err := execer.ExecContext(ctx, `
+DELETE FROM game_runtime.team_ranks WHERE id = $1 AND team_id = $2
+`, rankID, teamID); err != nil {
+		return fmt.Errorf("删除团队职阶失败: %w", err)
+	}
+	return nil
+}
+
+func (r *teamRankRepositoryImpl) GetByID(ctx context.Context, teamID, rankID string) (*interfaces.TeamRank, error) {
+	rank, err := scanTeamRank(r.db.QueryRowContext(ctx, `
+SELECT `+teamRankColumns+`
+FROM game_runtime.team_ranks
+WHERE id = $1 AND team_id = $2
+`, rankID, teamID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询团队职阶失败: %w", err)
+	}
+	return rank, nil
+}
+
+func (r *teamRankRepositoryImpl) ListByTeam(ctx context.Context, teamID string) ([]*interfaces.TeamRank, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT `+teamRankColumns+`
+FROM game_runtime.team_ranks
+WHERE team_id = $1
+ORDER BY level DESC, created_at ASC
+`, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("查询团队职阶列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var ranks []*interfaces.TeamRank
+	for rows.Next() {
+		rank, err := scanTeamRank(rows)
+		if err != nil {
+			return nil, fmt.Errorf("解析团队职阶失败: %w", err)
+		}
+		ranks = append(ranks, rank)
+	}
+	return ranks, rows.Err()
+}
+
+func (r *teamRankRepositoryImpl) CountByTeam(ctx context.Context, teamID string) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM game_runtime.team_ranks WHERE team_id = $1
+`, teamID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("统计团队职阶失败: %w", err)
+	}
+	return count, nil
+}
+
+func (r *teamRankRepositoryImpl) GetMemberRank(ctx context.Context, teamID, heroID string) (*interfaces.TeamRank, error) {
+	rank, err := scanTeamRank(r.db.QueryRowContext(ctx, `
+SELECT r.id, r.team_id, r.name, r.level, r.permissions, r.created_at, r.updated_at
+FROM game_runtime.team_members m
+JOIN game_runtime.team_ranks r ON r.id = m.rank_id
+WHERE m.team_id = $1 AND m.hero_id = $2
+`, teamID, heroID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询成员职阶失败: %w", err)
+	}
+	return rank, nil
+}
+
+func (r *teamRankRepositoryImpl) SetMemberRank(ctx context.Context, execer boil.ContextExecutor, teamID, heroID string, rankID *string) error {
+	if execer == nil {
+		execer = r.db
+	}
+	result, err := execer.ExecContext(ctx, `
+UPDATE game_runtime.team_members SET rank_id = $3
+WHERE team_id = $1 AND hero_id = $2
+`, teamID, heroID, rankID)
+	if err != nil {
+		return fmt.Errorf("设置成员职阶失败: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("设置成员职阶失败: %w", err)
+	}
+	if affected == 0 {
+		return interfaces.ErrTeamMemberNotFound
+	}
+	return nil
+}
+
+func (r *teamRankRepositoryImpl) ListMemberHeroIDs(ctx context.Context, teamID, rankID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT hero_id FROM game_runtime.team_members
+WHERE team_id = $1 AND rank_id = $2
+`, teamID, rankID)
+	if err != nil {
+		return nil, fmt.Errorf("查询职阶成员失败: %w", err)
+	}
+	defer rows.Close()
+
+	var heroIDs []string
+	for rows.Next() {
+		var heroID string
+		if err := rows.Scan(&heroID); err != nil {
+			return nil, fmt.Errorf("解析职阶成员失败: %w", err)
+		}
+		heroIDs = append(heroIDs, heroID)
+	}
+	return heroIDs, rows.Err()
+}
@@ -27,12 +27,18 @@ func (r *teamWarehouseLootLogRepositoryImpl) Log(ctx context.Context, execer boi
 		execer = r.db
 	}
 
+	entryType := req.EntryType
+	if entryType == "" {
+		entryType = interfaces.WarehouseLogEntryLoot
+	}
+
 	id := uuid.NewString()
 	_, err := execer.ExecContext(ctx, `
 INSERT INTO game_runtime.team_warehouse_loot_log
-    (id, team_id, warehouse_id, source_dungeon_id, gold_amount, items, result, reason)
-VALUES ($1, $2, $3, $4, $5, COALESCE($6, '[]'::jsonb), $7, $8)
-`, id, req.TeamID, req.WarehouseID, req.SourceDungeonID, req.GoldAmount, req.ItemsJSON, req.Result, req.Reason)
+    (id, team_id, warehouse_id, source_dungeon_id, gold_amount, items, result, reason, entry_type, hero_id, request_id)
+VALUES ($1, $2, $3, $4, $5, COALESCE($6, '[]'::jsonb), $7, $8, $9, $10, $11)
+`, id, req.TeamID, req.WarehouseID, req.SourceDungeonID, req.GoldAmount, req.ItemsJSON, req.Result, req.Reason,
+		entryType, req.HeroID, req.RequestID)
 	if err != nil {
 		return fmt.Errorf("写入入库审计日志失败: %w", err)
 	}
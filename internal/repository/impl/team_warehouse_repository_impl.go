@@ -59,6 +59,23 @@ func (r *teamWarehouseRepositoryImpl) GetByTeamID(ctx context.Context, teamID st
 	return warehouse, nil
 }
 
+// GetByTeamIDForUpdate 根据团队ID获取仓库（带行锁）
+func (r *teamWarehouseRepositoryImpl) GetByTeamIDForUpdate(ctx context.Context, tx *sql.Tx, teamID string) (*game_runtime.TeamWarehouse, error) {
+	warehouse, err := game_runtime.TeamWarehouses(
+		qm.Where("team_id = ?", teamID),
+		qm.For("UPDATE"),
+	).One(ctx, tx)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("团队仓库不存在")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询团队仓库失败: %w", err)
+	}
+
+	return warehouse, nil
+}
+
 // Update 更新仓库信息
 func (r *teamWarehouseRepositoryImpl) Update(ctx context.Context, execer boil.ContextExecutor, warehouse *game_runtime.TeamWarehouse) error {
 	// 更新时间戳
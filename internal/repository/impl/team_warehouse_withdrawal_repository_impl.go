@@ -0,0 +1,147 @@
+package impl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+
+	"tsu-self/internal/repository/interfaces"
+)
+
+type teamWarehouseWithdrawalRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewTeamWarehouseWithdrawalRepository 创建仓库取用申请仓储实例
+func NewTeamWarehouseWithdrawalRepository(db *sql.DB) interfaces.TeamWarehouseWithdrawalRepository {
+	return &teamWarehouseWithdrawalRepositoryImpl{db: db}
+}
+
+const teamWarehouseWithdrawalColumns = `id, team_id, warehouse_id, hero_id, item_id, quantity, note, status,
+       reviewer_hero_id, review_note, created_at, reviewed_at`
+
+func scanTeamWarehouseWithdrawal(scanner interface{ Scan(dest ...any) error }) (*interfaces.TeamWarehouseWithdrawal, error) {
+	var (
+		req        interfaces.TeamWarehouseWithdrawal
+		note       sql.NullString
+		reviewerID sql.NullString
+		reviewNote sql.NullString
+		reviewedAt sql.NullTime
+	)
+	if err := scanner.Scan(&req.ID, &req.TeamID, &req.WarehouseID, &req.HeroID, &req.ItemID, &req.Quantity, &note,
+		&req.Status, &reviewerID, &reviewNote, &req.CreatedAt, &reviewedAt); err != nil {
+		return nil, err
+	}
+	if note.Valid {
+		req.Note = &note.String
+	}
+	if reviewerID.Valid {
+		req.ReviewerHeroID = &reviewerID.String
+	}
+	if reviewNote.Valid {
+		req.ReviewNote = &reviewNote.String
+	}
+	if reviewedAt.Valid {
+		req.ReviewedAt = &reviewedAt.Time
+	}
+	return &req, nil
+}
+
+func (r *teamWarehouseWithdrawalRepositoryImpl) Create(ctx context.Context, execer boil.ContextExecutor, req *interfaces.TeamWarehouseWithdrawal) error {
+	if execer == nil {
+		execer = r.db
+	}
+	err := execer.QueryRowContext(ctx, `
+INSERT INTO game_runtime.team_warehouse_withdrawal_requests (team_id, warehouse_id, hero_id, item_id, quantity, note, status)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, created_at
+`, req.TeamID, req.WarehouseID, req.HeroID, req.ItemID, req.Quantity, req.Note, req.Status).Scan(&req.ID, &req.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("创建取用申请失败: %w", err)
+	}
+	return nil
+}
+
+func (r *teamWarehouseWithdrawalRepositoryImpl) GetForUpdate(ctx context.Context, tx *sql.Tx, requestID string) (*interfaces.TeamWarehouseWithdrawal, error) {
+	req, err := scanTeamWarehouseWithdrawal(tx.QueryRowContext(ctx, `
+SELECT `+teamWarehouseWithdrawalColumns+`
+FROM game_runtime.team_warehouse_withdrawal_requests
+WHERE id = $1
+FOR UPDATE
+`, requestID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询取用申请失败: %w", err)
+	}
+	return req, nil
+}
+
+func (r *teamWarehouseWithdrawalRepositoryImpl) UpdateReview(ctx context.Context, execer boil.ContextExecutor, req *interfaces.TeamWarehouseWithdrawal) error {
+	if execer == nil {
+		execer = r.db
+	}
+	_, err := execer.ExecContext(ctx, `
+UPDATE game_runtime.team_warehouse_withdrawal_requests
+SET status = $2, reviewer_hero_id = $3, review_note = $4, reviewed_at = $5
+WHERE id = $1
+`, req.ID, req.Status, req.ReviewerHeroID, req.ReviewNote, req.ReviewedAt)
+	if err != nil {
+		return fmt.Errorf("更新取用申请失败: %w", err)
+	}
+	return nil
+}
+
+func (r *teamWarehouseWithdrawalRepositoryImpl) CountPendingByHero(ctx context.Context, teamID, heroID string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM game_runtime.team_warehouse_withdrawal_requests
+WHERE team_id = $1 AND hero_id = $2 AND status = 'pending'
+`, teamID, heroID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("统计待审批取用申请失败: %w", err)
+	}
+	return count, nil
+}
+
+func (r *teamWarehouseWithdrawalRepositoryImpl) List(ctx context.Context, teamID, status, heroID string, limit, offset int) ([]*interfaces.TeamWarehouseWithdrawal, int64, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM game_runtime.team_warehouse_withdrawal_requests
+WHERE team_id = $1 AND ($2::text = '' OR status = $2) AND ($3::text = '' OR hero_id = NULLIF($3, '')::uuid)
+`, teamID, status, heroID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("统计取用申请失败: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+SELECT `+teamWarehouseWithdrawalColumns+`
+FROM game_runtime.team_warehouse_withdrawal_requests
+WHERE team_id = $1 AND ($2::text = '' OR status = $2) AND ($3::text = '' OR hero_id = NULLIF($3, '')::uuid)
+ORDER BY created_at DESC
+LIMIT $4 OFFSET $5
+`, teamID, status, heroID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询取用申请失败: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*interfaces.TeamWarehouseWithdrawal
+	for rows.Next() {
+		req, err := scanTeamWarehouseWithdrawal(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("扫描取用申请失败: %w", err)
+		}
+		list = append(list, req)
+	}
+	return list, total, rows.Err()
+}
@@ -185,3 +185,29 @@ func (r *worldDropStatsRepositoryImpl) GetStatsNeedingHourlyReset(ctx context.Co
 	return stats, nil
 }
 
+
+// ResetAllDaily 批量清零上次重置早于 periodStart 的每日计数
+func (r *worldDropStatsRepositoryImpl) ResetAllDaily(ctx context.Context, execer boil.ContextExecutor, periodStart time.Time) (int64, error) {
+	result, err := execer.ExecContext(ctx, `
+UPDATE game_runtime.world_drop_stats
+SET daily_dropped = 0, daily_reset_at = $1
+WHERE daily_reset_at IS NULL OR daily_reset_at < $1
+`, periodStart)
+	if err != nil {
+		return 0, fmt.Errorf("重置每日掉落统计失败: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ResetAllHourly 批量清零上次重置早于 periodStart 的每小时计数
+func (r *worldDropStatsRepositoryImpl) ResetAllHourly(ctx context.Context, execer boil.ContextExecutor, periodStart time.Time) (int64, error) {
+	result, err := execer.ExecContext(ctx, `
+UPDATE game_runtime.world_drop_stats
+SET hourly_dropped = 0, hourly_reset_at = $1
+WHERE hourly_reset_at IS NULL OR hourly_reset_at < $1
+`, periodStart)
+	if err != nil {
+		return 0, fmt.Errorf("重置每小时掉落统计失败: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -3,6 +3,10 @@ package interfaces
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
 )
 
 // BattleReport 描述战斗回调需要持久化的核心字段。
@@ -11,6 +15,8 @@ type BattleReport struct {
 	BattleCode   string          // 战斗模板/房间编码
 	TeamID       string          // 触发战斗的团队
 	DungeonID    string          // 关联地城
+	ProgressID   string          // 关联地城进度（房间战斗）
+	RoomID       string          // 关联房间 ID 或房间编码（房间战斗）
 	ResultStatus string          // 结果状态（victory/defeat/draw 等）
 	LootGold     int64           // 奖励金币
 	LootItems    json.RawMessage // 奖励物品 JSON
@@ -19,7 +25,60 @@ type BattleReport struct {
 	RawPayload   json.RawMessage // 完整原始 payload
 }
 
+// 战斗结算状态
+const (
+	BattleOutcomeProcessing = "processing"
+	BattleOutcomeCompleted  = "completed"
+	BattleOutcomeFailed     = "failed"
+)
+
+// ErrBattleOutcomeClaimLost 结算认领已被其他请求接管（处理超时后被重新认领）
+var ErrBattleOutcomeClaimLost = errors.New("battle outcome claim lost")
+
+// BattleOutcome 同一 battle_id 的结算记录。
+type BattleOutcome struct {
+	BattleID string
+	Status   string          // processing/completed/failed
+	Outcome  json.RawMessage // 首次结算结果
+}
+
 // BattleReportRepository 负责战斗回调的持久化。
 type BattleReportRepository interface {
 	Create(ctx context.Context, report *BattleReport) error
+
+	// GetSettledReport 查询已结算完成（结算记录为 completed）的战报，不存在或未结算完成时返回 nil
+	GetSettledReport(ctx context.Context, battleID string) (*BattleReport, error)
+
+	// ClaimOutcome 抢占 battle_id 的结算权：首次或上次失败/处理超时时返回本次认领的令牌，
+	// 否则令牌为空并返回已有记录
+	ClaimOutcome(ctx context.Context, battleID string, staleAfter time.Duration) (claimToken string, existing *BattleOutcome, err error)
+
+	// CompleteOutcome 记录结算结果；认领已被接管时返回 ErrBattleOutcomeClaimLost
+	CompleteOutcome(ctx context.Context, battleID, claimToken string, outcome json.RawMessage) error
+
+	// FailOutcome 标记结算失败，允许重试；认领已被接管时返回 ErrBattleOutcomeClaimLost
+	FailOutcome(ctx context.Context, battleID, claimToken, reason string) error
+}
+
+// 战斗结算中需要幂等的副作用步骤
+const (
+	BattleStepDurabilityWear   = "durability_wear"
+	BattleStepWorldDrop        = "world_drop"
+	BattleStepDungeonCompleted = "dungeon_completed"
+	BattleStepRoomResolved     = "room_resolved"
+)
+
+// BattleResultStepRepository 战斗结算步骤标记，与步骤的副作用在同一事务内写入。
+type BattleResultStepRepository interface {
+	// MarkStep 登记步骤已执行，已登记过时返回 false（调用方应回滚本次副作用）
+	MarkStep(ctx context.Context, execer boil.ContextExecutor, battleID, step string, result json.RawMessage) (bool, error)
+
+	// GetStep 查询已执行步骤的结果
+	GetStep(ctx context.Context, battleID, step string) (result json.RawMessage, found bool, err error)
+}
+
+// BattleCallbackNonceRepository 战斗回调签名 nonce 存储。
+type BattleCallbackNonceRepository interface {
+	// Reserve 登记 nonce，已存在且未过期时返回 false
+	Reserve(ctx context.Context, keyID, nonce string, expiresAt time.Time) (bool, error)
 }
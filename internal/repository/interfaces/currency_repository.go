@@ -0,0 +1,99 @@
+package interfaces
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+// 系统货币代码
+const (
+	CurrencyGold  = "gold"
+	CurrencyHonor = "honor"
+)
+
+// 货币奖励来源类型
+const (
+	CurrencyRewardDungeon = "dungeon"
+	CurrencyRewardEvent   = "dungeon_event"
+)
+
+// ErrInsufficientCurrency 货币余额不足
+var ErrInsufficientCurrency = errors.New("insufficient currency")
+
+// Currency 货币定义
+type Currency struct {
+	Code        string
+	Name        string
+	Description *string
+	IsSystem    bool
+	IsActive    bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// CurrencyReward 货币奖励配置（每名成员所得）
+type CurrencyReward struct {
+	SourceType   string
+	SourceID     string
+	CurrencyCode string
+	Amount       int64
+}
+
+// CurrencyRepository 货币定义与奖励配置仓储
+type CurrencyRepository interface {
+	// GetByCode 获取货币定义，不存在时返回 nil
+	GetByCode(ctx context.Context, code string) (*Currency, error)
+	// List 获取货币列表
+	List(ctx context.Context, includeInactive bool) ([]*Currency, error)
+	// Create 创建货币
+	Create(ctx context.Context, currency *Currency) error
+	// Update 更新货币名称、描述与启用状态
+	Update(ctx context.Context, currency *Currency) error
+	// Delete 删除非系统货币
+	Delete(ctx context.Context, code string) error
+
+	// ListRewards 获取某来源的货币奖励配置
+	ListRewards(ctx context.Context, sourceType, sourceID string) ([]*CurrencyReward, error)
+	// ReplaceRewards 覆盖某来源的货币奖励配置
+	ReplaceRewards(ctx context.Context, sourceType, sourceID string, rewards []*CurrencyReward) error
+}
+
+// CurrencyTransaction 货币流水
+type CurrencyTransaction struct {
+	ID           string
+	HeroID       string
+	CurrencyCode string
+	Amount       int64 // 正数为获得，负数为消耗
+	BalanceAfter int64
+	SourceType   string
+	SourceID     *string
+	Note         *string
+	Metadata     json.RawMessage
+	CreatedAt    time.Time
+}
+
+// CurrencyTransactionQuery 流水查询参数
+type CurrencyTransactionQuery struct {
+	HeroID       string
+	CurrencyCode string
+	SourceType   string
+	SourceID     string
+	Limit        int
+	Offset       int
+}
+
+// HeroCurrencyRepository 英雄货币账本仓储
+type HeroCurrencyRepository interface {
+	// ApplyTx 在事务内调整余额并追加流水，回填 ID、BalanceAfter 与 CreatedAt；余额不足时返回 ErrInsufficientCurrency
+	ApplyTx(ctx context.Context, execer boil.ContextExecutor, txn *CurrencyTransaction) error
+	// GetBalance 获取单个货币余额
+	GetBalance(ctx context.Context, heroID, currencyCode string) (int64, error)
+	// GetBalances 获取英雄全部货币余额（含金币），键为货币代码
+	GetBalances(ctx context.Context, heroID string) (map[string]int64, error)
+	// ListTransactions 分页查询流水（按时间倒序）
+	ListTransactions(ctx context.Context, query CurrencyTransactionQuery) ([]*CurrencyTransaction, int64, error)
+}
@@ -0,0 +1,28 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"tsu-self/internal/pkg/schedule"
+)
+
+// DungeonSchedule 地城周期性开放时间表
+type DungeonSchedule struct {
+	DungeonID string
+	Schedule  schedule.Schedule
+	IsActive  bool
+	UpdatedAt time.Time
+}
+
+// DungeonScheduleRepository 地城开放时间表仓储
+type DungeonScheduleRepository interface {
+	// GetByDungeonID 获取地城时间表，未配置时返回 nil
+	GetByDungeonID(ctx context.Context, dungeonID string) (*DungeonSchedule, error)
+	// ListByDungeonIDs 批量获取时间表，键为地城 ID，未配置的地城不出现在结果中
+	ListByDungeonIDs(ctx context.Context, dungeonIDs []string) (map[string]*DungeonSchedule, error)
+	// Upsert 创建或覆盖时间表
+	Upsert(ctx context.Context, s *DungeonSchedule) error
+	// Delete 删除时间表
+	Delete(ctx context.Context, dungeonID string) error
+}
@@ -0,0 +1,21 @@
+package interfaces
+
+import (
+	"context"
+)
+
+// DurabilityWearConfig 战斗耐久磨损配置
+type DurabilityWearConfig struct {
+	DungeonID      *string // 为空表示默认配置
+	VictoryLoss    int     // 胜利时每件装备损失
+	DefeatLoss     int     // 失败时每件装备损失
+	DeathExtraLoss int     // 阵亡额外损失
+}
+
+// EquipmentDurabilityConfigRepository 装备耐久配置仓储
+type EquipmentDurabilityConfigRepository interface {
+	// GetWearConfig 获取地城的磨损配置，未单独配置时回退到默认配置；都不存在时返回 nil
+	GetWearConfig(ctx context.Context, dungeonID string) (*DurabilityWearConfig, error)
+	// GetRepairGoldPerPoint 获取品质对应的每点耐久修理金币，未配置时 found 为 false
+	GetRepairGoldPerPoint(ctx context.Context, quality string) (goldPerPoint int, found bool, err error)
+}
@@ -0,0 +1,24 @@
+package interfaces
+
+import (
+	"context"
+)
+
+// EquipmentEnhancementLevel 装备强化等级配置
+type EquipmentEnhancementLevel struct {
+	ID               string
+	TargetLevel      int
+	SuccessRate      float64 // 成功概率 0-1
+	DowngradeRate    float64 // 失败后降级概率 0-1
+	DowngradeLevels  int     // 降级时下降的等级数
+	MaterialQuantity int     // 消耗强化材料数量
+	GoldMultiplier   float64 // 金币消耗倍率
+}
+
+// EquipmentEnhancementLevelRepository 装备强化等级配置仓储
+type EquipmentEnhancementLevelRepository interface {
+	// GetByTargetLevel 获取强化到目标等级的配置，不存在时返回 nil
+	GetByTargetLevel(ctx context.Context, targetLevel int) (*EquipmentEnhancementLevel, error)
+	// List 查询全部启用的强化等级配置（按目标等级升序）
+	List(ctx context.Context) ([]*EquipmentEnhancementLevel, error)
+}
@@ -43,5 +43,14 @@ type EquipmentSlotRepository interface {
 
 	// GetSlotsAddedByItem 查询由特定装备增加的槽位
 	GetSlotsAddedByItem(ctx context.Context, itemInstanceID string) ([]*game_runtime.HeroEquipmentSlot, error)
+
+	// ListSlotsByTypeForUpdate 查询英雄特定类型的槽位（带行锁，按索引排序）
+	ListSlotsByTypeForUpdate(ctx context.Context, tx *sql.Tx, heroID string, slotType string) ([]*game_runtime.HeroEquipmentSlot, error)
+
+	// GetSlotByEquippedItem 查询装备所在槽位，未装备时返回 nil
+	GetSlotByEquippedItem(ctx context.Context, execer boil.ContextExecutor, itemInstanceID string) (*game_runtime.HeroEquipmentSlot, error)
+
+	// CountEquippedByItemConfig 统计指定英雄范围内已穿戴某装备配置的数量（用于唯一性校验）
+	CountEquippedByItemConfig(ctx context.Context, execer boil.ContextExecutor, itemConfigID string, heroIDs []string, excludeSlotIDs []string) (int64, error)
 }
 
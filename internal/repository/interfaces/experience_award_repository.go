@@ -0,0 +1,67 @@
+package interfaces
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// 经验来源类型
+const (
+	ExperienceSourceDefault = "default"
+	ExperienceSourceBattle  = "battle"
+	ExperienceSourceEvent   = "dungeon_event"
+)
+
+// 经验分配方式
+const (
+	ExperienceSplitEqual = "equal" // 总经验由参与英雄平分
+	ExperienceSplitFull  = "full"  // 每名英雄获得全额
+)
+
+// ExperienceAwardRule 经验分配规则
+type ExperienceAwardRule struct {
+	SourceType        string
+	SplitMode         string
+	LevelPenaltyGrace int     // 超出内容等级区间不超过该值时不衰减
+	LevelPenaltyStep  float64 // 超出宽限后每级衰减比例
+	LevelPenaltyFloor float64 // 衰减后的最低比例
+	RestedBonusRate   float64 // 休息经验加成上限（占基础经验比例）
+	RestedXPPerHour   int64   // 休息经验每小时累积量（仅 default 行生效）
+	RestedXPCap       int64   // 休息经验池上限（仅 default 行生效）
+}
+
+// HeroRestedExperience 英雄休息经验池
+type HeroRestedExperience struct {
+	HeroID    string
+	RestedXP  int64
+	AccruedAt time.Time
+}
+
+// ExperienceAwardRecord 经验发放记录
+type ExperienceAwardRecord struct {
+	ID          string
+	HeroID      string
+	SourceType  string
+	SourceID    string
+	HeroLevel   int
+	BaseXP      int64
+	PenaltyRate float64
+	RestedBonus int64
+	AwardedXP   int64
+	CreatedAt   time.Time
+}
+
+// ExperienceAwardRepository 经验发放仓储
+type ExperienceAwardRepository interface {
+	// GetRule 获取来源的分配规则，未配置时返回 nil
+	GetRule(ctx context.Context, sourceType string) (*ExperienceAwardRule, error)
+	// GetRestedForUpdate 在事务内锁定英雄休息经验池，不存在时返回 nil
+	GetRestedForUpdate(ctx context.Context, tx *sql.Tx, heroID string) (*HeroRestedExperience, error)
+	// SaveRested 在事务内写入休息经验池
+	SaveRested(ctx context.Context, tx *sql.Tx, rested *HeroRestedExperience) error
+	// ClaimAward 在事务内写入发放记录；同一来源已对该英雄发放过时返回 false
+	ClaimAward(ctx context.Context, tx *sql.Tx, record *ExperienceAwardRecord) (bool, error)
+	// ListAwards 分页查询英雄的经验发放记录（按时间倒序）
+	ListAwards(ctx context.Context, heroID string, limit, offset int) ([]*ExperienceAwardRecord, int64, error)
+}
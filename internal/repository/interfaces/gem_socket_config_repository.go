@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"context"
+
+	"tsu-self/internal/entity/game_config"
+)
+
+// GemSocketConfigRepository 宝石镶嵌配置仓储（孔位类型、宝石效果）
+type GemSocketConfigRepository interface {
+	// GetSocketTypeByCode 根据孔位类型代码获取孔位布局，不存在时返回 nil
+	GetSocketTypeByCode(ctx context.Context, code string) (*game_config.SocketTypeConfig, error)
+	// ListActiveGemEffects 查询全部启用的宝石效果配置
+	ListActiveGemEffects(ctx context.Context) ([]*game_config.GemEffectConfig, error)
+}
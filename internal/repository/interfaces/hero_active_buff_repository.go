@@ -0,0 +1,32 @@
+package interfaces
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+// HeroActiveBuff 英雄生效中的 Buff
+type HeroActiveBuff struct {
+	ID          string
+	HeroID      string
+	BuffID      *string
+	BuffCode    string
+	BuffParams  json.RawMessage
+	CasterLevel int
+	SourceType  string  // dungeon_event/item
+	SourceID    *string // 事件代码、物品实例ID等
+	ProgressID  *string // 非空时仅在该地城进度进行中生效
+	ExpiresAt   *time.Time
+	CreatedAt   time.Time
+}
+
+// HeroActiveBuffRepository 英雄 Buff 仓储
+type HeroActiveBuffRepository interface {
+	// Create 施加 Buff
+	Create(ctx context.Context, execer boil.ContextExecutor, buff *HeroActiveBuff) error
+	// ListActiveByHero 查询英雄当前生效的 Buff（排除已过期和已结束地城的 Buff）
+	ListActiveByHero(ctx context.Context, heroID string) ([]*HeroActiveBuff, error)
+}
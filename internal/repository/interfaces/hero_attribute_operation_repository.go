@@ -22,6 +22,9 @@ type HeroAttributeOperationRepository interface {
 	// MarkAsRolledBack 标记为已回退
 	MarkAsRolledBack(ctx context.Context, execer boil.ContextExecutor, operationID string) error
 
+	// ExpireRollbackable 使英雄所有尚可回退的属性操作立即过期（重置属性后调用）
+	ExpireRollbackable(ctx context.Context, execer boil.ContextExecutor, heroID string) error
+
 	// GetTotalSpentXP 获取英雄在所有属性上花费的总经验
 	GetTotalSpentXP(ctx context.Context, heroID string) (int, error)
 
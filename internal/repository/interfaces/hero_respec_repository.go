@@ -0,0 +1,42 @@
+package interfaces
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// HeroRespecCost 洗点消耗配置（按英雄等级分档）
+type HeroRespecCost struct {
+	MinLevel  int
+	CostGold  int64
+	ItemID    *string // 可替代金币的洗点道具，为空表示只能支付金币
+	ItemCount int
+}
+
+// HeroRespecOperation 洗点审计记录
+type HeroRespecOperation struct {
+	ID              string
+	HeroID          string
+	PaymentMethod   string // gold/item
+	GoldSpent       int64
+	ItemID          *string
+	ItemsSpent      json.RawMessage
+	RefundedXP      int64
+	AttributesReset int
+	SkillsRemoved   int
+	SkillsReset     int
+	Snapshot        json.RawMessage // 洗点前的属性分配与技能等级
+	CreatedAt       time.Time
+}
+
+// HeroRespecRepository 英雄洗点仓储
+type HeroRespecRepository interface {
+	// GetCostForLevel 获取英雄等级适用的消耗档位，未配置时返回 nil
+	GetCostForLevel(ctx context.Context, level int) (*HeroRespecCost, error)
+	// CreateOperation 在事务内写入审计记录，ID 为空时由数据库生成
+	CreateOperation(ctx context.Context, tx *sql.Tx, op *HeroRespecOperation) error
+	// ListOperations 分页查询英雄的洗点记录（按时间倒序）
+	ListOperations(ctx context.Context, heroID string, limit, offset int) ([]*HeroRespecOperation, int64, error)
+}
@@ -22,6 +22,9 @@ type HeroSkillOperationRepository interface {
 	// MarkAsRolledBack 标记为已回退
 	MarkAsRolledBack(ctx context.Context, execer boil.ContextExecutor, operationID string) error
 
+	// ExpireRollbackable 使技能所有尚可回退的操作立即过期（重置技能后调用）
+	ExpireRollbackable(ctx context.Context, execer boil.ContextExecutor, heroSkillID string) error
+
 	// GetTotalSpentXPByHeroID 获取英雄在所有技能上花费的总经验
 	GetTotalSpentXPByHeroID(ctx context.Context, heroID string) (int, error)
 
@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"tsu-self/internal/entity/game_runtime"
+)
+
+// ItemOperationLogRepository 物品操作日志仓储接口
+type ItemOperationLogRepository interface {
+	// Create 写入操作日志（穿戴/卸下/强化/修理等）
+	Create(ctx context.Context, execer boil.ContextExecutor, log *game_runtime.ItemOperationLog) error
+
+	// ListByItemInstance 查询物品实例的操作日志（按时间倒序）
+	ListByItemInstance(ctx context.Context, itemInstanceID string, limit int) ([]*game_runtime.ItemOperationLog, error)
+}
@@ -0,0 +1,19 @@
+package interfaces
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// PeriodResetRepository 周期重置执行记录仓储
+type PeriodResetRepository interface {
+	// ClaimPeriod 在事务内登记重置项的周期；该周期已执行过返回 false
+	ClaimPeriod(ctx context.Context, tx *sql.Tx, resetKey string, periodStart time.Time) (bool, error)
+
+	// FinishPeriod 回写本周期重置影响的行数
+	FinishPeriod(ctx context.Context, tx *sql.Tx, resetKey string, periodStart time.Time, affected int64) error
+
+	// GetLastPeriod 获取重置项最近一次执行的周期起点，从未执行返回 nil
+	GetLastPeriod(ctx context.Context, resetKey string) (*time.Time, error)
+}
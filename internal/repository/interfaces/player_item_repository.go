@@ -19,6 +19,18 @@ type PlayerItemRepository interface {
 	// GetByIDForUpdate 根据ID获取装备实例（带行锁）
 	GetByIDForUpdate(ctx context.Context, tx *sql.Tx, itemInstanceID string) (*game_runtime.PlayerItem, error)
 
+	// ListBackpackStacksForUpdate 锁定英雄背包中指定物品配置的全部堆叠（数量少的优先）
+	ListBackpackStacksForUpdate(ctx context.Context, tx *sql.Tx, heroID, itemID string) ([]*game_runtime.PlayerItem, error)
+
+	// ListEquippedByItemForUpdate 锁定英雄已穿戴的指定物品配置实例
+	ListEquippedByItemForUpdate(ctx context.Context, tx *sql.Tx, heroID, itemID string) ([]*game_runtime.PlayerItem, error)
+
+	// CountHeroItems 统计英雄在指定位置（如 backpack、equipped）中指定物品配置的数量（按堆叠数累加），键为物品配置ID
+	CountHeroItems(ctx context.Context, heroID string, itemIDs []string, locations []string) (map[string]int, error)
+
+	// GetByIDIncludingDeletedForUpdate 根据ID获取物品实例（含已软删除，带行锁），不存在时返回 nil
+	GetByIDIncludingDeletedForUpdate(ctx context.Context, tx *sql.Tx, itemInstanceID string) (*game_runtime.PlayerItem, error)
+
 	// GetByOwner 查询玩家的装备实例列表
 	GetByOwner(ctx context.Context, ownerID string, location *string) ([]*game_runtime.PlayerItem, error)
 
@@ -40,6 +52,9 @@ type PlayerItemRepository interface {
 	// BatchUpdateDurability 批量更新装备耐久度
 	BatchUpdateDurability(ctx context.Context, execer boil.ContextExecutor, updates []DurabilityUpdate) error
 
+	// WearEquippedDurability 扣减英雄全部已穿戴装备的耐久（不低于0），返回发生变化的装备
+	WearEquippedDurability(ctx context.Context, execer boil.ContextExecutor, heroID string, amount int) ([]DurabilityUpdate, error)
+
 	// GetEquippedItems 查询已装备的物品
 	GetEquippedItems(ctx context.Context, ownerID string) ([]*game_runtime.PlayerItem, error)
 }
@@ -0,0 +1,104 @@
+package interfaces
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+// 团队动态分类
+const (
+	TeamActivityCategoryTeam         = "team"
+	TeamActivityCategoryMember       = "member"
+	TeamActivityCategoryRank         = "rank"
+	TeamActivityCategoryWarehouse    = "warehouse"
+	TeamActivityCategoryContribution = "contribution"
+	TeamActivityCategoryDungeon      = "dungeon"
+)
+
+// 团队动态事件类型
+const (
+	TeamActivityTeamCreated       = "team_created"
+	TeamActivityTeamUpdated       = "team_updated"
+	TeamActivityTeamDisbanded     = "team_disbanded"
+	TeamActivityLeaderTransferred = "leader_transferred"
+
+	TeamActivityJoinRequested      = "join_requested"
+	TeamActivityJoinApproved       = "join_approved"
+	TeamActivityJoinRejected       = "join_rejected"
+	TeamActivityMemberInvited      = "member_invited"
+	TeamActivityInvitationApproved = "invitation_approved"
+	TeamActivityInvitationRejected = "invitation_rejected"
+	TeamActivityInvitationAccepted = "invitation_accepted"
+	TeamActivityInvitationDeclined = "invitation_declined"
+	TeamActivityMemberLeft         = "member_left"
+	TeamActivityMemberKicked       = "member_kicked"
+	TeamActivityAdminPromoted      = "admin_promoted"
+	TeamActivityAdminDemoted       = "admin_demoted"
+
+	TeamActivityRankCreated  = "rank_created"
+	TeamActivityRankUpdated  = "rank_updated"
+	TeamActivityRankDeleted  = "rank_deleted"
+	TeamActivityRankAssigned = "rank_assigned"
+
+	TeamActivityLootStored          = "loot_stored"
+	TeamActivityGoldDistributed     = "gold_distributed"
+	TeamActivityItemsDistributed    = "items_distributed"
+	TeamActivityGoldDeposited       = "gold_deposited"
+	TeamActivityItemsDeposited      = "items_deposited"
+	TeamActivityWithdrawalRequested = "withdrawal_requested"
+	TeamActivityWithdrawalApproved  = "withdrawal_approved"
+	TeamActivityWithdrawalRejected  = "withdrawal_rejected"
+	TeamActivityWithdrawalCancelled = "withdrawal_cancelled"
+	TeamActivityLootPolicyUpdated   = "loot_policy_updated"
+	TeamActivityLootRollResolved    = "loot_roll_resolved"
+
+	TeamActivityContributionSettingsUpdated = "contribution_settings_updated"
+	TeamActivityContributionGranted         = "contribution_granted"
+	TeamActivityAuctionCreated              = "auction_created"
+	TeamActivityAuctionCancelled            = "auction_cancelled"
+	TeamActivityAuctionSettled              = "auction_settled"
+
+	TeamActivityDungeonSelected  = "dungeon_selected"
+	TeamActivityDungeonEntered   = "dungeon_entered"
+	TeamActivityDungeonCompleted = "dungeon_completed"
+	TeamActivityDungeonFailed    = "dungeon_failed"
+	TeamActivityDungeonAbandoned = "dungeon_abandoned"
+)
+
+// TeamActivity 团队动态记录
+type TeamActivity struct {
+	ID           string
+	TeamID       string
+	Category     string
+	EventType    string
+	ActorHeroID  *string // 系统触发（定时任务、自动分配）时为空
+	TargetHeroID *string
+	SourceID     *string
+	Details      json.RawMessage
+	CreatedAt    time.Time
+}
+
+// TeamActivityQuery 团队动态查询条件，空字符串/nil 表示不过滤
+type TeamActivityQuery struct {
+	TeamID    string
+	Category  string
+	EventType string
+	HeroID    string // 匹配操作者或受影响的英雄
+	Since     *time.Time
+	Until     *time.Time
+	Limit     int
+	Offset    int
+}
+
+// TeamActivityRepository 团队动态仓储
+type TeamActivityRepository interface {
+	// Create 写入团队动态（ID 为空时自动生成）
+	Create(ctx context.Context, execer boil.ContextExecutor, activity *TeamActivity) error
+	// List 按时间倒序分页查询团队动态
+	List(ctx context.Context, query TeamActivityQuery) ([]*TeamActivity, int64, error)
+	// DeleteBefore 删除早于指定时间的动态，单次最多删除 limit 条
+	DeleteBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+}
@@ -0,0 +1,128 @@
+package interfaces
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+// 贡献点来源类型
+const (
+	ContributionSourceDungeon       = "dungeon_completion"
+	ContributionSourceManual        = "manual_grant"
+	ContributionSourceAuctionBid    = "auction_bid"
+	ContributionSourceAuctionRefund = "auction_refund"
+)
+
+// 竞拍状态
+const (
+	ContributionAuctionOpen      = "open"
+	ContributionAuctionSettling  = "settling"
+	ContributionAuctionAwarded   = "awarded"
+	ContributionAuctionUnsold    = "unsold"
+	ContributionAuctionFailed    = "failed"
+	ContributionAuctionCancelled = "cancelled"
+)
+
+// LootPolicyAuction 竞拍获得的物品在分配历史中的分配方式
+const LootPolicyAuction = "auction"
+
+// ErrInsufficientContribution 贡献点不足
+var ErrInsufficientContribution = errors.New("insufficient contribution points")
+
+// TeamContributionSettings 团队贡献点配置
+type TeamContributionSettings struct {
+	TeamID                  string
+	DungeonCompletionPoints int
+	AuctionDurationSeconds  int
+	UpdatedAt               time.Time
+}
+
+// TeamContributionBalance 成员贡献点余额
+type TeamContributionBalance struct {
+	TeamID         string
+	HeroID         string
+	Balance        int64
+	LifetimeEarned int64
+	UpdatedAt      time.Time
+}
+
+// TeamContributionEntry 贡献点流水
+type TeamContributionEntry struct {
+	ID             string
+	TeamID         string
+	HeroID         string
+	Amount         int64 // 正数为获得，负数为消耗
+	BalanceAfter   int64
+	SourceType     string
+	SourceID       *string
+	OperatorHeroID *string
+	Note           *string
+	CreatedAt      time.Time
+}
+
+// TeamContributionAuction 仓库物品竞拍
+type TeamContributionAuction struct {
+	ID                  string
+	TeamID              string
+	WarehouseID         string
+	ItemID              string
+	Quantity            int
+	MinBid              int64
+	Status              string
+	CreatedBy           string
+	EndsAt              time.Time
+	HighestBid          int64
+	HighestBidderHeroID *string
+	ResultNote          *string
+	CreatedAt           time.Time
+	SettledAt           *time.Time
+}
+
+// TeamContributionBid 竞拍出价
+type TeamContributionBid struct {
+	ID        string
+	AuctionID string
+	HeroID    string
+	Amount    int64
+	CreatedAt time.Time
+}
+
+// TeamContributionRepository 团队贡献点仓储
+type TeamContributionRepository interface {
+	// GetSettings 获取团队配置，未配置时返回 nil
+	GetSettings(ctx context.Context, teamID string) (*TeamContributionSettings, error)
+	// UpsertSettings 保存团队配置
+	UpsertSettings(ctx context.Context, settings *TeamContributionSettings) error
+
+	// ApplyTx 在事务内调整余额并追加流水，回填 ID、BalanceAfter 与 CreatedAt；余额不足时返回 ErrInsufficientContribution
+	ApplyTx(ctx context.Context, execer boil.ContextExecutor, entry *TeamContributionEntry) error
+	// GetBalance 获取成员余额，无记录时返回 0
+	GetBalance(ctx context.Context, teamID, heroID string) (int64, error)
+	// ListBalances 获取团队贡献排行（按余额倒序）
+	ListBalances(ctx context.Context, teamID string) ([]*TeamContributionBalance, error)
+	// ListEntries 分页查询流水（按时间倒序），heroID 为空时查询全队
+	ListEntries(ctx context.Context, teamID, heroID string, limit, offset int) ([]*TeamContributionEntry, int64, error)
+
+	// CreateAuction 创建竞拍，回填 ID 与 CreatedAt
+	CreateAuction(ctx context.Context, execer boil.ContextExecutor, auction *TeamContributionAuction) error
+	// GetAuctionForUpdate 锁定竞拍，不存在时返回 nil
+	GetAuctionForUpdate(ctx context.Context, tx *sql.Tx, auctionID string) (*TeamContributionAuction, error)
+	// UpdateAuction 更新竞拍状态与最高出价
+	UpdateAuction(ctx context.Context, execer boil.ContextExecutor, auction *TeamContributionAuction) error
+	// SumOpenAuctionQuantity 统计仓库中某物品正在竞拍（竞拍中/结算中）的数量，excludeAuctionID 非空时不计该竞拍
+	SumOpenAuctionQuantity(ctx context.Context, execer boil.ContextExecutor, warehouseID, itemID, excludeAuctionID string) (int, error)
+	// ListAuctions 分页查询团队竞拍，status 为空时不过滤
+	ListAuctions(ctx context.Context, teamID, status string, limit, offset int) ([]*TeamContributionAuction, int64, error)
+	// ListEndedAuctionIDs 查询已到期仍在竞拍中的竞拍
+	ListEndedAuctionIDs(ctx context.Context, now time.Time, limit int) ([]string, error)
+	// ListStaleSettlingAuctionIDs 查询在 before 之前到期、仍停留在结算中的竞拍
+	ListStaleSettlingAuctionIDs(ctx context.Context, before time.Time, limit int) ([]string, error)
+	// CreateBid 记录出价
+	CreateBid(ctx context.Context, tx *sql.Tx, bid *TeamContributionBid) error
+	// ListBids 查询竞拍的出价记录（按时间倒序）
+	ListBids(ctx context.Context, auctionID string) ([]*TeamContributionBid, error)
+}
@@ -0,0 +1,35 @@
+package interfaces
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+// TeamDungeonEventLog 地城事件结算日志
+type TeamDungeonEventLog struct {
+	ID             string
+	ProgressID     string
+	TeamID         string
+	DungeonID      string
+	RoomID         string
+	EventID        *string
+	EventCode      *string
+	OperatorHeroID *string
+	AppliedEffects json.RawMessage // [{hero_id, buff_code, caster_level}]
+	LootGold       int64
+	LootItems      json.RawMessage // [{item_id, item_type, quantity}]
+	RewardExp      int
+	RewardedHeroes json.RawMessage // [hero_id]
+	RewardStatus   string          // pending/granted/failed
+	RewardError    *string
+}
+
+// TeamDungeonEventLogRepository 地城事件结算日志仓储
+type TeamDungeonEventLogRepository interface {
+	// Create 写入事件日志（ID 为空时自动生成）
+	Create(ctx context.Context, execer boil.ContextExecutor, log *TeamDungeonEventLog) error
+	// UpdateRewardStatus 更新奖励发放状态
+	UpdateRewardStatus(ctx context.Context, execer boil.ContextExecutor, id, status string, rewardErr *string) error
+}
@@ -17,4 +17,7 @@ type TeamDungeonRecordRepository interface {
 	GetByTeamAndDungeonForUpdate(ctx context.Context, execer boil.ContextExecutor, teamID, dungeonID string) (*game_runtime.TeamDungeonRecord, error)
 
 	ListByTeam(ctx context.Context, teamID string, limit, offset int) ([]*game_runtime.TeamDungeonRecord, int64, error)
+
+	// ResetAttempts 清零所有团队的挑战次数（周期重置）
+	ResetAttempts(ctx context.Context, execer boil.ContextExecutor) (int64, error)
 }
@@ -23,6 +23,8 @@ type TeamLootHistoryCreateReq struct {
 	ItemType         string // gold | item
 	ItemID           *string
 	Quantity         int64
+	Policy           string  // 分配方式，为空视为 manual
+	DecisionNote     *string // 决策说明
 }
 
 // TeamLootHistoryRow 查询结果
@@ -35,5 +37,7 @@ type TeamLootHistoryRow struct {
 	ItemType         string
 	ItemID           *string
 	Quantity         int64
+	Policy           string
+	DecisionNote     *string
 	DistributedAt    string
 }
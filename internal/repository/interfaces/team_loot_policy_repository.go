@@ -0,0 +1,102 @@
+package interfaces
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+// 团队战利品分配策略
+const (
+	LootPolicyManual     = "manual"      // 手动分配
+	LootPolicyEqualSplit = "equal_split" // 金币全员平分
+	LootPolicyRoundRobin = "round_robin" // 物品按入队顺序轮流
+	LootPolicyNeedGreed  = "need_greed"  // 需求/贪婪/放弃投骰
+	LootPolicyClassNeed  = "class_need"  // 仅可用职业能选需求的投骰
+)
+
+// 投骰选择
+const (
+	LootRollNeed  = "need"
+	LootRollGreed = "greed"
+	LootRollPass  = "pass"
+)
+
+// 投骰状态
+const (
+	LootRollStatusOpen      = "open"
+	LootRollStatusResolving = "resolving"
+	LootRollStatusAwarded   = "awarded"
+	LootRollStatusPassed    = "passed"
+	LootRollStatusFailed    = "failed"
+)
+
+// TeamLootPolicy 仓库分配策略
+type TeamLootPolicy struct {
+	WarehouseID        string
+	TeamID             string
+	GoldPolicy         string
+	ItemPolicy         string
+	RollTimeoutSeconds int
+}
+
+// TeamLootRoll 战利品投骰
+type TeamLootRoll struct {
+	ID            string
+	TeamID        string
+	WarehouseID   string
+	ItemID        string
+	ItemType      string
+	Quantity      int
+	Policy        string
+	Status        string
+	ExpiresAt     time.Time
+	WinnerHeroID  *string
+	WinningChoice *string
+	WinningRoll   *int
+	ResultNote    *string
+	CreatedAt     time.Time
+	ResolvedAt    *time.Time
+}
+
+// TeamLootRollVote 投骰选择
+type TeamLootRollVote struct {
+	RollID    string
+	HeroID    string
+	Choice    string
+	RollValue int
+	CreatedAt time.Time
+}
+
+// TeamLootPolicyRepository 团队战利品分配策略与投骰仓储
+type TeamLootPolicyRepository interface {
+	// GetPolicy 获取团队仓库的分配策略，仓库不存在返回 nil
+	GetPolicy(ctx context.Context, teamID string) (*TeamLootPolicy, error)
+	// UpdatePolicy 更新分配策略
+	UpdatePolicy(ctx context.Context, policy *TeamLootPolicy) error
+	// ReserveRoundRobin 原子地占用 count 个轮流顺位，返回起始游标
+	ReserveRoundRobin(ctx context.Context, warehouseID string, count int) (int, error)
+
+	// CreateRoll 创建投骰
+	CreateRoll(ctx context.Context, execer boil.ContextExecutor, roll *TeamLootRoll) error
+	// GetRollForUpdate 获取投骰（带行锁），不存在返回 nil
+	GetRollForUpdate(ctx context.Context, tx *sql.Tx, rollID string) (*TeamLootRoll, error)
+	// UpdateRollResult 更新投骰状态与结果
+	UpdateRollResult(ctx context.Context, execer boil.ContextExecutor, roll *TeamLootRoll) error
+	// ListRolls 分页查询团队投骰，status 为空时不过滤
+	ListRolls(ctx context.Context, teamID, status string, limit, offset int) ([]*TeamLootRoll, int64, error)
+	// ListExpiredRollIDs 查询已超时仍未结算的投骰
+	ListExpiredRollIDs(ctx context.Context, now time.Time, limit int) ([]string, error)
+	// ListStaleResolvingRollIDs 查询在 before 之前开始结算、仍停留在 resolving 的投骰
+	ListStaleResolvingRollIDs(ctx context.Context, before time.Time, limit int) ([]string, error)
+
+	// SaveVote 记录投骰选择；已投过返回 false
+	SaveVote(ctx context.Context, tx *sql.Tx, vote *TeamLootRollVote) (bool, error)
+	// ListVotes 查询投骰的全部选择
+	ListVotes(ctx context.Context, execer boil.ContextExecutor, rollID string) ([]*TeamLootRollVote, error)
+
+	// ListItemClassIDs 查询物品可用职业（item_class_relations），为空表示通用
+	ListItemClassIDs(ctx context.Context, itemID string) ([]string, error)
+}
@@ -0,0 +1,41 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+// TeamRank 团队自定义职阶
+type TeamRank struct {
+	ID          string
+	TeamID      string
+	Name        string
+	Level       int
+	Permissions []string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// TeamRankRepository 团队职阶仓储
+type TeamRankRepository interface {
+	// Create 创建职阶
+	Create(ctx context.Context, execer boil.ContextExecutor, rank *TeamRank) error
+	// Update 更新职阶名称、等级和权限
+	Update(ctx context.Context, execer boil.ContextExecutor, rank *TeamRank) error
+	// Delete 删除职阶，成员的职阶由外键置空
+	Delete(ctx context.Context, execer boil.ContextExecutor, teamID, rankID string) error
+	// GetByID 获取团队内的职阶，不存在返回 nil
+	GetByID(ctx context.Context, teamID, rankID string) (*TeamRank, error)
+	// ListByTeam 查询团队全部职阶，按等级从高到低
+	ListByTeam(ctx context.Context, teamID string) ([]*TeamRank, error)
+	// CountByTeam 统计团队职阶数
+	CountByTeam(ctx context.Context, teamID string) (int, error)
+	// GetMemberRank 获取成员当前职阶，未分配返回 nil
+	GetMemberRank(ctx context.Context, teamID, heroID string) (*TeamRank, error)
+	// SetMemberRank 设置成员职阶，rankID 为 nil 表示取消职阶
+	SetMemberRank(ctx context.Context, execer boil.ContextExecutor, teamID, heroID string, rankID *string) error
+	// ListMemberHeroIDs 查询持有该职阶的成员英雄ID
+	ListMemberHeroIDs(ctx context.Context, teamID, rankID string) ([]string, error)
+}
@@ -6,6 +6,13 @@ import (
 	"github.com/aarondl/sqlboiler/v4/boil"
 )
 
+// 仓库日志类型
+const (
+	WarehouseLogEntryLoot       = "loot"       // 战利品入库
+	WarehouseLogEntryDeposit    = "deposit"    // 成员捐献
+	WarehouseLogEntryWithdrawal = "withdrawal" // 取用申请
+)
+
 // TeamWarehouseLootLogRepository 战利品入库审计日志仓储
 type TeamWarehouseLootLogRepository interface {
 	Log(ctx context.Context, execer boil.ContextExecutor, req *TeamWarehouseLootLog) error
@@ -20,4 +27,7 @@ type TeamWarehouseLootLog struct {
 	ItemsJSON      string
 	Result         string // success|failed
 	Reason         *string
+	EntryType      string  // loot|deposit|withdrawal，为空视为 loot
+	HeroID         *string // 捐献者或申请人
+	RequestID      *string // 关联的取用申请
 }
@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"tsu-self/internal/entity/game_runtime"
@@ -15,6 +16,9 @@ type TeamWarehouseRepository interface {
 	// GetByTeamID 根据团队ID获取仓库
 	GetByTeamID(ctx context.Context, teamID string) (*game_runtime.TeamWarehouse, error)
 
+	// GetByTeamIDForUpdate 根据团队ID获取仓库（带行锁），用于串行化同一仓库的库存与容量校验
+	GetByTeamIDForUpdate(ctx context.Context, tx *sql.Tx, teamID string) (*game_runtime.TeamWarehouse, error)
+
 	// Update 更新仓库信息
 	Update(ctx context.Context, execer boil.ContextExecutor, warehouse *game_runtime.TeamWarehouse) error
 
@@ -0,0 +1,50 @@
+package interfaces
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+// 取用申请状态
+const (
+	WithdrawalStatusPending   = "pending"
+	WithdrawalStatusApproved  = "approved"
+	WithdrawalStatusDenied    = "denied"
+	WithdrawalStatusCancelled = "cancelled"
+)
+
+// LootPolicyWithdrawal 取用申请批准后发放的物品在分配历史中的分配方式
+const LootPolicyWithdrawal = "withdrawal"
+
+// TeamWarehouseWithdrawal 仓库物品取用申请
+type TeamWarehouseWithdrawal struct {
+	ID             string
+	TeamID         string
+	WarehouseID    string
+	HeroID         string
+	ItemID         string
+	Quantity       int
+	Note           *string
+	Status         string
+	ReviewerHeroID *string
+	ReviewNote     *string
+	CreatedAt      time.Time
+	ReviewedAt     *time.Time
+}
+
+// TeamWarehouseWithdrawalRepository 仓库取用申请仓储
+type TeamWarehouseWithdrawalRepository interface {
+	// Create 创建取用申请
+	Create(ctx context.Context, execer boil.ContextExecutor, req *TeamWarehouseWithdrawal) error
+	// GetForUpdate 锁定并获取申请，不存在返回 nil
+	GetForUpdate(ctx context.Context, tx *sql.Tx, requestID string) (*TeamWarehouseWithdrawal, error)
+	// UpdateReview 写回审批结果（状态、审批人、说明、审批时间）
+	UpdateReview(ctx context.Context, execer boil.ContextExecutor, req *TeamWarehouseWithdrawal) error
+	// CountPendingByHero 统计英雄在团队中待审批的申请数
+	CountPendingByHero(ctx context.Context, teamID, heroID string) (int, error)
+	// List 按团队查询申请，status/heroID 为空表示不过滤
+	List(ctx context.Context, teamID, status, heroID string, limit, offset int) ([]*TeamWarehouseWithdrawal, int64, error)
+}
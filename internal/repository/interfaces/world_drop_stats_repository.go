@@ -3,6 +3,7 @@ package interfaces
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"tsu-self/internal/entity/game_runtime"
@@ -36,5 +37,11 @@ type WorldDropStatsRepository interface {
 
 	// GetStatsNeedingHourlyReset 获取需要每小时重置的统计记录
 	GetStatsNeedingHourlyReset(ctx context.Context) ([]*game_runtime.WorldDropStat, error)
+
+	// ResetAllDaily 批量清零上次重置早于 periodStart 的每日计数
+	ResetAllDaily(ctx context.Context, execer boil.ContextExecutor, periodStart time.Time) (int64, error)
+
+	// ResetAllHourly 批量清零上次重置早于 periodStart 的每小时计数
+	ResetAllHourly(ctx context.Context, execer boil.ContextExecutor, periodStart time.Time) (int64, error)
 }
 
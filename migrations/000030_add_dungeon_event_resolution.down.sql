@@ -0,0 +1,4 @@
+-- 000030_add_dungeon_event_resolution.down.sql
+
+DROP TABLE IF EXISTS game_runtime.team_dungeon_event_logs;
+DROP TABLE IF EXISTS game_runtime.hero_active_buffs;
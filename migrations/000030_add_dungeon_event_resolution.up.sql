@@ -0,0 +1,65 @@
+-- 000030_add_dungeon_event_resolution.up.sql
+-- 地城事件房间结算：英雄生效中的 Buff、事件结算日志
+
+-- 1) 英雄生效中的 Buff（事件/道具施加）
+CREATE TABLE IF NOT EXISTS game_runtime.hero_active_buffs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    hero_id UUID NOT NULL REFERENCES game_runtime.heroes(id) ON DELETE CASCADE,
+    buff_id UUID REFERENCES game_config.buffs(id) ON DELETE SET NULL,
+    buff_code VARCHAR(50) NOT NULL,
+    buff_params JSONB NOT NULL DEFAULT '{}',
+    caster_level INT NOT NULL DEFAULT 1 CHECK (caster_level >= 1),
+    source_type VARCHAR(32) NOT NULL,
+    source_id VARCHAR(64),
+    progress_id UUID REFERENCES game_runtime.team_dungeon_progress(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_hero_active_buffs_hero ON game_runtime.hero_active_buffs(hero_id);
+CREATE INDEX IF NOT EXISTS idx_hero_active_buffs_progress ON game_runtime.hero_active_buffs(progress_id) WHERE progress_id IS NOT NULL;
+
+CREATE TRIGGER update_hero_active_buffs_updated_at
+    BEFORE UPDATE ON game_runtime.hero_active_buffs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE game_runtime.hero_active_buffs IS '英雄当前生效的 Buff（地城事件、道具等来源）';
+COMMENT ON COLUMN game_runtime.hero_active_buffs.source_type IS 'Buff 来源：dungeon_event/item 等';
+COMMENT ON COLUMN game_runtime.hero_active_buffs.source_id IS '来源 ID（事件代码、物品实例 ID 等）';
+COMMENT ON COLUMN game_runtime.hero_active_buffs.progress_id IS '地城进度 ID，非空时仅在该进度进行中生效';
+COMMENT ON COLUMN game_runtime.hero_active_buffs.expires_at IS '过期时间，为空表示不限时';
+
+-- 2) 地城事件结算日志
+CREATE TABLE IF NOT EXISTS game_runtime.team_dungeon_event_logs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    progress_id UUID NOT NULL REFERENCES game_runtime.team_dungeon_progress(id) ON DELETE CASCADE,
+    team_id UUID NOT NULL REFERENCES game_runtime.teams(id) ON DELETE CASCADE,
+    dungeon_id UUID NOT NULL REFERENCES game_config.dungeons(id),
+    room_id VARCHAR(64) NOT NULL,
+    event_id UUID REFERENCES game_config.dungeon_events(id),
+    event_code VARCHAR(50),
+    operator_hero_id UUID,
+    applied_effects JSONB NOT NULL DEFAULT '[]',
+    loot_gold BIGINT NOT NULL DEFAULT 0 CHECK (loot_gold >= 0),
+    loot_items JSONB NOT NULL DEFAULT '[]',
+    reward_exp INT NOT NULL DEFAULT 0 CHECK (reward_exp >= 0),
+    rewarded_heroes JSONB NOT NULL DEFAULT '[]',
+    reward_status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    reward_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dungeon_event_logs_progress ON game_runtime.team_dungeon_event_logs(progress_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_dungeon_event_logs_team ON game_runtime.team_dungeon_event_logs(team_id, created_at DESC);
+
+CREATE TRIGGER update_team_dungeon_event_logs_updated_at
+    BEFORE UPDATE ON game_runtime.team_dungeon_event_logs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE game_runtime.team_dungeon_event_logs IS '地城事件房间结算日志（效果、掉落、经验）';
+COMMENT ON COLUMN game_runtime.team_dungeon_event_logs.applied_effects IS '已施加的效果 JSON 列表 [{hero_id, buff_code, caster_level}]';
+COMMENT ON COLUMN game_runtime.team_dungeon_event_logs.loot_items IS '掉落物品 JSON 列表 [{item_id, item_type, quantity}]';
+COMMENT ON COLUMN game_runtime.team_dungeon_event_logs.rewarded_heroes IS '获得经验的英雄 ID 列表';
+COMMENT ON COLUMN game_runtime.team_dungeon_event_logs.reward_status IS '奖励发放状态：pending/granted/failed';
@@ -0,0 +1,3 @@
+-- 000031_add_equipment_enhancement.down.sql
+
+DROP TABLE IF EXISTS game_config.equipment_enhancement_levels;
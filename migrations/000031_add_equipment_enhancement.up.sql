@@ -0,0 +1,48 @@
+-- 000031_add_equipment_enhancement.up.sql
+-- 装备强化：按目标等级配置的成功/失败/降级概率表
+
+CREATE TABLE IF NOT EXISTS game_config.equipment_enhancement_levels (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    target_level SMALLINT NOT NULL UNIQUE CHECK (target_level >= 1),
+    success_rate DECIMAL(5,4) NOT NULL CHECK (success_rate >= 0 AND success_rate <= 1),
+    downgrade_rate DECIMAL(5,4) NOT NULL DEFAULT 0 CHECK (downgrade_rate >= 0 AND downgrade_rate <= 1),
+    downgrade_levels SMALLINT NOT NULL DEFAULT 1 CHECK (downgrade_levels >= 0),
+    material_quantity INT NOT NULL DEFAULT 1 CHECK (material_quantity >= 0),
+    gold_multiplier DECIMAL(6,2) NOT NULL DEFAULT 1 CHECK (gold_multiplier >= 0),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_equipment_enhancement_levels_updated_at
+    BEFORE UPDATE ON game_config.equipment_enhancement_levels
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE game_config.equipment_enhancement_levels IS '装备强化等级配置：强化到 target_level 时的概率与消耗';
+COMMENT ON COLUMN game_config.equipment_enhancement_levels.target_level IS '目标强化等级（当前等级 + 1）';
+COMMENT ON COLUMN game_config.equipment_enhancement_levels.success_rate IS '成功概率（0-1）';
+COMMENT ON COLUMN game_config.equipment_enhancement_levels.downgrade_rate IS '失败后降级概率（0-1），未降级则保持原等级';
+COMMENT ON COLUMN game_config.equipment_enhancement_levels.downgrade_levels IS '降级时下降的等级数';
+COMMENT ON COLUMN game_config.equipment_enhancement_levels.material_quantity IS '消耗强化材料数量（材料由 items.enhancement_material_id 指定）';
+COMMENT ON COLUMN game_config.equipment_enhancement_levels.gold_multiplier IS '金币消耗倍率（基础消耗为 items.enhancement_cost_gold）';
+
+-- 默认配置：+1~+5 必定成功，之后逐级降低成功率，+8 起失败可能降级
+INSERT INTO game_config.equipment_enhancement_levels
+    (target_level, success_rate, downgrade_rate, downgrade_levels, material_quantity, gold_multiplier)
+VALUES
+    (1, 1.0000, 0.0000, 0, 1, 1.00),
+    (2, 1.0000, 0.0000, 0, 1, 1.20),
+    (3, 1.0000, 0.0000, 0, 1, 1.40),
+    (4, 1.0000, 0.0000, 0, 2, 1.60),
+    (5, 1.0000, 0.0000, 0, 2, 1.80),
+    (6, 0.8000, 0.0000, 0, 2, 2.00),
+    (7, 0.7000, 0.0000, 0, 3, 2.50),
+    (8, 0.6000, 0.2000, 1, 3, 3.00),
+    (9, 0.5000, 0.3000, 1, 4, 3.50),
+    (10, 0.4000, 0.4000, 1, 4, 4.00),
+    (11, 0.3000, 0.5000, 1, 5, 5.00),
+    (12, 0.2500, 0.5000, 2, 5, 6.00),
+    (13, 0.2000, 0.6000, 2, 6, 7.00),
+    (14, 0.1500, 0.6000, 2, 6, 8.00),
+    (15, 0.1000, 0.7000, 3, 8, 10.00)
+ON CONFLICT (target_level) DO NOTHING;
@@ -0,0 +1,10 @@
+-- 000032_add_gem_socketing.down.sql
+-- PostgreSQL 不支持删除枚举值，'socketed' 保留；先将镶嵌中的宝石退回背包
+
+UPDATE game_runtime.player_items
+SET item_location = 'backpack'
+WHERE item_location = 'socketed';
+
+UPDATE game_runtime.player_items
+SET socketed_gems = NULL
+WHERE socketed_gems IS NOT NULL;
@@ -0,0 +1,8 @@
+-- 000032_add_gem_socketing.up.sql
+-- 宝石镶嵌：镶嵌中的宝石实例使用独立的物品位置
+
+ALTER TYPE item_location_enum ADD VALUE IF NOT EXISTS 'socketed';
+
+COMMENT ON COLUMN game_runtime.player_items.socketed_gems IS '镶嵌的宝石 - 仅装备类物品有效,格式: [{"socket_index":0,"gem_item_id":"宝石实例uuid","item_id":"宝石配置uuid"}]';
+COMMENT ON COLUMN game_config.socket_type_configs.socket_layout IS '孔位布局 - {"small":2,"large":1}(按 small、large 顺序编号) 或 [{"size":"small","colors":["red","blue"]}](colors 为空表示任意颜色)';
+COMMENT ON COLUMN game_config.gem_effect_configs.effects IS '效果配置 - 与 items.out_of_combat_effects 格式一致: [{"Data_type":"Status","Data_ID":"STR","Bouns_type":"bonus","Bouns_Number":"5"}]';
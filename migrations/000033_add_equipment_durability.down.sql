@@ -0,0 +1,4 @@
+-- 000033_add_equipment_durability.down.sql
+
+DROP TABLE IF EXISTS game_config.equipment_repair_costs;
+DROP TABLE IF EXISTS game_config.dungeon_durability_configs;
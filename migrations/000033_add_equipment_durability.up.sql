@@ -0,0 +1,52 @@
+-- 000033_add_equipment_durability.up.sql
+-- 装备耐久：战斗磨损配置（按地城）与金币修理价格
+
+-- 1) 战斗耐久磨损配置，dungeon_id 为空的行为默认配置
+CREATE TABLE IF NOT EXISTS game_config.dungeon_durability_configs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    dungeon_id UUID REFERENCES game_config.dungeons(id) ON DELETE CASCADE,
+    victory_loss INT NOT NULL DEFAULT 1 CHECK (victory_loss >= 0),
+    defeat_loss INT NOT NULL DEFAULT 3 CHECK (defeat_loss >= 0),
+    death_extra_loss INT NOT NULL DEFAULT 0 CHECK (death_extra_loss >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_dungeon_durability_configs_dungeon
+    ON game_config.dungeon_durability_configs(dungeon_id) WHERE dungeon_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_dungeon_durability_configs_default
+    ON game_config.dungeon_durability_configs((dungeon_id IS NULL)) WHERE dungeon_id IS NULL;
+
+CREATE TRIGGER update_dungeon_durability_configs_updated_at
+    BEFORE UPDATE ON game_config.dungeon_durability_configs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE game_config.dungeon_durability_configs IS '战斗耐久磨损配置，dungeon_id 为空表示默认配置';
+COMMENT ON COLUMN game_config.dungeon_durability_configs.victory_loss IS '胜利时每件已穿戴装备损失的耐久';
+COMMENT ON COLUMN game_config.dungeon_durability_configs.defeat_loss IS '失败时每件已穿戴装备损失的耐久';
+COMMENT ON COLUMN game_config.dungeon_durability_configs.death_extra_loss IS '参战英雄阵亡时额外损失的耐久';
+
+INSERT INTO game_config.dungeon_durability_configs (dungeon_id, victory_loss, defeat_loss, death_extra_loss)
+SELECT NULL, 1, 3, 2
+WHERE NOT EXISTS (SELECT 1 FROM game_config.dungeon_durability_configs WHERE dungeon_id IS NULL);
+
+-- 2) 金币修理价格（按装备品质，每点耐久）
+CREATE TABLE IF NOT EXISTS game_config.equipment_repair_costs (
+    item_quality item_quality_enum PRIMARY KEY,
+    gold_per_point INT NOT NULL CHECK (gold_per_point >= 0)
+);
+
+COMMENT ON TABLE game_config.equipment_repair_costs IS '金币修理价格：按装备品质配置每点耐久的金币消耗';
+
+INSERT INTO game_config.equipment_repair_costs (item_quality, gold_per_point)
+VALUES
+    ('poor', 1),
+    ('normal', 1),
+    ('fine', 2),
+    ('excellent', 3),
+    ('superb', 4),
+    ('master', 6),
+    ('epic', 8),
+    ('legendary', 12),
+    ('mythic', 20)
+ON CONFLICT (item_quality) DO NOTHING;
@@ -0,0 +1,6 @@
+-- 000034_add_item_use_effects.down.sql
+
+DELETE FROM game_config.effect_type_definitions
+WHERE effect_type_code IN ('ADD_EXPERIENCE', 'ADD_GOLD', 'RESET_ATTRIBUTES', 'RESET_SKILLS', 'APPLY_BUFF');
+
+COMMENT ON COLUMN game_config.items.use_effects IS NULL;
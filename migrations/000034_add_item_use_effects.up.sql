@@ -0,0 +1,36 @@
+-- 000034_add_item_use_effects.up.sql
+-- 消耗品使用效果：注册 use_effects 可用的元效果类型
+
+-- use_effects 格式与 in_combat_effects 一致：[{"Effect":"ADD_EXPERIENCE","params":{"amount":500}}]
+-- Effect 必须是 effect_type_definitions 中启用的 effect_type_code，parameter_list 为必填参数
+-- failure_handling = continue 时跳过失败的效果，其余情况整次使用失败且不消耗物品
+COMMENT ON COLUMN game_config.items.use_effects IS '使用效果(消耗品)，格式: [{"Effect":"ADD_EXPERIENCE","params":{"amount":500}}]，Effect 对应 effect_type_definitions.effect_type_code';
+
+INSERT INTO game_config.effect_type_definitions
+    (effect_type_code, effect_type_name, description, parameter_list, parameter_descriptions, parameter_definitions, failure_handling, json_template, example)
+SELECT v.code, v.name, v.description, v.params, v.param_desc, v.param_defs::jsonb, v.failure_handling, v.template::jsonb, v.example
+FROM (VALUES
+    ('ADD_EXPERIENCE', '增加经验', '使用物品后为英雄增加经验，并自动检查升级',
+        ARRAY['amount'], 'amount: 增加的经验值',
+        '{"amount":{"type":"integer","min":1}}', 'skip_remaining',
+        '{"Effect":"ADD_EXPERIENCE","params":{"amount":0}}', '经验药水：{"Effect":"ADD_EXPERIENCE","params":{"amount":500}}'),
+    ('ADD_GOLD', '增加金币', '使用物品后为英雄钱包增加金币，配置 amount_max 时在 [amount, amount_max] 内随机',
+        ARRAY['amount'], 'amount: 金币数量（或随机下限）; amount_max: 随机上限（可选）',
+        '{"amount":{"type":"integer","min":1},"amount_max":{"type":"integer","min":1,"optional":true}}', 'skip_remaining',
+        '{"Effect":"ADD_GOLD","params":{"amount":0}}', '金币袋：{"Effect":"ADD_GOLD","params":{"amount":100,"amount_max":300}}'),
+    ('RESET_ATTRIBUTES', '重置属性', '将所有已分配属性恢复为初始值并返还花费的经验',
+        ARRAY[]::TEXT[], NULL, '{}', 'skip_remaining',
+        '{"Effect":"RESET_ATTRIBUTES"}', '属性重置卷轴：{"Effect":"RESET_ATTRIBUTES"}'),
+    ('RESET_SKILLS', '重置技能', '遗忘非初始技能、初始技能降回 1 级，并返还花费的经验',
+        ARRAY[]::TEXT[], NULL, '{}', 'skip_remaining',
+        '{"Effect":"RESET_SKILLS"}', '技能重置卷轴：{"Effect":"RESET_SKILLS"}'),
+    ('APPLY_BUFF', '施加局外Buff', '为英雄施加限时局外 Buff，其余参数作为 buff_params 写入（attribute_code/bonus_type/value 或 modifiers）',
+        ARRAY['buff_code', 'duration_seconds'], 'buff_code: Buff代码; duration_seconds: 持续秒数',
+        '{"buff_code":{"type":"string"},"duration_seconds":{"type":"integer","min":1}}', 'skip_remaining',
+        '{"Effect":"APPLY_BUFF","params":{"buff_code":"","duration_seconds":0}}',
+        '力量药剂：{"Effect":"APPLY_BUFF","params":{"buff_code":"STR_ELIXIR","duration_seconds":1800,"attribute_code":"STR","bonus_type":"flat","value":5}}')
+) AS v(code, name, description, params, param_desc, param_defs, failure_handling, template, example)
+WHERE NOT EXISTS (
+    SELECT 1 FROM game_config.effect_type_definitions d
+    WHERE d.effect_type_code = v.code AND d.deleted_at IS NULL
+);
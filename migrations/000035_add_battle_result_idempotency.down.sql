@@ -0,0 +1,4 @@
+-- 000035_add_battle_result_idempotency.down.sql
+
+DROP TABLE IF EXISTS game_runtime.battle_callback_nonces;
+DROP TABLE IF EXISTS game_runtime.battle_result_outcomes;
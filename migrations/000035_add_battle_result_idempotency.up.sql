@@ -0,0 +1,37 @@
+-- 000035_add_battle_result_idempotency.up.sql
+-- 战斗结果回调幂等与防重放：battle_id 结算结果缓存 + 签名 nonce 记录
+
+CREATE TABLE IF NOT EXISTS game_runtime.battle_result_outcomes (
+    battle_id VARCHAR(64) PRIMARY KEY,
+    status VARCHAR(16) NOT NULL DEFAULT 'processing',
+    outcome JSONB,
+    error_message TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_battle_result_outcomes_status CHECK (status IN ('processing', 'completed', 'failed'))
+);
+
+CREATE TRIGGER update_battle_result_outcomes_updated_at
+    BEFORE UPDATE ON game_runtime.battle_result_outcomes
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE game_runtime.battle_result_outcomes IS '战斗结果结算记录，同一 battle_id 只结算一次，重复回调直接返回首次结果';
+COMMENT ON COLUMN game_runtime.battle_result_outcomes.battle_id IS '外部战斗引擎提供的唯一战斗 ID';
+COMMENT ON COLUMN game_runtime.battle_result_outcomes.status IS '结算状态：processing(处理中)/completed(已完成)/failed(失败，可重试)';
+COMMENT ON COLUMN game_runtime.battle_result_outcomes.outcome IS '首次结算结果 JSON（地城进度），非地城胜利为空';
+COMMENT ON COLUMN game_runtime.battle_result_outcomes.error_message IS '失败原因';
+
+CREATE TABLE IF NOT EXISTS game_runtime.battle_callback_nonces (
+    nonce VARCHAR(128) NOT NULL,
+    key_id VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (key_id, nonce)
+);
+
+CREATE INDEX IF NOT EXISTS idx_battle_callback_nonces_expires ON game_runtime.battle_callback_nonces(expires_at);
+
+COMMENT ON TABLE game_runtime.battle_callback_nonces IS '战斗回调签名 nonce，时间窗口内同一 nonce 只能使用一次';
+COMMENT ON COLUMN game_runtime.battle_callback_nonces.nonce IS '请求方生成的随机串';
+COMMENT ON COLUMN game_runtime.battle_callback_nonces.key_id IS '签名密钥 ID';
+COMMENT ON COLUMN game_runtime.battle_callback_nonces.expires_at IS '过期时间，过期后可清理';
@@ -0,0 +1,3 @@
+-- 000036_add_dungeon_schedules.down.sql
+
+DROP TABLE IF EXISTS game_config.dungeon_schedules;
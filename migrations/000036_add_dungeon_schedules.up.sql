@@ -0,0 +1,23 @@
+-- 000036_add_dungeon_schedules.up.sql
+-- 地城周期性开放时间表：按周/按月/cron 规则 + 时区 + 停开日期
+-- 与 dungeons.is_time_limited 的绝对时间范围同时生效：两者都满足时才开放
+
+CREATE TABLE IF NOT EXISTS game_config.dungeon_schedules (
+    dungeon_id UUID PRIMARY KEY REFERENCES game_config.dungeons(id) ON DELETE CASCADE,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    rules JSONB NOT NULL DEFAULT '[]'::jsonb,
+    blackout_dates JSONB NOT NULL DEFAULT '[]'::jsonb,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_dungeon_schedules_updated_at
+    BEFORE UPDATE ON game_config.dungeon_schedules
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE game_config.dungeon_schedules IS '地城周期性开放时间表，未配置或停用时地城不受周期限制';
+COMMENT ON COLUMN game_config.dungeon_schedules.timezone IS 'IANA 时区，例如 Asia/Shanghai';
+COMMENT ON COLUMN game_config.dungeon_schedules.rules IS '开放规则列表，例如 [{"type":"weekly","weekdays":[2,4],"start_time":"20:00","end_time":"22:00"}]，type 支持 weekly/monthly/cron';
+COMMENT ON COLUMN game_config.dungeon_schedules.blackout_dates IS '停开日期列表，例如 ["2026-02-17","2026-10-01~2026-10-07"]';
+COMMENT ON COLUMN game_config.dungeon_schedules.is_active IS '是否启用时间表';
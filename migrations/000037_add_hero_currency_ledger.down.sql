@@ -0,0 +1,7 @@
+-- 000037_add_hero_currency_ledger.down.sql
+
+DROP TABLE IF EXISTS game_config.currency_rewards;
+DROP TABLE IF EXISTS game_runtime.hero_currency_transactions;
+DROP FUNCTION IF EXISTS game_runtime.reject_currency_transaction_mutation();
+DROP TABLE IF EXISTS game_runtime.hero_currency_balances;
+DROP TABLE IF EXISTS game_config.currencies;
@@ -0,0 +1,96 @@
+-- 000037_add_hero_currency_ledger.up.sql
+-- 英雄多货币账本：货币定义 + 余额 + 只追加流水
+-- 金币余额仍保存在 hero_wallets.gold_amount，其余货币保存在 hero_currency_balances，所有变动统一写入流水
+
+-- 1) 货币定义（gold/honor 为系统货币，管理员可新增自定义货币）
+CREATE TABLE IF NOT EXISTS game_config.currencies (
+    currency_code VARCHAR(32) PRIMARY KEY,
+    currency_name VARCHAR(64) NOT NULL,
+    description TEXT,
+    is_system BOOLEAN NOT NULL DEFAULT FALSE,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT currencies_code_format CHECK (currency_code ~ '^[a-z][a-z0-9_]*$')
+);
+
+CREATE TRIGGER update_currencies_updated_at
+    BEFORE UPDATE ON game_config.currencies
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+INSERT INTO game_config.currencies (currency_code, currency_name, description, is_system)
+VALUES
+    ('gold', '金币', '通用货币，余额保存在 hero_wallets', TRUE),
+    ('honor', '荣誉', '职业进阶等玩法消耗的荣誉值', TRUE)
+ON CONFLICT (currency_code) DO NOTHING;
+
+COMMENT ON TABLE game_config.currencies IS '货币定义表';
+COMMENT ON COLUMN game_config.currencies.is_system IS '系统货币不可删除或停用';
+
+-- 2) 非金币货币余额
+CREATE TABLE IF NOT EXISTS game_runtime.hero_currency_balances (
+    hero_id UUID NOT NULL REFERENCES game_runtime.heroes(id) ON DELETE CASCADE,
+    currency_code VARCHAR(32) NOT NULL REFERENCES game_config.currencies(currency_code),
+    balance BIGINT NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (hero_id, currency_code)
+);
+
+CREATE TRIGGER update_hero_currency_balances_updated_at
+    BEFORE UPDATE ON game_runtime.hero_currency_balances
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE game_runtime.hero_currency_balances IS '英雄货币余额（金币除外）';
+
+-- 3) 只追加流水
+CREATE TABLE IF NOT EXISTS game_runtime.hero_currency_transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    hero_id UUID NOT NULL,
+    currency_code VARCHAR(32) NOT NULL REFERENCES game_config.currencies(currency_code),
+    amount BIGINT NOT NULL CHECK (amount <> 0),
+    balance_after BIGINT NOT NULL CHECK (balance_after >= 0),
+    source_type VARCHAR(32) NOT NULL,
+    source_id VARCHAR(64),
+    note TEXT,
+    metadata JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_hero_currency_transactions_hero
+    ON game_runtime.hero_currency_transactions (hero_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_hero_currency_transactions_source
+    ON game_runtime.hero_currency_transactions (source_type, source_id);
+
+CREATE OR REPLACE FUNCTION game_runtime.reject_currency_transaction_mutation()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'hero_currency_transactions is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER hero_currency_transactions_append_only
+    BEFORE UPDATE OR DELETE ON game_runtime.hero_currency_transactions
+    FOR EACH ROW EXECUTE FUNCTION game_runtime.reject_currency_transaction_mutation();
+
+COMMENT ON TABLE game_runtime.hero_currency_transactions IS '英雄货币流水（只追加，禁止修改与删除）';
+COMMENT ON COLUMN game_runtime.hero_currency_transactions.amount IS '变动数量，正数为获得，负数为消耗';
+COMMENT ON COLUMN game_runtime.hero_currency_transactions.source_type IS '来源：dungeon_completion/dungeon_event/class_advancement/admin_grant/enhancement/repair/item_use/warehouse';
+COMMENT ON COLUMN game_runtime.hero_currency_transactions.source_id IS '来源对象ID，例如地城ID、事件ID、目标职业ID';
+
+-- 4) 货币奖励配置：地城通关与事件房间为每名成员发放
+CREATE TABLE IF NOT EXISTS game_config.currency_rewards (
+    source_type VARCHAR(32) NOT NULL CHECK (source_type IN ('dungeon', 'dungeon_event')),
+    source_id UUID NOT NULL,
+    currency_code VARCHAR(32) NOT NULL REFERENCES game_config.currencies(currency_code) ON DELETE CASCADE,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (source_type, source_id, currency_code)
+);
+
+CREATE TRIGGER update_currency_rewards_updated_at
+    BEFORE UPDATE ON game_config.currency_rewards
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE game_config.currency_rewards IS '货币奖励配置：source_type=dungeon 时通关发放，dungeon_event 时事件房间结算发放，数量为每名成员所得';
@@ -0,0 +1,4 @@
+-- 000038_add_hero_respec.down.sql
+
+DROP TABLE IF EXISTS game_runtime.hero_respec_operations;
+DROP TABLE IF EXISTS game_config.hero_respec_costs;
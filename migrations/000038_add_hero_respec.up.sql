@@ -0,0 +1,46 @@
+-- 000038_add_hero_respec.up.sql
+-- 英雄洗点：按等级分档的消耗配置 + 聚合审计记录
+
+-- 1) 洗点消耗配置（取 min_level <= 英雄等级的最高一档）
+CREATE TABLE IF NOT EXISTS game_config.hero_respec_costs (
+    min_level INT PRIMARY KEY CHECK (min_level >= 1),
+    cost_gold BIGINT NOT NULL DEFAULT 0 CHECK (cost_gold >= 0),
+    item_id UUID REFERENCES game_config.items(id) ON DELETE SET NULL,
+    item_count INT NOT NULL DEFAULT 1 CHECK (item_count > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_hero_respec_costs_updated_at
+    BEFORE UPDATE ON game_config.hero_respec_costs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE game_config.hero_respec_costs IS '洗点消耗配置：按英雄等级分档，可支付金币或消耗洗点道具';
+COMMENT ON COLUMN game_config.hero_respec_costs.item_id IS '可替代金币的洗点道具，为空表示只能支付金币';
+
+INSERT INTO game_config.hero_respec_costs (min_level, cost_gold)
+VALUES (1, 100), (10, 1000), (20, 5000), (30, 20000)
+ON CONFLICT (min_level) DO NOTHING;
+
+-- 2) 洗点审计记录（一次洗点一条）
+CREATE TABLE IF NOT EXISTS game_runtime.hero_respec_operations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    hero_id UUID NOT NULL REFERENCES game_runtime.heroes(id) ON DELETE CASCADE,
+    payment_method VARCHAR(16) NOT NULL CHECK (payment_method IN ('gold', 'item')),
+    gold_spent BIGINT NOT NULL DEFAULT 0,
+    item_id UUID,
+    items_spent JSONB NOT NULL DEFAULT '[]'::jsonb,
+    refunded_xp BIGINT NOT NULL DEFAULT 0,
+    attributes_reset INT NOT NULL DEFAULT 0,
+    skills_removed INT NOT NULL DEFAULT 0,
+    skills_reset INT NOT NULL DEFAULT 0,
+    snapshot JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_hero_respec_operations_hero
+    ON game_runtime.hero_respec_operations (hero_id, created_at DESC);
+
+COMMENT ON TABLE game_runtime.hero_respec_operations IS '英雄洗点审计记录';
+COMMENT ON COLUMN game_runtime.hero_respec_operations.items_spent IS '实际扣除的道具堆叠 [{"instance_id","taken"}]';
+COMMENT ON COLUMN game_runtime.hero_respec_operations.snapshot IS '洗点前的属性分配与技能等级';
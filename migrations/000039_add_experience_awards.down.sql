@@ -0,0 +1,5 @@
+-- 000039_add_experience_awards.down.sql
+
+DROP TABLE IF EXISTS game_runtime.hero_experience_awards;
+DROP TABLE IF EXISTS game_runtime.hero_rested_experience;
+DROP TABLE IF EXISTS game_config.experience_award_rules;
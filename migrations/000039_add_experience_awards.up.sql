@@ -0,0 +1,67 @@
+-- 000039_add_experience_awards.up.sql
+-- 服务端经验发放：按来源配置的分配规则、休息经验池与发放记录
+
+-- 1) 经验分配规则（未单独配置的来源使用 default 行；休息经验累积参数只取 default 行）
+CREATE TABLE IF NOT EXISTS game_config.experience_award_rules (
+    source_type VARCHAR(32) PRIMARY KEY,
+    split_mode VARCHAR(16) NOT NULL DEFAULT 'equal' CHECK (split_mode IN ('equal', 'full')),
+    level_penalty_grace INT NOT NULL DEFAULT 3 CHECK (level_penalty_grace >= 0),
+    level_penalty_step NUMERIC(5,4) NOT NULL DEFAULT 0.1 CHECK (level_penalty_step >= 0 AND level_penalty_step <= 1),
+    level_penalty_floor NUMERIC(5,4) NOT NULL DEFAULT 0.1 CHECK (level_penalty_floor >= 0 AND level_penalty_floor <= 1),
+    rested_bonus_rate NUMERIC(6,4) NOT NULL DEFAULT 1 CHECK (rested_bonus_rate >= 0),
+    rested_xp_per_hour INT NOT NULL DEFAULT 0 CHECK (rested_xp_per_hour >= 0),
+    rested_xp_cap INT NOT NULL DEFAULT 0 CHECK (rested_xp_cap >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_experience_award_rules_updated_at
+    BEFORE UPDATE ON game_config.experience_award_rules
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE game_config.experience_award_rules IS '经验分配规则：按来源（battle/dungeon_event）配置平分方式、等级差衰减与休息经验加成';
+COMMENT ON COLUMN game_config.experience_award_rules.split_mode IS 'equal=总经验由参与英雄平分，full=每名英雄获得全额';
+COMMENT ON COLUMN game_config.experience_award_rules.level_penalty_grace IS '英雄等级超出内容等级区间不超过该值时不衰减';
+COMMENT ON COLUMN game_config.experience_award_rules.level_penalty_step IS '超出宽限后每级衰减比例';
+COMMENT ON COLUMN game_config.experience_award_rules.level_penalty_floor IS '衰减后的最低比例';
+COMMENT ON COLUMN game_config.experience_award_rules.rested_bonus_rate IS '休息经验加成上限（占本次基础经验的比例，1 表示最多翻倍）';
+COMMENT ON COLUMN game_config.experience_award_rules.rested_xp_per_hour IS '休息经验每小时累积量（仅 default 行生效）';
+COMMENT ON COLUMN game_config.experience_award_rules.rested_xp_cap IS '休息经验池上限（仅 default 行生效）';
+
+INSERT INTO game_config.experience_award_rules
+    (source_type, split_mode, level_penalty_grace, level_penalty_step, level_penalty_floor, rested_bonus_rate, rested_xp_per_hour, rested_xp_cap)
+VALUES
+    ('default', 'equal', 3, 0.1, 0.1, 1, 20, 2000),
+    ('battle', 'equal', 3, 0.1, 0.1, 1, 0, 0),
+    ('dungeon_event', 'full', 5, 0.1, 0.2, 0, 0, 0)
+ON CONFLICT (source_type) DO NOTHING;
+
+-- 2) 英雄休息经验池
+CREATE TABLE IF NOT EXISTS game_runtime.hero_rested_experience (
+    hero_id UUID PRIMARY KEY REFERENCES game_runtime.heroes(id) ON DELETE CASCADE,
+    rested_xp BIGINT NOT NULL DEFAULT 0 CHECK (rested_xp >= 0),
+    accrued_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE game_runtime.hero_rested_experience IS '英雄休息经验池：自上次结算起按小时累积，获得经验时按比例消耗为额外经验';
+
+-- 3) 经验发放记录（同一来源对同一英雄只发放一次）
+CREATE TABLE IF NOT EXISTS game_runtime.hero_experience_awards (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    hero_id UUID NOT NULL REFERENCES game_runtime.heroes(id) ON DELETE CASCADE,
+    source_type VARCHAR(32) NOT NULL,
+    source_id VARCHAR(64) NOT NULL,
+    hero_level INT NOT NULL,
+    base_xp BIGINT NOT NULL DEFAULT 0,
+    penalty_rate NUMERIC(5,4) NOT NULL DEFAULT 1,
+    rested_bonus BIGINT NOT NULL DEFAULT 0,
+    awarded_xp BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_hero_experience_awards_source UNIQUE (source_type, source_id, hero_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_hero_experience_awards_hero
+    ON game_runtime.hero_experience_awards (hero_id, created_at DESC);
+
+COMMENT ON TABLE game_runtime.hero_experience_awards IS '经验发放记录：base_xp 为分配并衰减后的经验，awarded_xp = base_xp + rested_bonus';
+COMMENT ON COLUMN game_runtime.hero_experience_awards.source_id IS '来源对象ID：战斗为 battle_id，事件为事件日志ID';
@@ -0,0 +1,3 @@
+-- 000040_add_period_resets.down.sql
+
+DROP TABLE IF EXISTS game_runtime.period_reset_runs;
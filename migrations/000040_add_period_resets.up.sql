@@ -0,0 +1,18 @@
+-- 000040_add_period_resets.up.sql
+-- 周期重置执行记录：每个重置项在每个周期只执行一次，停机后启动时补执行
+
+CREATE TABLE IF NOT EXISTS game_runtime.period_reset_runs (
+    reset_key VARCHAR(64) NOT NULL,
+    period_start TIMESTAMPTZ NOT NULL,
+    affected_rows BIGINT NOT NULL DEFAULT 0,
+    executed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (reset_key, period_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_period_reset_runs_executed_at
+    ON game_runtime.period_reset_runs (executed_at);
+
+COMMENT ON TABLE game_runtime.period_reset_runs IS '周期重置执行记录：(reset_key, period_start) 唯一，保证同一周期只重置一次';
+COMMENT ON COLUMN game_runtime.period_reset_runs.reset_key IS '重置项：dungeon_attempts/world_drop_daily/world_drop_hourly 等';
+COMMENT ON COLUMN game_runtime.period_reset_runs.period_start IS '周期起点（按配置的重置时刻与时区计算）';
+COMMENT ON COLUMN game_runtime.period_reset_runs.affected_rows IS '本次重置影响的行数';
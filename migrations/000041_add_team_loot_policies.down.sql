@@ -0,0 +1,17 @@
+-- 000041_add_team_loot_policies.down.sql
+
+DROP TABLE IF EXISTS game_runtime.team_loot_roll_votes;
+DROP TABLE IF EXISTS game_runtime.team_loot_rolls;
+
+ALTER TABLE game_runtime.team_loot_distribution_history
+    DROP COLUMN IF EXISTS decision_note,
+    DROP COLUMN IF EXISTS policy;
+
+ALTER TABLE game_runtime.team_warehouses
+    DROP CONSTRAINT IF EXISTS check_roll_timeout,
+    DROP CONSTRAINT IF EXISTS check_item_policy,
+    DROP CONSTRAINT IF EXISTS check_gold_policy,
+    DROP COLUMN IF EXISTS round_robin_cursor,
+    DROP COLUMN IF EXISTS roll_timeout_seconds,
+    DROP COLUMN IF EXISTS item_policy,
+    DROP COLUMN IF EXISTS gold_policy;
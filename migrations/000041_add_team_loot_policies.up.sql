@@ -0,0 +1,68 @@
+-- 000041_add_team_loot_policies.up.sql
+-- 团队战利品自动分配：仓库级分配策略、需求/贪婪/放弃投骰、分配历史记录策略与决策说明
+
+-- 1) 仓库分配策略（金币与物品分别配置）
+ALTER TABLE game_runtime.team_warehouses
+    ADD COLUMN IF NOT EXISTS gold_policy VARCHAR(20) NOT NULL DEFAULT 'manual',
+    ADD COLUMN IF NOT EXISTS item_policy VARCHAR(20) NOT NULL DEFAULT 'manual',
+    ADD COLUMN IF NOT EXISTS roll_timeout_seconds INT NOT NULL DEFAULT 120,
+    ADD COLUMN IF NOT EXISTS round_robin_cursor INT NOT NULL DEFAULT 0;
+
+ALTER TABLE game_runtime.team_warehouses
+    ADD CONSTRAINT check_gold_policy CHECK (gold_policy IN ('manual', 'equal_split')),
+    ADD CONSTRAINT check_item_policy CHECK (item_policy IN ('manual', 'round_robin', 'need_greed', 'class_need')),
+    ADD CONSTRAINT check_roll_timeout CHECK (roll_timeout_seconds BETWEEN 10 AND 86400);
+
+COMMENT ON COLUMN game_runtime.team_warehouses.gold_policy IS '金币分配策略：manual-手动, equal_split-入库后全员平分（余数留在仓库）';
+COMMENT ON COLUMN game_runtime.team_warehouses.item_policy IS '物品分配策略：manual-手动, round_robin-轮流, need_greed-需求/贪婪/放弃投骰, class_need-职业限制需求投骰';
+COMMENT ON COLUMN game_runtime.team_warehouses.roll_timeout_seconds IS '投骰超时秒数，超时未投视为放弃';
+COMMENT ON COLUMN game_runtime.team_warehouses.round_robin_cursor IS '轮流分配游标（按入队顺序）';
+
+-- 2) 分配历史增加策略与决策说明
+ALTER TABLE game_runtime.team_loot_distribution_history
+    ADD COLUMN IF NOT EXISTS policy VARCHAR(20) NOT NULL DEFAULT 'manual',
+    ADD COLUMN IF NOT EXISTS decision_note TEXT;
+
+COMMENT ON COLUMN game_runtime.team_loot_distribution_history.policy IS '分配方式：manual/equal_split/round_robin/need_greed/class_need';
+COMMENT ON COLUMN game_runtime.team_loot_distribution_history.decision_note IS '决策说明（如投骰结果、轮流顺位）';
+
+-- 3) 投骰
+CREATE TABLE IF NOT EXISTS game_runtime.team_loot_rolls (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    team_id UUID NOT NULL REFERENCES game_runtime.teams(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES game_runtime.team_warehouses(id) ON DELETE CASCADE,
+    item_id UUID NOT NULL,
+    item_type VARCHAR(50) NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    policy VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    expires_at TIMESTAMPTZ NOT NULL,
+    winner_hero_id UUID,
+    winning_choice VARCHAR(10),
+    winning_roll INT,
+    result_note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMPTZ,
+
+    CONSTRAINT check_loot_roll_policy CHECK (policy IN ('need_greed', 'class_need')),
+    CONSTRAINT check_loot_roll_status CHECK (status IN ('open', 'resolving', 'awarded', 'passed', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_loot_rolls_team ON game_runtime.team_loot_rolls (team_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_team_loot_rolls_open ON game_runtime.team_loot_rolls (expires_at) WHERE status = 'open';
+
+COMMENT ON TABLE game_runtime.team_loot_rolls IS '战利品投骰：物品留在仓库直至全员投骰或超时，由需求>贪婪、点数高者获得';
+COMMENT ON COLUMN game_runtime.team_loot_rolls.status IS 'open-投骰中, resolving-结算中, awarded-已发放, passed-全员放弃（留在仓库）, failed-发放失败（留在仓库）';
+
+CREATE TABLE IF NOT EXISTS game_runtime.team_loot_roll_votes (
+    roll_id UUID NOT NULL REFERENCES game_runtime.team_loot_rolls(id) ON DELETE CASCADE,
+    hero_id UUID NOT NULL REFERENCES game_runtime.heroes(id) ON DELETE CASCADE,
+    choice VARCHAR(10) NOT NULL,
+    roll_value INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (roll_id, hero_id),
+
+    CONSTRAINT check_loot_roll_choice CHECK (choice IN ('need', 'greed', 'pass'))
+);
+
+COMMENT ON TABLE game_runtime.team_loot_roll_votes IS '战利品投骰选择：need/greed 时服务端生成 1-100 点数，pass 为 0';
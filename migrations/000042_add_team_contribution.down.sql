@@ -0,0 +1,9 @@
+-- 000042_add_team_contribution.down.sql
+
+COMMENT ON COLUMN game_runtime.team_loot_distribution_history.policy IS '分配方式：manual/equal_split/round_robin/need_greed/class_need';
+
+DROP TABLE IF EXISTS game_runtime.team_contribution_bids;
+DROP TABLE IF EXISTS game_runtime.team_contribution_auctions;
+DROP TABLE IF EXISTS game_runtime.team_contribution_ledger;
+DROP TABLE IF EXISTS game_runtime.team_contribution_balances;
+DROP TABLE IF EXISTS game_runtime.team_contribution_settings;
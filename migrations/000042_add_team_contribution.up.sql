@@ -0,0 +1,96 @@
+-- 000042_add_team_contribution.up.sql
+-- 团队贡献点（DKP）：成员余额与流水、团队配置、仓库物品限时竞拍
+
+-- 1) 团队配置
+CREATE TABLE IF NOT EXISTS game_runtime.team_contribution_settings (
+    team_id UUID PRIMARY KEY REFERENCES game_runtime.teams(id) ON DELETE CASCADE,
+    dungeon_completion_points INT NOT NULL DEFAULT 10,
+    auction_duration_seconds INT NOT NULL DEFAULT 3600,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT check_dungeon_completion_points CHECK (dungeon_completion_points >= 0),
+    CONSTRAINT check_auction_duration CHECK (auction_duration_seconds BETWEEN 60 AND 604800)
+);
+
+COMMENT ON TABLE game_runtime.team_contribution_settings IS '团队贡献点配置，未配置时使用默认值';
+COMMENT ON COLUMN game_runtime.team_contribution_settings.dungeon_completion_points IS '通关地城时每名成员获得的贡献点';
+COMMENT ON COLUMN game_runtime.team_contribution_settings.auction_duration_seconds IS '竞拍默认时长（秒）';
+
+-- 2) 成员余额
+CREATE TABLE IF NOT EXISTS game_runtime.team_contribution_balances (
+    team_id UUID NOT NULL REFERENCES game_runtime.teams(id) ON DELETE CASCADE,
+    hero_id UUID NOT NULL REFERENCES game_runtime.heroes(id) ON DELETE CASCADE,
+    balance BIGINT NOT NULL DEFAULT 0,
+    lifetime_earned BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (team_id, hero_id),
+
+    CONSTRAINT check_contribution_balance CHECK (balance >= 0)
+);
+
+COMMENT ON TABLE game_runtime.team_contribution_balances IS '团队成员贡献点余额（出价冻结的点数已从余额扣除）';
+COMMENT ON COLUMN game_runtime.team_contribution_balances.lifetime_earned IS '累计获得的贡献点（不含竞拍退还）';
+
+-- 3) 流水
+CREATE TABLE IF NOT EXISTS game_runtime.team_contribution_ledger (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    team_id UUID NOT NULL REFERENCES game_runtime.teams(id) ON DELETE CASCADE,
+    hero_id UUID NOT NULL REFERENCES game_runtime.heroes(id) ON DELETE CASCADE,
+    amount BIGINT NOT NULL,
+    balance_after BIGINT NOT NULL,
+    source_type VARCHAR(30) NOT NULL,
+    source_id VARCHAR(100),
+    operator_hero_id UUID,
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT check_contribution_amount CHECK (amount <> 0),
+    CONSTRAINT check_contribution_source CHECK (source_type IN ('dungeon_completion', 'manual_grant', 'auction_bid', 'auction_refund'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_contribution_ledger_team ON game_runtime.team_contribution_ledger (team_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_team_contribution_ledger_hero ON game_runtime.team_contribution_ledger (team_id, hero_id, created_at DESC);
+
+COMMENT ON TABLE game_runtime.team_contribution_ledger IS '团队贡献点流水：正数为获得，负数为消耗';
+COMMENT ON COLUMN game_runtime.team_contribution_ledger.source_type IS 'dungeon_completion-通关地城, manual_grant-手动发放/扣除, auction_bid-竞拍出价冻结, auction_refund-被超价或流拍退还';
+COMMENT ON COLUMN game_runtime.team_contribution_ledger.source_id IS '来源ID（地城进度ID或竞拍ID）';
+
+-- 4) 竞拍
+CREATE TABLE IF NOT EXISTS game_runtime.team_contribution_auctions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    team_id UUID NOT NULL REFERENCES game_runtime.teams(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES game_runtime.team_warehouses(id) ON DELETE CASCADE,
+    item_id UUID NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    min_bid BIGINT NOT NULL DEFAULT 1 CHECK (min_bid > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    created_by UUID NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    highest_bid BIGINT NOT NULL DEFAULT 0,
+    highest_bidder_hero_id UUID,
+    result_note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    settled_at TIMESTAMPTZ,
+
+    CONSTRAINT check_contribution_auction_status CHECK (status IN ('open', 'settling', 'awarded', 'unsold', 'failed', 'cancelled'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_contribution_auctions_team ON game_runtime.team_contribution_auctions (team_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_team_contribution_auctions_open ON game_runtime.team_contribution_auctions (ends_at) WHERE status = 'open';
+
+COMMENT ON TABLE game_runtime.team_contribution_auctions IS '仓库物品贡献点竞拍：出价即冻结点数，被超价时退还；到期后最高出价者通过仓库分配获得物品';
+COMMENT ON COLUMN game_runtime.team_contribution_auctions.status IS 'open-竞拍中, settling-结算中, awarded-已发放, unsold-流拍, failed-发放失败（已退还）, cancelled-已取消（已退还）';
+
+CREATE TABLE IF NOT EXISTS game_runtime.team_contribution_bids (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    auction_id UUID NOT NULL REFERENCES game_runtime.team_contribution_auctions(id) ON DELETE CASCADE,
+    hero_id UUID NOT NULL REFERENCES game_runtime.heroes(id) ON DELETE CASCADE,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_contribution_bids_auction ON game_runtime.team_contribution_bids (auction_id, created_at DESC);
+
+COMMENT ON TABLE game_runtime.team_contribution_bids IS '竞拍出价记录';
+
+COMMENT ON COLUMN game_runtime.team_loot_distribution_history.policy IS '分配方式：manual/equal_split/round_robin/need_greed/class_need/auction';
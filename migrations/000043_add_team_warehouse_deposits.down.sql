@@ -0,0 +1,17 @@
+-- 000043_add_team_warehouse_deposits.down.sql
+
+COMMENT ON COLUMN game_runtime.team_loot_distribution_history.policy IS '分配方式：manual/equal_split/round_robin/need_greed/class_need/auction';
+
+DROP INDEX IF EXISTS game_runtime.idx_warehouse_loot_log_team_type;
+
+ALTER TABLE game_runtime.team_warehouse_loot_log
+    DROP CONSTRAINT IF EXISTS check_loot_log_entry_type,
+    DROP COLUMN IF EXISTS request_id,
+    DROP COLUMN IF EXISTS hero_id,
+    DROP COLUMN IF EXISTS entry_type;
+
+COMMENT ON TABLE game_runtime.team_warehouse_loot_log IS '团队仓库战利品入库审计日志';
+COMMENT ON COLUMN game_runtime.team_warehouse_loot_log.items IS '入库物品明细 JSON 数组';
+COMMENT ON COLUMN game_runtime.team_warehouse_loot_log.result IS '入库结果：success/failed';
+
+DROP TABLE IF EXISTS game_runtime.team_warehouse_withdrawal_requests;
@@ -0,0 +1,49 @@
+-- 000043_add_team_warehouse_deposits.up.sql
+-- 团队仓库成员捐献与取用申请：申请队列 + 仓库日志区分入库来源
+
+-- 1) 取用申请
+CREATE TABLE IF NOT EXISTS game_runtime.team_warehouse_withdrawal_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    team_id UUID NOT NULL REFERENCES game_runtime.teams(id) ON DELETE CASCADE,
+    warehouse_id UUID NOT NULL REFERENCES game_runtime.team_warehouses(id) ON DELETE CASCADE,
+    hero_id UUID NOT NULL REFERENCES game_runtime.heroes(id) ON DELETE CASCADE,
+    item_id UUID NOT NULL,
+    quantity INT NOT NULL,
+    note TEXT,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    reviewer_hero_id UUID,
+    review_note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    reviewed_at TIMESTAMPTZ,
+
+    CONSTRAINT check_withdrawal_quantity CHECK (quantity > 0),
+    CONSTRAINT check_withdrawal_status CHECK (status IN ('pending', 'approved', 'denied', 'cancelled'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_warehouse_withdrawal_team_status ON game_runtime.team_warehouse_withdrawal_requests (team_id, status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_warehouse_withdrawal_hero_status ON game_runtime.team_warehouse_withdrawal_requests (hero_id, status);
+
+COMMENT ON TABLE game_runtime.team_warehouse_withdrawal_requests IS '团队仓库物品取用申请，由队长或管理员审批';
+COMMENT ON COLUMN game_runtime.team_warehouse_withdrawal_requests.item_id IS '申请的物品配置ID（引用 game_config.items）';
+COMMENT ON COLUMN game_runtime.team_warehouse_withdrawal_requests.status IS 'pending-待审批, approved-已批准并发放, denied-已拒绝, cancelled-申请人撤回';
+COMMENT ON COLUMN game_runtime.team_warehouse_withdrawal_requests.reviewer_hero_id IS '审批人英雄ID';
+
+-- 2) 仓库日志扩展：成员捐献与取用
+ALTER TABLE game_runtime.team_warehouse_loot_log
+    ADD COLUMN IF NOT EXISTS entry_type VARCHAR(16) NOT NULL DEFAULT 'loot',
+    ADD COLUMN IF NOT EXISTS hero_id UUID,
+    ADD COLUMN IF NOT EXISTS request_id UUID;
+
+ALTER TABLE game_runtime.team_warehouse_loot_log
+    ADD CONSTRAINT check_loot_log_entry_type CHECK (entry_type IN ('loot', 'deposit', 'withdrawal'));
+
+CREATE INDEX IF NOT EXISTS idx_warehouse_loot_log_team_type ON game_runtime.team_warehouse_loot_log (team_id, entry_type, created_at DESC);
+
+COMMENT ON TABLE game_runtime.team_warehouse_loot_log IS '团队仓库出入库审计日志（战利品入库、成员捐献、取用申请）';
+COMMENT ON COLUMN game_runtime.team_warehouse_loot_log.items IS '物品明细 JSON 数组';
+COMMENT ON COLUMN game_runtime.team_warehouse_loot_log.result IS '结果：success/failed，取用申请另有 pending/denied/cancelled';
+COMMENT ON COLUMN game_runtime.team_warehouse_loot_log.entry_type IS 'loot-战利品入库, deposit-成员捐献, withdrawal-取用申请';
+COMMENT ON COLUMN game_runtime.team_warehouse_loot_log.hero_id IS '捐献者或申请人英雄ID';
+COMMENT ON COLUMN game_runtime.team_warehouse_loot_log.request_id IS '关联的取用申请ID';
+
+COMMENT ON COLUMN game_runtime.team_loot_distribution_history.policy IS '分配方式：manual/equal_split/round_robin/need_greed/class_need/auction/withdrawal';
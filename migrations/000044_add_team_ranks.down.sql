@@ -0,0 +1,8 @@
+-- 000044_add_team_ranks.down.sql
+
+DROP INDEX IF EXISTS game_runtime.idx_team_members_rank;
+
+ALTER TABLE game_runtime.team_members
+    DROP COLUMN IF EXISTS rank_id;
+
+DROP TABLE IF EXISTS game_runtime.team_ranks;
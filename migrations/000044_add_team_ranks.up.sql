@@ -0,0 +1,30 @@
+-- 000044_add_team_ranks.up.sql
+-- 团队自定义职阶：队长可创建带权限集的职阶（如 军官/军需官/新兵）并分配给成员
+
+-- 1) 职阶定义
+CREATE TABLE IF NOT EXISTS game_runtime.team_ranks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    team_id UUID NOT NULL REFERENCES game_runtime.teams(id) ON DELETE CASCADE,
+    name VARCHAR(32) NOT NULL,
+    level INT NOT NULL,
+    permissions TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT uq_team_ranks_team_name UNIQUE (team_id, name),
+    CONSTRAINT check_team_rank_level CHECK (level BETWEEN 1 AND 99)
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_ranks_team_level ON game_runtime.team_ranks (team_id, level DESC);
+
+COMMENT ON TABLE game_runtime.team_ranks IS '团队自定义职阶';
+COMMENT ON COLUMN game_runtime.team_ranks.level IS '职阶等级（1-99），队长固定为 100，只能管理等级更低的成员';
+COMMENT ON COLUMN game_runtime.team_ranks.permissions IS '职阶拥有的团队权限列表，如 distribute_loot、invite_member、kick_member';
+
+-- 2) 成员职阶
+ALTER TABLE game_runtime.team_members
+    ADD COLUMN IF NOT EXISTS rank_id UUID REFERENCES game_runtime.team_ranks(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_team_members_rank ON game_runtime.team_members (rank_id) WHERE rank_id IS NOT NULL;
+
+COMMENT ON COLUMN game_runtime.team_members.rank_id IS '自定义职阶，设置后非队长成员按职阶权限判定，为空时按 role 默认权限';
@@ -0,0 +1,3 @@
+-- 000045_add_team_activity_logs.down.sql
+
+DROP TABLE IF EXISTS game_runtime.team_activity_logs;
@@ -0,0 +1,30 @@
+-- 000045_add_team_activity_logs.up.sql
+-- 团队动态日志：各团队服务在业务事务内写入，统一提供“团队里发生了什么”的时间线
+
+CREATE TABLE IF NOT EXISTS game_runtime.team_activity_logs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    team_id UUID NOT NULL REFERENCES game_runtime.teams(id) ON DELETE CASCADE,
+    category VARCHAR(20) NOT NULL,
+    event_type VARCHAR(40) NOT NULL,
+    actor_hero_id UUID,
+    target_hero_id UUID,
+    source_id VARCHAR(100),
+    details JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT check_team_activity_category CHECK (category IN ('team', 'member', 'rank', 'warehouse', 'contribution', 'dungeon'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_activity_logs_team ON game_runtime.team_activity_logs (team_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_team_activity_logs_category ON game_runtime.team_activity_logs (team_id, category, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_team_activity_logs_actor ON game_runtime.team_activity_logs (team_id, actor_hero_id, created_at DESC) WHERE actor_hero_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_team_activity_logs_target ON game_runtime.team_activity_logs (team_id, target_hero_id, created_at DESC) WHERE target_hero_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_team_activity_logs_created ON game_runtime.team_activity_logs (created_at);
+
+COMMENT ON TABLE game_runtime.team_activity_logs IS '团队动态日志，过期记录由定时任务按保留天数清理';
+COMMENT ON COLUMN game_runtime.team_activity_logs.category IS 'team-团队, member-成员, rank-职阶, warehouse-仓库, contribution-贡献点, dungeon-地城';
+COMMENT ON COLUMN game_runtime.team_activity_logs.event_type IS '事件类型，如 member_kicked、gold_distributed、dungeon_completed';
+COMMENT ON COLUMN game_runtime.team_activity_logs.actor_hero_id IS '操作者英雄ID，系统触发的事件为空';
+COMMENT ON COLUMN game_runtime.team_activity_logs.target_hero_id IS '受影响的英雄ID（被踢出、被任命、获得分配的成员等）';
+COMMENT ON COLUMN game_runtime.team_activity_logs.source_id IS '关联的业务记录ID（申请、邀请、竞拍、地城进度等）';
+COMMENT ON COLUMN game_runtime.team_activity_logs.details IS '事件详情，按事件类型不同包含金额、物品、原因等';
@@ -0,0 +1,3 @@
+-- 000046_add_battle_result_steps.down.sql
+
+DROP TABLE IF EXISTS game_runtime.battle_result_steps;
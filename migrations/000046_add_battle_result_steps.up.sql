@@ -0,0 +1,15 @@
+-- 000046_add_battle_result_steps.up.sql
+-- 战斗结算步骤标记：耐久磨损、世界掉落统计、地城通关与标记在同一事务内写入，重试同一 battle_id 时跳过已完成的步骤
+
+CREATE TABLE IF NOT EXISTS game_runtime.battle_result_steps (
+    battle_id VARCHAR(64) NOT NULL REFERENCES game_runtime.battle_result_outcomes(battle_id) ON DELETE CASCADE,
+    step VARCHAR(32) NOT NULL,
+    result JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (battle_id, step),
+    CONSTRAINT chk_battle_result_steps_step CHECK (step IN ('durability_wear', 'world_drop', 'dungeon_completed'))
+);
+
+COMMENT ON TABLE game_runtime.battle_result_steps IS '战斗结算已生效的副作用步骤，同一 battle_id 每个步骤只执行一次';
+COMMENT ON COLUMN game_runtime.battle_result_steps.step IS '步骤：durability_wear(耐久磨损)/world_drop(世界掉落统计)/dungeon_completed(地城通关)';
+COMMENT ON COLUMN game_runtime.battle_result_steps.result IS '步骤结果 JSON（世界掉落物品、通关后的地城进度），重试时直接复用';
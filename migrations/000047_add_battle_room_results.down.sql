@@ -0,0 +1,16 @@
+-- 000047_add_battle_room_results.down.sql
+
+DELETE FROM game_runtime.battle_result_steps WHERE step = 'room_resolved';
+
+ALTER TABLE game_runtime.battle_result_steps
+    DROP CONSTRAINT IF EXISTS chk_battle_result_steps_step;
+ALTER TABLE game_runtime.battle_result_steps
+    ADD CONSTRAINT chk_battle_result_steps_step CHECK (step IN ('durability_wear', 'world_drop', 'dungeon_completed'));
+
+COMMENT ON COLUMN game_runtime.battle_result_steps.step IS '步骤：durability_wear(耐久磨损)/world_drop(世界掉落统计)/dungeon_completed(地城通关)';
+
+DROP INDEX IF EXISTS game_runtime.idx_battle_reports_progress;
+
+ALTER TABLE game_runtime.battle_reports
+    DROP COLUMN IF EXISTS room_id,
+    DROP COLUMN IF EXISTS progress_id;
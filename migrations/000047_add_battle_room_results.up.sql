@@ -0,0 +1,18 @@
+-- 000047_add_battle_room_results.up.sql
+-- 战斗房间结算：战报记录所属地城进度与房间，房间胜负以已结算的战斗结果为准，同一场战斗只能结算一次房间
+
+ALTER TABLE game_runtime.battle_reports
+    ADD COLUMN IF NOT EXISTS progress_id UUID,
+    ADD COLUMN IF NOT EXISTS room_id VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_battle_reports_progress ON game_runtime.battle_reports(progress_id);
+
+COMMENT ON COLUMN game_runtime.battle_reports.progress_id IS '所属团队地城进度 ID（房间战斗，可空）';
+COMMENT ON COLUMN game_runtime.battle_reports.room_id IS '所属房间 ID 或房间编码（房间战斗，可空）';
+
+ALTER TABLE game_runtime.battle_result_steps
+    DROP CONSTRAINT IF EXISTS chk_battle_result_steps_step;
+ALTER TABLE game_runtime.battle_result_steps
+    ADD CONSTRAINT chk_battle_result_steps_step CHECK (step IN ('durability_wear', 'world_drop', 'dungeon_completed', 'room_resolved'));
+
+COMMENT ON COLUMN game_runtime.battle_result_steps.step IS '步骤：durability_wear(耐久磨损)/world_drop(世界掉落统计)/dungeon_completed(地城通关)/room_resolved(已用于结算战斗房间)';
@@ -0,0 +1,4 @@
+-- 000048_add_battle_outcome_claim_token.down.sql
+
+ALTER TABLE game_runtime.battle_result_outcomes
+    DROP COLUMN IF EXISTS claim_token;
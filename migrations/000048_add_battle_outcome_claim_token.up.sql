@@ -0,0 +1,7 @@
+-- 000048_add_battle_outcome_claim_token.up.sql
+-- 战斗结算认领令牌：超时重新认领后，原处理者不能再覆盖结算结果或失败状态
+
+ALTER TABLE game_runtime.battle_result_outcomes
+    ADD COLUMN IF NOT EXISTS claim_token UUID;
+
+COMMENT ON COLUMN game_runtime.battle_result_outcomes.claim_token IS '当前认领者的令牌，每次认领（含失败/超时重新认领）都会更换；完成与失败只对持有该令牌的处理者生效';